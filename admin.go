@@ -0,0 +1,374 @@
+/*
+Copyright 2018-2022 Mailgun Technologies Inc
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gubernator
+
+import (
+	"context"
+	"strings"
+	"sync"
+
+	"github.com/mailgun/holster/v4/errors"
+	"github.com/mailgun/holster/v4/tracing"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+)
+
+// ResetRateLimits resets each of the requested rate limits on their owning peers as if the rate
+// limits were created new on first use.
+func (s *V1Instance) ResetRateLimits(ctx context.Context, r *ResetRateLimitsReq) (retval *ResetRateLimitsResp, reterr error) {
+	ctx = tracing.StartScope(ctx)
+	defer func() {
+		tracing.EndScope(ctx, reterr)
+	}()
+
+	if len(r.Requests) == 0 {
+		return nil, status.Error(codes.InvalidArgument, "must provide at least one rate limit to reset")
+	}
+
+	req := GetRateLimitsReq{Requests: make([]*RateLimitReq, len(r.Requests))}
+	for i, rl := range r.Requests {
+		// Copy the original since we are modifying the hits and behavior
+		rl = proto.Clone(rl).(*RateLimitReq)
+		rl.Hits = 0
+		rl.Cost = 0
+		// Reset the rate limit on the owning peer, not the local copy of a GLOBAL rate limit.
+		SetBehavior(&rl.Behavior, Behavior_GLOBAL, false)
+		SetBehavior(&rl.Behavior, Behavior_RESET_REMAINING, true)
+		req.Requests[i] = rl
+	}
+
+	resp, err := s.GetRateLimits(ctx, &req)
+	if err != nil {
+		return nil, err
+	}
+	return &ResetRateLimitsResp{Responses: resp.Responses}, nil
+}
+
+// SetRemaining sets the remaining hits of an existing rate limit on its owning peer.
+func (s *V1Instance) SetRemaining(ctx context.Context, r *SetRemainingReq) (retval *SetRemainingResp, reterr error) {
+	ctx = tracing.StartScope(ctx)
+	defer func() {
+		tracing.EndScope(ctx, reterr)
+	}()
+
+	if len(r.UniqueKey) == 0 {
+		return nil, status.Error(codes.InvalidArgument, "field 'unique_key' cannot be empty")
+	}
+	if len(r.Name) == 0 {
+		return nil, status.Error(codes.InvalidArgument, "field 'namespace' cannot be empty")
+	}
+
+	peer, err := s.GetPeer(ctx, r.Name+"_"+r.UniqueKey)
+	if err != nil {
+		return nil, errors.Wrapf(err, "while finding peer that owns rate limit '%s'", r.Name+"_"+r.UniqueKey)
+	}
+
+	if !peer.Info().IsOwner {
+		return peer.SetPeerRemaining(ctx, r)
+	}
+	return s.SetPeerRemaining(ctx, r)
+}
+
+// SetPeerRemaining sets the remaining hits of a rate limit owned by this instance. This method should
+// only be called by a peer relaying an AdminV1 SetRemaining request.
+func (s *V1Instance) SetPeerRemaining(ctx context.Context, r *SetRemainingReq) (retval *SetRemainingResp, reterr error) {
+	ctx = tracing.StartScope(ctx)
+	defer func() {
+		tracing.EndScope(ctx, reterr)
+	}()
+
+	rl, err := s.gubernatorPool.SetRemaining(ctx, r)
+	if err != nil {
+		return nil, err
+	}
+	return &SetRemainingResp{Response: rl}, nil
+}
+
+// DeleteRateLimit removes a rate limit from the cache and store of its owning peer.
+func (s *V1Instance) DeleteRateLimit(ctx context.Context, r *DeleteRateLimitReq) (retval *DeleteRateLimitResp, reterr error) {
+	ctx = tracing.StartScope(ctx)
+	defer func() {
+		tracing.EndScope(ctx, reterr)
+	}()
+
+	if len(r.UniqueKey) == 0 {
+		return nil, status.Error(codes.InvalidArgument, "field 'unique_key' cannot be empty")
+	}
+	if len(r.Name) == 0 {
+		return nil, status.Error(codes.InvalidArgument, "field 'namespace' cannot be empty")
+	}
+
+	peer, err := s.GetPeer(ctx, r.Name+"_"+r.UniqueKey)
+	if err != nil {
+		return nil, errors.Wrapf(err, "while finding peer that owns rate limit '%s'", r.Name+"_"+r.UniqueKey)
+	}
+
+	if !peer.Info().IsOwner {
+		return peer.DeletePeerRateLimit(ctx, r)
+	}
+	return s.DeletePeerRateLimit(ctx, r)
+}
+
+// DeletePeerRateLimit removes a rate limit owned by this instance from the cache and store. This method
+// should only be called by a peer relaying an AdminV1 DeleteRateLimit request.
+func (s *V1Instance) DeletePeerRateLimit(ctx context.Context, r *DeleteRateLimitReq) (retval *DeleteRateLimitResp, reterr error) {
+	ctx = tracing.StartScope(ctx)
+	defer func() {
+		tracing.EndScope(ctx, reterr)
+	}()
+
+	found, err := s.gubernatorPool.Delete(ctx, r)
+	if err != nil {
+		return nil, err
+	}
+	return &DeleteRateLimitResp{Found: found}, nil
+}
+
+// ResetNamespace removes the rate limits of a namespace from every peer in the local datacenter and
+// returns the number of rate limits removed.
+func (s *V1Instance) ResetNamespace(ctx context.Context, r *ResetNamespaceReq) (retval *ResetNamespaceResp, reterr error) {
+	ctx = tracing.StartScope(ctx)
+	defer func() {
+		tracing.EndScope(ctx, reterr)
+	}()
+
+	if len(r.Name) == 0 {
+		return nil, status.Error(codes.InvalidArgument, "field 'name' cannot be empty")
+	}
+
+	s.peerMutex.RLock()
+	peers := s.conf.LocalPicker.Peers()
+	s.peerMutex.RUnlock()
+
+	resps := make([]*ResetNamespaceResp, len(peers))
+	errs := make([]error, len(peers))
+	var wg sync.WaitGroup
+	for i, peer := range peers {
+		wg.Add(1)
+		go func(i int, peer *PeerClient) {
+			defer wg.Done()
+			if peer.Info().IsOwner {
+				resps[i], errs[i] = s.ResetPeerNamespace(ctx, r)
+				return
+			}
+			resps[i], errs[i] = peer.ResetPeerNamespace(ctx, r)
+		}(i, peer)
+	}
+	wg.Wait()
+
+	// The peers which could be asked have been reset, yet the namespace may not be empty
+	resp := &ResetNamespaceResp{}
+	for i, err := range errs {
+		if err != nil {
+			return nil, errors.Wrapf(err, "while resetting namespace on peer '%s'", peers[i].Info().GRPCAddress)
+		}
+		resp.Cleared += resps[i].Cleared
+	}
+	return resp, nil
+}
+
+// ResetPeerNamespace removes the rate limits of a namespace held by this instance from the cache and
+// store. This method should only be called by a peer fanning out an AdminV1 ResetNamespace request.
+func (s *V1Instance) ResetPeerNamespace(ctx context.Context, r *ResetNamespaceReq) (retval *ResetNamespaceResp, reterr error) {
+	ctx = tracing.StartScope(ctx)
+	defer func() {
+		tracing.EndScope(ctx, reterr)
+	}()
+
+	if len(r.Name) == 0 {
+		return nil, status.Error(codes.InvalidArgument, "field 'name' cannot be empty")
+	}
+
+	match := func(key string) bool {
+		return s.inNamespace(key, r.Name)
+	}
+	removed, err := s.gubernatorPool.RemoveMatching(ctx, match)
+	if err != nil {
+		return nil, err
+	}
+	keys := make(map[string]struct{}, len(removed))
+	for _, key := range removed {
+		keys[key] = struct{}{}
+	}
+
+	if store := s.conf.Store; store != nil {
+		// Only a BulkStore can tell us of the rate limits which are not in the cache
+		if s.bulkStore != nil {
+			ch, err := s.bulkStore.LoadAll()
+			if err != nil {
+				return nil, errors.Wrap(err, "Error in store.LoadAll")
+			}
+			for item := range ch {
+				if match(item.Key) {
+					keys[item.Key] = struct{}{}
+				}
+			}
+		}
+		for key := range keys {
+			store.Remove(ctx, key)
+		}
+	}
+
+	resp := &ResetNamespaceResp{}
+	for key := range keys {
+		// The cache and store hold nothing but rate limits. Only those owned by this instance are
+		// counted, skipping the local copies of GLOBAL rate limits owned by other peers, such that
+		// each rate limit is counted once across the peers.
+		if owner, err := s.GetPeer(ctx, key); err == nil && owner.Info().IsOwner {
+			resp.Cleared++
+		}
+	}
+	return resp, nil
+}
+
+// inNamespace returns true if the hash key is of a rate limit of the namespace. Since names may contain
+// the `_` separator the keys of the namespace `a_b` also begin with `a_`, such keys are excluded when the
+// longer namespace is known from Config.NamespaceDefaults.
+func (s *V1Instance) inNamespace(key, name string) bool {
+	prefix := name + "_"
+	if !strings.HasPrefix(key, prefix) {
+		return false
+	}
+	for other := range s.conf.NamespaceDefaults {
+		if strings.HasPrefix(other, prefix) && strings.HasPrefix(key, other+"_") {
+			return false
+		}
+	}
+	return true
+}
+
+// setRemaining sets the remaining hits of the rate limit in the cache and store, clamped to the
+// capacity of the rate limit. Returns codes.NotFound if the rate limit does not exist.
+func setRemaining(ctx context.Context, s Store, c Cache, r *SetRemainingReq) (*RateLimitResp, error) {
+	req := &RateLimitReq{Name: r.Name, UniqueKey: r.UniqueKey}
+	hashKey := req.HashKey()
+	now := MillisecondNow()
+
+	item, ok := c.GetItem(hashKey)
+	if s != nil && !ok {
+		if item, ok = s.Get(ctx, req); ok {
+			c.Add(item)
+		}
+	}
+	if !ok || item.ExpireAt <= now {
+		return nil, status.Errorf(codes.NotFound, "rate limit '%s' not found", hashKey)
+	}
+
+	clamp := func(max int64) int64 {
+		if r.Remaining < 0 {
+			return 0
+		}
+		if r.Remaining > max {
+			return max
+		}
+		return r.Remaining
+	}
+
+	var rl *RateLimitResp
+	switch t := item.Value.(type) {
+	case *TokenBucketItem:
+		t.Remaining = float64(clamp(tokenBucketCapacity(t)))
+		t.Status = Status_UNDER_LIMIT
+		if t.Remaining == 0 {
+			t.Status = Status_OVER_LIMIT
+		}
+		req.Algorithm = Algorithm_TOKEN_BUCKET
+		req.Limit = t.Limit
+		req.Duration = t.Duration
+		req.Burst = t.Burst
+		rl = &RateLimitResp{
+			Status:    t.Status,
+			Algorithm: Algorithm_TOKEN_BUCKET,
+			Limit:     t.Limit,
+			Remaining: int64(t.Remaining),
+			ResetTime: item.ExpireAt,
+		}
+	case *LeakyBucketItem:
+		// The bucket leaks from the new remaining from now on
+		t.Remaining = float64(clamp(t.Burst))
+		t.UpdatedAt = now
+		req.Algorithm = Algorithm_LEAKY_BUCKET
+		req.Limit = t.Limit
+		req.Duration = t.Duration
+		req.Burst = t.Burst
+		rl = &RateLimitResp{
+			Status:    Status_UNDER_LIMIT,
+			Algorithm: Algorithm_LEAKY_BUCKET,
+			Limit:     t.Limit,
+			Remaining: int64(t.Remaining),
+			ResetTime: leakyBucketResetTime(now, t.Limit, int64(t.Remaining), float64(t.Duration)/float64(t.Limit), LeakProfile_LINEAR),
+		}
+	case *HybridBucketItem:
+		t.Remaining = float64(clamp(t.Burst))
+		t.UpdatedAt = now
+		req.Algorithm = Algorithm_HYBRID
+		req.Limit = t.Limit
+		req.Duration = t.Duration
+		req.Burst = t.Burst
+		rl = &RateLimitResp{
+			Status:    Status_UNDER_LIMIT,
+			Algorithm: Algorithm_HYBRID,
+			Limit:     t.Limit,
+			Remaining: int64(t.Remaining),
+			ResetTime: hybridBucketResetTime(t, now),
+		}
+	default:
+		return nil, status.Errorf(codes.Internal, "rate limit '%s' has an invalid cache item", hashKey)
+	}
+
+	if s != nil {
+		s.OnChange(ctx, req, item)
+	}
+	return rl, nil
+}
+
+// dataPeersV1 serves the PeersV1 service with the data plane when the AdminV1 service is served by
+// separate admin servers. The methods which relay AdminV1 requests are refused, such that a client of
+// the data plane can not use them to bypass the admin servers; peers relay those to `PeerInfo.AdminAddress`.
+type dataPeersV1 struct {
+	*V1Instance
+}
+
+func (d *dataPeersV1) SetPeerRemaining(context.Context, *SetRemainingReq) (*SetRemainingResp, error) {
+	return nil, errAdminOnly("SetPeerRemaining")
+}
+
+func (d *dataPeersV1) DeletePeerRateLimit(context.Context, *DeleteRateLimitReq) (*DeleteRateLimitResp, error) {
+	return nil, errAdminOnly("DeletePeerRateLimit")
+}
+
+func (d *dataPeersV1) ListPeerRateLimits(context.Context, *ListRateLimitsReq) (*ListRateLimitsResp, error) {
+	return nil, errAdminOnly("ListPeerRateLimits")
+}
+
+func (d *dataPeersV1) StreamPeerRateLimits(*StreamRateLimitsReq, PeersV1_StreamPeerRateLimitsServer) error {
+	return errAdminOnly("StreamPeerRateLimits")
+}
+
+func (d *dataPeersV1) ResetPeerNamespace(context.Context, *ResetNamespaceReq) (*ResetNamespaceResp, error) {
+	return nil, errAdminOnly("ResetPeerNamespace")
+}
+
+func (d *dataPeersV1) ImportPeerRateLimits(context.Context, *ImportRateLimitsReq) (*ImportRateLimitsResp, error) {
+	return nil, errAdminOnly("ImportPeerRateLimits")
+}
+
+// errAdminOnly is returned by the data plane for a method which is only served by the admin servers
+func errAdminOnly(method string) error {
+	return status.Errorf(codes.PermissionDenied, "%s is only served on the admin listener", method)
+}
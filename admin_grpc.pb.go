@@ -0,0 +1,427 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+
+package gubernator
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.32.0 or later.
+const _ = grpc.SupportPackageIsVersion7
+
+// AdminV1Client is the client API for AdminV1 service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type AdminV1Client interface {
+	// Resets each of the provided rate limits as if created new on first use. The
+	// rate limits are reset on their owning peers.
+	ResetRateLimits(ctx context.Context, in *ResetRateLimitsReq, opts ...grpc.CallOption) (*ResetRateLimitsResp, error)
+	// Sets the remaining hits of an existing rate limit on its owning peer. The
+	// remaining hits are clamped to the capacity of the rate limit.
+	SetRemaining(ctx context.Context, in *SetRemainingReq, opts ...grpc.CallOption) (*SetRemainingResp, error)
+	// Removes a rate limit from the cache and store of its owning peer, such that
+	// the next request for the rate limit creates it new. Deleting a rate limit
+	// which does not exist is not an error.
+	DeleteRateLimit(ctx context.Context, in *DeleteRateLimitReq, opts ...grpc.CallOption) (*DeleteRateLimitResp, error)
+	// Lists the active rate limits of a namespace across all peers in the local
+	// datacenter, ordered by key.
+	ListRateLimits(ctx context.Context, in *ListRateLimitsReq, opts ...grpc.CallOption) (*ListRateLimitsResp, error)
+	// Streams the active rate limits of a namespace across all peers in the local
+	// datacenter, one peer at a time and in no particular order. Unlike ListRateLimits
+	// the namespace is never held in memory; the cache of each peer is walked as the
+	// client receives the rate limits, so clients should receive promptly.
+	StreamRateLimits(ctx context.Context, in *StreamRateLimitsReq, opts ...grpc.CallOption) (AdminV1_StreamRateLimitsClient, error)
+	// Reports the view each peer in the local datacenter holds of a GLOBAL rate limit, including
+	// the authoritative view of the owning peer, such that convergence lag between peers can be
+	// diagnosed. Peers which could not be asked are reported with an error.
+	GetGlobalRateLimitViews(ctx context.Context, in *GetGlobalRateLimitViewsReq, opts ...grpc.CallOption) (*GetGlobalRateLimitViewsResp, error)
+	// Removes every rate limit of a namespace from the cache and store of each peer in the local
+	// datacenter, such that the next request for each rate limit creates it new. Rate limits are
+	// only removed from stores which implement BulkStore, since other stores cannot be scanned.
+	ResetNamespace(ctx context.Context, in *ResetNamespaceReq, opts ...grpc.CallOption) (*ResetNamespaceResp, error)
+	// Seeds rate limits with known state, IE: when migrating from another rate limiter, such that
+	// users are not reset to the full limit. Each rate limit is written to the cache and store of its
+	// owning peer as provided, replacing any existing state of the same algorithm, without applying
+	// any hits. An existing rate limit of another algorithm is not replaced.
+	ImportRateLimits(ctx context.Context, in *ImportRateLimitsReq, opts ...grpc.CallOption) (*ImportRateLimitsResp, error)
+}
+
+type adminV1Client struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewAdminV1Client(cc grpc.ClientConnInterface) AdminV1Client {
+	return &adminV1Client{cc}
+}
+
+func (c *adminV1Client) ResetRateLimits(ctx context.Context, in *ResetRateLimitsReq, opts ...grpc.CallOption) (*ResetRateLimitsResp, error) {
+	out := new(ResetRateLimitsResp)
+	err := c.cc.Invoke(ctx, "/pb.gubernator.AdminV1/ResetRateLimits", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *adminV1Client) SetRemaining(ctx context.Context, in *SetRemainingReq, opts ...grpc.CallOption) (*SetRemainingResp, error) {
+	out := new(SetRemainingResp)
+	err := c.cc.Invoke(ctx, "/pb.gubernator.AdminV1/SetRemaining", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *adminV1Client) DeleteRateLimit(ctx context.Context, in *DeleteRateLimitReq, opts ...grpc.CallOption) (*DeleteRateLimitResp, error) {
+	out := new(DeleteRateLimitResp)
+	err := c.cc.Invoke(ctx, "/pb.gubernator.AdminV1/DeleteRateLimit", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *adminV1Client) ListRateLimits(ctx context.Context, in *ListRateLimitsReq, opts ...grpc.CallOption) (*ListRateLimitsResp, error) {
+	out := new(ListRateLimitsResp)
+	err := c.cc.Invoke(ctx, "/pb.gubernator.AdminV1/ListRateLimits", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *adminV1Client) StreamRateLimits(ctx context.Context, in *StreamRateLimitsReq, opts ...grpc.CallOption) (AdminV1_StreamRateLimitsClient, error) {
+	stream, err := c.cc.NewStream(ctx, &AdminV1_ServiceDesc.Streams[0], "/pb.gubernator.AdminV1/StreamRateLimits", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &adminV1StreamRateLimitsClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type AdminV1_StreamRateLimitsClient interface {
+	Recv() (*RateLimitItem, error)
+	grpc.ClientStream
+}
+
+type adminV1StreamRateLimitsClient struct {
+	grpc.ClientStream
+}
+
+func (x *adminV1StreamRateLimitsClient) Recv() (*RateLimitItem, error) {
+	m := new(RateLimitItem)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *adminV1Client) GetGlobalRateLimitViews(ctx context.Context, in *GetGlobalRateLimitViewsReq, opts ...grpc.CallOption) (*GetGlobalRateLimitViewsResp, error) {
+	out := new(GetGlobalRateLimitViewsResp)
+	err := c.cc.Invoke(ctx, "/pb.gubernator.AdminV1/GetGlobalRateLimitViews", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *adminV1Client) ResetNamespace(ctx context.Context, in *ResetNamespaceReq, opts ...grpc.CallOption) (*ResetNamespaceResp, error) {
+	out := new(ResetNamespaceResp)
+	err := c.cc.Invoke(ctx, "/pb.gubernator.AdminV1/ResetNamespace", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *adminV1Client) ImportRateLimits(ctx context.Context, in *ImportRateLimitsReq, opts ...grpc.CallOption) (*ImportRateLimitsResp, error) {
+	out := new(ImportRateLimitsResp)
+	err := c.cc.Invoke(ctx, "/pb.gubernator.AdminV1/ImportRateLimits", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// AdminV1Server is the server API for AdminV1 service.
+// All implementations must embed UnimplementedAdminV1Server
+// for forward compatibility
+type AdminV1Server interface {
+	// Resets each of the provided rate limits as if created new on first use. The
+	// rate limits are reset on their owning peers.
+	ResetRateLimits(context.Context, *ResetRateLimitsReq) (*ResetRateLimitsResp, error)
+	// Sets the remaining hits of an existing rate limit on its owning peer. The
+	// remaining hits are clamped to the capacity of the rate limit.
+	SetRemaining(context.Context, *SetRemainingReq) (*SetRemainingResp, error)
+	// Removes a rate limit from the cache and store of its owning peer, such that
+	// the next request for the rate limit creates it new. Deleting a rate limit
+	// which does not exist is not an error.
+	DeleteRateLimit(context.Context, *DeleteRateLimitReq) (*DeleteRateLimitResp, error)
+	// Lists the active rate limits of a namespace across all peers in the local
+	// datacenter, ordered by key.
+	ListRateLimits(context.Context, *ListRateLimitsReq) (*ListRateLimitsResp, error)
+	// Streams the active rate limits of a namespace across all peers in the local
+	// datacenter, one peer at a time and in no particular order. Unlike ListRateLimits
+	// the namespace is never held in memory; the cache of each peer is walked as the
+	// client receives the rate limits, so clients should receive promptly.
+	StreamRateLimits(*StreamRateLimitsReq, AdminV1_StreamRateLimitsServer) error
+	// Reports the view each peer in the local datacenter holds of a GLOBAL rate limit, including
+	// the authoritative view of the owning peer, such that convergence lag between peers can be
+	// diagnosed. Peers which could not be asked are reported with an error.
+	GetGlobalRateLimitViews(context.Context, *GetGlobalRateLimitViewsReq) (*GetGlobalRateLimitViewsResp, error)
+	// Removes every rate limit of a namespace from the cache and store of each peer in the local
+	// datacenter, such that the next request for each rate limit creates it new. Rate limits are
+	// only removed from stores which implement BulkStore, since other stores cannot be scanned.
+	ResetNamespace(context.Context, *ResetNamespaceReq) (*ResetNamespaceResp, error)
+	// Seeds rate limits with known state, IE: when migrating from another rate limiter, such that
+	// users are not reset to the full limit. Each rate limit is written to the cache and store of its
+	// owning peer as provided, replacing any existing state of the same algorithm, without applying
+	// any hits. An existing rate limit of another algorithm is not replaced.
+	ImportRateLimits(context.Context, *ImportRateLimitsReq) (*ImportRateLimitsResp, error)
+	mustEmbedUnimplementedAdminV1Server()
+}
+
+// UnimplementedAdminV1Server must be embedded to have forward compatible implementations.
+type UnimplementedAdminV1Server struct {
+}
+
+func (UnimplementedAdminV1Server) ResetRateLimits(context.Context, *ResetRateLimitsReq) (*ResetRateLimitsResp, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ResetRateLimits not implemented")
+}
+func (UnimplementedAdminV1Server) SetRemaining(context.Context, *SetRemainingReq) (*SetRemainingResp, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SetRemaining not implemented")
+}
+func (UnimplementedAdminV1Server) DeleteRateLimit(context.Context, *DeleteRateLimitReq) (*DeleteRateLimitResp, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DeleteRateLimit not implemented")
+}
+func (UnimplementedAdminV1Server) ListRateLimits(context.Context, *ListRateLimitsReq) (*ListRateLimitsResp, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListRateLimits not implemented")
+}
+func (UnimplementedAdminV1Server) StreamRateLimits(*StreamRateLimitsReq, AdminV1_StreamRateLimitsServer) error {
+	return status.Errorf(codes.Unimplemented, "method StreamRateLimits not implemented")
+}
+func (UnimplementedAdminV1Server) GetGlobalRateLimitViews(context.Context, *GetGlobalRateLimitViewsReq) (*GetGlobalRateLimitViewsResp, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetGlobalRateLimitViews not implemented")
+}
+func (UnimplementedAdminV1Server) ResetNamespace(context.Context, *ResetNamespaceReq) (*ResetNamespaceResp, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ResetNamespace not implemented")
+}
+func (UnimplementedAdminV1Server) ImportRateLimits(context.Context, *ImportRateLimitsReq) (*ImportRateLimitsResp, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ImportRateLimits not implemented")
+}
+func (UnimplementedAdminV1Server) mustEmbedUnimplementedAdminV1Server() {}
+
+// UnsafeAdminV1Server may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to AdminV1Server will
+// result in compilation errors.
+type UnsafeAdminV1Server interface {
+	mustEmbedUnimplementedAdminV1Server()
+}
+
+func RegisterAdminV1Server(s grpc.ServiceRegistrar, srv AdminV1Server) {
+	s.RegisterService(&AdminV1_ServiceDesc, srv)
+}
+
+func _AdminV1_ResetRateLimits_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ResetRateLimitsReq)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AdminV1Server).ResetRateLimits(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/pb.gubernator.AdminV1/ResetRateLimits",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AdminV1Server).ResetRateLimits(ctx, req.(*ResetRateLimitsReq))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AdminV1_SetRemaining_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SetRemainingReq)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AdminV1Server).SetRemaining(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/pb.gubernator.AdminV1/SetRemaining",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AdminV1Server).SetRemaining(ctx, req.(*SetRemainingReq))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AdminV1_DeleteRateLimit_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DeleteRateLimitReq)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AdminV1Server).DeleteRateLimit(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/pb.gubernator.AdminV1/DeleteRateLimit",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AdminV1Server).DeleteRateLimit(ctx, req.(*DeleteRateLimitReq))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AdminV1_ListRateLimits_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListRateLimitsReq)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AdminV1Server).ListRateLimits(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/pb.gubernator.AdminV1/ListRateLimits",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AdminV1Server).ListRateLimits(ctx, req.(*ListRateLimitsReq))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AdminV1_StreamRateLimits_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(StreamRateLimitsReq)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(AdminV1Server).StreamRateLimits(m, &adminV1StreamRateLimitsServer{stream})
+}
+
+type AdminV1_StreamRateLimitsServer interface {
+	Send(*RateLimitItem) error
+	grpc.ServerStream
+}
+
+type adminV1StreamRateLimitsServer struct {
+	grpc.ServerStream
+}
+
+func (x *adminV1StreamRateLimitsServer) Send(m *RateLimitItem) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _AdminV1_GetGlobalRateLimitViews_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetGlobalRateLimitViewsReq)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AdminV1Server).GetGlobalRateLimitViews(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/pb.gubernator.AdminV1/GetGlobalRateLimitViews",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AdminV1Server).GetGlobalRateLimitViews(ctx, req.(*GetGlobalRateLimitViewsReq))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AdminV1_ResetNamespace_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ResetNamespaceReq)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AdminV1Server).ResetNamespace(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/pb.gubernator.AdminV1/ResetNamespace",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AdminV1Server).ResetNamespace(ctx, req.(*ResetNamespaceReq))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AdminV1_ImportRateLimits_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ImportRateLimitsReq)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AdminV1Server).ImportRateLimits(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/pb.gubernator.AdminV1/ImportRateLimits",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AdminV1Server).ImportRateLimits(ctx, req.(*ImportRateLimitsReq))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// AdminV1_ServiceDesc is the grpc.ServiceDesc for AdminV1 service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var AdminV1_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "pb.gubernator.AdminV1",
+	HandlerType: (*AdminV1Server)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "ResetRateLimits",
+			Handler:    _AdminV1_ResetRateLimits_Handler,
+		},
+		{
+			MethodName: "SetRemaining",
+			Handler:    _AdminV1_SetRemaining_Handler,
+		},
+		{
+			MethodName: "DeleteRateLimit",
+			Handler:    _AdminV1_DeleteRateLimit_Handler,
+		},
+		{
+			MethodName: "ListRateLimits",
+			Handler:    _AdminV1_ListRateLimits_Handler,
+		},
+		{
+			MethodName: "GetGlobalRateLimitViews",
+			Handler:    _AdminV1_GetGlobalRateLimitViews_Handler,
+		},
+		{
+			MethodName: "ResetNamespace",
+			Handler:    _AdminV1_ResetNamespace_Handler,
+		},
+		{
+			MethodName: "ImportRateLimits",
+			Handler:    _AdminV1_ImportRateLimits_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "StreamRateLimits",
+			Handler:       _AdminV1_StreamRateLimits_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "admin.proto",
+}
@@ -0,0 +1,1030 @@
+/*
+Copyright 2018-2022 Mailgun Technologies Inc
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gubernator_test
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"testing"
+
+	"github.com/mailgun/gubernator/v2"
+	"github.com/mailgun/gubernator/v2/cluster"
+	"github.com/mailgun/holster/v4/clock"
+	"github.com/mailgun/holster/v4/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+func TestAdminListener(t *testing.T) {
+	conf := gubernator.DaemonConfig{
+		GRPCListenAddress:      "127.0.0.1:9675",
+		HTTPListenAddress:      "127.0.0.1:9676",
+		AdminGRPCListenAddress: "127.0.0.1:9677",
+	}
+
+	d := spawnDaemon(t, conf)
+	defer d.Close()
+
+	req := &gubernator.RateLimitReq{
+		Name:      "test_admin_listener",
+		UniqueKey: "account:1234",
+		Algorithm: gubernator.Algorithm_TOKEN_BUCKET,
+		Duration:  gubernator.Minute,
+		Limit:     10,
+		Hits:      5,
+	}
+
+	client, err := gubernator.DialV1Server(conf.GRPCListenAddress, nil)
+	require.NoError(t, err)
+
+	resp, err := client.GetRateLimits(context.Background(), &gubernator.GetRateLimitsReq{
+		Requests: []*gubernator.RateLimitReq{req},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, int64(5), resp.Responses[0].Remaining)
+
+	// The admin service should be rejected on the data plane
+	dataAdmin, err := gubernator.DialAdminV1Server(conf.GRPCListenAddress, nil)
+	require.NoError(t, err)
+
+	_, err = dataAdmin.ResetRateLimits(context.Background(), &gubernator.ResetRateLimitsReq{
+		Requests: []*gubernator.RateLimitReq{req},
+	})
+	require.Error(t, err)
+	assert.Equal(t, codes.Unimplemented, status.Code(err))
+
+	// The data plane should not be served on the admin listener
+	adminData, err := gubernator.DialV1Server(conf.AdminGRPCListenAddress, nil)
+	require.NoError(t, err)
+
+	_, err = adminData.GetRateLimits(context.Background(), &gubernator.GetRateLimitsReq{
+		Requests: []*gubernator.RateLimitReq{req},
+	})
+	require.Error(t, err)
+	assert.Equal(t, codes.Unimplemented, status.Code(err))
+
+	// The admin service should be served on the admin listener
+	admin, err := gubernator.DialAdminV1Server(conf.AdminGRPCListenAddress, nil)
+	require.NoError(t, err)
+
+	reset, err := admin.ResetRateLimits(context.Background(), &gubernator.ResetRateLimitsReq{
+		Requests: []*gubernator.RateLimitReq{req},
+	})
+	require.NoError(t, err)
+	require.Len(t, reset.Responses, 1)
+	assert.Equal(t, "", reset.Responses[0].Error)
+	assert.Equal(t, int64(10), reset.Responses[0].Remaining)
+
+	// The rate limit should have been reset
+	req.Hits = 1
+	resp, err = client.GetRateLimits(context.Background(), &gubernator.GetRateLimitsReq{
+		Requests: []*gubernator.RateLimitReq{req},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, int64(9), resp.Responses[0].Remaining)
+}
+
+func TestAdminWithoutListener(t *testing.T) {
+	conf := gubernator.DaemonConfig{
+		GRPCListenAddress: "127.0.0.1:9678",
+		HTTPListenAddress: "127.0.0.1:9679",
+	}
+
+	d := spawnDaemon(t, conf)
+	defer d.Close()
+
+	// Without a dedicated admin listener the admin service is served with the data plane
+	admin, err := gubernator.DialAdminV1Server(conf.GRPCListenAddress, nil)
+	require.NoError(t, err)
+
+	reset, err := admin.ResetRateLimits(context.Background(), &gubernator.ResetRateLimitsReq{
+		Requests: []*gubernator.RateLimitReq{
+			{
+				Name:      "test_admin_without_listener",
+				UniqueKey: "account:1234",
+				Algorithm: gubernator.Algorithm_TOKEN_BUCKET,
+				Duration:  gubernator.Minute,
+				Limit:     10,
+			},
+		},
+	})
+	require.NoError(t, err)
+	require.Len(t, reset.Responses, 1)
+	assert.Equal(t, int64(10), reset.Responses[0].Remaining)
+}
+
+func TestAdminPeerMethods(t *testing.T) {
+	conf := gubernator.DaemonConfig{
+		GRPCListenAddress:      "127.0.0.1:9660",
+		HTTPListenAddress:      "127.0.0.1:9661",
+		AdminGRPCListenAddress: "127.0.0.1:9662",
+	}
+
+	d := spawnDaemon(t, conf)
+	defer d.Close()
+
+	ctx := context.Background()
+	client, err := gubernator.DialV1Server(conf.GRPCListenAddress, nil)
+	require.NoError(t, err)
+
+	_, err = client.GetRateLimits(ctx, &gubernator.GetRateLimitsReq{
+		Requests: []*gubernator.RateLimitReq{
+			{
+				Name:      "test_admin_peer_methods",
+				UniqueKey: "account:1234",
+				Algorithm: gubernator.Algorithm_TOKEN_BUCKET,
+				Duration:  gubernator.Minute,
+				Limit:     10,
+				Hits:      1,
+			},
+		},
+	})
+	require.NoError(t, err)
+
+	dataPeers := dialPeersV1(t, conf.GRPCListenAddress)
+	adminPeers := dialPeersV1(t, conf.AdminGRPCListenAddress)
+
+	for _, tt := range []struct {
+		name string
+		call func(gubernator.PeersV1Client) error
+	}{
+		{
+			name: "SetPeerRemaining",
+			call: func(c gubernator.PeersV1Client) error {
+				_, err := c.SetPeerRemaining(ctx, &gubernator.SetRemainingReq{
+					Name:      "test_admin_peer_methods",
+					UniqueKey: "account:1234",
+					Remaining: 5,
+				})
+				return err
+			},
+		},
+		{
+			name: "DeletePeerRateLimit",
+			call: func(c gubernator.PeersV1Client) error {
+				_, err := c.DeletePeerRateLimit(ctx, &gubernator.DeleteRateLimitReq{
+					Name:      "test_admin_peer_methods",
+					UniqueKey: "account:1234",
+				})
+				return err
+			},
+		},
+		{
+			name: "ListPeerRateLimits",
+			call: func(c gubernator.PeersV1Client) error {
+				_, err := c.ListPeerRateLimits(ctx, &gubernator.ListRateLimitsReq{Name: "test_admin_peer_methods"})
+				return err
+			},
+		},
+		{
+			name: "StreamPeerRateLimits",
+			call: func(c gubernator.PeersV1Client) error {
+				stream, err := c.StreamPeerRateLimits(ctx, &gubernator.StreamRateLimitsReq{Name: "test_admin_peer_methods"})
+				if err != nil {
+					return err
+				}
+				for {
+					if _, err := stream.Recv(); err != nil {
+						if err == io.EOF {
+							return nil
+						}
+						return err
+					}
+				}
+			},
+		},
+		{
+			name: "ResetPeerNamespace",
+			call: func(c gubernator.PeersV1Client) error {
+				_, err := c.ResetPeerNamespace(ctx, &gubernator.ResetNamespaceReq{Name: "test_admin_peer_methods"})
+				return err
+			},
+		},
+		{
+			name: "ImportPeerRateLimits",
+			call: func(c gubernator.PeersV1Client) error {
+				_, err := c.ImportPeerRateLimits(ctx, &gubernator.ImportRateLimitsReq{
+					Items: []*gubernator.CacheItemSnapshot{
+						{
+							Name:      "test_admin_peer_methods",
+							UniqueKey: "account:5678",
+							Algorithm: gubernator.Algorithm_LEAKY_BUCKET,
+							Limit:     10,
+							Duration:  gubernator.Minute,
+							Remaining: 4,
+						},
+					},
+				})
+				return err
+			},
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			// The methods which relay admin requests should be refused on the data plane
+			err := tt.call(dataPeers)
+			require.Error(t, err)
+			assert.Equal(t, codes.PermissionDenied, status.Code(err))
+
+			// The admin listener should serve them
+			assert.NoError(t, tt.call(adminPeers))
+		})
+	}
+}
+
+func TestAdminListenerRelay(t *testing.T) {
+	conf1 := gubernator.DaemonConfig{
+		GRPCListenAddress:      "127.0.0.1:9663",
+		HTTPListenAddress:      "127.0.0.1:9664",
+		AdminGRPCListenAddress: "127.0.0.1:9665",
+	}
+	conf2 := gubernator.DaemonConfig{
+		GRPCListenAddress:      "127.0.0.1:9666",
+		HTTPListenAddress:      "127.0.0.1:9667",
+		AdminGRPCListenAddress: "127.0.0.1:9668",
+	}
+
+	d1 := spawnDaemon(t, conf1)
+	defer d1.Close()
+	d2 := spawnDaemon(t, conf2)
+	defer d2.Close()
+
+	peers := []gubernator.PeerInfo{
+		{GRPCAddress: conf1.GRPCListenAddress, AdminAddress: conf1.AdminGRPCListenAddress},
+		{GRPCAddress: conf2.GRPCListenAddress, AdminAddress: conf2.AdminGRPCListenAddress},
+	}
+	d1.SetPeers(peers)
+	d2.SetPeers(peers)
+
+	// Find a rate limit which is owned by the second daemon
+	ctx := context.Background()
+	var key string
+	for i := 0; key == ""; i++ {
+		k := fmt.Sprintf("account:%d", i)
+		peer, err := d1.V1Server.GetPeer(ctx, "test_admin_listener_relay_"+k)
+		require.NoError(t, err)
+		if peer.Info().GRPCAddress == conf2.GRPCListenAddress {
+			key = k
+		}
+	}
+
+	client, err := gubernator.DialV1Server(conf1.GRPCListenAddress, nil)
+	require.NoError(t, err)
+
+	req := &gubernator.RateLimitReq{
+		Name:      "test_admin_listener_relay",
+		UniqueKey: key,
+		Algorithm: gubernator.Algorithm_TOKEN_BUCKET,
+		Duration:  gubernator.Minute,
+		Limit:     10,
+		Hits:      1,
+	}
+	_, err = client.GetRateLimits(ctx, &gubernator.GetRateLimitsReq{Requests: []*gubernator.RateLimitReq{req}})
+	require.NoError(t, err)
+
+	// The first daemon should relay the request to the admin listener of the second daemon
+	admin, err := gubernator.DialAdminV1Server(conf1.AdminGRPCListenAddress, nil)
+	require.NoError(t, err)
+
+	set, err := admin.SetRemaining(ctx, &gubernator.SetRemainingReq{
+		Name:      req.Name,
+		UniqueKey: req.UniqueKey,
+		Remaining: 3,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), set.Response.Remaining)
+
+	resp, err := client.GetRateLimits(ctx, &gubernator.GetRateLimitsReq{Requests: []*gubernator.RateLimitReq{req}})
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), resp.Responses[0].Remaining)
+
+	list, err := admin.ListRateLimits(ctx, &gubernator.ListRateLimitsReq{Name: req.Name})
+	require.NoError(t, err)
+	require.Len(t, list.Items, 1)
+	assert.Equal(t, req.UniqueKey, list.Items[0].UniqueKey)
+
+	stream, err := admin.StreamRateLimits(ctx, &gubernator.StreamRateLimitsReq{Name: req.Name})
+	require.NoError(t, err)
+	item, err := stream.Recv()
+	require.NoError(t, err)
+	assert.Equal(t, req.UniqueKey, item.UniqueKey)
+	_, err = stream.Recv()
+	assert.Equal(t, io.EOF, err)
+
+	del, err := admin.DeleteRateLimit(ctx, &gubernator.DeleteRateLimitReq{
+		Name:      req.Name,
+		UniqueKey: req.UniqueKey,
+	})
+	require.NoError(t, err)
+	assert.True(t, del.Found)
+
+	_, err = client.GetRateLimits(ctx, &gubernator.GetRateLimitsReq{Requests: []*gubernator.RateLimitReq{req}})
+	require.NoError(t, err)
+
+	reset, err := admin.ResetNamespace(ctx, &gubernator.ResetNamespaceReq{Name: req.Name})
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), reset.Cleared)
+
+	imported, err := admin.ImportRateLimits(ctx, &gubernator.ImportRateLimitsReq{
+		Items: []*gubernator.CacheItemSnapshot{
+			{
+				Name:      req.Name,
+				UniqueKey: req.UniqueKey,
+				Algorithm: gubernator.Algorithm_LEAKY_BUCKET,
+				Limit:     10,
+				Duration:  gubernator.Minute,
+				Remaining: 7,
+			},
+		},
+	})
+	require.NoError(t, err)
+	require.Len(t, imported.Responses, 1)
+	assert.Equal(t, "", imported.Responses[0].Error)
+	assert.Equal(t, int64(7), imported.Responses[0].Remaining)
+}
+
+func dialPeersV1(t *testing.T, address string) gubernator.PeersV1Client {
+	t.Helper()
+
+	conn, err := grpc.Dial(address, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+	return gubernator.NewPeersV1Client(conn)
+}
+
+func TestSetRemaining(t *testing.T) {
+	client, err := gubernator.DialV1Server(cluster.GetRandomPeer(cluster.DataCenterNone).GRPCAddress, nil)
+	require.NoError(t, err)
+
+	for _, algorithm := range []gubernator.Algorithm{gubernator.Algorithm_TOKEN_BUCKET, gubernator.Algorithm_LEAKY_BUCKET} {
+		t.Run(algorithm.String(), func(t *testing.T) {
+			req := &gubernator.RateLimitReq{
+				Name:      "test_set_remaining",
+				UniqueKey: "account:" + algorithm.String(),
+				Algorithm: algorithm,
+				Duration:  gubernator.Minute * 60,
+				Limit:     100,
+				Hits:      1,
+			}
+			resp, err := client.GetRateLimits(context.Background(), &gubernator.GetRateLimitsReq{
+				Requests: []*gubernator.RateLimitReq{req},
+			})
+			require.NoError(t, err)
+			assert.Equal(t, int64(99), resp.Responses[0].Remaining)
+
+			// Set the remaining through every peer, so the request is relayed to the owner at least once
+			for i, peer := range localPeers() {
+				admin, err := gubernator.DialAdminV1Server(peer.GRPCAddress, nil)
+				require.NoError(t, err)
+
+				value := int64(50 - i*2)
+				set, err := admin.SetRemaining(context.Background(), &gubernator.SetRemainingReq{
+					Name:      req.Name,
+					UniqueKey: req.UniqueKey,
+					Remaining: value,
+				})
+				require.NoError(t, err)
+				assert.Equal(t, value, set.Response.Remaining)
+				assert.Equal(t, int64(100), set.Response.Limit)
+
+				// Subsequent requests decrement from the new value
+				resp, err = client.GetRateLimits(context.Background(), &gubernator.GetRateLimitsReq{
+					Requests: []*gubernator.RateLimitReq{req},
+				})
+				require.NoError(t, err)
+				assert.Equal(t, gubernator.Status_UNDER_LIMIT, resp.Responses[0].Status)
+				assert.Equal(t, value-1, resp.Responses[0].Remaining)
+			}
+
+			admin, err := gubernator.DialAdminV1Server(cluster.GetRandomPeer(cluster.DataCenterNone).GRPCAddress, nil)
+			require.NoError(t, err)
+
+			// The value is clamped to the capacity of the rate limit
+			set, err := admin.SetRemaining(context.Background(), &gubernator.SetRemainingReq{
+				Name:      req.Name,
+				UniqueKey: req.UniqueKey,
+				Remaining: 1000,
+			})
+			require.NoError(t, err)
+			assert.Equal(t, int64(100), set.Response.Remaining)
+
+			set, err = admin.SetRemaining(context.Background(), &gubernator.SetRemainingReq{
+				Name:      req.Name,
+				UniqueKey: req.UniqueKey,
+				Remaining: -5,
+			})
+			require.NoError(t, err)
+			assert.Equal(t, int64(0), set.Response.Remaining)
+
+			resp, err = client.GetRateLimits(context.Background(), &gubernator.GetRateLimitsReq{
+				Requests: []*gubernator.RateLimitReq{req},
+			})
+			require.NoError(t, err)
+			assert.Equal(t, gubernator.Status_OVER_LIMIT, resp.Responses[0].Status)
+		})
+	}
+
+	t.Run("NotFound", func(t *testing.T) {
+		for _, peer := range localPeers() {
+			admin, err := gubernator.DialAdminV1Server(peer.GRPCAddress, nil)
+			require.NoError(t, err)
+
+			_, err = admin.SetRemaining(context.Background(), &gubernator.SetRemainingReq{
+				Name:      "test_set_remaining",
+				UniqueKey: "account:unknown",
+				Remaining: 10,
+			})
+			require.Error(t, err)
+			assert.Equal(t, codes.NotFound, status.Code(err))
+		}
+	})
+}
+
+func TestListRateLimits(t *testing.T) {
+	client, err := gubernator.DialV1Server(cluster.GetRandomPeer(cluster.DataCenterNone).GRPCAddress, nil)
+	require.NoError(t, err)
+
+	// Enough keys that every peer owns some of them
+	var expected []string
+	for i := 0; i < 20; i++ {
+		key := fmt.Sprintf("account:%02d", i)
+		expected = append(expected, key)
+		algorithm := gubernator.Algorithm_TOKEN_BUCKET
+		if i%2 == 1 {
+			algorithm = gubernator.Algorithm_LEAKY_BUCKET
+		}
+		_, err := client.GetRateLimits(context.Background(), &gubernator.GetRateLimitsReq{
+			Requests: []*gubernator.RateLimitReq{
+				{
+					Name:      "test_list_rate_limits",
+					UniqueKey: key,
+					Algorithm: algorithm,
+					Duration:  gubernator.Minute * 60,
+					Limit:     100,
+					Hits:      int64(i),
+				},
+			},
+		})
+		require.NoError(t, err)
+	}
+
+	// A rate limit in another namespace should not be listed
+	_, err = client.GetRateLimits(context.Background(), &gubernator.GetRateLimitsReq{
+		Requests: []*gubernator.RateLimitReq{
+			{
+				Name:      "test_list_other",
+				UniqueKey: "account:00",
+				Duration:  gubernator.Minute,
+				Limit:     100,
+				Hits:      1,
+			},
+		},
+	})
+	require.NoError(t, err)
+
+	for _, peer := range localPeers() {
+		admin, err := gubernator.DialAdminV1Server(peer.GRPCAddress, nil)
+		require.NoError(t, err)
+
+		// Page through every rate limit in the namespace
+		var keys []string
+		var cursor string
+		for {
+			resp, err := admin.ListRateLimits(context.Background(), &gubernator.ListRateLimitsReq{
+				Name:     "test_list_rate_limits",
+				PageSize: 3,
+				Cursor:   cursor,
+			})
+			require.NoError(t, err)
+			require.LessOrEqual(t, len(resp.Items), 3)
+			for _, item := range resp.Items {
+				keys = append(keys, item.UniqueKey)
+				assert.Equal(t, "test_list_rate_limits", item.Name)
+				assert.Equal(t, int64(100), item.Limit)
+				var i int64
+				_, err := fmt.Sscanf(item.UniqueKey, "account:%d", &i)
+				require.NoError(t, err)
+				assert.Equal(t, 100-i, item.Remaining)
+				assert.NotZero(t, item.ResetTime)
+			}
+			if resp.NextCursor == "" {
+				break
+			}
+			cursor = resp.NextCursor
+		}
+		assert.Equal(t, expected, keys)
+	}
+
+	// Should be available via the HTTP gateway
+	var d *gubernator.Daemon
+	for _, daemon := range cluster.GetDaemons() {
+		if daemon.Config().DataCenter == cluster.DataCenterNone {
+			d = daemon
+			break
+		}
+	}
+	require.NotNil(t, d)
+
+	r, err := http.DefaultClient.Get("http://" + d.Config().HTTPListenAddress +
+		"/v1/admin/ListRateLimits?name=test_list_rate_limits")
+	require.NoError(t, err)
+	defer r.Body.Close()
+	assert.Equal(t, http.StatusOK, r.StatusCode)
+	b, err := ioutil.ReadAll(r.Body)
+	require.NoError(t, err)
+
+	var resp gubernator.ListRateLimitsResp
+	require.NoError(t, protojson.Unmarshal(b, &resp))
+	assert.Len(t, resp.Items, len(expected))
+	assert.Empty(t, resp.NextCursor)
+
+	admin, err := gubernator.DialAdminV1Server(d.Config().GRPCListenAddress, nil)
+	require.NoError(t, err)
+	_, err = admin.ListRateLimits(context.Background(), &gubernator.ListRateLimitsReq{})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+}
+
+func TestListRateLimitsCompressed(t *testing.T) {
+	client, err := gubernator.DialV1Server(cluster.GetRandomPeer(cluster.DataCenterNone).GRPCAddress, nil)
+	require.NoError(t, err)
+
+	// Enough keys that the listing is larger than the minimum compressed size
+	req := &gubernator.GetRateLimitsReq{}
+	for i := 0; i < 200; i++ {
+		req.Requests = append(req.Requests, &gubernator.RateLimitReq{
+			Name:      "test_list_compressed",
+			UniqueKey: fmt.Sprintf("account:%03d", i),
+			Duration:  gubernator.Minute * 60,
+			Limit:     100,
+			Hits:      1,
+		})
+	}
+	_, err = client.GetRateLimits(context.Background(), req)
+	require.NoError(t, err)
+
+	// Disable the transparent decompression of the client
+	httpClient := &http.Client{Transport: &http.Transport{DisableCompression: true}}
+	get := func(path, encoding string) (*http.Response, []byte) {
+		r, err := http.NewRequest(http.MethodGet, "http://"+cluster.GetRandomPeer(cluster.DataCenterNone).HTTPAddress+path, nil)
+		require.NoError(t, err)
+		if encoding != "" {
+			r.Header.Set("Accept-Encoding", encoding)
+		}
+		resp, err := httpClient.Do(r)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		b, err := ioutil.ReadAll(resp.Body)
+		require.NoError(t, err)
+		return resp, b
+	}
+
+	const path = "/v1/admin/ListRateLimits?name=test_list_compressed&page_size=200"
+	resp, plain := get(path, "")
+	assert.Empty(t, resp.Header.Get("Content-Encoding"))
+
+	for _, test := range []struct {
+		Encoding string
+		Reader   func(b []byte) io.Reader
+	}{
+		{
+			Encoding: "gzip",
+			Reader: func(b []byte) io.Reader {
+				r, err := gzip.NewReader(bytes.NewReader(b))
+				require.NoError(t, err)
+				return r
+			},
+		},
+		{
+			Encoding: "deflate",
+			Reader: func(b []byte) io.Reader {
+				return flate.NewReader(bytes.NewReader(b))
+			},
+		},
+	} {
+		t.Run(test.Encoding, func(t *testing.T) {
+			resp, compressed := get(path, test.Encoding)
+			assert.Equal(t, test.Encoding, resp.Header.Get("Content-Encoding"))
+			assert.Less(t, len(compressed), len(plain))
+
+			b, err := ioutil.ReadAll(test.Reader(compressed))
+			require.NoError(t, err)
+			assert.Equal(t, string(plain), string(b))
+
+			var list gubernator.ListRateLimitsResp
+			require.NoError(t, protojson.Unmarshal(b, &list))
+			assert.Len(t, list.Items, 200)
+		})
+	}
+
+	// Responses smaller than the minimum size are not compressed
+	resp, b := get("/v1/HealthCheck", "gzip")
+	assert.Empty(t, resp.Header.Get("Content-Encoding"))
+	assert.Contains(t, string(b), "peer_count")
+}
+
+func TestStreamRateLimits(t *testing.T) {
+	const name = "test_stream_rate_limits"
+	const count = 5000
+
+	client, err := gubernator.DialV1Server(cluster.GetRandomPeer(cluster.DataCenterNone).GRPCAddress, nil)
+	require.NoError(t, err)
+
+	for i := 0; i < count; {
+		req := &gubernator.GetRateLimitsReq{}
+		for ; i < count && len(req.Requests) < 1000; i++ {
+			req.Requests = append(req.Requests, &gubernator.RateLimitReq{
+				Name:      name,
+				UniqueKey: fmt.Sprintf("account:%d", i),
+				Duration:  gubernator.Minute * 60,
+				Limit:     100,
+				Hits:      1,
+			})
+		}
+		resp, err := client.GetRateLimits(context.Background(), req)
+		require.NoError(t, err)
+		for _, rl := range resp.Responses {
+			require.Empty(t, rl.Error)
+		}
+	}
+
+	for _, peer := range localPeers() {
+		admin, err := gubernator.DialAdminV1Server(peer.GRPCAddress, nil)
+		require.NoError(t, err)
+
+		stream, err := admin.StreamRateLimits(context.Background(), &gubernator.StreamRateLimitsReq{Name: name})
+		require.NoError(t, err)
+
+		// Every rate limit is received exactly once
+		keys := make(map[string]struct{})
+		var received int
+		for {
+			item, err := stream.Recv()
+			if err == io.EOF {
+				break
+			}
+			require.NoError(t, err)
+			assert.Equal(t, name, item.Name)
+			assert.Equal(t, int64(99), item.Remaining)
+			keys[item.UniqueKey] = struct{}{}
+			received++
+		}
+		assert.Equal(t, count, received, peer.GRPCAddress)
+		assert.Len(t, keys, count, peer.GRPCAddress)
+	}
+
+	admin, err := gubernator.DialAdminV1Server(localPeers()[0].GRPCAddress, nil)
+	require.NoError(t, err)
+	stream, err := admin.StreamRateLimits(context.Background(), &gubernator.StreamRateLimitsReq{})
+	require.NoError(t, err)
+	_, err = stream.Recv()
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+}
+
+func TestDeleteRateLimit(t *testing.T) {
+	client, err := gubernator.DialV1Server(cluster.GetRandomPeer(cluster.DataCenterNone).GRPCAddress, nil)
+	require.NoError(t, err)
+
+	req := &gubernator.RateLimitReq{
+		Name:      "test_delete_rate_limit",
+		UniqueKey: "account:1234",
+		Algorithm: gubernator.Algorithm_TOKEN_BUCKET,
+		Duration:  gubernator.Minute * 60,
+		Limit:     2,
+		Hits:      2,
+	}
+	resp, err := client.GetRateLimits(context.Background(), &gubernator.GetRateLimitsReq{
+		Requests: []*gubernator.RateLimitReq{req},
+	})
+	require.NoError(t, err)
+	require.Equal(t, int64(0), resp.Responses[0].Remaining)
+
+	// Trip the limit
+	resp, err = client.GetRateLimits(context.Background(), &gubernator.GetRateLimitsReq{
+		Requests: []*gubernator.RateLimitReq{req},
+	})
+	require.NoError(t, err)
+	require.Equal(t, gubernator.Status_OVER_LIMIT, resp.Responses[0].Status)
+
+	// Delete through every peer, so the request is relayed to the owner at least once. Only
+	// the first delete finds the rate limit.
+	for i, peer := range localPeers() {
+		admin, err := gubernator.DialAdminV1Server(peer.GRPCAddress, nil)
+		require.NoError(t, err)
+
+		resp, err := admin.DeleteRateLimit(context.Background(), &gubernator.DeleteRateLimitReq{
+			Name:      req.Name,
+			UniqueKey: req.UniqueKey,
+		})
+		require.NoError(t, err)
+		assert.Equal(t, i == 0, resp.Found)
+	}
+
+	// The next request starts fresh at the full limit
+	req.Hits = 1
+	resp, err = client.GetRateLimits(context.Background(), &gubernator.GetRateLimitsReq{
+		Requests: []*gubernator.RateLimitReq{req},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, gubernator.Status_UNDER_LIMIT, resp.Responses[0].Status)
+	assert.Equal(t, int64(1), resp.Responses[0].Remaining)
+
+	// Should be available via the HTTP gateway
+	var d *gubernator.Daemon
+	for _, daemon := range cluster.GetDaemons() {
+		if daemon.Config().DataCenter == cluster.DataCenterNone {
+			d = daemon
+			break
+		}
+	}
+	require.NotNil(t, d)
+
+	r, err := http.DefaultClient.Post("http://"+d.Config().HTTPListenAddress+"/v1/admin/DeleteRateLimit",
+		"application/json", bytes.NewBufferString(`{"name": "test_delete_rate_limit", "unique_key": "account:1234"}`))
+	require.NoError(t, err)
+	defer r.Body.Close()
+	assert.Equal(t, http.StatusOK, r.StatusCode)
+	b, err := ioutil.ReadAll(r.Body)
+	require.NoError(t, err)
+
+	var del gubernator.DeleteRateLimitResp
+	require.NoError(t, protojson.Unmarshal(b, &del))
+	assert.True(t, del.Found)
+
+	resp, err = client.GetRateLimits(context.Background(), &gubernator.GetRateLimitsReq{
+		Requests: []*gubernator.RateLimitReq{req},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), resp.Responses[0].Remaining)
+}
+
+func TestResetNamespace(t *testing.T) {
+	client, err := gubernator.DialV1Server(cluster.GetRandomPeer(cluster.DataCenterNone).GRPCAddress, nil)
+	require.NoError(t, err)
+
+	// Enough keys that each peer owns some of them
+	const numKeys = 50
+	newReqs := func(name string, hits int64) *gubernator.GetRateLimitsReq {
+		req := &gubernator.GetRateLimitsReq{}
+		for i := 0; i < numKeys; i++ {
+			req.Requests = append(req.Requests, &gubernator.RateLimitReq{
+				Name:      name,
+				UniqueKey: fmt.Sprintf("account:%d", i),
+				Algorithm: gubernator.Algorithm_TOKEN_BUCKET,
+				Duration:  gubernator.Minute * 60,
+				Limit:     10,
+				Hits:      hits,
+			})
+		}
+		return req
+	}
+	assertRemaining := func(name string, remaining int64) {
+		resp, err := client.GetRateLimits(context.Background(), newReqs(name, 0))
+		require.NoError(t, err)
+		for _, rl := range resp.Responses {
+			require.Empty(t, rl.Error)
+			assert.Equal(t, remaining, rl.Remaining)
+		}
+	}
+
+	for _, name := range []string{"test_reset_namespace", "test_keep_namespace"} {
+		_, err = client.GetRateLimits(context.Background(), newReqs(name, 5))
+		require.NoError(t, err)
+		assertRemaining(name, 5)
+	}
+
+	admin, err := gubernator.DialAdminV1Server(cluster.GetRandomPeer(cluster.DataCenterNone).GRPCAddress, nil)
+	require.NoError(t, err)
+
+	resp, err := admin.ResetNamespace(context.Background(), &gubernator.ResetNamespaceReq{Name: "test_reset_namespace"})
+	require.NoError(t, err)
+	assert.Equal(t, int64(numKeys), resp.Cleared)
+
+	// The reset namespace starts fresh while the other namespace is untouched
+	assertRemaining("test_reset_namespace", 10)
+	assertRemaining("test_keep_namespace", 5)
+
+	_, err = admin.ResetNamespace(context.Background(), &gubernator.ResetNamespaceReq{})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "field 'name' cannot be empty")
+}
+
+func TestImportRateLimits(t *testing.T) {
+	const name = "test_import_rate_limits"
+	client, err := gubernator.DialV1Server(cluster.GetRandomPeer(cluster.DataCenterNone).GRPCAddress, nil)
+	require.NoError(t, err)
+	admin, err := gubernator.DialAdminV1Server(cluster.GetRandomPeer(cluster.DataCenterNone).GRPCAddress, nil)
+	require.NoError(t, err)
+
+	// Enough keys that each peer owns some of them
+	const numKeys = 20
+	resetTime := gubernator.MillisecondNow() + gubernator.Second*30
+	req := &gubernator.ImportRateLimitsReq{}
+	for i := 0; i < numKeys; i++ {
+		req.Items = append(req.Items,
+			&gubernator.CacheItemSnapshot{
+				Name:      name,
+				UniqueKey: fmt.Sprintf("token:%d", i),
+				Algorithm: gubernator.Algorithm_TOKEN_BUCKET,
+				Limit:     10,
+				Duration:  gubernator.Minute,
+				Remaining: 3,
+				ResetTime: resetTime,
+			},
+			&gubernator.CacheItemSnapshot{
+				Name:      name,
+				UniqueKey: fmt.Sprintf("leaky:%d", i),
+				Algorithm: gubernator.Algorithm_LEAKY_BUCKET,
+				Limit:     10,
+				Duration:  gubernator.Minute * 60,
+				Remaining: 4,
+			})
+	}
+	resp, err := admin.ImportRateLimits(context.Background(), req)
+	require.NoError(t, err)
+	require.Len(t, resp.Responses, len(req.Items))
+	for _, rl := range resp.Responses {
+		assert.Empty(t, rl.Error)
+	}
+
+	// Hits are applied to the imported state rather than a fresh bucket
+	hits := &gubernator.GetRateLimitsReq{}
+	for _, item := range req.Items {
+		hits.Requests = append(hits.Requests, &gubernator.RateLimitReq{
+			Name:      item.Name,
+			UniqueKey: item.UniqueKey,
+			Algorithm: item.Algorithm,
+			Limit:     item.Limit,
+			Duration:  item.Duration,
+			Hits:      1,
+		})
+	}
+	got, err := client.GetRateLimits(context.Background(), hits)
+	require.NoError(t, err)
+	for i, rl := range got.Responses {
+		require.Empty(t, rl.Error)
+		if req.Items[i].Algorithm == gubernator.Algorithm_TOKEN_BUCKET {
+			assert.Equal(t, int64(2), rl.Remaining)
+			assert.Equal(t, resetTime, rl.ResetTime)
+		} else {
+			assert.Equal(t, int64(3), rl.Remaining)
+		}
+	}
+
+	// Importing a rate limit with a different algorithm than the existing one fails for that item only
+	resp, err = admin.ImportRateLimits(context.Background(), &gubernator.ImportRateLimitsReq{
+		Items: []*gubernator.CacheItemSnapshot{
+			{
+				Name:      name,
+				UniqueKey: "token:0",
+				Algorithm: gubernator.Algorithm_LEAKY_BUCKET,
+				Limit:     10,
+				Duration:  gubernator.Minute,
+				Remaining: 10,
+			},
+			{
+				Name:      name,
+				UniqueKey: "token:1",
+				Algorithm: gubernator.Algorithm_TOKEN_BUCKET,
+				Limit:     10,
+				Duration:  gubernator.Minute,
+				Remaining: 10,
+				ResetTime: resetTime,
+			},
+		},
+	})
+	require.NoError(t, err)
+	assert.Contains(t, resp.Responses[0].Error, "algorithm")
+	assert.Empty(t, resp.Responses[1].Error)
+
+	_, err = admin.ImportRateLimits(context.Background(), &gubernator.ImportRateLimitsReq{
+		Items: []*gubernator.CacheItemSnapshot{{
+			Name:      name,
+			UniqueKey: "token:2",
+			Algorithm: gubernator.Algorithm_TOKEN_BUCKET,
+			Limit:     10,
+			Duration:  gubernator.Minute,
+			Remaining: 11,
+			ResetTime: resetTime,
+		}},
+	})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "'remaining' of")
+}
+
+func TestGetGlobalRateLimitViews(t *testing.T) {
+	const (
+		name = "test_global_rate_limit_views"
+		key  = "account:1234"
+	)
+	owner, err := cluster.DaemonAt(0).V1Server.GetPeer(context.Background(), name+"_"+key)
+	require.NoError(t, err)
+
+	var nonOwner gubernator.PeerInfo
+	for _, peer := range localPeers() {
+		if peer.GRPCAddress != owner.Info().GRPCAddress {
+			nonOwner = peer
+			break
+		}
+	}
+	require.NotEmpty(t, nonOwner.GRPCAddress)
+
+	sendHit := func(address string, hits int64) {
+		client, err := gubernator.DialV1Server(address, nil)
+		require.NoError(t, err)
+		resp, err := client.GetRateLimits(context.Background(), &gubernator.GetRateLimitsReq{
+			Requests: []*gubernator.RateLimitReq{{
+				Name:      name,
+				UniqueKey: key,
+				Algorithm: gubernator.Algorithm_TOKEN_BUCKET,
+				Behavior:  gubernator.Behavior_GLOBAL | gubernator.Behavior_RETURN_TOTAL_HITS,
+				Duration:  gubernator.Minute * 60,
+				Limit:     100,
+				Hits:      hits,
+			}},
+		})
+		require.NoError(t, err)
+		require.Equal(t, "", resp.Responses[0].Error)
+	}
+
+	// Race hits on the owner and a non-owner, such that neither has seen the hits of the
+	// other until the async hits and broadcasts arrive.
+	sendHit(nonOwner.GRPCAddress, 1)
+	sendHit(owner.Info().GRPCAddress, 10)
+
+	admin, err := gubernator.DialAdminV1Server(nonOwner.GRPCAddress, nil)
+	require.NoError(t, err)
+	req := &gubernator.GetGlobalRateLimitViewsReq{Name: name, UniqueKey: key}
+
+	resp, err := admin.GetGlobalRateLimitViews(context.Background(), req)
+	require.NoError(t, err)
+	require.Len(t, resp.Views, len(localPeers()))
+
+	views := make(map[string]*gubernator.GlobalRateLimitView)
+	var owners int
+	for _, view := range resp.Views {
+		assert.Equal(t, "", view.Error)
+		views[view.PeerAddress] = view
+		if view.IsOwner {
+			owners++
+		}
+	}
+	assert.Equal(t, 1, owners)
+	require.True(t, views[owner.Info().GRPCAddress].IsOwner)
+	require.True(t, views[owner.Info().GRPCAddress].Found)
+	require.True(t, views[nonOwner.GRPCAddress].Found)
+	assert.Equal(t, int64(99), views[nonOwner.GRPCAddress].Remaining)
+	assert.NotEqual(t, views[nonOwner.GRPCAddress].Remaining, views[owner.Info().GRPCAddress].Remaining)
+
+	// Every peer converges on the view of the owner once the hits and broadcasts arrive
+	testutil.UntilPass(t, 20, clock.Millisecond*100, func(t testutil.TestingT) {
+		resp, err := admin.GetGlobalRateLimitViews(context.Background(), req)
+		if !assert.NoError(t, err) {
+			return
+		}
+		for _, view := range resp.Views {
+			assert.Equal(t, "", view.Error)
+			assert.True(t, view.Found)
+			assert.Equal(t, int64(89), view.Remaining)
+			assert.Equal(t, int64(11), view.TotalHits)
+		}
+	})
+
+	_, err = admin.GetGlobalRateLimitViews(context.Background(), &gubernator.GetGlobalRateLimitViewsReq{Name: name})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+}
+
+// localPeers returns the peers of the cluster which share the hash ring of DataCenterNone
+func localPeers() []gubernator.PeerInfo {
+	var peers []gubernator.PeerInfo
+	for _, peer := range cluster.GetPeers() {
+		if peer.DataCenter == cluster.DataCenterNone {
+			peers = append(peers, peer)
+		}
+	}
+	return peers
+}
@@ -0,0 +1,128 @@
+/*
+Copyright 2018-2022 Mailgun Technologies Inc
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gubernator
+
+import (
+	"context"
+	"net"
+	"strings"
+	"sync"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	grpcpeer "google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// The number of clients tracked by the admission guard
+const admissionCacheSize = 10_000
+
+// admissionGuard protects the instance from a single client overwhelming it by limiting the number
+// of GetRateLimits() requests each client may make, using a TOKEN_BUCKET rate limit per client address.
+type admissionGuard struct {
+	mutex    sync.Mutex
+	cache    Cache
+	log      FieldLogger
+	limit    int64
+	duration int64
+	localIPs map[string]struct{}
+}
+
+func newAdmissionGuard(conf Config, log FieldLogger) *admissionGuard {
+	g := admissionGuard{
+		cache:    NewLRUCache(admissionCacheSize),
+		log:      log,
+		limit:    conf.AdmissionLimit,
+		duration: conf.AdmissionDuration.Milliseconds(),
+		localIPs: make(map[string]struct{}),
+	}
+	addrs, err := net.InterfaceAddrs()
+	if err != nil {
+		log.WithError(err).Warn("while listing interface addresses; x-forwarded-for is only trusted from loopback")
+	}
+	for _, addr := range addrs {
+		if ipNet, ok := addr.(*net.IPNet); ok {
+			g.localIPs[ipNet.IP.String()] = struct{}{}
+		}
+	}
+	return &g
+}
+
+// admit returns a codes.ResourceExhausted error if the client of the request has exceeded the admission limit
+func (g *admissionGuard) admit(ctx context.Context) error {
+	client := g.clientAddr(ctx)
+	if client == "" {
+		return nil
+	}
+
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+	rl, err := tokenBucket(ctx, g.log, nil, g.cache, &RateLimitReq{
+		Name:      "admission",
+		UniqueKey: client,
+		Hits:      1,
+		Limit:     g.limit,
+		Duration:  g.duration,
+	}, false, storePolicy{}, 0)
+	// Never reject a request because the guard itself failed
+	if err != nil {
+		return nil
+	}
+	if rl.Status == Status_OVER_LIMIT {
+		return status.Errorf(codes.ResourceExhausted,
+			"client '%s' exceeded the limit of '%d' requests per '%dms'", client, g.limit, g.duration)
+	}
+	return nil
+}
+
+// clientAddr returns the IP address of the client which made the request. The `x-forwarded-for` address
+// appended by the HTTP gateway is used for requests from this host, else the address of the connection.
+func (g *admissionGuard) clientAddr(ctx context.Context) string {
+	p, ok := grpcpeer.FromContext(ctx)
+	if !ok {
+		return ""
+	}
+	host, _, err := net.SplitHostPort(p.Addr.String())
+	if err != nil {
+		host = p.Addr.String()
+	}
+
+	if !g.isLocal(host) {
+		return host
+	}
+	md, _ := metadata.FromIncomingContext(ctx)
+	if values := md.Get("x-forwarded-for"); len(values) != 0 {
+		// The last address is the one appended by the gateway, any prior addresses are provided by the client
+		hops := strings.Split(values[len(values)-1], ",")
+		if addr := strings.TrimSpace(hops[len(hops)-1]); addr != "" {
+			return addr
+		}
+	}
+	return host
+}
+
+func (g *admissionGuard) isLocal(host string) bool {
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return false
+	}
+	if ip.IsLoopback() {
+		return true
+	}
+	_, ok := g.localIPs[ip.String()]
+	return ok
+}
@@ -18,8 +18,11 @@ package gubernator
 
 import (
 	"context"
+	"fmt"
+	"math"
+	"time"
 
-	"github.com/mailgun/holster/v4/clock"
+	"github.com/OneOfOne/xxhash"
 	"github.com/mailgun/holster/v4/tracing"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/sirupsen/logrus"
@@ -27,8 +30,17 @@ import (
 	"go.opentelemetry.io/otel/trace"
 )
 
+// The window over which reset times are spread when Behavior_RESET_JITTER is set and no window is requested
+const defaultResetJitter = 1000
+
+// The milliseconds the reset moves on each rejected hit when Behavior_SLIDING_PENALTY is set and no step is requested
+const defaultPenaltyStep = 1000
+
+// The precision to which the remaining of a TOKEN_BUCKET rate limit is tracked when using fractional costs
+const costPrecision = 1e9
+
 // Implements token bucket algorithm for rate limiting. https://en.wikipedia.org/wiki/Token_bucket
-func tokenBucket(ctx context.Context, s Store, c Cache, r *RateLimitReq) (resp *RateLimitResp, err error) {
+func tokenBucket(ctx context.Context, log FieldLogger, s Store, c Cache, r *RateLimitReq, disableMigration bool, policy storePolicy, segment int32) (resp *RateLimitResp, err error) {
 	ctx = tracing.StartScopeDebug(ctx)
 	defer func() {
 		tracing.EndScope(ctx, err)
@@ -37,22 +49,37 @@ func tokenBucket(ctx context.Context, s Store, c Cache, r *RateLimitReq) (resp *
 
 	tokenBucketTimer := prometheus.NewTimer(funcTimeMetric.WithLabelValues("tokenBucket"))
 	defer tokenBucketTimer.ObserveDuration()
+	start := time.Now()
 
 	// Get rate limit from cache.
 	hashKey := r.HashKey()
+	var stale *CacheItem
+	if s != nil {
+		// Must be fetched first, as the cache removes an item it invalidated on GetItem()
+		stale = getStaleItem(c, hashKey, policy)
+	}
 	item, ok := c.GetItem(hashKey)
+	defer observeAlgorithm(Algorithm_TOKEN_BUCKET, ok, start)
 	span.AddEvent("c.GetItem()")
 
 	if s != nil && !ok {
 		// Cache miss.
 		// Check our store for the item.
-		if item, ok = s.Get(ctx, r); ok {
+		if item, ok, err = getStoreItem(ctx, s, r, stale, policy); err != nil {
+			return nil, err
+		}
+		if ok {
 			span.AddEvent("Check store for rate limit")
 			c.Add(item)
 			span.AddEvent("c.Add()")
 		}
 	}
 
+	// Leave the rate limit untouched if the request was answered as timed out meanwhile
+	if err := applyBatchItem(ctx); err != nil {
+		return nil, err
+	}
+
 	// Sanity checks.
 	if ok {
 		if item.Value == nil {
@@ -62,7 +89,11 @@ func tokenBucket(ctx context.Context, s Store, c Cache, r *RateLimitReq) (resp *
 				attribute.String("key", r.UniqueKey),
 				attribute.String("name", r.Name),
 			))
-			logrus.Error(msgPart)
+			log.WithFields(logrus.Fields{
+				"hashKey": hashKey,
+				"key":     r.UniqueKey,
+				"name":    r.Name,
+			}).Error(msgPart)
 			ok = false
 		} else if item.Key != hashKey {
 			msgPart := "tokenBucket: Invalid cache item; key mismatch"
@@ -71,7 +102,11 @@ func tokenBucket(ctx context.Context, s Store, c Cache, r *RateLimitReq) (resp *
 				attribute.String("hashKey", hashKey),
 				attribute.String("name", r.Name),
 			))
-			logrus.Error(msgPart)
+			log.WithFields(logrus.Fields{
+				"itemKey": item.Key,
+				"hashKey": hashKey,
+				"name":    r.Name,
+			}).Error(msgPart)
 			ok = false
 		}
 	}
@@ -90,8 +125,9 @@ func tokenBucket(ctx context.Context, s Store, c Cache, r *RateLimitReq) (resp *
 			}
 			return &RateLimitResp{
 				Status:    Status_UNDER_LIMIT,
+				Algorithm: Algorithm_TOKEN_BUCKET,
 				Limit:     r.Limit,
-				Remaining: r.Limit,
+				Remaining: tokenBucketBurst(r),
 				ResetTime: 0,
 			}, nil
 		}
@@ -103,6 +139,9 @@ func tokenBucket(ctx context.Context, s Store, c Cache, r *RateLimitReq) (resp *
 		// 100 emails and the request will succeed.
 		t, ok := item.Value.(*TokenBucketItem)
 		if !ok {
+			if disableMigration {
+				return nil, algorithmMismatch(log, hashKey, Algorithm_TOKEN_BUCKET)
+			}
 			// Client switched algorithms; perhaps due to a migration?
 			span.AddEvent("Client switched algorithms; perhaps due to a migration?")
 
@@ -114,51 +153,105 @@ func tokenBucket(ctx context.Context, s Store, c Cache, r *RateLimitReq) (resp *
 				span.AddEvent("s.Remove()")
 			}
 
-			return tokenBucketNewItem(ctx, s, c, r)
+			return tokenBucketNewItem(ctx, s, c, r, segment)
+		}
+		updatePriority(c, item, r)
+		updateSchedule(item, r, segment)
+		tokenBucketRewind(t, item, r)
+
+		drip := tokenBucketDrips(r)
+		if drip {
+			tokenBucketDrip(t)
+		} else {
+			tokenBucketRefill(t)
 		}
 
 		// Update the limit if it changed.
 		span.AddEvent("Update the limit if changed")
-		if t.Limit != r.Limit {
-			// Add difference to remaining.
-			t.Remaining += r.Limit - t.Limit
+		if burst := tokenBucketBurst(r); t.Limit != r.Limit || tokenBucketCapacity(t) != burst {
+			// Add difference to remaining. The difference is computed as a float
+			// such that it cannot overflow, and remaining is kept within [0, burst].
+			t.Remaining += float64(burst) - float64(tokenBucketCapacity(t))
 			if t.Remaining < 0 {
 				t.Remaining = 0
 			}
+			if t.Remaining > float64(burst) {
+				t.Remaining = float64(burst)
+			}
 			t.Limit = r.Limit
+			t.Burst = 0
+			if burst > r.Limit {
+				t.Burst = burst
+			}
 		}
 
+		cost := requestCost(r)
 		rl := &RateLimitResp{
 			Status:    t.Status,
+			Algorithm: Algorithm_TOKEN_BUCKET,
 			Limit:     r.Limit,
-			Remaining: t.Remaining,
+			Remaining: tokenBucketRemaining(t.Remaining),
 			ResetTime: item.ExpireAt,
 		}
+		if t.Burst != 0 {
+			// The item outlives the duration, the remaining is next refilled at the end of the duration
+			rl.ResetTime = addMillis(t.CreatedAt, t.Duration)
+		}
+
+		// Reject every hit until the cooldown has passed.
+		if t.OverLimitAt != 0 {
+			if end := addMillis(t.OverLimitAt, r.Penalty); HasBehavior(r.Behavior, Behavior_PENALTY_COOLDOWN) && MillisecondNow() < end {
+				span.AddEvent("Penalty cooldown")
+				rl.Status = Status_OVER_LIMIT
+				rl.Remaining = 0
+				rl.ResetTime = end
+				if cost > 0 {
+					overLimitCounter.Add(1)
+				}
+				return rl, nil
+			}
+			t.OverLimitAt = 0
+		}
 
 		// If the duration config changed, update the new ExpireAt.
 		if t.Duration != r.Duration {
 			span.AddEvent("Duration changed")
-			expire := t.CreatedAt + r.Duration
+			expire := addMillis(t.CreatedAt, r.Duration)
 			if HasBehavior(r.Behavior, Behavior_DURATION_IS_GREGORIAN) {
-				expire, err = GregorianExpiration(clock.Now(), r.Duration)
+				n, err := gregorianNow(r)
+				if err != nil {
+					return nil, err
+				}
+				expire, err = gregorianResetTime(n, r.Duration)
 				if err != nil {
 					return nil, err
 				}
 			}
+			expire = addMillis(expire, resetJitter(r))
 
 			// If our new duration means we are currently expired.
 			now := MillisecondNow()
-			if expire <= now {
-				// Renew item.
-				span.AddEvent("Limit has expired")
-				expire = now + r.Duration
-				t.CreatedAt = now
-				t.Remaining = t.Limit
-			}
+			if expire <= now && cost == 0 {
+				// A status read only observes, it reports the expired window and leaves the rate
+				// limit as it was. The next request with hits renews it.
+				span.AddEvent("Limit has expired, not renewed by a status read")
+				rl.Status = Status_UNDER_LIMIT
+				rl.Remaining = tokenBucketCapacity(t)
+				rl.ResetTime = expire
+			} else {
+				if expire <= now {
+					// Renew item.
+					span.AddEvent("Limit has expired")
+					expire = addMillis(addMillis(now, r.Duration), resetJitter(r))
+					t.CreatedAt = now
+					t.Remaining = float64(tokenBucketCapacity(t))
+					t.TotalHits = 0
+				}
 
-			item.ExpireAt = expire
-			t.Duration = r.Duration
-			rl.ResetTime = expire
+				item.ExpireAt = expire
+				t.Duration = r.Duration
+				rl.ResetTime = expire
+			}
 		}
 
 		if s != nil {
@@ -168,51 +261,325 @@ func tokenBucket(ctx context.Context, s Store, c Cache, r *RateLimitReq) (resp *
 			}()
 		}
 
+		// Runs before the store is told of the change
+		if drip {
+			defer tokenBucketDripExpire(t, item, r, rl)
+		} else if t.Burst != 0 {
+			defer tokenBucketBurstExpire(t, item)
+		}
+
 		// Client is only interested in retrieving the current status or
 		// updating the rate limit config.
-		if r.Hits == 0 {
+		if cost == 0 {
 			span.AddEvent("Return current status, apply no change")
 			return rl, nil
 		}
 
 		// If we are already at the limit.
-		if rl.Remaining == 0 && r.Hits > 0 {
+		if t.Remaining == 0 && cost > 0 {
 			span.AddEvent("Already over the limit")
 			overLimitCounter.Add(1)
 			rl.Status = Status_OVER_LIMIT
 			t.Status = rl.Status
+			if HasBehavior(r.Behavior, Behavior_DRAIN_OVER_LIMIT) {
+				tokenBucketDrain(t, item, r, rl)
+			}
+			tokenBucketSlide(t, item, r, rl)
+			tokenBucketPenalty(t, item, r, rl)
 			return rl, nil
 		}
 
-		// If requested hits takes the remainder.
-		if t.Remaining == r.Hits {
+		// If requested hits takes the remainder. Like the leaky bucket, the hits were accepted and the
+		// reset time is that of the drained bucket, even if the bucket was OVER_LIMIT before tokens were
+		// returned to it.
+		if t.Remaining == cost {
 			span.AddEvent("At the limit")
 			t.Remaining = 0
+			t.TotalHits += acceptedHits(r)
+			t.Status = Status_UNDER_LIMIT
+			rl.Status = Status_UNDER_LIMIT
 			rl.Remaining = 0
 			return rl, nil
 		}
 
 		// If requested is more than available, then return over the limit
 		// without updating the cache.
-		if r.Hits > t.Remaining {
+		if cost > t.Remaining {
 			span.AddEvent("Over the limit")
 			overLimitCounter.Add(1)
 			rl.Status = Status_OVER_LIMIT
+			if HasBehavior(r.Behavior, Behavior_DRAIN_OVER_LIMIT) {
+				// The rejected hits still count; drain the bucket so the limit stays OVER_LIMIT.
+				span.AddEvent("Drain the remaining")
+				t.Status = rl.Status
+				tokenBucketDrain(t, item, r, rl)
+			}
+			tokenBucketSlide(t, item, r, rl)
+			tokenBucketPenalty(t, item, r, rl)
 			return rl, nil
 		}
 
 		span.AddEvent("Under the limit")
-		t.Remaining -= r.Hits
-		rl.Remaining = t.Remaining
+		t.Remaining = subtractCost(t.Remaining, cost)
+		t.TotalHits += acceptedHits(r)
+		t.Status = Status_UNDER_LIMIT
+		rl.Status = Status_UNDER_LIMIT
+		rl.Remaining = tokenBucketRemaining(t.Remaining)
 		return rl, nil
 	}
 
 	// Item is not found in cache or store, create new.
-	return tokenBucketNewItem(ctx, s, c, r)
+	return tokenBucketNewItem(ctx, s, c, r, segment)
+}
+
+// tokenBucketBurst returns the number of tokens a TOKEN_BUCKET may accumulate, which is `burst` if it
+// exceeds the limit, else the limit. Burst is not supported with gregorian durations.
+func tokenBucketBurst(r *RateLimitReq) int64 {
+	if r.Burst <= r.Limit || r.Limit <= 0 || HasBehavior(r.Behavior, Behavior_DURATION_IS_GREGORIAN) {
+		return r.Limit
+	}
+	return r.Burst
+}
+
+// tokenBucketCapacity returns the number of tokens the bucket may accumulate
+func tokenBucketCapacity(t *TokenBucketItem) int64 {
+	if t.Burst != 0 {
+		return t.Burst
+	}
+	return t.Limit
+}
+
+// tokenBucketRewind guards a TOKEN_BUCKET against the clock stepping backwards past the beginning of the
+// window, IE: an NTP adjustment. The window is moved back by the step, rather than lasting for the duration
+// plus the step. A bucket with a burst or which drips is left alone, as Behavior_SLIDING_PENALTY moves
+// its `CreatedAt` into the future on purpose.
+func tokenBucketRewind(t *TokenBucketItem, item *CacheItem, r *RateLimitReq) {
+	now := MillisecondNow()
+	if t.CreatedAt <= now || t.Burst != 0 || tokenBucketDrips(r) {
+		return
+	}
+	step := t.CreatedAt - now
+	t.CreatedAt = now
+	if !HasBehavior(r.Behavior, Behavior_DURATION_IS_GREGORIAN) {
+		item.ExpireAt = addMillis(item.ExpireAt, -step)
+	}
+}
+
+// tokenBucketRefill adds `limit` tokens to a TOKEN_BUCKET with a burst for each duration which has
+// passed since the bucket was last refilled, up to the burst. A bucket without a burst is instead
+// replaced by a new item once the duration expires.
+func tokenBucketRefill(t *TokenBucketItem) {
+	if t.Burst == 0 || t.Duration <= 0 {
+		return
+	}
+	now := MillisecondNow()
+	if now < t.CreatedAt+t.Duration {
+		return
+	}
+	windows := (now - t.CreatedAt) / t.Duration
+	t.Remaining = math.Min(float64(t.Burst), t.Remaining+float64(windows)*float64(t.Limit))
+	t.CreatedAt = addMillis(t.CreatedAt, mulMillis(windows, t.Duration))
+	t.Status = Status_UNDER_LIMIT
+	t.TotalHits = 0
+}
+
+// tokenBucketBurstExpire keeps a TOKEN_BUCKET with a burst in the cache until it has refilled to the
+// burst, after which the item may expire since a new item starts with the full burst.
+func tokenBucketBurstExpire(t *TokenBucketItem, item *CacheItem) {
+	windows := int64(math.Ceil((float64(t.Burst) - t.Remaining) / float64(t.Limit)))
+	if windows < 1 {
+		windows = 1
+	}
+	if expire := addMillis(t.CreatedAt, mulMillis(windows, t.Duration)); expire > item.ExpireAt {
+		item.ExpireAt = expire
+	}
+}
+
+// tokenBucketDrips returns true if the TOKEN_BUCKET is refilled continuously as requested by
+// Behavior_TOKEN_DRIP. Not supported with gregorian durations.
+func tokenBucketDrips(r *RateLimitReq) bool {
+	return HasBehavior(r.Behavior, Behavior_TOKEN_DRIP) && r.Limit > 0 && r.Duration > 0 &&
+		!HasBehavior(r.Behavior, Behavior_DURATION_IS_GREGORIAN)
+}
+
+// tokenBucketDrip adds the tokens which dripped into a TOKEN_BUCKET since it was last updated at a rate
+// of one token per `duration / limit`, up to the capacity of the bucket. Partial tokens are kept, such
+// that the whole tokens reported are the whole intervals which have passed. `CreatedAt` is the time of
+// the last drip.
+func tokenBucketDrip(t *TokenBucketItem) {
+	now := MillisecondNow()
+	if t.Limit <= 0 || t.Duration <= 0 || now <= t.CreatedAt {
+		return
+	}
+	capacity := float64(tokenBucketCapacity(t))
+	dripped := float64(now-t.CreatedAt) * float64(t.Limit) / float64(t.Duration)
+	t.Remaining = math.Min(capacity, t.Remaining+dripped)
+	t.CreatedAt = now
+	if t.Remaining >= 1 {
+		t.Status = Status_UNDER_LIMIT
+	}
+	if t.Remaining == capacity {
+		t.TotalHits = 0
+	}
+}
+
+// tokenBucketDripExpire expires a TOKEN_BUCKET with Behavior_TOKEN_DRIP once it has dripped full, since
+// a new item starts full, and reports that time as the reset time unless a cooldown is in effect.
+func tokenBucketDripExpire(t *TokenBucketItem, item *CacheItem, r *RateLimitReq, rl *RateLimitResp) {
+	full := t.CreatedAt
+	if missing := float64(tokenBucketCapacity(t)) - t.Remaining; missing > 0 {
+		full = addMillis(full, floatMillis(math.Ceil(missing*float64(t.Duration)/float64(t.Limit))))
+	}
+	item.ExpireAt = full
+
+	if t.OverLimitAt != 0 && HasBehavior(r.Behavior, Behavior_PENALTY_COOLDOWN) {
+		if end := addMillis(t.OverLimitAt, r.Penalty); end > item.ExpireAt {
+			item.ExpireAt = end
+		}
+		return
+	}
+	rl.ResetTime = full
+}
+
+// resetJitter returns the delay added to the reset time of the rate limit when Behavior_RESET_JITTER is set.
+// The delay is derived from the hash key, such that every request for the rate limit agrees on the reset time.
+func resetJitter(r *RateLimitReq) int64 {
+	if !HasBehavior(r.Behavior, Behavior_RESET_JITTER) {
+		return 0
+	}
+	window := r.ResetJitter
+	if window <= 0 {
+		window = defaultResetJitter
+	}
+	return int64(xxhash.ChecksumString64(r.HashKey()) % uint64(window))
+}
+
+// addMillis returns `t + d`, saturated at the bounds of an int64 rather than overflowing, such that
+// the reset times computed from very large durations and penalties remain monotonic.
+func addMillis(t, d int64) int64 {
+	if d > 0 && t > math.MaxInt64-d {
+		return math.MaxInt64
+	}
+	if d < 0 && t < math.MinInt64-d {
+		return math.MinInt64
+	}
+	return t + d
+}
+
+// elapsedMillis returns the milliseconds from `since` until `now`, or zero if the clock stepped backwards
+// past `since`, IE: an NTP adjustment, such that a step of the clock never takes hits from a bucket.
+func elapsedMillis(now, since int64) int64 {
+	if now < since {
+		return 0
+	}
+	return now - since
+}
+
+// mulMillis returns `n * d` for non-negative `n` and `d`, saturated at math.MaxInt64
+func mulMillis(n, d int64) int64 {
+	if n != 0 && d > math.MaxInt64/n {
+		return math.MaxInt64
+	}
+	return n * d
+}
+
+// floatMillis converts the milliseconds to an int64, saturated at math.MaxInt64
+func floatMillis(f float64) int64 {
+	if f >= math.MaxInt64 {
+		return math.MaxInt64
+	}
+	return int64(f)
+}
+
+// requestCost returns the amount a request consumes from a TOKEN_BUCKET rate limit, which
+// is `Cost` if provided, else `Hits`.
+func requestCost(r *RateLimitReq) float64 {
+	if r.Cost != 0 {
+		return r.Cost
+	}
+	return float64(r.Hits)
+}
+
+// aggregateHits adds the hits and cost of `src` to `dst`
+func aggregateHits(dst, src *RateLimitReq) {
+	if dst.Cost != 0 || src.Cost != 0 {
+		dst.Cost = requestCost(dst) + requestCost(src)
+	}
+	dst.Hits += src.Hits
+}
+
+// subtractCost subtracts the cost from the remaining, rounding away the float error accrued by repeated
+// fractional costs, such that ten costs of 0.1 consume exactly 1 token.
+func subtractCost(remaining, cost float64) float64 {
+	return math.Round((remaining-cost)*costPrecision) / costPrecision
+}
+
+// tokenBucketRemaining returns the whole number of tokens remaining, as reported to clients
+func tokenBucketRemaining(remaining float64) int64 {
+	if remaining < 0 {
+		return 0
+	}
+	return int64(math.Floor(remaining))
+}
+
+// Called by tokenBucket() when a hit is rejected and Behavior_DRAIN_OVER_LIMIT is set. Drives
+// the remaining to zero and, unless the duration is gregorian, restarts the duration such that
+// the rate limit remains OVER_LIMIT until no hits are received for the full duration.
+func tokenBucketDrain(t *TokenBucketItem, item *CacheItem, r *RateLimitReq, rl *RateLimitResp) {
+	t.Remaining = 0
+	rl.Remaining = 0
+
+	if HasBehavior(r.Behavior, Behavior_DURATION_IS_GREGORIAN) {
+		return
+	}
+	now := MillisecondNow()
+	t.CreatedAt = now
+	item.ExpireAt = addMillis(addMillis(now, r.Duration), resetJitter(r))
+	rl.ResetTime = item.ExpireAt
+}
+
+// Called by tokenBucket() when a hit is rejected. If Behavior_SLIDING_PENALTY is set, moves the reset of the
+// rate limit `penalty_step` milliseconds further away. Buckets with a burst or Behavior_TOKEN_DRIP are
+// replenished from `CreatedAt`, which is moved instead of the expiry of the item.
+func tokenBucketSlide(t *TokenBucketItem, item *CacheItem, r *RateLimitReq, rl *RateLimitResp) {
+	if !HasBehavior(r.Behavior, Behavior_SLIDING_PENALTY) || HasBehavior(r.Behavior, Behavior_DURATION_IS_GREGORIAN) {
+		return
+	}
+	step := r.PenaltyStep
+	if step <= 0 {
+		step = defaultPenaltyStep
+	}
+
+	switch {
+	case tokenBucketDrips(r):
+		// The reset time is reported by tokenBucketDripExpire()
+		t.CreatedAt = addMillis(t.CreatedAt, step)
+	case t.Burst != 0:
+		t.CreatedAt = addMillis(t.CreatedAt, step)
+		rl.ResetTime = addMillis(t.CreatedAt, t.Duration)
+	default:
+		item.ExpireAt = addMillis(item.ExpireAt, step)
+		rl.ResetTime = item.ExpireAt
+	}
+}
+
+// Called by tokenBucket() when a hit is rejected. If Behavior_PENALTY_COOLDOWN is set, begins the
+// cooldown and ensures the item remains in the cache until the cooldown ends.
+func tokenBucketPenalty(t *TokenBucketItem, item *CacheItem, r *RateLimitReq, rl *RateLimitResp) {
+	if !HasBehavior(r.Behavior, Behavior_PENALTY_COOLDOWN) {
+		return
+	}
+	t.OverLimitAt = MillisecondNow()
+	end := addMillis(t.OverLimitAt, r.Penalty)
+	if end > item.ExpireAt {
+		item.ExpireAt = end
+	}
+	rl.Remaining = 0
+	rl.ResetTime = end
 }
 
 // Called by tokenBucket() when adding a new item in the store.
-func tokenBucketNewItem(ctx context.Context, s Store, c Cache, r *RateLimitReq) (resp *RateLimitResp, err error) {
+func tokenBucketNewItem(ctx context.Context, s Store, c Cache, r *RateLimitReq, segment int32) (resp *RateLimitResp, err error) {
 	ctx = tracing.StartScopeDebug(ctx)
 	defer func() {
 		tracing.EndScope(ctx, err)
@@ -220,44 +587,75 @@ func tokenBucketNewItem(ctx context.Context, s Store, c Cache, r *RateLimitReq)
 	span := trace.SpanFromContext(ctx)
 
 	now := MillisecondNow()
-	expire := now + r.Duration
+	expire := addMillis(now, r.Duration)
+
+	// Add a new rate limit to the cache.
+	span.AddEvent("Add a new rate limit to the cache")
+	if HasBehavior(r.Behavior, Behavior_DURATION_IS_GREGORIAN) {
+		n, err := gregorianNow(r)
+		if err != nil {
+			return nil, err
+		}
+		expire, err = gregorianResetTime(n, r.Duration)
+		if err != nil {
+			return nil, err
+		}
+	}
+	expire = addMillis(expire, resetJitter(r))
 
+	cost := requestCost(r)
+	burst := tokenBucketBurst(r)
 	t := &TokenBucketItem{
 		Limit:     r.Limit,
 		Duration:  r.Duration,
-		Remaining: r.Limit - r.Hits,
+		Remaining: subtractCost(float64(burst), cost),
 		CreatedAt: now,
+		TotalHits: acceptedHits(r),
+	}
+	if burst > r.Limit {
+		t.Burst = burst
 	}
 	item := &CacheItem{
 		Algorithm: Algorithm_TOKEN_BUCKET,
 		Key:       r.HashKey(),
 		Value:     t,
 		ExpireAt:  expire,
+		Priority:  r.Priority,
 	}
-
-	// Add a new rate limit to the cache.
-	span.AddEvent("Add a new rate limit to the cache")
-	if HasBehavior(r.Behavior, Behavior_DURATION_IS_GREGORIAN) {
-		expire, err = GregorianExpiration(clock.Now(), r.Duration)
-		if err != nil {
-			return nil, err
-		}
-	}
+	item.ScheduleSegment = segment
 
 	rl := &RateLimitResp{
 		Status:    Status_UNDER_LIMIT,
+		Algorithm: Algorithm_TOKEN_BUCKET,
 		Limit:     r.Limit,
-		Remaining: t.Remaining,
+		Remaining: tokenBucketRemaining(t.Remaining),
 		ResetTime: expire,
 	}
 
-	// Client could be requesting that we always return OVER_LIMIT.
-	if r.Hits > r.Limit {
+	// Client could be requesting that we always return OVER_LIMIT. Like the leaky bucket, report
+	// nothing remaining, but keep the bucket full such that a request within the limit succeeds.
+	if cost > float64(burst) && HasBehavior(r.Behavior, Behavior_FIRST_HIT_FREE) {
+		span.AddEvent("First hit free")
+		rl.Remaining = 0
+		t.Remaining = 0
+	} else if cost > float64(burst) {
 		span.AddEvent("Over the limit")
 		overLimitCounter.Add(1)
 		rl.Status = Status_OVER_LIMIT
-		rl.Remaining = r.Limit
-		t.Remaining = r.Limit
+		rl.Remaining = 0
+		t.Remaining = float64(burst)
+		t.TotalHits = 0
+		if HasBehavior(r.Behavior, Behavior_DRAIN_OVER_LIMIT) {
+			t.Remaining = 0
+			t.Status = Status_OVER_LIMIT
+		}
+		tokenBucketSlide(t, item, r, rl)
+		tokenBucketPenalty(t, item, r, rl)
+	}
+	if tokenBucketDrips(r) {
+		tokenBucketDripExpire(t, item, r, rl)
+	} else if t.Burst != 0 {
+		tokenBucketBurstExpire(t, item)
 	}
 
 	c.Add(item)
@@ -272,7 +670,7 @@ func tokenBucketNewItem(ctx context.Context, s Store, c Cache, r *RateLimitReq)
 }
 
 // Implements leaky bucket algorithm for rate limiting https://en.wikipedia.org/wiki/Leaky_bucket
-func leakyBucket(ctx context.Context, s Store, c Cache, r *RateLimitReq) (resp *RateLimitResp, err error) {
+func leakyBucket(ctx context.Context, log FieldLogger, s Store, c Cache, r *RateLimitReq, disableMigration bool, policy storePolicy, segment int32) (resp *RateLimitResp, err error) {
 	ctx = tracing.StartScopeDebug(ctx)
 	defer func() {
 		tracing.EndScope(ctx, err)
@@ -281,6 +679,7 @@ func leakyBucket(ctx context.Context, s Store, c Cache, r *RateLimitReq) (resp *
 
 	leakyBucketTimer := prometheus.NewTimer(funcTimeMetric.WithLabelValues("V1Instance.getRateLimit_leakyBucket"))
 	defer leakyBucketTimer.ObserveDuration()
+	start := time.Now()
 
 	if r.Burst == 0 {
 		r.Burst = r.Limit
@@ -290,19 +689,33 @@ func leakyBucket(ctx context.Context, s Store, c Cache, r *RateLimitReq) (resp *
 
 	// Get rate limit from cache.
 	hashKey := r.HashKey()
+	var stale *CacheItem
+	if s != nil {
+		// Must be fetched first, as the cache removes an item it invalidated on GetItem()
+		stale = getStaleItem(c, hashKey, policy)
+	}
 	item, ok := c.GetItem(hashKey)
+	defer observeAlgorithm(Algorithm_LEAKY_BUCKET, ok, start)
 	span.AddEvent("c.GetItem()")
 
 	if s != nil && !ok {
 		// Cache miss.
 		// Check our store for the item.
-		if item, ok = s.Get(ctx, r); ok {
+		if item, ok, err = getStoreItem(ctx, s, r, stale, policy); err != nil {
+			return nil, err
+		}
+		if ok {
 			span.AddEvent("Check store for rate limit")
 			c.Add(item)
 			span.AddEvent("c.Add()")
 		}
 	}
 
+	// Leave the rate limit untouched if the request was answered as timed out meanwhile
+	if err := applyBatchItem(ctx); err != nil {
+		return nil, err
+	}
+
 	// Sanity checks.
 	if ok {
 		if item.Value == nil {
@@ -312,7 +725,11 @@ func leakyBucket(ctx context.Context, s Store, c Cache, r *RateLimitReq) (resp *
 				attribute.String("key", r.UniqueKey),
 				attribute.String("name", r.Name),
 			))
-			logrus.Error(msgPart)
+			log.WithFields(logrus.Fields{
+				"hashKey": hashKey,
+				"key":     r.UniqueKey,
+				"name":    r.Name,
+			}).Error(msgPart)
 			ok = false
 		} else if item.Key != hashKey {
 			msgPart := "leakyBucket: Invalid cache item; key mismatch"
@@ -321,7 +738,11 @@ func leakyBucket(ctx context.Context, s Store, c Cache, r *RateLimitReq) (resp *
 				attribute.String("hashKey", hashKey),
 				attribute.String("name", r.Name),
 			))
-			logrus.Error(msgPart)
+			log.WithFields(logrus.Fields{
+				"itemKey": item.Key,
+				"hashKey": hashKey,
+				"name":    r.Name,
+			}).Error(msgPart)
 			ok = false
 		}
 	}
@@ -332,6 +753,9 @@ func leakyBucket(ctx context.Context, s Store, c Cache, r *RateLimitReq) (resp *
 
 		b, ok := item.Value.(*LeakyBucketItem)
 		if !ok {
+			if disableMigration {
+				return nil, algorithmMismatch(log, hashKey, Algorithm_LEAKY_BUCKET)
+			}
 			// Client switched algorithms; perhaps due to a migration?
 			c.Remove(hashKey)
 			span.AddEvent("c.Remove()")
@@ -341,8 +765,10 @@ func leakyBucket(ctx context.Context, s Store, c Cache, r *RateLimitReq) (resp *
 				span.AddEvent("s.Remove()")
 			}
 
-			return leakyBucketNewItem(ctx, s, c, r)
+			return leakyBucketNewItem(ctx, s, c, r, segment)
 		}
+		updatePriority(c, item, r)
+		updateSchedule(item, r, segment)
 
 		if HasBehavior(r.Behavior, Behavior_RESET_REMAINING) {
 			b.Remaining = float64(r.Burst)
@@ -360,21 +786,26 @@ func leakyBucket(ctx context.Context, s Store, c Cache, r *RateLimitReq) (resp *
 		b.Duration = r.Duration
 
 		duration := r.Duration
+		period := r.Duration
 		rate := float64(duration) / float64(r.Limit)
 
 		if HasBehavior(r.Behavior, Behavior_DURATION_IS_GREGORIAN) {
-			d, err := GregorianDuration(clock.Now(), r.Duration)
+			n, err := gregorianNow(r)
+			if err != nil {
+				return nil, err
+			}
+			d, err := GregorianDuration(n, r.Duration)
 			if err != nil {
 				return nil, err
 			}
-			n := clock.Now()
-			expire, err := GregorianExpiration(n, r.Duration)
+			expire, err := gregorianResetTime(n, r.Duration)
 			if err != nil {
 				return nil, err
 			}
 
 			// Calculate the rate using the entire duration of the gregorian interval
 			// IE: Minute = 60,000 milliseconds, etc.. etc..
+			period = d
 			rate = float64(d) / float64(r.Limit)
 			// Update the duration to be the end of the gregorian interval
 			duration = expire - (n.UnixNano() / 1000000)
@@ -384,11 +815,19 @@ func leakyBucket(ctx context.Context, s Store, c Cache, r *RateLimitReq) (resp *
 			c.UpdateExpiration(r.HashKey(), now+duration)
 		}
 
-		// Calculate how much leaked out of the bucket since the last time we leaked a hit
-		elapsed := now - b.UpdatedAt
-		leak := float64(elapsed) / rate
+		// Calculate how much leaked out of the bucket since the last time we leaked a hit. Should the clock
+		// have stepped backwards, leak from now on rather than waiting for the clock to catch up.
+		elapsed := elapsedMillis(now, b.UpdatedAt)
+		if b.UpdatedAt > now {
+			b.UpdatedAt = now
+		}
+		// NOTE: Avoid dividing by `rate` here, as the float rounding of `rate` could cause a hit which arrives
+		// exactly when a leak is due to be counted against the previous leak interval.
+		leak := leakyBucketLeak(r.LeakProfile, b.Remaining, elapsed, r.Limit, period)
 
-		if int64(leak) > 0 {
+		// The point on the QUADRATIC curve is kept by the remaining, as such a partial leak is applied
+		// rather than waiting for a whole hit to leak
+		if int64(leak) > 0 || (r.LeakProfile == LeakProfile_QUADRATIC && leak > 0) {
 			b.Remaining += leak
 			b.UpdatedAt = now
 		}
@@ -396,12 +835,17 @@ func leakyBucket(ctx context.Context, s Store, c Cache, r *RateLimitReq) (resp *
 		if int64(b.Remaining) > b.Burst {
 			b.Remaining = float64(b.Burst)
 		}
+		// Every accepted hit has leaked out of the bucket
+		if int64(b.Remaining) >= b.Burst {
+			b.TotalHits = 0
+		}
 
 		rl := &RateLimitResp{
+			Algorithm: Algorithm_LEAKY_BUCKET,
 			Limit:     b.Limit,
 			Remaining: int64(b.Remaining),
 			Status:    Status_UNDER_LIMIT,
-			ResetTime: now + (b.Limit-int64(b.Remaining))*int64(rate),
+			ResetTime: leakyBucketResetTime(now, b.Limit, int64(b.Remaining), rate, r.LeakProfile),
 		}
 
 		// TODO: Feature missing: check for Duration change between item/request.
@@ -417,22 +861,40 @@ func leakyBucket(ctx context.Context, s Store, c Cache, r *RateLimitReq) (resp *
 		if int64(b.Remaining) == 0 && r.Hits > 0 {
 			overLimitCounter.Add(1)
 			rl.Status = Status_OVER_LIMIT
+			if HasBehavior(r.Behavior, Behavior_PARTIAL_CONSUME) {
+				rl.OverHits = r.Hits
+			}
 			return rl, nil
 		}
 
 		// If requested hits takes the remainder
 		if int64(b.Remaining) == r.Hits {
 			b.Remaining -= float64(r.Hits)
+			b.TotalHits += acceptedHits(r)
 			rl.Remaining = 0
-			rl.ResetTime = now + (rl.Limit-rl.Remaining)*int64(rate)
+			rl.ResetTime = leakyBucketResetTime(now, rl.Limit, rl.Remaining, rate, r.LeakProfile)
 			return rl, nil
 		}
 
 		// If requested is more than available, then return over the limit
-		// without updating the bucket.
+		// without updating the bucket, unless asked to drain the bucket.
 		if r.Hits > int64(b.Remaining) {
 			overLimitCounter.Add(1)
 			rl.Status = Status_OVER_LIMIT
+			switch {
+			case HasBehavior(r.Behavior, Behavior_PARTIAL_CONSUME):
+				// Consume the whole hits which remain, keeping the partial leak toward the next hit
+				consumed := int64(b.Remaining)
+				b.Remaining -= float64(consumed)
+				b.TotalHits += consumed
+				rl.OverHits = r.Hits - consumed
+			case HasBehavior(r.Behavior, Behavior_DRAIN_OVER_LIMIT):
+				b.Remaining = 0
+			default:
+				return rl, nil
+			}
+			rl.Remaining = 0
+			rl.ResetTime = leakyBucketResetTime(now, rl.Limit, 0, rate, r.LeakProfile)
 			return rl, nil
 		}
 
@@ -442,16 +904,56 @@ func leakyBucket(ctx context.Context, s Store, c Cache, r *RateLimitReq) (resp *
 		}
 
 		b.Remaining -= float64(r.Hits)
+		b.TotalHits += acceptedHits(r)
 		rl.Remaining = int64(b.Remaining)
-		rl.ResetTime = now + (rl.Limit-rl.Remaining)*int64(rate)
+		rl.ResetTime = leakyBucketResetTime(now, rl.Limit, rl.Remaining, rate, r.LeakProfile)
 		return rl, nil
 	}
 
-	return leakyBucketNewItem(ctx, s, c, r)
+	return leakyBucketNewItem(ctx, s, c, r, segment)
+}
+
+// leakyBucketLeak returns the hits which leak out of a LEAKY_BUCKET rate limit with `remaining` hits in
+// `elapsed` milliseconds, where `limit` hits leak in `period` milliseconds along the curve of the profile,
+// see LeakProfile.
+func leakyBucketLeak(profile LeakProfile, remaining float64, elapsed, limit, period int64) float64 {
+	linear := float64(elapsed) * float64(limit) / float64(period)
+	if profile != LeakProfile_QUADRATIC || remaining >= float64(limit) {
+		return linear
+	}
+
+	// The bucket is at the point of the curve `remaining = limit * (t / period)^2` where `t` is the time
+	// since it was last full. Since the point depends on `remaining` alone, the leak is the same whether
+	// the elapsed time is leaked at once or a little at a time.
+	p := float64(period)
+	t := p*math.Sqrt(math.Max(remaining, 0)/float64(limit)) + float64(elapsed)
+	if t > p {
+		// Past the end of the curve a `burst` beyond the limit leaks at the LINEAR rate
+		return float64(limit) - remaining + (t-p)*float64(limit)/p
+	}
+	x := t / p
+	return float64(limit)*x*x - remaining
+}
+
+// leakyBucketResetTime returns when a LEAKY_BUCKET rate limit with `remaining` hits will have leaked back to the
+// limit. The time is computed from the fractional `rate` in milliseconds per hit, truncating only the result.
+func leakyBucketResetTime(now, limit, remaining int64, rate float64, profile LeakProfile) int64 {
+	if profile == LeakProfile_QUADRATIC && limit > 0 {
+		if remaining >= limit {
+			return now
+		}
+		if remaining < 0 {
+			remaining = 0
+		}
+		// The bucket is `period * sqrt(remaining / limit)` along the curve and reaches `limit` at `period`
+		period := rate * float64(limit)
+		return now + int64(period*(1-math.Sqrt(float64(remaining)/float64(limit))))
+	}
+	return now + int64(float64(limit-remaining)*rate)
 }
 
 // Called by leakyBucket() when adding a new item in the store.
-func leakyBucketNewItem(ctx context.Context, s Store, c Cache, r *RateLimitReq) (resp *RateLimitResp, err error) {
+func leakyBucketNewItem(ctx context.Context, s Store, c Cache, r *RateLimitReq, segment int32) (resp *RateLimitResp, err error) {
 	ctx = tracing.StartScopeDebug(ctx)
 	defer func() {
 		tracing.EndScope(ctx, err)
@@ -462,11 +964,20 @@ func leakyBucketNewItem(ctx context.Context, s Store, c Cache, r *RateLimitReq)
 	duration := r.Duration
 	rate := float64(duration) / float64(r.Limit)
 	if HasBehavior(r.Behavior, Behavior_DURATION_IS_GREGORIAN) {
-		n := clock.Now()
-		expire, err := GregorianExpiration(n, r.Duration)
+		n, err := gregorianNow(r)
+		if err != nil {
+			return nil, err
+		}
+		d, err := GregorianDuration(n, r.Duration)
 		if err != nil {
 			return nil, err
 		}
+		expire, err := gregorianResetTime(n, r.Duration)
+		if err != nil {
+			return nil, err
+		}
+		// Calculate the rate using the entire duration of the gregorian interval, as leakyBucket() does
+		rate = float64(d) / float64(r.Limit)
 		// Set the initial duration as the remainder of time until
 		// the end of the gregorian interval.
 		duration = expire - (n.UnixNano() / 1000000)
@@ -479,30 +990,43 @@ func leakyBucketNewItem(ctx context.Context, s Store, c Cache, r *RateLimitReq)
 		Duration:  duration,
 		UpdatedAt: now,
 		Burst:     r.Burst,
+		TotalHits: acceptedHits(r),
 	}
 
 	rl := RateLimitResp{
+		Algorithm: Algorithm_LEAKY_BUCKET,
 		Status:    Status_UNDER_LIMIT,
 		Limit:     b.Limit,
 		Remaining: r.Burst - r.Hits,
-		ResetTime: now + (b.Limit-(r.Burst-r.Hits))*int64(rate),
+		ResetTime: leakyBucketResetTime(now, b.Limit, r.Burst-r.Hits, rate, r.LeakProfile),
 	}
 
 	// Client could be requesting that we start with the bucket OVER_LIMIT
-	if r.Hits > r.Burst {
+	if r.Hits > r.Burst && HasBehavior(r.Behavior, Behavior_FIRST_HIT_FREE) {
+		rl.Remaining = 0
+		rl.ResetTime = leakyBucketResetTime(now, rl.Limit, rl.Remaining, rate, r.LeakProfile)
+		b.Remaining = 0
+	} else if r.Hits > r.Burst {
 		overLimitCounter.Add(1)
 		rl.Status = Status_OVER_LIMIT
 		rl.Remaining = 0
-		rl.ResetTime = now + (rl.Limit-rl.Remaining)*int64(rate)
+		rl.ResetTime = leakyBucketResetTime(now, rl.Limit, rl.Remaining, rate, r.LeakProfile)
 		b.Remaining = 0
+		b.TotalHits = 0
+		if HasBehavior(r.Behavior, Behavior_PARTIAL_CONSUME) {
+			b.TotalHits = r.Burst
+			rl.OverHits = r.Hits - r.Burst
+		}
 	}
 
 	item := &CacheItem{
-		ExpireAt:  now + duration,
+		ExpireAt:  addMillis(now, duration),
 		Algorithm: r.Algorithm,
 		Key:       r.HashKey(),
 		Value:     &b,
+		Priority:  r.Priority,
 	}
+	item.ScheduleSegment = segment
 
 	c.Add(item)
 	span.AddEvent("c.Add()")
@@ -514,3 +1038,378 @@ func leakyBucketNewItem(ctx context.Context, s Store, c Cache, r *RateLimitReq)
 
 	return &rl, nil
 }
+
+// Implements a bucket which leaks at `limit / duration` like the leaky bucket, and refills to the burst
+// at the end of each window of `duration` like the token bucket.
+func hybridBucket(ctx context.Context, log FieldLogger, s Store, c Cache, r *RateLimitReq, disableMigration bool, policy storePolicy, segment int32) (resp *RateLimitResp, err error) {
+	ctx = tracing.StartScopeDebug(ctx)
+	defer func() {
+		tracing.EndScope(ctx, err)
+	}()
+	span := trace.SpanFromContext(ctx)
+
+	hybridBucketTimer := prometheus.NewTimer(funcTimeMetric.WithLabelValues("V1Instance.getRateLimit_hybridBucket"))
+	defer hybridBucketTimer.ObserveDuration()
+	start := time.Now()
+
+	if r.Burst == 0 {
+		r.Burst = r.Limit
+	}
+
+	now := MillisecondNow()
+
+	// Get rate limit from cache.
+	hashKey := r.HashKey()
+	var stale *CacheItem
+	if s != nil {
+		// Must be fetched first, as the cache removes an item it invalidated on GetItem()
+		stale = getStaleItem(c, hashKey, policy)
+	}
+	item, ok := c.GetItem(hashKey)
+	defer observeAlgorithm(Algorithm_HYBRID, ok, start)
+	span.AddEvent("c.GetItem()")
+
+	if s != nil && !ok {
+		// Cache miss.
+		// Check our store for the item.
+		if item, ok, err = getStoreItem(ctx, s, r, stale, policy); err != nil {
+			return nil, err
+		}
+		if ok {
+			span.AddEvent("Check store for rate limit")
+			c.Add(item)
+			span.AddEvent("c.Add()")
+		}
+	}
+
+	// Leave the rate limit untouched if the request was answered as timed out meanwhile
+	if err := applyBatchItem(ctx); err != nil {
+		return nil, err
+	}
+
+	// Sanity checks.
+	if ok {
+		if item.Value == nil {
+			msgPart := "hybridBucket: Invalid cache item; Value is nil"
+			span.AddEvent(msgPart, trace.WithAttributes(
+				attribute.String("hashKey", hashKey),
+				attribute.String("key", r.UniqueKey),
+				attribute.String("name", r.Name),
+			))
+			log.WithFields(logrus.Fields{
+				"hashKey": hashKey,
+				"key":     r.UniqueKey,
+				"name":    r.Name,
+			}).Error(msgPart)
+			ok = false
+		} else if item.Key != hashKey {
+			msgPart := "hybridBucket: Invalid cache item; key mismatch"
+			span.AddEvent(msgPart, trace.WithAttributes(
+				attribute.String("itemKey", item.Key),
+				attribute.String("hashKey", hashKey),
+				attribute.String("name", r.Name),
+			))
+			log.WithFields(logrus.Fields{
+				"itemKey": item.Key,
+				"hashKey": hashKey,
+				"name":    r.Name,
+			}).Error(msgPart)
+			ok = false
+		}
+	}
+
+	if !ok {
+		return hybridBucketNewItem(ctx, s, c, r, segment)
+	}
+
+	// Item found in cache or store.
+	span.AddEvent("Update existing rate limit")
+
+	b, ok := item.Value.(*HybridBucketItem)
+	if !ok {
+		if disableMigration {
+			return nil, algorithmMismatch(log, hashKey, Algorithm_HYBRID)
+		}
+		// Client switched algorithms; perhaps due to a migration?
+		c.Remove(hashKey)
+		span.AddEvent("c.Remove()")
+
+		if s != nil {
+			s.Remove(ctx, hashKey)
+			span.AddEvent("s.Remove()")
+		}
+
+		return hybridBucketNewItem(ctx, s, c, r, segment)
+	}
+	updatePriority(c, item, r)
+	updateSchedule(item, r, segment)
+
+	if HasBehavior(r.Behavior, Behavior_RESET_REMAINING) {
+		b.Remaining = float64(r.Burst)
+		b.TotalHits = 0
+	}
+
+	// Update burst, limit and duration if they changed
+	if b.Burst != r.Burst {
+		if r.Burst > int64(b.Remaining) {
+			b.Remaining = float64(r.Burst)
+		}
+		b.Burst = r.Burst
+	}
+	b.Limit = r.Limit
+	b.Duration = r.Duration
+
+	// Should the clock have stepped backwards, the window begins now rather than lasting for the step
+	if b.CreatedAt > now {
+		b.CreatedAt = now
+	}
+	if b.UpdatedAt > now {
+		b.UpdatedAt = now
+	}
+
+	if end := addMillis(b.CreatedAt, b.Duration); now >= end || b.Duration <= 0 {
+		// The window ended; refill the bucket and begin the window we are in
+		span.AddEvent("Window has ended")
+		if b.Duration > 0 && b.CreatedAt <= now {
+			b.CreatedAt = now - (now-b.CreatedAt)%b.Duration
+		} else {
+			b.CreatedAt = now
+		}
+		b.Remaining = float64(b.Burst)
+		b.UpdatedAt = now
+		b.TotalHits = 0
+	} else {
+		// Calculate how much leaked out of the bucket since the last time we leaked a hit
+		leak := float64(elapsedMillis(now, b.UpdatedAt)) * float64(b.Limit) / float64(b.Duration)
+		if int64(leak) > 0 {
+			b.Remaining += leak
+			b.UpdatedAt = now
+		}
+		if int64(b.Remaining) > b.Burst {
+			b.Remaining = float64(b.Burst)
+		}
+	}
+	item.ExpireAt = addMillis(b.CreatedAt, b.Duration)
+
+	rl := &RateLimitResp{
+		Algorithm: Algorithm_HYBRID,
+		Limit:     b.Limit,
+		Remaining: int64(b.Remaining),
+		Status:    Status_UNDER_LIMIT,
+		ResetTime: hybridBucketResetTime(b, now),
+	}
+
+	if s != nil {
+		defer func() {
+			s.OnChange(ctx, r, item)
+			span.AddEvent("s.OnChange()")
+		}()
+	}
+
+	// Client is only interested in retrieving the current status
+	if r.Hits == 0 {
+		return rl, nil
+	}
+
+	// If requested is more than available, then return over the limit
+	// without updating the bucket, unless asked to drain the bucket.
+	if r.Hits > int64(b.Remaining) {
+		overLimitCounter.Add(1)
+		rl.Status = Status_OVER_LIMIT
+		switch {
+		case HasBehavior(r.Behavior, Behavior_PARTIAL_CONSUME):
+			// Consume the whole hits which remain, keeping the partial leak toward the next hit
+			consumed := int64(b.Remaining)
+			b.Remaining -= float64(consumed)
+			b.TotalHits += consumed
+			rl.OverHits = r.Hits - consumed
+		case HasBehavior(r.Behavior, Behavior_DRAIN_OVER_LIMIT):
+			b.Remaining = 0
+		default:
+			return rl, nil
+		}
+		rl.Remaining = 0
+		rl.ResetTime = hybridBucketResetTime(b, now)
+		return rl, nil
+	}
+
+	b.Remaining -= float64(r.Hits)
+	if int64(b.Remaining) > b.Burst {
+		b.Remaining = float64(b.Burst)
+	}
+	b.TotalHits += acceptedHits(r)
+	rl.Remaining = int64(b.Remaining)
+	rl.ResetTime = hybridBucketResetTime(b, now)
+	return rl, nil
+}
+
+// hybridBucketResetTime returns when a HYBRID rate limit will be full again, either after the remaining
+// hits have leaked back to the burst or at the end of the window, whichever comes first.
+func hybridBucketResetTime(b *HybridBucketItem, now int64) int64 {
+	end := addMillis(b.CreatedAt, b.Duration)
+	if b.Limit <= 0 {
+		return end
+	}
+	leaked := now + floatMillis((float64(b.Burst)-b.Remaining)*float64(b.Duration)/float64(b.Limit))
+	if leaked < end {
+		return leaked
+	}
+	return end
+}
+
+// Called by hybridBucket() when adding a new item in the store.
+func hybridBucketNewItem(ctx context.Context, s Store, c Cache, r *RateLimitReq, segment int32) (resp *RateLimitResp, err error) {
+	ctx = tracing.StartScopeDebug(ctx)
+	defer func() {
+		tracing.EndScope(ctx, err)
+	}()
+	span := trace.SpanFromContext(ctx)
+
+	now := MillisecondNow()
+
+	// Create a new hybrid bucket
+	b := HybridBucketItem{
+		Remaining: float64(r.Burst - r.Hits),
+		Limit:     r.Limit,
+		Duration:  r.Duration,
+		UpdatedAt: now,
+		CreatedAt: now,
+		Burst:     r.Burst,
+		TotalHits: acceptedHits(r),
+	}
+
+	rl := RateLimitResp{
+		Algorithm: Algorithm_HYBRID,
+		Status:    Status_UNDER_LIMIT,
+		Limit:     b.Limit,
+		Remaining: r.Burst - r.Hits,
+	}
+
+	// Client could be requesting that we start with the bucket OVER_LIMIT
+	if r.Hits > r.Burst && HasBehavior(r.Behavior, Behavior_FIRST_HIT_FREE) {
+		b.Remaining = 0
+		rl.Remaining = 0
+	} else if r.Hits > r.Burst {
+		overLimitCounter.Add(1)
+		rl.Status = Status_OVER_LIMIT
+		rl.Remaining = 0
+		// Like the token bucket, keep the bucket full such that a request within the limit succeeds
+		b.Remaining = float64(r.Burst)
+		b.TotalHits = 0
+		switch {
+		case HasBehavior(r.Behavior, Behavior_PARTIAL_CONSUME):
+			b.Remaining = 0
+			b.TotalHits = r.Burst
+			rl.OverHits = r.Hits - r.Burst
+		case HasBehavior(r.Behavior, Behavior_DRAIN_OVER_LIMIT):
+			b.Remaining = 0
+		}
+	} else if b.Remaining > float64(r.Burst) {
+		// Negative hits cannot fill the bucket beyond the burst
+		b.Remaining = float64(r.Burst)
+		rl.Remaining = r.Burst
+	}
+	rl.ResetTime = hybridBucketResetTime(&b, now)
+
+	item := &CacheItem{
+		ExpireAt:  addMillis(now, r.Duration),
+		Algorithm: r.Algorithm,
+		Key:       r.HashKey(),
+		Value:     &b,
+		Priority:  r.Priority,
+	}
+	item.ScheduleSegment = segment
+
+	c.Add(item)
+	span.AddEvent("c.Add()")
+
+	if s != nil {
+		s.OnChange(ctx, r, item)
+		span.AddEvent("s.OnChange()")
+	}
+
+	return &rl, nil
+}
+
+// cacheItemToConfig returns the configuration of the rate limit held by the item, or nil if the
+// item does not hold the state of a rate limit algorithm
+func cacheItemToConfig(item *CacheItem) *RateLimitConfig {
+	switch t := item.Value.(type) {
+	case *TokenBucketItem:
+		return &RateLimitConfig{
+			Algorithm: Algorithm_TOKEN_BUCKET,
+			Limit:     t.Limit,
+			Duration:  t.Duration,
+			Burst:     tokenBucketCapacity(t),
+		}
+	case *LeakyBucketItem:
+		return &RateLimitConfig{
+			Algorithm: Algorithm_LEAKY_BUCKET,
+			Limit:     t.Limit,
+			Duration:  t.Duration,
+			Burst:     t.Burst,
+		}
+	case *HybridBucketItem:
+		return &RateLimitConfig{
+			Algorithm: Algorithm_HYBRID,
+			Limit:     t.Limit,
+			Duration:  t.Duration,
+			Burst:     t.Burst,
+		}
+	}
+	return nil
+}
+
+// cacheItemTotalHits returns the hits accepted during the current window of the rate limit held by the item
+func cacheItemTotalHits(item *CacheItem) int64 {
+	switch t := item.Value.(type) {
+	case *TokenBucketItem:
+		return t.TotalHits
+	case *LeakyBucketItem:
+		return t.TotalHits
+	case *HybridBucketItem:
+		return t.TotalHits
+	}
+	return 0
+}
+
+// updatePriority applies a change in the priority of the request to the cached item, such that the
+// cache evicts the rate limit according to its new priority
+func updatePriority(c Cache, item *CacheItem, r *RateLimitReq) {
+	if item.Priority != r.Priority {
+		item.Priority = r.Priority
+		c.Add(item)
+	}
+}
+
+// observeAlgorithm records the time spent in the algorithm since `start`. The store is only
+// consulted on a cache miss, so hits and misses are observed separately. Unlike the rate limits,
+// which read the time through holster/clock, the duration is measured with the `time` package, as
+// a frozen or advanced clock would misreport it.
+func observeAlgorithm(algorithm Algorithm, cached bool, start time.Time) {
+	label := "miss"
+	if cached {
+		label = "hit"
+	}
+	algorithmDurationMetric.WithLabelValues(algorithm.String(), label).Observe(time.Since(start).Seconds())
+}
+
+// acceptedHits returns the hits of an accepted request counted by Behavior_RETURN_TOTAL_HITS. Negative
+// hits return tokens to the bucket and are not counted.
+func acceptedHits(r *RateLimitReq) int64 {
+	if r.Hits < 0 {
+		return 0
+	}
+	return r.Hits
+}
+
+// algorithmMismatch returns the error of a request for an existing rate limit of another algorithm when
+// Config.DisableAlgorithmMigration is set
+func algorithmMismatch(log FieldLogger, hashKey string, requested Algorithm) error {
+	log.WithField("key", hashKey).
+		WithField("algorithm", requested.String()).
+		Error("Rejected request which would change the algorithm of the rate limit; algorithm migration is disabled")
+	checkErrorCounter.WithLabelValues("Algorithm mismatch").Add(1)
+	return fmt.Errorf("rate limit '%s' exists with another algorithm than '%s' and algorithm migration is disabled",
+		hashKey, requested)
+}
@@ -0,0 +1,220 @@
+/*
+Copyright 2018-2022 Mailgun Technologies Inc
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gubernator
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/mailgun/holster/v4/syncutil"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var storeFlushMetric = prometheus.NewSummary(prometheus.SummaryOpts{
+	Name: "gubernator_store_flush_size",
+	Help: "The number of changed rate limits flushed to the store in a single batch.",
+	Objectives: map[float64]float64{
+		0.99: 0.001,
+	},
+})
+
+type asyncStoreChange struct {
+	req  *RateLimitReq
+	item *CacheItem
+}
+
+// asyncStore wraps a Store such that calls to OnChange() do not block the request path on store I/O.
+// Changed rate limits are buffered by key and flushed to the wrapped store in batches, either when
+// the flush interval elapses or when the buffer fills. Only the latest change to a rate limit is kept
+// in the buffer.
+type asyncStore struct {
+	store      Store
+	bufferSize int
+	wg         syncutil.WaitGroup
+	flushCh    chan struct{}
+
+	mutex    sync.Mutex
+	dirty    map[string]asyncStoreChange
+	flushing map[string]asyncStoreChange
+	closed   bool
+	// The key of the change the flush is writing to the store, signalled on `written` once written
+	writing string
+	written *sync.Cond
+
+	// Ensures only one flush writes to the store at a time
+	flushMutex sync.Mutex
+}
+
+var _ FallibleStore = &asyncStore{}
+
+func newAsyncStore(store Store, interval time.Duration, bufferSize int) *asyncStore {
+	s := &asyncStore{
+		store:      store,
+		bufferSize: bufferSize,
+		flushCh:    make(chan struct{}, 1),
+		dirty:      make(map[string]asyncStoreChange),
+	}
+	s.written = sync.NewCond(&s.mutex)
+
+	tick := time.NewTicker(interval)
+	s.wg.Until(func(done chan struct{}) bool {
+		select {
+		case <-tick.C:
+			s.flush(context.Background())
+		case <-s.flushCh:
+			s.flush(context.Background())
+		case <-done:
+			tick.Stop()
+			return false
+		}
+		return true
+	})
+	return s
+}
+
+func (s *asyncStore) OnChange(ctx context.Context, r *RateLimitReq, item *CacheItem) {
+	// The algorithms continue to modify the item after OnChange() returns, so we
+	// must buffer a copy of the item as it is now.
+	change := asyncStoreChange{req: r, item: copyCacheItem(item)}
+
+	s.mutex.Lock()
+	if s.closed {
+		s.mutex.Unlock()
+		// Ensure we don't race with the final flush
+		s.flushMutex.Lock()
+		s.store.OnChange(ctx, change.req, change.item)
+		s.flushMutex.Unlock()
+		return
+	}
+	s.dirty[item.Key] = change
+	full := len(s.dirty) >= s.bufferSize
+	s.mutex.Unlock()
+
+	if full {
+		select {
+		case s.flushCh <- struct{}{}:
+		default:
+		}
+	}
+}
+
+func (s *asyncStore) Get(ctx context.Context, r *RateLimitReq) (*CacheItem, bool) {
+	item, ok, _ := s.GetWithError(ctx, r)
+	return item, ok
+}
+
+func (s *asyncStore) GetWithError(ctx context.Context, r *RateLimitReq) (*CacheItem, bool, error) {
+	// Changes which have not yet reached the store are more recent than the store
+	s.mutex.Lock()
+	change, ok := s.dirty[r.HashKey()]
+	if !ok {
+		change, ok = s.flushing[r.HashKey()]
+	}
+	s.mutex.Unlock()
+	if ok {
+		return copyCacheItem(change.item), true, nil
+	}
+	return getFromStore(ctx, s.store, r)
+}
+
+func (s *asyncStore) Remove(ctx context.Context, key string) {
+	s.mutex.Lock()
+	delete(s.dirty, key)
+	delete(s.flushing, key)
+	// A write which finished after the removal would restore the rate limit
+	for s.writing == key {
+		s.written.Wait()
+	}
+	s.mutex.Unlock()
+	s.store.Remove(ctx, key)
+}
+
+// flush writes all buffered changes to the store
+func (s *asyncStore) flush(ctx context.Context) {
+	s.flushMutex.Lock()
+	defer s.flushMutex.Unlock()
+
+	s.mutex.Lock()
+	s.flushing = s.dirty
+	s.dirty = make(map[string]asyncStoreChange)
+	keys := make([]string, 0, len(s.flushing))
+	for key := range s.flushing {
+		keys = append(keys, key)
+	}
+	s.mutex.Unlock()
+
+	if len(keys) == 0 {
+		return
+	}
+	storeFlushMetric.Observe(float64(len(keys)))
+
+	for _, key := range keys {
+		s.mutex.Lock()
+		// The rate limit might have been removed since the flush began
+		change, ok := s.flushing[key]
+		if ok {
+			s.writing = key
+		}
+		s.mutex.Unlock()
+		if !ok {
+			continue
+		}
+		s.store.OnChange(ctx, change.req, change.item)
+
+		s.mutex.Lock()
+		s.writing = ""
+		s.written.Broadcast()
+		s.mutex.Unlock()
+	}
+
+	s.mutex.Lock()
+	s.flushing = nil
+	s.mutex.Unlock()
+}
+
+// Close stops the background flush and synchronously flushes any buffered changes to the store.
+// Changes received after Close() are written to the store synchronously.
+func (s *asyncStore) Close(ctx context.Context) {
+	s.mutex.Lock()
+	if s.closed {
+		s.mutex.Unlock()
+		return
+	}
+	s.closed = true
+	s.mutex.Unlock()
+
+	s.wg.Stop()
+
+	s.flush(ctx)
+}
+
+func copyCacheItem(item *CacheItem) *CacheItem {
+	c := *item
+	switch v := item.Value.(type) {
+	case *TokenBucketItem:
+		t := *v
+		c.Value = &t
+	case *LeakyBucketItem:
+		b := *v
+		c.Value = &b
+	case *HybridBucketItem:
+		b := *v
+		c.Value = &b
+	}
+	return &c
+}
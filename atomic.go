@@ -0,0 +1,123 @@
+/*
+Copyright 2018-2022 Mailgun Technologies Inc
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gubernator
+
+import (
+	"context"
+
+	"github.com/mailgun/holster/v4/tracing"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+)
+
+// AtomicGetRateLimits applies the hits of every request only if each of the rate limits would be
+// UNDER_LIMIT. Since the rate limits may be owned by different peers, the requests are applied in two
+// phases:
+//
+//  1. Each request is sent with Behavior_PEEK to the owning peer. If any rate limit would be OVER_LIMIT, or
+//     any request returns an error, the peek responses are returned and no hits are applied.
+//  2. The requests are sent as provided. Another client may have consumed the remaining of a rate limit
+//     between the two phases, in which case the hits applied to the rate limits which were UNDER_LIMIT are
+//     rolled back by applying the negated hits, and the responses of the second phase are returned.
+//
+// Even with the roll back, the two phases are not isolated from other clients; a concurrent request may
+// observe the hits of the second phase before they are rolled back.
+func (s *V1Instance) AtomicGetRateLimits(ctx context.Context, r *GetRateLimitsReq) (retval *GetRateLimitsResp, reterr error) {
+	ctx = tracing.StartScope(ctx)
+	defer func() {
+		tracing.EndScope(ctx, reterr)
+	}()
+
+	if len(r.Requests) > maxBatchSize {
+		checkErrorCounter.WithLabelValues("Request too large").Add(1)
+		return nil, status.Errorf(codes.OutOfRange,
+			"Requests.RateLimits list too large; max size is '%d'", maxBatchSize)
+	}
+
+	return s.applyAtomic(ctx, r.Requests)
+}
+
+// applyAtomic applies the hits of every request only if each of the rate limits would be UNDER_LIMIT,
+// see AtomicGetRateLimits()
+func (s *V1Instance) applyAtomic(ctx context.Context, reqs []*RateLimitReq) (*GetRateLimitsResp, error) {
+	// Phase 1: ask each owner if the hits would be allowed
+	peek := &GetRateLimitsReq{Requests: make([]*RateLimitReq, len(reqs))}
+	for i, req := range reqs {
+		req = proto.Clone(req).(*RateLimitReq)
+		SetBehavior(&req.Behavior, Behavior_PEEK, true)
+		peek.Requests[i] = req
+	}
+
+	resp, err := s.GetRateLimits(ctx, peek)
+	if err != nil {
+		return nil, err
+	}
+	if !allUnderLimit(resp) {
+		return resp, nil
+	}
+
+	// Phase 2: apply the hits
+	apply := &GetRateLimitsReq{Requests: make([]*RateLimitReq, len(reqs))}
+	for i, req := range reqs {
+		req = proto.Clone(req).(*RateLimitReq)
+		SetBehavior(&req.Behavior, Behavior_PEEK, false)
+		apply.Requests[i] = req
+	}
+
+	resp, err = s.GetRateLimits(ctx, apply)
+	if err != nil {
+		return nil, err
+	}
+	if allUnderLimit(resp) {
+		return resp, nil
+	}
+
+	// Roll back the hits which were applied
+	rollback := &GetRateLimitsReq{}
+	for i, rl := range resp.Responses {
+		if rl.Error == "" && rl.Status == Status_UNDER_LIMIT && requestCost(apply.Requests[i]) != 0 {
+			req := apply.Requests[i]
+			req.Hits = -req.Hits
+			req.Cost = -req.Cost
+			rollback.Requests = append(rollback.Requests, req)
+		}
+	}
+	if len(rollback.Requests) != 0 {
+		rb, err := s.GetRateLimits(ctx, rollback)
+		if err != nil {
+			s.log.WithContext(ctx).WithError(err).Error("while rolling back AtomicGetRateLimits")
+		} else {
+			for _, rl := range rb.Responses {
+				if rl.Error != "" {
+					s.log.WithContext(ctx).Errorf("while rolling back AtomicGetRateLimits: %s", rl.Error)
+				}
+			}
+		}
+	}
+	return resp, nil
+}
+
+// allUnderLimit returns true if none of the responses are OVER_LIMIT or contain an error
+func allUnderLimit(resp *GetRateLimitsResp) bool {
+	for _, rl := range resp.Responses {
+		if rl.Error != "" || rl.Status == Status_OVER_LIMIT {
+			return false
+		}
+	}
+	return true
+}
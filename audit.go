@@ -0,0 +1,189 @@
+/*
+Copyright 2018-2022 Mailgun Technologies Inc
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gubernator
+
+import (
+	"encoding/json"
+	"io"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/mailgun/holster/v4/clock"
+	"github.com/mailgun/holster/v4/syncutil"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// auditBatchSize is the maximum number of records passed to a single AuditSink.Write()
+const auditBatchSize = 100
+
+var auditDroppedCounter = prometheus.NewCounter(prometheus.CounterOpts{
+	Name: "gubernator_audit_dropped_count",
+	Help: "The number of sampled audit records dropped because the buffer of Config.AuditSink was full.",
+})
+
+// AuditRecord is a rate limit decision sampled for the audit trail, see Config.AuditSink
+type AuditRecord struct {
+	// The name of the rate limit
+	Name string
+	// The hash key of the rate limit, which is `name_unique_key`
+	HashKey   string
+	Hits      int64
+	Status    Status
+	Remaining int64
+	// When the instance which received the request made the decision
+	Timestamp time.Time
+}
+
+// AuditSink receives the rate limit decisions sampled for the audit trail. Write() is called from a
+// single goroutine off the request path, with the records in the order the decisions were made.
+// Implementations may write the records to a file, a message queue, etc.
+type AuditSink interface {
+	Write(records []AuditRecord) error
+}
+
+// NewJSONAuditSink returns an AuditSink which writes each record as a line of JSON
+func NewJSONAuditSink(w io.Writer) AuditSink {
+	return &jsonAuditSink{enc: json.NewEncoder(w)}
+}
+
+type jsonAuditSink struct {
+	enc *json.Encoder
+}
+
+type jsonAuditRecord struct {
+	Name      string    `json:"name"`
+	HashKey   string    `json:"hash_key"`
+	Hits      int64     `json:"hits"`
+	Status    string    `json:"status"`
+	Remaining int64     `json:"remaining"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+func (s *jsonAuditSink) Write(records []AuditRecord) error {
+	for _, r := range records {
+		err := s.enc.Encode(jsonAuditRecord{
+			Name:      r.Name,
+			HashKey:   r.HashKey,
+			Hits:      r.Hits,
+			Status:    r.Status.String(),
+			Remaining: r.Remaining,
+			Timestamp: r.Timestamp,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// auditLog samples the decisions of rate limits in Config.AuditNamespaces and hands them to the
+// AuditSink from a background goroutine. Records are dropped rather than blocking the request
+// when the buffer is full.
+type auditLog struct {
+	sink  AuditSink
+	names map[string]struct{}
+	rate  float64
+	log   FieldLogger
+	count uint64
+	queue chan AuditRecord
+	wg    syncutil.WaitGroup
+	once  sync.Once
+}
+
+func newAuditLog(conf Config, log FieldLogger) *auditLog {
+	a := auditLog{
+		sink:  conf.AuditSink,
+		names: make(map[string]struct{}, len(conf.AuditNamespaces)),
+		rate:  *conf.AuditSampleRate,
+		log:   log,
+		queue: make(chan AuditRecord, conf.AuditBufferSize),
+	}
+	for _, name := range conf.AuditNamespaces {
+		a.names[name] = struct{}{}
+	}
+	a.runWriter()
+	return &a
+}
+
+// Observe samples the decision of the rate limit if it belongs to one of the audited namespaces
+func (a *auditLog) Observe(r *RateLimitReq, rl *RateLimitResp) {
+	if rl.Error != "" {
+		return
+	}
+	if _, ok := a.names[r.Name]; !ok {
+		return
+	}
+
+	// Sample every 1/rate decisions, such that the rate is exact rather than probable
+	n := atomic.AddUint64(&a.count, 1)
+	if uint64(float64(n)*a.rate) == uint64(float64(n-1)*a.rate) {
+		return
+	}
+
+	record := AuditRecord{
+		Name:      r.Name,
+		HashKey:   r.HashKey(),
+		Hits:      r.Hits,
+		Status:    rl.Status,
+		Remaining: rl.Remaining,
+		Timestamp: clock.Now(),
+	}
+	select {
+	case a.queue <- record:
+	default:
+		auditDroppedCounter.Add(1)
+	}
+}
+
+func (a *auditLog) runWriter() {
+	a.wg.Until(func(done chan struct{}) bool {
+		select {
+		case record := <-a.queue:
+			a.write(record)
+			return true
+		case <-done:
+			// Write the records which were sampled before we were closed
+			for {
+				select {
+				case record := <-a.queue:
+					a.write(record)
+				default:
+					return false
+				}
+			}
+		}
+	})
+}
+
+// write writes the record along with any records queued behind it
+func (a *auditLog) write(first AuditRecord) {
+	records := []AuditRecord{first}
+	for len(records) < auditBatchSize && len(a.queue) != 0 {
+		records = append(records, <-a.queue)
+	}
+	if err := a.sink.Write(records); err != nil {
+		a.log.WithError(err).Errorf("while writing '%d' audit records", len(records))
+	}
+}
+
+// Close writes the records which remain in the buffer. Records sampled after Close() are dropped.
+func (a *auditLog) Close() {
+	a.once.Do(func() {
+		a.wg.Stop()
+	})
+}
@@ -23,6 +23,14 @@ func BenchmarkCache(b *testing.B) {
 			},
 			LockRequired: true,
 		},
+		{
+			Name: "ShardedLRUCache",
+			NewTestCache: func() gubernator.Cache {
+				cache, _ := gubernator.NewShardedLRUCache(gubernator.LRUCacheConfig{})
+				return cache
+			},
+			LockRequired: false,
+		},
 	}
 
 	for _, testCase := range testCases {
@@ -155,6 +163,43 @@ func BenchmarkCache(b *testing.B) {
 				wg.Wait()
 			})
 
+			b.Run("Parallel reads and writes", func(b *testing.B) {
+				cache := testCase.NewTestCache()
+				expire := clock.Now().Add(time.Hour).UnixMilli()
+				const keys = 10_000
+
+				for i := 0; i < keys; i++ {
+					cache.Add(&gubernator.CacheItem{
+						Key:      strconv.Itoa(i),
+						Value:    i,
+						ExpireAt: expire,
+					})
+				}
+
+				var mutex sync.Mutex
+				b.ReportAllocs()
+				b.ResetTimer()
+
+				b.RunParallel(func(pb *testing.PB) {
+					var i int
+					for pb.Next() {
+						key := strconv.Itoa(i % keys)
+						if testCase.LockRequired {
+							mutex.Lock()
+						}
+						if i%4 == 0 {
+							cache.UpdateExpiration(key, expire)
+						} else {
+							_, _ = cache.GetItem(key)
+						}
+						if testCase.LockRequired {
+							mutex.Unlock()
+						}
+						i++
+					}
+				})
+			})
+
 		})
 	}
 }
@@ -0,0 +1,124 @@
+/*
+Copyright 2018-2022 Mailgun Technologies Inc
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gubernator
+
+import (
+	"math"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+const defaultBoundedHashFactor = 1.25
+
+// BoundedHash implements PeerPicker using "Consistent Hashing with Bounded Loads"
+// (https://arxiv.org/abs/1608.01350). Keys are assigned to peers on a replicated consistent hash
+// ring, however no peer is assigned more than `factor * average` keys. When the peer that owns a key
+// on the ring is at capacity, the key spills over to the next peer on the ring with capacity. Weighted
+// peers may be assigned up to `factor * average * weight` keys.
+//
+// Once assigned, a key remains with the same peer for the life of the picker. Because the assignment
+// depends on the order in which keys are seen, every instance routing requests for the same keys
+// must see them in the same order to agree on ownership.
+type BoundedHash struct {
+	ring   *ReplicatedConsistentHash
+	factor float64
+
+	mutex    sync.Mutex
+	assigned map[string]*PeerClient
+	loads    map[string]int
+}
+
+// NewBoundedHash returns a BoundedHash which assigns no more than `factor * average` keys to any
+// single peer. A factor less than 1 is treated as 1. A very large factor disables the load bound,
+// making the picker behave as a plain consistent hash.
+func NewBoundedHash(factor float64) *BoundedHash {
+	if factor < 1 {
+		factor = 1
+	}
+	return &BoundedHash{
+		ring:     NewReplicatedConsistentHash(nil, defaultReplicas),
+		factor:   factor,
+		assigned: make(map[string]*PeerClient),
+		loads:    make(map[string]int),
+	}
+}
+
+func (bh *BoundedHash) New() PeerPicker {
+	return &BoundedHash{
+		ring:     bh.ring.New().(*ReplicatedConsistentHash),
+		factor:   bh.factor,
+		assigned: make(map[string]*PeerClient),
+		loads:    make(map[string]int),
+	}
+}
+
+func (bh *BoundedHash) Peers() []*PeerClient {
+	return bh.ring.Peers()
+}
+
+// Adds a peer to the hash
+func (bh *BoundedHash) Add(peer *PeerClient) {
+	bh.ring.Add(peer)
+}
+
+// Returns number of peers in the picker
+func (bh *BoundedHash) Size() int {
+	return bh.ring.Size()
+}
+
+// Returns the peer by hostname
+func (bh *BoundedHash) GetByPeerInfo(peer PeerInfo) *PeerClient {
+	return bh.ring.GetByPeerInfo(peer)
+}
+
+// Given a key, return the peer that key is assigned too
+func (bh *BoundedHash) Get(key string) (*PeerClient, error) {
+	if bh.Size() == 0 {
+		return nil, errors.New("unable to pick a peer; pool is empty")
+	}
+
+	bh.mutex.Lock()
+	defer bh.mutex.Unlock()
+
+	if peer, ok := bh.assigned[key]; ok {
+		return peer, nil
+	}
+
+	var weights int
+	for _, peer := range bh.ring.peers {
+		weights += peer.Info().weight()
+	}
+	assigned := float64(len(bh.assigned) + 1)
+
+	// Walk the ring from the key's position until we find a peer with capacity. Since
+	// the factor is at least 1, at least one peer is always below capacity.
+	keys := bh.ring.peerKeys
+	idx := bh.ring.index(key)
+	for i := 0; i < len(keys); i++ {
+		peer := keys[(idx+i)%len(keys)].peer
+		addr := peer.Info().GRPCAddress
+		// The maximum number of keys this peer may hold once this key is assigned
+		capacity := math.Ceil(bh.factor * assigned * float64(peer.Info().weight()) / float64(weights))
+		if float64(bh.loads[addr]) < capacity {
+			bh.assigned[key] = peer
+			bh.loads[addr]++
+			return peer, nil
+		}
+	}
+	return nil, errors.New("unable to pick a peer; all peers are at capacity")
+}
@@ -0,0 +1,129 @@
+/*
+Copyright 2018-2022 Mailgun Technologies Inc
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gubernator
+
+import (
+	"fmt"
+	"math"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBoundedHash(t *testing.T) {
+	hosts := []string{"a.svc.local", "b.svc.local", "c.svc.local", "d.svc.local", "e.svc.local"}
+
+	keys := make([]string, 10000)
+	for i := range keys {
+		keys[i] = net.IPv4(192, 168, byte(i>>8), byte(i)).String()
+	}
+
+	t.Run("Empty", func(t *testing.T) {
+		hash := NewBoundedHash(1.25)
+		_, err := hash.Get("key")
+		assert.EqualError(t, err, "unable to pick a peer; pool is empty")
+	})
+
+	t.Run("Peers", func(t *testing.T) {
+		hash := NewBoundedHash(1.25)
+		for _, h := range hosts {
+			hash.Add(&PeerClient{conf: PeerConfig{Info: PeerInfo{GRPCAddress: h}}})
+		}
+		assert.Equal(t, len(hosts), hash.Size())
+		assert.Len(t, hash.Peers(), len(hosts))
+		assert.Equal(t, "a.svc.local", hash.GetByPeerInfo(PeerInfo{GRPCAddress: "a.svc.local"}).Info().GRPCAddress)
+		assert.Equal(t, 0, hash.New().Size())
+	})
+
+	t.Run("MaxLoad", func(t *testing.T) {
+		for _, factor := range []float64{0.5, 1, 1.1, 1.25, 2} {
+			t.Run(fmt.Sprintf("%.2f", factor), func(t *testing.T) {
+				hash := NewBoundedHash(factor)
+				distribution := make(map[string]int)
+				for _, h := range hosts {
+					hash.Add(&PeerClient{conf: PeerConfig{Info: PeerInfo{GRPCAddress: h}}})
+				}
+
+				for i, key := range keys {
+					peer, err := hash.Get(key)
+					require.NoError(t, err)
+					distribution[peer.Info().GRPCAddress]++
+
+					// The bound must hold after every assignment, not just at the end
+					bound := math.Ceil(math.Max(factor, 1) * float64(i+1) / float64(len(hosts)))
+					for host, load := range distribution {
+						require.LessOrEqual(t, float64(load), bound, host)
+					}
+				}
+			})
+		}
+	})
+
+	t.Run("Weighted", func(t *testing.T) {
+		hash := NewBoundedHash(1.25)
+		hash.Add(&PeerClient{conf: PeerConfig{Info: PeerInfo{GRPCAddress: "heavy.svc.local", Weight: 3}}})
+		hash.Add(&PeerClient{conf: PeerConfig{Info: PeerInfo{GRPCAddress: "light.svc.local"}}})
+
+		distribution := make(map[string]int)
+		for _, key := range keys {
+			peer, err := hash.Get(key)
+			require.NoError(t, err)
+			distribution[peer.Info().GRPCAddress]++
+		}
+		assert.LessOrEqual(t, float64(distribution["light.svc.local"]), math.Ceil(1.25*float64(len(keys))/4))
+		assert.LessOrEqual(t, float64(distribution["heavy.svc.local"]), math.Ceil(1.25*float64(len(keys))*3/4))
+	})
+
+	t.Run("Stable", func(t *testing.T) {
+		hash := NewBoundedHash(1)
+		for _, h := range hosts {
+			hash.Add(&PeerClient{conf: PeerConfig{Info: PeerInfo{GRPCAddress: h}}})
+		}
+
+		assigned := make(map[string]*PeerClient)
+		for _, key := range keys {
+			peer, err := hash.Get(key)
+			require.NoError(t, err)
+			assigned[key] = peer
+		}
+		for _, key := range keys {
+			peer, err := hash.Get(key)
+			require.NoError(t, err)
+			assert.Equal(t, assigned[key], peer)
+		}
+	})
+
+	t.Run("LargeFactor", func(t *testing.T) {
+		hash := NewBoundedHash(math.MaxFloat64)
+		ring := NewReplicatedConsistentHash(nil, defaultReplicas)
+		for _, h := range hosts {
+			peer := &PeerClient{conf: PeerConfig{Info: PeerInfo{GRPCAddress: h}}}
+			hash.Add(peer)
+			ring.Add(peer)
+		}
+
+		for _, key := range keys {
+			expected, err := ring.Get(key)
+			require.NoError(t, err)
+			peer, err := hash.Get(key)
+			require.NoError(t, err)
+			assert.Equal(t, expected, peer)
+		}
+	})
+}
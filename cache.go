@@ -27,16 +27,39 @@ type Cache interface {
 	Close() error
 }
 
+// ExpiringCache is implemented by caches which support proactively removing expired items. When
+// `Config.CacheExpireInterval` is set, each worker periodically calls RemoveExpired() on its cache.
+type ExpiringCache interface {
+	// RemoveExpired removes all expired items from the cache and returns the number of items removed
+	RemoveExpired() int
+}
+
+// StaleCache is implemented by caches which can provide an item after `CacheItem.InvalidAt` has passed,
+// such that it may be served when the store cannot be read or be reconciled with the item the store
+// provides, see StoreErrorServeCached and StoreReconcilePolicy.
+type StaleCache interface {
+	// GetStaleItem returns the item if it has not expired, even if it was invalidated. Unlike GetItem()
+	// it neither removes an invalidated item nor counts a hit or miss.
+	GetStaleItem(key string) (*CacheItem, bool)
+}
+
 type CacheItem struct {
 	Algorithm Algorithm
 	Key       string
 	Value     interface{}
 
-	// Timestamp when rate limit expires in epoch milliseconds.
+	// Timestamp when rate limit expires in epoch milliseconds. This is the first millisecond of the
+	// next rate limit window, as such a request which arrives at exactly `ExpireAt` belongs to the new window.
 	ExpireAt int64
 	// Timestamp when the cache should invalidate this rate limit. This is useful when used in conjunction with
 	// a persistent store to ensure our node has the most up to date info from the store. Ignored if set to `0`
 	// It is set by the persistent store implementation to indicate when the node should query the persistent store
 	// for the latest rate limit data.
 	InvalidAt int64
+	// The eviction priority of the rate limit, see RateLimitReq.Priority. A cache which is full evicts
+	// items with a lower priority first.
+	Priority int32
+	// The window of RateLimitReq.Schedule which was active when the rate limit was last updated, counting
+	// from 1, or 0 for the `limit` of the request
+	ScheduleSegment int32
 }
@@ -0,0 +1,289 @@
+/*
+Copyright 2018-2022 Mailgun Technologies Inc
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gubernator_test
+
+import (
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/mailgun/gubernator/v2"
+	"github.com/mailgun/holster/v4/clock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var cacheImplementations = []struct {
+	name     string
+	newCache func(maxSize int) gubernator.Cache
+}{
+	{
+		name:     "LRUCache",
+		newCache: func(maxSize int) gubernator.Cache { return gubernator.NewLRUCache(maxSize) },
+	},
+	{
+		name: "ShardedLRUCache",
+		newCache: func(maxSize int) gubernator.Cache {
+			c, _ := gubernator.NewShardedLRUCache(gubernator.LRUCacheConfig{MaxSize: maxSize, Shards: 4})
+			return c
+		},
+	},
+	{
+		name:     "LFUCache",
+		newCache: func(maxSize int) gubernator.Cache { return gubernator.NewLFUCache(maxSize) },
+	},
+	{
+		name:     "SegmentedLRUCache",
+		newCache: func(maxSize int) gubernator.Cache { return gubernator.NewSegmentedLRUCache(maxSize) },
+	},
+}
+
+// TestCacheConformance runs the same checks against each Cache implementation
+func TestCacheConformance(t *testing.T) {
+	expireAt := clock.Now().Add(time.Hour).UnixMilli()
+
+	for _, impl := range cacheImplementations {
+		t.Run(impl.name, func(t *testing.T) {
+			t.Run("Add and get", func(t *testing.T) {
+				cache := impl.newCache(100)
+				for i := 0; i < 50; i++ {
+					exists := cache.Add(&gubernator.CacheItem{Key: strconv.Itoa(i), Value: i, ExpireAt: expireAt})
+					assert.False(t, exists)
+				}
+				assert.Equal(t, int64(50), cache.Size())
+
+				for i := 0; i < 50; i++ {
+					item, ok := cache.GetItem(strconv.Itoa(i))
+					require.True(t, ok)
+					assert.Equal(t, i, item.Value)
+				}
+				_, ok := cache.GetItem("unknown")
+				assert.False(t, ok)
+			})
+
+			t.Run("Update an existing key", func(t *testing.T) {
+				cache := impl.newCache(100)
+				require.False(t, cache.Add(&gubernator.CacheItem{Key: "foobar", Value: "initial", ExpireAt: expireAt}))
+				require.True(t, cache.Add(&gubernator.CacheItem{Key: "foobar", Value: "updated", ExpireAt: expireAt}))
+				assert.Equal(t, int64(1), cache.Size())
+
+				item, ok := cache.GetItem("foobar")
+				require.True(t, ok)
+				assert.Equal(t, "updated", item.Value)
+			})
+
+			t.Run("Remove", func(t *testing.T) {
+				cache := impl.newCache(100)
+				cache.Add(&gubernator.CacheItem{Key: "a", ExpireAt: expireAt})
+				cache.Add(&gubernator.CacheItem{Key: "b", ExpireAt: expireAt})
+				cache.Remove("a")
+				cache.Remove("unknown")
+
+				_, ok := cache.GetItem("a")
+				assert.False(t, ok)
+				_, ok = cache.GetItem("b")
+				assert.True(t, ok)
+				assert.Equal(t, int64(1), cache.Size())
+			})
+
+			t.Run("Respects ExpireAt", func(t *testing.T) {
+				cache := impl.newCache(100)
+				now := gubernator.MillisecondNow()
+				cache.Add(&gubernator.CacheItem{Key: "expired", ExpireAt: now - 1})
+				cache.Add(&gubernator.CacheItem{Key: "invalid", ExpireAt: expireAt, InvalidAt: now - 1})
+				cache.Add(&gubernator.CacheItem{Key: "live", ExpireAt: expireAt})
+
+				_, ok := cache.GetItem("expired")
+				assert.False(t, ok)
+				_, ok = cache.GetItem("invalid")
+				assert.False(t, ok)
+				_, ok = cache.GetItem("live")
+				assert.True(t, ok)
+				assert.Equal(t, int64(1), cache.Size())
+			})
+
+			t.Run("UpdateExpiration", func(t *testing.T) {
+				cache := impl.newCache(100)
+				cache.Add(&gubernator.CacheItem{Key: "foobar", ExpireAt: expireAt})
+
+				assert.False(t, cache.UpdateExpiration("unknown", expireAt))
+				assert.True(t, cache.UpdateExpiration("foobar", expireAt+1000))
+				item, ok := cache.GetItem("foobar")
+				require.True(t, ok)
+				assert.Equal(t, expireAt+1000, item.ExpireAt)
+
+				// The item is not returned once it expires
+				assert.True(t, cache.UpdateExpiration("foobar", gubernator.MillisecondNow()-1))
+				_, ok = cache.GetItem("foobar")
+				assert.False(t, ok)
+			})
+
+			t.Run("Each", func(t *testing.T) {
+				cache := impl.newCache(100)
+				for i := 0; i < 10; i++ {
+					cache.Add(&gubernator.CacheItem{Key: strconv.Itoa(i), ExpireAt: expireAt})
+				}
+				keys := make(map[string]bool)
+				for item := range cache.Each() {
+					keys[item.Key] = true
+				}
+				assert.Len(t, keys, 10)
+			})
+
+			t.Run("Bounded by max size", func(t *testing.T) {
+				cache := impl.newCache(100)
+				for i := 0; i < 500; i++ {
+					cache.Add(&gubernator.CacheItem{Key: strconv.Itoa(i), ExpireAt: expireAt})
+					require.LessOrEqual(t, cache.Size(), int64(100))
+				}
+				// The most recently added item is never evicted
+				_, ok := cache.GetItem("499")
+				assert.True(t, ok)
+			})
+
+			t.Run("RemoveExpired", func(t *testing.T) {
+				cache := impl.newCache(100)
+				ec, ok := cache.(gubernator.ExpiringCache)
+				if !ok {
+					t.Skip("not an ExpiringCache")
+				}
+				now := gubernator.MillisecondNow()
+				for i := 0; i < 10; i++ {
+					exp := expireAt
+					if i%2 == 0 {
+						exp = now - 1
+					}
+					cache.Add(&gubernator.CacheItem{Key: strconv.Itoa(i), ExpireAt: exp})
+				}
+				assert.Equal(t, 5, ec.RemoveExpired())
+				assert.Equal(t, int64(5), cache.Size())
+			})
+		})
+	}
+}
+
+func TestLFUCacheEviction(t *testing.T) {
+	expireAt := clock.Now().Add(time.Hour).UnixMilli()
+	cache := gubernator.NewLFUCache(10)
+
+	// A hot key survives many keys which are only used once
+	cache.Add(&gubernator.CacheItem{Key: "hot", ExpireAt: expireAt})
+	for i := 0; i < 3; i++ {
+		_, ok := cache.GetItem("hot")
+		require.True(t, ok)
+	}
+	for i := 0; i < 100; i++ {
+		cache.Add(&gubernator.CacheItem{Key: strconv.Itoa(i), ExpireAt: expireAt})
+	}
+	_, ok := cache.GetItem("hot")
+	assert.True(t, ok)
+
+	// Keys used equally often are evicted least recently used first
+	_, ok = cache.GetItem("90")
+	assert.False(t, ok)
+	_, ok = cache.GetItem("99")
+	assert.True(t, ok)
+	assert.Equal(t, int64(91), cache.Stats().Evictions)
+}
+
+func TestSegmentedLRUCacheEviction(t *testing.T) {
+	expireAt := clock.Now().Add(time.Hour).UnixMilli()
+	cache := gubernator.NewSegmentedLRUCache(10)
+
+	// Keys used more than once are protected from a scan of new keys
+	for i := 0; i < 5; i++ {
+		cache.Add(&gubernator.CacheItem{Key: "hot:" + strconv.Itoa(i), ExpireAt: expireAt})
+		_, ok := cache.GetItem("hot:" + strconv.Itoa(i))
+		require.True(t, ok)
+	}
+	for i := 0; i < 100; i++ {
+		cache.Add(&gubernator.CacheItem{Key: strconv.Itoa(i), ExpireAt: expireAt})
+	}
+	for i := 0; i < 5; i++ {
+		_, ok := cache.GetItem("hot:" + strconv.Itoa(i))
+		assert.True(t, ok)
+	}
+
+	// The scan itself is evicted least recently used first
+	_, ok := cache.GetItem("94")
+	assert.False(t, ok)
+	_, ok = cache.GetItem("99")
+	assert.True(t, ok)
+	assert.Equal(t, int64(10), cache.Size())
+}
+
+func TestCachePriorityEviction(t *testing.T) {
+	expireAt := clock.Now().Add(time.Hour).UnixMilli()
+
+	for _, impl := range cacheImplementations {
+		if impl.name != "LRUCache" && impl.name != "LFUCache" {
+			continue
+		}
+		t.Run(impl.name, func(t *testing.T) {
+			cache := impl.newCache(10)
+
+			// Fill the cache with mixed priorities, the high priority keys are the least recently used
+			for i := 0; i < 5; i++ {
+				cache.Add(&gubernator.CacheItem{Key: "high:" + strconv.Itoa(i), ExpireAt: expireAt, Priority: 10})
+			}
+			for i := 0; i < 5; i++ {
+				cache.Add(&gubernator.CacheItem{Key: "low:" + strconv.Itoa(i), ExpireAt: expireAt})
+			}
+			require.Equal(t, int64(10), cache.Size())
+
+			// New low priority keys evict the low priority keys first
+			for i := 5; i < 10; i++ {
+				cache.Add(&gubernator.CacheItem{Key: "low:" + strconv.Itoa(i), ExpireAt: expireAt})
+			}
+			for i := 0; i < 5; i++ {
+				_, ok := cache.GetItem("high:" + strconv.Itoa(i))
+				assert.True(t, ok, "high:%d", i)
+				_, ok = cache.GetItem("low:" + strconv.Itoa(i))
+				assert.False(t, ok, "low:%d", i)
+			}
+
+			// Once no low priority keys remain, the high priority keys are evicted
+			for i := 0; i < 5; i++ {
+				cache.Add(&gubernator.CacheItem{Key: "higher:" + strconv.Itoa(i), ExpireAt: expireAt, Priority: 20})
+			}
+			for i := 5; i < 10; i++ {
+				_, ok := cache.GetItem("low:" + strconv.Itoa(i))
+				assert.False(t, ok, "low:%d", i)
+			}
+			cache.Add(&gubernator.CacheItem{Key: "higher:5", ExpireAt: expireAt, Priority: 20})
+			assert.Equal(t, int64(10), cache.Size())
+			var high int
+			for i := 0; i < 5; i++ {
+				if _, ok := cache.GetItem("high:" + strconv.Itoa(i)); ok {
+					high++
+				}
+			}
+			assert.Equal(t, 4, high)
+			for i := 0; i < 6; i++ {
+				_, ok := cache.GetItem("higher:" + strconv.Itoa(i))
+				assert.True(t, ok, "higher:%d", i)
+			}
+
+			// Lowering the priority of an item makes it the first to be evicted
+			cache.Add(&gubernator.CacheItem{Key: "higher:0", ExpireAt: expireAt, Priority: 1})
+			cache.Add(&gubernator.CacheItem{Key: "new", ExpireAt: expireAt, Priority: 20})
+			_, ok := cache.GetItem("higher:0")
+			assert.False(t, ok)
+			assert.Equal(t, int64(10), cache.Size())
+		})
+	}
+}
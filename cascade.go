@@ -0,0 +1,88 @@
+/*
+Copyright 2018-2022 Mailgun Technologies Inc
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gubernator
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/mailgun/holster/v4/tracing"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+)
+
+// checkParents returns an InvalidArgument error if any of the parents of a cascading request are invalid
+func (s *V1Instance) checkParents(r *RateLimitReq) error {
+	for _, p := range r.Parents {
+		if len(p.Parents) != 0 {
+			return status.Errorf(codes.InvalidArgument, "parent '%s' of '%s' cannot have parents",
+				p.HashKey(), r.HashKey())
+		}
+		if err := s.checkRequest(s.applyNamespaceDefaults(p)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// getCascadingRateLimit applies the hits of the request to the rate limit and each of its parents only
+// if every one of them would be UNDER_LIMIT, such that a per org limit is only consumed by the requests
+// which pass the per user limit. The response of the rate limit holds the responses of the parents.
+func (s *V1Instance) getCascadingRateLimit(ctx context.Context, r *RateLimitReq) (retval *RateLimitResp, reterr error) {
+	ctx = tracing.StartScope(ctx)
+	defer func() {
+		tracing.EndScope(ctx, reterr)
+	}()
+
+	peek := HasBehavior(r.Behavior, Behavior_PEEK)
+	levels := make([]*RateLimitReq, 0, len(r.Parents)+1)
+	req := proto.Clone(r).(*RateLimitReq)
+	req.Parents = nil
+	levels = append(levels, req)
+	for _, p := range r.Parents {
+		p = proto.Clone(p).(*RateLimitReq)
+		p.Hits = r.Hits
+		p.Cost = r.Cost
+		SetBehavior(&p.Behavior, Behavior_PEEK, peek)
+		levels = append(levels, p)
+	}
+
+	var resp *GetRateLimitsResp
+	var err error
+	if peek {
+		// Nothing is applied, so there is nothing to commit
+		resp, err = s.GetRateLimits(ctx, &GetRateLimitsReq{Requests: levels})
+	} else {
+		resp, err = s.applyAtomic(ctx, levels)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	rl := resp.Responses[0]
+	rl.Parents = resp.Responses[1:]
+	for i, p := range rl.Parents {
+		if p.Status == Status_OVER_LIMIT {
+			rl.Status = Status_OVER_LIMIT
+		}
+		if p.Error != "" && rl.Error == "" {
+			rl.Error = fmt.Sprintf("parent '%s': %s", levels[i+1].HashKey(), p.Error)
+		}
+	}
+	return rl, nil
+}
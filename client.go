@@ -17,15 +17,20 @@ limitations under the License.
 package gubernator
 
 import (
+	"context"
 	"crypto/tls"
 	"math/rand"
+	"sync/atomic"
 	"time"
 
 	"github.com/mailgun/holster/v4/clock"
+	"github.com/mailgun/holster/v4/setter"
 	"github.com/pkg/errors"
 	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/status"
 )
 
 const (
@@ -38,8 +43,167 @@ func (m *RateLimitReq) HashKey() string {
 	return m.Name + "_" + m.UniqueKey
 }
 
+// Allowed returns true if the rate limit response indicates the request should be allowed. A response
+// which contains an error is never allowed, since all other fields of the response should be ignored; callers
+// who wish to fail open when gubernator is unable to answer should check `Error` before calling Allowed().
+func (m *RateLimitResp) Allowed() bool {
+	if m.Error != "" {
+		return false
+	}
+	return m.Status == Status_UNDER_LIMIT
+}
+
+// RetryAfter returns how long the caller should wait before retrying a request which was not allowed.
+// Returns zero if the request was allowed, or if the response contains an error and as such the reset
+// time is unknown.
+func (m *RateLimitResp) RetryAfter() time.Duration {
+	if m.Error != "" || m.Status != Status_OVER_LIMIT {
+		return 0
+	}
+	d := FromUnixMilliseconds(m.ResetTime).Sub(clock.Now())
+	if d < 0 {
+		return 0
+	}
+	return d
+}
+
 // DialV1Server is a convenience function for dialing gubernator instances
 func DialV1Server(server string, tls *tls.Config) (V1Client, error) {
+	conn, err := dial(server, tls)
+	if err != nil {
+		return nil, err
+	}
+	return NewV1Client(conn), nil
+}
+
+// RetryPolicy controls how a client returned by DialV1Servers() retries failed requests
+type RetryPolicy struct {
+	// The maximum number of attempts made for each request including the first attempt. Defaults to 3.
+	MaxAttempts int
+	// How long to wait before the first retry, the wait doubles after each retry. Defaults to 10ms.
+	BaseDelay time.Duration
+	// The maximum amount of time to wait between retries. Defaults to 1s.
+	MaxDelay time.Duration
+}
+
+// DialV1Servers is a convenience function for dialing a list of gubernator instances. Requests are sent to
+// the first server in the list until a request fails with `Unavailable` or `DeadlineExceeded`, at which point
+// the request is retried against the next server in the list according to the RetryPolicy provided. If
+// `policy` is nil the default RetryPolicy is used.
+func DialV1Servers(servers []string, tls *tls.Config, policy *RetryPolicy) (V1Client, error) {
+	if len(servers) == 0 {
+		return nil, errors.New("servers is empty; must provide at least one server")
+	}
+
+	c := &failoverV1Client{}
+	if policy != nil {
+		c.policy = *policy
+	}
+	setter.SetDefault(&c.policy.MaxAttempts, 3)
+	setter.SetDefault(&c.policy.BaseDelay, 10*clock.Millisecond)
+	setter.SetDefault(&c.policy.MaxDelay, clock.Second)
+
+	for _, server := range servers {
+		conn, err := dial(server, tls)
+		if err != nil {
+			return nil, err
+		}
+		c.clients = append(c.clients, NewV1Client(conn))
+	}
+	return c, nil
+}
+
+type failoverV1Client struct {
+	clients []V1Client
+	policy  RetryPolicy
+	current uint32
+}
+
+func (c *failoverV1Client) GetRateLimits(ctx context.Context, in *GetRateLimitsReq, opts ...grpc.CallOption) (*GetRateLimitsResp, error) {
+	var resp *GetRateLimitsResp
+	err := c.retry(ctx, func(client V1Client) (err error) {
+		resp, err = client.GetRateLimits(ctx, in, opts...)
+		return err
+	})
+	return resp, err
+}
+
+func (c *failoverV1Client) AtomicGetRateLimits(ctx context.Context, in *GetRateLimitsReq, opts ...grpc.CallOption) (*GetRateLimitsResp, error) {
+	var resp *GetRateLimitsResp
+	err := c.retry(ctx, func(client V1Client) (err error) {
+		resp, err = client.AtomicGetRateLimits(ctx, in, opts...)
+		return err
+	})
+	return resp, err
+}
+
+func (c *failoverV1Client) HealthCheck(ctx context.Context, in *HealthCheckReq, opts ...grpc.CallOption) (*HealthCheckResp, error) {
+	var resp *HealthCheckResp
+	err := c.retry(ctx, func(client V1Client) (err error) {
+		resp, err = client.HealthCheck(ctx, in, opts...)
+		return err
+	})
+	return resp, err
+}
+
+func (c *failoverV1Client) GetPeerInfo(ctx context.Context, in *GetPeerInfoReq, opts ...grpc.CallOption) (*GetPeerInfoResp, error) {
+	var resp *GetPeerInfoResp
+	err := c.retry(ctx, func(client V1Client) (err error) {
+		resp, err = client.GetPeerInfo(ctx, in, opts...)
+		return err
+	})
+	return resp, err
+}
+
+// retry calls `fn` with the current client, failing over to the next client and
+// retrying with exponential backoff if `fn` returns a transient error.
+func (c *failoverV1Client) retry(ctx context.Context, fn func(V1Client) error) error {
+	delay := c.policy.BaseDelay
+	for attempt := 1; ; attempt++ {
+		current := atomic.LoadUint32(&c.current)
+		err := fn(c.clients[current])
+		if err == nil {
+			return nil
+		}
+
+		switch status.Code(err) {
+		case codes.Unavailable, codes.DeadlineExceeded:
+		default:
+			return err
+		}
+
+		// Send future requests to the next server, unless another request already has
+		atomic.CompareAndSwapUint32(&c.current, current, (current+1)%uint32(len(c.clients)))
+
+		if attempt >= c.policy.MaxAttempts || ctx.Err() != nil {
+			return err
+		}
+
+		timer := clock.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		case <-timer.C():
+		}
+
+		delay *= 2
+		if delay > c.policy.MaxDelay {
+			delay = c.policy.MaxDelay
+		}
+	}
+}
+
+// DialAdminV1Server is a convenience function for dialing the admin service of gubernator instances
+func DialAdminV1Server(server string, tls *tls.Config) (AdminV1Client, error) {
+	conn, err := dial(server, tls)
+	if err != nil {
+		return nil, err
+	}
+	return NewAdminV1Client(conn), nil
+}
+
+func dial(server string, tls *tls.Config) (*grpc.ClientConn, error) {
 	if len(server) == 0 {
 		return nil, errors.New("server is empty; must provide a server")
 	}
@@ -59,8 +223,7 @@ func DialV1Server(server string, tls *tls.Config) (V1Client, error) {
 	if err != nil {
 		return nil, errors.Wrapf(err, "failed to dial server %s", server)
 	}
-
-	return NewV1Client(conn), nil
+	return conn, nil
 }
 
 // ToTimeStamp is a convenience function to convert a time.Duration
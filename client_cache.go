@@ -0,0 +1,197 @@
+/*
+Copyright 2018-2022 Mailgun Technologies Inc
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gubernator
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/mailgun/holster/v4/clock"
+	"github.com/mailgun/holster/v4/setter"
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/proto"
+)
+
+// LocalCacheConfig controls when a client returned by WithLocalCache() answers requests locally
+type LocalCacheConfig struct {
+	// (Optional) Requests are answered locally while the remaining hits known to the client stay at or
+	// above this fraction of the limit. Defaults to 0.5
+	Threshold float64
+
+	// (Optional) The maximum amount of time the client answers requests for a rate limit locally before
+	// syncing with the server. Defaults to 1 second
+	MaxStaleness time.Duration
+}
+
+// WithLocalCache returns a V1Client which remembers the last response from the server for each rate limit
+// and answers GetRateLimits() requests locally while the rate limit is far from the limit. Hits answered
+// locally are sent to the server with the next request for the rate limit which is not answered locally.
+//
+// This trades accuracy for fewer round trips to the server and is intended for hot keys, typically with
+// Behavior_GLOBAL. Hits answered locally are lost if no further requests are made for the rate limit
+// and the remaining hits reported by the server do not account for hits made by other clients until
+// the client syncs. Requests which set `cost`, `parents`, `hits <= 0`, Behavior_PEEK or
+// Behavior_RESET_REMAINING are always sent to the server.
+func WithLocalCache(client V1Client, conf LocalCacheConfig) V1Client {
+	setter.SetDefault(&conf.Threshold, 0.5)
+	setter.SetDefault(&conf.MaxStaleness, clock.Second)
+	return &localCacheV1Client{
+		V1Client: client,
+		conf:     conf,
+		cache:    make(map[string]*localCacheItem),
+	}
+}
+
+type localCacheV1Client struct {
+	V1Client
+	conf  LocalCacheConfig
+	mutex sync.Mutex
+	cache map[string]*localCacheItem
+}
+
+type localCacheItem struct {
+	limit     int64
+	duration  int64
+	remaining int64
+	resetTime int64
+	syncedAt  time.Time
+	config    *RateLimitConfig
+	owner     string
+	// Hits answered locally which have not been sent to the server
+	pending int64
+}
+
+func (c *localCacheV1Client) GetRateLimits(ctx context.Context, in *GetRateLimitsReq, opts ...grpc.CallOption) (*GetRateLimitsResp, error) {
+	resp := &GetRateLimitsResp{Responses: make([]*RateLimitResp, len(in.Requests))}
+	remote := &GetRateLimitsReq{}
+	var idx []int
+
+	c.mutex.Lock()
+	for i, r := range in.Requests {
+		if rl := c.answer(r); rl != nil {
+			resp.Responses[i] = rl
+			continue
+		}
+		// Send any hits we answered locally along with this request
+		if item, ok := c.cache[r.HashKey()]; ok && item.pending != 0 {
+			r = proto.Clone(r).(*RateLimitReq)
+			r.Hits += item.pending
+			item.pending = 0
+		}
+		remote.Requests = append(remote.Requests, r)
+		idx = append(idx, i)
+	}
+	c.mutex.Unlock()
+
+	if len(remote.Requests) == 0 {
+		return resp, nil
+	}
+
+	out, err := c.V1Client.GetRateLimits(ctx, remote, opts...)
+	if err != nil {
+		c.restore(remote, in, idx)
+		return nil, err
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	now := clock.Now()
+	for j, rl := range out.Responses {
+		r := in.Requests[idx[j]]
+		resp.Responses[idx[j]] = rl
+		if !cacheable(r) || rl.Error != "" {
+			delete(c.cache, r.HashKey())
+			continue
+		}
+		item, ok := c.cache[r.HashKey()]
+		if !ok {
+			item = &localCacheItem{}
+			c.cache[r.HashKey()] = item
+		}
+		item.limit = r.Limit
+		item.duration = r.Duration
+		item.remaining = rl.Remaining
+		item.resetTime = rl.ResetTime
+		item.syncedAt = now
+		item.owner = rl.Owner
+		if rl.Config != nil {
+			item.config = rl.Config
+		}
+	}
+	return resp, nil
+}
+
+// answer returns a response for the request if it can be answered locally, else nil.
+// Must be called with the mutex held.
+func (c *localCacheV1Client) answer(r *RateLimitReq) *RateLimitResp {
+	if !cacheable(r) {
+		return nil
+	}
+	item, ok := c.cache[r.HashKey()]
+	if !ok || item.limit != r.Limit || item.duration != r.Duration {
+		return nil
+	}
+
+	now := clock.Now()
+	if now.Sub(item.syncedAt) >= c.conf.MaxStaleness || MillisecondNow() >= item.resetTime {
+		return nil
+	}
+
+	remaining := item.remaining - r.Hits
+	if float64(remaining) < c.conf.Threshold*float64(item.limit) {
+		return nil
+	}
+
+	// The server must provide the config before the client can
+	returnConfig := HasBehavior(r.Behavior, Behavior_RETURN_CONFIG)
+	if returnConfig && item.config == nil {
+		return nil
+	}
+
+	item.remaining = remaining
+	item.pending += r.Hits
+	rl := &RateLimitResp{
+		Status:    Status_UNDER_LIMIT,
+		Limit:     item.limit,
+		Remaining: item.remaining,
+		ResetTime: item.resetTime,
+		Owner:     item.owner,
+	}
+	if returnConfig {
+		rl.Config = item.config
+	}
+	echoMetadata(r, rl)
+	return rl
+}
+
+// restore returns the pending hits taken by requests which failed to reach the server
+func (c *localCacheV1Client) restore(remote, in *GetRateLimitsReq, idx []int) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	for j, r := range remote.Requests {
+		if item, ok := c.cache[r.HashKey()]; ok {
+			item.pending += r.Hits - in.Requests[idx[j]].Hits
+		}
+	}
+}
+
+func cacheable(r *RateLimitReq) bool {
+	return r.Hits > 0 && r.Cost == 0 && len(r.Parents) == 0 &&
+		!HasBehavior(r.Behavior, Behavior_PEEK) &&
+		!HasBehavior(r.Behavior, Behavior_RESET_REMAINING)
+}
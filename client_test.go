@@ -0,0 +1,277 @@
+/*
+Copyright 2018-2022 Mailgun Technologies Inc
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gubernator_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/mailgun/gubernator/v2"
+	"github.com/mailgun/holster/v4/clock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestRateLimitRespAllowed(t *testing.T) {
+	defer clock.Freeze(clock.Now().Truncate(clock.Millisecond)).Unfreeze()
+	now := gubernator.MillisecondNow()
+
+	tests := []struct {
+		Name       string
+		Resp       *gubernator.RateLimitResp
+		Allowed    bool
+		RetryAfter time.Duration
+	}{
+		{
+			Name: "under the limit",
+			Resp: &gubernator.RateLimitResp{
+				Status:    gubernator.Status_UNDER_LIMIT,
+				Remaining: 5,
+				ResetTime: now + 1000,
+			},
+			Allowed:    true,
+			RetryAfter: 0,
+		},
+		{
+			Name: "under the limit with no remaining",
+			Resp: &gubernator.RateLimitResp{
+				Status:    gubernator.Status_UNDER_LIMIT,
+				Remaining: 0,
+				ResetTime: now + 1000,
+			},
+			Allowed:    true,
+			RetryAfter: 0,
+		},
+		{
+			Name: "over the limit",
+			Resp: &gubernator.RateLimitResp{
+				Status:    gubernator.Status_OVER_LIMIT,
+				ResetTime: now + 1500,
+			},
+			Allowed:    false,
+			RetryAfter: 1500 * time.Millisecond,
+		},
+		{
+			Name: "over the limit with reset time in the past",
+			Resp: &gubernator.RateLimitResp{
+				Status:    gubernator.Status_OVER_LIMIT,
+				ResetTime: now - 1000,
+			},
+			Allowed:    false,
+			RetryAfter: 0,
+		},
+		{
+			Name: "error",
+			Resp: &gubernator.RateLimitResp{
+				Status:    gubernator.Status_UNDER_LIMIT,
+				ResetTime: now + 1000,
+				Error:     "field 'namespace' cannot be empty",
+			},
+			Allowed:    false,
+			RetryAfter: 0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.Name, func(t *testing.T) {
+			assert.Equal(t, tt.Allowed, tt.Resp.Allowed())
+			assert.Equal(t, tt.RetryAfter, tt.Resp.RetryAfter())
+		})
+	}
+}
+
+func TestDialV1ServersFailover(t *testing.T) {
+	first := spawnDaemon(t, gubernator.DaemonConfig{
+		GRPCListenAddress: "127.0.0.1:9650",
+		HTTPListenAddress: "127.0.0.1:9651",
+	})
+	defer first.Close()
+	second := spawnDaemon(t, gubernator.DaemonConfig{
+		GRPCListenAddress: "127.0.0.1:9652",
+		HTTPListenAddress: "127.0.0.1:9653",
+	})
+	defer second.Close()
+
+	client, err := gubernator.DialV1Servers([]string{"127.0.0.1:9650", "127.0.0.1:9652"}, nil, nil)
+	require.NoError(t, err)
+
+	sendHit := func(ctx context.Context) (*gubernator.GetRateLimitsResp, error) {
+		return client.GetRateLimits(ctx, &gubernator.GetRateLimitsReq{
+			Requests: []*gubernator.RateLimitReq{
+				{
+					Name:      "test_dial_v1_servers",
+					UniqueKey: "account:1234",
+					Algorithm: gubernator.Algorithm_TOKEN_BUCKET,
+					Duration:  gubernator.Minute,
+					Limit:     10,
+					Hits:      1,
+				},
+			},
+		})
+	}
+
+	// The first server in the list should handle the request
+	resp, err := sendHit(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, int64(9), resp.Responses[0].Remaining)
+
+	// Once the first server goes away the request should fail over to the second
+	first.Close()
+	resp, err = sendHit(context.Background())
+	require.NoError(t, err)
+	assert.Empty(t, resp.Responses[0].Error)
+	assert.Equal(t, int64(9), resp.Responses[0].Remaining)
+
+	// Subsequent requests should remain with the second server
+	resp, err = sendHit(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, int64(8), resp.Responses[0].Remaining)
+
+	// Retries should stop once the context is cancelled
+	second.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 50*clock.Millisecond)
+	defer cancel()
+	client, err = gubernator.DialV1Servers([]string{"127.0.0.1:9650", "127.0.0.1:9652"}, nil,
+		&gubernator.RetryPolicy{MaxAttempts: 100, BaseDelay: clock.Second})
+	require.NoError(t, err)
+
+	start := clock.Now()
+	_, err = sendHit(ctx)
+	require.Error(t, err)
+	assert.Equal(t, codes.Unavailable, status.Code(err))
+	assert.Less(t, clock.Since(start), clock.Second)
+}
+
+// countingV1Client counts the calls to GetRateLimits() which reach the wrapped client
+type countingV1Client struct {
+	gubernator.V1Client
+	calls int
+}
+
+func (c *countingV1Client) GetRateLimits(ctx context.Context, in *gubernator.GetRateLimitsReq, opts ...grpc.CallOption) (*gubernator.GetRateLimitsResp, error) {
+	c.calls++
+	return c.V1Client.GetRateLimits(ctx, in, opts...)
+}
+
+func TestWithLocalCache(t *testing.T) {
+	d := spawnDaemon(t, gubernator.DaemonConfig{
+		GRPCListenAddress: "127.0.0.1:9654",
+		HTTPListenAddress: "127.0.0.1:9655",
+	})
+	defer d.Close()
+
+	server, err := gubernator.DialV1Server(d.Config().GRPCListenAddress, nil)
+	require.NoError(t, err)
+	counter := &countingV1Client{V1Client: server}
+	client := gubernator.WithLocalCache(counter, gubernator.LocalCacheConfig{
+		Threshold:    0.5,
+		MaxStaleness: clock.Minute,
+	})
+
+	req := &gubernator.RateLimitReq{
+		Name:      "test_with_local_cache",
+		UniqueKey: "account:1234",
+		Algorithm: gubernator.Algorithm_TOKEN_BUCKET,
+		Duration:  gubernator.Minute,
+		Limit:     10,
+		Hits:      1,
+	}
+	sendHit := func(r *gubernator.RateLimitReq) *gubernator.RateLimitResp {
+		resp, err := client.GetRateLimits(context.Background(), &gubernator.GetRateLimitsReq{
+			Requests: []*gubernator.RateLimitReq{r},
+		})
+		require.NoError(t, err)
+		require.Empty(t, resp.Responses[0].Error)
+		return resp.Responses[0]
+	}
+
+	// The first request must ask the server
+	rl := sendHit(req)
+	assert.Equal(t, int64(9), rl.Remaining)
+	assert.Equal(t, 1, counter.calls)
+
+	// While far from the limit requests are answered locally
+	for remaining := int64(8); remaining >= 5; remaining-- {
+		rl = sendHit(req)
+		assert.Equal(t, gubernator.Status_UNDER_LIMIT, rl.Status)
+		assert.Equal(t, remaining, rl.Remaining)
+	}
+	assert.Equal(t, 1, counter.calls)
+
+	// Nearing the limit the client syncs with the server, sending the hits answered locally
+	rl = sendHit(req)
+	assert.Equal(t, int64(4), rl.Remaining)
+	assert.Equal(t, 2, counter.calls)
+
+	// Below the threshold every request is sent to the server
+	for remaining := int64(3); remaining >= 0; remaining-- {
+		rl = sendHit(req)
+		assert.Equal(t, remaining, rl.Remaining)
+	}
+	rl = sendHit(req)
+	assert.Equal(t, gubernator.Status_OVER_LIMIT, rl.Status)
+	assert.Equal(t, 7, counter.calls)
+}
+
+func TestWithLocalCacheStaleness(t *testing.T) {
+	defer clock.Freeze(clock.Now()).Unfreeze()
+
+	d := spawnDaemon(t, gubernator.DaemonConfig{
+		GRPCListenAddress: "127.0.0.1:9654",
+		HTTPListenAddress: "127.0.0.1:9655",
+	})
+	defer d.Close()
+
+	server, err := gubernator.DialV1Server(d.Config().GRPCListenAddress, nil)
+	require.NoError(t, err)
+	counter := &countingV1Client{V1Client: server}
+	client := gubernator.WithLocalCache(counter, gubernator.LocalCacheConfig{
+		MaxStaleness: clock.Second,
+	})
+
+	sendHit := func() *gubernator.RateLimitResp {
+		resp, err := client.GetRateLimits(context.Background(), &gubernator.GetRateLimitsReq{
+			Requests: []*gubernator.RateLimitReq{
+				{
+					Name:      "test_with_local_cache_staleness",
+					UniqueKey: "account:1234",
+					Algorithm: gubernator.Algorithm_TOKEN_BUCKET,
+					Duration:  gubernator.Minute,
+					Limit:     100,
+					Hits:      1,
+				},
+			},
+		})
+		require.NoError(t, err)
+		return resp.Responses[0]
+	}
+
+	sendHit()
+	rl := sendHit()
+	assert.Equal(t, int64(98), rl.Remaining)
+	assert.Equal(t, 1, counter.calls)
+
+	// Once the staleness bound passes the client syncs with the server
+	clock.Advance(clock.Second)
+	rl = sendHit()
+	assert.Equal(t, int64(97), rl.Remaining)
+	assert.Equal(t, 2, counter.calls)
+}
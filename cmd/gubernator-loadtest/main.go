@@ -0,0 +1,97 @@
+/*
+Copyright 2018-2022 Mailgun Technologies Inc
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"time"
+
+	guber "github.com/mailgun/gubernator/v2"
+	"github.com/mailgun/gubernator/v2/loadtest"
+	"github.com/sirupsen/logrus"
+)
+
+// Drive a load of rate limit checks against a cluster and report the throughput and latency. Starts an
+// in-process cluster unless the addresses of a running cluster are provided with -e.
+func main() {
+	var (
+		conf      loadtest.Config
+		addresses string
+		algorithm string
+		behaviors string
+		verbose   bool
+	)
+	flag.StringVar(&addresses, "e", "", "Comma separated GRPC addresses of a running cluster (default an in-process cluster)")
+	flag.IntVar(&conf.Peers, "peers", 3, "Instances of the in-process cluster")
+	flag.Float64Var(&conf.QPS, "qps", 0, "Requests per second overall, 0 = as fast as possible")
+	flag.IntVar(&conf.Concurrency, "concurrency", 10, "Concurrent workers")
+	flag.IntVar(&conf.Requests, "requests", 0, "Requests to send, 0 = send requests for -duration")
+	flag.DurationVar(&conf.Duration, "duration", 10*time.Second, "How long to send requests for")
+	flag.IntVar(&conf.Keys, "keys", 1000, "Distinct unique keys to spread the checks across")
+	flag.IntVar(&conf.ChecksPerRequest, "checks", 1, "Rate checks per request")
+	flag.DurationVar(&conf.Timeout, "timeout", time.Second, "Request timeout")
+	flag.StringVar(&algorithm, "algorithm", "TOKEN_BUCKET", "Algorithm of the rate limits, TOKEN_BUCKET, LEAKY_BUCKET or HYBRID")
+	flag.StringVar(&behaviors, "behavior", "", "Comma separated behaviors of the rate limits, IE: GLOBAL,NO_BATCHING")
+	flag.Int64Var(&conf.Limit, "limit", 1000, "Limit of the rate limits")
+	flag.Int64Var(&conf.RateLimitDuration, "rate-limit-duration", guber.Minute, "Duration of the rate limits in milliseconds")
+	flag.Int64Var(&conf.Hits, "hits", 1, "Hits of each rate check")
+	flag.BoolVar(&verbose, "v", false, "Log the activity of the in-process cluster")
+	flag.Parse()
+
+	if !verbose {
+		logrus.SetLevel(logrus.ErrorLevel)
+	}
+
+	a, ok := guber.Algorithm_value[strings.ToUpper(algorithm)]
+	if !ok {
+		checkErr(fmt.Errorf("unknown algorithm '%s'", algorithm))
+	}
+	conf.Algorithm = guber.Algorithm(a)
+	for _, name := range strings.Split(behaviors, ",") {
+		if name == "" {
+			continue
+		}
+		b, ok := guber.Behavior_value[strings.ToUpper(strings.TrimSpace(name))]
+		if !ok {
+			checkErr(fmt.Errorf("unknown behavior '%s'", name))
+		}
+		conf.Behavior |= guber.Behavior(b)
+	}
+	if addresses != "" {
+		conf.Addresses = strings.Split(addresses, ",")
+	}
+
+	// Stop early and report on interrupt
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer cancel()
+
+	res, err := loadtest.Run(ctx, conf)
+	checkErr(err)
+	fmt.Println(res)
+}
+
+func checkErr(err error) {
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
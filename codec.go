@@ -0,0 +1,97 @@
+/*
+Copyright 2018-2022 Mailgun Technologies Inc
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gubernator
+
+import (
+	"fmt"
+
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+// Codec serializes the state of a rate limit for a Store or Loader which persists rate limits outside of the
+// process. Unmarshal restores the concrete type of `CacheItem.Value` from the algorithm of the item, as the
+// algorithms expect a *TokenBucketItem, *LeakyBucketItem or *HybridBucketItem once a rate limit is reloaded from the store.
+type Codec interface {
+	Marshal(item *CacheItem) ([]byte, error)
+	Unmarshal(data []byte) (*CacheItem, error)
+}
+
+// CodecSetter is implemented by a Store or Loader which should serialize rate limits with the codec
+// provided by Config.StoreCodec. SetCodec() is called once when the instance is created.
+type CodecSetter interface {
+	SetCodec(Codec)
+}
+
+// ProtoCodec serializes rate limits as protobuf, which is compact and fast. This is the default codec.
+type ProtoCodec struct{}
+
+// JSONCodec serializes rate limits as JSON, which is larger and slower than ProtoCodec but can be read
+// by a human when debugging the contents of a store.
+type JSONCodec struct{}
+
+var _ Codec = ProtoCodec{}
+var _ Codec = JSONCodec{}
+
+func (ProtoCodec) Marshal(item *CacheItem) ([]byte, error) {
+	ti, err := marshalTransferItem(item)
+	if err != nil {
+		return nil, err
+	}
+	return proto.Marshal(ti)
+}
+
+func (ProtoCodec) Unmarshal(data []byte) (*CacheItem, error) {
+	var ti TransferItem
+	if err := proto.Unmarshal(data, &ti); err != nil {
+		return nil, err
+	}
+	return unmarshalTransferItem(&ti)
+}
+
+func (JSONCodec) Marshal(item *CacheItem) ([]byte, error) {
+	ti, err := marshalTransferItem(item)
+	if err != nil {
+		return nil, err
+	}
+	return protojson.MarshalOptions{UseProtoNames: true}.Marshal(ti)
+}
+
+func (JSONCodec) Unmarshal(data []byte) (*CacheItem, error) {
+	var ti TransferItem
+	if err := protojson.Unmarshal(data, &ti); err != nil {
+		return nil, err
+	}
+	return unmarshalTransferItem(&ti)
+}
+
+// The codecs share the representation used to transfer rate limits between peers
+func marshalTransferItem(item *CacheItem) (*TransferItem, error) {
+	ti := cacheItemToTransfer(item)
+	if ti == nil {
+		return nil, fmt.Errorf("cannot marshal rate limit '%s' with a value of type '%T'", item.Key, item.Value)
+	}
+	return ti, nil
+}
+
+func unmarshalTransferItem(ti *TransferItem) (*CacheItem, error) {
+	item := transferToCacheItem(ti)
+	if item == nil {
+		return nil, fmt.Errorf("cannot unmarshal rate limit '%s' with unknown algorithm '%d'", ti.Key, ti.Algorithm)
+	}
+	return item, nil
+}
@@ -0,0 +1,127 @@
+/*
+Copyright 2018-2022 Mailgun Technologies Inc
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gubernator_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/mailgun/gubernator/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCodec(t *testing.T) {
+	items := []*gubernator.CacheItem{
+		{
+			Algorithm: gubernator.Algorithm_TOKEN_BUCKET,
+			Key:       "test_codec_account:1",
+			ExpireAt:  1_700_000_060_000,
+			InvalidAt: 1_700_000_030_000,
+			Value: &gubernator.TokenBucketItem{
+				Status:      gubernator.Status_OVER_LIMIT,
+				Limit:       10,
+				Duration:    60_000,
+				Remaining:   2.5,
+				CreatedAt:   1_700_000_000_000,
+				OverLimitAt: 1_700_000_001_000,
+				Burst:       20,
+				TotalHits:   8,
+			},
+		},
+		{
+			Algorithm: gubernator.Algorithm_LEAKY_BUCKET,
+			Key:       "test_codec_account:2",
+			ExpireAt:  1_700_000_060_000,
+			Value: &gubernator.LeakyBucketItem{
+				Limit:     10,
+				Duration:  60_000,
+				Remaining: 3.25,
+				UpdatedAt: 1_700_000_000_000,
+				Burst:     15,
+				TotalHits: 7,
+			},
+		},
+		{
+			Algorithm: gubernator.Algorithm_HYBRID,
+			Key:       "test_codec_account:4",
+			ExpireAt:  1_700_000_060_000,
+			Value: &gubernator.HybridBucketItem{
+				Limit:     10,
+				Duration:  60_000,
+				Remaining: 4.75,
+				UpdatedAt: 1_700_000_030_000,
+				CreatedAt: 1_700_000_000_000,
+				Burst:     12,
+				TotalHits: 6,
+			},
+		},
+	}
+
+	for _, test := range []struct {
+		Name  string
+		Codec gubernator.Codec
+	}{
+		{Name: "proto", Codec: gubernator.ProtoCodec{}},
+		{Name: "json", Codec: gubernator.JSONCodec{}},
+	} {
+		t.Run(test.Name, func(t *testing.T) {
+			for _, item := range items {
+				data, err := test.Codec.Marshal(item)
+				require.NoError(t, err)
+
+				restored, err := test.Codec.Unmarshal(data)
+				require.NoError(t, err)
+				assert.IsType(t, item.Value, restored.Value, item.Algorithm)
+				assert.Equal(t, item, restored)
+			}
+
+			_, err := test.Codec.Marshal(&gubernator.CacheItem{Key: "test_codec_account:3", Value: &gubernator.RateLimitResp{}})
+			assert.EqualError(t, err, "cannot marshal rate limit 'test_codec_account:3' with a value of type '*gubernator.RateLimitResp'")
+		})
+	}
+
+	// The JSON names the algorithm, such that it can be read when debugging a store
+	data, err := gubernator.JSONCodec{}.Marshal(items[1])
+	require.NoError(t, err)
+	var doc map[string]interface{}
+	require.NoError(t, json.Unmarshal(data, &doc))
+	assert.Equal(t, "LEAKY_BUCKET", doc["algorithm"])
+	assert.Equal(t, "test_codec_account:2", doc["key"])
+}
+
+// codecStore records the codec provided by Config.StoreCodec
+type codecStore struct {
+	*gubernator.MockStore
+	codec gubernator.Codec
+}
+
+func (s *codecStore) SetCodec(c gubernator.Codec) {
+	s.codec = c
+}
+
+func TestStoreCodec(t *testing.T) {
+	store := &codecStore{MockStore: gubernator.NewMockStore()}
+	srv := newV1Server(t, "", gubernator.Config{Store: store})
+	srv.Close()
+	assert.Equal(t, gubernator.ProtoCodec{}, store.codec)
+
+	store = &codecStore{MockStore: gubernator.NewMockStore()}
+	srv = newV1Server(t, "", gubernator.Config{Store: store, StoreCodec: gubernator.JSONCodec{}})
+	srv.Close()
+	assert.Equal(t, gubernator.JSONCodec{}, store.codec)
+}
@@ -0,0 +1,159 @@
+/*
+Copyright 2018-2022 Mailgun Technologies Inc
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gubernator
+
+import (
+	"compress/flate"
+	"compress/gzip"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// The default minimum size in bytes of an HTTP response before it is compressed
+const defaultCompressMinSize = 1024
+
+// compressHandler compresses the responses of `h` with gzip or deflate when the client accepts it
+// and the response is at least `minSize` bytes. Responses which are already encoded by `h` are
+// passed through unchanged.
+func compressHandler(h http.Handler, minSize int) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Vary", "Accept-Encoding")
+		encoding := acceptedEncoding(r.Header.Get("Accept-Encoding"))
+		if encoding == "" || r.Method == http.MethodHead {
+			h.ServeHTTP(w, r)
+			return
+		}
+
+		cw := &compressWriter{ResponseWriter: w, encoding: encoding, minSize: minSize}
+		defer cw.Close()
+		h.ServeHTTP(cw, r)
+	})
+}
+
+// acceptedEncoding returns the preferred encoding of those accepted by the `Accept-Encoding` header,
+// or an empty string if the client accepts neither gzip nor deflate.
+func acceptedEncoding(header string) string {
+	var deflate bool
+	for _, part := range strings.Split(header, ",") {
+		fields := strings.Split(part, ";")
+		name := strings.ToLower(strings.TrimSpace(fields[0]))
+		if len(fields) > 1 && strings.ReplaceAll(strings.TrimSpace(fields[1]), " ", "") == "q=0" {
+			continue
+		}
+		switch name {
+		case "gzip":
+			return "gzip"
+		case "deflate":
+			deflate = true
+		}
+	}
+	if deflate {
+		return "deflate"
+	}
+	return ""
+}
+
+// compressWriter buffers the response until it reaches `minSize`, at which point the response is
+// compressed. Responses smaller than `minSize` are written uncompressed when the writer is closed.
+type compressWriter struct {
+	http.ResponseWriter
+	encoding string
+	minSize  int
+	status   int
+	buf      []byte
+	// Set once the response is compressed
+	cw io.WriteCloser
+	// Set once the response is written uncompressed
+	passthrough bool
+}
+
+func (c *compressWriter) WriteHeader(status int) {
+	if c.status == 0 {
+		c.status = status
+	}
+}
+
+func (c *compressWriter) Write(p []byte) (int, error) {
+	if c.cw != nil {
+		return c.cw.Write(p)
+	}
+	if c.passthrough {
+		return c.ResponseWriter.Write(p)
+	}
+
+	// The handler encoded the response itself
+	if c.Header().Get("Content-Encoding") != "" {
+		c.writeHeader()
+		c.passthrough = true
+		return c.ResponseWriter.Write(p)
+	}
+
+	c.buf = append(c.buf, p...)
+	if len(c.buf) < c.minSize {
+		return len(p), nil
+	}
+
+	c.Header().Del("Content-Length")
+	c.Header().Set("Content-Encoding", c.encoding)
+	c.writeHeader()
+	if c.encoding == "gzip" {
+		c.cw = gzip.NewWriter(c.ResponseWriter)
+	} else {
+		c.cw, _ = flate.NewWriter(c.ResponseWriter, flate.DefaultCompression)
+	}
+	buf := c.buf
+	c.buf = nil
+	if _, err := c.cw.Write(buf); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// Close completes the compressed response, or writes the buffered response uncompressed
+func (c *compressWriter) Close() {
+	if c.cw != nil {
+		_ = c.cw.Close()
+		return
+	}
+	if c.passthrough {
+		return
+	}
+	c.writeHeader()
+	if len(c.buf) != 0 {
+		_, _ = c.ResponseWriter.Write(c.buf)
+	}
+}
+
+func (c *compressWriter) writeHeader() {
+	if c.status != 0 {
+		c.ResponseWriter.WriteHeader(c.status)
+	}
+}
+
+// Flush sends any buffered data to the client, compressing it if the response is compressed
+func (c *compressWriter) Flush() {
+	if c.cw == nil && !c.passthrough {
+		return
+	}
+	if f, ok := c.cw.(interface{ Flush() error }); ok {
+		_ = f.Flush()
+	}
+	if f, ok := c.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
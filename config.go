@@ -28,18 +28,25 @@ import (
 	"strings"
 	"time"
 
+	"github.com/OneOfOne/xxhash"
 	"github.com/davecgh/go-spew/spew"
 	"github.com/mailgun/holster/v4/clock"
 	"github.com/mailgun/holster/v4/setter"
 	"github.com/mailgun/holster/v4/slice"
 	"github.com/pkg/errors"
+	"github.com/prometheus/common/model"
 	"github.com/segmentio/fasthash/fnv1"
 	"github.com/segmentio/fasthash/fnv1a"
 	"github.com/sirupsen/logrus"
 	etcd "go.etcd.io/etcd/client/v3"
+	"go.opentelemetry.io/otel/trace"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/keepalive"
 )
 
+// The default maximum size of a message received by the GRPC server
+const defaultMaxRecvMsgSize = 1024 * 1024
+
 // BehaviorConfig controls the handling of rate limits in the cluster
 type BehaviorConfig struct {
 	// How long we should wait for a batched response from a peer
@@ -48,6 +55,13 @@ type BehaviorConfig struct {
 	BatchWait time.Duration
 	// The max number of requests we can batch into a single peer request
 	BatchLimit int
+	// How long the owning peer works on a single request of a batch before answering it with a
+	// DeadlineExceeded error, such that a slow rate limit does not hold up the rest of the batch. The
+	// requests handled by the same pool worker as the slow rate limit may time out as well. A request
+	// answered with a DeadlineExceeded error is not applied, a request the pool worker already began to
+	// apply by the deadline is answered once applied instead.
+	// Defaults to 80% of BatchTimeout, leaving the remainder to send the responses.
+	BatchItemTimeout time.Duration
 
 	// How long a non-owning peer should wait before syncing hits to the owning peer
 	GlobalSyncWait time.Duration
@@ -62,6 +76,94 @@ type BehaviorConfig struct {
 	MultiRegionTimeout time.Duration
 	// The max number of requests the current region will collect
 	MultiRegionBatchLimit int
+
+	// How often peers in the local data center are health checked. Peers which fail a health check are
+	// removed from the picker until they pass a health check. If zero, peers are not health checked.
+	PeerHealthCheckInterval time.Duration
+	// How long we should wait for a health check response from a peer
+	PeerHealthCheckTimeout time.Duration
+
+	// How long the owner of a rate limit collects changes before replicating them to its successors,
+	// see Config.ReplicationFactor
+	ReplicationSyncWait time.Duration
+}
+
+// GRPCTransportConfig tunes the GRPC connections between clients and the server, and between peers
+type GRPCTransportConfig struct {
+	// (Optional) The maximum size in bytes of a message received. Raise to accept large batches of rate limits.
+	// The server defaults to 1MB, connections to peers default to the GRPC default of 4MB
+	MaxRecvMsgSize int
+
+	// (Optional) The maximum size in bytes of a message sent. Defaults to the GRPC default, which is unlimited
+	MaxSendMsgSize int
+
+	// (Optional) How long a connection may be idle before it is pinged to check it is still alive. Also the
+	// minimum time the server allows between pings from clients. Defaults to the GRPC defaults.
+	KeepAliveTime time.Duration
+
+	// (Optional) How long to wait for a response to a ping before the connection is closed.
+	// Defaults to the GRPC default of 20 seconds
+	KeepAliveTimeout time.Duration
+
+	// (Optional) When true, connections are pinged even when there are no active requests, which prevents
+	// idle connections from being dropped by intermediaries.
+	KeepAlivePermitWithoutStream bool
+}
+
+// ServerOptions returns the GRPC server options for the transport config. `maxConnectionAge` is included
+// in the keepalive parameters of the server when greater than zero.
+func (c GRPCTransportConfig) ServerOptions(maxConnectionAge time.Duration) []grpc.ServerOption {
+	maxRecv := c.MaxRecvMsgSize
+	if maxRecv == 0 {
+		maxRecv = defaultMaxRecvMsgSize
+	}
+	opts := []grpc.ServerOption{grpc.MaxRecvMsgSize(maxRecv)}
+	if c.MaxSendMsgSize != 0 {
+		opts = append(opts, grpc.MaxSendMsgSize(c.MaxSendMsgSize))
+	}
+
+	params := keepalive.ServerParameters{
+		Time:                  c.KeepAliveTime,
+		Timeout:               c.KeepAliveTimeout,
+		MaxConnectionAge:      maxConnectionAge,
+		MaxConnectionAgeGrace: maxConnectionAge,
+	}
+	if params != (keepalive.ServerParameters{}) {
+		opts = append(opts, grpc.KeepaliveParams(params))
+	}
+
+	// Allow clients to ping as often as we ping them, else the server closes their connections
+	if c.KeepAliveTime != 0 || c.KeepAlivePermitWithoutStream {
+		opts = append(opts, grpc.KeepaliveEnforcementPolicy(keepalive.EnforcementPolicy{
+			MinTime:             c.KeepAliveTime,
+			PermitWithoutStream: c.KeepAlivePermitWithoutStream,
+		}))
+	}
+	return opts
+}
+
+// DialOptions returns the GRPC dial options for the transport config
+func (c GRPCTransportConfig) DialOptions() []grpc.DialOption {
+	var opts []grpc.DialOption
+	var callOpts []grpc.CallOption
+	if c.MaxRecvMsgSize != 0 {
+		callOpts = append(callOpts, grpc.MaxCallRecvMsgSize(c.MaxRecvMsgSize))
+	}
+	if c.MaxSendMsgSize != 0 {
+		callOpts = append(callOpts, grpc.MaxCallSendMsgSize(c.MaxSendMsgSize))
+	}
+	if len(callOpts) != 0 {
+		opts = append(opts, grpc.WithDefaultCallOptions(callOpts...))
+	}
+
+	if c.KeepAliveTime != 0 || c.KeepAlivePermitWithoutStream {
+		opts = append(opts, grpc.WithKeepaliveParams(keepalive.ClientParameters{
+			Time:                c.KeepAliveTime,
+			Timeout:             c.KeepAliveTimeout,
+			PermitWithoutStream: c.KeepAlivePermitWithoutStream,
+		}))
+	}
+	return opts
 }
 
 // Config for a gubernator instance
@@ -69,18 +171,102 @@ type Config struct {
 	// (Required) A list of GRPC servers to register our instance with
 	GRPCServers []*grpc.Server
 
+	// (Optional) A list of GRPC servers to register the privileged AdminV1 service with. This allows the
+	// admin service to be served on a separate listener from the data plane; When provided, the AdminV1
+	// service is NOT registered with `GRPCServers` and the PeersV1 methods which relay AdminV1 requests
+	// are refused by `GRPCServers`. Peers relay those requests to the `PeerInfo.AdminAddress` instead.
+	AdminGRPCServers []*grpc.Server
+
 	// (Optional) Adjust how gubernator behaviors are configured
 	Behaviors BehaviorConfig
 
-	// (Optional) The cache implementation
+	// (Optional) The cache implementation, IE: NewLRUCache(), NewLFUCache() or NewSegmentedLRUCache().
+	// Defaults to NewLRUCache()
 	CacheFactory func(maxSize int) Cache
 
+	// (Optional) The maximum number of rate limits held in the cache, divided evenly between
+	// the caches of each of the `PoolWorkers`. Defaults to 50,000.
+	CacheSize int
+
+	// (Optional) How often each of the `PoolWorkers` removes expired rate limits from its cache, if the cache
+	// implements ExpiringCache. If zero, expired rate limits remain in the cache until they are accessed or
+	// evicted to make room for newer rate limits.
+	CacheExpireInterval time.Duration
+
 	// (Optional) A persistent store implementation. Allows the implementor the ability to store the rate limits this
 	// instance of gubernator owns. It's up to the implementor to decide what rate limits to persist.
 	// For instance an implementor might only persist rate limits that have an expiration of
 	// longer than 1 hour.
 	Store Store
 
+	// (Optional) When set, changes to rate limits are buffered and written to the `Store` asynchronously
+	// on this interval, instead of calling `Store.OnChange()` synchronously on every change. Buffered
+	// changes are flushed to the store when the instance is closed.
+	StoreFlushInterval time.Duration
+
+	// (Optional) The number of changed rate limits buffered before they are flushed to the `Store`
+	// regardless of `StoreFlushInterval`. Ignored unless `StoreFlushInterval` is set. Default is 1000
+	StoreBufferSize int
+
+	// (Optional) The codec provided to a `Store` or `Loader` which implements CodecSetter, such that the
+	// format of persisted rate limits is chosen by configuration rather than by the store. Default is ProtoCodec
+	StoreCodec Codec
+
+	// (Optional) The number of consecutive `Store` calls which fail or exceed `StoreBreakerTimeout` before the
+	// circuit breaker around the store opens. While open the store is not called; rate limits missing from
+	// the cache are handled according to `StoreErrorPolicy` and changes are not written to the store. Only
+	// the errors of a store which implements FallibleStore are known. Defaults to 0, which disables the circuit breaker
+	StoreBreakerThreshold int
+
+	// (Optional) The time a `Store` call may take before it counts as a failure of the store. Calls are made
+	// with a context which expires after this time. Ignored unless `StoreBreakerThreshold` is set.
+	// Default is 500 milliseconds
+	StoreBreakerTimeout time.Duration
+
+	// (Optional) How long the circuit breaker around the `Store` stays open before a single `Store.Get()`
+	// probes whether the store has recovered. Ignored unless `StoreBreakerThreshold` is set. Default is
+	// 10 seconds
+	StoreBreakerCooldown time.Duration
+
+	// (Optional) How a rate limit missing from the cache is handled when the `Store` could not be read,
+	// including while the circuit breaker is open. See StoreErrorNew, StoreErrorFail and
+	// StoreErrorServeCached. Defaults to StoreErrorNew
+	StoreErrorPolicy StoreErrorPolicy
+
+	// (Optional) Chooses between the rate limit the `Store` provides and the one the cache invalidated to
+	// read it from the store, should they differ. See StoreReconcileStore, StoreReconcileNewest and
+	// StoreReconcileConservative. Defaults to StoreReconcileStore
+	StoreReconcilePolicy StoreReconcilePolicy
+
+	// (Optional) When true, the rate limits owned by this instance are handed off to the peers which
+	// will own them once this instance leaves the cluster when the instance is closed. This preserves
+	// the remaining count of rate limits across a rolling restart of the cluster.
+	DrainOnShutdown bool
+
+	// (Optional) The maximum `duration` a request may ask for. GetRateLimits() rejects any batch containing
+	// a request which exceeds it with codes.InvalidArgument, as are requests with a negative `duration`.
+	// Ignored for Behavior_DURATION_IS_GREGORIAN. Defaults to 1000 years, which is also the largest
+	// maximum allowed
+	MaxDuration time.Duration
+
+	// (Optional) The maximum absolute `hits` a request may ask for. GetRateLimits() rejects any batch
+	// containing a request which exceeds it with codes.InvalidArgument. Default is no maximum
+	MaxHits int64
+
+	// (Optional) When true, GetRateLimits() rejects any batch containing a request with an empty `name` or
+	// `unique_key` with codes.InvalidArgument, as does GetPeerRateLimits(). By default only the offending
+	// request fails with an error in its response and the remainder of the batch is applied.
+	StrictKeys bool
+
+	// (Optional) The maximum number of GetRateLimits() requests a single client may make per `AdmissionDuration`.
+	// Requests over the limit are rejected with codes.ResourceExhausted, such that a misbehaving client cannot
+	// overwhelm the instance. Clients are identified by IP address, or by the `x-forwarded-for` address of
+	// requests made through the HTTP gateway. Default is no limit
+	AdmissionLimit int64
+
+	// (Optional) The duration of `AdmissionLimit`. Default is 1 second
+	AdmissionDuration time.Duration
+
 	// (Optional) A loader from a persistent store. Allows the implementor the ability to load and save
 	// the contents of the cache when the gubernator instance is started and stopped
 	Loader Loader
@@ -104,15 +290,114 @@ type Config struct {
 	// (Optional) The TLS config used when connecting to gubernator peers
 	PeerTLS *tls.Config
 
+	// (Optional) The TLS config used when relaying AdminV1 requests to the `PeerInfo.AdminAddress` of
+	// gubernator peers. Defaults to `PeerTLS`
+	AdminPeerTLS *tls.Config
+
+	// (Optional) Tunes the GRPC connections to gubernator peers
+	PeerTransport GRPCTransportConfig
+
+	// (Optional) The OpenTelemetry tracer provider used to trace requests forwarded to peers and calls
+	// to the Store. Defaults to the global tracer provider.
+	TracerProvider trace.TracerProvider
+
 	// (Optional) Number of worker goroutines to launch for request processing in GubernatorPool.
 	// Default is set to number of CPUs.
 	PoolWorkers int
+
+	// (Optional) Default rate limit configs keyed by `RateLimitReq.Name`. Fields which are unset in a
+	// request for a named rate limit are filled in from the default before the request is processed,
+	// allowing clients to provide only the name and unique key. Fields set in the request take precedence.
+	// A field set to its zero value, IE: `Algorithm_TOKEN_BUCKET` over a `LEAKY_BUCKET` default, is only
+	// taken from the request if listed in `RateLimitReq.ExplicitFields`.
+	NamespaceDefaults map[string]*RateLimitReq
+
+	// (Optional) Returns the key used to pick the peer which owns a rate limit, given the hash key of the
+	// rate limit (`RateLimitReq.HashKey()`, IE: `name_unique_key`). Rate limits with the same shard key are
+	// owned by the same peer, IE: return only the name to co-locate all the rate limits of a namespace.
+	// Rate limits are still identified by their hash key. Every peer must use the same function.
+	// Defaults to the hash key.
+	KeyShardFunc func(hashKey string) string
+
+	// (Optional) The number of peers which hold the state of a rate limit, including the owner. When
+	// greater than 1, the owner asynchronously copies changes to the next `ReplicationFactor - 1` peers
+	// on the ring, such that the peer which takes over when the owner leaves already holds recent
+	// state. The LocalPicker must implement SuccessorPicker. Defaults to 1, which disables replication.
+	ReplicationFactor int
+
+	// (Optional) How long the owning peer remembers the response to a request with an `idempotency_key`.
+	// Default is 30 seconds
+	IdempotencyTTL time.Duration
+
+	// (Optional) The delay added to the `suggested_retry_after` of the first rejection of a rate limit with
+	// `Behavior_BACKOFF_HINT`. The delay doubles with each consecutive rejection. Default is 100 milliseconds
+	BackoffHintStep time.Duration
+
+	// (Optional) The maximum delay added to the `suggested_retry_after` of a rejection, regardless of the
+	// number of consecutive rejections. Default is 1 minute
+	BackoffHintMax time.Duration
+
+	// (Optional) The difference between the clock of this instance and that of a peer tolerated before the
+	// `reset_time` of a response forwarded from the owning peer is translated to our clock. The owner reports
+	// `reset_after` from its own clock, which is immune to skew, such that the translated `reset_time` is
+	// as far in the future as the owner intended. Defaults to 100 milliseconds
+	ClockSkewTolerance time.Duration
+
+	// (Optional) Keys of the request `metadata` whose values label the `gubernator_metadata_check_counter`
+	// metric. Requests without the key are labeled with an empty value. Each key must be a valid
+	// prometheus label name other than `status`. Defaults to none, which disables the metric.
+	MetadataLabels []string
+
+	// (Optional) The maximum number of distinct values counted for each of the `MetadataLabels`, guarding
+	// against unbounded metric cardinality. Values seen once the maximum is reached are labeled `other`.
+	// Defaults to 100
+	MetadataLabelValues int
+
+	// (Optional) The names of the rate limits whose OVER_LIMIT responses are counted by the
+	// `gubernator_namespace_over_limit_counter` metric under their own `name` label, the responses of
+	// other names are labeled `other`. Defaults to none, which counts the first `OverLimitNamespaceValues`
+	// names seen under their own label.
+	OverLimitNamespaces []string
+
+	// (Optional) The maximum number of distinct names counted by `gubernator_namespace_over_limit_counter`
+	// when `OverLimitNamespaces` is not set. Names seen once the maximum is reached are labeled `other`.
+	// Defaults to 100
+	OverLimitNamespaceValues int
+
+	// (Optional) Receives a sample of the decisions made for the rate limits in `AuditNamespaces`, for use as
+	// an audit trail. Decisions are recorded by the instance which received the request and handed to the
+	// sink in batches from a background goroutine. Defaults to nil, which disables the audit trail.
+	AuditSink AuditSink
+
+	// (Optional) The names of the rate limits whose decisions are sampled for the `AuditSink`. Decisions
+	// of other names are never recorded. Defaults to none.
+	AuditNamespaces []string
+
+	// (Optional) The fraction of decisions in `AuditNamespaces` which are recorded, between 0 and 1. A rate
+	// of 0.1 records every 10th decision and a rate of 0 records none. Defaults to 1, which records every
+	// decision.
+	AuditSampleRate *float64
+
+	// (Optional) The number of sampled decisions buffered for the `AuditSink`. Decisions sampled while the
+	// buffer is full are dropped and counted by the `gubernator_audit_dropped_count` metric. Default is 1000
+	AuditBufferSize int
+
+	// (Optional) The location in which the intervals of `DURATION_IS_GREGORIAN` rate limits which do not
+	// provide a `timezone` are computed. The name of the location is forwarded to the owning peer, as such
+	// it must be loaded with time.LoadLocation(). Defaults to the local time zone of the owning peer.
+	GregorianLocation *time.Location
+
+	// (Optional) Reject a request for an existing rate limit with another algorithm than the rate limit was
+	// created with, rather than silently replacing the rate limit with a new one of the requested algorithm.
+	// This guarantees the algorithm of a rate limit never changes, such that client bugs are caught.
+	DisableAlgorithmMigration bool
 }
 
 func (c *Config) SetDefaults() error {
 	setter.SetDefault(&c.Behaviors.BatchTimeout, time.Millisecond*500)
 	setter.SetDefault(&c.Behaviors.BatchLimit, maxBatchSize)
 	setter.SetDefault(&c.Behaviors.BatchWait, time.Microsecond*500)
+	setter.SetDefault(&c.Behaviors.BatchItemTimeout, c.Behaviors.BatchTimeout*4/5)
 
 	setter.SetDefault(&c.Behaviors.GlobalTimeout, time.Millisecond*500)
 	setter.SetDefault(&c.Behaviors.GlobalBatchLimit, maxBatchSize)
@@ -122,12 +407,39 @@ func (c *Config) SetDefaults() error {
 	setter.SetDefault(&c.Behaviors.MultiRegionBatchLimit, maxBatchSize)
 	setter.SetDefault(&c.Behaviors.MultiRegionSyncWait, time.Second)
 
+	setter.SetDefault(&c.Behaviors.PeerHealthCheckTimeout, time.Millisecond*500)
+	setter.SetDefault(&c.Behaviors.ReplicationSyncWait, time.Millisecond*100)
+
 	setter.SetDefault(&c.LocalPicker, NewReplicatedConsistentHash(nil, defaultReplicas))
 	setter.SetDefault(&c.RegionPicker, NewRegionPicker(nil))
 
+	setter.SetDefault(&c.StoreBufferSize, 1000)
+	setter.SetDefault(&c.StoreCodec, Codec(ProtoCodec{}))
+	setter.SetDefault(&c.StoreBreakerTimeout, time.Millisecond*500)
+	setter.SetDefault(&c.StoreBreakerCooldown, time.Second*10)
+	setter.SetDefault(&c.AdmissionDuration, time.Second)
+	setter.SetDefault(&c.IdempotencyTTL, time.Second*30)
+	setter.SetDefault(&c.BackoffHintStep, time.Millisecond*100)
+	setter.SetDefault(&c.BackoffHintMax, time.Minute)
+	setter.SetDefault(&c.ClockSkewTolerance, time.Millisecond*100)
+	setter.SetDefault(&c.MetadataLabelValues, 100)
+	setter.SetDefault(&c.OverLimitNamespaceValues, 100)
+	// Copied, such that an explicit rate of 0 is kept and our caller may change theirs
+	auditSampleRate := 1.0
+	if c.AuditSampleRate != nil {
+		auditSampleRate = *c.AuditSampleRate
+	}
+	c.AuditSampleRate = &auditSampleRate
+	setter.SetDefault(&c.AuditBufferSize, 1000)
+
 	numCpus := runtime.NumCPU()
 	setter.SetDefault(&c.PoolWorkers, numCpus)
 
+	if c.CacheSize < 0 {
+		return fmt.Errorf("CacheSize cannot be negative; got '%d'", c.CacheSize)
+	}
+	setter.SetDefault(&c.CacheSize, 50_000)
+
 	if c.CacheFactory == nil {
 		c.CacheFactory = func(maxSize int) Cache {
 			return NewLRUCache(maxSize)
@@ -138,10 +450,29 @@ func (c *Config) SetDefaults() error {
 		return fmt.Errorf("Behaviors.BatchLimit cannot exceed '%d'", maxBatchSize)
 	}
 
+	for name, d := range c.NamespaceDefaults {
+		if d == nil {
+			return fmt.Errorf("NamespaceDefaults['%s'] cannot be nil", name)
+		}
+	}
+
+	for _, key := range c.MetadataLabels {
+		if !model.LabelName(key).IsValid() || key == "status" {
+			return fmt.Errorf("MetadataLabels cannot contain '%s'; must be a valid label name other than 'status'", key)
+		}
+	}
+
+	if *c.AuditSampleRate < 0 || *c.AuditSampleRate > 1 {
+		return fmt.Errorf("AuditSampleRate must be between 0 and 1; got '%g'", *c.AuditSampleRate)
+	}
+
 	// Make a copy of the TLS config in case our caller decides to make changes
 	if c.PeerTLS != nil {
 		c.PeerTLS = c.PeerTLS.Clone()
 	}
+	if c.AdminPeerTLS != nil {
+		c.AdminPeerTLS = c.AdminPeerTLS.Clone()
+	}
 
 	return nil
 }
@@ -153,8 +484,14 @@ type PeerInfo struct {
 	HTTPAddress string `json:"http-address"`
 	// (Required) The grpc address:port of the peer
 	GRPCAddress string `json:"grpc-address"`
+	// (Optional) The grpc address:port of the admin listener of the peer. If provided, AdminV1 requests
+	// are relayed to the peer over this address rather than `GRPCAddress`
+	AdminAddress string `json:"admin-address,omitempty"`
 	// (Optional) Is true if PeerInfo is for this instance of gubernator
 	IsOwner bool `json:"is-owner,omitempty"`
+	// (Optional) The relative share of rate limits assigned to this peer by the picker. A peer with a weight
+	// of 3 is assigned three times as many rate limits as a peer with a weight of 1. Defaults to 1.
+	Weight int `json:"weight,omitempty"`
 }
 
 // HashKey returns the hash key used to identify this peer in the Picker.
@@ -162,6 +499,14 @@ func (p PeerInfo) HashKey() string {
 	return p.GRPCAddress
 }
 
+// weight returns the weight of the peer in the Picker, which is at least 1
+func (p PeerInfo) weight() int {
+	if p.Weight < 1 {
+		return 1
+	}
+	return p.Weight
+}
+
 type UpdateFunc func([]PeerInfo)
 
 var DebugEnabled = false
@@ -180,6 +525,35 @@ type DaemonConfig struct {
 	// provide client certificate but you want to enforce mTLS in other RPCs (like in K8s)
 	HTTPStatusListenAddress string
 
+	// (Optional) The `address:port` that will accept GRPC requests for the privileged AdminV1 service.
+	// When provided, the AdminV1 service is only served on this address and not on `GRPCListenAddress`
+	AdminGRPCListenAddress string
+
+	// (Optional) The `address:port` of the admin listener that is advertised to other Gubernator peers.
+	// Defaults to `AdminGRPCListenAddress`
+	AdminAdvertiseAddress string
+
+	// (Optional) The TLS config used by the `AdminGRPCListenAddress` listener. If not provided, the
+	// admin listener uses the same TLS config as `GRPCListenAddress`
+	AdminTLS *TLSConfig
+
+	// (Optional) Registers the GRPC reflection service, such that tools like grpcurl can
+	// discover the API without the .proto files. Defaults to false
+	EnableReflection bool
+
+	// (Optional) Tunes the GRPC server and the connections to gubernator peers
+	GRPCTransport GRPCTransportConfig
+
+	// (Optional) The maximum amount of time Close() waits for in-flight requests to complete before the
+	// remaining requests are cancelled. Pending changes are flushed to the store regardless. If zero,
+	// Close() waits for all in-flight requests to complete.
+	ShutdownTimeout time.Duration
+
+	// (Optional) The minimum size in bytes of an HTTP response before it is compressed for clients which
+	// send `Accept-Encoding: gzip` or `deflate`. Set to a negative value to disable compression.
+	// Defaults to 1024
+	HTTPCompressMinSize int
+
 	// (Optional) Defines the max age connection from client in seconds.
 	// Default is infinity
 	GRPCMaxConnectionAgeSeconds int
@@ -191,6 +565,87 @@ type DaemonConfig struct {
 	// (Optional) The number of items in the cache. Defaults to 50,000
 	CacheSize int
 
+	// (Optional) The eviction policy of the cache. Valid options are [lru, lfu, segmented-lru]
+	// (Defaults to 'lru'), see NewLRUCache(), NewLFUCache() and NewSegmentedLRUCache()
+	CacheType string
+
+	// (Optional) How often expired rate limits are removed from the cache. If zero, expired
+	// rate limits are removed when accessed or evicted.
+	CacheExpireInterval time.Duration
+
+	// (Optional) Hand off the rate limits owned by this instance to the remaining peers on shutdown
+	DrainOnShutdown bool
+
+	// (Optional) The maximum `duration` a request may ask for, see Config.MaxDuration
+	MaxDuration time.Duration
+
+	// (Optional) The maximum absolute `hits` a request may ask for, see Config.MaxHits
+	MaxHits int64
+
+	// (Optional) Reject batches containing a request with an empty name or unique key, see Config.StrictKeys
+	StrictKeys bool
+
+	// (Optional) The maximum number of requests a single client may make per `AdmissionDuration`,
+	// see Config.AdmissionLimit
+	AdmissionLimit int64
+
+	// (Optional) The duration of `AdmissionLimit`, see Config.AdmissionDuration
+	AdmissionDuration time.Duration
+
+	// (Optional) Default rate limit configs keyed by name, see Config.NamespaceDefaults
+	NamespaceDefaults map[string]*RateLimitReq
+
+	// (Optional) Returns the key used to pick the peer which owns a rate limit, see Config.KeyShardFunc
+	KeyShardFunc func(hashKey string) string
+
+	// (Optional) The number of peers which hold the state of a rate limit, see Config.ReplicationFactor
+	ReplicationFactor int
+
+	// (Optional) How long the response to a request with an `idempotency_key` is remembered, see
+	// Config.IdempotencyTTL
+	IdempotencyTTL time.Duration
+
+	// (Optional) The delay added to the suggested retry of the first rejection, see Config.BackoffHintStep
+	BackoffHintStep time.Duration
+
+	// (Optional) The maximum delay added to the suggested retry, see Config.BackoffHintMax
+	BackoffHintMax time.Duration
+
+	// (Optional) The clock difference tolerated between peers, see Config.ClockSkewTolerance
+	ClockSkewTolerance time.Duration
+
+	// (Optional) Keys of the request metadata used as metric labels, see Config.MetadataLabels
+	MetadataLabels []string
+
+	// (Optional) The maximum distinct values of each metadata label, see Config.MetadataLabelValues
+	MetadataLabelValues int
+
+	// (Optional) Names counted by the over limit metric, see Config.OverLimitNamespaces
+	OverLimitNamespaces []string
+
+	// (Optional) The maximum distinct names of the over limit metric, see Config.OverLimitNamespaceValues
+	OverLimitNamespaceValues int
+
+	// (Optional) Receives the sampled decisions of the `AuditNamespaces`, see Config.AuditSink
+	AuditSink AuditSink
+
+	// (Optional) The path of a file to which the sampled decisions are appended as lines of JSON when
+	// `AuditSink` is not provided
+	AuditFile string
+
+	// (Optional) Names of the rate limits whose decisions are sampled, see Config.AuditNamespaces
+	AuditNamespaces []string
+
+	// (Optional) The fraction of decisions which are sampled, see Config.AuditSampleRate
+	AuditSampleRate *float64
+
+	// (Optional) The location of gregorian intervals, see Config.GregorianLocation
+	GregorianLocation *time.Location
+
+	// (Optional) Reject requests which would change the algorithm of a rate limit, see
+	// Config.DisableAlgorithmMigration
+	DisableAlgorithmMigration bool
+
 	// (Optional) Configure how behaviours behave
 	Behaviors BehaviorConfig
 
@@ -220,6 +675,10 @@ type DaemonConfig struct {
 	// (Optional) A Logger which implements the declared logger interface (typically *logrus.Entry)
 	Logger FieldLogger
 
+	// (Optional) The OpenTelemetry tracer provider used to trace gRPC requests, requests forwarded
+	// to peers and calls to the Store. Defaults to the global tracer provider.
+	TracerProvider trace.TracerProvider
+
 	// (Optional) TLS Configuration; SpawnDaemon() will modify the passed TLS config in an
 	// attempt to build a complete TLS config if one is not provided.
 	TLS *TLSConfig
@@ -291,7 +750,54 @@ func SetupDaemonConfig(logger *logrus.Logger, configFile string) (DaemonConfig,
 	setter.SetDefault(&conf.HTTPListenAddress, os.Getenv("GUBER_HTTP_ADDRESS"), "localhost:80")
 	setter.SetDefault(&conf.HTTPStatusListenAddress, os.Getenv("GUBER_STATUS_HTTP_ADDRESS"), "")
 	setter.SetDefault(&conf.GRPCMaxConnectionAgeSeconds, getEnvInteger(log, "GUBER_GRPC_MAX_CONN_AGE_SEC"), 0)
+	setter.SetDefault(&conf.HTTPCompressMinSize, getEnvInteger(log, "GUBER_HTTP_COMPRESS_MIN_SIZE"))
+	setter.SetDefault(&conf.GRPCTransport.MaxRecvMsgSize, getEnvInteger(log, "GUBER_GRPC_MAX_RECV_MSG_SIZE"))
+	setter.SetDefault(&conf.GRPCTransport.MaxSendMsgSize, getEnvInteger(log, "GUBER_GRPC_MAX_SEND_MSG_SIZE"))
+	setter.SetDefault(&conf.GRPCTransport.KeepAliveTime, getEnvDuration(log, "GUBER_GRPC_KEEPALIVE_TIME"))
+	setter.SetDefault(&conf.GRPCTransport.KeepAliveTimeout, getEnvDuration(log, "GUBER_GRPC_KEEPALIVE_TIMEOUT"))
+	setter.SetDefault(&conf.GRPCTransport.KeepAlivePermitWithoutStream,
+		getEnvBool(log, "GUBER_GRPC_KEEPALIVE_PERMIT_WITHOUT_STREAM"))
 	setter.SetDefault(&conf.CacheSize, getEnvInteger(log, "GUBER_CACHE_SIZE"), 50_000)
+	setter.SetDefault(&conf.CacheExpireInterval, getEnvDuration(log, "GUBER_CACHE_EXPIRE_INTERVAL"))
+	cacheTypes := []string{"lru", "lfu", "segmented-lru"}
+	setter.SetDefault(&conf.CacheType, os.Getenv("GUBER_CACHE_TYPE"), "lru")
+	if !slice.ContainsString(conf.CacheType, cacheTypes, nil) {
+		return conf, fmt.Errorf("GUBER_CACHE_TYPE is invalid; choices are [%s]", strings.Join(cacheTypes, ","))
+	}
+	setter.SetDefault(&conf.DrainOnShutdown, getEnvBool(log, "GUBER_DRAIN_ON_SHUTDOWN"))
+	setter.SetDefault(&conf.ShutdownTimeout, getEnvDuration(log, "GUBER_SHUTDOWN_TIMEOUT"))
+	setter.SetDefault(&conf.EnableReflection, getEnvBool(log, "GUBER_GRPC_REFLECTION"))
+	setter.SetDefault(&conf.MaxDuration, getEnvDuration(log, "GUBER_MAX_DURATION"))
+	setter.SetDefault(&conf.MaxHits, int64(getEnvInteger(log, "GUBER_MAX_HITS")))
+	setter.SetDefault(&conf.StrictKeys, getEnvBool(log, "GUBER_STRICT_KEYS"))
+	setter.SetDefault(&conf.AdmissionLimit, int64(getEnvInteger(log, "GUBER_ADMISSION_LIMIT")))
+	setter.SetDefault(&conf.AdmissionDuration, getEnvDuration(log, "GUBER_ADMISSION_DURATION"))
+	setter.SetDefault(&conf.ReplicationFactor, getEnvInteger(log, "GUBER_REPLICATION_FACTOR"))
+	setter.SetDefault(&conf.IdempotencyTTL, getEnvDuration(log, "GUBER_IDEMPOTENCY_TTL"))
+	setter.SetDefault(&conf.BackoffHintStep, getEnvDuration(log, "GUBER_BACKOFF_HINT_STEP"))
+	setter.SetDefault(&conf.BackoffHintMax, getEnvDuration(log, "GUBER_BACKOFF_HINT_MAX"))
+	setter.SetDefault(&conf.ClockSkewTolerance, getEnvDuration(log, "GUBER_CLOCK_SKEW_TOLERANCE"))
+	setter.SetDefault(&conf.MetadataLabels, getEnvSlice("GUBER_METADATA_LABELS"))
+	setter.SetDefault(&conf.MetadataLabelValues, getEnvInteger(log, "GUBER_METADATA_LABEL_VALUES"))
+	setter.SetDefault(&conf.OverLimitNamespaces, getEnvSlice("GUBER_OVER_LIMIT_NAMESPACES"))
+	setter.SetDefault(&conf.OverLimitNamespaceValues, getEnvInteger(log, "GUBER_OVER_LIMIT_NAMESPACE_VALUES"))
+	setter.SetDefault(&conf.DisableAlgorithmMigration, getEnvBool(log, "GUBER_DISABLE_ALGORITHM_MIGRATION"))
+	setter.SetDefault(&conf.AuditFile, os.Getenv("GUBER_AUDIT_FILE"))
+	setter.SetDefault(&conf.AuditNamespaces, getEnvSlice("GUBER_AUDIT_NAMESPACES"))
+	if v := os.Getenv("GUBER_AUDIT_SAMPLE_RATE"); v != "" && conf.AuditSampleRate == nil {
+		rate, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			return conf, errors.Wrap(err, "GUBER_AUDIT_SAMPLE_RATE is invalid; expected a number between 0 and 1")
+		}
+		conf.AuditSampleRate = &rate
+	}
+	if tz := os.Getenv("GUBER_GREGORIAN_TIMEZONE"); tz != "" && conf.GregorianLocation == nil {
+		loc, err := time.LoadLocation(tz)
+		if err != nil {
+			return conf, errors.Wrap(err, "GUBER_GREGORIAN_TIMEZONE is invalid; expected an IANA time zone")
+		}
+		conf.GregorianLocation = loc
+	}
 	setter.SetDefault(&conf.AdvertiseAddress, os.Getenv("GUBER_ADVERTISE_ADDRESS"), conf.GRPCListenAddress)
 	setter.SetDefault(&conf.DataCenter, os.Getenv("GUBER_DATA_CENTER"), "")
 	setter.SetDefault(&conf.MetricFlags, getEnvMetricFlags(log, "GUBER_METRIC_FLAGS"))
@@ -319,6 +825,7 @@ func SetupDaemonConfig(logger *logrus.Logger, configFile string) (DaemonConfig,
 	setter.SetDefault(&conf.Behaviors.BatchTimeout, getEnvDuration(log, "GUBER_BATCH_TIMEOUT"))
 	setter.SetDefault(&conf.Behaviors.BatchLimit, getEnvInteger(log, "GUBER_BATCH_LIMIT"))
 	setter.SetDefault(&conf.Behaviors.BatchWait, getEnvDuration(log, "GUBER_BATCH_WAIT"))
+	setter.SetDefault(&conf.Behaviors.BatchItemTimeout, getEnvDuration(log, "GUBER_BATCH_ITEM_TIMEOUT"))
 
 	setter.SetDefault(&conf.Behaviors.GlobalTimeout, getEnvDuration(log, "GUBER_GLOBAL_TIMEOUT"))
 	setter.SetDefault(&conf.Behaviors.GlobalBatchLimit, getEnvInteger(log, "GUBER_GLOBAL_BATCH_LIMIT"))
@@ -328,35 +835,34 @@ func SetupDaemonConfig(logger *logrus.Logger, configFile string) (DaemonConfig,
 	setter.SetDefault(&conf.Behaviors.MultiRegionBatchLimit, getEnvInteger(log, "GUBER_MULTI_REGION_BATCH_LIMIT"))
 	setter.SetDefault(&conf.Behaviors.MultiRegionSyncWait, getEnvDuration(log, "GUBER_MULTI_REGION_SYNC_WAIT"))
 
+	setter.SetDefault(&conf.Behaviors.PeerHealthCheckInterval, getEnvDuration(log, "GUBER_PEER_HEALTH_CHECK_INTERVAL"))
+	setter.SetDefault(&conf.Behaviors.PeerHealthCheckTimeout, getEnvDuration(log, "GUBER_PEER_HEALTH_CHECK_TIMEOUT"))
+
+	setter.SetDefault(&conf.Behaviors.ReplicationSyncWait, getEnvDuration(log, "GUBER_REPLICATION_SYNC_WAIT"))
+
 	// TLS Config
-	if anyHasPrefix("GUBER_TLS_", os.Environ()) {
-		conf.TLS = &TLSConfig{}
-		setter.SetDefault(&conf.TLS.CaFile, os.Getenv("GUBER_TLS_CA"))
-		setter.SetDefault(&conf.TLS.CaKeyFile, os.Getenv("GUBER_TLS_CA_KEY"))
-		setter.SetDefault(&conf.TLS.KeyFile, os.Getenv("GUBER_TLS_KEY"))
-		setter.SetDefault(&conf.TLS.CertFile, os.Getenv("GUBER_TLS_CERT"))
-		setter.SetDefault(&conf.TLS.AutoTLS, getEnvBool(log, "GUBER_TLS_AUTO"))
-
-		clientAuth := os.Getenv("GUBER_TLS_CLIENT_AUTH")
-		if clientAuth != "" {
-			clientAuthTypes := map[string]tls.ClientAuthType{
-				"request-cert":       tls.RequestClientCert,
-				"verify-cert":        tls.VerifyClientCertIfGiven,
-				"require-any-cert":   tls.RequireAnyClientCert,
-				"require-and-verify": tls.RequireAndVerifyClientCert,
-			}
-			t, ok := clientAuthTypes[clientAuth]
-			if !ok {
-				return conf, errors.Errorf("'GUBER_TLS_CLIENT_AUTH=%s' is invalid; choices are [%s]",
-					clientAuth, validClientAuthTypes(clientAuthTypes))
-			}
-			conf.TLS.ClientAuth = t
+	conf.TLS, err = getEnvTLSConfig(log, "GUBER_TLS_")
+	if err != nil {
+		return conf, err
+	}
+
+	// Admin Config
+	setter.SetDefault(&conf.AdminGRPCListenAddress, os.Getenv("GUBER_ADMIN_GRPC_ADDRESS"), "")
+	setter.SetDefault(&conf.AdminAdvertiseAddress, os.Getenv("GUBER_ADMIN_ADVERTISE_ADDRESS"), conf.AdminGRPCListenAddress)
+	if conf.AdminAdvertiseAddress != "" && conf.PeerDiscoveryType != "k8s" {
+		adminAddr, adminPort, err := net.SplitHostPort(conf.AdminAdvertiseAddress)
+		if err != nil {
+			return conf, errors.Wrap(err, "GUBER_ADMIN_ADVERTISE_ADDRESS is invalid; expected format is `address:port`")
+		}
+		adminAddr, err = ResolveHostIP(adminAddr)
+		if err != nil {
+			return conf, errors.Wrap(err, "failed to discover host ip for GUBER_ADMIN_ADVERTISE_ADDRESS")
 		}
-		setter.SetDefault(&conf.TLS.ClientAuthKeyFile, os.Getenv("GUBER_TLS_CLIENT_AUTH_KEY"))
-		setter.SetDefault(&conf.TLS.ClientAuthCertFile, os.Getenv("GUBER_TLS_CLIENT_AUTH_CERT"))
-		setter.SetDefault(&conf.TLS.ClientAuthCaFile, os.Getenv("GUBER_TLS_CLIENT_AUTH_CA_CERT"))
-		setter.SetDefault(&conf.TLS.InsecureSkipVerify, getEnvBool(log, "GUBER_TLS_INSECURE_SKIP_VERIFY"))
-		setter.SetDefault(&conf.TLS.ClientAuthServerName, os.Getenv("GUBER_TLS_CLIENT_AUTH_SERVER_NAME"))
+		conf.AdminAdvertiseAddress = net.JoinHostPort(adminAddr, adminPort)
+	}
+	conf.AdminTLS, err = getEnvTLSConfig(log, "GUBER_ADMIN_TLS_")
+	if err != nil {
+		return conf, err
 	}
 
 	// ETCD Config
@@ -368,11 +874,15 @@ func SetupDaemonConfig(logger *logrus.Logger, configFile string) (DaemonConfig,
 	setter.SetDefault(&conf.EtcdPoolConf.EtcdConfig.Password, os.Getenv("GUBER_ETCD_PASSWORD"))
 	setter.SetDefault(&conf.EtcdPoolConf.Advertise.GRPCAddress, os.Getenv("GUBER_ETCD_ADVERTISE_ADDRESS"), conf.AdvertiseAddress)
 	setter.SetDefault(&conf.EtcdPoolConf.Advertise.DataCenter, os.Getenv("GUBER_ETCD_DATA_CENTER"), conf.DataCenter)
+	setter.SetDefault(&conf.EtcdPoolConf.Advertise.AdminAddress, conf.AdminAdvertiseAddress)
+	setter.SetDefault(&conf.EtcdPoolConf.Advertise.Weight, getEnvInteger(log, "GUBER_PEER_WEIGHT"))
 
 	setter.SetDefault(&conf.MemberListPoolConf.Advertise.GRPCAddress, os.Getenv("GUBER_MEMBERLIST_ADVERTISE_ADDRESS"), conf.AdvertiseAddress)
 	setter.SetDefault(&conf.MemberListPoolConf.MemberListAddress, os.Getenv("GUBER_MEMBERLIST_ADDRESS"), fmt.Sprintf("%s:7946", advAddr))
 	setter.SetDefault(&conf.MemberListPoolConf.KnownNodes, getEnvSlice("GUBER_MEMBERLIST_KNOWN_NODES"), []string{})
 	setter.SetDefault(&conf.MemberListPoolConf.Advertise.DataCenter, conf.DataCenter)
+	setter.SetDefault(&conf.MemberListPoolConf.Advertise.AdminAddress, conf.AdminAdvertiseAddress)
+	setter.SetDefault(&conf.MemberListPoolConf.Advertise.Weight, getEnvInteger(log, "GUBER_PEER_WEIGHT"))
 
 	// Kubernetes Config
 	setter.SetDefault(&conf.K8PoolConf.Namespace, os.Getenv("GUBER_K8S_NAMESPACE"), "default")
@@ -402,8 +912,9 @@ func SetupDaemonConfig(logger *logrus.Logger, configFile string) (DaemonConfig,
 			conf.Picker = NewReplicatedConsistentHash(nil, replicas)
 			setter.SetDefault(&hash, os.Getenv("GUBER_PEER_PICKER_HASH"), "fnv1a")
 			hashFuncs := map[string]HashString64{
-				"fnv1a": fnv1a.HashString64,
-				"fnv1":  fnv1.HashString64,
+				"fnv1a":  fnv1a.HashString64,
+				"fnv1":   fnv1.HashString64,
+				"xxhash": xxhash.ChecksumString64,
 			}
 			fn, ok := hashFuncs[hash]
 			if !ok {
@@ -411,8 +922,12 @@ func SetupDaemonConfig(logger *logrus.Logger, configFile string) (DaemonConfig,
 					hash, validHash64Keys(hashFuncs))
 			}
 			conf.Picker = NewReplicatedConsistentHash(fn, replicas)
+		case "bounded-hash":
+			var factor float64
+			setter.SetDefault(&factor, getEnvFloat(log, "GUBER_BOUNDED_HASH_FACTOR"), defaultBoundedHashFactor)
+			conf.Picker = NewBoundedHash(factor)
 		default:
-			return conf, errors.Errorf("'GUBER_PEER_PICKER=%s' is invalid; choices are ['replicated-hash', 'consistent-hash']", pp)
+			return conf, errors.Errorf("'GUBER_PEER_PICKER=%s' is invalid; choices are ['replicated-hash', 'bounded-hash']", pp)
 		}
 	}
 
@@ -495,6 +1010,43 @@ func setupEtcdTLS(conf *etcd.Config) error {
 	return nil
 }
 
+// getEnvTLSConfig returns a TLSConfig as configured by environment variables with the provided prefix,
+// or nil if no environment variables with the prefix are set.
+func getEnvTLSConfig(log logrus.FieldLogger, prefix string) (*TLSConfig, error) {
+	if !anyHasPrefix(prefix, os.Environ()) {
+		return nil, nil
+	}
+
+	conf := &TLSConfig{}
+	setter.SetDefault(&conf.CaFile, os.Getenv(prefix+"CA"))
+	setter.SetDefault(&conf.CaKeyFile, os.Getenv(prefix+"CA_KEY"))
+	setter.SetDefault(&conf.KeyFile, os.Getenv(prefix+"KEY"))
+	setter.SetDefault(&conf.CertFile, os.Getenv(prefix+"CERT"))
+	setter.SetDefault(&conf.AutoTLS, getEnvBool(log, prefix+"AUTO"))
+
+	clientAuth := os.Getenv(prefix + "CLIENT_AUTH")
+	if clientAuth != "" {
+		clientAuthTypes := map[string]tls.ClientAuthType{
+			"request-cert":       tls.RequestClientCert,
+			"verify-cert":        tls.VerifyClientCertIfGiven,
+			"require-any-cert":   tls.RequireAnyClientCert,
+			"require-and-verify": tls.RequireAndVerifyClientCert,
+		}
+		auth, ok := clientAuthTypes[clientAuth]
+		if !ok {
+			return nil, errors.Errorf("'%sCLIENT_AUTH=%s' is invalid; choices are [%s]",
+				prefix, clientAuth, validClientAuthTypes(clientAuthTypes))
+		}
+		conf.ClientAuth = auth
+	}
+	setter.SetDefault(&conf.ClientAuthKeyFile, os.Getenv(prefix+"CLIENT_AUTH_KEY"))
+	setter.SetDefault(&conf.ClientAuthCertFile, os.Getenv(prefix+"CLIENT_AUTH_CERT"))
+	setter.SetDefault(&conf.ClientAuthCaFile, os.Getenv(prefix+"CLIENT_AUTH_CA_CERT"))
+	setter.SetDefault(&conf.InsecureSkipVerify, getEnvBool(log, prefix+"INSECURE_SKIP_VERIFY"))
+	setter.SetDefault(&conf.ClientAuthServerName, os.Getenv(prefix+"CLIENT_AUTH_SERVER_NAME"))
+	return conf, nil
+}
+
 func anyHasPrefix(prefix string, items []string) bool {
 	for _, i := range items {
 		if strings.HasPrefix(i, prefix) {
@@ -530,6 +1082,19 @@ func getEnvInteger(log logrus.FieldLogger, name string) int {
 	return int(i)
 }
 
+func getEnvFloat(log logrus.FieldLogger, name string) float64 {
+	v := os.Getenv(name)
+	if v == "" {
+		return 0
+	}
+	f, err := strconv.ParseFloat(v, 64)
+	if err != nil {
+		log.WithError(err).Errorf("while parsing '%s' as a float", name)
+		return 0
+	}
+	return f
+}
+
 func getEnvDuration(log logrus.FieldLogger, name string) time.Duration {
 	v := os.Getenv(name)
 	if v == "" {
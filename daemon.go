@@ -22,7 +22,9 @@ import (
 	"log"
 	"net"
 	"net/http"
+	"os"
 	"strings"
+	"sync/atomic"
 	"time"
 
 	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
@@ -38,13 +40,16 @@ import (
 	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/credentials"
-	"google.golang.org/grpc/keepalive"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/reflection"
 	"google.golang.org/protobuf/encoding/protojson"
 )
 
 type Daemon struct {
 	GRPCListeners []net.Listener
 	HTTPListener  net.Listener
+	AdminListener net.Listener
 	V1Server      *V1Instance
 
 	log           FieldLogger
@@ -53,11 +58,15 @@ type Daemon struct {
 	httpSrv       *http.Server
 	httpSrvNoMTLS *http.Server
 	grpcSrvs      []*grpc.Server
+	adminSrv      *grpc.Server
+	healthSrv     *health.Server
 	wg            syncutil.WaitGroup
 	statsHandler  *GRPCStatsHandler
 	promRegister  *prometheus.Registry
 	gwCancel      context.CancelFunc
 	gubeConfig    Config
+	closing       int32
+	auditFile     *os.File
 }
 
 // SpawnDaemon starts a new gubernator daemon according to the provided DaemonConfig.
@@ -84,35 +93,49 @@ func (s *Daemon) Start(ctx context.Context) error {
 
 	s.promRegister = prometheus.NewRegistry()
 
-	// The LRU cache for storing rate limits.
+	// The cache for storing rate limits.
 	cacheCollector := NewLRUCacheCollector()
 	s.promRegister.Register(cacheCollector)
 
+	var newCache func(maxSize int) Cache
+	switch s.conf.CacheType {
+	case "", "lru":
+		newCache = func(maxSize int) Cache { return NewLRUCache(maxSize) }
+	case "lfu":
+		newCache = func(maxSize int) Cache { return NewLFUCache(maxSize) }
+	case "segmented-lru":
+		newCache = func(maxSize int) Cache { return NewSegmentedLRUCache(maxSize) }
+	default:
+		return errors.Errorf("DaemonConfig.CacheType is invalid; choices are [lru,lfu,segmented-lru]; got '%s'", s.conf.CacheType)
+	}
 	cacheFactory := func(maxSize int) Cache {
-		cache := NewLRUCache(maxSize)
+		cache := newCache(maxSize)
 		cacheCollector.AddCache(cache)
 		return cache
 	}
 
+	auditSink := s.conf.AuditSink
+	if auditSink == nil && s.conf.AuditFile != "" {
+		s.auditFile, err = os.OpenFile(s.conf.AuditFile, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+		if err != nil {
+			return errors.Wrap(err, "while opening DaemonConfig.AuditFile")
+		}
+		auditSink = NewJSONAuditSink(s.auditFile)
+	}
+
 	// Handler to collect duration and API access metrics for GRPC
 	s.statsHandler = NewGRPCStatsHandler()
 	s.promRegister.Register(s.statsHandler)
 
 	opts := []grpc.ServerOption{
 		grpc.StatsHandler(s.statsHandler),
-		grpc.MaxRecvMsgSize(1024 * 1024),
 
 		// OpenTelemetry instrumentation on gRPC endpoints.
-		grpc.UnaryInterceptor(otelgrpc.UnaryServerInterceptor()),
-		grpc.StreamInterceptor(otelgrpc.StreamServerInterceptor()),
-	}
-
-	if s.conf.GRPCMaxConnectionAgeSeconds > 0 {
-		opts = append(opts, grpc.KeepaliveParams(keepalive.ServerParameters{
-			MaxConnectionAge:      time.Second * time.Duration(s.conf.GRPCMaxConnectionAgeSeconds),
-			MaxConnectionAgeGrace: time.Second * time.Duration(s.conf.GRPCMaxConnectionAgeSeconds),
-		}))
+		grpc.UnaryInterceptor(otelgrpc.UnaryServerInterceptor(otelgrpcOptions(s.conf.TracerProvider)...)),
+		grpc.StreamInterceptor(otelgrpc.StreamServerInterceptor(otelgrpcOptions(s.conf.TracerProvider)...)),
 	}
+	maxConnectionAge := time.Second * time.Duration(s.conf.GRPCMaxConnectionAgeSeconds)
+	opts = append(opts, s.conf.GRPCTransport.ServerOptions(maxConnectionAge)...)
 
 	if err := SetupTLS(s.conf.TLS); err != nil {
 		return err
@@ -125,21 +148,83 @@ func (s *Daemon) Start(ctx context.Context) error {
 	}
 	s.grpcSrvs = append(s.grpcSrvs, grpc.NewServer(opts...))
 
+	// Create a separate GRPC server for the privileged admin service
+	var adminSrvs []*grpc.Server
+	var adminPeerTLS *tls.Config
+	if s.conf.AdminGRPCListenAddress != "" {
+		adminTLS := s.conf.ServerTLS()
+		if s.conf.AdminTLS != nil {
+			if err := SetupTLS(s.conf.AdminTLS); err != nil {
+				return errors.Wrap(err, "while setting up admin TLS")
+			}
+			adminTLS = s.conf.AdminTLS.ServerTLS
+			adminPeerTLS = s.conf.AdminTLS.ClientTLS
+		}
+
+		adminOpts := append([]grpc.ServerOption{}, opts...)
+		if adminTLS != nil {
+			adminOpts = append(adminOpts, grpc.Creds(credentials.NewTLS(adminTLS)))
+		}
+		s.adminSrv = grpc.NewServer(adminOpts...)
+		adminSrvs = append(adminSrvs, s.adminSrv)
+	}
+
 	// Registers a new gubernator instance with the GRPC server
 	s.gubeConfig = Config{
-		PeerTLS:      s.conf.ClientTLS(),
-		DataCenter:   s.conf.DataCenter,
-		LocalPicker:  s.conf.Picker,
-		GRPCServers:  s.grpcSrvs,
-		Logger:       s.log,
-		CacheFactory: cacheFactory,
-		Behaviors:    s.conf.Behaviors,
+		PeerTLS:                   s.conf.ClientTLS(),
+		AdminPeerTLS:              adminPeerTLS,
+		PeerTransport:             s.conf.GRPCTransport,
+		TracerProvider:            s.conf.TracerProvider,
+		DataCenter:                s.conf.DataCenter,
+		LocalPicker:               s.conf.Picker,
+		GRPCServers:               s.grpcSrvs,
+		AdminGRPCServers:          adminSrvs,
+		Logger:                    s.log,
+		CacheFactory:              cacheFactory,
+		CacheSize:                 s.conf.CacheSize,
+		CacheExpireInterval:       s.conf.CacheExpireInterval,
+		DrainOnShutdown:           s.conf.DrainOnShutdown,
+		MaxDuration:               s.conf.MaxDuration,
+		MaxHits:                   s.conf.MaxHits,
+		StrictKeys:                s.conf.StrictKeys,
+		AdmissionLimit:            s.conf.AdmissionLimit,
+		AdmissionDuration:         s.conf.AdmissionDuration,
+		NamespaceDefaults:         s.conf.NamespaceDefaults,
+		KeyShardFunc:              s.conf.KeyShardFunc,
+		ReplicationFactor:         s.conf.ReplicationFactor,
+		IdempotencyTTL:            s.conf.IdempotencyTTL,
+		BackoffHintStep:           s.conf.BackoffHintStep,
+		BackoffHintMax:            s.conf.BackoffHintMax,
+		ClockSkewTolerance:        s.conf.ClockSkewTolerance,
+		MetadataLabels:            s.conf.MetadataLabels,
+		MetadataLabelValues:       s.conf.MetadataLabelValues,
+		OverLimitNamespaces:       s.conf.OverLimitNamespaces,
+		OverLimitNamespaceValues:  s.conf.OverLimitNamespaceValues,
+		GregorianLocation:         s.conf.GregorianLocation,
+		DisableAlgorithmMigration: s.conf.DisableAlgorithmMigration,
+		AuditSink:                 auditSink,
+		AuditNamespaces:           s.conf.AuditNamespaces,
+		AuditSampleRate:           s.conf.AuditSampleRate,
+		Behaviors:                 s.conf.Behaviors,
 	}
 	s.V1Server, err = NewV1Instance(s.gubeConfig)
 	if err != nil {
 		return errors.Wrap(err, "while creating new gubernator instance")
 	}
 
+	// Register the standard GRPC health service, which reports SERVING once the daemon is ready
+	s.healthSrv = health.NewServer()
+	s.updateHealth()
+	for _, srv := range s.grpcSrvs {
+		healthpb.RegisterHealthServer(srv, s.healthSrv)
+	}
+
+	if s.conf.EnableReflection {
+		for _, srv := range append(s.grpcSrvs, adminSrvs...) {
+			reflection.Register(srv)
+		}
+	}
+
 	// V1Server instance also implements prometheus.Collector interface
 	s.promRegister.Register(s.V1Server)
 
@@ -157,6 +242,20 @@ func (s *Daemon) Start(ctx context.Context) error {
 		}
 	})
 
+	if s.adminSrv != nil {
+		s.AdminListener, err = net.Listen("tcp", s.conf.AdminGRPCListenAddress)
+		if err != nil {
+			return errors.Wrap(err, "while starting admin GRPC listener")
+		}
+
+		s.wg.Go(func() {
+			s.log.Infof("Admin GRPC Listening on %s ...", s.conf.AdminGRPCListenAddress)
+			if err := s.adminSrv.Serve(s.AdminListener); err != nil {
+				s.log.WithError(err).Error("while starting admin GRPC server")
+			}
+		})
+	}
+
 	var gatewayAddr string
 	if s.conf.ServerTLS() != nil {
 		// We start a new local GRPC instance because we can't guarantee the TLS cert provided by the
@@ -185,13 +284,13 @@ func (s *Daemon) Start(ctx context.Context) error {
 	switch s.conf.PeerDiscoveryType {
 	case "k8s":
 		// Source our list of peers from kubernetes endpoint API
-		s.conf.K8PoolConf.OnUpdate = s.V1Server.SetPeers
+		s.conf.K8PoolConf.OnUpdate = s.onUpdate
 		s.pool, err = NewK8sPool(s.conf.K8PoolConf)
 		if err != nil {
 			return errors.Wrap(err, "while querying kubernetes API")
 		}
 	case "etcd":
-		s.conf.EtcdPoolConf.OnUpdate = s.V1Server.SetPeers
+		s.conf.EtcdPoolConf.OnUpdate = s.onUpdate
 		// Register ourselves with other peers via ETCD
 		s.conf.EtcdPoolConf.Client, err = etcdutil.NewClient(s.conf.EtcdPoolConf.EtcdConfig)
 		if err != nil {
@@ -203,13 +302,13 @@ func (s *Daemon) Start(ctx context.Context) error {
 			return errors.Wrap(err, "while creating etcd pool")
 		}
 	case "dns":
-		s.conf.DNSPoolConf.OnUpdate = s.V1Server.SetPeers
+		s.conf.DNSPoolConf.OnUpdate = s.onUpdate
 		s.pool, err = NewDNSPool(s.conf.DNSPoolConf)
 		if err != nil {
 			return errors.Wrap(err, "while creating the DNS pool")
 		}
 	case "member-list":
-		s.conf.MemberListPoolConf.OnUpdate = s.V1Server.SetPeers
+		s.conf.MemberListPoolConf.OnUpdate = s.onUpdate
 		s.conf.MemberListPoolConf.Logger = s.log
 
 		// Register peer on member list
@@ -225,16 +324,16 @@ func (s *Daemon) Start(ctx context.Context) error {
 	// Our protobuf files follow convention described here
 	// https://developers.google.com/protocol-buffers/docs/style#message-and-field-names
 	// Camel case breaks unmarshalling our GRPC gateway responses with protobuf structs.
+	// Unknown fields are rejected such that a misspelled field is not silently ignored.
 	gateway := runtime.NewServeMux(
-		runtime.WithMarshalerOption(runtime.MIMEWildcard, &runtime.JSONPb{
+		runtime.WithMarshalerOption(runtime.MIMEWildcard, &gatewayMarshaler{runtime.JSONPb{
 			MarshalOptions: protojson.MarshalOptions{
 				UseProtoNames:   true,
 				EmitUnpopulated: true,
 			},
-			UnmarshalOptions: protojson.UnmarshalOptions{
-				DiscardUnknown: true,
-			},
-		}),
+		}}),
+		runtime.WithForwardResponseOption(setResetTimestamp),
+		runtime.WithForwardResponseOption(setRetryAfter),
 	)
 
 	// Setup an JSON Gateway API for our GRPC methods
@@ -245,6 +344,14 @@ func (s *Daemon) Start(ctx context.Context) error {
 		return errors.Wrap(err, "while registering GRPC gateway handler")
 	}
 
+	// The privileged AdminV1 methods are only exposed over HTTP when they are served with the data plane
+	if s.conf.AdminGRPCListenAddress == "" {
+		err = RegisterAdminV1HandlerFromEndpoint(gwCtx, gateway, gatewayAddr, []grpc.DialOption{grpc.WithInsecure()})
+		if err != nil {
+			return errors.Wrap(err, "while registering GRPC admin gateway handler")
+		}
+	}
+
 	// Serve the JSON Gateway and metrics handlers via standard HTTP/1
 	mux := http.NewServeMux()
 
@@ -265,9 +372,16 @@ func (s *Daemon) Start(ctx context.Context) error {
 	mux.Handle("/metrics", promhttp.InstrumentMetricHandler(
 		s.promRegister, promhttp.HandlerFor(s.promRegister, promhttp.HandlerOpts{}),
 	))
+	mux.HandleFunc("/healthz", s.handleHealthz)
+	mux.HandleFunc("/readyz", s.handleReadyz)
 	mux.Handle("/", gateway)
 	log := log.New(newLogWriter(s.log), "", 0)
-	s.httpSrv = &http.Server{Addr: s.conf.HTTPListenAddress, Handler: mux, ErrorLog: log}
+	var handler http.Handler = mux
+	setter.SetDefault(&s.conf.HTTPCompressMinSize, defaultCompressMinSize)
+	if s.conf.HTTPCompressMinSize >= 0 {
+		handler = compressHandler(mux, s.conf.HTTPCompressMinSize)
+	}
+	s.httpSrv = &http.Server{Addr: s.conf.HTTPListenAddress, Handler: handler, ErrorLog: log}
 
 	s.HTTPListener, err = net.Listen("tcp", s.conf.HTTPListenAddress)
 	if err != nil {
@@ -284,6 +398,8 @@ func (s *Daemon) Start(ctx context.Context) error {
 			addrs = append(addrs, s.conf.HTTPStatusListenAddress)
 			muxNoMTLS := http.NewServeMux()
 			muxNoMTLS.Handle("/v1/HealthCheck", gateway)
+			muxNoMTLS.HandleFunc("/healthz", s.handleHealthz)
+			muxNoMTLS.HandleFunc("/readyz", s.handleReadyz)
 			s.httpSrvNoMTLS = &http.Server{
 				Addr:      s.conf.HTTPStatusListenAddress,
 				Handler:   muxNoMTLS,
@@ -331,6 +447,9 @@ func (s *Daemon) Start(ctx context.Context) error {
 	for _, l := range s.GRPCListeners {
 		addrs = append(addrs, l.Addr().String())
 	}
+	if s.AdminListener != nil {
+		addrs = append(addrs, s.AdminListener.Addr().String())
+	}
 	if err := WaitForConnect(ctx, addrs); err != nil {
 		return err
 	}
@@ -338,25 +457,62 @@ func (s *Daemon) Start(ctx context.Context) error {
 	return nil
 }
 
-// Close gracefully closes all server connections and listening sockets
+// Close gracefully closes all server connections and listening sockets, waiting at
+// most `DaemonConfig.ShutdownTimeout` for in-flight requests to complete.
 func (s *Daemon) Close() {
+	ctx := context.Background()
+	if s.conf.ShutdownTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, s.conf.ShutdownTimeout)
+		defer cancel()
+	}
+	if err := s.Shutdown(ctx); err != nil {
+		s.log.WithError(err).Warn("while shutting down")
+	}
+}
+
+// Shutdown stops accepting new requests and waits for in-flight requests to complete before flushing
+// any pending changes to the store and closing all server connections and listening sockets. If the
+// context is cancelled before the in-flight requests complete, the remaining requests are cancelled
+// and the context error is returned once the daemon is closed.
+func (s *Daemon) Shutdown(ctx context.Context) error {
 	if s.httpSrv == nil && s.httpSrvNoMTLS == nil {
-		return
+		return nil
 	}
 
+	// Report not ready such that load balancers stop sending us requests
+	atomic.StoreInt32(&s.closing, 1)
+	s.healthSrv.Shutdown()
+
 	if s.pool != nil {
 		s.pool.Close()
 	}
 
 	s.log.Infof("HTTP Gateway close for %s ...", s.conf.HTTPListenAddress)
-	s.httpSrv.Shutdown(context.Background())
+	shutdownHTTP(ctx, s.httpSrv)
 	if s.httpSrvNoMTLS != nil {
 		s.log.Infof("HTTP Status Gateway close for %s ...", s.conf.HTTPStatusListenAddress)
-		s.httpSrvNoMTLS.Shutdown(context.Background())
+		shutdownHTTP(ctx, s.httpSrvNoMTLS)
 	}
 	for i, srv := range s.grpcSrvs {
 		s.log.Infof("GRPC close for %s ...", s.GRPCListeners[i].Addr())
-		srv.GracefulStop()
+		gracefulStop(ctx, srv)
+	}
+	if s.adminSrv != nil {
+		s.log.Infof("Admin GRPC close for %s ...", s.AdminListener.Addr())
+		gracefulStop(ctx, s.adminSrv)
+		s.adminSrv = nil
+	}
+
+	// Flush pending store writes, even if in-flight requests were cancelled
+	if err := s.V1Server.Close(); err != nil {
+		s.log.WithError(err).Error("while closing the V1 instance")
+	}
+	if s.auditFile != nil {
+		if err := s.auditFile.Close(); err != nil {
+			s.log.WithError(err).Error("while closing the audit file")
+		}
+		s.auditFile = nil
 	}
 	s.wg.Stop()
 	s.statsHandler.Close()
@@ -364,6 +520,101 @@ func (s *Daemon) Close() {
 	s.httpSrv = nil
 	s.httpSrvNoMTLS = nil
 	s.grpcSrvs = nil
+	return ctx.Err()
+}
+
+// gracefulStop waits for in-flight requests to the server to complete, or if the context
+// is cancelled first, closes all connections to the server cancelling the remaining requests.
+func gracefulStop(ctx context.Context, srv *grpc.Server) {
+	done := make(chan struct{})
+	go func() {
+		srv.GracefulStop()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-ctx.Done():
+		srv.Stop()
+		<-done
+	}
+}
+
+// shutdownHTTP waits for in-flight requests to the server to complete, or if the context
+// is cancelled first, closes all connections to the server.
+func shutdownHTTP(ctx context.Context, srv *http.Server) {
+	if err := srv.Shutdown(ctx); err != nil {
+		_ = srv.Close()
+	}
+}
+
+// handleHealthz reports the daemon is alive for use as a liveness probe
+func (s *Daemon) handleHealthz(w http.ResponseWriter, r *http.Request) {
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write([]byte("ok"))
+}
+
+// handleReadyz reports if the daemon is ready to serve requests for use as a readiness probe. The daemon
+// is not ready until it has received a list of peers and is no longer ready once Close() is called.
+func (s *Daemon) handleReadyz(w http.ResponseWriter, r *http.Request) {
+	if err := s.ready(); err != nil {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		_, _ = w.Write([]byte(err.Error()))
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write([]byte("ok"))
+}
+
+func (s *Daemon) ready() error {
+	if atomic.LoadInt32(&s.closing) == 1 {
+		return errors.New("shutting down")
+	}
+	if len(s.V1Server.getPeerInfo()) == 0 {
+		return errors.New("waiting for peers")
+	}
+	return nil
+}
+
+// updateHealth sets the status reported by the GRPC health service according to the readiness of the daemon
+func (s *Daemon) updateHealth() {
+	status := healthpb.HealthCheckResponse_SERVING
+	if s.ready() != nil {
+		status = healthpb.HealthCheckResponse_NOT_SERVING
+	}
+	s.healthSrv.SetServingStatus("", status)
+	s.healthSrv.SetServingStatus(V1_ServiceDesc.ServiceName, status)
+}
+
+// onUpdate is called by the peer discovery pools when the list of peers changes
+func (s *Daemon) onUpdate(peers []PeerInfo) {
+	s.V1Server.SetPeers(s.setAdminAddress(peers))
+	s.updateHealth()
+}
+
+// setAdminAddress sets the admin address of the peers found by a discovery which does not advertise
+// it, IE: k8s and dns. Such peers are expected to accept admin requests on the same port as this instance.
+func (s *Daemon) setAdminAddress(peers []PeerInfo) []PeerInfo {
+	if s.conf.AdminGRPCListenAddress == "" || (s.conf.PeerDiscoveryType != "k8s" && s.conf.PeerDiscoveryType != "dns") {
+		return peers
+	}
+	_, port, err := net.SplitHostPort(s.conf.AdminGRPCListenAddress)
+	if err != nil {
+		s.log.WithError(err).Error("while parsing the admin listen address")
+		return peers
+	}
+	out := make([]PeerInfo, len(peers))
+	copy(out, peers)
+	for i, p := range out {
+		if p.AdminAddress != "" {
+			continue
+		}
+		host, _, err := net.SplitHostPort(p.GRPCAddress)
+		if err != nil {
+			continue
+		}
+		out[i].AdminAddress = net.JoinHostPort(host, port)
+	}
+	return out
 }
 
 // SetPeers sets the peers for this daemon
@@ -376,7 +627,7 @@ func (s *Daemon) SetPeers(in []PeerInfo) {
 			peers[i].IsOwner = true
 		}
 	}
-	s.V1Server.SetPeers(peers)
+	s.onUpdate(peers)
 }
 
 // Config returns the current config for this Daemon
@@ -0,0 +1,82 @@
+/*
+Copyright 2018-2022 Mailgun Technologies Inc
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gubernator
+
+import (
+	"time"
+
+	"github.com/mailgun/holster/v4/errors"
+)
+
+// LimitDescription is the capacity of a rate limit as computed by DescribeLimit()
+type LimitDescription struct {
+	// The length of the rate limit window in milliseconds. For `DURATION_IS_GREGORIAN` rate limits, the
+	// length of the current interval, IE: the milliseconds in the current month.
+	Duration int64
+	// The steady state rate in hits per second, IE: `limit / duration`
+	RatePerSecond float64
+	// The hits which may be made at once by a client which has not made any, IE: the `burst` of a
+	// rate limit which allows one, else the `limit`
+	Burst int64
+	// The time it takes a single hit to leak out of a LEAKY_BUCKET or HYBRID rate limit, or to drip into
+	// a TOKEN_BUCKET with `Behavior_TOKEN_DRIP`, IE: `duration / limit`. Zero if the hits of the rate
+	// limit are only returned at the end of the window.
+	LeakInterval time.Duration
+}
+
+// DescribeLimit computes the capacity of the rate limit requested, IE: to tell capacity planners how
+// many requests per second a `limit` and `duration` allow and how many may be made at once. Only the
+// request is consulted, no rate limit is created or changed.
+func DescribeLimit(r *RateLimitReq) (*LimitDescription, error) {
+	if r.Limit < 0 {
+		return nil, errors.Errorf("field 'limit' cannot be negative; got '%d'", r.Limit)
+	}
+
+	d := LimitDescription{Duration: r.Duration}
+	if HasBehavior(r.Behavior, Behavior_DURATION_IS_GREGORIAN) {
+		now, err := gregorianNow(r)
+		if err != nil {
+			return nil, err
+		}
+		if d.Duration, err = GregorianDuration(now, r.Duration); err != nil {
+			return nil, err
+		}
+	}
+	if d.Duration <= 0 {
+		return nil, errors.Errorf("field 'duration' must be greater than 0; got '%d'", r.Duration)
+	}
+
+	d.RatePerSecond = float64(r.Limit) * 1000 / float64(d.Duration)
+	leaks := true
+	switch r.Algorithm {
+	case Algorithm_TOKEN_BUCKET:
+		d.Burst = tokenBucketBurst(r)
+		leaks = tokenBucketDrips(r)
+	case Algorithm_LEAKY_BUCKET, Algorithm_HYBRID:
+		d.Burst = r.Burst
+		if d.Burst == 0 {
+			d.Burst = r.Limit
+		}
+	default:
+		return nil, errors.Errorf("invalid rate limit algorithm '%d'", r.Algorithm)
+	}
+
+	if leaks && r.Limit > 0 {
+		d.LeakInterval = time.Duration(float64(d.Duration) / float64(r.Limit) * float64(time.Millisecond))
+	}
+	return &d, nil
+}
@@ -0,0 +1,145 @@
+/*
+Copyright 2018-2022 Mailgun Technologies Inc
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gubernator_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/mailgun/gubernator/v2"
+	"github.com/mailgun/holster/v4/clock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDescribeLimit(t *testing.T) {
+	// A leap year February, such that the gregorian month is 29 days
+	defer clock.Freeze(time.Date(2024, time.February, 10, 12, 0, 0, 0, time.UTC)).Unfreeze()
+
+	tests := []struct {
+		Name         string
+		Request      *gubernator.RateLimitReq
+		Duration     int64
+		Rate         float64
+		Burst        int64
+		LeakInterval time.Duration
+	}{
+		{
+			Name: "token bucket",
+			Request: &gubernator.RateLimitReq{
+				Algorithm: gubernator.Algorithm_TOKEN_BUCKET,
+				Limit:     600,
+				Duration:  gubernator.Minute,
+			},
+			Duration: gubernator.Minute,
+			Rate:     10,
+			Burst:    600,
+		},
+		{
+			Name: "token bucket with burst",
+			Request: &gubernator.RateLimitReq{
+				Algorithm: gubernator.Algorithm_TOKEN_BUCKET,
+				Limit:     100,
+				Duration:  gubernator.Second * 10,
+				Burst:     250,
+			},
+			Duration: gubernator.Second * 10,
+			Rate:     10,
+			Burst:    250,
+		},
+		{
+			Name: "token bucket drip",
+			Request: &gubernator.RateLimitReq{
+				Algorithm: gubernator.Algorithm_TOKEN_BUCKET,
+				Behavior:  gubernator.Behavior_TOKEN_DRIP,
+				Limit:     4,
+				Duration:  gubernator.Second,
+			},
+			Duration:     gubernator.Second,
+			Rate:         4,
+			Burst:        4,
+			LeakInterval: time.Millisecond * 250,
+		},
+		{
+			Name: "leaky bucket",
+			Request: &gubernator.RateLimitReq{
+				Algorithm: gubernator.Algorithm_LEAKY_BUCKET,
+				Limit:     3,
+				Duration:  gubernator.Second * 2,
+			},
+			Duration:     gubernator.Second * 2,
+			Rate:         1.5,
+			Burst:        3,
+			LeakInterval: time.Second * 2 / 3,
+		},
+		{
+			Name: "leaky bucket with burst",
+			Request: &gubernator.RateLimitReq{
+				Algorithm: gubernator.Algorithm_LEAKY_BUCKET,
+				Limit:     60,
+				Duration:  gubernator.Minute,
+				Burst:     20,
+			},
+			Duration:     gubernator.Minute,
+			Rate:         1,
+			Burst:        20,
+			LeakInterval: time.Second,
+		},
+		{
+			Name: "hybrid",
+			Request: &gubernator.RateLimitReq{
+				Algorithm: gubernator.Algorithm_HYBRID,
+				Limit:     3600,
+				Duration:  60 * gubernator.Minute,
+			},
+			Duration:     60 * gubernator.Minute,
+			Rate:         1,
+			Burst:        3600,
+			LeakInterval: time.Second,
+		},
+		{
+			Name: "gregorian month",
+			Request: &gubernator.RateLimitReq{
+				Algorithm: gubernator.Algorithm_LEAKY_BUCKET,
+				Behavior:  gubernator.Behavior_DURATION_IS_GREGORIAN,
+				Timezone:  "UTC",
+				Limit:     29 * 24 * 3600,
+				Duration:  gubernator.GregorianMonths,
+			},
+			Duration:     29 * 24 * 60 * gubernator.Minute,
+			Rate:         1,
+			Burst:        29 * 24 * 3600,
+			LeakInterval: time.Second,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			d, err := gubernator.DescribeLimit(test.Request)
+			require.NoError(t, err)
+			assert.Equal(t, test.Duration, d.Duration)
+			assert.InDelta(t, test.Rate, d.RatePerSecond, 0.000001)
+			assert.Equal(t, test.Burst, d.Burst)
+			assert.Equal(t, test.LeakInterval, d.LeakInterval)
+		})
+	}
+
+	t.Run("invalid duration", func(t *testing.T) {
+		_, err := gubernator.DescribeLimit(&gubernator.RateLimitReq{Limit: 10})
+		assert.Error(t, err)
+	})
+}
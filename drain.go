@@ -0,0 +1,224 @@
+/*
+Copyright 2018-2022 Mailgun Technologies Inc
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gubernator
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/mailgun/holster/v4/errors"
+	"github.com/mailgun/holster/v4/tracing"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// TransferRateLimits adds the rate limits handed off by a departing peer to the local cache. This method
+// should only be called by a peer which is leaving the cluster and owned the rate limits provided.
+func (s *V1Instance) TransferRateLimits(ctx context.Context, r *TransferRateLimitsReq) (retval *TransferRateLimitsResp, reterr error) {
+	ctx = tracing.StartScope(ctx)
+	defer func() {
+		tracing.EndScope(ctx, reterr)
+	}()
+
+	if len(r.Items) > maxBatchSize {
+		return nil, status.Errorf(codes.OutOfRange,
+			"'TransferRateLimitsReq.items' list too large; max size is '%d'", maxBatchSize)
+	}
+
+	for _, i := range r.Items {
+		item := transferToCacheItem(i)
+		if item == nil {
+			return nil, status.Errorf(codes.InvalidArgument, "invalid algorithm '%d' for key '%s'", i.Algorithm, i.Key)
+		}
+		// The state of the departing owner replaces whatever we may have cached
+		if err := s.gubernatorPool.AddCacheItem(ctx, i.Key, item); err != nil {
+			return nil, errors.Wrap(err, "Error in checkHandlerPool.AddCacheItem")
+		}
+	}
+
+	return &TransferRateLimitsResp{}, nil
+}
+
+// drain hands off each of the rate limits this instance owns to the peer which will own the
+// rate limit once this instance is removed from the cluster.
+func (s *V1Instance) drain(ctx context.Context) (reterr error) {
+	ctx = tracing.StartScope(ctx)
+	defer func() {
+		tracing.EndScope(ctx, reterr)
+	}()
+
+	// Build a picker which represents the cluster once we have left
+	s.peerMutex.RLock()
+	picker := s.conf.LocalPicker.New()
+	for _, peer := range s.conf.LocalPicker.Peers() {
+		if !peer.Info().IsOwner {
+			picker.Add(peer)
+		}
+	}
+	s.peerMutex.RUnlock()
+
+	if picker.Size() == 0 {
+		return nil
+	}
+
+	var errs []error
+	batches := make(map[*PeerClient][]*TransferItem)
+	now := MillisecondNow()
+
+	// The channel must be read to completion, else the pool workers remain locked
+	for item := range s.gubernatorPool.each(ctx) {
+		if item.ExpireAt <= now {
+			continue
+		}
+		owner, err := s.GetPeer(ctx, item.Key)
+		// Skip the rate limits we don't own, such as the local copies of GLOBAL rate limits
+		if err != nil || !owner.Info().IsOwner {
+			continue
+		}
+		ti := cacheItemToTransfer(item)
+		if ti == nil {
+			continue
+		}
+		peer, err := picker.Get(s.shardKey(item.Key))
+		if err != nil {
+			errs = append(errs, errors.Wrap(err, "Error in picker.Get"))
+			continue
+		}
+		batches[peer] = append(batches[peer], ti)
+	}
+
+	for peer, items := range batches {
+		for len(items) != 0 {
+			size := len(items)
+			if size > maxBatchSize {
+				size = maxBatchSize
+			}
+			if err := s.transfer(ctx, peer, items[:size]); err != nil {
+				errs = append(errs, err)
+				break
+			}
+			items = items[size:]
+		}
+	}
+
+	if len(errs) != 0 {
+		return fmt.Errorf("while draining rate limits to peers: %v", errs)
+	}
+	return nil
+}
+
+func (s *V1Instance) transfer(ctx context.Context, peer *PeerClient, items []*TransferItem) error {
+	ctx, cancel := context.WithTimeout(ctx, s.conf.Behaviors.BatchTimeout)
+	defer cancel()
+
+	_, err := peer.TransferRateLimits(ctx, &TransferRateLimitsReq{Items: items})
+	if err != nil {
+		return errors.Wrapf(err, "while transferring %d rate limits to '%s'", len(items), peer.Info().GRPCAddress)
+	}
+	s.log.Debugf("transferred %d rate limits to '%s'", len(items), peer.Info().GRPCAddress)
+	return nil
+}
+
+// cacheItemToTransfer returns nil if the item does not hold the state of a rate limit algorithm
+func cacheItemToTransfer(item *CacheItem) *TransferItem {
+	ti := &TransferItem{
+		Key:             item.Key,
+		Algorithm:       item.Algorithm,
+		ExpireAt:        item.ExpireAt,
+		InvalidAt:       item.InvalidAt,
+		Priority:        item.Priority,
+		ScheduleSegment: item.ScheduleSegment,
+	}
+
+	switch t := item.Value.(type) {
+	case *TokenBucketItem:
+		ti.Status = t.Status
+		ti.Limit = t.Limit
+		ti.Duration = t.Duration
+		ti.Remaining = t.Remaining
+		ti.UpdatedAt = t.CreatedAt
+		ti.OverLimitAt = t.OverLimitAt
+		ti.Burst = t.Burst
+		ti.TotalHits = t.TotalHits
+	case *LeakyBucketItem:
+		ti.Limit = t.Limit
+		ti.Duration = t.Duration
+		ti.Remaining = t.Remaining
+		ti.UpdatedAt = t.UpdatedAt
+		ti.Burst = t.Burst
+		ti.TotalHits = t.TotalHits
+	case *HybridBucketItem:
+		ti.Limit = t.Limit
+		ti.Duration = t.Duration
+		ti.Remaining = t.Remaining
+		ti.UpdatedAt = t.UpdatedAt
+		ti.WindowStart = t.CreatedAt
+		ti.Burst = t.Burst
+		ti.TotalHits = t.TotalHits
+	default:
+		return nil
+	}
+	return ti
+}
+
+// transferToCacheItem returns nil if the algorithm of the item is unknown
+func transferToCacheItem(ti *TransferItem) *CacheItem {
+	item := &CacheItem{
+		Key:             ti.Key,
+		Algorithm:       ti.Algorithm,
+		ExpireAt:        ti.ExpireAt,
+		InvalidAt:       ti.InvalidAt,
+		Priority:        ti.Priority,
+		ScheduleSegment: ti.ScheduleSegment,
+	}
+
+	switch ti.Algorithm {
+	case Algorithm_TOKEN_BUCKET:
+		item.Value = &TokenBucketItem{
+			Status:      ti.Status,
+			Limit:       ti.Limit,
+			Duration:    ti.Duration,
+			Remaining:   ti.Remaining,
+			CreatedAt:   ti.UpdatedAt,
+			OverLimitAt: ti.OverLimitAt,
+			Burst:       ti.Burst,
+			TotalHits:   ti.TotalHits,
+		}
+	case Algorithm_LEAKY_BUCKET:
+		item.Value = &LeakyBucketItem{
+			Limit:     ti.Limit,
+			Duration:  ti.Duration,
+			Remaining: ti.Remaining,
+			UpdatedAt: ti.UpdatedAt,
+			Burst:     ti.Burst,
+			TotalHits: ti.TotalHits,
+		}
+	case Algorithm_HYBRID:
+		item.Value = &HybridBucketItem{
+			Limit:     ti.Limit,
+			Duration:  ti.Duration,
+			Remaining: ti.Remaining,
+			UpdatedAt: ti.UpdatedAt,
+			CreatedAt: ti.WindowStart,
+			Burst:     ti.Burst,
+			TotalHits: ti.TotalHits,
+		}
+	default:
+		return nil
+	}
+	return item
+}
@@ -0,0 +1,101 @@
+/*
+Copyright 2018-2022 Mailgun Technologies Inc
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gubernator_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/mailgun/gubernator/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDrainOnShutdown(t *testing.T) {
+	var confs []gubernator.DaemonConfig
+	var peers []gubernator.PeerInfo
+	for i := 0; i < 3; i++ {
+		conf := gubernator.DaemonConfig{
+			GRPCListenAddress: fmt.Sprintf("127.0.0.1:%d", 9640+i*2),
+			HTTPListenAddress: fmt.Sprintf("127.0.0.1:%d", 9641+i*2),
+			DrainOnShutdown:   true,
+		}
+		confs = append(confs, conf)
+		peers = append(peers, gubernator.PeerInfo{GRPCAddress: conf.GRPCListenAddress})
+	}
+
+	var daemons []*gubernator.Daemon
+	for _, conf := range confs {
+		d := spawnDaemon(t, conf)
+		defer d.Close()
+		daemons = append(daemons, d)
+	}
+	for _, d := range daemons {
+		d.SetPeers(peers)
+	}
+
+	// Find a rate limit owned by the peer we will shut down
+	victim := daemons[2]
+	var key string
+	for i := 0; ; i++ {
+		key = fmt.Sprintf("account:%d", i)
+		peer, err := daemons[0].V1Server.GetPeer(context.Background(), "test_drain_"+key)
+		require.NoError(t, err)
+		if peer.Info().GRPCAddress == victim.Config().GRPCListenAddress {
+			break
+		}
+	}
+
+	client, err := gubernator.DialV1Server(confs[0].GRPCListenAddress, nil)
+	require.NoError(t, err)
+
+	sendHit := func() *gubernator.RateLimitResp {
+		resp, err := client.GetRateLimits(context.Background(), &gubernator.GetRateLimitsReq{
+			Requests: []*gubernator.RateLimitReq{
+				{
+					Name:      "test_drain",
+					UniqueKey: key,
+					Algorithm: gubernator.Algorithm_TOKEN_BUCKET,
+					Behavior:  gubernator.Behavior_NO_BATCHING,
+					Duration:  gubernator.Minute,
+					Limit:     10,
+					Hits:      1,
+				},
+			},
+		})
+		require.NoError(t, err)
+		require.Empty(t, resp.Responses[0].Error)
+		return resp.Responses[0]
+	}
+
+	for i := 0; i < 3; i++ {
+		resp := sendHit()
+		assert.Equal(t, victim.Config().GRPCListenAddress, resp.Metadata["owner"])
+	}
+
+	victim.Close()
+	for _, d := range daemons[:2] {
+		d.SetPeers(peers[:2])
+	}
+
+	// The new owner should continue where the departed owner left off
+	resp := sendHit()
+	assert.NotEqual(t, victim.Config().GRPCListenAddress, resp.Metadata["owner"])
+	assert.Equal(t, int64(6), resp.Remaining)
+	assert.Equal(t, gubernator.Status_UNDER_LIMIT, resp.Status)
+}
@@ -0,0 +1,83 @@
+/*
+Copyright 2018-2022 Mailgun Technologies Inc
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gubernator
+
+// expiringMap holds values which are forgotten once they expire, IE: the responses remembered for
+// idempotent requests. It is kept apart from the rate limit Cache such that these values neither
+// take the place of rate limits in the cache nor reach a Loader or Store. Not thread safe, each pool
+// worker holds its own.
+type expiringMap struct {
+	items   map[string]expiringValue
+	maxSize int
+}
+
+type expiringValue struct {
+	value    interface{}
+	expireAt int64
+}
+
+func newExpiringMap(maxSize int) *expiringMap {
+	return &expiringMap{
+		items:   make(map[string]expiringValue),
+		maxSize: maxSize,
+	}
+}
+
+// Get returns the value of the key if it has not expired
+func (m *expiringMap) Get(key string) (interface{}, bool) {
+	v, ok := m.items[key]
+	if !ok {
+		return nil, false
+	}
+	if v.expireAt <= MillisecondNow() {
+		delete(m.items, key)
+		return nil, false
+	}
+	return v.value, true
+}
+
+// Set holds the value of the key until `expireAt` in epoch milliseconds. Once the map is full the
+// expired values are removed, if none have expired an arbitrary value is dropped to make room.
+func (m *expiringMap) Set(key string, value interface{}, expireAt int64) {
+	if _, ok := m.items[key]; !ok && m.maxSize > 0 && len(m.items) >= m.maxSize {
+		m.RemoveExpired()
+		for k := range m.items {
+			if len(m.items) < m.maxSize {
+				break
+			}
+			delete(m.items, k)
+		}
+	}
+	m.items[key] = expiringValue{value: value, expireAt: expireAt}
+}
+
+func (m *expiringMap) Remove(key string) {
+	delete(m.items, key)
+}
+
+// RemoveExpired removes the values which have expired, returns the count of values removed
+func (m *expiringMap) RemoveExpired() int {
+	now := MillisecondNow()
+	var count int
+	for key, v := range m.items {
+		if v.expireAt <= now {
+			delete(m.items, key)
+			count++
+		}
+	}
+	return count
+}
@@ -0,0 +1,110 @@
+/*
+Copyright 2018-2022 Mailgun Technologies Inc
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gubernator
+
+import (
+	"context"
+	"time"
+
+	"github.com/mailgun/holster/v4/clock"
+	"github.com/mailgun/holster/v4/ctxutil"
+	"github.com/mailgun/holster/v4/errors"
+)
+
+// PeerResult is the value returned by a single peer during a fan out.
+type PeerResult struct {
+	Peer  PeerInfo
+	Value interface{}
+}
+
+// PeerFailure identifies a peer which did not respond during a fan out and why.
+type PeerFailure struct {
+	Peer PeerInfo
+	Err  error
+}
+
+// FanOutResult contains the partial results of a fan out. Peers which responded
+// successfully are included in `Results` while peers which returned an error or
+// did not respond before the timeout are included in `Unresponsive`.
+type FanOutResult struct {
+	Results      []PeerResult
+	Unresponsive []PeerFailure
+}
+
+// FanOutFunc is called once for each peer during a fan out.
+type FanOutFunc func(ctx context.Context, peer *PeerClient) (interface{}, error)
+
+type fanOutReply struct {
+	idx   int
+	value interface{}
+	err   error
+}
+
+// FanOut calls `fn` concurrently for each of the peers provided and waits at most `timeout` for all
+// the peers to respond. A single slow or failing peer does not fail the entire operation, instead
+// the results from the peers which did respond are returned along with a list of the peers which
+// did not. Results and failures are returned in the same order as the peers provided.
+func FanOut(ctx context.Context, peers []*PeerClient, timeout time.Duration, fn FanOutFunc) FanOutResult {
+	var result FanOutResult
+	if len(peers) == 0 {
+		return result
+	}
+
+	replies := make(chan fanOutReply, len(peers))
+	for i, p := range peers {
+		go func(idx int, peer *PeerClient) {
+			ctx, cancel := ctxutil.WithTimeout(ctx, timeout)
+			defer cancel()
+			value, err := fn(ctx, peer)
+			replies <- fanOutReply{idx: idx, value: value, err: err}
+		}(i, p)
+	}
+
+	timer := clock.NewTimer(timeout)
+	defer timer.Stop()
+
+	responded := make(map[int]fanOutReply, len(peers))
+wait:
+	for len(responded) != len(peers) {
+		select {
+		case r := <-replies:
+			responded[r.idx] = r
+		case <-timer.C():
+			break wait
+		case <-ctx.Done():
+			break wait
+		}
+	}
+
+	for i, p := range peers {
+		r, ok := responded[i]
+		if !ok {
+			result.Unresponsive = append(result.Unresponsive, PeerFailure{
+				Peer: p.Info(),
+				Err:  errors.Errorf("peer '%s' did not respond within %s", p.Info().GRPCAddress, timeout),
+			})
+			continue
+		}
+		if r.err != nil {
+			result.Unresponsive = append(result.Unresponsive, PeerFailure{Peer: p.Info(), Err: r.err})
+			continue
+		}
+		result.Results = append(result.Results, PeerResult{Peer: p.Info(), Value: r.value})
+	}
+
+	return result
+}
@@ -0,0 +1,74 @@
+/*
+Copyright 2018-2022 Mailgun Technologies Inc
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gubernator_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/mailgun/gubernator/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFanOutPartialResults(t *testing.T) {
+	var peers []*gubernator.PeerClient
+	for _, addr := range []string{"peer-1", "slow-peer", "peer-3", "error-peer"} {
+		peers = append(peers, gubernator.NewPeerClient(gubernator.PeerConfig{
+			Info: gubernator.PeerInfo{GRPCAddress: addr},
+		}))
+	}
+
+	start := time.Now()
+	result := gubernator.FanOut(context.Background(), peers, 100*time.Millisecond,
+		func(ctx context.Context, peer *gubernator.PeerClient) (interface{}, error) {
+			switch peer.Info().GRPCAddress {
+			case "slow-peer":
+				// Simulate a peer which ignores the context deadline
+				time.Sleep(2 * time.Second)
+			case "error-peer":
+				return nil, errors.New("peer error")
+			}
+			return peer.Info().GRPCAddress, nil
+		})
+
+	// Should not wait for the slow peer
+	assert.Less(t, time.Since(start), time.Second)
+
+	require.Len(t, result.Results, 2)
+	assert.Equal(t, "peer-1", result.Results[0].Peer.GRPCAddress)
+	assert.Equal(t, "peer-1", result.Results[0].Value)
+	assert.Equal(t, "peer-3", result.Results[1].Peer.GRPCAddress)
+	assert.Equal(t, "peer-3", result.Results[1].Value)
+
+	require.Len(t, result.Unresponsive, 2)
+	assert.Equal(t, "slow-peer", result.Unresponsive[0].Peer.GRPCAddress)
+	assert.Contains(t, result.Unresponsive[0].Err.Error(), "did not respond")
+	assert.Equal(t, "error-peer", result.Unresponsive[1].Peer.GRPCAddress)
+	assert.EqualError(t, result.Unresponsive[1].Err, "peer error")
+}
+
+func TestFanOutNoPeers(t *testing.T) {
+	result := gubernator.FanOut(context.Background(), nil, time.Second,
+		func(ctx context.Context, peer *gubernator.PeerClient) (interface{}, error) {
+			return nil, nil
+		})
+	assert.Empty(t, result.Results)
+	assert.Empty(t, result.Unresponsive)
+}
@@ -17,25 +17,40 @@ limitations under the License.
 package gubernator_test
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
 	"io"
 	"io/ioutil"
+	"math"
+	"net"
 	"net/http"
 	"os"
+	"strconv"
 	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/mailgun/gubernator/v2"
 	guber "github.com/mailgun/gubernator/v2"
 	"github.com/mailgun/gubernator/v2/cluster"
 	"github.com/mailgun/holster/v4/clock"
 	"github.com/mailgun/holster/v4/testutil"
+	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/common/expfmt"
 	"github.com/prometheus/common/model"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	reflectionpb "google.golang.org/grpc/reflection/grpc_reflection_v1alpha"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
 )
 
 // Setup and shutdown the mock gubernator cluster for the entire test suite
@@ -217,6 +232,92 @@ func TestTokenBucket(t *testing.T) {
 	}
 }
 
+func TestTokenBucketCost(t *testing.T) {
+	client, errs := guber.DialV1Server(cluster.GetRandomPeer(cluster.DataCenterNone).GRPCAddress, nil)
+	require.Nil(t, errs)
+
+	tests := []struct {
+		Name      string
+		Hits      int64
+		Cost      float64
+		Repeat    int // The number of additional times the request is sent
+		Remaining int64
+		Status    guber.Status
+	}{
+		{
+			Name:      "large cost",
+			Cost:      5,
+			Remaining: 5,
+			Status:    guber.Status_UNDER_LIMIT,
+		},
+		{
+			Name:      "fractional cost should round the remaining down",
+			Cost:      2.5,
+			Remaining: 2,
+			Status:    guber.Status_UNDER_LIMIT,
+		},
+		{
+			Name:      "hits are used when cost is not provided",
+			Hits:      1,
+			Remaining: 1,
+			Status:    guber.Status_UNDER_LIMIT,
+		},
+		{
+			Name:      "cost larger than the remaining is rejected",
+			Cost:      2,
+			Remaining: 1,
+			Status:    guber.Status_OVER_LIMIT,
+		},
+		{
+			Name:      "many small costs",
+			Cost:      0.1,
+			Repeat:    4,
+			Remaining: 1,
+			Status:    guber.Status_UNDER_LIMIT,
+		},
+		{
+			Name:      "cost which takes the remainder",
+			Cost:      1,
+			Remaining: 0,
+			Status:    guber.Status_UNDER_LIMIT,
+		},
+		{
+			Name:      "should be over the limit",
+			Cost:      0.1,
+			Remaining: 0,
+			Status:    guber.Status_OVER_LIMIT,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.Name, func(t *testing.T) {
+			var rl *guber.RateLimitResp
+			for i := 0; i <= tt.Repeat; i++ {
+				resp, err := client.GetRateLimits(context.Background(), &guber.GetRateLimitsReq{
+					Requests: []*guber.RateLimitReq{
+						{
+							Name:      "test_token_bucket_cost",
+							UniqueKey: "account:1234",
+							Algorithm: guber.Algorithm_TOKEN_BUCKET,
+							Duration:  guber.Minute,
+							Limit:     10,
+							Hits:      tt.Hits,
+							Cost:      tt.Cost,
+						},
+					},
+				})
+				require.Nil(t, err)
+				rl = resp.Responses[0]
+				require.Empty(t, rl.Error)
+			}
+
+			assert.Equal(t, tt.Status, rl.Status)
+			assert.Equal(t, tt.Remaining, rl.Remaining)
+			assert.Equal(t, int64(10), rl.Limit)
+		})
+	}
+}
+
 func TestTokenBucketGregorian(t *testing.T) {
 	defer clock.Freeze(clock.Now()).Unfreeze()
 
@@ -364,6 +465,52 @@ func TestTokenBucketNegativeHits(t *testing.T) {
 	}
 }
 
+func TestResetJitter(t *testing.T) {
+	defer clock.Freeze(clock.Now()).Unfreeze()
+
+	client, errs := guber.DialV1Server(cluster.GetRandomPeer(cluster.DataCenterNone).GRPCAddress, nil)
+	require.Nil(t, errs)
+
+	const jitter = 10_000
+	expire, err := guber.GregorianExpiration(clock.Now(), guber.GregorianHours)
+	require.NoError(t, err)
+	resetTime := expire + 1
+
+	getResetTime := func(key string) int64 {
+		resp, err := client.GetRateLimits(context.Background(), &guber.GetRateLimitsReq{
+			Requests: []*guber.RateLimitReq{
+				{
+					Name:        "test_reset_jitter",
+					UniqueKey:   key,
+					Algorithm:   guber.Algorithm_TOKEN_BUCKET,
+					Behavior:    guber.Behavior_DURATION_IS_GREGORIAN | guber.Behavior_RESET_JITTER,
+					Duration:    guber.GregorianHours,
+					ResetJitter: jitter,
+					Limit:       10,
+					Hits:        1,
+				},
+			},
+		})
+		require.NoError(t, err)
+		require.Empty(t, resp.Responses[0].Error)
+		return resp.Responses[0].ResetTime
+	}
+
+	first := getResetTime("account:1")
+	second := getResetTime("account:2")
+	assert.NotEqual(t, first, second)
+
+	for _, rt := range []int64{first, second} {
+		assert.GreaterOrEqual(t, rt, resetTime)
+		assert.Less(t, rt, resetTime+jitter)
+	}
+
+	// Subsequent requests should agree on the reset time
+	clock.Advance(clock.Second)
+	assert.Equal(t, first, getResetTime("account:1"))
+	assert.Equal(t, second, getResetTime("account:2"))
+}
+
 func TestLeakyBucket(t *testing.T) {
 	defer clock.Freeze(clock.Now()).Unfreeze()
 
@@ -599,7 +746,9 @@ func TestLeakyBucketWithBurst(t *testing.T) {
 }
 
 func TestLeakyBucketGregorian(t *testing.T) {
-	defer clock.Freeze(clock.Now()).Unfreeze()
+	// Start at the beginning of a minute, else the bucket may expire at the end of the
+	// gregorian minute while the test is running
+	defer clock.Freeze(clock.Now().Truncate(clock.Minute).Add(clock.Minute)).Unfreeze()
 
 	client, errs := guber.DialV1Server(cluster.PeerAt(0).GRPCAddress, nil)
 	require.Nil(t, errs)
@@ -609,6 +758,8 @@ func TestLeakyBucketGregorian(t *testing.T) {
 		Hits      int64
 		Remaining int64
 		Status    guber.Status
+		// The milliseconds from the start of the test until the bucket has leaked back to the limit
+		ResetTime int64
 		Sleep     clock.Duration
 	}{
 		{
@@ -616,6 +767,7 @@ func TestLeakyBucketGregorian(t *testing.T) {
 			Hits:      1,
 			Remaining: 59,
 			Status:    guber.Status_UNDER_LIMIT,
+			ResetTime: 1000,
 			Sleep:     clock.Millisecond * 500,
 		},
 		{
@@ -623,6 +775,7 @@ func TestLeakyBucketGregorian(t *testing.T) {
 			Hits:      1,
 			Remaining: 58,
 			Status:    guber.Status_UNDER_LIMIT,
+			ResetTime: 2500,
 			Sleep:     clock.Second,
 		},
 		{
@@ -630,6 +783,7 @@ func TestLeakyBucketGregorian(t *testing.T) {
 			Hits:      1,
 			Remaining: 58,
 			Status:    guber.Status_UNDER_LIMIT,
+			ResetTime: 3500,
 		},
 	}
 
@@ -649,7 +803,6 @@ func TestLeakyBucketGregorian(t *testing.T) {
 					},
 				},
 			})
-			clock.Freeze(clock.Now())
 			require.NoError(t, err)
 
 			rl := resp.Responses[0]
@@ -658,12 +811,173 @@ func TestLeakyBucketGregorian(t *testing.T) {
 			assert.Equal(t, test.Remaining, rl.Remaining)
 			assert.Equal(t, int64(60), rl.Limit)
 			assert.True(t, rl.ResetTime > now.Unix())
+			// A hit leaks every minute / limit milliseconds
+			assert.Equal(t, now.UnixMilli()+test.ResetTime, rl.ResetTime)
 			clock.Advance(test.Sleep)
 		})
 	}
 }
 
-func TestLeakyBucketNegativeHits(t *testing.T) {
+func TestLeakyBucketLeakProfile(t *testing.T) {
+	defer clock.Freeze(clock.Now()).Unfreeze()
+
+	client, err := guber.DialV1Server(cluster.GetRandomPeer(cluster.DataCenterNone).GRPCAddress, nil)
+	require.NoError(t, err)
+
+	send := func(profile guber.LeakProfile, hits int64) *guber.RateLimitResp {
+		resp, err := client.GetRateLimits(context.Background(), &guber.GetRateLimitsReq{
+			Requests: []*guber.RateLimitReq{
+				{
+					Name:        "test_leak_profile",
+					UniqueKey:   "account:" + profile.String(),
+					Algorithm:   guber.Algorithm_LEAKY_BUCKET,
+					LeakProfile: profile,
+					Duration:    guber.Second * 10,
+					Limit:       10,
+					Hits:        hits,
+				},
+			},
+		})
+		require.NoError(t, err)
+		rl := resp.Responses[0]
+		require.Empty(t, rl.Error)
+		return rl
+	}
+
+	// Each profile leaks the drained bucket along its own curve, and all of them refill by the end of the duration
+	tests := []struct {
+		Profile   guber.LeakProfile
+		Remaining []int64
+	}{
+		{
+			Profile:   guber.LeakProfile_LINEAR,
+			Remaining: []int64{5, 10},
+		},
+		{
+			Profile:   guber.LeakProfile_QUADRATIC,
+			Remaining: []int64{2, 10},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Profile.String(), func(t *testing.T) {
+			now := clock.Now().UnixNano() / 1000000
+			rl := send(test.Profile, 10)
+			assert.Equal(t, int64(0), rl.Remaining)
+			assert.Equal(t, now+guber.Second*10, rl.ResetTime)
+
+			// 5 seconds leak 10 * 5/10 = 5 linear hits, but only 10 * (5/10)^2 = 2.5 quadratic hits
+			clock.Advance(clock.Second * 5)
+			rl = send(test.Profile, 0)
+			assert.Equal(t, test.Remaining[0], rl.Remaining)
+
+			clock.Advance(clock.Second * 10)
+			rl = send(test.Profile, 0)
+			assert.Equal(t, test.Remaining[1], rl.Remaining)
+		})
+	}
+
+	// The reset time follows the curve of the profile
+	now := clock.Now().UnixNano() / 1000000
+	rl := send(guber.LeakProfile_QUADRATIC, 9)
+	assert.Equal(t, int64(1), rl.Remaining)
+	// 10s * (1 - sqrt(1/10))
+	assert.Equal(t, now+6837, rl.ResetTime)
+
+	// A bucket requested every second leaks as much as one requested once
+	rl = send(guber.LeakProfile_QUADRATIC, 1)
+	assert.Equal(t, int64(0), rl.Remaining)
+	for i := 0; i < 5; i++ {
+		clock.Advance(clock.Second)
+		rl = send(guber.LeakProfile_QUADRATIC, 0)
+	}
+	assert.Equal(t, int64(2), rl.Remaining)
+	for i := 0; i < 5; i++ {
+		clock.Advance(clock.Second)
+		rl = send(guber.LeakProfile_QUADRATIC, 0)
+	}
+	assert.Equal(t, int64(10), rl.Remaining)
+}
+
+func TestGregorianInvalidDuration(t *testing.T) {
+	client, err := guber.DialV1Server(cluster.GetRandomPeer(cluster.DataCenterNone).GRPCAddress, nil)
+	require.NoError(t, err)
+
+	for _, algorithm := range []guber.Algorithm{guber.Algorithm_TOKEN_BUCKET, guber.Algorithm_LEAKY_BUCKET} {
+		t.Run(algorithm.String(), func(t *testing.T) {
+			_, err := client.GetRateLimits(context.Background(), &guber.GetRateLimitsReq{
+				Requests: []*guber.RateLimitReq{
+					{
+						Name:      "test_gregorian_invalid_duration",
+						UniqueKey: "account:" + algorithm.String(),
+						Algorithm: algorithm,
+						Behavior:  guber.Behavior_DURATION_IS_GREGORIAN,
+						Duration:  guber.Second,
+						Limit:     10,
+						Hits:      1,
+					},
+				},
+			})
+			require.Error(t, err)
+			assert.Equal(t, codes.InvalidArgument, status.Code(err))
+			assert.Contains(t, status.Convert(err).Message(), "'duration' of '1000'")
+			assert.Contains(t, status.Convert(err).Message(),
+				"must be 0 (minutes), 1 (hours), 2 (days), 3 (weeks), 4 (months), 5 (years) or 6 (quarters)")
+		})
+	}
+}
+
+func TestChangeLimitWithinBounds(t *testing.T) {
+	defer clock.Freeze(clock.Now()).Unfreeze()
+
+	client, errs := guber.DialV1Server(cluster.PeerAt(0).GRPCAddress, nil)
+	require.Nil(t, errs)
+
+	for _, algorithm := range []guber.Algorithm{guber.Algorithm_TOKEN_BUCKET, guber.Algorithm_LEAKY_BUCKET} {
+		t.Run(algorithm.String(), func(t *testing.T) {
+			for _, test := range []struct {
+				Name  string
+				Limit int64
+				Hits  int64
+				// The remaining of the TOKEN_BUCKET, which adds the difference in limit to remaining
+				TokenRemaining int64
+			}{
+				{Name: "first hit", Limit: 10, Hits: 2, TokenRemaining: 8},
+				{Name: "raise limit", Limit: 1000, Hits: 0, TokenRemaining: 998},
+				{Name: "hit after raise", Limit: 1000, Hits: 5, TokenRemaining: 993},
+				{Name: "lower limit", Limit: 5, Hits: 0, TokenRemaining: 0},
+				{Name: "lower limit below hits", Limit: 1, Hits: 0, TokenRemaining: 0},
+				{Name: "raise limit a lot", Limit: 1 << 40, Hits: 1, TokenRemaining: 1<<40 - 2},
+				{Name: "lower limit a lot", Limit: 3, Hits: 1, TokenRemaining: 0},
+			} {
+				resp, err := client.GetRateLimits(context.Background(), &guber.GetRateLimitsReq{
+					Requests: []*guber.RateLimitReq{
+						{
+							Name:      "test_change_limit_within_bounds",
+							UniqueKey: "account:" + algorithm.String(),
+							Algorithm: algorithm,
+							Duration:  guber.Minute * 60,
+							Limit:     test.Limit,
+							Hits:      test.Hits,
+						},
+					},
+				})
+				require.NoError(t, err, test.Name)
+				rl := resp.Responses[0]
+				assert.Empty(t, rl.Error, test.Name)
+				assert.Equal(t, test.Limit, rl.Limit, test.Name)
+				assert.GreaterOrEqual(t, rl.Remaining, int64(0), test.Name)
+				assert.LessOrEqual(t, rl.Remaining, test.Limit, test.Name)
+				if algorithm == guber.Algorithm_TOKEN_BUCKET {
+					assert.Equal(t, test.TokenRemaining, rl.Remaining, test.Name)
+				}
+				clock.Advance(clock.Millisecond)
+			}
+		})
+	}
+}
+
+func TestTokenBucketBurst(t *testing.T) {
 	defer clock.Freeze(clock.Now()).Unfreeze()
 
 	client, errs := guber.DialV1Server(cluster.PeerAt(0).GRPCAddress, nil)
@@ -677,549 +991,3840 @@ func TestLeakyBucketNegativeHits(t *testing.T) {
 		Sleep     clock.Duration
 	}{
 		{
-			Name:      "first hit",
-			Hits:      1,
-			Remaining: 9,
+			Name:      "consume the full burst",
+			Hits:      30,
+			Remaining: 0,
 			Status:    guber.Status_UNDER_LIMIT,
 			Sleep:     clock.Duration(0),
 		},
 		{
-			Name:      "can increase remaining",
-			Hits:      -1,
-			Remaining: 10,
-			Status:    guber.Status_UNDER_LIMIT,
-			Sleep:     clock.Duration(0),
+			Name:      "over the limit once the burst is consumed",
+			Hits:      1,
+			Remaining: 0,
+			Status:    guber.Status_OVER_LIMIT,
+			Sleep:     clock.Second,
 		},
 		{
-			Name:      "remaining should be zero",
+			Name:      "refills the limit after the duration",
 			Hits:      10,
 			Remaining: 0,
 			Status:    guber.Status_UNDER_LIMIT,
 			Sleep:     clock.Duration(0),
 		},
 		{
-			Name:      "can append one to remaining when remaining is zero",
-			Hits:      -1,
-			Remaining: 1,
+			Name:      "refill does not exceed the limit",
+			Hits:      1,
+			Remaining: 0,
+			Status:    guber.Status_OVER_LIMIT,
+			Sleep:     clock.Second * 2,
+		},
+		{
+			Name:      "refills the limit each duration",
+			Hits:      0,
+			Remaining: 20,
+			Status:    guber.Status_UNDER_LIMIT,
+			Sleep:     clock.Second * 5,
+		},
+		{
+			Name:      "refill does not exceed the burst",
+			Hits:      0,
+			Remaining: 30,
 			Status:    guber.Status_UNDER_LIMIT,
 			Sleep:     clock.Duration(0),
 		},
+		{
+			Name:      "hits above the burst are rejected",
+			Hits:      31,
+			Remaining: 30,
+			Status:    guber.Status_OVER_LIMIT,
+			Sleep:     clock.Duration(0),
+		},
 	}
 
 	for _, test := range tests {
-		t.Run(test.Name, func(t *testing.T) {
-			resp, err := client.GetRateLimits(context.Background(), &guber.GetRateLimitsReq{
-				Requests: []*guber.RateLimitReq{
-					{
-						Name:      "test_leaky_bucket_negative",
-						UniqueKey: "account:12345",
-						Algorithm: guber.Algorithm_LEAKY_BUCKET,
-						Duration:  guber.Second * 30,
-						Hits:      test.Hits,
-						Limit:     10,
-					},
+		resp, err := client.GetRateLimits(context.Background(), &guber.GetRateLimitsReq{
+			Requests: []*guber.RateLimitReq{
+				{
+					Name:      "test_token_bucket_burst",
+					UniqueKey: "account:1234",
+					Algorithm: guber.Algorithm_TOKEN_BUCKET,
+					Duration:  guber.Second,
+					Limit:     10,
+					Burst:     30,
+					Hits:      test.Hits,
 				},
-			})
-			require.NoError(t, err)
-			require.Len(t, resp.Responses, 1)
-
-			rl := resp.Responses[0]
-
-			assert.Equal(t, test.Status, rl.Status)
-			assert.Equal(t, test.Remaining, rl.Remaining)
-			assert.Equal(t, int64(10), rl.Limit)
-			assert.Equal(t, clock.Now().Unix()+(rl.Limit-rl.Remaining)*3, rl.ResetTime/1000)
-			clock.Advance(test.Sleep)
+			},
 		})
+		require.NoError(t, err, test.Name)
+		rl := resp.Responses[0]
+		assert.Empty(t, rl.Error, test.Name)
+		assert.Equal(t, test.Status, rl.Status, test.Name)
+		assert.Equal(t, test.Remaining, rl.Remaining, test.Name)
+		assert.Equal(t, int64(10), rl.Limit, test.Name)
+		clock.Advance(test.Sleep)
 	}
 }
 
-func TestMissingFields(t *testing.T) {
-	client, errs := guber.DialV1Server(cluster.GetRandomPeer(cluster.DataCenterNone).GRPCAddress, nil)
+func TestTokenBucketDrip(t *testing.T) {
+	defer clock.Freeze(clock.Now()).Unfreeze()
+
+	client, errs := guber.DialV1Server(cluster.PeerAt(0).GRPCAddress, nil)
 	require.Nil(t, errs)
 
+	// One token drips into the bucket every 100ms
 	tests := []struct {
-		Req    *guber.RateLimitReq
-		Status guber.Status
-		Error  string
+		Name      string
+		Hits      int64
+		Remaining int64
+		Status    guber.Status
+		// The expected reset time relative to the time of the request
+		ResetAfter int64
+		Sleep      clock.Duration
 	}{
 		{
-			Req: &guber.RateLimitReq{
-				Name:      "test_missing_fields",
-				UniqueKey: "account:1234",
-				Hits:      1,
-				Limit:     10,
-				Duration:  0,
-			},
-			Error:  "", // No Error
-			Status: guber.Status_UNDER_LIMIT,
+			Name:       "consume all the tokens",
+			Hits:       10,
+			Remaining:  0,
+			Status:     guber.Status_UNDER_LIMIT,
+			ResetAfter: 1000,
+			Sleep:      clock.Millisecond * 50,
 		},
 		{
-			Req: &guber.RateLimitReq{
-				Name:      "test_missing_fields",
-				UniqueKey: "account:12345",
-				Hits:      1,
-				Duration:  10000,
-				Limit:     0,
-			},
-			Error:  "", // No Error
-			Status: guber.Status_OVER_LIMIT,
+			Name:       "over the limit before a whole token has dripped",
+			Hits:       1,
+			Remaining:  0,
+			Status:     guber.Status_OVER_LIMIT,
+			ResetAfter: 950,
+			Sleep:      clock.Millisecond * 50,
 		},
 		{
-			Req: &guber.RateLimitReq{
-				UniqueKey: "account:1234",
-				Hits:      1,
-				Duration:  10000,
-				Limit:     5,
-			},
-			Error:  "field 'namespace' cannot be empty",
-			Status: guber.Status_UNDER_LIMIT,
+			Name:       "a token drips after a single interval",
+			Hits:       0,
+			Remaining:  1,
+			Status:     guber.Status_UNDER_LIMIT,
+			ResetAfter: 900,
+			Sleep:      clock.Millisecond * 250,
 		},
 		{
-			Req: &guber.RateLimitReq{
-				Name:     "test_missing_fields",
-				Hits:     1,
-				Duration: 10000,
-				Limit:    5,
-			},
-			Error:  "field 'unique_key' cannot be empty",
-			Status: guber.Status_UNDER_LIMIT,
+			Name:       "partial intervals are not lost",
+			Hits:       0,
+			Remaining:  3,
+			Status:     guber.Status_UNDER_LIMIT,
+			ResetAfter: 650,
+			Sleep:      clock.Duration(0),
+		},
+		{
+			Name:       "consume the dripped tokens",
+			Hits:       3,
+			Remaining:  0,
+			Status:     guber.Status_UNDER_LIMIT,
+			ResetAfter: 950,
+			Sleep:      clock.Millisecond * 50,
+		},
+		{
+			Name:       "the partial token completes",
+			Hits:       1,
+			Remaining:  0,
+			Status:     guber.Status_UNDER_LIMIT,
+			ResetAfter: 1000,
+			Sleep:      clock.Second * 2,
+		},
+		{
+			Name:       "drip does not exceed the limit",
+			Hits:       0,
+			Remaining:  10,
+			Status:     guber.Status_UNDER_LIMIT,
+			ResetAfter: 0,
+			Sleep:      clock.Duration(0),
 		},
 	}
 
-	for i, test := range tests {
+	for _, test := range tests {
 		resp, err := client.GetRateLimits(context.Background(), &guber.GetRateLimitsReq{
-			Requests: []*guber.RateLimitReq{test.Req},
+			Requests: []*guber.RateLimitReq{
+				{
+					Name:      "test_token_bucket_drip",
+					UniqueKey: "account:1234",
+					Algorithm: guber.Algorithm_TOKEN_BUCKET,
+					Behavior:  guber.Behavior_TOKEN_DRIP,
+					Duration:  guber.Second,
+					Limit:     10,
+					Hits:      test.Hits,
+				},
+			},
 		})
-		require.Nil(t, err)
-		assert.Equal(t, test.Error, resp.Responses[0].Error, i)
-		assert.Equal(t, test.Status, resp.Responses[0].Status, i)
+		require.NoError(t, err, test.Name)
+		rl := resp.Responses[0]
+		assert.Empty(t, rl.Error, test.Name)
+		assert.Equal(t, test.Status, rl.Status, test.Name)
+		assert.Equal(t, test.Remaining, rl.Remaining, test.Name)
+		assert.Equal(t, test.ResetAfter, rl.ResetTime-clock.Now().UnixMilli(), test.Name)
+		clock.Advance(test.Sleep)
 	}
 }
 
-func TestGlobalRateLimits(t *testing.T) {
-	peer := cluster.PeerAt(0).GRPCAddress
-	client, errs := guber.DialV1Server(peer, nil)
-	require.NoError(t, errs)
+func TestHybridBucket(t *testing.T) {
+	defer clock.Freeze(clock.Now()).Unfreeze()
 
-	sendHit := func(status guber.Status, remain int64, i int) string {
-		ctx, cancel := context.WithTimeout(context.Background(), clock.Second*5)
-		defer cancel()
-		resp, err := client.GetRateLimits(ctx, &guber.GetRateLimitsReq{
+	client, err := guber.DialV1Server(cluster.GetRandomPeer(cluster.DataCenterNone).GRPCAddress, nil)
+	require.NoError(t, err)
+
+	sendHit := func(algorithm guber.Algorithm, hits int64) *guber.RateLimitResp {
+		resp, err := client.GetRateLimits(context.Background(), &guber.GetRateLimitsReq{
 			Requests: []*guber.RateLimitReq{
 				{
-					Name:      "test_global",
-					UniqueKey: "account:12345",
-					Algorithm: guber.Algorithm_TOKEN_BUCKET,
-					Behavior:  guber.Behavior_GLOBAL,
-					Duration:  guber.Second * 3,
-					Hits:      1,
-					Limit:     5,
+					Name:      "test_hybrid_bucket",
+					UniqueKey: "account:" + algorithm.String(),
+					Algorithm: algorithm,
+					Duration:  guber.Minute,
+					Limit:     10,
+					Hits:      hits,
 				},
 			},
 		})
-		require.NoError(t, err, i)
-		assert.Equal(t, "", resp.Responses[0].Error, i)
-		assert.Equal(t, status, resp.Responses[0].Status, i)
-		assert.Equal(t, remain, resp.Responses[0].Remaining, i)
-		assert.Equal(t, int64(5), resp.Responses[0].Limit, i)
+		require.NoError(t, err)
+		rl := resp.Responses[0]
+		require.Empty(t, rl.Error)
+		return rl
+	}
 
-		// ensure that we have a canonical host
-		assert.NotEmpty(t, resp.Responses[0].Metadata["owner"])
+	// The same traffic is sent to each algorithm. The hybrid leaks like the leaky bucket
+	// during the window and is full again at the end of the window like the token bucket.
+	tests := []struct {
+		Name      string
+		Sleep     clock.Duration
+		Hits      int64
+		Token     int64
+		Leaky     int64
+		Hybrid    int64
+		OverLimit []guber.Algorithm
+	}{
+		{
+			Name:   "first hit drains every bucket",
+			Hits:   10,
+			Token:  0,
+			Leaky:  0,
+			Hybrid: 0,
+		},
+		{
+			Name:      "no other hit is allowed",
+			Hits:      1,
+			Token:     0,
+			Leaky:     0,
+			Hybrid:    0,
+			OverLimit: []guber.Algorithm{guber.Algorithm_TOKEN_BUCKET, guber.Algorithm_LEAKY_BUCKET, guber.Algorithm_HYBRID},
+		},
+		{
+			Name:      "half the limit leaked after half the duration",
+			Sleep:     clock.Second * 30,
+			Hits:      0,
+			Token:     0,
+			Leaky:     5,
+			Hybrid:    5,
+			OverLimit: []guber.Algorithm{guber.Algorithm_TOKEN_BUCKET},
+		},
+		{
+			Name:      "leaked hits are allowed",
+			Hits:      5,
+			Token:     0,
+			Leaky:     0,
+			Hybrid:    0,
+			OverLimit: []guber.Algorithm{guber.Algorithm_TOKEN_BUCKET},
+		},
+		{
+			Name:   "the window ends and the hybrid is full again",
+			Sleep:  clock.Second * 31,
+			Hits:   0,
+			Token:  10,
+			Leaky:  5,
+			Hybrid: 10,
+		},
+		{
+			Name:      "the full limit is allowed once the window ends",
+			Hits:      10,
+			Token:     0,
+			Leaky:     5,
+			Hybrid:    0,
+			OverLimit: []guber.Algorithm{guber.Algorithm_LEAKY_BUCKET},
+		},
+		{
+			Name:   "the hybrid leaks during the next window",
+			Sleep:  clock.Second * 12,
+			Hits:   0,
+			Token:  0,
+			Leaky:  7,
+			Hybrid: 2,
+		},
+	}
 
-		// name/key should ensure our connected peer is NOT the owner,
-		// the peer we are connected to should forward requests asynchronously to the owner.
-		assert.NotEqual(t, peer, resp.Responses[0].Metadata["owner"])
+	algorithms := []guber.Algorithm{guber.Algorithm_TOKEN_BUCKET, guber.Algorithm_LEAKY_BUCKET, guber.Algorithm_HYBRID}
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			clock.Advance(test.Sleep)
+			expected := map[guber.Algorithm]int64{
+				guber.Algorithm_TOKEN_BUCKET: test.Token,
+				guber.Algorithm_LEAKY_BUCKET: test.Leaky,
+				guber.Algorithm_HYBRID:       test.Hybrid,
+			}
+			for _, algorithm := range algorithms {
+				rl := sendHit(algorithm, test.Hits)
+				assert.Equal(t, algorithm, rl.Algorithm)
+				assert.Equal(t, expected[algorithm], rl.Remaining, algorithm.String())
 
-		return resp.Responses[0].Metadata["owner"]
+				status := guber.Status_UNDER_LIMIT
+				for _, a := range test.OverLimit {
+					if a == algorithm {
+						status = guber.Status_OVER_LIMIT
+					}
+				}
+				assert.Equal(t, status, rl.Status, algorithm.String())
+			}
+		})
 	}
+}
 
-	// Our first hit should create the request on the peer and queue for async forward
-	sendHit(guber.Status_UNDER_LIMIT, 4, 1)
+func TestHybridBucketResetTime(t *testing.T) {
+	defer clock.Freeze(clock.Now()).Unfreeze()
 
-	// Our second should be processed as if we own it since the async forward hasn't occurred yet
-	sendHit(guber.Status_UNDER_LIMIT, 3, 2)
+	client, err := guber.DialV1Server(cluster.GetRandomPeer(cluster.DataCenterNone).GRPCAddress, nil)
+	require.NoError(t, err)
 
-	testutil.UntilPass(t, 20, clock.Millisecond*200, func(t testutil.TestingT) {
-		// Inspect our metrics, ensure they collected the counts we expected during this test
-		d := cluster.DaemonAt(0)
-		config := d.Config()
-		resp, err := http.Get(fmt.Sprintf("http://%s/metrics", config.HTTPListenAddress))
-		if !assert.NoError(t, err) {
-			return
+	req := func(hits int64, behavior guber.Behavior) *guber.GetRateLimitsReq {
+		return &guber.GetRateLimitsReq{
+			Requests: []*guber.RateLimitReq{
+				{
+					Name:      "test_hybrid_bucket_reset_time",
+					UniqueKey: "account:1",
+					Algorithm: guber.Algorithm_HYBRID,
+					Behavior:  behavior,
+					Duration:  guber.Minute,
+					Limit:     10,
+					Hits:      hits,
+				},
+			},
 		}
-		defer resp.Body.Close()
+	}
+	sendHit := func(hits int64) *guber.RateLimitResp {
+		resp, err := client.GetRateLimits(context.Background(), req(hits, 0))
+		require.NoError(t, err)
+		return resp.Responses[0]
+	}
 
-		m := getMetric(t, resp.Body, "gubernator_async_durations_count")
-		assert.NotEqual(t, 0, int(m.Value))
+	// The bucket is full once the hits have leaked
+	start := clock.Now().UnixNano() / 1000000
+	rl := sendHit(4)
+	require.Empty(t, rl.Error)
+	assert.Equal(t, int64(6), rl.Remaining)
+	assert.Equal(t, start+24_000, rl.ResetTime)
 
-		// V1Instance 2 should be the owner of our global rate limit
-		d = cluster.DaemonAt(2)
-		config = d.Config()
-		resp, err = http.Get(fmt.Sprintf("http://%s/metrics", config.HTTPListenAddress))
-		if !assert.NoError(t, err) {
-			return
-		}
-		defer resp.Body.Close()
+	clock.Advance(clock.Second * 12)
+	rl = sendHit(1)
+	require.Empty(t, rl.Error)
+	assert.Equal(t, int64(7), rl.Remaining)
+	assert.Equal(t, start+30_000, rl.ResetTime)
 
-		m = getMetric(t, resp.Body, "gubernator_broadcast_durations_count")
-		assert.NotEqual(t, 0, int(m.Value))
-	})
+	// The window ends before the hits would have leaked
+	rl = sendHit(7)
+	require.Empty(t, rl.Error)
+	assert.Equal(t, int64(0), rl.Remaining)
+	assert.Equal(t, start+guber.Minute, rl.ResetTime)
+
+	// Gregorian durations are not supported
+	_, err = client.GetRateLimits(context.Background(), req(1, guber.Behavior_DURATION_IS_GREGORIAN))
+	require.Error(t, err)
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	assert.Contains(t, status.Convert(err).Message(), "is not supported by algorithm HYBRID")
 }
 
-func TestChangeLimit(t *testing.T) {
-	client, errs := guber.DialV1Server(cluster.GetRandomPeer(cluster.DataCenterNone).GRPCAddress, nil)
+func TestOverLimitOnFirstContact(t *testing.T) {
+	client, err := guber.DialV1Server(cluster.GetRandomPeer(cluster.DataCenterNone).GRPCAddress, nil)
+	require.NoError(t, err)
+
+	var responses []*guber.RateLimitResp
+	for _, algorithm := range []guber.Algorithm{guber.Algorithm_TOKEN_BUCKET, guber.Algorithm_LEAKY_BUCKET} {
+		resp, err := client.GetRateLimits(context.Background(), &guber.GetRateLimitsReq{
+			Requests: []*guber.RateLimitReq{
+				{
+					Name:      "test_over_limit_on_first_contact",
+					UniqueKey: "account:" + algorithm.String(),
+					Algorithm: algorithm,
+					Duration:  guber.Minute,
+					Limit:     10,
+					Hits:      11,
+				},
+			},
+		})
+		require.NoError(t, err)
+		rl := resp.Responses[0]
+		require.Empty(t, rl.Error)
+		assert.Equal(t, guber.Status_OVER_LIMIT, rl.Status, algorithm)
+		assert.Equal(t, int64(0), rl.Remaining, algorithm)
+		assert.Equal(t, int64(10), rl.Limit, algorithm)
+		responses = append(responses, rl)
+	}
+	assert.Equal(t, responses[0].Status, responses[1].Status)
+	assert.Equal(t, responses[0].Remaining, responses[1].Remaining)
+}
+
+func TestFirstHitFree(t *testing.T) {
+	client, err := guber.DialV1Server(cluster.GetRandomPeer(cluster.DataCenterNone).GRPCAddress, nil)
+	require.NoError(t, err)
+
+	for _, algorithm := range []guber.Algorithm{guber.Algorithm_TOKEN_BUCKET, guber.Algorithm_LEAKY_BUCKET} {
+		t.Run(algorithm.String(), func(t *testing.T) {
+			sendHit := func(hits int64) *guber.RateLimitResp {
+				resp, err := client.GetRateLimits(context.Background(), &guber.GetRateLimitsReq{
+					Requests: []*guber.RateLimitReq{
+						{
+							Name:      "test_first_hit_free",
+							UniqueKey: "account:" + algorithm.String(),
+							Algorithm: algorithm,
+							Behavior:  guber.Behavior_FIRST_HIT_FREE,
+							Duration:  guber.Minute,
+							Limit:     10,
+							Hits:      hits,
+						},
+					},
+				})
+				require.NoError(t, err)
+				rl := resp.Responses[0]
+				require.Empty(t, rl.Error)
+				return rl
+			}
+
+			// The request which creates the rate limit is allowed and drains it
+			rl := sendHit(11)
+			assert.Equal(t, guber.Status_UNDER_LIMIT, rl.Status)
+			assert.Equal(t, int64(0), rl.Remaining)
+
+			// Later requests are enforced
+			rl = sendHit(1)
+			assert.Equal(t, guber.Status_OVER_LIMIT, rl.Status)
+			assert.Equal(t, int64(0), rl.Remaining)
+
+			rl = sendHit(11)
+			assert.Equal(t, guber.Status_OVER_LIMIT, rl.Status)
+		})
+	}
+}
+
+func TestIdempotencyKey(t *testing.T) {
+	client, err := guber.DialV1Server(cluster.GetRandomPeer(cluster.DataCenterNone).GRPCAddress, nil)
+	require.NoError(t, err)
+
+	for _, algorithm := range []guber.Algorithm{guber.Algorithm_TOKEN_BUCKET, guber.Algorithm_LEAKY_BUCKET} {
+		t.Run(algorithm.String(), func(t *testing.T) {
+			sendHit := func(idempotencyKey string) *guber.RateLimitResp {
+				resp, err := client.GetRateLimits(context.Background(), &guber.GetRateLimitsReq{
+					Requests: []*guber.RateLimitReq{
+						{
+							Name:           "test_idempotency_key",
+							UniqueKey:      "account:" + algorithm.String(),
+							Algorithm:      algorithm,
+							Duration:       guber.Minute,
+							Limit:          10,
+							Hits:           1,
+							IdempotencyKey: idempotencyKey,
+						},
+					},
+				})
+				require.NoError(t, err)
+				require.Empty(t, resp.Responses[0].Error)
+				return resp.Responses[0]
+			}
+
+			assert.Equal(t, int64(9), sendHit("request-1").Remaining)
+			// A retry of the same request is not counted again
+			assert.Equal(t, int64(9), sendHit("request-1").Remaining)
+			assert.Equal(t, int64(8), sendHit("request-2").Remaining)
+			// A late retry returns the response to the original request
+			assert.Equal(t, int64(9), sendHit("request-1").Remaining)
+			// Requests without an idempotency key are always counted
+			assert.Equal(t, int64(7), sendHit("").Remaining)
+			assert.Equal(t, int64(6), sendHit("").Remaining)
+		})
+	}
+}
+
+func TestTotalHits(t *testing.T) {
+	defer clock.Freeze(clock.Now()).Unfreeze()
+
+	client, err := guber.DialV1Server(cluster.GetRandomPeer(cluster.DataCenterNone).GRPCAddress, nil)
+	require.NoError(t, err)
+
+	for _, algorithm := range []guber.Algorithm{guber.Algorithm_TOKEN_BUCKET, guber.Algorithm_LEAKY_BUCKET} {
+		t.Run(algorithm.String(), func(t *testing.T) {
+			sendHits := func(hits int64, behavior guber.Behavior) *guber.RateLimitResp {
+				resp, err := client.GetRateLimits(context.Background(), &guber.GetRateLimitsReq{
+					Requests: []*guber.RateLimitReq{
+						{
+							Name:      "test_total_hits",
+							UniqueKey: "account:" + algorithm.String(),
+							Algorithm: algorithm,
+							Behavior:  behavior,
+							Duration:  guber.Second,
+							Limit:     10,
+							Hits:      hits,
+						},
+					},
+				})
+				require.NoError(t, err)
+				require.Empty(t, resp.Responses[0].Error)
+				return resp.Responses[0]
+			}
+
+			for _, tt := range []struct {
+				hits      int64
+				totalHits int64
+			}{
+				{hits: 1, totalHits: 1},
+				{hits: 3, totalHits: 4},
+				{hits: 0, totalHits: 4},
+				// Rejected hits are not counted
+				{hits: 7, totalHits: 4},
+				{hits: 6, totalHits: 10},
+			} {
+				rl := sendHits(tt.hits, guber.Behavior_RETURN_TOTAL_HITS)
+				assert.Equal(t, tt.totalHits, rl.TotalHits, tt.hits)
+			}
+
+			// Not provided unless asked for
+			assert.Zero(t, sendHits(0, 0).TotalHits)
+
+			// The count restarts once the window resets or the bucket has leaked
+			clock.Advance(clock.Second * 2)
+			assert.Equal(t, int64(2), sendHits(2, guber.Behavior_RETURN_TOTAL_HITS).TotalHits)
+			assert.Equal(t, int64(5), sendHits(3, guber.Behavior_RETURN_TOTAL_HITS).TotalHits)
+		})
+	}
+}
+
+func TestLeakyBucketNegativeHits(t *testing.T) {
+	defer clock.Freeze(clock.Now()).Unfreeze()
+
+	client, errs := guber.DialV1Server(cluster.PeerAt(0).GRPCAddress, nil)
 	require.Nil(t, errs)
 
 	tests := []struct {
+		Name      string
+		Hits      int64
 		Remaining int64
-		Algorithm guber.Algorithm
 		Status    guber.Status
-		Name      string
-		Limit     int64
+		Sleep     clock.Duration
 	}{
 		{
-			Name:      "Should subtract 1 from remaining",
-			Algorithm: guber.Algorithm_TOKEN_BUCKET,
-			Status:    guber.Status_UNDER_LIMIT,
-			Remaining: 99,
-			Limit:     100,
-		},
-		{
-			Name:      "Should subtract 1 from remaining",
-			Algorithm: guber.Algorithm_TOKEN_BUCKET,
-			Status:    guber.Status_UNDER_LIMIT,
-			Remaining: 98,
-			Limit:     100,
-		},
-		{
-			Name:      "Should subtract 1 from remaining and change limit to 10",
-			Algorithm: guber.Algorithm_TOKEN_BUCKET,
-			Status:    guber.Status_UNDER_LIMIT,
-			Remaining: 7,
-			Limit:     10,
-		},
-		{
-			Name:      "Should subtract 1 from remaining with new limit of 10",
-			Algorithm: guber.Algorithm_TOKEN_BUCKET,
-			Status:    guber.Status_UNDER_LIMIT,
-			Remaining: 6,
-			Limit:     10,
-		},
-		{
-			Name:      "Should subtract 1 from remaining with new limit of 200",
-			Algorithm: guber.Algorithm_TOKEN_BUCKET,
+			Name:      "first hit",
+			Hits:      1,
+			Remaining: 9,
 			Status:    guber.Status_UNDER_LIMIT,
-			Remaining: 195,
-			Limit:     200,
+			Sleep:     clock.Duration(0),
 		},
 		{
-			Name:      "Should subtract 1 from remaining for leaky bucket",
-			Algorithm: guber.Algorithm_LEAKY_BUCKET,
+			Name:      "can increase remaining",
+			Hits:      -1,
+			Remaining: 10,
 			Status:    guber.Status_UNDER_LIMIT,
-			Remaining: 99,
-			Limit:     100,
+			Sleep:     clock.Duration(0),
 		},
 		{
-			Name:      "Should subtract 1 from remaining for leaky bucket after limit change",
-			Algorithm: guber.Algorithm_LEAKY_BUCKET,
+			Name:      "remaining should be zero",
+			Hits:      10,
+			Remaining: 0,
 			Status:    guber.Status_UNDER_LIMIT,
-			Remaining: 9,
-			Limit:     10,
+			Sleep:     clock.Duration(0),
 		},
 		{
-			Name:      "Should subtract 1 from remaining for leaky bucket with new limit",
-			Algorithm: guber.Algorithm_LEAKY_BUCKET,
+			Name:      "can append one to remaining when remaining is zero",
+			Hits:      -1,
+			Remaining: 1,
 			Status:    guber.Status_UNDER_LIMIT,
-			Remaining: 8,
-			Limit:     10,
+			Sleep:     clock.Duration(0),
 		},
 	}
 
-	for _, tt := range tests {
-		t.Run(tt.Name, func(t *testing.T) {
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
 			resp, err := client.GetRateLimits(context.Background(), &guber.GetRateLimitsReq{
 				Requests: []*guber.RateLimitReq{
 					{
-						Name:      "test_change_limit",
-						UniqueKey: "account:1234",
-						Algorithm: tt.Algorithm,
-						Duration:  guber.Millisecond * 9000,
-						Limit:     tt.Limit,
-						Hits:      1,
+						Name:      "test_leaky_bucket_negative",
+						UniqueKey: "account:12345",
+						Algorithm: guber.Algorithm_LEAKY_BUCKET,
+						Duration:  guber.Second * 30,
+						Hits:      test.Hits,
+						Limit:     10,
 					},
 				},
 			})
-			require.Nil(t, err)
+			require.NoError(t, err)
+			require.Len(t, resp.Responses, 1)
 
 			rl := resp.Responses[0]
 
-			assert.Equal(t, tt.Status, rl.Status)
-			assert.Equal(t, tt.Remaining, rl.Remaining)
-			assert.Equal(t, tt.Limit, rl.Limit)
-			assert.True(t, rl.ResetTime != 0)
+			assert.Equal(t, test.Status, rl.Status)
+			assert.Equal(t, test.Remaining, rl.Remaining)
+			assert.Equal(t, int64(10), rl.Limit)
+			assert.Equal(t, clock.Now().Unix()+(rl.Limit-rl.Remaining)*3, rl.ResetTime/1000)
+			clock.Advance(test.Sleep)
 		})
 	}
 }
 
-func TestResetRemaining(t *testing.T) {
-	client, errs := guber.DialV1Server(cluster.GetRandomPeer(cluster.DataCenterNone).GRPCAddress, nil)
-	require.Nil(t, errs)
+func TestExactDrain(t *testing.T) {
+	defer clock.Freeze(clock.Now()).Unfreeze()
 
-	tests := []struct {
-		Remaining int64
-		Algorithm guber.Algorithm
-		Behavior  guber.Behavior
-		Status    guber.Status
+	client, err := guber.DialV1Server(cluster.GetRandomPeer(cluster.DataCenterNone).GRPCAddress, nil)
+	require.NoError(t, err)
+
+	send := func(key string, algorithm guber.Algorithm, behavior guber.Behavior, hits int64) *guber.RateLimitResp {
+		resp, err := client.GetRateLimits(context.Background(), &guber.GetRateLimitsReq{
+			Requests: []*guber.RateLimitReq{
+				{
+					Name:      "test_exact_drain",
+					UniqueKey: key + ":" + algorithm.String(),
+					Algorithm: algorithm,
+					Behavior:  behavior,
+					Duration:  guber.Minute,
+					Limit:     10,
+					Hits:      hits,
+				},
+			},
+		})
+		require.NoError(t, err)
+		rl := resp.Responses[0]
+		require.Empty(t, rl.Error)
+		return rl
+	}
+
+	// Each request is sent to both algorithms, the final request exactly drains the bucket
+	tests := []struct {
+		Name     string
+		Requests []int64
+		Behavior guber.Behavior
+	}{
+		{
+			Name:     "new rate limit",
+			Requests: []int64{10},
+		},
+		{
+			Name:     "existing rate limit",
+			Requests: []int64{4, 6},
+		},
+		{
+			Name:     "tokens returned to a drained rate limit",
+			Requests: []int64{11, -3, 3},
+			Behavior: guber.Behavior_DRAIN_OVER_LIMIT,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			var token, leaky *guber.RateLimitResp
+			for _, hits := range test.Requests {
+				token = send(test.Name, guber.Algorithm_TOKEN_BUCKET, test.Behavior, hits)
+				leaky = send(test.Name, guber.Algorithm_LEAKY_BUCKET, test.Behavior, hits)
+			}
+
+			now := clock.Now().UnixNano() / 1000000
+			for _, rl := range []*guber.RateLimitResp{token, leaky} {
+				assert.Equal(t, guber.Status_UNDER_LIMIT, rl.Status, rl.Algorithm.String())
+				assert.Equal(t, int64(0), rl.Remaining, rl.Algorithm.String())
+				assert.Equal(t, int64(10), rl.Limit, rl.Algorithm.String())
+				assert.Equal(t, now+guber.Minute, rl.ResetTime, rl.Algorithm.String())
+			}
+
+			// A status check agrees with the response of the hit which drained the bucket
+			for _, rl := range []*guber.RateLimitResp{token, leaky} {
+				status := send(test.Name, rl.Algorithm, test.Behavior, 0)
+				assert.Equal(t, rl.Status, status.Status, rl.Algorithm.String())
+				assert.Equal(t, rl.Remaining, status.Remaining, rl.Algorithm.String())
+				assert.Equal(t, rl.ResetTime, status.ResetTime, rl.Algorithm.String())
+			}
+		})
+	}
+}
+
+func TestStatusReadDoesNotRenew(t *testing.T) {
+	defer clock.Freeze(clock.Now()).Unfreeze()
+
+	client, err := guber.DialV1Server(cluster.GetRandomPeer(cluster.DataCenterNone).GRPCAddress, nil)
+	require.NoError(t, err)
+
+	send := func(duration, hits int64) *guber.RateLimitResp {
+		resp, err := client.GetRateLimits(context.Background(), &guber.GetRateLimitsReq{
+			Requests: []*guber.RateLimitReq{
+				{
+					Name:      "test_status_read_renew",
+					UniqueKey: "account:1234",
+					Algorithm: guber.Algorithm_TOKEN_BUCKET,
+					Duration:  duration,
+					Limit:     10,
+					Hits:      hits,
+				},
+			},
+		})
+		require.NoError(t, err)
+		rl := resp.Responses[0]
+		require.Empty(t, rl.Error)
+		return rl
+	}
+
+	created := clock.Now().UnixNano() / 1000000
+	rl := send(guber.Minute, 10)
+	assert.Equal(t, int64(0), rl.Remaining)
+
+	// The shorter duration ended the window, a status read reports it as expired
+	clock.Advance(clock.Second * 30)
+	rl = send(guber.Second*10, 0)
+	assert.Equal(t, guber.Status_UNDER_LIMIT, rl.Status)
+	assert.Equal(t, int64(10), rl.Remaining)
+	assert.Equal(t, created+guber.Second*10, rl.ResetTime)
+
+	// The read did not renew the rate limit
+	rl = send(guber.Minute, 0)
+	assert.Equal(t, guber.Status_UNDER_LIMIT, rl.Status)
+	assert.Equal(t, int64(0), rl.Remaining)
+	assert.Equal(t, created+guber.Minute, rl.ResetTime)
+
+	// A hit renews the rate limit with the shorter duration
+	now := clock.Now().UnixNano() / 1000000
+	rl = send(guber.Second*10, 1)
+	assert.Equal(t, guber.Status_UNDER_LIMIT, rl.Status)
+	assert.Equal(t, int64(9), rl.Remaining)
+	assert.Equal(t, now+guber.Second*10, rl.ResetTime)
+}
+
+func TestResetBoundary(t *testing.T) {
+	// Freeze the clock at the beginning of a minute so we can land exactly on the gregorian boundary
+	defer clock.Freeze(clock.Now().Truncate(clock.Minute)).Unfreeze()
+
+	client, errs := guber.DialV1Server(cluster.GetRandomPeer(cluster.DataCenterNone).GRPCAddress, nil)
+	require.Nil(t, errs)
+
+	tests := []struct {
 		Name      string
+		Algorithm guber.Algorithm
+		Behavior  guber.Behavior
+		Duration  int64
 		Limit     int64
+		// The time after the first hit at which the next window begins
+		Window clock.Duration
+		// If true, send a hit one millisecond before the boundary which should be over the limit
+		Probe bool
+		// The remaining expected after a single hit on the boundary
+		Remaining int64
 	}{
 		{
-			Name:      "Should subtract 1 from remaining",
+			Name:      "token bucket",
 			Algorithm: guber.Algorithm_TOKEN_BUCKET,
-			Status:    guber.Status_UNDER_LIMIT,
-			Behavior:  guber.Behavior_BATCHING,
-			Remaining: 99,
-			Limit:     100,
+			Duration:  guber.Millisecond * 1000,
+			Limit:     10,
+			Window:    clock.Second,
+			Probe:     true,
+			Remaining: 9,
 		},
 		{
-			Name:      "Should subtract 2 from remaining",
+			Name:      "token bucket gregorian",
 			Algorithm: guber.Algorithm_TOKEN_BUCKET,
-			Status:    guber.Status_UNDER_LIMIT,
-			Behavior:  guber.Behavior_BATCHING,
-			Remaining: 98,
-			Limit:     100,
+			Behavior:  guber.Behavior_DURATION_IS_GREGORIAN,
+			Duration:  guber.GregorianMinutes,
+			Limit:     10,
+			Window:    clock.Minute,
+			Probe:     true,
+			Remaining: 9,
 		},
 		{
-			Name:      "Should reset the remaining",
-			Algorithm: guber.Algorithm_TOKEN_BUCKET,
-			Status:    guber.Status_UNDER_LIMIT,
-			Behavior:  guber.Behavior_RESET_REMAINING,
-			Remaining: 100,
-			Limit:     100,
+			Name:      "leaky bucket single leak",
+			Algorithm: guber.Algorithm_LEAKY_BUCKET,
+			Duration:  guber.Millisecond * 1000,
+			Limit:     10,
+			Window:    clock.Millisecond * 100,
+			Probe:     true,
+			Remaining: 0,
 		},
 		{
-			Name:      "Should subtract 1 from remaining after reset",
-			Algorithm: guber.Algorithm_TOKEN_BUCKET,
-			Status:    guber.Status_UNDER_LIMIT,
-			Behavior:  guber.Behavior_BATCHING,
-			Remaining: 99,
-			Limit:     100,
+			Name:      "leaky bucket full duration",
+			Algorithm: guber.Algorithm_LEAKY_BUCKET,
+			Duration:  guber.Millisecond * 1000,
+			Limit:     3,
+			Window:    clock.Second,
+			Remaining: 2,
+		},
+		{
+			Name:      "leaky bucket gregorian",
+			Algorithm: guber.Algorithm_LEAKY_BUCKET,
+			Behavior:  guber.Behavior_DURATION_IS_GREGORIAN,
+			Duration:  guber.GregorianMinutes,
+			Limit:     3,
+			Window:    clock.Minute,
+			Remaining: 2,
 		},
 	}
 
-	for _, tt := range tests {
+	for i, tt := range tests {
 		t.Run(tt.Name, func(t *testing.T) {
-			resp, err := client.GetRateLimits(context.Background(), &guber.GetRateLimitsReq{
-				Requests: []*guber.RateLimitReq{
-					{
-						Name:      "test_reset_remaining",
-						UniqueKey: "account:1234",
-						Algorithm: tt.Algorithm,
-						Duration:  guber.Millisecond * 9000,
-						Behavior:  tt.Behavior,
-						Limit:     tt.Limit,
-						Hits:      1,
+			sendHit := func(hits int64) *guber.RateLimitResp {
+				resp, err := client.GetRateLimits(context.Background(), &guber.GetRateLimitsReq{
+					Requests: []*guber.RateLimitReq{
+						{
+							Name:      "test_reset_boundary",
+							UniqueKey: fmt.Sprintf("account:%d", i),
+							Algorithm: tt.Algorithm,
+							Behavior:  tt.Behavior,
+							Duration:  tt.Duration,
+							Limit:     tt.Limit,
+							Hits:      hits,
+						},
 					},
-				},
-			})
-			require.Nil(t, err)
+				})
+				require.NoError(t, err)
+				require.Len(t, resp.Responses, 1)
+				rl := resp.Responses[0]
+				require.Empty(t, rl.Error)
+				return rl
+			}
 
-			rl := resp.Responses[0]
+			start := guber.MillisecondNow()
+			rl := sendHit(tt.Limit)
+			assert.Equal(t, guber.Status_UNDER_LIMIT, rl.Status)
+			assert.Equal(t, int64(0), rl.Remaining)
 
-			assert.Equal(t, tt.Status, rl.Status)
+			// One millisecond before the boundary the hit belongs to the old window
+			clock.Advance(tt.Window - clock.Millisecond)
+			if tt.Probe {
+				rl = sendHit(1)
+				assert.Equal(t, guber.Status_OVER_LIMIT, rl.Status)
+			}
+
+			// Exactly on the boundary the hit belongs to the new window
+			clock.Advance(clock.Millisecond)
+			require.Equal(t, start+tt.Window.Milliseconds(), guber.MillisecondNow())
+			rl = sendHit(1)
+			assert.Equal(t, guber.Status_UNDER_LIMIT, rl.Status)
 			assert.Equal(t, tt.Remaining, rl.Remaining)
-			assert.Equal(t, tt.Limit, rl.Limit)
+
+			// Leave the clock at the beginning of the next minute for the next test
+			clock.Advance(clock.Minute - tt.Window)
 		})
 	}
 }
 
-func TestHealthCheck(t *testing.T) {
-	client, err := guber.DialV1Server(cluster.DaemonAt(0).GRPCListeners[0].Addr().String(), nil)
-	require.NoError(t, err)
-
-	// Check that the cluster is healthy to start with
-	healthResp, err := client.HealthCheck(context.Background(), &guber.HealthCheckReq{})
-	require.NoError(t, err)
-
-	require.Equal(t, "healthy", healthResp.GetStatus())
+func TestMissingFields(t *testing.T) {
+	client, errs := guber.DialV1Server(cluster.GetRandomPeer(cluster.DataCenterNone).GRPCAddress, nil)
+	require.Nil(t, errs)
 
-	// Create a global rate limit that will need to be sent to all peers in the cluster
-	_, err = client.GetRateLimits(context.Background(), &guber.GetRateLimitsReq{
-		Requests: []*guber.RateLimitReq{
-			{
-				Name:      "test_health_check",
+	tests := []struct {
+		Req    *guber.RateLimitReq
+		Status guber.Status
+		Error  string
+	}{
+		{
+			Req: &guber.RateLimitReq{
+				Name:      "test_missing_fields",
+				UniqueKey: "account:1234",
+				Hits:      1,
+				Limit:     10,
+				Duration:  0,
+			},
+			Error:  "", // No Error
+			Status: guber.Status_UNDER_LIMIT,
+		},
+		{
+			Req: &guber.RateLimitReq{
+				Name:      "test_missing_fields",
 				UniqueKey: "account:12345",
-				Algorithm: guber.Algorithm_TOKEN_BUCKET,
-				Behavior:  guber.Behavior_BATCHING,
-				Duration:  guber.Second * 3,
 				Hits:      1,
+				Duration:  10000,
+				Limit:     0,
+			},
+			Error:  "", // No Error
+			Status: guber.Status_OVER_LIMIT,
+		},
+		{
+			Req: &guber.RateLimitReq{
+				UniqueKey: "account:1234",
+				Hits:      1,
+				Duration:  10000,
 				Limit:     5,
 			},
+			Error:  "field 'namespace' cannot be empty",
+			Status: guber.Status_UNDER_LIMIT,
+		},
+		{
+			Req: &guber.RateLimitReq{
+				Name:     "test_missing_fields",
+				Hits:     1,
+				Duration: 10000,
+				Limit:    5,
+			},
+			Error:  "field 'unique_key' cannot be empty",
+			Status: guber.Status_UNDER_LIMIT,
 		},
+	}
+
+	for i, test := range tests {
+		resp, err := client.GetRateLimits(context.Background(), &guber.GetRateLimitsReq{
+			Requests: []*guber.RateLimitReq{test.Req},
+		})
+		require.Nil(t, err)
+		assert.Equal(t, test.Error, resp.Responses[0].Error, i)
+		assert.Equal(t, test.Status, resp.Responses[0].Status, i)
+	}
+}
+
+func TestStrictKeys(t *testing.T) {
+	srv := newV1Server(t, "", guber.Config{StrictKeys: true})
+	defer srv.Close()
+
+	client, err := guber.DialV1Server(srv.listener.Addr().String(), nil)
+	require.NoError(t, err)
+	peer := guber.NewPeerClient(guber.PeerConfig{
+		Info: guber.PeerInfo{GRPCAddress: srv.listener.Addr().String()},
 	})
-	require.Nil(t, err)
 
-	// Stop the rest of the cluster to ensure errors occur on our instance and
-	// collect daemons to restart the stopped peers after the test completes
-	var daemons []*guber.Daemon
-	for i := 1; i < cluster.NumOfDaemons(); i++ {
-		d := cluster.DaemonAt(i)
-		require.NotNil(t, d)
-		d.Close()
-		daemons = append(daemons, d)
+	valid := &guber.RateLimitReq{
+		Name:      "test_strict_keys",
+		UniqueKey: "account:1234",
+		Algorithm: guber.Algorithm_TOKEN_BUCKET,
+		Duration:  guber.Minute,
+		Limit:     10,
+		Hits:      1,
 	}
 
-	// Hit the global rate limit again this time causing a connection error
-	_, err = client.GetRateLimits(context.Background(), &guber.GetRateLimitsReq{
-		Requests: []*guber.RateLimitReq{
-			{
-				Name:      "test_health_check",
-				UniqueKey: "account:12345",
-				Algorithm: guber.Algorithm_TOKEN_BUCKET,
-				Behavior:  guber.Behavior_GLOBAL,
-				Duration:  guber.Second * 3,
-				Hits:      1,
-				Limit:     5,
-			},
+	for _, test := range []struct {
+		name  string
+		req   *guber.RateLimitReq
+		error string
+	}{
+		{
+			name:  "empty unique key",
+			req:   &guber.RateLimitReq{Name: "test_strict_keys", Duration: guber.Minute, Limit: 10, Hits: 1},
+			error: "field 'unique_key' cannot be empty",
 		},
-	})
-	require.Nil(t, err)
+		{
+			name:  "empty name",
+			req:   &guber.RateLimitReq{UniqueKey: "account:1234", Duration: guber.Minute, Limit: 10, Hits: 1},
+			error: "field 'namespace' cannot be empty",
+		},
+		{
+			name:  "empty name and unique key",
+			req:   &guber.RateLimitReq{Duration: guber.Minute, Limit: 10, Hits: 1},
+			error: "field 'unique_key' cannot be empty",
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			// The whole batch is rejected, including the valid request
+			_, err := client.GetRateLimits(context.Background(), &guber.GetRateLimitsReq{
+				Requests: []*guber.RateLimitReq{valid, test.req},
+			})
+			require.Error(t, err)
+			assert.Equal(t, codes.InvalidArgument, status.Code(err))
+			assert.Equal(t, test.error, status.Convert(err).Message())
 
-	testutil.UntilPass(t, 20, clock.Millisecond*300, func(t testutil.TestingT) {
-		// Check the health again to get back the connection error
-		healthResp, err = client.HealthCheck(context.Background(), &guber.HealthCheckReq{})
-		if assert.Nil(t, err) {
-			return
-		}
+			_, err = peer.GetPeerRateLimits(context.Background(), &guber.GetPeerRateLimitsReq{
+				Requests: []*guber.RateLimitReq{valid, test.req},
+			})
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), test.error)
+		})
+	}
 
-		assert.Equal(t, "unhealthy", healthResp.GetStatus())
-		assert.Contains(t, healthResp.GetMessage(), "connect: connection refused")
+	// The valid request was not applied by the rejected batches
+	resp, err := client.GetRateLimits(context.Background(), &guber.GetRateLimitsReq{
+		Requests: []*guber.RateLimitReq{valid},
 	})
-
-	// Restart stopped instances
-	ctx, cancel := context.WithTimeout(context.Background(), clock.Second*15)
-	defer cancel()
-	require.NoError(t, cluster.Restart(ctx))
+	require.NoError(t, err)
+	assert.Empty(t, resp.Responses[0].Error)
+	assert.Equal(t, int64(9), resp.Responses[0].Remaining)
 }
 
-func TestLeakyBucketDivBug(t *testing.T) {
-	defer clock.Freeze(clock.Now()).Unfreeze()
+func TestDistinctKeysDoNotCollide(t *testing.T) {
+	client, errs := guber.DialV1Server(cluster.GetRandomPeer(cluster.DataCenterNone).GRPCAddress, nil)
+	require.Nil(t, errs)
 
-	client, err := guber.DialV1Server(cluster.GetRandomPeer(cluster.DataCenterNone).GRPCAddress, nil)
+	// Keys which differ only by case, whitespace, a prefix or a suffix each get a rate limit of their own
+	keys := []string{"account:1", "account:10", "Account:1", "account:1 ", " account:1", "account:", "1", "a"}
+	req := &guber.GetRateLimitsReq{}
+	for _, key := range keys {
+		req.Requests = append(req.Requests, &guber.RateLimitReq{
+			Name:      "test_distinct_keys_do_not_collide",
+			UniqueKey: key,
+			Algorithm: guber.Algorithm_TOKEN_BUCKET,
+			Duration:  guber.Minute,
+			Limit:     1,
+			Hits:      1,
+		})
+	}
+
+	resp, err := client.GetRateLimits(context.Background(), req)
 	require.NoError(t, err)
+	for i, rl := range resp.Responses {
+		assert.Empty(t, rl.Error, keys[i])
+		assert.Equal(t, guber.Status_UNDER_LIMIT, rl.Status, keys[i])
+		assert.Equal(t, int64(0), rl.Remaining, keys[i])
+	}
+}
 
-	resp, err := client.GetRateLimits(context.Background(), &guber.GetRateLimitsReq{
-		Requests: []*guber.RateLimitReq{
-			{
+func TestMetadataEcho(t *testing.T) {
+	newReq := func(metadata map[string]string) *guber.RateLimitReq {
+		return &guber.RateLimitReq{
+			Name:      "test_metadata_echo",
+			UniqueKey: "account:1234",
+			Algorithm: guber.Algorithm_TOKEN_BUCKET,
+			Duration:  guber.Minute,
+			Limit:     10,
+			Hits:      1,
+			Metadata:  metadata,
+		}
+	}
+
+	// Metadata is not part of the key of the rate limit
+	assert.Equal(t, newReq(nil).HashKey(), newReq(map[string]string{"route": "/v1/send"}).HashKey())
+
+	owner, err := cluster.DaemonAt(0).V1Server.GetPeer(context.Background(), newReq(nil).HashKey())
+	require.NoError(t, err)
+
+	// Ask the owner and a peer which forwards to the owner, such that both paths echo the metadata
+	addrs := []string{owner.Info().GRPCAddress}
+	for _, peer := range cluster.GetPeers() {
+		if peer.DataCenter == cluster.DataCenterNone && peer.GRPCAddress != addrs[0] {
+			addrs = append(addrs, peer.GRPCAddress)
+			break
+		}
+	}
+	require.Len(t, addrs, 2)
+
+	for i, addr := range addrs {
+		client, errs := guber.DialV1Server(addr, nil)
+		require.NoError(t, errs)
+
+		req := newReq(map[string]string{
+			"request_id": fmt.Sprintf("request-%d", i),
+			"route":      "/v1/send",
+			"served":     "spoofed",
+		})
+		resp, err := client.GetRateLimits(context.Background(), &guber.GetRateLimitsReq{
+			Requests: []*guber.RateLimitReq{req},
+		})
+		require.NoError(t, err)
+		rl := resp.Responses[0]
+		require.Empty(t, rl.Error)
+
+		// Requests with different metadata share the same bucket
+		assert.Equal(t, guber.Status_UNDER_LIMIT, rl.Status)
+		assert.Equal(t, int64(10-(i+1)), rl.Remaining)
+
+		assert.Equal(t, fmt.Sprintf("request-%d", i), rl.Metadata["request_id"])
+		assert.Equal(t, "/v1/send", rl.Metadata["route"])
+		// The keys set by the server take precedence
+		assert.NotEqual(t, "spoofed", rl.Metadata["served"])
+	}
+}
+
+func TestGlobalRateLimits(t *testing.T) {
+	peer := cluster.PeerAt(0).GRPCAddress
+	client, errs := guber.DialV1Server(peer, nil)
+	require.NoError(t, errs)
+
+	sendHit := func(status guber.Status, remain int64, i int) string {
+		ctx, cancel := context.WithTimeout(context.Background(), clock.Second*5)
+		defer cancel()
+		resp, err := client.GetRateLimits(ctx, &guber.GetRateLimitsReq{
+			Requests: []*guber.RateLimitReq{
+				{
+					Name:      "test_global",
+					UniqueKey: "account:12345",
+					Algorithm: guber.Algorithm_TOKEN_BUCKET,
+					Behavior:  guber.Behavior_GLOBAL,
+					Duration:  guber.Second * 3,
+					Hits:      1,
+					Limit:     5,
+				},
+			},
+		})
+		require.NoError(t, err, i)
+		assert.Equal(t, "", resp.Responses[0].Error, i)
+		assert.Equal(t, status, resp.Responses[0].Status, i)
+		assert.Equal(t, remain, resp.Responses[0].Remaining, i)
+		assert.Equal(t, int64(5), resp.Responses[0].Limit, i)
+
+		// ensure that we have a canonical host
+		assert.NotEmpty(t, resp.Responses[0].Metadata["owner"])
+
+		// name/key should ensure our connected peer is NOT the owner,
+		// the peer we are connected to should forward requests asynchronously to the owner.
+		assert.NotEqual(t, peer, resp.Responses[0].Metadata["owner"])
+
+		return resp.Responses[0].Metadata["owner"]
+	}
+
+	// Our first hit should create the request on the peer and queue for async forward
+	sendHit(guber.Status_UNDER_LIMIT, 4, 1)
+
+	// Our second should be processed as if we own it since the async forward hasn't occurred yet
+	sendHit(guber.Status_UNDER_LIMIT, 3, 2)
+
+	testutil.UntilPass(t, 20, clock.Millisecond*200, func(t testutil.TestingT) {
+		// Inspect our metrics, ensure they collected the counts we expected during this test
+		d := cluster.DaemonAt(0)
+		config := d.Config()
+		resp, err := http.Get(fmt.Sprintf("http://%s/metrics", config.HTTPListenAddress))
+		if !assert.NoError(t, err) {
+			return
+		}
+		defer resp.Body.Close()
+
+		m := getMetric(t, resp.Body, "gubernator_async_durations_count")
+		assert.NotEqual(t, 0, int(m.Value))
+
+		// V1Instance 2 should be the owner of our global rate limit
+		d = cluster.DaemonAt(2)
+		config = d.Config()
+		resp, err = http.Get(fmt.Sprintf("http://%s/metrics", config.HTTPListenAddress))
+		if !assert.NoError(t, err) {
+			return
+		}
+		defer resp.Body.Close()
+
+		m = getMetric(t, resp.Body, "gubernator_broadcast_durations_count")
+		assert.NotEqual(t, 0, int(m.Value))
+	})
+}
+
+func TestGlobalRateLimitsConvergence(t *testing.T) {
+	const hitsPerPeer = 20
+	const limit = 1000
+
+	var peers []guber.PeerInfo
+	for _, p := range cluster.GetPeers() {
+		if p.DataCenter == cluster.DataCenterNone {
+			peers = append(peers, p)
+		}
+	}
+
+	sendHit := func(client guber.V1Client, hits int64) *guber.RateLimitResp {
+		resp, err := client.GetRateLimits(context.Background(), &guber.GetRateLimitsReq{
+			Requests: []*guber.RateLimitReq{
+				{
+					Name:      "test_global_convergence",
+					UniqueKey: "account:1234",
+					Algorithm: guber.Algorithm_TOKEN_BUCKET,
+					Behavior:  guber.Behavior_GLOBAL,
+					Duration:  guber.Minute,
+					Hits:      hits,
+					Limit:     limit,
+				},
+			},
+		})
+		require.NoError(t, err)
+		require.Empty(t, resp.Responses[0].Error)
+		return resp.Responses[0]
+	}
+
+	// Every peer counts hits against its local copy of the rate limit concurrently
+	var clients []guber.V1Client
+	var wg sync.WaitGroup
+	for _, p := range peers {
+		client, err := guber.DialV1Server(p.GRPCAddress, nil)
+		require.NoError(t, err)
+		clients = append(clients, client)
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < hitsPerPeer; i++ {
+				sendHit(client, 1)
+			}
+		}()
+	}
+	wg.Wait()
+
+	// Once the hits have been sent to the owner and broadcast back, every peer should agree
+	expected := int64(limit - hitsPerPeer*len(peers))
+	testutil.UntilPass(t, 20, clock.Millisecond*100, func(t testutil.TestingT) {
+		for i, client := range clients {
+			rl := sendHit(client, 0)
+			assert.Equal(t, expected, rl.Remaining, peers[i].GRPCAddress)
+		}
+	})
+}
+
+func TestChangeLimit(t *testing.T) {
+	client, errs := guber.DialV1Server(cluster.GetRandomPeer(cluster.DataCenterNone).GRPCAddress, nil)
+	require.Nil(t, errs)
+
+	tests := []struct {
+		Remaining int64
+		Algorithm guber.Algorithm
+		Status    guber.Status
+		Name      string
+		Limit     int64
+	}{
+		{
+			Name:      "Should subtract 1 from remaining",
+			Algorithm: guber.Algorithm_TOKEN_BUCKET,
+			Status:    guber.Status_UNDER_LIMIT,
+			Remaining: 99,
+			Limit:     100,
+		},
+		{
+			Name:      "Should subtract 1 from remaining",
+			Algorithm: guber.Algorithm_TOKEN_BUCKET,
+			Status:    guber.Status_UNDER_LIMIT,
+			Remaining: 98,
+			Limit:     100,
+		},
+		{
+			Name:      "Should subtract 1 from remaining and change limit to 10",
+			Algorithm: guber.Algorithm_TOKEN_BUCKET,
+			Status:    guber.Status_UNDER_LIMIT,
+			Remaining: 7,
+			Limit:     10,
+		},
+		{
+			Name:      "Should subtract 1 from remaining with new limit of 10",
+			Algorithm: guber.Algorithm_TOKEN_BUCKET,
+			Status:    guber.Status_UNDER_LIMIT,
+			Remaining: 6,
+			Limit:     10,
+		},
+		{
+			Name:      "Should subtract 1 from remaining with new limit of 200",
+			Algorithm: guber.Algorithm_TOKEN_BUCKET,
+			Status:    guber.Status_UNDER_LIMIT,
+			Remaining: 195,
+			Limit:     200,
+		},
+		{
+			Name:      "Should subtract 1 from remaining for leaky bucket",
+			Algorithm: guber.Algorithm_LEAKY_BUCKET,
+			Status:    guber.Status_UNDER_LIMIT,
+			Remaining: 99,
+			Limit:     100,
+		},
+		{
+			Name:      "Should subtract 1 from remaining for leaky bucket after limit change",
+			Algorithm: guber.Algorithm_LEAKY_BUCKET,
+			Status:    guber.Status_UNDER_LIMIT,
+			Remaining: 9,
+			Limit:     10,
+		},
+		{
+			Name:      "Should subtract 1 from remaining for leaky bucket with new limit",
+			Algorithm: guber.Algorithm_LEAKY_BUCKET,
+			Status:    guber.Status_UNDER_LIMIT,
+			Remaining: 8,
+			Limit:     10,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.Name, func(t *testing.T) {
+			resp, err := client.GetRateLimits(context.Background(), &guber.GetRateLimitsReq{
+				Requests: []*guber.RateLimitReq{
+					{
+						Name:      "test_change_limit",
+						UniqueKey: "account:1234",
+						Algorithm: tt.Algorithm,
+						Duration:  guber.Millisecond * 9000,
+						Limit:     tt.Limit,
+						Hits:      1,
+					},
+				},
+			})
+			require.Nil(t, err)
+
+			rl := resp.Responses[0]
+
+			assert.Equal(t, tt.Status, rl.Status)
+			assert.Equal(t, tt.Remaining, rl.Remaining)
+			assert.Equal(t, tt.Limit, rl.Limit)
+			assert.Equal(t, tt.Algorithm, rl.Algorithm)
+			assert.True(t, rl.ResetTime != 0)
+		})
+	}
+}
+
+func TestChangeAlgorithm(t *testing.T) {
+	client, errs := guber.DialV1Server(cluster.GetRandomPeer(cluster.DataCenterNone).GRPCAddress, nil)
+	require.Nil(t, errs)
+
+	tests := []struct {
+		Name      string
+		Algorithm guber.Algorithm
+		Remaining int64
+	}{
+		{
+			Name:      "Should use token bucket",
+			Algorithm: guber.Algorithm_TOKEN_BUCKET,
+			Remaining: 9,
+		},
+		{
+			Name:      "Should reset when switching to leaky bucket",
+			Algorithm: guber.Algorithm_LEAKY_BUCKET,
+			Remaining: 9,
+		},
+		{
+			Name:      "Should subtract from leaky bucket",
+			Algorithm: guber.Algorithm_LEAKY_BUCKET,
+			Remaining: 8,
+		},
+		{
+			Name:      "Should reset when switching back to token bucket",
+			Algorithm: guber.Algorithm_TOKEN_BUCKET,
+			Remaining: 9,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.Name, func(t *testing.T) {
+			resp, err := client.GetRateLimits(context.Background(), &guber.GetRateLimitsReq{
+				Requests: []*guber.RateLimitReq{
+					{
+						Name:      "test_change_algorithm",
+						UniqueKey: "account:1234",
+						Algorithm: tt.Algorithm,
+						Duration:  guber.Minute,
+						Limit:     10,
+						Hits:      1,
+					},
+				},
+			})
+			require.Nil(t, err)
+
+			rl := resp.Responses[0]
+			assert.Empty(t, rl.Error)
+			assert.Equal(t, tt.Algorithm, rl.Algorithm)
+			assert.Equal(t, tt.Remaining, rl.Remaining)
+		})
+	}
+}
+
+func TestDisableAlgorithmMigration(t *testing.T) {
+	srv := newV1Server(t, "", guber.Config{DisableAlgorithmMigration: true})
+	defer srv.Close()
+
+	send := func(algorithm guber.Algorithm) *guber.RateLimitResp {
+		resp, err := srv.srv.GetRateLimits(context.Background(), &guber.GetRateLimitsReq{
+			Requests: []*guber.RateLimitReq{
+				{
+					Name:      "test_disable_algorithm_migration",
+					UniqueKey: "account:1234",
+					Algorithm: algorithm,
+					Duration:  guber.Minute,
+					Limit:     10,
+					Hits:      1,
+				},
+			},
+		})
+		require.NoError(t, err)
+		require.Len(t, resp.Responses, 1)
+		return resp.Responses[0]
+	}
+
+	rl := send(guber.Algorithm_TOKEN_BUCKET)
+	assert.Empty(t, rl.Error)
+	assert.Equal(t, int64(9), rl.Remaining)
+
+	// The token bucket is not replaced with a leaky bucket
+	rl = send(guber.Algorithm_LEAKY_BUCKET)
+	assert.Contains(t, rl.Error, "algorithm migration is disabled")
+	assert.Contains(t, rl.Error, "test_disable_algorithm_migration_account:1234")
+
+	rl = send(guber.Algorithm_TOKEN_BUCKET)
+	assert.Empty(t, rl.Error)
+	assert.Equal(t, int64(8), rl.Remaining)
+}
+
+func TestResetRemaining(t *testing.T) {
+	client, errs := guber.DialV1Server(cluster.GetRandomPeer(cluster.DataCenterNone).GRPCAddress, nil)
+	require.Nil(t, errs)
+
+	tests := []struct {
+		Remaining int64
+		Algorithm guber.Algorithm
+		Behavior  guber.Behavior
+		Status    guber.Status
+		Name      string
+		Limit     int64
+	}{
+		{
+			Name:      "Should subtract 1 from remaining",
+			Algorithm: guber.Algorithm_TOKEN_BUCKET,
+			Status:    guber.Status_UNDER_LIMIT,
+			Behavior:  guber.Behavior_BATCHING,
+			Remaining: 99,
+			Limit:     100,
+		},
+		{
+			Name:      "Should subtract 2 from remaining",
+			Algorithm: guber.Algorithm_TOKEN_BUCKET,
+			Status:    guber.Status_UNDER_LIMIT,
+			Behavior:  guber.Behavior_BATCHING,
+			Remaining: 98,
+			Limit:     100,
+		},
+		{
+			Name:      "Should reset the remaining",
+			Algorithm: guber.Algorithm_TOKEN_BUCKET,
+			Status:    guber.Status_UNDER_LIMIT,
+			Behavior:  guber.Behavior_RESET_REMAINING,
+			Remaining: 100,
+			Limit:     100,
+		},
+		{
+			Name:      "Should subtract 1 from remaining after reset",
+			Algorithm: guber.Algorithm_TOKEN_BUCKET,
+			Status:    guber.Status_UNDER_LIMIT,
+			Behavior:  guber.Behavior_BATCHING,
+			Remaining: 99,
+			Limit:     100,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.Name, func(t *testing.T) {
+			resp, err := client.GetRateLimits(context.Background(), &guber.GetRateLimitsReq{
+				Requests: []*guber.RateLimitReq{
+					{
+						Name:      "test_reset_remaining",
+						UniqueKey: "account:1234",
+						Algorithm: tt.Algorithm,
+						Duration:  guber.Millisecond * 9000,
+						Behavior:  tt.Behavior,
+						Limit:     tt.Limit,
+						Hits:      1,
+					},
+				},
+			})
+			require.Nil(t, err)
+
+			rl := resp.Responses[0]
+
+			assert.Equal(t, tt.Status, rl.Status)
+			assert.Equal(t, tt.Remaining, rl.Remaining)
+			assert.Equal(t, tt.Limit, rl.Limit)
+		})
+	}
+}
+
+func TestDrainOverLimit(t *testing.T) {
+	// The clock is not frozen, as the GLOBAL rate limits of the previous tests may still be
+	// syncing in the background of the cluster. The reset times are checked against the time
+	// of the request instead.
+	client, errs := guber.DialV1Server(cluster.GetRandomPeer(cluster.DataCenterNone).GRPCAddress, nil)
+	require.Nil(t, errs)
+
+	const duration = guber.Millisecond * 1000
+
+	tests := []struct {
+		Name      string
+		Key       string
+		Behavior  guber.Behavior
+		Hits      int64
+		Remaining int64
+		Status    guber.Status
+		// The reset time is a full duration from the time of the request
+		Renew bool
+		// The response has no reset time
+		NoResetTime bool
+		Sleep       clock.Duration
+	}{
+		{
+			Name:      "without drain; should subtract 8 from remaining",
+			Key:       "account:1",
+			Behavior:  guber.Behavior_BATCHING,
+			Hits:      8,
+			Remaining: 2,
+			Status:    guber.Status_UNDER_LIMIT,
+			Renew:     true,
+			Sleep:     clock.Millisecond * 100,
+		},
+		{
+			Name:      "without drain; over the limit should not change remaining or reset time",
+			Key:       "account:1",
+			Behavior:  guber.Behavior_BATCHING,
+			Hits:      5,
+			Remaining: 2,
+			Status:    guber.Status_OVER_LIMIT,
+			Sleep:     clock.Millisecond * 100,
+		},
+		{
+			Name:      "without drain; retry with fewer hits should succeed",
+			Key:       "account:1",
+			Behavior:  guber.Behavior_BATCHING,
+			Hits:      2,
+			Remaining: 0,
+			Status:    guber.Status_UNDER_LIMIT,
+			Sleep:     clock.Duration(0),
+		},
+		{
+			Name:      "with drain; should subtract 8 from remaining",
+			Key:       "account:2",
+			Behavior:  guber.Behavior_DRAIN_OVER_LIMIT,
+			Hits:      8,
+			Remaining: 2,
+			Status:    guber.Status_UNDER_LIMIT,
+			Renew:     true,
+			Sleep:     clock.Millisecond * 100,
+		},
+		{
+			Name:      "with drain; over the limit should drain remaining and advance reset time",
+			Key:       "account:2",
+			Behavior:  guber.Behavior_DRAIN_OVER_LIMIT,
+			Hits:      5,
+			Remaining: 0,
+			Status:    guber.Status_OVER_LIMIT,
+			Renew:     true,
+			Sleep:     clock.Millisecond * 100,
+		},
+		{
+			Name:      "with drain; retry with fewer hits should remain over the limit",
+			Key:       "account:2",
+			Behavior:  guber.Behavior_DRAIN_OVER_LIMIT,
+			Hits:      1,
+			Remaining: 0,
+			Status:    guber.Status_OVER_LIMIT,
+			Renew:     true,
+			Sleep:     clock.Duration(0),
+		},
+		{
+			Name:        "reset remaining takes precedence over drain",
+			Key:         "account:2",
+			Behavior:    guber.Behavior_DRAIN_OVER_LIMIT | guber.Behavior_RESET_REMAINING,
+			Hits:        0,
+			Remaining:   10,
+			Status:      guber.Status_UNDER_LIMIT,
+			NoResetTime: true,
+			Sleep:       clock.Duration(0),
+		},
+	}
+
+	var resetTime int64
+	for _, tt := range tests {
+		t.Run(tt.Name, func(t *testing.T) {
+			before := guber.MillisecondNow()
+			resp, err := client.GetRateLimits(context.Background(), &guber.GetRateLimitsReq{
+				Requests: []*guber.RateLimitReq{
+					{
+						Name:      "test_drain_over_limit",
+						UniqueKey: tt.Key,
+						Algorithm: guber.Algorithm_TOKEN_BUCKET,
+						Duration:  duration,
+						Behavior:  tt.Behavior,
+						Limit:     10,
+						Hits:      tt.Hits,
+					},
+				},
+			})
+			require.Nil(t, err)
+			after := guber.MillisecondNow()
+
+			rl := resp.Responses[0]
+
+			assert.Empty(t, rl.Error)
+			assert.Equal(t, tt.Status, rl.Status)
+			assert.Equal(t, tt.Remaining, rl.Remaining)
+			assert.Equal(t, int64(10), rl.Limit)
+			switch {
+			case tt.NoResetTime:
+				assert.Equal(t, int64(0), rl.ResetTime)
+			case tt.Renew:
+				assert.GreaterOrEqual(t, rl.ResetTime, before+duration)
+				assert.LessOrEqual(t, rl.ResetTime, after+duration)
+			default:
+				assert.Equal(t, resetTime, rl.ResetTime)
+			}
+			resetTime = rl.ResetTime
+			clock.Sleep(tt.Sleep)
+		})
+	}
+}
+
+func TestPartialConsume(t *testing.T) {
+	defer clock.Freeze(clock.Now()).Unfreeze()
+
+	client, errs := guber.DialV1Server(cluster.GetRandomPeer(cluster.DataCenterNone).GRPCAddress, nil)
+	require.Nil(t, errs)
+
+	tests := []struct {
+		Name      string
+		Key       string
+		Behavior  guber.Behavior
+		Hits      int64
+		Remaining int64
+		OverHits  int64
+		TotalHits int64
+		Status    guber.Status
+	}{
+		{
+			Name:      "without partial consume; should subtract 7 from remaining",
+			Key:       "account:1",
+			Hits:      7,
+			Remaining: 3,
+			TotalHits: 7,
+			Status:    guber.Status_UNDER_LIMIT,
+		},
+		{
+			Name:      "without partial consume; over the limit should consume nothing",
+			Key:       "account:1",
+			Hits:      5,
+			Remaining: 3,
+			TotalHits: 7,
+			Status:    guber.Status_OVER_LIMIT,
+		},
+		{
+			Name:      "with partial consume; should subtract 7 from remaining",
+			Key:       "account:2",
+			Behavior:  guber.Behavior_PARTIAL_CONSUME,
+			Hits:      7,
+			Remaining: 3,
+			TotalHits: 7,
+			Status:    guber.Status_UNDER_LIMIT,
+		},
+		{
+			Name:      "with partial consume; over the limit should consume the remaining and report the excess",
+			Key:       "account:2",
+			Behavior:  guber.Behavior_PARTIAL_CONSUME,
+			Hits:      5,
+			Remaining: 0,
+			OverHits:  2,
+			TotalHits: 10,
+			Status:    guber.Status_OVER_LIMIT,
+		},
+		{
+			Name:      "with partial consume; every hit is excess once drained",
+			Key:       "account:2",
+			Behavior:  guber.Behavior_PARTIAL_CONSUME,
+			Hits:      4,
+			Remaining: 0,
+			OverHits:  4,
+			TotalHits: 10,
+			Status:    guber.Status_OVER_LIMIT,
+		},
+		{
+			Name:      "with partial consume; a new bucket consumes the burst and reports the excess",
+			Key:       "account:3",
+			Behavior:  guber.Behavior_PARTIAL_CONSUME,
+			Hits:      12,
+			Remaining: 0,
+			OverHits:  2,
+			TotalHits: 10,
+			Status:    guber.Status_OVER_LIMIT,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			resp, err := client.GetRateLimits(context.Background(), &guber.GetRateLimitsReq{
+				Requests: []*guber.RateLimitReq{
+					{
+						Name:      "test_partial_consume",
+						UniqueKey: test.Key,
+						Algorithm: guber.Algorithm_LEAKY_BUCKET,
+						Behavior:  test.Behavior | guber.Behavior_RETURN_TOTAL_HITS,
+						Duration:  guber.Minute,
+						Limit:     10,
+						Hits:      test.Hits,
+					},
+				},
+			})
+			require.NoError(t, err)
+			rl := resp.Responses[0]
+			assert.Empty(t, rl.Error)
+			assert.Equal(t, test.Status, rl.Status)
+			assert.Equal(t, test.Remaining, rl.Remaining)
+			assert.Equal(t, test.OverHits, rl.OverHits)
+			assert.Equal(t, test.TotalHits, rl.TotalHits)
+		})
+	}
+}
+
+func TestPenaltyCooldown(t *testing.T) {
+	defer clock.Freeze(clock.Now()).Unfreeze()
+
+	client, errs := guber.DialV1Server(cluster.GetRandomPeer(cluster.DataCenterNone).GRPCAddress, nil)
+	require.Nil(t, errs)
+
+	const duration = guber.Millisecond * 1000
+	start := guber.MillisecondNow()
+
+	tests := []struct {
+		Name      string
+		Key       string
+		Penalty   int64
+		Hits      int64
+		Remaining int64
+		Status    guber.Status
+		ResetTime int64
+		Sleep     clock.Duration
+	}{
+		{
+			Name:      "should take the remainder",
+			Key:       "account:1",
+			Penalty:   3000,
+			Hits:      2,
+			Remaining: 0,
+			Status:    guber.Status_UNDER_LIMIT,
+			ResetTime: start + duration,
+			Sleep:     clock.Millisecond * 100,
+		},
+		{
+			Name:      "over the limit should begin the cooldown",
+			Key:       "account:1",
+			Penalty:   3000,
+			Hits:      1,
+			Remaining: 0,
+			Status:    guber.Status_OVER_LIMIT,
+			ResetTime: start + 100 + 3000,
+			Sleep:     clock.Millisecond * 1000,
+		},
+		{
+			Name:      "should remain over the limit after the duration has passed",
+			Key:       "account:1",
+			Penalty:   3000,
+			Hits:      1,
+			Remaining: 0,
+			Status:    guber.Status_OVER_LIMIT,
+			ResetTime: start + 100 + 3000,
+			Sleep:     clock.Millisecond * 2000,
+		},
+		{
+			Name:      "should succeed once the cooldown has passed",
+			Key:       "account:1",
+			Penalty:   3000,
+			Hits:      1,
+			Remaining: 1,
+			Status:    guber.Status_UNDER_LIMIT,
+			ResetTime: start + 3100 + duration,
+			Sleep:     clock.Duration(0),
+		},
+		{
+			Name:      "more hits than the limit should begin the cooldown",
+			Key:       "account:2",
+			Penalty:   300,
+			Hits:      5,
+			Remaining: 0,
+			Status:    guber.Status_OVER_LIMIT,
+			ResetTime: start + 3100 + 300,
+			Sleep:     clock.Millisecond * 100,
+		},
+		{
+			Name:      "status should report the cooldown",
+			Key:       "account:2",
+			Penalty:   300,
+			Hits:      0,
+			Remaining: 0,
+			Status:    guber.Status_OVER_LIMIT,
+			ResetTime: start + 3100 + 300,
+			Sleep:     clock.Millisecond * 200,
+		},
+		{
+			Name:      "should resume the duration once the cooldown has passed",
+			Key:       "account:2",
+			Penalty:   300,
+			Hits:      1,
+			Remaining: 1,
+			Status:    guber.Status_UNDER_LIMIT,
+			ResetTime: start + 3100 + duration,
+			Sleep:     clock.Duration(0),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.Name, func(t *testing.T) {
+			resp, err := client.GetRateLimits(context.Background(), &guber.GetRateLimitsReq{
+				Requests: []*guber.RateLimitReq{
+					{
+						Name:      "test_penalty_cooldown",
+						UniqueKey: tt.Key,
+						Algorithm: guber.Algorithm_TOKEN_BUCKET,
+						Duration:  duration,
+						Behavior:  guber.Behavior_PENALTY_COOLDOWN,
+						Penalty:   tt.Penalty,
+						Limit:     2,
+						Hits:      tt.Hits,
+					},
+				},
+			})
+			require.Nil(t, err)
+
+			rl := resp.Responses[0]
+
+			assert.Empty(t, rl.Error)
+			assert.Equal(t, tt.Status, rl.Status)
+			assert.Equal(t, tt.Remaining, rl.Remaining)
+			assert.Equal(t, int64(2), rl.Limit)
+			assert.Equal(t, tt.ResetTime, rl.ResetTime)
+			clock.Advance(tt.Sleep)
+		})
+	}
+}
+
+func TestSlidingPenalty(t *testing.T) {
+	defer clock.Freeze(clock.Now()).Unfreeze()
+
+	client, errs := guber.DialV1Server(cluster.GetRandomPeer(cluster.DataCenterNone).GRPCAddress, nil)
+	require.Nil(t, errs)
+
+	sendHit := func(key string, behavior guber.Behavior) *guber.RateLimitResp {
+		resp, err := client.GetRateLimits(context.Background(), &guber.GetRateLimitsReq{
+			Requests: []*guber.RateLimitReq{
+				{
+					Name:        "test_sliding_penalty",
+					UniqueKey:   key,
+					Algorithm:   guber.Algorithm_TOKEN_BUCKET,
+					Behavior:    behavior,
+					Duration:    guber.Second,
+					PenaltyStep: 500,
+					Limit:       1,
+					Hits:        1,
+				},
+			},
+		})
+		require.NoError(t, err)
+		require.Empty(t, resp.Responses[0].Error)
+		return resp.Responses[0]
+	}
+
+	for _, tt := range []struct {
+		Name     string
+		Key      string
+		Behavior guber.Behavior
+		Step     int64
+	}{
+		{Name: "without the behavior the reset time is fixed", Key: "account:1", Behavior: guber.Behavior_BATCHING},
+		{Name: "each rejected hit moves the reset time", Key: "account:2", Behavior: guber.Behavior_SLIDING_PENALTY, Step: 500},
+		{Name: "each rejected hit delays the drip", Key: "account:3", Behavior: guber.Behavior_SLIDING_PENALTY | guber.Behavior_TOKEN_DRIP, Step: 500},
+	} {
+		t.Run(tt.Name, func(t *testing.T) {
+			rl := sendHit(tt.Key, tt.Behavior)
+			assert.Equal(t, guber.Status_UNDER_LIMIT, rl.Status)
+			resetTime := rl.ResetTime
+
+			for i := 0; i < 10; i++ {
+				clock.Advance(clock.Millisecond * 50)
+				rl = sendHit(tt.Key, tt.Behavior)
+				assert.Equal(t, guber.Status_OVER_LIMIT, rl.Status)
+				assert.Equal(t, resetTime+tt.Step, rl.ResetTime)
+				resetTime = rl.ResetTime
+			}
+		})
+	}
+}
+
+func TestResetAfter(t *testing.T) {
+	defer clock.Freeze(clock.Now()).Unfreeze()
+
+	client, errs := guber.DialV1Server(cluster.GetRandomPeer(cluster.DataCenterNone).GRPCAddress, nil)
+	require.Nil(t, errs)
+
+	for _, algorithm := range []guber.Algorithm{guber.Algorithm_TOKEN_BUCKET, guber.Algorithm_LEAKY_BUCKET} {
+		t.Run(algorithm.String(), func(t *testing.T) {
+			resp, err := client.GetRateLimits(context.Background(), &guber.GetRateLimitsReq{
+				Requests: []*guber.RateLimitReq{
+					{
+						Name:      "test_reset_after",
+						UniqueKey: "account:" + algorithm.String(),
+						Algorithm: algorithm,
+						Duration:  guber.Minute,
+						Limit:     10,
+						Hits:      10,
+					},
+				},
+			})
+			require.Nil(t, err)
+
+			rl := resp.Responses[0]
+			assert.Empty(t, rl.Error)
+			assert.NotZero(t, rl.ResetAfter)
+			assert.Equal(t, rl.ResetTime-guber.MillisecondNow(), rl.ResetAfter)
+
+			clock.Advance(clock.Second)
+			resp, err = client.GetRateLimits(context.Background(), &guber.GetRateLimitsReq{
+				Requests: []*guber.RateLimitReq{
+					{
+						Name:      "test_reset_after",
+						UniqueKey: "account:" + algorithm.String(),
+						Algorithm: algorithm,
+						Duration:  guber.Minute,
+						Limit:     10,
+						Hits:      0,
+					},
+				},
+			})
+			require.Nil(t, err)
+
+			rl = resp.Responses[0]
+			assert.Empty(t, rl.Error)
+			assert.Equal(t, rl.ResetTime-guber.MillisecondNow(), rl.ResetAfter)
+		})
+	}
+}
+
+// skewedPeer answers GetPeerRateLimits as if its clock were `offset` ahead of ours
+type skewedPeer struct {
+	guber.UnimplementedPeersV1Server
+	offset int64
+}
+
+func (p *skewedPeer) GetPeerRateLimits(ctx context.Context, r *guber.GetPeerRateLimitsReq) (*guber.GetPeerRateLimitsResp, error) {
+	resp := &guber.GetPeerRateLimitsResp{}
+	for _, req := range r.Requests {
+		resp.RateLimits = append(resp.RateLimits, &guber.RateLimitResp{
+			Limit:      req.Limit,
+			Remaining:  req.Limit - req.Hits,
+			ResetTime:  guber.MillisecondNow() + p.offset + guber.Second*30,
+			ResetAfter: guber.Second * 30,
+		})
+	}
+	return resp, nil
+}
+
+func TestForwardedResetTimeClockSkew(t *testing.T) {
+	const skew = guber.Minute * 60
+
+	for _, test := range []struct {
+		Name      string
+		Tolerance clock.Duration
+		// The expected reset time relative to our clock
+		ResetAfter int64
+	}{
+		{Name: "translated to our clock", ResetAfter: guber.Second * 30},
+		{Name: "skew within the tolerance", Tolerance: clock.Hour * 2, ResetAfter: skew + guber.Second*30},
+	} {
+		t.Run(test.Name, func(t *testing.T) {
+			conf := guber.DaemonConfig{
+				GRPCListenAddress:  "127.0.0.1:9752",
+				HTTPListenAddress:  "127.0.0.1:9753",
+				ClockSkewTolerance: test.Tolerance,
+			}
+			ctx, cancel := context.WithTimeout(context.Background(), clock.Second*10)
+			defer cancel()
+			d, err := guber.SpawnDaemon(ctx, conf)
+			require.NoError(t, err)
+			defer d.Close()
+
+			// The owner of the rate limit, whose clock is an hour ahead of ours
+			l, err := net.Listen("tcp", "127.0.0.1:9754")
+			require.NoError(t, err)
+			srv := grpc.NewServer()
+			guber.RegisterPeersV1Server(srv, &skewedPeer{offset: skew})
+			go func() { _ = srv.Serve(l) }()
+			defer srv.Stop()
+
+			d.SetPeers([]guber.PeerInfo{{GRPCAddress: conf.GRPCListenAddress}, {GRPCAddress: l.Addr().String()}})
+
+			var key string
+			for i := 0; ; i++ {
+				key = fmt.Sprintf("account:%d", i)
+				owner, err := d.V1Server.GetPeer(ctx, "test_forwarded_reset_time_"+key)
+				require.NoError(t, err)
+				if owner.Info().GRPCAddress == l.Addr().String() {
+					break
+				}
+			}
+
+			client, err := guber.DialV1Server(conf.GRPCListenAddress, nil)
+			require.NoError(t, err)
+			resp, err := client.GetRateLimits(ctx, &guber.GetRateLimitsReq{
+				Requests: []*guber.RateLimitReq{
+					{
+						Name:      "test_forwarded_reset_time",
+						UniqueKey: key,
+						Behavior:  guber.Behavior_NO_BATCHING,
+						Duration:  guber.Minute,
+						Limit:     10,
+						Hits:      1,
+					},
+				},
+			})
+			require.NoError(t, err)
+			rl := resp.Responses[0]
+			require.Empty(t, rl.Error)
+			assert.Equal(t, "forward", rl.Metadata["served"])
+
+			// Allow for the time spent forwarding the request
+			assert.InDelta(t, test.ResetAfter, rl.ResetTime-guber.MillisecondNow(), 1000)
+			assert.InDelta(t, test.ResetAfter, rl.ResetAfter, 1000)
+		})
+	}
+}
+
+func TestPeek(t *testing.T) {
+	defer clock.Freeze(clock.Now()).Unfreeze()
+
+	client, errs := guber.DialV1Server(cluster.GetRandomPeer(cluster.DataCenterNone).GRPCAddress, nil)
+	require.Nil(t, errs)
+
+	for _, algorithm := range []guber.Algorithm{guber.Algorithm_TOKEN_BUCKET, guber.Algorithm_LEAKY_BUCKET} {
+		t.Run(algorithm.String(), func(t *testing.T) {
+			sendHit := func(behavior guber.Behavior, hits int64) *guber.RateLimitResp {
+				resp, err := client.GetRateLimits(context.Background(), &guber.GetRateLimitsReq{
+					Requests: []*guber.RateLimitReq{
+						{
+							Name:      "test_peek",
+							UniqueKey: "account:" + algorithm.String(),
+							Algorithm: algorithm,
+							Behavior:  behavior,
+							Duration:  guber.Minute,
+							Limit:     10,
+							Hits:      hits,
+						},
+					},
+				})
+				require.Nil(t, err)
+				require.Empty(t, resp.Responses[0].Error)
+				return resp.Responses[0]
+			}
+
+			// Peek should not create the rate limit
+			rl := sendHit(guber.Behavior_PEEK, 4)
+			assert.Equal(t, guber.Status_UNDER_LIMIT, rl.Status)
+			assert.Equal(t, int64(6), rl.Remaining)
+
+			rl = sendHit(guber.Behavior_BATCHING, 3)
+			assert.Equal(t, guber.Status_UNDER_LIMIT, rl.Status)
+			assert.Equal(t, int64(7), rl.Remaining)
+
+			// Repeated peeks should report the same result without consuming the hits
+			for i := 0; i < 3; i++ {
+				rl = sendHit(guber.Behavior_PEEK, 5)
+				assert.Equal(t, guber.Status_UNDER_LIMIT, rl.Status)
+				assert.Equal(t, int64(2), rl.Remaining)
+			}
+
+			rl = sendHit(guber.Behavior_PEEK, 8)
+			assert.Equal(t, guber.Status_OVER_LIMIT, rl.Status)
+
+			rl = sendHit(guber.Behavior_PEEK, 7)
+			assert.Equal(t, guber.Status_UNDER_LIMIT, rl.Status)
+			assert.Equal(t, int64(0), rl.Remaining)
+
+			rl = sendHit(guber.Behavior_BATCHING, 0)
+			assert.Equal(t, guber.Status_UNDER_LIMIT, rl.Status)
+			assert.Equal(t, int64(7), rl.Remaining)
+		})
+	}
+}
+
+func TestAtomicGetRateLimits(t *testing.T) {
+	client, errs := guber.DialV1Server(cluster.GetRandomPeer(cluster.DataCenterNone).GRPCAddress, nil)
+	require.Nil(t, errs)
+
+	newReqs := func(hits int64) *guber.GetRateLimitsReq {
+		return &guber.GetRateLimitsReq{
+			Requests: []*guber.RateLimitReq{
+				{
+					Name:      "test_atomic_account",
+					UniqueKey: "account:1234",
+					Algorithm: guber.Algorithm_TOKEN_BUCKET,
+					Duration:  guber.Minute,
+					Limit:     10,
+					Hits:      hits,
+				},
+				{
+					Name:      "test_atomic_ip",
+					UniqueKey: "ip:10.2.10.7",
+					Algorithm: guber.Algorithm_LEAKY_BUCKET,
+					Duration:  guber.Minute,
+					Limit:     2,
+					Hits:      hits,
+				},
+			},
+		}
+	}
+
+	// Both rate limits are under the limit, the hits should be applied to both
+	for _, remaining := range []int64{1, 0} {
+		resp, err := client.AtomicGetRateLimits(context.Background(), newReqs(1))
+		require.NoError(t, err)
+		require.Len(t, resp.Responses, 2)
+		for _, rl := range resp.Responses {
+			assert.Empty(t, rl.Error)
+			assert.Equal(t, guber.Status_UNDER_LIMIT, rl.Status)
+		}
+		assert.Equal(t, 8+remaining, resp.Responses[0].Remaining)
+		assert.Equal(t, remaining, resp.Responses[1].Remaining)
+	}
+
+	// The ip rate limit would reject the hit, so neither rate limit should change
+	resp, err := client.AtomicGetRateLimits(context.Background(), newReqs(1))
+	require.NoError(t, err)
+	require.Len(t, resp.Responses, 2)
+	assert.Equal(t, guber.Status_UNDER_LIMIT, resp.Responses[0].Status)
+	assert.Equal(t, guber.Status_OVER_LIMIT, resp.Responses[1].Status)
+
+	resp, err = client.GetRateLimits(context.Background(), newReqs(0))
+	require.NoError(t, err)
+	require.Len(t, resp.Responses, 2)
+	assert.Equal(t, int64(8), resp.Responses[0].Remaining)
+	assert.Equal(t, int64(0), resp.Responses[1].Remaining)
+
+	// Each request for the same rate limit would be allowed on its own, but not together. The hit
+	// which was applied should be rolled back.
+	dup := &guber.RateLimitReq{
+		Name:      "test_atomic_dup",
+		UniqueKey: "account:1234",
+		Algorithm: guber.Algorithm_TOKEN_BUCKET,
+		Duration:  guber.Minute,
+		Limit:     1,
+		Hits:      1,
+	}
+	resp, err = client.AtomicGetRateLimits(context.Background(), &guber.GetRateLimitsReq{
+		Requests: []*guber.RateLimitReq{dup, dup},
+	})
+	require.NoError(t, err)
+	require.Len(t, resp.Responses, 2)
+	assert.NotEqual(t, resp.Responses[0].Status, resp.Responses[1].Status)
+
+	dup.Hits = 0
+	resp, err = client.GetRateLimits(context.Background(), &guber.GetRateLimitsReq{
+		Requests: []*guber.RateLimitReq{dup},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), resp.Responses[0].Remaining)
+}
+
+func TestNamespaceDefaults(t *testing.T) {
+	srv := newV1Server(t, "", guber.Config{
+		NamespaceDefaults: map[string]*guber.RateLimitReq{
+			"test_namespace_defaults": {
+				Algorithm: guber.Algorithm_LEAKY_BUCKET,
+				Duration:  guber.Minute,
+				Limit:     5,
+			},
+		},
+	})
+	defer srv.Close()
+
+	client, err := guber.DialV1Server(srv.listener.Addr().String(), nil)
+	require.NoError(t, err)
+
+	// Only the name and key are provided; the rest is inherited from the default
+	resp, err := client.GetRateLimits(context.Background(), &guber.GetRateLimitsReq{
+		Requests: []*guber.RateLimitReq{
+			{
+				Name:      "test_namespace_defaults",
+				UniqueKey: "account:1234",
+				Hits:      1,
+			},
+		},
+	})
+	require.NoError(t, err)
+	rl := resp.Responses[0]
+	assert.Empty(t, rl.Error)
+	assert.Equal(t, guber.Algorithm_LEAKY_BUCKET, rl.Algorithm)
+	assert.Equal(t, int64(5), rl.Limit)
+	assert.Equal(t, int64(4), rl.Remaining)
+
+	// Fields provided by the request override the default
+	resp, err = client.GetRateLimits(context.Background(), &guber.GetRateLimitsReq{
+		Requests: []*guber.RateLimitReq{
+			{
+				Name:      "test_namespace_defaults",
+				UniqueKey: "account:5678",
+				Limit:     10,
+				Hits:      1,
+			},
+		},
+	})
+	require.NoError(t, err)
+	rl = resp.Responses[0]
+	assert.Empty(t, rl.Error)
+	assert.Equal(t, guber.Algorithm_LEAKY_BUCKET, rl.Algorithm)
+	assert.Equal(t, int64(10), rl.Limit)
+	assert.Equal(t, int64(9), rl.Remaining)
+
+	// The zero value TOKEN_BUCKET cannot be told apart from an unset algorithm
+	resp, err = client.GetRateLimits(context.Background(), &guber.GetRateLimitsReq{
+		Requests: []*guber.RateLimitReq{
+			{
+				Name:      "test_namespace_defaults",
+				UniqueKey: "account:9012",
+				Algorithm: guber.Algorithm_TOKEN_BUCKET,
+				Hits:      1,
+			},
+		},
+	})
+	require.NoError(t, err)
+	rl = resp.Responses[0]
+	assert.Empty(t, rl.Error)
+	assert.Equal(t, guber.Algorithm_LEAKY_BUCKET, rl.Algorithm)
+
+	// Unless the request lists it as explicitly set
+	resp, err = client.GetRateLimits(context.Background(), &guber.GetRateLimitsReq{
+		Requests: []*guber.RateLimitReq{
+			{
+				Name:           "test_namespace_defaults",
+				UniqueKey:      "account:3456",
+				Algorithm:      guber.Algorithm_TOKEN_BUCKET,
+				ExplicitFields: []string{"algorithm"},
+				Hits:           1,
+			},
+		},
+	})
+	require.NoError(t, err)
+	rl = resp.Responses[0]
+	assert.Empty(t, rl.Error)
+	assert.Equal(t, guber.Algorithm_TOKEN_BUCKET, rl.Algorithm)
+	assert.Equal(t, int64(5), rl.Limit)
+	assert.Equal(t, int64(4), rl.Remaining)
+
+	// Names without a default are unchanged
+	resp, err = client.GetRateLimits(context.Background(), &guber.GetRateLimitsReq{
+		Requests: []*guber.RateLimitReq{
+			{
+				Name:      "test_namespace_no_defaults",
+				UniqueKey: "account:1234",
+				Duration:  guber.Minute,
+				Limit:     2,
+				Hits:      1,
+			},
+		},
+	})
+	require.NoError(t, err)
+	rl = resp.Responses[0]
+	assert.Empty(t, rl.Error)
+	assert.Equal(t, guber.Algorithm_TOKEN_BUCKET, rl.Algorithm)
+	assert.Equal(t, int64(2), rl.Limit)
+}
+
+func TestReturnConfig(t *testing.T) {
+	store := &syncStore{items: make(map[string]*guber.CacheItem)}
+	srv := newV1Server(t, "", guber.Config{
+		NamespaceDefaults: map[string]*guber.RateLimitReq{
+			"test_return_config": {
+				Algorithm: guber.Algorithm_LEAKY_BUCKET,
+				Duration:  guber.Minute,
+				Limit:     5,
+				Burst:     8,
+			},
+		},
+		Store: store,
+	})
+	defer srv.Close()
+
+	client, err := guber.DialV1Server(srv.listener.Addr().String(), nil)
+	require.NoError(t, err)
+
+	for _, test := range []struct {
+		Name     string
+		Req      *guber.RateLimitReq
+		Expected *guber.RateLimitConfig
+	}{
+		{
+			Name: "without behavior",
+			Req:  &guber.RateLimitReq{Hits: 1},
+		},
+		{
+			Name: "config from namespace defaults",
+			Req:  &guber.RateLimitReq{Hits: 1, Behavior: guber.Behavior_RETURN_CONFIG},
+			Expected: &guber.RateLimitConfig{
+				Algorithm: guber.Algorithm_LEAKY_BUCKET,
+				Duration:  guber.Minute,
+				Limit:     5,
+				Burst:     8,
+			},
+		},
+		{
+			Name: "config changed by request",
+			Req: &guber.RateLimitReq{
+				Hits:     1,
+				Behavior: guber.Behavior_RETURN_CONFIG,
+				Duration: guber.Minute * 2,
+				Limit:    10,
+				Burst:    12,
+			},
+			Expected: &guber.RateLimitConfig{
+				Algorithm: guber.Algorithm_LEAKY_BUCKET,
+				Duration:  guber.Minute * 2,
+				Limit:     10,
+				Burst:     12,
+			},
+		},
+		{
+			Name: "token bucket without namespace defaults",
+			Req: &guber.RateLimitReq{
+				Name:      "test_return_config_token",
+				Hits:      1,
+				Behavior:  guber.Behavior_RETURN_CONFIG,
+				Algorithm: guber.Algorithm_TOKEN_BUCKET,
+				Duration:  guber.Minute * 3,
+				Limit:     20,
+			},
+			Expected: &guber.RateLimitConfig{
+				Algorithm: guber.Algorithm_TOKEN_BUCKET,
+				Duration:  guber.Minute * 3,
+				Limit:     20,
+				Burst:     20,
+			},
+		},
+	} {
+		t.Run(test.Name, func(t *testing.T) {
+			if test.Req.Name == "" {
+				test.Req.Name = "test_return_config"
+			}
+			test.Req.UniqueKey = "account:1234"
+			resp, err := client.GetRateLimits(context.Background(), &guber.GetRateLimitsReq{
+				Requests: []*guber.RateLimitReq{test.Req},
+			})
+			require.NoError(t, err)
+			rl := resp.Responses[0]
+			require.Empty(t, rl.Error)
+
+			if test.Expected == nil {
+				assert.Nil(t, rl.Config)
+				return
+			}
+			require.NotNil(t, rl.Config)
+			assert.Equal(t, test.Expected.Algorithm, rl.Config.Algorithm)
+			assert.Equal(t, test.Expected.Limit, rl.Config.Limit)
+			assert.Equal(t, test.Expected.Duration, rl.Config.Duration)
+			assert.Equal(t, test.Expected.Burst, rl.Config.Burst)
+
+			// The config should match the item held in the store
+			store.mutex.Lock()
+			item := store.items[test.Req.HashKey()]
+			store.mutex.Unlock()
+			require.NotNil(t, item)
+			assert.Equal(t, item.Algorithm, rl.Config.Algorithm)
+			switch v := item.Value.(type) {
+			case *guber.TokenBucketItem:
+				assert.Equal(t, v.Limit, rl.Config.Limit)
+				assert.Equal(t, v.Duration, rl.Config.Duration)
+			case *guber.LeakyBucketItem:
+				assert.Equal(t, v.Limit, rl.Config.Limit)
+				assert.Equal(t, v.Duration, rl.Config.Duration)
+				assert.Equal(t, v.Burst, rl.Config.Burst)
+			}
+		})
+	}
+}
+
+func TestMaxDurationAndHits(t *testing.T) {
+	srv := newV1Server(t, "", guber.Config{
+		MaxDuration: clock.Minute,
+		MaxHits:     10,
+	})
+	defer srv.Close()
+
+	client, err := guber.DialV1Server(srv.listener.Addr().String(), nil)
+	require.NoError(t, err)
+
+	req := func(key string, hits, duration int64) *guber.RateLimitReq {
+		return &guber.RateLimitReq{
+			Name:      "test_max_duration_hits",
+			UniqueKey: key,
+			Algorithm: guber.Algorithm_TOKEN_BUCKET,
+			Duration:  duration,
+			Limit:     100,
+			Hits:      hits,
+		}
+	}
+
+	// Values at the boundary are accepted
+	resp, err := client.GetRateLimits(context.Background(), &guber.GetRateLimitsReq{
+		Requests: []*guber.RateLimitReq{req("account:1", 10, guber.Minute)},
+	})
+	require.NoError(t, err)
+	assert.Empty(t, resp.Responses[0].Error)
+	assert.Equal(t, int64(90), resp.Responses[0].Remaining)
+
+	for _, test := range []struct {
+		name string
+		req  *guber.RateLimitReq
+	}{
+		{name: "duration", req: req("account:2", 1, guber.Minute+1)},
+		{name: "hits", req: req("account:2", 11, guber.Minute)},
+		{name: "negative hits", req: req("account:2", -11, guber.Minute)},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			// The whole batch is rejected, including the valid request
+			_, err := client.GetRateLimits(context.Background(), &guber.GetRateLimitsReq{
+				Requests: []*guber.RateLimitReq{req("account:1", 1, guber.Minute), test.req},
+			})
+			require.Error(t, err)
+			assert.Equal(t, codes.InvalidArgument, status.Code(err))
+		})
+	}
+
+	// Neither rate limit was touched by the rejected batches
+	resp, err = client.GetRateLimits(context.Background(), &guber.GetRateLimitsReq{
+		Requests: []*guber.RateLimitReq{req("account:1", 0, guber.Minute), req("account:2", 0, guber.Minute)},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, int64(90), resp.Responses[0].Remaining)
+	assert.Equal(t, int64(100), resp.Responses[1].Remaining)
+}
+
+func TestLargeDurations(t *testing.T) {
+	srv := newV1Server(t, "", guber.Config{})
+	defer srv.Close()
+	defer clock.Freeze(clock.Now()).Unfreeze()
+
+	const thousandYears = guber.Minute * 60 * 24 * 366 * 1000
+	send := func(r *guber.RateLimitReq) (*guber.RateLimitResp, error) {
+		r.Name = "test_large_durations"
+		r.Algorithm = guber.Algorithm_TOKEN_BUCKET
+		resp, err := srv.srv.GetRateLimits(context.Background(), &guber.GetRateLimitsReq{
+			Requests: []*guber.RateLimitReq{r},
+		})
+		if err != nil {
+			return nil, err
+		}
+		require.Empty(t, resp.Responses[0].Error)
+		return resp.Responses[0], nil
+	}
+
+	t.Run("Boundary durations", func(t *testing.T) {
+		rl, err := send(&guber.RateLimitReq{UniqueKey: "account:1", Duration: thousandYears, Limit: 10, Hits: 1})
+		require.NoError(t, err)
+		assert.Equal(t, guber.MillisecondNow()+thousandYears, rl.ResetTime)
+
+		for _, duration := range []int64{thousandYears + 1, math.MaxInt64, -1, math.MinInt64} {
+			_, err := send(&guber.RateLimitReq{UniqueKey: "account:2", Duration: duration, Limit: 10, Hits: 1})
+			require.Error(t, err, duration)
+			assert.Equal(t, codes.InvalidArgument, status.Code(err), duration)
+		}
+	})
+
+	t.Run("Growing durations", func(t *testing.T) {
+		var last int64
+		for _, duration := range []int64{guber.Minute, guber.Minute * 60 * 24 * 366, thousandYears} {
+			rl, err := send(&guber.RateLimitReq{UniqueKey: "account:3", Duration: duration, Limit: 10, Hits: 1})
+			require.NoError(t, err)
+			assert.Greater(t, rl.ResetTime, last, duration)
+			last = rl.ResetTime
+		}
+	})
+
+	t.Run("Saturating penalties", func(t *testing.T) {
+		var last int64
+		for i := 0; i < 5; i++ {
+			rl, err := send(&guber.RateLimitReq{
+				UniqueKey:   "account:4",
+				Behavior:    guber.Behavior_SLIDING_PENALTY,
+				Duration:    thousandYears,
+				PenaltyStep: math.MaxInt64 / 2,
+				Limit:       1,
+				Hits:        1,
+			})
+			require.NoError(t, err)
+			// The reset time never moves backwards, even once it can no longer move forwards
+			assert.GreaterOrEqual(t, rl.ResetTime, last)
+			last = rl.ResetTime
+		}
+		assert.Equal(t, int64(math.MaxInt64), last)
+
+		rl, err := send(&guber.RateLimitReq{
+			UniqueKey: "account:5",
+			Behavior:  guber.Behavior_PENALTY_COOLDOWN,
+			Duration:  guber.Minute,
+			Penalty:   math.MaxInt64,
+			Limit:     1,
+			Hits:      2,
+		})
+		require.NoError(t, err)
+		assert.Equal(t, guber.Status_OVER_LIMIT, rl.Status)
+		assert.Equal(t, int64(math.MaxInt64), rl.ResetTime)
+	})
+
+	t.Run("Leaky bucket boundary duration", func(t *testing.T) {
+		// The new item must not expire before the second request
+		for _, expected := range []int64{9, 8} {
+			resp, err := srv.srv.GetRateLimits(context.Background(), &guber.GetRateLimitsReq{
+				Requests: []*guber.RateLimitReq{{
+					Name:      "test_large_durations",
+					UniqueKey: "account:6",
+					Algorithm: guber.Algorithm_LEAKY_BUCKET,
+					Duration:  thousandYears,
+					Limit:     10,
+					Hits:      1,
+				}},
+			})
+			require.NoError(t, err)
+			rl := resp.Responses[0]
+			require.Empty(t, rl.Error)
+			assert.Equal(t, expected, rl.Remaining)
+		}
+	})
+}
+
+func TestCascadingRateLimits(t *testing.T) {
+	client, errs := guber.DialV1Server(cluster.GetRandomPeer(cluster.DataCenterNone).GRPCAddress, nil)
+	require.Nil(t, errs)
+
+	newReq := func(name, key string, limit, hits int64) *guber.RateLimitReq {
+		return &guber.RateLimitReq{
+			Name:      name,
+			UniqueKey: key,
+			Algorithm: guber.Algorithm_TOKEN_BUCKET,
+			Duration:  guber.Minute,
+			Limit:     limit,
+			Hits:      hits,
+		}
+	}
+	// Each user may make 5 requests, but the users of the org may only make 3 between them
+	newCascade := func(user string) *guber.RateLimitReq {
+		req := newReq("test_cascade_user", user, 5, 1)
+		req.Parents = []*guber.RateLimitReq{
+			newReq("test_cascade_org", "org:1", 3, 0),
+			newReq("test_cascade_account", "account:1", 10, 0),
+		}
+		return req
+	}
+	send := func(req *guber.RateLimitReq) *guber.RateLimitResp {
+		resp, err := client.GetRateLimits(context.Background(), &guber.GetRateLimitsReq{
+			Requests: []*guber.RateLimitReq{req},
+		})
+		require.NoError(t, err)
+		require.Len(t, resp.Responses, 1)
+		return resp.Responses[0]
+	}
+
+	for i, user := range []string{"user:1", "user:1", "user:2"} {
+		rl := send(newCascade(user))
+		assert.Empty(t, rl.Error, i)
+		assert.Equal(t, guber.Status_UNDER_LIMIT, rl.Status, i)
+		require.Len(t, rl.Parents, 2, i)
+		assert.Equal(t, int64(2-i), rl.Parents[0].Remaining, i)
+		assert.Equal(t, int64(9-i), rl.Parents[1].Remaining, i)
+	}
+
+	// The org limit is exhausted, so the hit is rejected even though user:2 has room
+	rl := send(newCascade("user:2"))
+	assert.Equal(t, guber.Status_OVER_LIMIT, rl.Status)
+	require.Len(t, rl.Parents, 2)
+	assert.Equal(t, guber.Status_OVER_LIMIT, rl.Parents[0].Status)
+	assert.Equal(t, guber.Status_UNDER_LIMIT, rl.Parents[1].Status)
+
+	// None of the levels were changed by the rejected hit
+	assert.Equal(t, int64(4), send(newReq("test_cascade_user", "user:2", 5, 0)).Remaining)
+	assert.Equal(t, int64(0), send(newReq("test_cascade_org", "org:1", 3, 0)).Remaining)
+	assert.Equal(t, int64(7), send(newReq("test_cascade_account", "account:1", 10, 0)).Remaining)
+
+	// Parents cannot have parents of their own
+	req := newCascade("user:3")
+	req.Parents[0].Parents = []*guber.RateLimitReq{newReq("test_cascade_account", "account:1", 10, 0)}
+	_, err := client.GetRateLimits(context.Background(), &guber.GetRateLimitsReq{
+		Requests: []*guber.RateLimitReq{req},
+	})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+}
+
+func TestHealthCheck(t *testing.T) {
+	client, err := guber.DialV1Server(cluster.DaemonAt(0).GRPCListeners[0].Addr().String(), nil)
+	require.NoError(t, err)
+
+	// Check that the cluster is healthy to start with
+	healthResp, err := client.HealthCheck(context.Background(), &guber.HealthCheckReq{})
+	require.NoError(t, err)
+
+	require.Equal(t, "healthy", healthResp.GetStatus())
+
+	// Create a global rate limit that will need to be sent to all peers in the cluster
+	_, err = client.GetRateLimits(context.Background(), &guber.GetRateLimitsReq{
+		Requests: []*guber.RateLimitReq{
+			{
+				Name:      "test_health_check",
+				UniqueKey: "account:12345",
+				Algorithm: guber.Algorithm_TOKEN_BUCKET,
+				Behavior:  guber.Behavior_BATCHING,
+				Duration:  guber.Second * 3,
+				Hits:      1,
+				Limit:     5,
+			},
+		},
+	})
+	require.Nil(t, err)
+
+	// Stop the rest of the cluster to ensure errors occur on our instance and
+	// collect daemons to restart the stopped peers after the test completes
+	var daemons []*guber.Daemon
+	for i := 1; i < cluster.NumOfDaemons(); i++ {
+		d := cluster.DaemonAt(i)
+		require.NotNil(t, d)
+		d.Close()
+		daemons = append(daemons, d)
+	}
+
+	// Hit the global rate limit again this time causing a connection error
+	_, err = client.GetRateLimits(context.Background(), &guber.GetRateLimitsReq{
+		Requests: []*guber.RateLimitReq{
+			{
+				Name:      "test_health_check",
+				UniqueKey: "account:12345",
+				Algorithm: guber.Algorithm_TOKEN_BUCKET,
+				Behavior:  guber.Behavior_GLOBAL,
+				Duration:  guber.Second * 3,
+				Hits:      1,
+				Limit:     5,
+			},
+		},
+	})
+	require.Nil(t, err)
+
+	testutil.UntilPass(t, 20, clock.Millisecond*300, func(t testutil.TestingT) {
+		// Check the health again to get back the connection error
+		healthResp, err = client.HealthCheck(context.Background(), &guber.HealthCheckReq{})
+		if assert.Nil(t, err) {
+			return
+		}
+
+		assert.Equal(t, "unhealthy", healthResp.GetStatus())
+		assert.Contains(t, healthResp.GetMessage(), "connect: connection refused")
+	})
+
+	// Restart stopped instances
+	ctx, cancel := context.WithTimeout(context.Background(), clock.Second*15)
+	defer cancel()
+	require.NoError(t, cluster.Restart(ctx))
+}
+
+func TestLeakyBucketDivBug(t *testing.T) {
+	defer clock.Freeze(clock.Now()).Unfreeze()
+
+	client, err := guber.DialV1Server(cluster.GetRandomPeer(cluster.DataCenterNone).GRPCAddress, nil)
+	require.NoError(t, err)
+
+	resp, err := client.GetRateLimits(context.Background(), &guber.GetRateLimitsReq{
+		Requests: []*guber.RateLimitReq{
+			{
+				Name:      "test_leaky_bucket_div",
+				UniqueKey: "account:12345",
+				Algorithm: guber.Algorithm_LEAKY_BUCKET,
+				Duration:  guber.Millisecond * 1000,
+				Hits:      1,
+				Limit:     2000,
+			},
+		},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "", resp.Responses[0].Error)
+	assert.Equal(t, guber.Status_UNDER_LIMIT, resp.Responses[0].Status)
+	assert.Equal(t, int64(1999), resp.Responses[0].Remaining)
+	assert.Equal(t, int64(2000), resp.Responses[0].Limit)
+
+	// Should result in a rate of 0.5
+	resp, err = client.GetRateLimits(context.Background(), &guber.GetRateLimitsReq{
+		Requests: []*guber.RateLimitReq{
+			{
 				Name:      "test_leaky_bucket_div",
 				UniqueKey: "account:12345",
 				Algorithm: guber.Algorithm_LEAKY_BUCKET,
 				Duration:  guber.Millisecond * 1000,
+				Hits:      100,
+				Limit:     2000,
+			},
+		},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, int64(1899), resp.Responses[0].Remaining)
+	assert.Equal(t, int64(2000), resp.Responses[0].Limit)
+}
+
+func TestLeakyBucketResetTime(t *testing.T) {
+	defer clock.Freeze(clock.Now()).Unfreeze()
+
+	client, err := guber.DialV1Server(cluster.GetRandomPeer(cluster.DataCenterNone).GRPCAddress, nil)
+	require.NoError(t, err)
+
+	const limit = 7
+	// A rate of 8571.428... milliseconds per hit
+	rate := float64(guber.Minute) / limit
+
+	for _, tc := range []struct {
+		hits      int64
+		remaining int64
+	}{
+		// Creates the rate limit
+		{hits: 1, remaining: 6},
+		{hits: 3, remaining: 3},
+		{hits: 0, remaining: 3},
+		{hits: 3, remaining: 0},
+	} {
+		resp, err := client.GetRateLimits(context.Background(), &guber.GetRateLimitsReq{
+			Requests: []*guber.RateLimitReq{
+				{
+					Name:      "test_leaky_bucket_reset_time",
+					UniqueKey: "account:1234",
+					Algorithm: guber.Algorithm_LEAKY_BUCKET,
+					Duration:  guber.Minute,
+					Hits:      tc.hits,
+					Limit:     limit,
+				},
+			},
+		})
+		require.NoError(t, err)
+		rl := resp.Responses[0]
+		require.Empty(t, rl.Error)
+		assert.Equal(t, tc.remaining, rl.Remaining)
+
+		expected := float64(guber.MillisecondNow()) + float64(limit-tc.remaining)*rate
+		assert.InDelta(t, expected, float64(rl.ResetTime), 1)
+	}
+}
+
+func TestMultiRegion(t *testing.T) {
+
+	// TODO: Queue a rate limit with multi region behavior on the DataCenterNone cluster
+	// TODO: Check the immediate response is correct
+	// TODO: Wait until the rate limit count shows up on the DataCenterOne and DataCenterTwo cluster
+
+	// TODO: Increment the counts on the DataCenterTwo and DataCenterOne clusters
+	// TODO: Wait until both rate limit count show up on all datacenters
+}
+
+func TestGRPCGateway(t *testing.T) {
+	resp, err := http.DefaultClient.Get("http://" + cluster.GetRandomPeer(cluster.DataCenterNone).HTTPAddress + "/v1/HealthCheck")
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	b, err := ioutil.ReadAll(resp.Body)
+
+	// This test ensures future upgrades don't accidentally change `under_score` to `camelCase` again.
+	assert.Contains(t, string(b), "peer_count")
+
+	// Should unmarshall JSON correctly
+	var hc guber.HealthCheckResp
+	require.NoError(t, json.Unmarshal(b, &hc))
+	assert.Equal(t, int32(10), hc.PeerCount)
+
+	require.NoError(t, err)
+}
+
+func TestGRPCGatewayJSON(t *testing.T) {
+	post := func(body string) (int, []byte) {
+		resp, err := http.DefaultClient.Post("http://"+cluster.GetRandomPeer(cluster.DataCenterNone).HTTPAddress+"/v1/GetRateLimits",
+			"application/json", strings.NewReader(body))
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		b, err := ioutil.ReadAll(resp.Body)
+		require.NoError(t, err)
+		return resp.StatusCode, b
+	}
+
+	t.Run("behaviors as names", func(t *testing.T) {
+		code, b := post(`{"requests": [{
+			"name": "test_grpc_gateway_json",
+			"unique_key": "account:1234",
+			"algorithm": "LEAKY_BUCKET",
+			"behavior": ["NO_BATCHING", "RETURN_CONFIG"],
+			"duration": "60000",
+			"limit": "10",
+			"burst": 20,
+			"hits": 1
+		}]}`)
+		require.Equal(t, http.StatusOK, code, string(b))
+
+		var resp struct {
+			Responses []struct {
+				Status         string `json:"status"`
+				Algorithm      string `json:"algorithm"`
+				Remaining      string `json:"remaining"`
+				ResetTime      string `json:"reset_time"`
+				ResetTimestamp string `json:"reset_timestamp"`
+				Config         struct {
+					Burst string `json:"burst"`
+				} `json:"config"`
+			} `json:"responses"`
+		}
+		require.NoError(t, json.Unmarshal(b, &resp))
+		require.Len(t, resp.Responses, 1)
+		rl := resp.Responses[0]
+		assert.Equal(t, "UNDER_LIMIT", rl.Status)
+		assert.Equal(t, "LEAKY_BUCKET", rl.Algorithm)
+		assert.Equal(t, "19", rl.Remaining)
+		// The config is only returned if RETURN_CONFIG was parsed from the list of behaviors
+		assert.Equal(t, "20", rl.Config.Burst)
+
+		resetAt, err := time.Parse(time.RFC3339Nano, rl.ResetTimestamp)
+		require.NoError(t, err)
+		assert.Equal(t, rl.ResetTime, fmt.Sprint(resetAt.UnixMilli()))
+
+		// The response maps to RateLimitResp
+		var pb guber.GetRateLimitsResp
+		require.NoError(t, protojson.Unmarshal(b, &pb))
+		assert.Equal(t, guber.Status_UNDER_LIMIT, pb.Responses[0].Status)
+		assert.Equal(t, pb.Responses[0].ResetTime, pb.Responses[0].ResetTimestamp.AsTime().UnixMilli())
+	})
+
+	t.Run("behavior as a name", func(t *testing.T) {
+		code, b := post(`{"requests": [{"name": "test_grpc_gateway_json", "unique_key": "account:5678",
+			"behavior": "RETURN_CONFIG", "duration": 60000, "limit": 10, "hits": 1}]}`)
+		require.Equal(t, http.StatusOK, code, string(b))
+		var pb guber.GetRateLimitsResp
+		require.NoError(t, protojson.Unmarshal(b, &pb))
+		assert.NotNil(t, pb.Responses[0].Config)
+	})
+
+	for _, test := range []struct {
+		Name string
+		Body string
+	}{
+		{
+			Name: "unknown field",
+			Body: `{"requests": [{"name": "test_grpc_gateway_json", "unique_key": "account:1234", "limt": 10}]}`,
+		},
+		{
+			Name: "unknown behavior",
+			Body: `{"requests": [{"name": "test_grpc_gateway_json", "unique_key": "account:1234", "behavior": ["GLOBAL", "FAST"]}]}`,
+		},
+		{
+			Name: "unknown algorithm",
+			Body: `{"requests": [{"name": "test_grpc_gateway_json", "unique_key": "account:1234", "algorithm": "FAST"}]}`,
+		},
+	} {
+		t.Run(test.Name, func(t *testing.T) {
+			code, b := post(test.Body)
+			assert.Equal(t, http.StatusBadRequest, code, string(b))
+		})
+	}
+}
+
+func TestGRPCGatewayRetryAfter(t *testing.T) {
+	post := func(key, behavior string) *http.Response {
+		resp, err := http.DefaultClient.Post("http://"+cluster.GetRandomPeer(cluster.DataCenterNone).HTTPAddress+"/v1/GetRateLimits",
+			"application/json", strings.NewReader(fmt.Sprintf(`{"requests": [{
+				"name": "test_grpc_gateway_retry_after",
+				"unique_key": "%s",
+				"behavior": [%s],
+				"duration": 60000,
+				"limit": 2,
+				"hits": 1
+			}]}`, key, behavior)))
+		require.NoError(t, err)
+		return resp
+	}
+	overLimit := func(resp *http.Response) bool {
+		defer resp.Body.Close()
+		var pb guber.GetRateLimitsResp
+		b, err := ioutil.ReadAll(resp.Body)
+		require.NoError(t, err)
+		require.NoError(t, protojson.Unmarshal(b, &pb))
+		require.Len(t, pb.Responses, 1)
+		return pb.Responses[0].Status == guber.Status_OVER_LIMIT
+	}
+
+	for i := 0; i < 2; i++ {
+		resp := post("account:1234", `"RETRY_AFTER"`)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Empty(t, resp.Header.Get("Retry-After"))
+		assert.False(t, overLimit(resp))
+	}
+
+	// The JSON body is still provided along with the status and header
+	resp := post("account:1234", `"RETRY_AFTER"`)
+	assert.Equal(t, http.StatusTooManyRequests, resp.StatusCode)
+	retryAfter, err := strconv.Atoi(resp.Header.Get("Retry-After"))
+	require.NoError(t, err)
+	assert.Greater(t, retryAfter, 0)
+	assert.LessOrEqual(t, retryAfter, 60)
+	assert.Empty(t, resp.Header.Get("Grpc-Metadata-Gubernator-Retry-After"))
+	assert.True(t, overLimit(resp))
+
+	// Without the behavior an over limit response is a success
+	for i := 0; i < 2; i++ {
+		assert.False(t, overLimit(post("account:5678", "")))
+	}
+	resp = post("account:5678", "")
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Empty(t, resp.Header.Get("Retry-After"))
+	assert.True(t, overLimit(resp))
+}
+
+func TestHealthzReadyz(t *testing.T) {
+	conf := guber.DaemonConfig{
+		GRPCListenAddress: "127.0.0.1:9725",
+		HTTPListenAddress: "127.0.0.1:9726",
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), clock.Second*10)
+	d, err := guber.SpawnDaemon(ctx, conf)
+	cancel()
+	require.NoError(t, err)
+	defer d.Close()
+
+	get := func(path string) (int, string) {
+		resp, err := http.DefaultClient.Get("http://" + conf.HTTPListenAddress + path)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		b, err := ioutil.ReadAll(resp.Body)
+		require.NoError(t, err)
+		return resp.StatusCode, string(b)
+	}
+
+	code, _ := get("/healthz")
+	assert.Equal(t, http.StatusOK, code)
+
+	// Not ready until the daemon has received a list of peers
+	code, body := get("/readyz")
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.Equal(t, "waiting for peers", body)
+
+	d.SetPeers([]guber.PeerInfo{{GRPCAddress: conf.GRPCListenAddress}})
+	code, body = get("/readyz")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "ok", body)
+}
+
+func TestGRPCHealthAndReflection(t *testing.T) {
+	conf := guber.DaemonConfig{
+		GRPCListenAddress: "127.0.0.1:9727",
+		HTTPListenAddress: "127.0.0.1:9728",
+		EnableReflection:  true,
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), clock.Second*10)
+	defer cancel()
+	d, err := guber.SpawnDaemon(ctx, conf)
+	require.NoError(t, err)
+	defer d.Close()
+
+	conn, err := grpc.DialContext(ctx, conf.GRPCListenAddress, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	require.NoError(t, err)
+	defer conn.Close()
+
+	health := healthpb.NewHealthClient(conn)
+	check := func(service string) healthpb.HealthCheckResponse_ServingStatus {
+		resp, err := health.Check(ctx, &healthpb.HealthCheckRequest{Service: service})
+		require.NoError(t, err)
+		return resp.Status
+	}
+
+	// Not serving until the daemon has received a list of peers
+	assert.Equal(t, healthpb.HealthCheckResponse_NOT_SERVING, check(""))
+
+	d.SetPeers([]guber.PeerInfo{{GRPCAddress: conf.GRPCListenAddress}})
+	assert.Equal(t, healthpb.HealthCheckResponse_SERVING, check(""))
+	assert.Equal(t, healthpb.HealthCheckResponse_SERVING, check(guber.V1_ServiceDesc.ServiceName))
+
+	// The reflection service lists the gubernator services
+	stream, err := reflectionpb.NewServerReflectionClient(conn).ServerReflectionInfo(ctx)
+	require.NoError(t, err)
+	require.NoError(t, stream.Send(&reflectionpb.ServerReflectionRequest{
+		MessageRequest: &reflectionpb.ServerReflectionRequest_ListServices{},
+	}))
+	resp, err := stream.Recv()
+	require.NoError(t, err)
+	require.NoError(t, stream.CloseSend())
+
+	var services []string
+	for _, svc := range resp.GetListServicesResponse().Service {
+		services = append(services, svc.Name)
+	}
+	assert.Contains(t, services, guber.V1_ServiceDesc.ServiceName)
+	assert.Contains(t, services, "grpc.health.v1.Health")
+}
+
+func TestGRPCMaxRecvMsgSize(t *testing.T) {
+	// A batch of rate limits with long keys which exceeds the default 1MB limit
+	req := &guber.GetRateLimitsReq{}
+	for i := 0; i < 1000; i++ {
+		req.Requests = append(req.Requests, &guber.RateLimitReq{
+			Name:      "test_grpc_max_recv_msg_size",
+			UniqueKey: fmt.Sprintf("%04d-%s", i, strings.Repeat("x", 2048)),
+			Duration:  guber.Minute,
+			Limit:     10,
+			Hits:      1,
+		})
+	}
+
+	for _, test := range []struct {
+		Name      string
+		Conf      guber.DaemonConfig
+		Succeeded bool
+	}{
+		{
+			Name: "default limit",
+			Conf: guber.DaemonConfig{
+				GRPCListenAddress: "127.0.0.1:9729",
+				HTTPListenAddress: "127.0.0.1:9730",
+			},
+			Succeeded: false,
+		},
+		{
+			Name: "raised limit",
+			Conf: guber.DaemonConfig{
+				GRPCListenAddress: "127.0.0.1:9731",
+				HTTPListenAddress: "127.0.0.1:9732",
+				GRPCTransport: guber.GRPCTransportConfig{
+					MaxRecvMsgSize: 8 * 1024 * 1024,
+				},
+			},
+			Succeeded: true,
+		},
+	} {
+		t.Run(test.Name, func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), clock.Second*10)
+			defer cancel()
+			d, err := guber.SpawnDaemon(ctx, test.Conf)
+			require.NoError(t, err)
+			defer d.Close()
+			d.SetPeers([]guber.PeerInfo{{GRPCAddress: test.Conf.GRPCListenAddress}})
+
+			client, err := guber.DialV1Server(test.Conf.GRPCListenAddress, nil)
+			require.NoError(t, err)
+
+			resp, err := client.GetRateLimits(ctx, req)
+			if !test.Succeeded {
+				assert.Equal(t, codes.ResourceExhausted, status.Code(err))
+				return
+			}
+			require.NoError(t, err)
+			require.Len(t, resp.Responses, len(req.Requests))
+			for _, rl := range resp.Responses {
+				assert.Empty(t, rl.Error)
+				assert.Equal(t, int64(9), rl.Remaining)
+			}
+		})
+	}
+}
+
+func TestAdmissionLimit(t *testing.T) {
+	conf := guber.DaemonConfig{
+		GRPCListenAddress: "127.0.0.1:9736",
+		HTTPListenAddress: "127.0.0.1:9737",
+		AdmissionLimit:    5,
+		AdmissionDuration: clock.Minute,
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), clock.Second*10)
+	defer cancel()
+	d, err := guber.SpawnDaemon(ctx, conf)
+	require.NoError(t, err)
+	defer d.Close()
+	d.SetPeers([]guber.PeerInfo{{GRPCAddress: conf.GRPCListenAddress}})
+
+	client, err := guber.DialV1Server(conf.GRPCListenAddress, nil)
+	require.NoError(t, err)
+
+	// Simulate clients behind a proxy on this host
+	sendFrom := func(addr string) error {
+		ctx := metadata.AppendToOutgoingContext(ctx, "x-forwarded-for", addr)
+		_, err := client.GetRateLimits(ctx, &guber.GetRateLimitsReq{
+			Requests: []*guber.RateLimitReq{
+				{
+					Name:      "test_admission_limit",
+					UniqueKey: addr,
+					Duration:  guber.Minute,
+					Limit:     100,
+					Hits:      1,
+				},
+			},
+		})
+		return err
+	}
+
+	// A client flooding the server is shed once over the limit
+	for i := 0; i < 10; i++ {
+		err := sendFrom("10.0.0.1")
+		if i < 5 {
+			assert.NoError(t, err, i)
+			continue
+		}
+		assert.Equal(t, codes.ResourceExhausted, status.Code(err), i)
+	}
+
+	// Other clients are unaffected
+	for i := 0; i < 5; i++ {
+		assert.NoError(t, sendFrom("10.0.0.2"), i)
+	}
+}
+
+// slowPeer answers GetPeerRateLimits after a delay
+type slowPeer struct {
+	guber.UnimplementedPeersV1Server
+	received chan struct{}
+}
+
+func (p *slowPeer) GetPeerRateLimits(ctx context.Context, r *guber.GetPeerRateLimitsReq) (*guber.GetPeerRateLimitsResp, error) {
+	p.received <- struct{}{}
+	clock.Sleep(clock.Millisecond * 500)
+	resp := &guber.GetPeerRateLimitsResp{}
+	for _, req := range r.Requests {
+		resp.RateLimits = append(resp.RateLimits, &guber.RateLimitResp{Limit: req.Limit, Remaining: req.Limit - req.Hits})
+	}
+	return resp, nil
+}
+
+func TestGracefulShutdown(t *testing.T) {
+	for _, test := range []struct {
+		Name      string
+		Timeout   clock.Duration
+		Completed bool
+	}{
+		{Name: "in-flight requests complete", Timeout: clock.Second * 5, Completed: true},
+		{Name: "in-flight requests are cancelled after the timeout", Timeout: clock.Millisecond * 100, Completed: false},
+	} {
+		t.Run(test.Name, func(t *testing.T) {
+			conf := guber.DaemonConfig{
+				GRPCListenAddress: "127.0.0.1:9733",
+				HTTPListenAddress: "127.0.0.1:9734",
+			}
+			ctx, cancel := context.WithTimeout(context.Background(), clock.Second*10)
+			defer cancel()
+			d, err := guber.SpawnDaemon(ctx, conf)
+			require.NoError(t, err)
+			defer d.Close()
+
+			// A peer which is slow to answer the requests forwarded to it
+			peer := &slowPeer{received: make(chan struct{}, 1)}
+			l, err := net.Listen("tcp", "127.0.0.1:9735")
+			require.NoError(t, err)
+			srv := grpc.NewServer()
+			guber.RegisterPeersV1Server(srv, peer)
+			go func() { _ = srv.Serve(l) }()
+			defer srv.Stop()
+
+			d.SetPeers([]guber.PeerInfo{{GRPCAddress: conf.GRPCListenAddress}, {GRPCAddress: l.Addr().String()}})
+
+			// Find a rate limit owned by the slow peer
+			var key string
+			for i := 0; ; i++ {
+				key = fmt.Sprintf("account:%d", i)
+				owner, err := d.V1Server.GetPeer(ctx, "test_graceful_shutdown_"+key)
+				require.NoError(t, err)
+				if owner.Info().GRPCAddress == l.Addr().String() {
+					break
+				}
+			}
+
+			client, err := guber.DialV1Server(conf.GRPCListenAddress, nil)
+			require.NoError(t, err)
+
+			type result struct {
+				resp *guber.GetRateLimitsResp
+				err  error
+			}
+			done := make(chan result, 1)
+			go func() {
+				resp, err := client.GetRateLimits(ctx, &guber.GetRateLimitsReq{
+					Requests: []*guber.RateLimitReq{
+						{
+							Name:      "test_graceful_shutdown",
+							UniqueKey: key,
+							Behavior:  guber.Behavior_NO_BATCHING,
+							Duration:  guber.Minute,
+							Limit:     10,
+							Hits:      1,
+						},
+					},
+				})
+				done <- result{resp: resp, err: err}
+			}()
+
+			// Shutdown the daemon while the request is in-flight
+			<-peer.received
+			shutdownCtx, cancel := context.WithTimeout(ctx, test.Timeout)
+			defer cancel()
+			err = d.Shutdown(shutdownCtx)
+
+			res := <-done
+			if !test.Completed {
+				assert.ErrorIs(t, err, context.DeadlineExceeded)
+				assert.Error(t, res.err)
+				return
+			}
+			require.NoError(t, err)
+			require.NoError(t, res.err)
+			assert.Empty(t, res.resp.Responses[0].Error)
+			assert.Equal(t, int64(9), res.resp.Responses[0].Remaining)
+		})
+	}
+}
+
+func TestGetPeerInfo(t *testing.T) {
+	d := cluster.DaemonAt(0)
+	client, err := guber.DialV1Server(d.Config().GRPCListenAddress, nil)
+	require.NoError(t, err)
+
+	resp, err := client.GetPeerInfo(context.Background(), &guber.GetPeerInfoReq{
+		Name:      "test_get_peer_info",
+		UniqueKey: "account:1234",
+	})
+	require.NoError(t, err)
+
+	assert.Len(t, resp.Peers, len(cluster.GetPeers()))
+	var self []string
+	for _, p := range resp.Peers {
+		assert.True(t, p.Healthy)
+		if p.IsOwner {
+			self = append(self, p.GrpcAddress)
+		}
+	}
+	assert.Equal(t, []string{d.Config().GRPCListenAddress}, self)
+
+	// The owner should match the selection of the picker
+	peer, err := d.V1Server.GetPeer(context.Background(), "test_get_peer_info_account:1234")
+	require.NoError(t, err)
+	require.NotNil(t, resp.Owner)
+	assert.Equal(t, peer.Info().GRPCAddress, resp.Owner.GrpcAddress)
+
+	// Should be available via the HTTP gateway
+	r, err := http.DefaultClient.Get("http://" + d.Config().HTTPListenAddress +
+		"/v1/GetPeerInfo?name=test_get_peer_info&unique_key=account:1234")
+	require.NoError(t, err)
+	defer r.Body.Close()
+	assert.Equal(t, http.StatusOK, r.StatusCode)
+	b, err := ioutil.ReadAll(r.Body)
+	require.NoError(t, err)
+
+	var info guber.GetPeerInfoResp
+	require.NoError(t, json.Unmarshal(b, &info))
+	require.NotNil(t, info.Owner)
+	assert.Equal(t, peer.Info().GRPCAddress, info.Owner.GrpcAddress)
+
+	// Must provide both the name and unique key
+	_, err = client.GetPeerInfo(context.Background(), &guber.GetPeerInfoReq{Name: "test_get_peer_info"})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+}
+
+func TestGetPeerRateLimits(t *testing.T) {
+	ctx := context.Background()
+	peerClient := gubernator.NewPeerClient(gubernator.PeerConfig{
+		Info: cluster.GetRandomPeer(cluster.DataCenterNone),
+	})
+
+	t.Run("Stable rate check request order", func(t *testing.T) {
+		// Ensure response order matches rate check request order.
+		// Try various batch sizes.
+		testCases := []int{1, 2, 5, 10, 100, 1000}
+
+		for _, n := range testCases {
+			t.Run(fmt.Sprintf("Batch size %d", n), func(t *testing.T) {
+				// Build request.
+				req := &gubernator.GetPeerRateLimitsReq{
+					Requests: make([]*gubernator.RateLimitReq, n),
+				}
+				for i := 0; i < n; i++ {
+					req.Requests[i] = &gubernator.RateLimitReq{
+						Name:      "Foobar",
+						UniqueKey: fmt.Sprintf("%08x", i),
+						Hits:      0,
+						Limit:     1000 + int64(i),
+						Duration:  1000,
+						Algorithm: gubernator.Algorithm_TOKEN_BUCKET,
+						Behavior:  gubernator.Behavior_BATCHING,
+					}
+				}
+
+				// Send request.
+				resp, err := peerClient.GetPeerRateLimits(ctx, req)
+
+				// Verify.
+				require.NoError(t, err)
+				require.NotNil(t, resp)
+				assert.Len(t, resp.RateLimits, n)
+
+				for i, item := range resp.RateLimits {
+					// Identify response by its unique limit.
+					assert.Equal(t, req.Requests[i].Limit, item.Limit)
+				}
+			})
+		}
+	})
+}
+
+func TestForwardToOwner(t *testing.T) {
+	const name = "test_forward_to_owner"
+	const key = "account:1234"
+
+	owner, err := cluster.DaemonAt(0).V1Server.GetPeer(context.Background(), name+"_"+key)
+	require.NoError(t, err)
+
+	// Find a daemon in the local data center which does not own the rate limit
+	var ownerDaemon, other *guber.Daemon
+	for _, d := range cluster.GetDaemons() {
+		if d.Config().DataCenter != cluster.DataCenterNone {
+			continue
+		}
+		if d.Config().GRPCListenAddress == owner.Info().GRPCAddress {
+			ownerDaemon = d
+		} else if other == nil {
+			other = d
+		}
+	}
+	require.NotNil(t, ownerDaemon)
+	require.NotNil(t, other)
+
+	sendHit := func(d *guber.Daemon, hits int64) *guber.RateLimitResp {
+		client, err := guber.DialV1Server(d.Config().GRPCListenAddress, nil)
+		require.NoError(t, err)
+		resp, err := client.GetRateLimits(context.Background(), &guber.GetRateLimitsReq{
+			Requests: []*guber.RateLimitReq{
+				{
+					Name:      name,
+					UniqueKey: key,
+					Algorithm: guber.Algorithm_TOKEN_BUCKET,
+					Duration:  guber.Minute,
+					Limit:     10,
+					Hits:      hits,
+				},
+			},
+		})
+		require.NoError(t, err)
+		require.Empty(t, resp.Responses[0].Error)
+		return resp.Responses[0]
+	}
+
+	// Requests sent to a non-owner are answered by the owner
+	for i := int64(1); i <= 3; i++ {
+		rl := sendHit(other, 1)
+		assert.Equal(t, owner.Info().GRPCAddress, rl.Metadata["owner"])
+		assert.Equal(t, 10-i, rl.Remaining)
+	}
+
+	// The owner reports the same count
+	rl := sendHit(ownerDaemon, 0)
+	assert.Equal(t, int64(7), rl.Remaining)
+
+	// Peer requests are never forwarded again, a non-owner applies the request locally
+	peer := guber.NewPeerClient(guber.PeerConfig{
+		Info: guber.PeerInfo{GRPCAddress: other.Config().GRPCListenAddress},
+	})
+	defer func() { _ = peer.Shutdown(context.Background()) }()
+	resp, err := peer.GetPeerRateLimits(context.Background(), &guber.GetPeerRateLimitsReq{
+		Requests: []*guber.RateLimitReq{
+			{
+				Name:      name,
+				UniqueKey: key,
+				Algorithm: guber.Algorithm_TOKEN_BUCKET,
+				Duration:  guber.Minute,
+				Limit:     10,
 				Hits:      1,
-				Limit:     2000,
 			},
 		},
-	})
-	require.NoError(t, err)
-	assert.Equal(t, "", resp.Responses[0].Error)
-	assert.Equal(t, guber.Status_UNDER_LIMIT, resp.Responses[0].Status)
-	assert.Equal(t, int64(1999), resp.Responses[0].Remaining)
-	assert.Equal(t, int64(2000), resp.Responses[0].Limit)
-
-	// Should result in a rate of 0.5
-	resp, err = client.GetRateLimits(context.Background(), &guber.GetRateLimitsReq{
-		Requests: []*guber.RateLimitReq{
-			{
-				Name:      "test_leaky_bucket_div",
-				UniqueKey: "account:12345",
-				Algorithm: guber.Algorithm_LEAKY_BUCKET,
-				Duration:  guber.Millisecond * 1000,
-				Hits:      100,
-				Limit:     2000,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, int64(9), resp.RateLimits[0].Remaining)
+
+	// The authoritative count on the owner is unchanged
+	rl = sendHit(ownerDaemon, 0)
+	assert.Equal(t, int64(7), rl.Remaining)
+}
+
+func TestOwnerInResponse(t *testing.T) {
+	const name = "test_owner_in_response"
+	// Enough keys that each peer owns some of them
+	const numKeys = 20
+
+	for _, d := range cluster.GetDaemons() {
+		if d.Config().DataCenter != cluster.DataCenterNone {
+			continue
+		}
+		client, err := guber.DialV1Server(d.Config().GRPCListenAddress, nil)
+		require.NoError(t, err)
+
+		for _, behavior := range []guber.Behavior{guber.Behavior_BATCHING, guber.Behavior_GLOBAL} {
+			req := &guber.GetRateLimitsReq{}
+			for i := 0; i < numKeys; i++ {
+				req.Requests = append(req.Requests, &guber.RateLimitReq{
+					Name:      name,
+					UniqueKey: fmt.Sprintf("account:%d", i),
+					Algorithm: guber.Algorithm_TOKEN_BUCKET,
+					Behavior:  behavior,
+					Duration:  guber.Minute,
+					Limit:     100,
+					Hits:      1,
+				})
+			}
+			resp, err := client.GetRateLimits(context.Background(), req)
+			require.NoError(t, err)
+
+			owners := make(map[string]struct{})
+			for i, rl := range resp.Responses {
+				require.Empty(t, rl.Error)
+				owner, err := d.V1Server.GetPeer(context.Background(), req.Requests[i].HashKey())
+				require.NoError(t, err)
+				assert.Equal(t, owner.Info().GRPCAddress, rl.Owner, req.Requests[i].UniqueKey)
+				owners[rl.Owner] = struct{}{}
+			}
+			assert.Greater(t, len(owners), 1)
+		}
+	}
+}
+
+func TestKeyShardFunc(t *testing.T) {
+	const name = "test_key_shard_func"
+
+	// Shard on the name only, such that all the rate limits of a namespace are owned by one peer
+	shard := func(hashKey string) string {
+		return hashKey[:strings.LastIndex(hashKey, "_")]
+	}
+
+	var daemons []*guber.Daemon
+	var peers []guber.PeerInfo
+	for i := 0; i < 3; i++ {
+		d := spawnDaemon(t, guber.DaemonConfig{
+			GRPCListenAddress: fmt.Sprintf("127.0.0.1:%d", 9738+i*2),
+			HTTPListenAddress: fmt.Sprintf("127.0.0.1:%d", 9739+i*2),
+			KeyShardFunc:      shard,
+		})
+		defer d.Close()
+		daemons = append(daemons, d)
+		peers = append(peers, guber.PeerInfo{GRPCAddress: d.Config().GRPCListenAddress})
+	}
+	for _, d := range daemons {
+		d.SetPeers(peers)
+	}
+
+	client, err := guber.DialV1Server(daemons[0].Config().GRPCListenAddress, nil)
+	require.NoError(t, err)
+
+	owners := make(map[string]struct{})
+	for i := 0; i < 20; i++ {
+		key := fmt.Sprintf("account:%d", i)
+		resp, err := client.GetRateLimits(context.Background(), &guber.GetRateLimitsReq{
+			Requests: []*guber.RateLimitReq{
+				{
+					Name:      name,
+					UniqueKey: key,
+					Behavior:  guber.Behavior_NO_BATCHING,
+					Duration:  guber.Minute,
+					Limit:     10,
+					Hits:      1,
+				},
+			},
+		})
+		require.NoError(t, err)
+		rl := resp.Responses[0]
+		require.Empty(t, rl.Error)
+		// Each key is still a separate rate limit
+		assert.Equal(t, int64(9), rl.Remaining, key)
+
+		owner, err := daemons[0].V1Server.GetPeer(context.Background(), name+"_"+key)
+		require.NoError(t, err)
+		owners[owner.Info().GRPCAddress] = struct{}{}
+		if rl.Metadata["served"] == "forward" {
+			assert.Equal(t, owner.Info().GRPCAddress, rl.Metadata["owner"])
+		}
+	}
+	assert.Len(t, owners, 1)
+
+	// Without the shard func the keys are spread across the peers
+	owners = make(map[string]struct{})
+	for i := 0; i < 20; i++ {
+		owner, err := cluster.DaemonAt(0).V1Server.GetPeer(context.Background(), fmt.Sprintf("%s_account:%d", name, i))
+		require.NoError(t, err)
+		owners[owner.Info().GRPCAddress] = struct{}{}
+	}
+	assert.Greater(t, len(owners), 1)
+}
+
+func TestReplicationFactor(t *testing.T) {
+	const name = "test_replication_factor"
+	const key = "account:1234"
+
+	var daemons []*guber.Daemon
+	var peers []guber.PeerInfo
+	for i := 0; i < 3; i++ {
+		d := spawnDaemon(t, guber.DaemonConfig{
+			GRPCListenAddress: fmt.Sprintf("127.0.0.1:%d", 9746+i*2),
+			HTTPListenAddress: fmt.Sprintf("127.0.0.1:%d", 9747+i*2),
+			ReplicationFactor: 2,
+			Behaviors: guber.BehaviorConfig{
+				ReplicationSyncWait: clock.Millisecond * 10,
+			},
+		})
+		defer d.Close()
+		daemons = append(daemons, d)
+		peers = append(peers, guber.PeerInfo{GRPCAddress: d.Config().GRPCListenAddress})
+	}
+	for _, d := range daemons {
+		d.SetPeers(peers)
+	}
+
+	sendHits := func(d *guber.Daemon, hits int64) *guber.RateLimitResp {
+		client, err := guber.DialV1Server(d.Config().GRPCListenAddress, nil)
+		require.NoError(t, err)
+		resp, err := client.GetRateLimits(context.Background(), &guber.GetRateLimitsReq{
+			Requests: []*guber.RateLimitReq{
+				{
+					Name:      name,
+					UniqueKey: key,
+					Behavior:  guber.Behavior_NO_BATCHING,
+					Duration:  guber.Minute,
+					Limit:     10,
+					Hits:      hits,
+				},
+			},
+		})
+		require.NoError(t, err)
+		require.Empty(t, resp.Responses[0].Error)
+		return resp.Responses[0]
+	}
+
+	owner, err := daemons[0].V1Server.GetPeer(context.Background(), name+"_"+key)
+	require.NoError(t, err)
+	rl := sendHits(daemons[0], 6)
+	assert.Equal(t, int64(4), rl.Remaining)
+
+	// Wait for the owner to replicate the rate limit
+	clock.Sleep(clock.Millisecond * 200)
+
+	// The owner dies and is removed from the cluster
+	var remaining []*guber.Daemon
+	var remainingPeers []guber.PeerInfo
+	for _, d := range daemons {
+		if d.Config().GRPCListenAddress == owner.Info().GRPCAddress {
+			d.Close()
+			continue
+		}
+		remaining = append(remaining, d)
+		remainingPeers = append(remainingPeers, guber.PeerInfo{GRPCAddress: d.Config().GRPCListenAddress})
+	}
+	require.Len(t, remaining, 2)
+	for _, d := range remaining {
+		d.SetPeers(remainingPeers)
+	}
+
+	// The successor serves the rate limit with the state held before the owner died
+	for _, d := range remaining {
+		rl = sendHits(d, 0)
+		assert.Equal(t, int64(4), rl.Remaining, d.Config().GRPCListenAddress)
+	}
+}
+
+func TestServedLocallyOrForwarded(t *testing.T) {
+	const name = "test_served_locally_or_forwarded"
+	const key = "account:1234"
+
+	owner, err := cluster.DaemonAt(0).V1Server.GetPeer(context.Background(), name+"_"+key)
+	require.NoError(t, err)
+	addr := owner.Info().GRPCAddress
+
+	// Find a daemon in the local data center which does not own the rate limit
+	var ownerDaemon, other *guber.Daemon
+	for _, d := range cluster.GetDaemons() {
+		if d.Config().DataCenter != cluster.DataCenterNone {
+			continue
+		}
+		if d.Config().GRPCListenAddress == addr {
+			ownerDaemon = d
+		} else if other == nil {
+			other = d
+		}
+	}
+	require.NotNil(t, ownerDaemon)
+	require.NotNil(t, other)
+
+	local := fmt.Sprintf(`gubernator_getratelimit_peer_counter{calltype="local", peerAddr="%s"}`, addr)
+	forward := fmt.Sprintf(`gubernator_getratelimit_peer_counter{calltype="forward", peerAddr="%s"}`, addr)
+	scrape := func() map[string]float64 {
+		resp, err := http.Get(fmt.Sprintf("http://%s/metrics", ownerDaemon.Config().HTTPListenAddress))
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		b, err := ioutil.ReadAll(resp.Body)
+		require.NoError(t, err)
+
+		values := make(map[string]float64)
+		for _, name := range []string{local, forward} {
+			if m := getMetric(t, strings.NewReader(string(b)), name); m != nil {
+				values[name] = float64(m.Value)
+			}
+		}
+		return values
+	}
+
+	sendHit := func(d *guber.Daemon) *guber.RateLimitResp {
+		client, err := guber.DialV1Server(d.Config().GRPCListenAddress, nil)
+		require.NoError(t, err)
+		resp, err := client.GetRateLimits(context.Background(), &guber.GetRateLimitsReq{
+			Requests: []*guber.RateLimitReq{
+				{
+					Name:      name,
+					UniqueKey: key,
+					Algorithm: guber.Algorithm_TOKEN_BUCKET,
+					Behavior:  guber.Behavior_NO_BATCHING,
+					Duration:  guber.Minute,
+					Limit:     10,
+					Hits:      1,
+				},
+			},
+		})
+		require.NoError(t, err)
+		require.Empty(t, resp.Responses[0].Error)
+		return resp.Responses[0]
+	}
+
+	// A request sent to a non-owner is forwarded to the owner
+	before := scrape()
+	rl := sendHit(other)
+	assert.Equal(t, "forward", rl.Metadata["served"])
+	assert.Equal(t, addr, rl.Metadata["owner"])
+	after := scrape()
+	assert.Equal(t, 1.0, after[forward]-before[forward])
+	assert.Equal(t, 0.0, after[local]-before[local])
+
+	// A request sent to the owner is served locally
+	before = after
+	rl = sendHit(ownerDaemon)
+	assert.Equal(t, "local", rl.Metadata["served"])
+	after = scrape()
+	assert.Equal(t, 0.0, after[forward]-before[forward])
+	assert.Equal(t, 1.0, after[local]-before[local])
+}
+
+func TestAlgorithmDurationMetric(t *testing.T) {
+	d := spawnDaemon(t, guber.DaemonConfig{
+		GRPCListenAddress: "127.0.0.1:9744",
+		HTTPListenAddress: "127.0.0.1:9745",
+	})
+	defer d.Close()
+
+	client, err := guber.DialV1Server(d.Config().GRPCListenAddress, nil)
+	require.NoError(t, err)
+
+	// Returns the number of observations for each algorithm and cache label
+	scrape := func() map[string]float64 {
+		resp, err := http.Get(fmt.Sprintf("http://%s/metrics", d.Config().HTTPListenAddress))
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		b, err := ioutil.ReadAll(resp.Body)
+		require.NoError(t, err)
+
+		values := make(map[string]float64)
+		for _, algorithm := range []guber.Algorithm{guber.Algorithm_TOKEN_BUCKET, guber.Algorithm_LEAKY_BUCKET} {
+			for _, cache := range []string{"hit", "miss"} {
+				name := fmt.Sprintf(`gubernator_algorithm_duration_count{algorithm="%s", cache="%s"}`, algorithm, cache)
+				if m := getMetric(t, strings.NewReader(string(b)), name); m != nil {
+					values[algorithm.String()+"/"+cache] = float64(m.Value)
+				}
+			}
+		}
+		return values
+	}
+
+	for _, algorithm := range []guber.Algorithm{guber.Algorithm_TOKEN_BUCKET, guber.Algorithm_LEAKY_BUCKET} {
+		t.Run(algorithm.String(), func(t *testing.T) {
+			sendHit := func() {
+				resp, err := client.GetRateLimits(context.Background(), &guber.GetRateLimitsReq{
+					Requests: []*guber.RateLimitReq{
+						{
+							Name:      "test_algorithm_duration_metric",
+							UniqueKey: "account:" + algorithm.String(),
+							Algorithm: algorithm,
+							Duration:  guber.Minute,
+							Limit:     10,
+							Hits:      1,
+						},
+					},
+				})
+				require.NoError(t, err)
+				require.Empty(t, resp.Responses[0].Error)
+			}
+			hit := algorithm.String() + "/hit"
+			miss := algorithm.String() + "/miss"
+
+			// The first request creates the rate limit
+			before := scrape()
+			sendHit()
+			after := scrape()
+			assert.Equal(t, 1.0, after[miss]-before[miss])
+			assert.Equal(t, 0.0, after[hit]-before[hit])
+
+			// The next request finds it in the cache
+			before = after
+			sendHit()
+			after = scrape()
+			assert.Equal(t, 0.0, after[miss]-before[miss])
+			assert.Equal(t, 1.0, after[hit]-before[hit])
+		})
+	}
+}
+
+func TestPeerClientBatching(t *testing.T) {
+	const requests = 50
+	d := cluster.DaemonAt(1)
+
+	// Returns the number of GetPeerRateLimits RPCs the daemon has handled
+	peerRPCs := func(t testutil.TestingT) float64 {
+		resp, err := http.Get(fmt.Sprintf("http://%s/metrics", d.Config().HTTPListenAddress))
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		m := getMetric(t, resp.Body, `gubernator_grpc_request_counts{method="/pb.gubernator.PeersV1/GetPeerRateLimits", status="success"}`)
+		if m == nil {
+			return 0
+		}
+		return float64(m.Value)
+	}
+
+	for _, tc := range []struct {
+		name     string
+		behavior guber.Behavior
+		assert   func(t testutil.TestingT, rpcs float64)
+	}{
+		{
+			name:     "batching",
+			behavior: guber.Behavior_BATCHING,
+			assert: func(t testutil.TestingT, rpcs float64) {
+				assert.GreaterOrEqual(t, rpcs, 1.0)
+				assert.Less(t, rpcs, float64(requests)/2)
+			},
+		},
+		{
+			name:     "no batching",
+			behavior: guber.Behavior_NO_BATCHING,
+			assert: func(t testutil.TestingT, rpcs float64) {
+				assert.Equal(t, float64(requests), rpcs)
 			},
 		},
-	})
-	require.NoError(t, err)
-	assert.Equal(t, int64(1899), resp.Responses[0].Remaining)
-	assert.Equal(t, int64(2000), resp.Responses[0].Limit)
-}
-
-func TestMultiRegion(t *testing.T) {
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			peer := guber.NewPeerClient(guber.PeerConfig{
+				Info: guber.PeerInfo{GRPCAddress: d.Config().GRPCListenAddress},
+				Behavior: guber.BehaviorConfig{
+					BatchTimeout: clock.Second,
+					BatchWait:    50 * clock.Millisecond,
+					BatchLimit:   100,
+				},
+			})
+			defer func() { _ = peer.Shutdown(context.Background()) }()
 
-	// TODO: Queue a rate limit with multi region behavior on the DataCenterNone cluster
-	// TODO: Check the immediate response is correct
-	// TODO: Wait until the rate limit count shows up on the DataCenterOne and DataCenterTwo cluster
+			before := peerRPCs(t)
+			var wg sync.WaitGroup
+			for i := 0; i < requests; i++ {
+				wg.Add(1)
+				go func(i int) {
+					defer wg.Done()
+					rl, err := peer.GetPeerRateLimit(context.Background(), &guber.RateLimitReq{
+						Name:      "test_peer_client_batching_" + tc.name,
+						UniqueKey: fmt.Sprintf("account:%d", i),
+						Algorithm: guber.Algorithm_TOKEN_BUCKET,
+						Behavior:  tc.behavior,
+						Duration:  guber.Minute,
+						Limit:     10,
+						Hits:      2,
+					})
+					require.NoError(t, err)
+					// Each caller receives the response for its own rate limit
+					assert.Empty(t, rl.Error)
+					assert.Equal(t, int64(8), rl.Remaining)
+				}(i)
+			}
+			wg.Wait()
 
-	// TODO: Increment the counts on the DataCenterTwo and DataCenterOne clusters
-	// TODO: Wait until both rate limit count show up on all datacenters
+			// The stats handler records requests asynchronously
+			testutil.UntilPass(t, 20, 50*clock.Millisecond, func(t testutil.TestingT) {
+				tc.assert(t, peerRPCs(t)-before)
+			})
+		})
+	}
 }
 
-func TestGRPCGateway(t *testing.T) {
-	resp, err := http.DefaultClient.Get("http://" + cluster.GetRandomPeer(cluster.DataCenterNone).HTTPAddress + "/v1/HealthCheck")
+// TODO: Add a test for sending no rate limits RateLimitReqList.RateLimits = nil
+
+func TestPrometheusMetrics(t *testing.T) {
+	d := cluster.DaemonAt(0)
+	client, err := guber.DialV1Server(d.Config().GRPCListenAddress, nil)
 	require.NoError(t, err)
-	assert.Equal(t, http.StatusOK, resp.StatusCode)
-	b, err := ioutil.ReadAll(resp.Body)
 
-	// This test ensures future upgrades don't accidentally change `under_score` to `camelCase` again.
-	assert.Contains(t, string(b), "peer_count")
+	scrape := func() map[string]float64 {
+		resp, err := http.Get(fmt.Sprintf("http://%s/metrics", d.Config().HTTPListenAddress))
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		require.Equal(t, http.StatusOK, resp.StatusCode)
 
-	// Should unmarshall JSON correctly
-	var hc guber.HealthCheckResp
-	require.NoError(t, json.Unmarshal(b, &hc))
-	assert.Equal(t, int32(10), hc.PeerCount)
+		b, err := ioutil.ReadAll(resp.Body)
+		require.NoError(t, err)
 
-	require.NoError(t, err)
-}
+		values := make(map[string]float64)
+		for _, name := range []string{
+			"gubernator_check_counter",
+			"gubernator_over_limit_counter",
+			`gubernator_cache_access_count{type="hit"}`,
+			`gubernator_cache_access_count{type="miss"}`,
+			"gubernator_getratelimits_duration_count",
+		} {
+			// Metrics which have not yet been observed are not reported
+			if m := getMetric(t, strings.NewReader(string(b)), name); m != nil {
+				values[name] = float64(m.Value)
+			}
+		}
+		return values
+	}
 
-func TestGetPeerRateLimits(t *testing.T) {
-	ctx := context.Background()
-	peerClient := gubernator.NewPeerClient(gubernator.PeerConfig{
-		Info: cluster.GetRandomPeer(cluster.DataCenterNone),
-	})
+	before := scrape()
+	for i := 0; i < 3; i++ {
+		resp, err := client.GetRateLimits(context.Background(), &guber.GetRateLimitsReq{
+			Requests: []*guber.RateLimitReq{
+				{
+					Name:      "test_prometheus_metrics",
+					UniqueKey: "account:1234",
+					Algorithm: guber.Algorithm_TOKEN_BUCKET,
+					Behavior:  guber.Behavior_NO_BATCHING,
+					Duration:  guber.Minute,
+					Limit:     2,
+					Hits:      1,
+				},
+			},
+		})
+		require.NoError(t, err)
+		require.Empty(t, resp.Responses[0].Error)
+	}
+	after := scrape()
 
-	t.Run("Stable rate check request order", func(t *testing.T) {
-		// Ensure response order matches rate check request order.
-		// Try various batch sizes.
-		testCases := []int{1, 2, 5, 10, 100, 1000}
+	// Metrics are shared by all the daemons in the test cluster, so other
+	// activity in the cluster may also increment the counters.
+	assert.GreaterOrEqual(t, after["gubernator_check_counter"]-before["gubernator_check_counter"], 3.0)
+	assert.GreaterOrEqual(t, after["gubernator_over_limit_counter"]-before["gubernator_over_limit_counter"], 1.0)
+	assert.GreaterOrEqual(t, after[`gubernator_cache_access_count{type="miss"}`]-before[`gubernator_cache_access_count{type="miss"}`], 1.0)
+	assert.GreaterOrEqual(t, after[`gubernator_cache_access_count{type="hit"}`]-before[`gubernator_cache_access_count{type="hit"}`], 2.0)
+	assert.GreaterOrEqual(t, after["gubernator_getratelimits_duration_count"]-before["gubernator_getratelimits_duration_count"], 3.0)
+}
 
-		for _, n := range testCases {
-			t.Run(fmt.Sprintf("Batch size %d", n), func(t *testing.T) {
-				// Build request.
-				req := &gubernator.GetPeerRateLimitsReq{
-					Requests: make([]*gubernator.RateLimitReq, n),
-				}
-				for i := 0; i < n; i++ {
-					req.Requests[i] = &gubernator.RateLimitReq{
-						Name:      "Foobar",
-						UniqueKey: fmt.Sprintf("%08x", i),
-						Hits:      0,
-						Limit:     1000 + int64(i),
-						Duration:  1000,
-						Algorithm: gubernator.Algorithm_TOKEN_BUCKET,
-						Behavior:  gubernator.Behavior_BATCHING,
-					}
-				}
+func TestNamespaceOverLimitMetric(t *testing.T) {
+	srv := newV1Server(t, "", guber.Config{
+		OverLimitNamespaces: []string{"test_namespace_over_limit_a", "test_namespace_over_limit_b"},
+	})
+	defer srv.Close()
 
-				// Send request.
-				resp, err := peerClient.GetPeerRateLimits(ctx, req)
+	trip := func(name string, count int) {
+		for i := 0; i < count; i++ {
+			resp, err := srv.srv.GetRateLimits(context.Background(), &guber.GetRateLimitsReq{
+				Requests: []*guber.RateLimitReq{
+					{
+						Name:      name,
+						UniqueKey: "account:1234",
+						Algorithm: guber.Algorithm_TOKEN_BUCKET,
+						Duration:  guber.Minute,
+						Limit:     1,
+						Hits:      1,
+					},
+				},
+			})
+			require.NoError(t, err)
+			require.Empty(t, resp.Responses[0].Error)
+		}
+	}
+	// The first request of each is under the limit
+	trip("test_namespace_over_limit_a", 4)
+	trip("test_namespace_over_limit_b", 3)
+	trip("test_namespace_over_limit_c", 2)
 
-				// Verify.
-				require.NoError(t, err)
-				require.NotNil(t, resp)
-				assert.Len(t, resp.RateLimits, n)
+	reg := prometheus.NewRegistry()
+	require.NoError(t, reg.Register(srv.srv))
+	families, err := reg.Gather()
+	require.NoError(t, err)
 
-				for i, item := range resp.RateLimits {
-					// Identify response by its unique limit.
-					assert.Equal(t, req.Requests[i].Limit, item.Limit)
+	counts := make(map[string]float64)
+	for _, f := range families {
+		if f.GetName() != "gubernator_namespace_over_limit_counter" {
+			continue
+		}
+		for _, m := range f.GetMetric() {
+			for _, l := range m.GetLabel() {
+				if l.GetName() == "name" {
+					counts[l.GetValue()] = m.GetCounter().GetValue()
 				}
-			})
+			}
 		}
-	})
+	}
+	assert.Equal(t, map[string]float64{
+		"test_namespace_over_limit_a": 3,
+		"test_namespace_over_limit_b": 2,
+		"other":                       1,
+	}, counts)
 }
 
-// TODO: Add a test for sending no rate limits RateLimitReqList.RateLimits = nil
-
 func getMetric(t testutil.TestingT, in io.Reader, name string) *model.Sample {
 	dec := expfmt.SampleDecoder{
 		Dec: expfmt.NewDecoder(in, expfmt.FmtText),
@@ -1246,3 +4851,350 @@ func getMetric(t testutil.TestingT, in io.Reader, name string) *model.Sample {
 	}
 	return nil
 }
+
+// auditSink collects the records written by the audit trail
+type auditSink struct {
+	mutex   sync.Mutex
+	records []guber.AuditRecord
+}
+
+func (s *auditSink) Write(records []guber.AuditRecord) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.records = append(s.records, records...)
+	return nil
+}
+
+func TestAuditSink(t *testing.T) {
+	sink := &auditSink{}
+	rate := 0.25
+	srv := newV1Server(t, "", guber.Config{
+		AuditSink:       sink,
+		AuditNamespaces: []string{"test_audit_sink"},
+		AuditSampleRate: &rate,
+	})
+
+	send := func(name string, hits int64) {
+		resp, err := srv.srv.GetRateLimits(context.Background(), &guber.GetRateLimitsReq{
+			Requests: []*guber.RateLimitReq{
+				{
+					Name:      name,
+					UniqueKey: "account:1234",
+					Duration:  guber.Minute,
+					Limit:     20,
+					Hits:      hits,
+				},
+			},
+		})
+		require.NoError(t, err)
+		require.Empty(t, resp.Responses[0].Error)
+	}
+
+	start := clock.Now()
+	for i := 0; i < 40; i++ {
+		send("test_audit_sink", 1)
+		send("test_audit_sink_other", 1)
+	}
+	// Records remaining in the buffer are written when the instance is closed
+	require.NoError(t, srv.Close())
+
+	sink.mutex.Lock()
+	defer sink.mutex.Unlock()
+
+	// Every 4th decision of the audited namespace is recorded
+	require.Len(t, sink.records, 10)
+	for i, r := range sink.records {
+		assert.Equal(t, "test_audit_sink", r.Name)
+		assert.Equal(t, "test_audit_sink_account:1234", r.HashKey)
+		assert.Equal(t, int64(1), r.Hits)
+		assert.False(t, r.Timestamp.Before(start))
+
+		// The 4th, 8th, ... hit of the limit of 20
+		remaining := int64(20 - (i+1)*4)
+		status := guber.Status_UNDER_LIMIT
+		if remaining < 0 {
+			remaining = 0
+			status = guber.Status_OVER_LIMIT
+		}
+		assert.Equal(t, remaining, r.Remaining)
+		assert.Equal(t, status, r.Status)
+	}
+}
+
+func TestAuditSinkSampleRateZero(t *testing.T) {
+	sink := &auditSink{}
+	rate := 0.0
+	srv := newV1Server(t, "", guber.Config{
+		AuditSink:       sink,
+		AuditNamespaces: []string{"test_audit_sink_zero"},
+		AuditSampleRate: &rate,
+	})
+
+	for i := 0; i < 10; i++ {
+		resp, err := srv.srv.GetRateLimits(context.Background(), &guber.GetRateLimitsReq{
+			Requests: []*guber.RateLimitReq{
+				{
+					Name:      "test_audit_sink_zero",
+					UniqueKey: "account:1234",
+					Duration:  guber.Minute,
+					Limit:     20,
+					Hits:      1,
+				},
+			},
+		})
+		require.NoError(t, err)
+		require.Empty(t, resp.Responses[0].Error)
+	}
+	require.NoError(t, srv.Close())
+
+	// An explicit rate of 0 records no decisions rather than every decision
+	sink.mutex.Lock()
+	defer sink.mutex.Unlock()
+	assert.Empty(t, sink.records)
+}
+
+func TestJSONAuditSink(t *testing.T) {
+	var buf bytes.Buffer
+	sink := guber.NewJSONAuditSink(&buf)
+	ts := time.Date(2023, 1, 2, 3, 4, 5, 0, time.UTC)
+	require.NoError(t, sink.Write([]guber.AuditRecord{
+		{Name: "test_audit", HashKey: "test_audit_account:1", Hits: 2, Status: guber.Status_OVER_LIMIT, Remaining: 0, Timestamp: ts},
+		{Name: "test_audit", HashKey: "test_audit_account:2", Hits: 1, Status: guber.Status_UNDER_LIMIT, Remaining: 9, Timestamp: ts},
+	}))
+	assert.Equal(t, `{"name":"test_audit","hash_key":"test_audit_account:1","hits":2,"status":"OVER_LIMIT","remaining":0,"timestamp":"2023-01-02T03:04:05Z"}
+{"name":"test_audit","hash_key":"test_audit_account:2","hits":1,"status":"UNDER_LIMIT","remaining":9,"timestamp":"2023-01-02T03:04:05Z"}
+`, buf.String())
+}
+
+func TestPriorityEviction(t *testing.T) {
+	srv := newV1Server(t, "", guber.Config{
+		PoolWorkers: 1,
+		CacheSize:   10,
+		// The priority of a long window limit is derived from its name
+		NamespaceDefaults: map[string]*guber.RateLimitReq{
+			"test_priority_billing": {Priority: 10},
+		},
+	})
+	defer srv.Close()
+
+	send := func(name, key string, hits int64) *guber.RateLimitResp {
+		resp, err := srv.srv.GetRateLimits(context.Background(), &guber.GetRateLimitsReq{
+			Requests: []*guber.RateLimitReq{
+				{
+					Name:      name,
+					UniqueKey: key,
+					Duration:  guber.Minute,
+					Limit:     10,
+					Hits:      hits,
+				},
+			},
+		})
+		require.NoError(t, err)
+		rl := resp.Responses[0]
+		require.Empty(t, rl.Error)
+		return rl
+	}
+
+	for i := 0; i < 5; i++ {
+		send("test_priority_billing", strconv.Itoa(i), 5)
+	}
+	// Fill the cache many times over with lower priority rate limits
+	for i := 0; i < 50; i++ {
+		send("test_priority_api", strconv.Itoa(i), 5)
+	}
+
+	// The billing limits were not evicted, the first api limits were
+	for i := 0; i < 5; i++ {
+		assert.Equal(t, int64(5), send("test_priority_billing", strconv.Itoa(i), 0).Remaining)
+	}
+	assert.Equal(t, int64(10), send("test_priority_api", "0", 0).Remaining)
+}
+
+func TestBackoffHint(t *testing.T) {
+	defer clock.Freeze(clock.Now()).Unfreeze()
+
+	srv := newV1Server(t, "", guber.Config{
+		PoolWorkers:     1,
+		BackoffHintStep: clock.Second,
+		BackoffHintMax:  clock.Second * 5,
+	})
+	defer srv.Close()
+
+	send := func(hits int64) *guber.RateLimitResp {
+		resp, err := srv.srv.GetRateLimits(context.Background(), &guber.GetRateLimitsReq{
+			Requests: []*guber.RateLimitReq{
+				{
+					Name:      "test_backoff_hint",
+					UniqueKey: "account:1234",
+					Duration:  guber.Second * 10,
+					Limit:     1,
+					Hits:      hits,
+					Behavior:  guber.Behavior_BACKOFF_HINT,
+				},
+			},
+		})
+		require.NoError(t, err)
+		rl := resp.Responses[0]
+		require.Empty(t, rl.Error)
+		return rl
+	}
+
+	rl := send(1)
+	assert.Equal(t, guber.Status_UNDER_LIMIT, rl.Status)
+	assert.Equal(t, int64(0), rl.SuggestedRetryAfter)
+
+	// Each consecutive rejection doubles the delay added to the reset, up to the max
+	for _, delay := range []int64{1000, 2000, 4000, 5000, 5000} {
+		rl = send(1)
+		assert.Equal(t, guber.Status_OVER_LIMIT, rl.Status)
+		assert.Equal(t, int64(10_000)+delay, rl.SuggestedRetryAfter)
+	}
+
+	// A successful request restarts the count
+	clock.Advance(clock.Second * 10)
+	rl = send(1)
+	assert.Equal(t, guber.Status_UNDER_LIMIT, rl.Status)
+	assert.Equal(t, int64(0), rl.SuggestedRetryAfter)
+
+	rl = send(1)
+	assert.Equal(t, guber.Status_OVER_LIMIT, rl.Status)
+	assert.Equal(t, int64(10_000)+1000, rl.SuggestedRetryAfter)
+}
+
+func TestClockStepBackwards(t *testing.T) {
+	defer clock.Freeze(clock.Now()).Unfreeze()
+
+	client, err := guber.DialV1Server(cluster.GetRandomPeer(cluster.DataCenterNone).GRPCAddress, nil)
+	require.NoError(t, err)
+
+	send := func(algorithm guber.Algorithm, profile guber.LeakProfile, hits int64) *guber.RateLimitResp {
+		resp, err := client.GetRateLimits(context.Background(), &guber.GetRateLimitsReq{
+			Requests: []*guber.RateLimitReq{
+				{
+					Name:        "test_clock_step_backwards",
+					UniqueKey:   "account:" + algorithm.String() + ":" + profile.String(),
+					Algorithm:   algorithm,
+					LeakProfile: profile,
+					Duration:    guber.Second * 10,
+					Limit:       10,
+					Hits:        hits,
+				},
+			},
+		})
+		require.NoError(t, err)
+		rl := resp.Responses[0]
+		require.Empty(t, rl.Error)
+		return rl
+	}
+
+	tests := []struct {
+		Algorithm guber.Algorithm
+		Profile   guber.LeakProfile
+		// The remaining once a second has passed after the step
+		Remaining int64
+	}{
+		{Algorithm: guber.Algorithm_TOKEN_BUCKET, Remaining: 5},
+		{Algorithm: guber.Algorithm_LEAKY_BUCKET, Remaining: 6},
+		{Algorithm: guber.Algorithm_LEAKY_BUCKET, Profile: guber.LeakProfile_QUADRATIC, Remaining: 6},
+		{Algorithm: guber.Algorithm_HYBRID, Remaining: 6},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Algorithm.String()+"/"+test.Profile.String(), func(t *testing.T) {
+			rl := send(test.Algorithm, test.Profile, 5)
+			assert.Equal(t, int64(5), rl.Remaining)
+
+			// The step neither takes nor returns hits, and the window lasts no longer than a duration
+			clock.Advance(-clock.Second * 5)
+			now := clock.Now().UnixNano() / 1000000
+			rl = send(test.Algorithm, test.Profile, 0)
+			assert.Equal(t, guber.Status_UNDER_LIMIT, rl.Status)
+			assert.Equal(t, int64(5), rl.Remaining)
+			assert.LessOrEqual(t, rl.ResetTime, now+guber.Second*10)
+
+			// The bucket leaks from the stepped back clock rather than waiting for it to catch up
+			clock.Advance(clock.Second)
+			rl = send(test.Algorithm, test.Profile, 0)
+			assert.Equal(t, test.Remaining, rl.Remaining)
+		})
+	}
+}
+
+func TestSchedule(t *testing.T) {
+	day := time.Date(2024, time.March, 4, 0, 0, 0, 0, time.UTC)
+	defer clock.Freeze(day.Add(10 * time.Hour)).Unfreeze()
+
+	client, err := guber.DialV1Server(cluster.GetRandomPeer(cluster.DataCenterNone).GRPCAddress, nil)
+	require.NoError(t, err)
+
+	send := func(algorithm guber.Algorithm, hits int64) *guber.RateLimitResp {
+		resp, err := client.GetRateLimits(context.Background(), &guber.GetRateLimitsReq{
+			Requests: []*guber.RateLimitReq{
+				{
+					Name:      "test_schedule",
+					UniqueKey: "account:" + algorithm.String(),
+					Algorithm: algorithm,
+					// Long enough for neither bucket to reset or leak a hit during the test
+					Duration: 100 * 24 * 60 * guber.Minute,
+					Limit:    10,
+					Hits:     hits,
+					Timezone: "UTC",
+					Schedule: []*guber.ScheduleWindow{
+						// Business hours from 09:00 to 17:00
+						{Start: 9 * 60, End: 17 * 60, Limit: 100},
+					},
+				},
+			},
+		})
+		require.NoError(t, err)
+		rl := resp.Responses[0]
+		require.Empty(t, rl.Error)
+		return rl
+	}
+
+	for i, algorithm := range []guber.Algorithm{guber.Algorithm_TOKEN_BUCKET, guber.Algorithm_LEAKY_BUCKET} {
+		t.Run(algorithm.String(), func(t *testing.T) {
+			clock.Advance(day.AddDate(0, 0, 2*i).Add(10 * time.Hour).Sub(clock.Now()))
+
+			// The business hours limit applies at 10:00
+			rl := send(algorithm, 50)
+			assert.Equal(t, guber.Status_UNDER_LIMIT, rl.Status)
+			assert.Equal(t, int64(100), rl.Limit)
+			assert.Equal(t, int64(50), rl.Remaining)
+
+			// Half of the off-hours limit remains at 17:30, rather than the whole of it
+			clock.Advance(7*time.Hour + 30*time.Minute)
+			rl = send(algorithm, 0)
+			assert.Equal(t, int64(10), rl.Limit)
+			assert.Equal(t, int64(5), rl.Remaining)
+
+			rl = send(algorithm, 5)
+			assert.Equal(t, guber.Status_UNDER_LIMIT, rl.Status)
+			assert.Equal(t, int64(0), rl.Remaining)
+			rl = send(algorithm, 1)
+			assert.Equal(t, guber.Status_OVER_LIMIT, rl.Status)
+
+			// The drained rate limit remains drained once business hours begin again at 09:00
+			clock.Advance(15*time.Hour + 30*time.Minute)
+			rl = send(algorithm, 0)
+			assert.Equal(t, int64(100), rl.Limit)
+			assert.Equal(t, int64(0), rl.Remaining)
+		})
+	}
+
+	t.Run("invalid window", func(t *testing.T) {
+		_, err := client.GetRateLimits(context.Background(), &guber.GetRateLimitsReq{
+			Requests: []*guber.RateLimitReq{
+				{
+					Name:      "test_schedule",
+					UniqueKey: "account:invalid",
+					Duration:  guber.Minute,
+					Limit:     10,
+					Hits:      1,
+					Schedule:  []*guber.ScheduleWindow{{Start: 9 * 60, End: 9 * 60, Limit: 100}},
+				},
+			},
+		})
+		assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	})
+}
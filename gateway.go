@@ -0,0 +1,220 @@
+/*
+Copyright 2018-2022 Mailgun Technologies Inc
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gubernator
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// gatewayMarshaler is the JSON marshaler of the HTTP gateway. In addition to the protojson mapping of
+// the messages, the `behavior` of a request may be provided as a list of behavior names or numbers
+// which are combined into the bitmask.
+type gatewayMarshaler struct {
+	runtime.JSONPb
+}
+
+func (m *gatewayMarshaler) Unmarshal(data []byte, v interface{}) error {
+	data, err := combineBehaviors(data)
+	if err != nil {
+		return err
+	}
+	return m.JSONPb.Unmarshal(data, v)
+}
+
+func (m *gatewayMarshaler) NewDecoder(r io.Reader) runtime.Decoder {
+	return runtime.DecoderFunc(func(v interface{}) error {
+		data, err := ioutil.ReadAll(r)
+		if err != nil {
+			return err
+		}
+		if len(bytes.TrimSpace(data)) == 0 {
+			return io.EOF
+		}
+		return m.Unmarshal(data, v)
+	})
+}
+
+// combineBehaviors replaces each `behavior` list in the JSON document with the bitmask of the behaviors.
+// The document is returned unchanged if it contains no lists of behaviors.
+func combineBehaviors(data []byte) ([]byte, error) {
+	if !bytes.Contains(data, []byte(`"behavior"`)) {
+		return data, nil
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var doc interface{}
+	if err := dec.Decode(&doc); err != nil {
+		// Let protojson report the error
+		return data, nil
+	}
+
+	changed, err := walkBehaviors(doc)
+	if err != nil || !changed {
+		return data, err
+	}
+	return json.Marshal(doc)
+}
+
+func walkBehaviors(v interface{}) (bool, error) {
+	changed := false
+	switch t := v.(type) {
+	case map[string]interface{}:
+		for k, value := range t {
+			if list, ok := value.([]interface{}); ok && k == "behavior" {
+				b, err := behaviorMask(list)
+				if err != nil {
+					return false, err
+				}
+				t[k] = b
+				changed = true
+				continue
+			}
+			c, err := walkBehaviors(value)
+			if err != nil {
+				return false, err
+			}
+			changed = changed || c
+		}
+	case []interface{}:
+		for _, value := range t {
+			c, err := walkBehaviors(value)
+			if err != nil {
+				return false, err
+			}
+			changed = changed || c
+		}
+	}
+	return changed, nil
+}
+
+func behaviorMask(list []interface{}) (int32, error) {
+	var mask int32
+	for _, item := range list {
+		switch t := item.(type) {
+		case string:
+			b, ok := Behavior_value[t]
+			if !ok {
+				return 0, fmt.Errorf("invalid value for enum type: \"pb.gubernator.Behavior\": unknown behavior '%s'", t)
+			}
+			mask |= b
+		case json.Number:
+			b, err := t.Int64()
+			if err != nil {
+				return 0, fmt.Errorf("invalid value for enum type: \"pb.gubernator.Behavior\": %s", t)
+			}
+			mask |= int32(b)
+		default:
+			return 0, fmt.Errorf("invalid value for enum type: \"pb.gubernator.Behavior\": %v", item)
+		}
+	}
+	return mask, nil
+}
+
+// setResetTimestamp is a gateway forward response option which provides the `reset_timestamp` of
+// each rate limit in the response
+func setResetTimestamp(_ context.Context, _ http.ResponseWriter, m proto.Message) error {
+	var responses []*RateLimitResp
+	switch t := m.(type) {
+	case *GetRateLimitsResp:
+		responses = t.Responses
+	case *ResetRateLimitsResp:
+		responses = t.Responses
+	case *SetRemainingResp:
+		responses = []*RateLimitResp{t.Response}
+	}
+	for _, rl := range responses {
+		if rl != nil && rl.ResetTime != 0 {
+			rl.ResetTimestamp = timestamppb.New(FromUnixMilliseconds(rl.ResetTime))
+		}
+	}
+	return nil
+}
+
+// retryAfterHeader is the GRPC response header which carries the seconds until the rate limits of
+// the Behavior_RETRY_AFTER requests which were OVER_LIMIT reset, see setRetryAfter()
+const retryAfterHeader = "gubernator-retry-after"
+
+// setRetryAfterHeader provides the seconds until the last of the Behavior_RETRY_AFTER requests which
+// were OVER_LIMIT resets in the GRPC response header, for the HTTP gateway to translate. `responses`
+// must provide `reset_after`.
+func setRetryAfterHeader(ctx context.Context, reqs []*RateLimitReq, responses []*RateLimitResp) {
+	retryAfter := int64(-1)
+	for i, rl := range responses {
+		if rl == nil || rl.Status != Status_OVER_LIMIT || !HasBehavior(reqs[i].Behavior, Behavior_RETRY_AFTER) {
+			continue
+		}
+		wait := rl.ResetAfter
+		if rl.SuggestedRetryAfter > wait {
+			wait = rl.SuggestedRetryAfter
+		}
+		// Round up, such that the client does not retry before the reset
+		seconds := (wait + 999) / 1000
+		if seconds < 0 {
+			seconds = 0
+		}
+		if seconds > retryAfter {
+			retryAfter = seconds
+		}
+	}
+	if retryAfter < 0 {
+		return
+	}
+	// Fails if not called by a GRPC server, in which case there is no one to tell
+	_ = grpc.SetHeader(ctx, metadata.Pairs(retryAfterHeader, strconv.FormatInt(retryAfter, 10)))
+}
+
+// setRetryAfter is a gateway forward response option which responds with `429 Too Many Requests` and
+// a `Retry-After` header when setRetryAfterHeader() provided the seconds to wait
+func setRetryAfter(ctx context.Context, w http.ResponseWriter, _ proto.Message) error {
+	md, ok := runtime.ServerMetadataFromContext(ctx)
+	if !ok {
+		return nil
+	}
+	values := md.HeaderMD.Get(retryAfterHeader)
+	if len(values) == 0 {
+		return nil
+	}
+
+	// Internal calls such as the phases of AtomicGetRateLimits() may each provide a value
+	retryAfter := int64(-1)
+	for _, v := range values {
+		if seconds, err := strconv.ParseInt(v, 10, 64); err == nil && seconds > retryAfter {
+			retryAfter = seconds
+		}
+	}
+	w.Header().Del(runtime.MetadataHeaderPrefix + retryAfterHeader)
+	if retryAfter < 0 {
+		return nil
+	}
+	w.Header().Set("Retry-After", strconv.FormatInt(retryAfter, 10))
+	w.WriteHeader(http.StatusTooManyRequests)
+	return nil
+}
@@ -89,7 +89,7 @@ func (gm *globalManager) runAsyncHits() {
 			key := r.HashKey()
 			_, ok := hits[key]
 			if ok {
-				hits[key].Hits += r.Hits
+				aggregateHits(hits[key], r)
 			} else {
 				hits[key] = r
 			}
@@ -213,6 +213,7 @@ func (gm *globalManager) broadcastPeers(ctx context.Context, updates map[string]
 		// we clear the behavior flag so we don't get queued for update again.
 		SetBehavior(&rl.Behavior, Behavior_GLOBAL, false)
 		rl.Hits = 0
+		rl.Cost = 0
 
 		status, err := gm.instance.getRateLimit(ctx, rl)
 		if err != nil {
@@ -227,22 +228,25 @@ func (gm *globalManager) broadcastPeers(ctx context.Context, updates map[string]
 		})
 	}
 
+	var peers []*PeerClient
 	for _, peer := range gm.instance.GetPeerList() {
 		// Exclude ourselves from the update
 		if peer.Info().IsOwner {
 			continue
 		}
+		peers = append(peers, peer)
+	}
 
-		ctx, cancel := ctxutil.WithTimeout(context.Background(), gm.conf.GlobalTimeout)
-		_, err := peer.UpdatePeerGlobals(ctx, &req)
-		cancel()
+	// Broadcast to all peers concurrently such that a single slow peer doesn't delay the others
+	result := FanOut(context.Background(), peers, gm.conf.GlobalTimeout,
+		func(ctx context.Context, peer *PeerClient) (interface{}, error) {
+			return peer.UpdatePeerGlobals(ctx, &req)
+		})
 
-		if err != nil {
-			// Skip peers that are not in a ready state
-			if !IsNotReady(err) {
-				gm.log.WithError(err).Errorf("while broadcasting global updates to '%s'", peer.Info().GRPCAddress)
-			}
-			continue
+	for _, f := range result.Unresponsive {
+		// Skip peers that are not in a ready state
+		if !IsNotReady(f.Err) {
+			gm.log.WithError(f.Err).Errorf("while broadcasting global updates to '%s'", f.Peer.GRPCAddress)
 		}
 	}
 
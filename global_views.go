@@ -0,0 +1,137 @@
+/*
+Copyright 2018-2022 Mailgun Technologies Inc
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gubernator
+
+import (
+	"context"
+	"sort"
+
+	"github.com/mailgun/holster/v4/errors"
+	"github.com/mailgun/holster/v4/tracing"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// GetGlobalRateLimitViews asks each peer in the local datacenter for its view of a GLOBAL rate limit.
+// Peers which do not respond within `GlobalTimeout` are reported with an error instead of failing
+// the request.
+func (s *V1Instance) GetGlobalRateLimitViews(ctx context.Context, r *GetGlobalRateLimitViewsReq) (retval *GetGlobalRateLimitViewsResp, reterr error) {
+	ctx = tracing.StartScope(ctx)
+	defer func() {
+		tracing.EndScope(ctx, reterr)
+	}()
+
+	if err := checkGlobalViewRequest(r); err != nil {
+		return nil, err
+	}
+
+	key := r.Name + "_" + r.UniqueKey
+	owner, err := s.GetPeer(ctx, key)
+	if err != nil {
+		return nil, errors.Wrapf(err, "while finding peer that owns rate limit '%s'", key)
+	}
+	ownerAddress := owner.Info().GRPCAddress
+
+	s.peerMutex.RLock()
+	peers := s.conf.LocalPicker.Peers()
+	s.peerMutex.RUnlock()
+
+	result := FanOut(ctx, peers, s.conf.Behaviors.GlobalTimeout, func(ctx context.Context, peer *PeerClient) (interface{}, error) {
+		if peer.Info().IsOwner {
+			return s.GetPeerGlobalView(ctx, r)
+		}
+		return peer.GetPeerGlobalView(ctx, r)
+	})
+
+	resp := &GetGlobalRateLimitViewsResp{}
+	for _, res := range result.Results {
+		view := res.Value.(*GlobalRateLimitView)
+		view.PeerAddress = res.Peer.GRPCAddress
+		view.IsOwner = res.Peer.GRPCAddress == ownerAddress
+		resp.Views = append(resp.Views, view)
+	}
+	for _, f := range result.Unresponsive {
+		resp.Views = append(resp.Views, &GlobalRateLimitView{
+			PeerAddress: f.Peer.GRPCAddress,
+			IsOwner:     f.Peer.GRPCAddress == ownerAddress,
+			Error:       f.Err.Error(),
+		})
+	}
+
+	sort.Slice(resp.Views, func(i, j int) bool {
+		return resp.Views[i].PeerAddress < resp.Views[j].PeerAddress
+	})
+	return resp, nil
+}
+
+// GetPeerGlobalView returns the view this instance holds of a GLOBAL rate limit. This method should
+// only be called by a peer fanning out an AdminV1 GetGlobalRateLimitViews request.
+func (s *V1Instance) GetPeerGlobalView(ctx context.Context, r *GetGlobalRateLimitViewsReq) (retval *GlobalRateLimitView, reterr error) {
+	ctx = tracing.StartScope(ctx)
+	defer func() {
+		tracing.EndScope(ctx, reterr)
+	}()
+
+	if err := checkGlobalViewRequest(r); err != nil {
+		return nil, err
+	}
+
+	item, ok, err := s.gubernatorPool.GetCacheItem(ctx, r.Name+"_"+r.UniqueKey)
+	if err != nil {
+		return nil, errors.Wrap(err, "Error in gubernatorPool.GetCacheItem")
+	}
+	now := MillisecondNow()
+	if !ok || item.ExpireAt <= now {
+		return &GlobalRateLimitView{}, nil
+	}
+
+	// Peers other than the owner hold the last response broadcast by the owner, unless the
+	// broadcast has yet to arrive since the peer received its first hit.
+	if rl, ok := item.Value.(*RateLimitResp); ok {
+		return &GlobalRateLimitView{
+			Found:     true,
+			Status:    rl.Status,
+			Remaining: rl.Remaining,
+			TotalHits: rl.TotalHits,
+		}, nil
+	}
+
+	rl := cacheItemToListItem(item, now)
+	if rl == nil {
+		return &GlobalRateLimitView{}, nil
+	}
+	view := &GlobalRateLimitView{
+		Found:     true,
+		Status:    Status_UNDER_LIMIT,
+		Remaining: rl.Remaining,
+		TotalHits: cacheItemTotalHits(item),
+	}
+	if rl.Remaining == 0 {
+		view.Status = Status_OVER_LIMIT
+	}
+	return view, nil
+}
+
+func checkGlobalViewRequest(r *GetGlobalRateLimitViewsReq) error {
+	if len(r.UniqueKey) == 0 {
+		return status.Error(codes.InvalidArgument, "field 'unique_key' cannot be empty")
+	}
+	if len(r.Name) == 0 {
+		return status.Error(codes.InvalidArgument, "field 'name' cannot be empty")
+	}
+	return nil
+}
@@ -19,6 +19,7 @@ package gubernator
 import (
 	"context"
 	"fmt"
+	"sort"
 	"strings"
 	"sync"
 	"sync/atomic"
@@ -41,11 +42,15 @@ const (
 	maxBatchSize = 1000
 	Healthy      = "healthy"
 	UnHealthy    = "unhealthy"
+
+	// The largest `duration` of any rate limit, well within the range of an int64 once added to a timestamp
+	maxRateLimitDuration = Minute * 60 * 24 * 366 * 1000
 )
 
 type V1Instance struct {
 	UnimplementedV1Server
 	UnimplementedPeersV1Server
+	UnimplementedAdminV1Server
 	global               *globalManager
 	mutliRegion          *mutliRegionManager
 	peerMutex            sync.RWMutex
@@ -54,12 +59,27 @@ type V1Instance struct {
 	isClosed             bool
 	getRateLimitsCounter int64
 	gubernatorPool       *GubernatorPool
+	asyncStore           *asyncStore
+	// The Config.Store as provided, if it implements BulkStore. Unlike `conf.Store` it is not wrapped.
+	bulkStore        BulkStore
+	peerHealth       *peerHealthChecker
+	admission        *admissionGuard
+	replication      *replicationManager
+	metadataCounter  *metadataCounter
+	namespaceCounter *namespaceCounter
+	audit            *auditLog
+	peerInfo         []PeerInfo
+	setPeersMutex    sync.Mutex
 }
 
 var getRateLimitCounter = prometheus.NewCounterVec(prometheus.CounterOpts{
 	Name: "gubernator_getratelimit_counter",
 	Help: "The count of getRateLimit() calls.  Label \"calltype\" may be \"local\" for calls handled by the same peer, \"forward\" for calls forwarded to another peer, or \"global\" for global rate limits.",
 }, []string{"calltype"})
+var getRateLimitPeerCounter = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Name: "gubernator_getratelimit_peer_counter",
+	Help: "The count of getRateLimit() calls per owning peer.  Label \"calltype\" may be \"local\" for calls handled by the same peer or \"forward\" for calls forwarded to another peer.  Label \"peerAddr\" is the address of the owning peer.",
+}, []string{"calltype", "peerAddr"})
 var funcTimeMetric = prometheus.NewSummaryVec(prometheus.SummaryOpts{
 	Name: "gubernator_func_duration",
 	Help: "The timings of key functions in Gubernator in seconds.",
@@ -67,6 +87,11 @@ var funcTimeMetric = prometheus.NewSummaryVec(prometheus.SummaryOpts{
 		0.99: 0.001,
 	},
 }, []string{"name"})
+var algorithmDurationMetric = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+	Name:    "gubernator_algorithm_duration",
+	Help:    "The timings of the rate limit algorithms in seconds.  Label \"algorithm\" is the algorithm name.  Label \"cache\" is \"hit\" when the rate limit was found in the cache or \"miss\" when it was read from the store or created.",
+	Buckets: prometheus.ExponentialBuckets(0.00001, 4, 10),
+}, []string{"algorithm", "cache"})
 var asyncRequestRetriesCounter = prometheus.NewCounterVec(prometheus.CounterOpts{
 	Name: "gubernator_asyncrequest_retries",
 	Help: "The count of retries occurred in asyncRequests() forwarding a request to another peer.",
@@ -82,6 +107,11 @@ var checkCounter = prometheus.NewCounter(prometheus.CounterOpts{
 	Name: "gubernator_check_counter",
 	Help: "The number of rate limits checked.",
 })
+var getRateLimitsDurationMetric = prometheus.NewHistogram(prometheus.HistogramOpts{
+	Name:    "gubernator_getratelimits_duration",
+	Help:    "The timings of GetRateLimits requests in seconds, including requests forwarded to other peers.",
+	Buckets: prometheus.DefBuckets,
+})
 var overLimitCounter = prometheus.NewCounter(prometheus.CounterOpts{
 	Name: "gubernator_over_limit_counter",
 	Help: "The number of rate limit checks that are over the limit.",
@@ -104,6 +134,10 @@ var poolWorkerQueueLength = prometheus.NewSummaryVec(prometheus.SummaryOpts{
 		0.99: 0.001,
 	},
 }, []string{"method", "worker"})
+var peerChangeCounter = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Name: "gubernator_peer_changes",
+	Help: "The number of peers added to or removed from the local hash ring.  Label \"change\" may be \"added\" or \"removed\".",
+}, []string{"change"})
 var batchSendDurationMetric = prometheus.NewSummaryVec(prometheus.SummaryOpts{
 	Name: "gubernator_batch_send_duration",
 	Help: "The timings of batch send operations to a remote peer.",
@@ -133,24 +167,86 @@ func NewV1Instance(conf Config) (retval *V1Instance, reterr error) {
 	}
 	setter.SetDefault(&s.log, logrus.WithField("category", "gubernator"))
 
-	s.gubernatorPool = NewGubernatorPool(&conf, conf.PoolWorkers, 0)
+	if cs, ok := conf.Store.(CodecSetter); ok {
+		cs.SetCodec(conf.StoreCodec)
+	}
+	if cs, ok := conf.Loader.(CodecSetter); ok {
+		cs.SetCodec(conf.StoreCodec)
+	}
+	s.bulkStore, _ = conf.Store.(BulkStore)
+
+	if conf.Store != nil {
+		conf.Store = newTracedStore(conf.Store, conf.TracerProvider)
+	}
+
+	// Fall back to the cache alone while the store is failing
+	if conf.Store != nil && conf.StoreBreakerThreshold > 0 {
+		conf.Store = newBreakerStore(conf.Store, conf, s.log)
+	}
+
+	// Buffer changes to the store such that store I/O is not in the request path
+	if conf.Store != nil && conf.StoreFlushInterval != 0 {
+		s.asyncStore = newAsyncStore(conf.Store, conf.StoreFlushInterval, conf.StoreBufferSize)
+		conf.Store = s.asyncStore
+	}
+	// Changes made outside the pool, IE: resetting a namespace, must also pass through the wrappers
+	s.conf.Store = conf.Store
+
+	s.gubernatorPool = NewGubernatorPool(&conf, conf.PoolWorkers, conf.CacheSize)
 	s.global = newGlobalManager(conf.Behaviors, &s)
 	s.mutliRegion = newMultiRegionManager(conf.Behaviors, &s)
+	if conf.Behaviors.PeerHealthCheckInterval != 0 {
+		s.peerHealth = newPeerHealthChecker(conf.Behaviors, &s)
+	}
+	if conf.AdmissionLimit > 0 {
+		s.admission = newAdmissionGuard(conf, s.log)
+	}
+	if len(conf.MetadataLabels) != 0 {
+		s.metadataCounter = newMetadataCounter(conf.MetadataLabels, conf.MetadataLabelValues)
+	}
+	s.namespaceCounter = newNamespaceCounter(conf.OverLimitNamespaces, conf.OverLimitNamespaceValues)
+	if conf.AuditSink != nil && len(conf.AuditNamespaces) != 0 {
+		s.audit = newAuditLog(conf, s.log)
+	}
+	if conf.ReplicationFactor > 1 {
+		if _, ok := conf.LocalPicker.(SuccessorPicker); !ok {
+			return nil, errors.Errorf("ReplicationFactor requires a LocalPicker which implements SuccessorPicker; got '%T'", conf.LocalPicker)
+		}
+		s.replication = newReplicationManager(conf.Behaviors, conf.ReplicationFactor, &s)
+	}
 
 	// Register our instance with all GRPC servers
 	for _, srv := range conf.GRPCServers {
 		RegisterV1Server(srv, &s)
+		if len(conf.AdminGRPCServers) == 0 {
+			RegisterPeersV1Server(srv, &s)
+			RegisterAdminV1Server(srv, &s)
+			continue
+		}
+		RegisterPeersV1Server(srv, &dataPeersV1{V1Instance: &s})
+	}
+
+	// Privileged methods are only served by the admin servers if provided
+	for _, srv := range conf.AdminGRPCServers {
 		RegisterPeersV1Server(srv, &s)
+		RegisterAdminV1Server(srv, &s)
 	}
 
-	if s.conf.Loader == nil {
-		return &s, nil
+	if s.conf.Loader != nil {
+		// Load the cache.
+		err := s.gubernatorPool.Load(ctx)
+		if err != nil {
+			return nil, errors.Wrap(err, "Error in checkHandlerPool.Load")
+		}
 	}
 
-	// Load the cache.
-	err := s.gubernatorPool.Load(ctx)
-	if err != nil {
-		return nil, errors.Wrap(err, "Error in checkHandlerPool.Load")
+	// Warm the cache if the store supports bulk loading, else
+	// rate limits are loaded from the store as they are requested.
+	if s.bulkStore != nil {
+		err := s.gubernatorPool.LoadStore(ctx, s.bulkStore)
+		if err != nil {
+			return nil, errors.Wrap(err, "Error in checkHandlerPool.LoadStore")
+		}
 	}
 
 	return &s, nil
@@ -166,6 +262,28 @@ func (s *V1Instance) Close() (reterr error) {
 		return nil
 	}
 
+	if s.peerHealth != nil {
+		s.peerHealth.Close()
+	}
+
+	if s.replication != nil {
+		s.replication.Close()
+	}
+
+	if s.conf.DrainOnShutdown {
+		if err := s.drain(ctx); err != nil {
+			s.log.WithError(err).Error("Error in V1Instance.drain")
+		}
+	}
+
+	if s.asyncStore != nil {
+		s.asyncStore.Close(ctx)
+	}
+
+	if s.audit != nil {
+		s.audit.Close()
+	}
+
 	if s.conf.Loader == nil {
 		return nil
 	}
@@ -203,18 +321,41 @@ func (s *V1Instance) GetRateLimits(ctx context.Context, r *GetRateLimitsReq) (re
 
 	funcTimer := prometheus.NewTimer(funcTimeMetric.WithLabelValues("V1Instance.GetRateLimits"))
 	defer funcTimer.ObserveDuration()
+	durationTimer := prometheus.NewTimer(getRateLimitsDurationMetric)
+	defer durationTimer.ObserveDuration()
 
 	concurrentCounter := atomic.AddInt64(&s.getRateLimitsCounter, 1)
 	defer atomic.AddInt64(&s.getRateLimitsCounter, -1)
 	span.SetAttributes(attribute.Int64("concurrentCounter", concurrentCounter))
 	concurrentChecksMetric.Observe(float64(concurrentCounter))
 
+	if s.admission != nil {
+		if err := s.admission.admit(ctx); err != nil {
+			checkErrorCounter.WithLabelValues("Admission limit exceeded").Add(1)
+			return nil, err
+		}
+	}
+
 	if len(r.Requests) > maxBatchSize {
 		checkErrorCounter.WithLabelValues("Request too large").Add(1)
 		return nil, status.Errorf(codes.OutOfRange,
 			"Requests.RateLimits list too large; max size is '%d'", maxBatchSize)
 	}
 
+	// Reject the whole batch before any rate limit is touched
+	reqs := make([]*RateLimitReq, len(r.Requests))
+	for i, req := range r.Requests {
+		reqs[i] = s.applyGregorianLocation(s.applyNamespaceDefaults(req))
+		if err := s.checkRequest(reqs[i]); err != nil {
+			checkErrorCounter.WithLabelValues("Invalid request").Add(1)
+			return nil, err
+		}
+		if err := s.checkParents(reqs[i]); err != nil {
+			checkErrorCounter.WithLabelValues("Invalid request").Add(1)
+			return nil, err
+		}
+	}
+
 	resp := GetRateLimitsResp{
 		Responses: make([]*RateLimitResp, len(r.Requests)),
 	}
@@ -223,21 +364,15 @@ func (s *V1Instance) GetRateLimits(ctx context.Context, r *GetRateLimitsReq) (re
 	asyncCh := make(chan AsyncResp, len(r.Requests))
 
 	// For each item in the request body
-	for i, req := range r.Requests {
+	for i, req := range reqs {
 		_ = tracing.NamedScope(ctx, "Iterate requests", func(ctx context.Context) error {
 			key := req.Name + "_" + req.UniqueKey
 			var peer *PeerClient
 			var err error
 
-			if len(req.UniqueKey) == 0 {
+			if err := checkKeys(req); err != nil {
 				checkErrorCounter.WithLabelValues("Invalid request").Add(1)
-				resp.Responses[i] = &RateLimitResp{Error: "field 'unique_key' cannot be empty"}
-				return nil
-			}
-
-			if len(req.Name) == 0 {
-				checkErrorCounter.WithLabelValues("Invalid request").Add(1)
-				resp.Responses[i] = &RateLimitResp{Error: "field 'namespace' cannot be empty"}
+				resp.Responses[i] = &RateLimitResp{Error: status.Convert(err).Message()}
 				return nil
 			}
 
@@ -250,6 +385,22 @@ func (s *V1Instance) GetRateLimits(ctx context.Context, r *GetRateLimitsReq) (re
 				return nil
 			}
 
+			// Cascading requests are applied to each of the levels by getCascadingRateLimit()
+			if len(req.Parents) != 0 {
+				wg.Add(1)
+				go func(i int, req *RateLimitReq) {
+					defer wg.Done()
+					rl, err := s.getCascadingRateLimit(ctx, req)
+					if err != nil {
+						err = errors.Wrap(err, "Error in getCascadingRateLimit")
+						span.RecordError(err)
+						rl = &RateLimitResp{Error: err.Error()}
+					}
+					asyncCh <- AsyncResp{Idx: i, Resp: rl}
+				}(i, req)
+				return nil
+			}
+
 			peer, err = s.GetPeer(ctx, key)
 			if err != nil {
 				countError(err, "Error in GetPeer")
@@ -263,15 +414,22 @@ func (s *V1Instance) GetRateLimits(ctx context.Context, r *GetRateLimitsReq) (re
 			// If our server instance is the owner of this rate limit
 			if peer.Info().IsOwner {
 				// Apply our rate limit algorithm to the request
-				getRateLimitCounter.WithLabelValues("local").Add(1)
+				countServed("local", peer)
 				funcTimer1 := prometheus.NewTimer(funcTimeMetric.WithLabelValues("V1Instance.getRateLimit (local)"))
 				resp.Responses[i], err = s.getRateLimit(ctx, req)
 				funcTimer1.ObserveDuration()
 				if err != nil {
+					s.log.WithContext(ctx).
+						WithError(err).
+						WithField("key", key).
+						WithFields(metadataFields(req)).
+						Error("Error applying rate limit")
 					err = errors.Wrapf(err, "Error while apply rate limit for '%s'", key)
 					span.RecordError(err)
 					resp.Responses[i] = &RateLimitResp{Error: err.Error()}
 				}
+				setMetadata(resp.Responses[i], "served", "local")
+				resp.Responses[i].Owner = peer.Info().GRPCAddress
 			} else {
 				if HasBehavior(req.Behavior, Behavior_GLOBAL) {
 					resp.Responses[i], err = s.getGlobalRateLimit(ctx, req)
@@ -283,6 +441,7 @@ func (s *V1Instance) GetRateLimits(ctx context.Context, r *GetRateLimitsReq) (re
 
 					// Inform the client of the owner key of the key
 					resp.Responses[i].Metadata = map[string]string{"owner": peer.Info().GRPCAddress}
+					resp.Responses[i].Owner = peer.Info().GRPCAddress
 					return nil
 				}
 
@@ -313,9 +472,165 @@ func (s *V1Instance) GetRateLimits(ctx context.Context, r *GetRateLimitsReq) (re
 		resp.Responses[a.Idx] = a.Resp
 	}
 
+	// The client is no longer waiting for the responses
+	if err := ctx.Err(); err != nil {
+		checkErrorCounter.WithLabelValues("Request canceled").Add(1)
+		return nil, status.FromContextError(err).Err()
+	}
+
+	now := MillisecondNow()
+	for i, rl := range resp.Responses {
+		setResetAfter(rl, now)
+		echoMetadata(reqs[i], rl)
+		if s.metadataCounter != nil {
+			s.metadataCounter.Observe(reqs[i], rl)
+		}
+		s.namespaceCounter.Observe(reqs[i], rl)
+		if s.audit != nil {
+			s.audit.Observe(reqs[i], rl)
+		}
+	}
+	setRetryAfterHeader(ctx, reqs, resp.Responses)
+
 	return &resp, nil
 }
 
+// checkRequest returns an InvalidArgument error if the request cannot be applied
+func (s *V1Instance) checkRequest(req *RateLimitReq) error {
+	if err := s.checkBounds(req); err != nil {
+		return err
+	}
+	if err := checkGregorian(req); err != nil {
+		return err
+	}
+	if err := checkSchedule(req); err != nil {
+		return err
+	}
+	if s.conf.StrictKeys {
+		return checkKeys(req)
+	}
+	return nil
+}
+
+// checkBounds returns an InvalidArgument error if the request exceeds Config.MaxDuration or Config.MaxHits
+func (s *V1Instance) checkBounds(req *RateLimitReq) error {
+	// The duration of a gregorian rate limit is an interval, not milliseconds
+	if !HasBehavior(req.Behavior, Behavior_DURATION_IS_GREGORIAN) {
+		if req.Duration < 0 {
+			return status.Errorf(codes.InvalidArgument, "'duration' of '%d' for '%s' cannot be negative",
+				req.Duration, req.HashKey())
+		}
+		max := int64(maxRateLimitDuration)
+		if s.conf.MaxDuration != 0 && s.conf.MaxDuration.Milliseconds() < max {
+			max = s.conf.MaxDuration.Milliseconds()
+		}
+		if req.Duration > max {
+			return status.Errorf(codes.InvalidArgument, "'duration' of '%d' for '%s' exceeds the maximum of '%d'",
+				req.Duration, req.HashKey(), max)
+		}
+	}
+	if s.conf.MaxHits != 0 && (req.Hits > s.conf.MaxHits || req.Hits < -s.conf.MaxHits) {
+		return status.Errorf(codes.InvalidArgument, "'hits' of '%d' for '%s' exceeds the maximum of '%d'",
+			req.Hits, req.HashKey(), s.conf.MaxHits)
+	}
+	return nil
+}
+
+// checkKeys returns an InvalidArgument error if the request is missing the name or unique key which
+// make up its hash key, since such requests from unrelated clients would share a single rate limit.
+func checkKeys(req *RateLimitReq) error {
+	if len(req.UniqueKey) == 0 {
+		return status.Error(codes.InvalidArgument, "field 'unique_key' cannot be empty")
+	}
+	if len(req.Name) == 0 {
+		return status.Error(codes.InvalidArgument, "field 'namespace' cannot be empty")
+	}
+	return nil
+}
+
+// applyNamespaceDefaults returns a copy of the request with unset fields filled in from
+// Config.NamespaceDefaults, or the request itself if there is no default for the name. Fields
+// listed in `RateLimitReq.ExplicitFields` are kept even when they hold the zero value.
+func (s *V1Instance) applyNamespaceDefaults(req *RateLimitReq) *RateLimitReq {
+	d, ok := s.conf.NamespaceDefaults[req.Name]
+	if !ok {
+		return req
+	}
+
+	explicit := make(map[string]bool, len(req.ExplicitFields))
+	for _, f := range req.ExplicitFields {
+		explicit[f] = true
+	}
+
+	req = proto.Clone(req).(*RateLimitReq)
+	if !explicit["limit"] {
+		setter.SetDefault(&req.Limit, d.Limit)
+	}
+	if !explicit["duration"] {
+		setter.SetDefault(&req.Duration, d.Duration)
+	}
+	if !explicit["algorithm"] {
+		setter.SetDefault(&req.Algorithm, d.Algorithm)
+	}
+	if !explicit["behavior"] {
+		setter.SetDefault(&req.Behavior, d.Behavior)
+	}
+	if !explicit["burst"] {
+		setter.SetDefault(&req.Burst, d.Burst)
+	}
+	if !explicit["penalty"] {
+		setter.SetDefault(&req.Penalty, d.Penalty)
+	}
+	if !explicit["reset_jitter"] {
+		setter.SetDefault(&req.ResetJitter, d.ResetJitter)
+	}
+	if !explicit["priority"] {
+		setter.SetDefault(&req.Priority, d.Priority)
+	}
+	return req
+}
+
+// applyGregorianLocation provides the name of Config.GregorianLocation as the `timezone` of a
+// DURATION_IS_GREGORIAN or scheduled request which does not provide one, such that the owning peer
+// computes the intervals and the time of day in our location
+func (s *V1Instance) applyGregorianLocation(req *RateLimitReq) *RateLimitReq {
+	if s.conf.GregorianLocation == nil || req.Timezone != "" ||
+		(!HasBehavior(req.Behavior, Behavior_DURATION_IS_GREGORIAN) && len(req.Schedule) == 0) {
+		return req
+	}
+
+	req = proto.Clone(req).(*RateLimitReq)
+	req.Timezone = s.conf.GregorianLocation.String()
+	return req
+}
+
+// setResetAfter computes the milliseconds until the rate limit resets using the server clock,
+// such that clients need not rely on their own clock agreeing with ours.
+func setResetAfter(rl *RateLimitResp, now int64) {
+	if rl == nil || rl.ResetTime <= now {
+		return
+	}
+	rl.ResetAfter = rl.ResetTime - now
+}
+
+// translateResetTime translates the `reset_time` of a response forwarded from the owning peer to our clock
+// when the clock of the owner differs from ours by more than Config.ClockSkewTolerance. The `reset_after`
+// computed by the owner is relative, as such it stays correct regardless of the skew.
+func (s *V1Instance) translateResetTime(rl *RateLimitResp) {
+	// Owners which predate `reset_after` on the peer API leave it unset
+	if rl.ResetAfter <= 0 {
+		return
+	}
+	now := MillisecondNow()
+	skew := rl.ResetTime - rl.ResetAfter - now
+	if skew < 0 {
+		skew = -skew
+	}
+	if skew > s.conf.ClockSkewTolerance.Milliseconds() {
+		rl.ResetTime = now + rl.ResetAfter
+	}
+}
+
 type AsyncResp struct {
 	Idx  int
 	Resp *RateLimitResp
@@ -357,6 +672,7 @@ func (s *V1Instance) asyncRequests(ctx context.Context, req *AsyncReq) {
 			s.log.WithContext(ctx).
 				WithError(err).
 				WithField("key", req.Key).
+				WithFields(metadataFields(req.Req)).
 				Error("GetPeer() returned peer that is not connected")
 			countError(err, "Peer not connected")
 			err = errors.Wrapf(err, "GetPeer() keeps returning peers that are not connected for '%s'", req.Key)
@@ -367,22 +683,25 @@ func (s *V1Instance) asyncRequests(ctx context.Context, req *AsyncReq) {
 		// If we are attempting again, the owner of this rate limit might have changed to us!
 		if attempts != 0 {
 			if req.Peer.Info().IsOwner {
-				getRateLimitCounter.WithLabelValues("local").Add(1)
+				countServed("local", req.Peer)
 				resp.Resp, err = s.getRateLimit(ctx, req.Req)
 				if err != nil {
 					s.log.WithContext(ctx).
 						WithError(err).
 						WithField("key", req.Key).
+						WithFields(metadataFields(req.Req)).
 						Error("Error applying rate limit")
 					err = errors.Wrapf(err, "Error in getRateLimit for '%s'", req.Key)
 					resp.Resp = &RateLimitResp{Error: err.Error()}
 				}
+				setMetadata(resp.Resp, "served", "local")
+				resp.Resp.Owner = req.Peer.Info().GRPCAddress
 				break
 			}
 		}
 
 		// Make an RPC call to the peer that owns this rate limit
-		getRateLimitCounter.WithLabelValues("forward").Add(1)
+		countServed("forward", req.Peer)
 		r, err := req.Peer.GetPeerRateLimit(ctx, req.Req)
 		if err != nil {
 			if IsNotReady(err) {
@@ -391,7 +710,11 @@ func (s *V1Instance) asyncRequests(ctx context.Context, req *AsyncReq) {
 				req.Peer, err = s.GetPeer(ctx, req.Key)
 				if err != nil {
 					errPart := fmt.Sprintf("Error finding peer that owns rate limit '%s'", req.Key)
-					s.log.WithContext(ctx).WithError(err).WithField("key", req.Key).Error(errPart)
+					s.log.WithContext(ctx).
+						WithError(err).
+						WithField("key", req.Key).
+						WithFields(metadataFields(req.Req)).
+						Error(errPart)
 					countError(err, "Error in GetPeer")
 					err = errors.Wrap(err, errPart)
 					resp.Resp = &RateLimitResp{Error: err.Error()}
@@ -409,7 +732,9 @@ func (s *V1Instance) asyncRequests(ctx context.Context, req *AsyncReq) {
 
 		// Inform the client of the owner key of the key
 		resp.Resp = r
-		resp.Resp.Metadata = map[string]string{"owner": req.Peer.Info().GRPCAddress}
+		resp.Resp.Metadata = map[string]string{"owner": req.Peer.Info().GRPCAddress, "served": "forward"}
+		resp.Resp.Owner = req.Peer.Info().GRPCAddress
+		s.translateResetTime(resp.Resp)
 		break
 	}
 
@@ -421,6 +746,20 @@ func (s *V1Instance) asyncRequests(ctx context.Context, req *AsyncReq) {
 	}
 }
 
+// countServed counts a getRateLimit() call of `calltype` served by the owning peer
+func countServed(calltype string, peer *PeerClient) {
+	getRateLimitCounter.WithLabelValues(calltype).Add(1)
+	getRateLimitPeerCounter.WithLabelValues(calltype, peer.Info().GRPCAddress).Add(1)
+}
+
+// setMetadata sets the metadata `key` of the response to `value`
+func setMetadata(rl *RateLimitResp, key, value string) {
+	if rl.Metadata == nil {
+		rl.Metadata = make(map[string]string)
+	}
+	rl.Metadata[key] = value
+}
+
 // getGlobalRateLimit handles rate limits that are marked as `Behavior = GLOBAL`. Rate limit responses
 // are returned from the local cache and the hits are queued to be sent to the owning peer.
 func (s *V1Instance) getGlobalRateLimit(ctx context.Context, req *RateLimitReq) (retval *RateLimitResp, reterr error) {
@@ -435,7 +774,10 @@ func (s *V1Instance) getGlobalRateLimit(ctx context.Context, req *RateLimitReq)
 	// NOTE: The defer here avoids a race condition where we queue the req to
 	// be forwarded to the owning peer in a separate goroutine but simultaneously
 	// access and possibly copy the req in this method.
-	defer s.global.QueueHit(req)
+	peek := HasBehavior(req.Behavior, Behavior_PEEK)
+	if !peek {
+		defer s.global.QueueHit(req)
+	}
 
 	item, ok, err := s.gubernatorPool.GetCacheItem(ctx, req.HashKey())
 	if err != nil {
@@ -446,6 +788,11 @@ func (s *V1Instance) getGlobalRateLimit(ctx context.Context, req *RateLimitReq)
 		// Global rate limits are always stored as RateLimitResp regardless of algorithm
 		rl, ok := item.Value.(*RateLimitResp)
 		if ok {
+			// Return a copy, as the caller may modify the response after the cache is updated
+			rl = proto.Clone(rl).(*RateLimitResp)
+			if peek {
+				return peekGlobal(req, rl), nil
+			}
 			return rl, nil
 		}
 		// We get here if the owning node hasn't asynchronously forwarded it's updates to us yet and
@@ -454,6 +801,7 @@ func (s *V1Instance) getGlobalRateLimit(ctx context.Context, req *RateLimitReq)
 
 	cpy := proto.Clone(req).(*RateLimitReq)
 	cpy.Behavior = Behavior_NO_BATCHING
+	SetBehavior(&cpy.Behavior, Behavior_PEEK, peek)
 
 	// Process the rate limit like we own it
 	getRateLimitCounter.WithLabelValues("global").Add(1)
@@ -504,6 +852,15 @@ func (s *V1Instance) GetPeerRateLimits(ctx context.Context, r *GetPeerRateLimits
 		return nil, status.Error(codes.OutOfRange, err.Error())
 	}
 
+	if s.conf.StrictKeys {
+		for _, req := range r.Requests {
+			if err := checkKeys(req); err != nil {
+				checkErrorCounter.WithLabelValues("Invalid request").Add(1)
+				return nil, err
+			}
+		}
+	}
+
 	// Invoke each rate limit request.
 	type reqIn struct {
 		idx int
@@ -517,47 +874,139 @@ func (s *V1Instance) GetPeerRateLimits(ctx context.Context, r *GetPeerRateLimits
 	resp := &GetPeerRateLimitsResp{
 		RateLimits: make([]*RateLimitResp, len(r.Requests)),
 	}
-	respChan := make(chan respOut)
-	var respWg sync.WaitGroup
-	respWg.Add(1)
+	// Buffered such that the requests still running once the deadline passed do not block
+	respChan := make(chan respOut, len(r.Requests))
 
+	// Requests not answered by the deadline are answered with an error, such that a slow
+	// rate limit does not hold up the rest of the batch.
+	itemCtx, cancel := ctxutil.WithTimeout(ctx, s.conf.Behaviors.BatchItemTimeout)
+	defer cancel()
+
+	// Each request is cancelled on its own once answered as timed out, see batchItem
+	items := make([]*batchItem, len(r.Requests))
+	for i := range items {
+		items[i] = &batchItem{}
+		items[i].ctx, items[i].cancel = context.WithCancel(context.WithValue(ctx, batchItemContextKey{}, items[i]))
+		defer items[i].cancel()
+	}
+
+	// Fan out requests. Requests from peers are always applied locally and never forwarded, even if
+	// our view of the ring disagrees with the sender, so a stale ring cannot cause a forwarding loop.
 	go func() {
-		// Capture each response and keep in stable order.
-		for out := range respChan {
-			resp.RateLimits[out.idx] = out.rl
-		}
+		concurrencyLimit := s.conf.PoolWorkers
+		fan := syncutil.NewFanOut(concurrencyLimit)
+		for idx, req := range r.Requests {
+			fan.Run(func(in interface{}) error {
+				rin := in.(reqIn)
+				// Peers only forward requests which passed GetRateLimits() validation, but PeersV1 may be called directly
+				if err := checkKeys(rin.req); err != nil {
+					checkErrorCounter.WithLabelValues("Invalid request").Add(1)
+					respChan <- respOut{rin.idx, &RateLimitResp{Error: status.Convert(err).Message()}}
+					return nil
+				}
+				rl, err := s.getRateLimit(items[rin.idx].ctx, rin.req)
+				if err != nil {
+					// Return the error for this request
+					err = errors.Wrap(err, "Error in getRateLimit")
+					span.RecordError(err)
+					rl = &RateLimitResp{Error: err.Error()}
+					// checkErrorCounter is updated within getRateLimit().
+				}
 
-		respWg.Done()
+				respChan <- respOut{rin.idx, rl}
+				return nil
+			}, reqIn{idx, req})
+		}
 	}()
 
-	// Fan out requests.
-	concurrencyLimit := s.conf.PoolWorkers
-	fan := syncutil.NewFanOut(concurrencyLimit)
-	for idx, req := range r.Requests {
-		fan.Run(func(in interface{}) error {
-			rin := in.(reqIn)
-			rl, err := s.getRateLimit(ctx, rin.req)
-			if err != nil {
-				// Return the error for this request
-				err = errors.Wrap(err, "Error in getRateLimit")
-				span.RecordError(err)
-				rl = &RateLimitResp{Error: err.Error()}
-				// checkErrorCounter is updated within getRateLimit().
-			}
+	// Capture each response and keep in stable order.
+collect:
+	for answered := 0; answered < len(r.Requests); answered++ {
+		select {
+		case out := <-respChan:
+			resp.RateLimits[out.idx] = out.rl
+		case <-itemCtx.Done():
+			break collect
+		}
+	}
 
-			respChan <- respOut{rin.idx, rl}
-			return nil
-		}, reqIn{idx, req})
+	// A request the worker began to apply by the deadline is waited for, such that the hits of a
+	// request answered as timed out are never applied.
+	var applying int
+	for i, rl := range resp.RateLimits {
+		if rl != nil {
+			continue
+		}
+		if !items[i].expire() {
+			applying++
+			continue
+		}
+		checkErrorCounter.WithLabelValues("Timeout").Add(1)
+		resp.RateLimits[i] = &RateLimitResp{
+			Error: fmt.Sprintf("%s: rate limit was not answered within the batch item timeout of '%s'",
+				codes.DeadlineExceeded, s.conf.Behaviors.BatchItemTimeout),
+		}
+	}
+	for applying > 0 {
+		out := <-respChan
+		// Skip the requests answered as timed out
+		if resp.RateLimits[out.idx] != nil {
+			continue
+		}
+		resp.RateLimits[out.idx] = out.rl
+		applying--
+	}
+
+	// The requesting peer is no longer waiting for the responses
+	if err := ctx.Err(); err != nil {
+		return nil, status.FromContextError(err).Err()
 	}
 
-	// Wait for all requests to be handled, then clean up.
-	_ = fan.Wait()
-	close(respChan)
-	respWg.Wait()
+	// Allows the requesting peer to translate the reset time should our clocks disagree
+	now := MillisecondNow()
+	for _, rl := range resp.RateLimits {
+		setResetAfter(rl, now)
+	}
 
 	return resp, nil
 }
 
+type batchItemContextKey struct{}
+
+// batchItem decides the race between the worker applying a request of GetPeerRateLimits() and the
+// request being answered as timed out; whichever comes first wins.
+type batchItem struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+	state  int32
+}
+
+const (
+	batchItemPending int32 = iota
+	batchItemApplied
+	batchItemExpired
+)
+
+// expire answers the request as timed out unless the worker began to apply it, in which case false is returned
+func (b *batchItem) expire() bool {
+	if !atomic.CompareAndSwapInt32(&b.state, batchItemPending, batchItemExpired) {
+		return false
+	}
+	b.cancel()
+	return true
+}
+
+// applyBatchItem must be called by the worker before it changes a rate limit on behalf of the request.
+// Returns an error if the request is part of a batch which answered it as timed out.
+func applyBatchItem(ctx context.Context) error {
+	b, ok := ctx.Value(batchItemContextKey{}).(*batchItem)
+	if !ok || atomic.CompareAndSwapInt32(&b.state, batchItemPending, batchItemApplied) ||
+		atomic.LoadInt32(&b.state) == batchItemApplied {
+		return nil
+	}
+	return context.Canceled
+}
+
 // HealthCheck Returns the health of our instance.
 func (s *V1Instance) HealthCheck(ctx context.Context, r *HealthCheckReq) (retval *HealthCheckResp, reterr error) {
 	ctx = tracing.StartScope(ctx)
@@ -650,13 +1099,23 @@ func (s *V1Instance) getRateLimit(ctx context.Context, r *RateLimitReq) (retval
 		checkErrorCounter.WithLabelValues("Timeout").Add(1)
 	}
 
+	// The state of GLOBAL rate limits is already broadcast to every peer
+	if s.replication != nil && err == nil &&
+		!HasBehavior(r.Behavior, Behavior_GLOBAL) && !HasBehavior(r.Behavior, Behavior_PEEK) {
+		s.replication.QueueUpdate(r.HashKey())
+	}
+
 	return resp, err
 }
 
 // SetPeers is called by the implementor to indicate the pool of peers has changed
 func (s *V1Instance) SetPeers(peerInfo []PeerInfo) {
+	s.setPeersMutex.Lock()
+	defer s.setPeersMutex.Unlock()
+
 	localPicker := s.conf.LocalPicker.New()
 	regionPicker := s.conf.RegionPicker.New()
+	var replacedPeers []*PeerClient
 
 	for _, info := range peerInfo {
 		// Add peers that are not in our local DC to the RegionPicker
@@ -665,28 +1124,52 @@ func (s *V1Instance) SetPeers(peerInfo []PeerInfo) {
 			// If we don't have an existing PeerClient create a new one
 			if peer == nil {
 				peer = NewPeerClient(PeerConfig{
-					TLS:      s.conf.PeerTLS,
-					Behavior: s.conf.Behaviors,
-					Log:      s.log,
-					Info:     info,
+					TLS:            s.conf.PeerTLS,
+					AdminTLS:       s.conf.AdminPeerTLS,
+					Transport:      s.conf.PeerTransport,
+					Behavior:       s.conf.Behaviors,
+					Log:            s.log,
+					Info:           info,
+					TracerProvider: s.conf.TracerProvider,
 				})
 			}
 			regionPicker.Add(peer)
 			continue
 		}
+		// Peers which failed a health check are left out until they pass a health check
+		if !info.IsOwner && !s.peerHealth.IsHealthy(info.GRPCAddress) {
+			continue
+		}
 		// If we don't have an existing PeerClient create a new one
 		peer := s.conf.LocalPicker.GetByPeerInfo(info)
+		// The picker reads the weight from the PeerClient and the PeerClient dials the admin address, so
+		// replace the client if either changed
+		if peer != nil && (peer.Info().weight() != info.weight() || peer.Info().AdminAddress != info.AdminAddress) {
+			replacedPeers = append(replacedPeers, peer)
+			peer = nil
+		}
 		if peer == nil {
 			peer = NewPeerClient(PeerConfig{
-				TLS:      s.conf.PeerTLS,
-				Behavior: s.conf.Behaviors,
-				Log:      s.log,
-				Info:     info,
+				TLS:            s.conf.PeerTLS,
+				AdminTLS:       s.conf.AdminPeerTLS,
+				Transport:      s.conf.PeerTransport,
+				Behavior:       s.conf.Behaviors,
+				Log:            s.log,
+				Info:           info,
+				TracerProvider: s.conf.TracerProvider,
 			})
 		}
 		localPicker.Add(peer)
 	}
 
+	added, removed := diffPeers(s.conf.LocalPicker, localPicker)
+	if len(added) == 0 && len(removed) == 0 && len(replacedPeers) == 0 {
+		// Keep the current ring, the membership of our data center has not changed
+		localPicker = s.conf.LocalPicker
+	}
+	peerChangeCounter.WithLabelValues("added").Add(float64(len(added)))
+	peerChangeCounter.WithLabelValues("removed").Add(float64(len(removed)))
+
 	s.peerMutex.Lock()
 
 	// Replace our current pickers
@@ -694,15 +1177,20 @@ func (s *V1Instance) SetPeers(peerInfo []PeerInfo) {
 	oldRegionPicker := s.conf.RegionPicker
 	s.conf.LocalPicker = localPicker
 	s.conf.RegionPicker = regionPicker
+	s.peerInfo = peerInfo
 	s.peerMutex.Unlock()
 
-	s.log.WithField("peers", peerInfo).Debug("peers updated")
+	s.log.WithFields(logrus.Fields{
+		"peers":   peerInfo,
+		"added":   added,
+		"removed": removed,
+	}).Debug("peers updated")
 
 	// Shutdown any old peers we no longer need
 	ctx, cancel := ctxutil.WithTimeout(context.Background(), s.conf.Behaviors.BatchTimeout)
 	defer cancel()
 
-	var shutdownPeers []*PeerClient
+	shutdownPeers := replacedPeers
 	for _, peer := range oldLocalPicker.Peers() {
 		if peerInfo := s.conf.LocalPicker.GetByPeerInfo(peer.Info()); peerInfo == nil {
 			shutdownPeers = append(shutdownPeers, peer)
@@ -739,6 +1227,24 @@ func (s *V1Instance) SetPeers(peerInfo []PeerInfo) {
 	}
 }
 
+// diffPeers returns the addresses of the peers in `next` which are not in `prev` and
+// the addresses of the peers in `prev` which are not in `next`
+func diffPeers(prev, next PeerPicker) (added, removed []string) {
+	for _, peer := range next.Peers() {
+		if prev.GetByPeerInfo(peer.Info()) == nil {
+			added = append(added, peer.Info().GRPCAddress)
+		}
+	}
+	for _, peer := range prev.Peers() {
+		if next.GetByPeerInfo(peer.Info()) == nil {
+			removed = append(removed, peer.Info().GRPCAddress)
+		}
+	}
+	sort.Strings(added)
+	sort.Strings(removed)
+	return added, removed
+}
+
 // GetPeer returns a peer client for the hash key provided
 func (s *V1Instance) GetPeer(ctx context.Context, key string) (retval *PeerClient, reterr error) {
 	ctx = tracing.StartScope(ctx)
@@ -756,7 +1262,7 @@ func (s *V1Instance) GetPeer(ctx context.Context, key string) (retval *PeerClien
 	span.AddEvent("peerMutex.RLock()")
 	lockTimer.ObserveDuration()
 
-	peer, err := s.conf.LocalPicker.Get(key)
+	peer, err := s.conf.LocalPicker.Get(s.shardKey(key))
 	if err != nil {
 		return nil, errors.Wrap(err, "Error in conf.LocalPicker.Get")
 	}
@@ -764,12 +1270,71 @@ func (s *V1Instance) GetPeer(ctx context.Context, key string) (retval *PeerClien
 	return peer, nil
 }
 
+// shardKey returns the key used to pick the peer which owns the rate limit with the hash key provided
+func (s *V1Instance) shardKey(key string) string {
+	if s.conf.KeyShardFunc == nil {
+		return key
+	}
+	return s.conf.KeyShardFunc(key)
+}
+
+// GetPeerInfo returns the peers known to this instance and the owner of the requested rate limit
+func (s *V1Instance) GetPeerInfo(ctx context.Context, r *GetPeerInfoReq) (retval *GetPeerInfoResp, reterr error) {
+	ctx = tracing.StartScope(ctx)
+	defer func() {
+		tracing.EndScope(ctx, reterr)
+	}()
+
+	if (r.Name == "") != (r.UniqueKey == "") {
+		return nil, status.Error(codes.InvalidArgument, "must provide both 'name' and 'unique_key' or neither")
+	}
+
+	resp := &GetPeerInfoResp{}
+	for _, info := range s.getPeerInfo() {
+		resp.Peers = append(resp.Peers, s.peerDetails(info))
+	}
+
+	if r.Name != "" {
+		key := r.Name + "_" + r.UniqueKey
+		peer, err := s.GetPeer(ctx, key)
+		if err != nil {
+			return nil, status.Errorf(codes.Unavailable, "while looking up peer that owns rate limit '%s': %s", key, err)
+		}
+		resp.Owner = s.peerDetails(peer.Info())
+	}
+	return resp, nil
+}
+
+func (s *V1Instance) peerDetails(info PeerInfo) *PeerDetails {
+	s.peerMutex.RLock()
+	// Only peers in our data center are health checked and added to the local picker
+	healthy := s.conf.LocalPicker.GetByPeerInfo(info) != nil || info.DataCenter != s.conf.DataCenter
+	s.peerMutex.RUnlock()
+
+	return &PeerDetails{
+		GrpcAddress: info.GRPCAddress,
+		HttpAddress: info.HTTPAddress,
+		DataCenter:  info.DataCenter,
+		IsOwner:     info.IsOwner,
+		Weight:      int32(info.weight()),
+		Healthy:     healthy,
+	}
+}
+
 func (s *V1Instance) GetPeerList() []*PeerClient {
 	s.peerMutex.RLock()
 	defer s.peerMutex.RUnlock()
 	return s.conf.LocalPicker.Peers()
 }
 
+// getPeerInfo returns the peers most recently provided to SetPeers(), including those
+// which are not in the local picker because they failed a health check.
+func (s *V1Instance) getPeerInfo() []PeerInfo {
+	s.peerMutex.RLock()
+	defer s.peerMutex.RUnlock()
+	return s.peerInfo
+}
+
 func (s *V1Instance) GetRegionPickers() map[string]PeerPicker {
 	s.peerMutex.RLock()
 	defer s.peerMutex.RUnlock()
@@ -781,15 +1346,27 @@ func (s *V1Instance) Describe(ch chan<- *prometheus.Desc) {
 	ch <- s.global.asyncMetrics.Desc()
 	ch <- s.global.broadcastMetrics.Desc()
 	getRateLimitCounter.Describe(ch)
+	getRateLimitPeerCounter.Describe(ch)
 	funcTimeMetric.Describe(ch)
+	algorithmDurationMetric.Describe(ch)
 	asyncRequestRetriesCounter.Describe(ch)
 	queueLengthMetric.Describe(ch)
 	concurrentChecksMetric.Describe(ch)
 	checkErrorCounter.Describe(ch)
 	overLimitCounter.Describe(ch)
 	checkCounter.Describe(ch)
+	getRateLimitsDurationMetric.Describe(ch)
 	poolWorkerQueueLength.Describe(ch)
 	batchSendDurationMetric.Describe(ch)
+	storeFlushMetric.Describe(ch)
+	peerHealthMetric.Describe(ch)
+	peerChangeCounter.Describe(ch)
+	storeBreakerCounter.Describe(ch)
+	auditDroppedCounter.Describe(ch)
+	if s.metadataCounter != nil {
+		s.metadataCounter.counter.Describe(ch)
+	}
+	s.namespaceCounter.counter.Describe(ch)
 }
 
 // Collect fetches metrics from the server for use by prometheus
@@ -797,15 +1374,27 @@ func (s *V1Instance) Collect(ch chan<- prometheus.Metric) {
 	ch <- s.global.asyncMetrics
 	ch <- s.global.broadcastMetrics
 	getRateLimitCounter.Collect(ch)
+	getRateLimitPeerCounter.Collect(ch)
 	funcTimeMetric.Collect(ch)
+	algorithmDurationMetric.Collect(ch)
 	asyncRequestRetriesCounter.Collect(ch)
 	queueLengthMetric.Collect(ch)
 	concurrentChecksMetric.Collect(ch)
 	checkErrorCounter.Collect(ch)
 	overLimitCounter.Collect(ch)
 	checkCounter.Collect(ch)
+	getRateLimitsDurationMetric.Collect(ch)
 	poolWorkerQueueLength.Collect(ch)
 	batchSendDurationMetric.Collect(ch)
+	storeFlushMetric.Collect(ch)
+	peerHealthMetric.Collect(ch)
+	peerChangeCounter.Collect(ch)
+	storeBreakerCounter.Collect(ch)
+	auditDroppedCounter.Collect(ch)
+	if s.metadataCounter != nil {
+		s.metadataCounter.counter.Collect(ch)
+	}
+	s.namespaceCounter.counter.Collect(ch)
 }
 
 // HasBehavior returns true if the provided behavior is set
@@ -25,6 +25,7 @@ import (
 	_ "google.golang.org/genproto/googleapis/api/annotations"
 	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
 	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	timestamppb "google.golang.org/protobuf/types/known/timestamppb"
 	reflect "reflect"
 	sync "sync"
 )
@@ -43,6 +44,9 @@ const (
 	Algorithm_TOKEN_BUCKET Algorithm = 0
 	// Leaky bucket algorithm https://en.wikipedia.org/wiki/Leaky_bucket
 	Algorithm_LEAKY_BUCKET Algorithm = 1
+	// Leaks like LEAKY_BUCKET during the window and refills to the burst at the end of each
+	// window like TOKEN_BUCKET
+	Algorithm_HYBRID Algorithm = 2
 )
 
 // Enum value maps for Algorithm.
@@ -50,10 +54,12 @@ var (
 	Algorithm_name = map[int32]string{
 		0: "TOKEN_BUCKET",
 		1: "LEAKY_BUCKET",
+		2: "HYBRID",
 	}
 	Algorithm_value = map[string]int32{
 		"TOKEN_BUCKET": 0,
 		"LEAKY_BUCKET": 1,
+		"HYBRID":       2,
 	}
 )
 
@@ -84,6 +90,63 @@ func (Algorithm) EnumDescriptor() ([]byte, []int) {
 	return file_gubernator_proto_rawDescGZIP(), []int{0}
 }
 
+// The curve along which a LEAKY_BUCKET replenishes, where `t` is the milliseconds since the bucket
+// was last full (no hits remaining), `P` the milliseconds in which `limit` hits leak, `L` the limit and
+// `R` the hits remaining. A bucket with `R` hits remaining is taken to be at the point of the curve at
+// which `R` hits have leaked, such that the leak is the same however often the rate limit is requested.
+// Every profile replenishes `L` hits once `t = P`, a `burst` beyond the limit replenishes at the
+// LINEAR rate.
+type LeakProfile int32
+
+const (
+	// `R = L * t / P`, IE: at a constant rate of one hit every `P / L` milliseconds. A bucket with `R`
+	// hits remaining has `L` remaining after `(L - R) * P / L` milliseconds.
+	LeakProfile_LINEAR LeakProfile = 0
+	// `R = L * (t / P)^2`, which starts slower than LINEAR and accelerates, such that a client which keeps
+	// the bucket full is replenished slowly while an idle client recovers by the end of `P`. A bucket with
+	// `R` hits remaining has `L` remaining after `P * (1 - sqrt(R / L))` milliseconds.
+	LeakProfile_QUADRATIC LeakProfile = 1
+)
+
+// Enum value maps for LeakProfile.
+var (
+	LeakProfile_name = map[int32]string{
+		0: "LINEAR",
+		1: "QUADRATIC",
+	}
+	LeakProfile_value = map[string]int32{
+		"LINEAR":    0,
+		"QUADRATIC": 1,
+	}
+)
+
+func (x LeakProfile) Enum() *LeakProfile {
+	p := new(LeakProfile)
+	*p = x
+	return p
+}
+
+func (x LeakProfile) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (LeakProfile) Descriptor() protoreflect.EnumDescriptor {
+	return file_gubernator_proto_enumTypes[1].Descriptor()
+}
+
+func (LeakProfile) Type() protoreflect.EnumType {
+	return &file_gubernator_proto_enumTypes[1]
+}
+
+func (x LeakProfile) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use LeakProfile.Descriptor instead.
+func (LeakProfile) EnumDescriptor() ([]byte, []int) {
+	return file_gubernator_proto_rawDescGZIP(), []int{1}
+}
+
 // A set of int32 flags used to control the behavior of a rate limit in gubernator
 type Behavior int32
 
@@ -125,6 +188,9 @@ const (
 	//   3 = Weeks
 	//   4 = Months
 	//   5 = Years
+	//   6 = Quarters
+	//
+	// Weeks begin on Monday, Quarters begin on the first day of January, April, July and October.
 	//
 	// Examples when using `Behavior = DURATION_IS_GREGORIAN`
 	//
@@ -146,17 +212,97 @@ const (
 	// 'member-list' peer discovery. Also requires GUBER_DATA_CENTER to be set to different values on at
 	// least 2 instances of Gubernator.
 	Behavior_MULTI_REGION Behavior = 16
+	// By default a request which asks for more hits than are remaining is rejected as OVER_LIMIT without
+	// changing the remaining count, allowing the client to retry with fewer hits within the same duration.
+	// When `DRAIN_OVER_LIMIT` is set the rejected hits are still counted; the remaining count is drained to
+	// zero and every further over limit hit restarts the duration, such that the rate limit stays OVER_LIMIT
+	// until the client stops sending hits for the full duration.
+	Behavior_DRAIN_OVER_LIMIT Behavior = 32
+	// Imposes a cooldown on a `TOKEN_BUCKET` rate limit once it goes OVER_LIMIT. Every hit is rejected
+	// as OVER_LIMIT until `RateLimitReq.penalty` milliseconds have passed since the rate limit went over
+	// the limit, even if the duration of the rate limit would have replenished the remaining sooner. The
+	// `ResetTime` of responses during the cooldown is the time the cooldown ends.
+	Behavior_PENALTY_COOLDOWN Behavior = 64
+	// Computes the `Status` and `Remaining` of the rate limit as if the `Hits` were applied, without
+	// changing the rate limit. Use this to ask if a request would be allowed when the hits are to be
+	// applied by a later request. The rate limit is not created if it does not exist.
+	Behavior_PEEK Behavior = 128
+	// Delays the reset of a `TOKEN_BUCKET` rate limit by up to `RateLimitReq.reset_jitter` milliseconds, such
+	// that rate limits of the same duration, IE: those with `DURATION_IS_GREGORIAN`, do not all reset at the same
+	// instant and cause clients to retry in unison. The delay is derived from `name` and `unique_key`, such that
+	// every request for a rate limit agrees on the reset time.
+	Behavior_RESET_JITTER Behavior = 256
+	// Includes the effective configuration of the rate limit held by the owning peer in
+	// `RateLimitResp.config`, such that clients relying on namespace defaults or which
+	// changed the configuration of a rate limit can learn the configuration actually applied.
+	Behavior_RETURN_CONFIG Behavior = 512
+	// Skips the `Store` configured on the owning peer, such that the rate limit only lives in the cache.
+	// Use this for short-lived rate limits which are not worth persisting. The rate limit is still subject
+	// to cache eviction and expiration, and is lost when the owning peer restarts.
+	Behavior_NO_STORE Behavior = 1024
+	// Includes the number of hits accepted by the rate limit during the current window in
+	// `RateLimitResp.total_hits`, such that clients can compare demand with the limit. The count
+	// restarts when a `TOKEN_BUCKET` is renewed or refilled, and when a `LEAKY_BUCKET` has leaked
+	// all of its hits.
+	Behavior_RETURN_TOTAL_HITS Behavior = 2048
+	// Refills a `TOKEN_BUCKET` continuously rather than all at once when the duration expires. One token
+	// is added for each `duration / limit` milliseconds which pass, up to the limit or `RateLimitReq.burst`,
+	// such that a bucket which was emptied is full again after `duration`. The `ResetTime` is the time the
+	// bucket will be full. Ignored with `DURATION_IS_GREGORIAN`.
+	Behavior_TOKEN_DRIP Behavior = 4096
+	// When a `LEAKY_BUCKET` request asks for more hits than are remaining, consumes the hits which
+	// remain rather than rejecting the request outright. The bucket is drained to zero, the response
+	// is OVER_LIMIT and `RateLimitResp.over_hits` reports the hits which could not be consumed. By
+	// default such a request is rejected without consuming any hits.
+	Behavior_PARTIAL_CONSUME Behavior = 8192
+	// Moves the reset of a `TOKEN_BUCKET` rate limit `RateLimitReq.penalty_step` milliseconds further away
+	// on each hit rejected as OVER_LIMIT, such that a client which keeps sending hits while over the limit
+	// sees an ever receding `ResetTime`. Unlike `PENALTY_COOLDOWN` the penalty grows with each rejected
+	// hit. Ignored with `DURATION_IS_GREGORIAN`.
+	Behavior_SLIDING_PENALTY Behavior = 16384
+	// When served by the HTTP gateway and the rate limit is OVER_LIMIT, the HTTP response has the status
+	// `429 Too Many Requests` and a `Retry-After` header with the seconds until the rate limit resets,
+	// such that generic HTTP clients back off without parsing the JSON body. The body is unchanged.
+	Behavior_RETRY_AFTER Behavior = 32768
+	// The request which creates the rate limit is never OVER_LIMIT, even if it asks for more hits than the
+	// limit allows. The hits drain the new rate limit to zero remaining and later requests are enforced as
+	// usual. Intended as a one time grace for new keys, IE: a user signing up. Applies whenever the rate
+	// limit is created, including after the previous rate limit for the key expired.
+	Behavior_FIRST_HIT_FREE Behavior = 65536
+	// An OVER_LIMIT response provides `RateLimitResp.suggested_retry_after`, the milliseconds the client
+	// should wait before retrying. The suggestion is the time until the rate limit resets plus a delay
+	// which doubles with each consecutive rejection of the rate limit, such that clients which keep
+	// retrying back off further. The count of rejections restarts once a request is UNDER_LIMIT.
+	Behavior_BACKOFF_HINT Behavior = 131072
+	// A rate limit missing from the cache which the store could not be read for is created anew rather than
+	// failing the request, even when `Config.StoreErrorPolicy` is `StoreErrorFail`. Prefers availability over
+	// accuracy for this request; the remaining of a rate limit the store could not provide is reset.
+	Behavior_IGNORE_STORE_ERRORS Behavior = 262144
 )
 
 // Enum value maps for Behavior.
 var (
 	Behavior_name = map[int32]string{
-		0:  "BATCHING",
-		1:  "NO_BATCHING",
-		2:  "GLOBAL",
-		4:  "DURATION_IS_GREGORIAN",
-		8:  "RESET_REMAINING",
-		16: "MULTI_REGION",
+		0:      "BATCHING",
+		1:      "NO_BATCHING",
+		2:      "GLOBAL",
+		4:      "DURATION_IS_GREGORIAN",
+		8:      "RESET_REMAINING",
+		16:     "MULTI_REGION",
+		32:     "DRAIN_OVER_LIMIT",
+		64:     "PENALTY_COOLDOWN",
+		128:    "PEEK",
+		256:    "RESET_JITTER",
+		512:    "RETURN_CONFIG",
+		1024:   "NO_STORE",
+		2048:   "RETURN_TOTAL_HITS",
+		4096:   "TOKEN_DRIP",
+		8192:   "PARTIAL_CONSUME",
+		16384:  "SLIDING_PENALTY",
+		32768:  "RETRY_AFTER",
+		65536:  "FIRST_HIT_FREE",
+		131072: "BACKOFF_HINT",
+		262144: "IGNORE_STORE_ERRORS",
 	}
 	Behavior_value = map[string]int32{
 		"BATCHING":              0,
@@ -165,6 +311,20 @@ var (
 		"DURATION_IS_GREGORIAN": 4,
 		"RESET_REMAINING":       8,
 		"MULTI_REGION":          16,
+		"DRAIN_OVER_LIMIT":      32,
+		"PENALTY_COOLDOWN":      64,
+		"PEEK":                  128,
+		"RESET_JITTER":          256,
+		"RETURN_CONFIG":         512,
+		"NO_STORE":              1024,
+		"RETURN_TOTAL_HITS":     2048,
+		"TOKEN_DRIP":            4096,
+		"PARTIAL_CONSUME":       8192,
+		"SLIDING_PENALTY":       16384,
+		"RETRY_AFTER":           32768,
+		"FIRST_HIT_FREE":        65536,
+		"BACKOFF_HINT":          131072,
+		"IGNORE_STORE_ERRORS":   262144,
 	}
 )
 
@@ -179,11 +339,11 @@ func (x Behavior) String() string {
 }
 
 func (Behavior) Descriptor() protoreflect.EnumDescriptor {
-	return file_gubernator_proto_enumTypes[1].Descriptor()
+	return file_gubernator_proto_enumTypes[2].Descriptor()
 }
 
 func (Behavior) Type() protoreflect.EnumType {
-	return &file_gubernator_proto_enumTypes[1]
+	return &file_gubernator_proto_enumTypes[2]
 }
 
 func (x Behavior) Number() protoreflect.EnumNumber {
@@ -192,7 +352,7 @@ func (x Behavior) Number() protoreflect.EnumNumber {
 
 // Deprecated: Use Behavior.Descriptor instead.
 func (Behavior) EnumDescriptor() ([]byte, []int) {
-	return file_gubernator_proto_rawDescGZIP(), []int{1}
+	return file_gubernator_proto_rawDescGZIP(), []int{2}
 }
 
 type Status int32
@@ -225,11 +385,11 @@ func (x Status) String() string {
 }
 
 func (Status) Descriptor() protoreflect.EnumDescriptor {
-	return file_gubernator_proto_enumTypes[2].Descriptor()
+	return file_gubernator_proto_enumTypes[3].Descriptor()
 }
 
 func (Status) Type() protoreflect.EnumType {
-	return &file_gubernator_proto_enumTypes[2]
+	return &file_gubernator_proto_enumTypes[3]
 }
 
 func (x Status) Number() protoreflect.EnumNumber {
@@ -238,7 +398,7 @@ func (x Status) Number() protoreflect.EnumNumber {
 
 // Deprecated: Use Status.Descriptor instead.
 func (Status) EnumDescriptor() ([]byte, []int) {
-	return file_gubernator_proto_rawDescGZIP(), []int{2}
+	return file_gubernator_proto_rawDescGZIP(), []int{3}
 }
 
 // Must specify at least one Request
@@ -359,10 +519,62 @@ type RateLimitReq struct {
 	// The algorithm used to calculate the rate limit. The algorithm may change on
 	// subsequent requests, when this occurs any previous rate limit hit counts are reset.
 	Algorithm Algorithm `protobuf:"varint,6,opt,name=algorithm,proto3,enum=pb.gubernator.Algorithm" json:"algorithm,omitempty"`
-	// Behavior is a set of int32 flags that control the behavior of the rate limit in gubernator
+	// Behavior is a set of int32 flags that control the behavior of the rate limit in gubernator. The
+	// HTTP gateway also accepts a list of behavior names, IE: `["GLOBAL", "NO_BATCHING"]`
 	Behavior Behavior `protobuf:"varint,7,opt,name=behavior,proto3,enum=pb.gubernator.Behavior" json:"behavior,omitempty"`
-	// Maximum burst size that the limit can accept.
+	// Maximum burst size that the limit can accept. A TOKEN_BUCKET with a burst greater than the limit
+	// accumulates `limit` hits each duration up to `burst`. Defaults to the limit.
 	Burst int64 `protobuf:"varint,8,opt,name=burst,proto3" json:"burst,omitempty"`
+	// The duration in milliseconds of the cooldown imposed when `Behavior = PENALTY_COOLDOWN`
+	// and the rate limit goes over the limit.
+	Penalty int64 `protobuf:"varint,9,opt,name=penalty,proto3" json:"penalty,omitempty"`
+	// The amount the request consumes from a `TOKEN_BUCKET` rate limit. When non-zero `cost` is applied
+	// instead of `hits`, allowing operations to consume different or fractional amounts of the limit.
+	// The `remaining` reported in responses is rounded down to a whole number.
+	Cost float64 `protobuf:"fixed64,10,opt,name=cost,proto3" json:"cost,omitempty"`
+	// The window in milliseconds over which reset times are spread when `Behavior = RESET_JITTER`.
+	// Defaults to 1 second.
+	ResetJitter int64 `protobuf:"varint,11,opt,name=reset_jitter,json=resetJitter,proto3" json:"reset_jitter,omitempty"`
+	// (Optional) Identifies the request such that a retry is not counted twice. The owning peer
+	// remembers the response to a request with an `idempotency_key` for a short time, during which
+	// a request for the same rate limit with the same key returns the remembered response instead
+	// of applying the hits again. Not supported with `Behavior = GLOBAL`.
+	IdempotencyKey string `protobuf:"bytes,12,opt,name=idempotency_key,json=idempotencyKey,proto3" json:"idempotency_key,omitempty"`
+	// (Optional) Opaque context such as a request id or route, which is echoed in the `metadata` of the
+	// response and included in the server logs. It does not affect the rate limit and is not part of
+	// the key of the rate limit. Server provided keys such as `owner` take precedence over those echoed.
+	Metadata map[string]string `protobuf:"bytes,13,rep,name=metadata,proto3" json:"metadata,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"bytes,2,opt,name=value,proto3"`
+	// The milliseconds the reset is moved on each rejected hit when `Behavior = SLIDING_PENALTY`.
+	// Defaults to 1 second.
+	PenaltyStep int64 `protobuf:"varint,14,opt,name=penalty_step,json=penaltyStep,proto3" json:"penalty_step,omitempty"`
+	// (Optional) The parent rate limits of a cascading request, IE: the per org and per account limits
+	// of a per user request. The hits are applied to this rate limit and each of the parents only if
+	// every one of them would be UNDER_LIMIT, see `AtomicGetRateLimits`. The `hits` and `cost` of the
+	// parents are taken from this request. Parents cannot have parents of their own.
+	Parents []*RateLimitReq `protobuf:"bytes,15,rep,name=parents,proto3" json:"parents,omitempty"`
+	// (Optional) The IANA time zone, IE: 'America/New_York', in which the intervals of a
+	// `DURATION_IS_GREGORIAN` rate limit and the time of day of a `schedule` are computed, such that a
+	// daily limit resets at local midnight.
+	// Defaults to the `GregorianLocation` of the server, else the local time zone of the owning peer.
+	Timezone string `protobuf:"bytes,16,opt,name=timezone,proto3" json:"timezone,omitempty"`
+	// (Optional) The importance of keeping the rate limit in the cache of the owning peer. When the cache
+	// is full, rate limits with a lower priority are evicted before those with a higher priority, IE: give
+	// a long window billing limit a higher priority than a per second API limit. Defaults to 0.
+	Priority int32 `protobuf:"varint,17,opt,name=priority,proto3" json:"priority,omitempty"`
+	// (Optional) The curve along which a LEAKY_BUCKET replenishes, see LeakProfile. Ignored by the
+	// other algorithms. Defaults to LINEAR.
+	LeakProfile LeakProfile `protobuf:"varint,18,opt,name=leak_profile,json=leakProfile,proto3,enum=pb.gubernator.LeakProfile" json:"leak_profile,omitempty"`
+	// (Optional) The limits of the rate limit by time of day, in the `timezone` of the request. The `limit` of
+	// the first window which contains the current time of day applies, else `limit` of the request. When the
+	// active window changes, the remaining of the rate limit is scaled in proportion to the new limit rather
+	// than being reset, IE: a client which used half of the business hours limit has half of the off-hours
+	// limit remaining. The cached rate limit remains the same.
+	Schedule []*ScheduleWindow `protobuf:"bytes,19,rep,name=schedule,proto3" json:"schedule,omitempty"`
+	// (Optional) The names of the fields which the request sets explicitly, even to their zero value, such
+	// that the namespace defaults of the server do not apply to them, IE: `algorithm` to use a TOKEN_BUCKET
+	// or `behavior` to use BATCHING for a name which defaults to LEAKY_BUCKET or GLOBAL. Applies to the
+	// `limit`, `duration`, `algorithm`, `behavior`, `burst`, `penalty`, `reset_jitter` and `priority`.
+	ExplicitFields []string `protobuf:"bytes,20,rep,name=explicit_fields,json=explicitFields,proto3" json:"explicit_fields,omitempty"`
 }
 
 func (x *RateLimitReq) Reset() {
@@ -453,6 +665,158 @@ func (x *RateLimitReq) GetBurst() int64 {
 	return 0
 }
 
+func (x *RateLimitReq) GetPenalty() int64 {
+	if x != nil {
+		return x.Penalty
+	}
+	return 0
+}
+
+func (x *RateLimitReq) GetCost() float64 {
+	if x != nil {
+		return x.Cost
+	}
+	return 0
+}
+
+func (x *RateLimitReq) GetResetJitter() int64 {
+	if x != nil {
+		return x.ResetJitter
+	}
+	return 0
+}
+
+func (x *RateLimitReq) GetIdempotencyKey() string {
+	if x != nil {
+		return x.IdempotencyKey
+	}
+	return ""
+}
+
+func (x *RateLimitReq) GetMetadata() map[string]string {
+	if x != nil {
+		return x.Metadata
+	}
+	return nil
+}
+
+func (x *RateLimitReq) GetPenaltyStep() int64 {
+	if x != nil {
+		return x.PenaltyStep
+	}
+	return 0
+}
+
+func (x *RateLimitReq) GetParents() []*RateLimitReq {
+	if x != nil {
+		return x.Parents
+	}
+	return nil
+}
+
+func (x *RateLimitReq) GetTimezone() string {
+	if x != nil {
+		return x.Timezone
+	}
+	return ""
+}
+
+func (x *RateLimitReq) GetPriority() int32 {
+	if x != nil {
+		return x.Priority
+	}
+	return 0
+}
+
+func (x *RateLimitReq) GetLeakProfile() LeakProfile {
+	if x != nil {
+		return x.LeakProfile
+	}
+	return LeakProfile_LINEAR
+}
+
+func (x *RateLimitReq) GetSchedule() []*ScheduleWindow {
+	if x != nil {
+		return x.Schedule
+	}
+	return nil
+}
+
+func (x *RateLimitReq) GetExplicitFields() []string {
+	if x != nil {
+		return x.ExplicitFields
+	}
+	return nil
+}
+
+// A time of day range during which a rate limit with a `RateLimitReq.schedule` has a limit of its own
+type ScheduleWindow struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// The minute of the day the window begins, from 0 (midnight) to 1439
+	Start int32 `protobuf:"varint,1,opt,name=start,proto3" json:"start,omitempty"`
+	// The minute of the day the window ends, exclusive, from 1 to 1440. A window which ends before it begins
+	// spans midnight, IE: from 1080 (18:00) to 540 (09:00)
+	End int32 `protobuf:"varint,2,opt,name=end,proto3" json:"end,omitempty"`
+	// The limit while the window is active
+	Limit int64 `protobuf:"varint,3,opt,name=limit,proto3" json:"limit,omitempty"`
+}
+
+func (x *ScheduleWindow) Reset() {
+	*x = ScheduleWindow{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_gubernator_proto_msgTypes[3]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *ScheduleWindow) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ScheduleWindow) ProtoMessage() {}
+
+func (x *ScheduleWindow) ProtoReflect() protoreflect.Message {
+	mi := &file_gubernator_proto_msgTypes[3]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ScheduleWindow.ProtoReflect.Descriptor instead.
+func (*ScheduleWindow) Descriptor() ([]byte, []int) {
+	return file_gubernator_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *ScheduleWindow) GetStart() int32 {
+	if x != nil {
+		return x.Start
+	}
+	return 0
+}
+
+func (x *ScheduleWindow) GetEnd() int32 {
+	if x != nil {
+		return x.End
+	}
+	return 0
+}
+
+func (x *ScheduleWindow) GetLimit() int64 {
+	if x != nil {
+		return x.Limit
+	}
+	return 0
+}
+
 type RateLimitResp struct {
 	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
@@ -470,12 +834,43 @@ type RateLimitResp struct {
 	Error string `protobuf:"bytes,5,opt,name=error,proto3" json:"error,omitempty"`
 	// This is additional metadata that a client might find useful. (IE: Additional headers, corrdinator ownership, etc..)
 	Metadata map[string]string `protobuf:"bytes,6,rep,name=metadata,proto3" json:"metadata,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"bytes,2,opt,name=value,proto3"`
+	// The algorithm used to calculate the rate limit. Clients can compare this to the algorithm
+	// requested to detect when a rate limit has not been migrated to the requested algorithm.
+	Algorithm Algorithm `protobuf:"varint,7,opt,name=algorithm,proto3,enum=pb.gubernator.Algorithm" json:"algorithm,omitempty"`
+	// The number of milliseconds until `reset_time`, as computed by the server clock. Clients should
+	// prefer this over `reset_time` when the client clock may not agree with the server, IE: when
+	// computing a `Retry-After` header. When the request is forwarded to the owning peer, the owner
+	// provides `reset_after` and the `reset_time` is translated to the clock of the peer which received
+	// the request should the clocks of the peers disagree, see `Config.ClockSkewTolerance`.
+	ResetAfter int64 `protobuf:"varint,8,opt,name=reset_after,json=resetAfter,proto3" json:"reset_after,omitempty"`
+	// The effective configuration of the rate limit, only provided when `Behavior_RETURN_CONFIG` is set
+	// and the rate limit exists once the request was applied.
+	Config *RateLimitConfig `protobuf:"bytes,9,opt,name=config,proto3" json:"config,omitempty"`
+	// The `reset_time` as a timestamp, formatted as RFC3339 in JSON. Only provided in responses from
+	// the HTTP gateway.
+	ResetTimestamp *timestamppb.Timestamp `protobuf:"bytes,10,opt,name=reset_timestamp,json=resetTimestamp,proto3" json:"reset_timestamp,omitempty"`
+	// The number of hits accepted by the rate limit during the current window, only provided when
+	// `Behavior_RETURN_TOTAL_HITS` is set.
+	TotalHits int64 `protobuf:"varint,11,opt,name=total_hits,json=totalHits,proto3" json:"total_hits,omitempty"`
+	// The hits of the request which exceeded the remaining and were not consumed, only provided when
+	// `Behavior_PARTIAL_CONSUME` is set and the request is OVER_LIMIT.
+	OverHits int64 `protobuf:"varint,12,opt,name=over_hits,json=overHits,proto3" json:"over_hits,omitempty"`
+	// The responses of the `RateLimitReq.parents` of a cascading request, in the same order. The
+	// `status` of the response is OVER_LIMIT if this rate limit or any of the parents is OVER_LIMIT.
+	Parents []*RateLimitResp `protobuf:"bytes,13,rep,name=parents,proto3" json:"parents,omitempty"`
+	// The GRPC address of the peer which owns the rate limit, as chosen by the picker of the peer which
+	// received the request. For `Behavior_GLOBAL` rate limits answered from the local copy, the peer
+	// which will receive the hits.
+	Owner string `protobuf:"bytes,14,opt,name=owner,proto3" json:"owner,omitempty"`
+	// The milliseconds the client should wait before retrying an OVER_LIMIT request with
+	// `Behavior_BACKOFF_HINT`, including the escalation for consecutive rejections. Zero otherwise.
+	SuggestedRetryAfter int64 `protobuf:"varint,15,opt,name=suggested_retry_after,json=suggestedRetryAfter,proto3" json:"suggested_retry_after,omitempty"`
 }
 
 func (x *RateLimitResp) Reset() {
 	*x = RateLimitResp{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_gubernator_proto_msgTypes[3]
+		mi := &file_gubernator_proto_msgTypes[4]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
@@ -488,7 +883,7 @@ func (x *RateLimitResp) String() string {
 func (*RateLimitResp) ProtoMessage() {}
 
 func (x *RateLimitResp) ProtoReflect() protoreflect.Message {
-	mi := &file_gubernator_proto_msgTypes[3]
+	mi := &file_gubernator_proto_msgTypes[4]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -501,7 +896,7 @@ func (x *RateLimitResp) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use RateLimitResp.ProtoReflect.Descriptor instead.
 func (*RateLimitResp) Descriptor() ([]byte, []int) {
-	return file_gubernator_proto_rawDescGZIP(), []int{3}
+	return file_gubernator_proto_rawDescGZIP(), []int{4}
 }
 
 func (x *RateLimitResp) GetStatus() Status {
@@ -546,6 +941,141 @@ func (x *RateLimitResp) GetMetadata() map[string]string {
 	return nil
 }
 
+func (x *RateLimitResp) GetAlgorithm() Algorithm {
+	if x != nil {
+		return x.Algorithm
+	}
+	return Algorithm_TOKEN_BUCKET
+}
+
+func (x *RateLimitResp) GetResetAfter() int64 {
+	if x != nil {
+		return x.ResetAfter
+	}
+	return 0
+}
+
+func (x *RateLimitResp) GetConfig() *RateLimitConfig {
+	if x != nil {
+		return x.Config
+	}
+	return nil
+}
+
+func (x *RateLimitResp) GetResetTimestamp() *timestamppb.Timestamp {
+	if x != nil {
+		return x.ResetTimestamp
+	}
+	return nil
+}
+
+func (x *RateLimitResp) GetTotalHits() int64 {
+	if x != nil {
+		return x.TotalHits
+	}
+	return 0
+}
+
+func (x *RateLimitResp) GetOverHits() int64 {
+	if x != nil {
+		return x.OverHits
+	}
+	return 0
+}
+
+func (x *RateLimitResp) GetParents() []*RateLimitResp {
+	if x != nil {
+		return x.Parents
+	}
+	return nil
+}
+
+func (x *RateLimitResp) GetOwner() string {
+	if x != nil {
+		return x.Owner
+	}
+	return ""
+}
+
+func (x *RateLimitResp) GetSuggestedRetryAfter() int64 {
+	if x != nil {
+		return x.SuggestedRetryAfter
+	}
+	return 0
+}
+
+// The configuration of a rate limit as held by the peer which owns the rate limit
+type RateLimitConfig struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Algorithm Algorithm `protobuf:"varint,1,opt,name=algorithm,proto3,enum=pb.gubernator.Algorithm" json:"algorithm,omitempty"`
+	Limit     int64     `protobuf:"varint,2,opt,name=limit,proto3" json:"limit,omitempty"`
+	Duration  int64     `protobuf:"varint,3,opt,name=duration,proto3" json:"duration,omitempty"`
+	Burst     int64     `protobuf:"varint,4,opt,name=burst,proto3" json:"burst,omitempty"`
+}
+
+func (x *RateLimitConfig) Reset() {
+	*x = RateLimitConfig{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_gubernator_proto_msgTypes[5]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *RateLimitConfig) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RateLimitConfig) ProtoMessage() {}
+
+func (x *RateLimitConfig) ProtoReflect() protoreflect.Message {
+	mi := &file_gubernator_proto_msgTypes[5]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RateLimitConfig.ProtoReflect.Descriptor instead.
+func (*RateLimitConfig) Descriptor() ([]byte, []int) {
+	return file_gubernator_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *RateLimitConfig) GetAlgorithm() Algorithm {
+	if x != nil {
+		return x.Algorithm
+	}
+	return Algorithm_TOKEN_BUCKET
+}
+
+func (x *RateLimitConfig) GetLimit() int64 {
+	if x != nil {
+		return x.Limit
+	}
+	return 0
+}
+
+func (x *RateLimitConfig) GetDuration() int64 {
+	if x != nil {
+		return x.Duration
+	}
+	return 0
+}
+
+func (x *RateLimitConfig) GetBurst() int64 {
+	if x != nil {
+		return x.Burst
+	}
+	return 0
+}
+
 type HealthCheckReq struct {
 	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
@@ -555,7 +1085,7 @@ type HealthCheckReq struct {
 func (x *HealthCheckReq) Reset() {
 	*x = HealthCheckReq{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_gubernator_proto_msgTypes[4]
+		mi := &file_gubernator_proto_msgTypes[6]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
@@ -568,7 +1098,7 @@ func (x *HealthCheckReq) String() string {
 func (*HealthCheckReq) ProtoMessage() {}
 
 func (x *HealthCheckReq) ProtoReflect() protoreflect.Message {
-	mi := &file_gubernator_proto_msgTypes[4]
+	mi := &file_gubernator_proto_msgTypes[6]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -581,7 +1111,7 @@ func (x *HealthCheckReq) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use HealthCheckReq.ProtoReflect.Descriptor instead.
 func (*HealthCheckReq) Descriptor() ([]byte, []int) {
-	return file_gubernator_proto_rawDescGZIP(), []int{4}
+	return file_gubernator_proto_rawDescGZIP(), []int{6}
 }
 
 type HealthCheckResp struct {
@@ -600,7 +1130,7 @@ type HealthCheckResp struct {
 func (x *HealthCheckResp) Reset() {
 	*x = HealthCheckResp{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_gubernator_proto_msgTypes[5]
+		mi := &file_gubernator_proto_msgTypes[7]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
@@ -613,7 +1143,7 @@ func (x *HealthCheckResp) String() string {
 func (*HealthCheckResp) ProtoMessage() {}
 
 func (x *HealthCheckResp) ProtoReflect() protoreflect.Message {
-	mi := &file_gubernator_proto_msgTypes[5]
+	mi := &file_gubernator_proto_msgTypes[7]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -626,7 +1156,7 @@ func (x *HealthCheckResp) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use HealthCheckResp.ProtoReflect.Descriptor instead.
 func (*HealthCheckResp) Descriptor() ([]byte, []int) {
-	return file_gubernator_proto_rawDescGZIP(), []int{5}
+	return file_gubernator_proto_rawDescGZIP(), []int{7}
 }
 
 func (x *HealthCheckResp) GetStatus() string {
@@ -650,96 +1180,437 @@ func (x *HealthCheckResp) GetPeerCount() int32 {
 	return 0
 }
 
+type GetPeerInfoReq struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// (Optional) The name of the rate limit to find the owner of
+	Name string `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	// (Optional) The unique key of the rate limit to find the owner of
+	UniqueKey string `protobuf:"bytes,2,opt,name=unique_key,json=uniqueKey,proto3" json:"unique_key,omitempty"`
+}
+
+func (x *GetPeerInfoReq) Reset() {
+	*x = GetPeerInfoReq{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_gubernator_proto_msgTypes[8]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *GetPeerInfoReq) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetPeerInfoReq) ProtoMessage() {}
+
+func (x *GetPeerInfoReq) ProtoReflect() protoreflect.Message {
+	mi := &file_gubernator_proto_msgTypes[8]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetPeerInfoReq.ProtoReflect.Descriptor instead.
+func (*GetPeerInfoReq) Descriptor() ([]byte, []int) {
+	return file_gubernator_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *GetPeerInfoReq) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *GetPeerInfoReq) GetUniqueKey() string {
+	if x != nil {
+		return x.UniqueKey
+	}
+	return ""
+}
+
+type GetPeerInfoResp struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// The peers most recently provided to this instance by peer discovery
+	Peers []*PeerDetails `protobuf:"bytes,1,rep,name=peers,proto3" json:"peers,omitempty"`
+	// The peer which owns the requested rate limit. Only set if name and unique_key were provided
+	Owner *PeerDetails `protobuf:"bytes,2,opt,name=owner,proto3" json:"owner,omitempty"`
+}
+
+func (x *GetPeerInfoResp) Reset() {
+	*x = GetPeerInfoResp{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_gubernator_proto_msgTypes[9]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *GetPeerInfoResp) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetPeerInfoResp) ProtoMessage() {}
+
+func (x *GetPeerInfoResp) ProtoReflect() protoreflect.Message {
+	mi := &file_gubernator_proto_msgTypes[9]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetPeerInfoResp.ProtoReflect.Descriptor instead.
+func (*GetPeerInfoResp) Descriptor() ([]byte, []int) {
+	return file_gubernator_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *GetPeerInfoResp) GetPeers() []*PeerDetails {
+	if x != nil {
+		return x.Peers
+	}
+	return nil
+}
+
+func (x *GetPeerInfoResp) GetOwner() *PeerDetails {
+	if x != nil {
+		return x.Owner
+	}
+	return nil
+}
+
+type PeerDetails struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	GrpcAddress string `protobuf:"bytes,1,opt,name=grpc_address,json=grpcAddress,proto3" json:"grpc_address,omitempty"`
+	HttpAddress string `protobuf:"bytes,2,opt,name=http_address,json=httpAddress,proto3" json:"http_address,omitempty"`
+	DataCenter  string `protobuf:"bytes,3,opt,name=data_center,json=dataCenter,proto3" json:"data_center,omitempty"`
+	// True if the peer is the instance which handled the request
+	IsOwner bool `protobuf:"varint,4,opt,name=is_owner,json=isOwner,proto3" json:"is_owner,omitempty"`
+	// The relative share of rate limits assigned to the peer
+	Weight int32 `protobuf:"varint,5,opt,name=weight,proto3" json:"weight,omitempty"`
+	// False if the peer has been removed from the hash ring because it failed a health check
+	Healthy bool `protobuf:"varint,6,opt,name=healthy,proto3" json:"healthy,omitempty"`
+}
+
+func (x *PeerDetails) Reset() {
+	*x = PeerDetails{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_gubernator_proto_msgTypes[10]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *PeerDetails) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PeerDetails) ProtoMessage() {}
+
+func (x *PeerDetails) ProtoReflect() protoreflect.Message {
+	mi := &file_gubernator_proto_msgTypes[10]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PeerDetails.ProtoReflect.Descriptor instead.
+func (*PeerDetails) Descriptor() ([]byte, []int) {
+	return file_gubernator_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *PeerDetails) GetGrpcAddress() string {
+	if x != nil {
+		return x.GrpcAddress
+	}
+	return ""
+}
+
+func (x *PeerDetails) GetHttpAddress() string {
+	if x != nil {
+		return x.HttpAddress
+	}
+	return ""
+}
+
+func (x *PeerDetails) GetDataCenter() string {
+	if x != nil {
+		return x.DataCenter
+	}
+	return ""
+}
+
+func (x *PeerDetails) GetIsOwner() bool {
+	if x != nil {
+		return x.IsOwner
+	}
+	return false
+}
+
+func (x *PeerDetails) GetWeight() int32 {
+	if x != nil {
+		return x.Weight
+	}
+	return 0
+}
+
+func (x *PeerDetails) GetHealthy() bool {
+	if x != nil {
+		return x.Healthy
+	}
+	return false
+}
+
 var File_gubernator_proto protoreflect.FileDescriptor
 
 var file_gubernator_proto_rawDesc = []byte{
 	0x0a, 0x10, 0x67, 0x75, 0x62, 0x65, 0x72, 0x6e, 0x61, 0x74, 0x6f, 0x72, 0x2e, 0x70, 0x72, 0x6f,
 	0x74, 0x6f, 0x12, 0x0d, 0x70, 0x62, 0x2e, 0x67, 0x75, 0x62, 0x65, 0x72, 0x6e, 0x61, 0x74, 0x6f,
 	0x72, 0x1a, 0x1c, 0x67, 0x6f, 0x6f, 0x67, 0x6c, 0x65, 0x2f, 0x61, 0x70, 0x69, 0x2f, 0x61, 0x6e,
-	0x6e, 0x6f, 0x74, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x73, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x22,
-	0x4b, 0x0a, 0x10, 0x47, 0x65, 0x74, 0x52, 0x61, 0x74, 0x65, 0x4c, 0x69, 0x6d, 0x69, 0x74, 0x73,
-	0x52, 0x65, 0x71, 0x12, 0x37, 0x0a, 0x08, 0x72, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x73, 0x18,
-	0x01, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x1b, 0x2e, 0x70, 0x62, 0x2e, 0x67, 0x75, 0x62, 0x65, 0x72,
-	0x6e, 0x61, 0x74, 0x6f, 0x72, 0x2e, 0x52, 0x61, 0x74, 0x65, 0x4c, 0x69, 0x6d, 0x69, 0x74, 0x52,
-	0x65, 0x71, 0x52, 0x08, 0x72, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x73, 0x22, 0x4f, 0x0a, 0x11,
-	0x47, 0x65, 0x74, 0x52, 0x61, 0x74, 0x65, 0x4c, 0x69, 0x6d, 0x69, 0x74, 0x73, 0x52, 0x65, 0x73,
-	0x70, 0x12, 0x3a, 0x0a, 0x09, 0x72, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x73, 0x18, 0x01,
-	0x20, 0x03, 0x28, 0x0b, 0x32, 0x1c, 0x2e, 0x70, 0x62, 0x2e, 0x67, 0x75, 0x62, 0x65, 0x72, 0x6e,
-	0x61, 0x74, 0x6f, 0x72, 0x2e, 0x52, 0x61, 0x74, 0x65, 0x4c, 0x69, 0x6d, 0x69, 0x74, 0x52, 0x65,
-	0x73, 0x70, 0x52, 0x09, 0x72, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x73, 0x22, 0x8a, 0x02,
-	0x0a, 0x0c, 0x52, 0x61, 0x74, 0x65, 0x4c, 0x69, 0x6d, 0x69, 0x74, 0x52, 0x65, 0x71, 0x12, 0x12,
-	0x0a, 0x04, 0x6e, 0x61, 0x6d, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x6e, 0x61,
-	0x6d, 0x65, 0x12, 0x1d, 0x0a, 0x0a, 0x75, 0x6e, 0x69, 0x71, 0x75, 0x65, 0x5f, 0x6b, 0x65, 0x79,
-	0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x09, 0x75, 0x6e, 0x69, 0x71, 0x75, 0x65, 0x4b, 0x65,
-	0x79, 0x12, 0x12, 0x0a, 0x04, 0x68, 0x69, 0x74, 0x73, 0x18, 0x03, 0x20, 0x01, 0x28, 0x03, 0x52,
-	0x04, 0x68, 0x69, 0x74, 0x73, 0x12, 0x14, 0x0a, 0x05, 0x6c, 0x69, 0x6d, 0x69, 0x74, 0x18, 0x04,
-	0x20, 0x01, 0x28, 0x03, 0x52, 0x05, 0x6c, 0x69, 0x6d, 0x69, 0x74, 0x12, 0x1a, 0x0a, 0x08, 0x64,
-	0x75, 0x72, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x18, 0x05, 0x20, 0x01, 0x28, 0x03, 0x52, 0x08, 0x64,
-	0x75, 0x72, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x12, 0x36, 0x0a, 0x09, 0x61, 0x6c, 0x67, 0x6f, 0x72,
-	0x69, 0x74, 0x68, 0x6d, 0x18, 0x06, 0x20, 0x01, 0x28, 0x0e, 0x32, 0x18, 0x2e, 0x70, 0x62, 0x2e,
-	0x67, 0x75, 0x62, 0x65, 0x72, 0x6e, 0x61, 0x74, 0x6f, 0x72, 0x2e, 0x41, 0x6c, 0x67, 0x6f, 0x72,
-	0x69, 0x74, 0x68, 0x6d, 0x52, 0x09, 0x61, 0x6c, 0x67, 0x6f, 0x72, 0x69, 0x74, 0x68, 0x6d, 0x12,
-	0x33, 0x0a, 0x08, 0x62, 0x65, 0x68, 0x61, 0x76, 0x69, 0x6f, 0x72, 0x18, 0x07, 0x20, 0x01, 0x28,
-	0x0e, 0x32, 0x17, 0x2e, 0x70, 0x62, 0x2e, 0x67, 0x75, 0x62, 0x65, 0x72, 0x6e, 0x61, 0x74, 0x6f,
-	0x72, 0x2e, 0x42, 0x65, 0x68, 0x61, 0x76, 0x69, 0x6f, 0x72, 0x52, 0x08, 0x62, 0x65, 0x68, 0x61,
-	0x76, 0x69, 0x6f, 0x72, 0x12, 0x14, 0x0a, 0x05, 0x62, 0x75, 0x72, 0x73, 0x74, 0x18, 0x08, 0x20,
-	0x01, 0x28, 0x03, 0x52, 0x05, 0x62, 0x75, 0x72, 0x73, 0x74, 0x22, 0xac, 0x02, 0x0a, 0x0d, 0x52,
-	0x61, 0x74, 0x65, 0x4c, 0x69, 0x6d, 0x69, 0x74, 0x52, 0x65, 0x73, 0x70, 0x12, 0x2d, 0x0a, 0x06,
-	0x73, 0x74, 0x61, 0x74, 0x75, 0x73, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0e, 0x32, 0x15, 0x2e, 0x70,
-	0x62, 0x2e, 0x67, 0x75, 0x62, 0x65, 0x72, 0x6e, 0x61, 0x74, 0x6f, 0x72, 0x2e, 0x53, 0x74, 0x61,
-	0x74, 0x75, 0x73, 0x52, 0x06, 0x73, 0x74, 0x61, 0x74, 0x75, 0x73, 0x12, 0x14, 0x0a, 0x05, 0x6c,
-	0x69, 0x6d, 0x69, 0x74, 0x18, 0x02, 0x20, 0x01, 0x28, 0x03, 0x52, 0x05, 0x6c, 0x69, 0x6d, 0x69,
-	0x74, 0x12, 0x1c, 0x0a, 0x09, 0x72, 0x65, 0x6d, 0x61, 0x69, 0x6e, 0x69, 0x6e, 0x67, 0x18, 0x03,
-	0x20, 0x01, 0x28, 0x03, 0x52, 0x09, 0x72, 0x65, 0x6d, 0x61, 0x69, 0x6e, 0x69, 0x6e, 0x67, 0x12,
-	0x1d, 0x0a, 0x0a, 0x72, 0x65, 0x73, 0x65, 0x74, 0x5f, 0x74, 0x69, 0x6d, 0x65, 0x18, 0x04, 0x20,
-	0x01, 0x28, 0x03, 0x52, 0x09, 0x72, 0x65, 0x73, 0x65, 0x74, 0x54, 0x69, 0x6d, 0x65, 0x12, 0x14,
-	0x0a, 0x05, 0x65, 0x72, 0x72, 0x6f, 0x72, 0x18, 0x05, 0x20, 0x01, 0x28, 0x09, 0x52, 0x05, 0x65,
-	0x72, 0x72, 0x6f, 0x72, 0x12, 0x46, 0x0a, 0x08, 0x6d, 0x65, 0x74, 0x61, 0x64, 0x61, 0x74, 0x61,
-	0x18, 0x06, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x2a, 0x2e, 0x70, 0x62, 0x2e, 0x67, 0x75, 0x62, 0x65,
+	0x6e, 0x6f, 0x74, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x73, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x1a,
+	0x1f, 0x67, 0x6f, 0x6f, 0x67, 0x6c, 0x65, 0x2f, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x62, 0x75, 0x66,
+	0x2f, 0x74, 0x69, 0x6d, 0x65, 0x73, 0x74, 0x61, 0x6d, 0x70, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f,
+	0x22, 0x4b, 0x0a, 0x10, 0x47, 0x65, 0x74, 0x52, 0x61, 0x74, 0x65, 0x4c, 0x69, 0x6d, 0x69, 0x74,
+	0x73, 0x52, 0x65, 0x71, 0x12, 0x37, 0x0a, 0x08, 0x72, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x73,
+	0x18, 0x01, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x1b, 0x2e, 0x70, 0x62, 0x2e, 0x67, 0x75, 0x62, 0x65,
 	0x72, 0x6e, 0x61, 0x74, 0x6f, 0x72, 0x2e, 0x52, 0x61, 0x74, 0x65, 0x4c, 0x69, 0x6d, 0x69, 0x74,
-	0x52, 0x65, 0x73, 0x70, 0x2e, 0x4d, 0x65, 0x74, 0x61, 0x64, 0x61, 0x74, 0x61, 0x45, 0x6e, 0x74,
-	0x72, 0x79, 0x52, 0x08, 0x6d, 0x65, 0x74, 0x61, 0x64, 0x61, 0x74, 0x61, 0x1a, 0x3b, 0x0a, 0x0d,
-	0x4d, 0x65, 0x74, 0x61, 0x64, 0x61, 0x74, 0x61, 0x45, 0x6e, 0x74, 0x72, 0x79, 0x12, 0x10, 0x0a,
-	0x03, 0x6b, 0x65, 0x79, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x03, 0x6b, 0x65, 0x79, 0x12,
-	0x14, 0x0a, 0x05, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x05,
-	0x76, 0x61, 0x6c, 0x75, 0x65, 0x3a, 0x02, 0x38, 0x01, 0x22, 0x10, 0x0a, 0x0e, 0x48, 0x65, 0x61,
-	0x6c, 0x74, 0x68, 0x43, 0x68, 0x65, 0x63, 0x6b, 0x52, 0x65, 0x71, 0x22, 0x62, 0x0a, 0x0f, 0x48,
-	0x65, 0x61, 0x6c, 0x74, 0x68, 0x43, 0x68, 0x65, 0x63, 0x6b, 0x52, 0x65, 0x73, 0x70, 0x12, 0x16,
-	0x0a, 0x06, 0x73, 0x74, 0x61, 0x74, 0x75, 0x73, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x06,
-	0x73, 0x74, 0x61, 0x74, 0x75, 0x73, 0x12, 0x18, 0x0a, 0x07, 0x6d, 0x65, 0x73, 0x73, 0x61, 0x67,
-	0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x07, 0x6d, 0x65, 0x73, 0x73, 0x61, 0x67, 0x65,
-	0x12, 0x1d, 0x0a, 0x0a, 0x70, 0x65, 0x65, 0x72, 0x5f, 0x63, 0x6f, 0x75, 0x6e, 0x74, 0x18, 0x03,
-	0x20, 0x01, 0x28, 0x05, 0x52, 0x09, 0x70, 0x65, 0x65, 0x72, 0x43, 0x6f, 0x75, 0x6e, 0x74, 0x2a,
-	0x2f, 0x0a, 0x09, 0x41, 0x6c, 0x67, 0x6f, 0x72, 0x69, 0x74, 0x68, 0x6d, 0x12, 0x10, 0x0a, 0x0c,
-	0x54, 0x4f, 0x4b, 0x45, 0x4e, 0x5f, 0x42, 0x55, 0x43, 0x4b, 0x45, 0x54, 0x10, 0x00, 0x12, 0x10,
-	0x0a, 0x0c, 0x4c, 0x45, 0x41, 0x4b, 0x59, 0x5f, 0x42, 0x55, 0x43, 0x4b, 0x45, 0x54, 0x10, 0x01,
-	0x2a, 0x77, 0x0a, 0x08, 0x42, 0x65, 0x68, 0x61, 0x76, 0x69, 0x6f, 0x72, 0x12, 0x0c, 0x0a, 0x08,
-	0x42, 0x41, 0x54, 0x43, 0x48, 0x49, 0x4e, 0x47, 0x10, 0x00, 0x12, 0x0f, 0x0a, 0x0b, 0x4e, 0x4f,
-	0x5f, 0x42, 0x41, 0x54, 0x43, 0x48, 0x49, 0x4e, 0x47, 0x10, 0x01, 0x12, 0x0a, 0x0a, 0x06, 0x47,
-	0x4c, 0x4f, 0x42, 0x41, 0x4c, 0x10, 0x02, 0x12, 0x19, 0x0a, 0x15, 0x44, 0x55, 0x52, 0x41, 0x54,
-	0x49, 0x4f, 0x4e, 0x5f, 0x49, 0x53, 0x5f, 0x47, 0x52, 0x45, 0x47, 0x4f, 0x52, 0x49, 0x41, 0x4e,
-	0x10, 0x04, 0x12, 0x13, 0x0a, 0x0f, 0x52, 0x45, 0x53, 0x45, 0x54, 0x5f, 0x52, 0x45, 0x4d, 0x41,
-	0x49, 0x4e, 0x49, 0x4e, 0x47, 0x10, 0x08, 0x12, 0x10, 0x0a, 0x0c, 0x4d, 0x55, 0x4c, 0x54, 0x49,
-	0x5f, 0x52, 0x45, 0x47, 0x49, 0x4f, 0x4e, 0x10, 0x10, 0x2a, 0x29, 0x0a, 0x06, 0x53, 0x74, 0x61,
-	0x74, 0x75, 0x73, 0x12, 0x0f, 0x0a, 0x0b, 0x55, 0x4e, 0x44, 0x45, 0x52, 0x5f, 0x4c, 0x49, 0x4d,
-	0x49, 0x54, 0x10, 0x00, 0x12, 0x0e, 0x0a, 0x0a, 0x4f, 0x56, 0x45, 0x52, 0x5f, 0x4c, 0x49, 0x4d,
-	0x49, 0x54, 0x10, 0x01, 0x32, 0xdd, 0x01, 0x0a, 0x02, 0x56, 0x31, 0x12, 0x70, 0x0a, 0x0d, 0x47,
-	0x65, 0x74, 0x52, 0x61, 0x74, 0x65, 0x4c, 0x69, 0x6d, 0x69, 0x74, 0x73, 0x12, 0x1f, 0x2e, 0x70,
-	0x62, 0x2e, 0x67, 0x75, 0x62, 0x65, 0x72, 0x6e, 0x61, 0x74, 0x6f, 0x72, 0x2e, 0x47, 0x65, 0x74,
-	0x52, 0x61, 0x74, 0x65, 0x4c, 0x69, 0x6d, 0x69, 0x74, 0x73, 0x52, 0x65, 0x71, 0x1a, 0x20, 0x2e,
-	0x70, 0x62, 0x2e, 0x67, 0x75, 0x62, 0x65, 0x72, 0x6e, 0x61, 0x74, 0x6f, 0x72, 0x2e, 0x47, 0x65,
-	0x74, 0x52, 0x61, 0x74, 0x65, 0x4c, 0x69, 0x6d, 0x69, 0x74, 0x73, 0x52, 0x65, 0x73, 0x70, 0x22,
-	0x1c, 0x82, 0xd3, 0xe4, 0x93, 0x02, 0x16, 0x22, 0x11, 0x2f, 0x76, 0x31, 0x2f, 0x47, 0x65, 0x74,
-	0x52, 0x61, 0x74, 0x65, 0x4c, 0x69, 0x6d, 0x69, 0x74, 0x73, 0x3a, 0x01, 0x2a, 0x12, 0x65, 0x0a,
-	0x0b, 0x48, 0x65, 0x61, 0x6c, 0x74, 0x68, 0x43, 0x68, 0x65, 0x63, 0x6b, 0x12, 0x1d, 0x2e, 0x70,
-	0x62, 0x2e, 0x67, 0x75, 0x62, 0x65, 0x72, 0x6e, 0x61, 0x74, 0x6f, 0x72, 0x2e, 0x48, 0x65, 0x61,
-	0x6c, 0x74, 0x68, 0x43, 0x68, 0x65, 0x63, 0x6b, 0x52, 0x65, 0x71, 0x1a, 0x1e, 0x2e, 0x70, 0x62,
-	0x2e, 0x67, 0x75, 0x62, 0x65, 0x72, 0x6e, 0x61, 0x74, 0x6f, 0x72, 0x2e, 0x48, 0x65, 0x61, 0x6c,
-	0x74, 0x68, 0x43, 0x68, 0x65, 0x63, 0x6b, 0x52, 0x65, 0x73, 0x70, 0x22, 0x17, 0x82, 0xd3, 0xe4,
-	0x93, 0x02, 0x11, 0x12, 0x0f, 0x2f, 0x76, 0x31, 0x2f, 0x48, 0x65, 0x61, 0x6c, 0x74, 0x68, 0x43,
-	0x68, 0x65, 0x63, 0x6b, 0x42, 0x22, 0x5a, 0x1d, 0x67, 0x69, 0x74, 0x68, 0x75, 0x62, 0x2e, 0x63,
-	0x6f, 0x6d, 0x2f, 0x6d, 0x61, 0x69, 0x6c, 0x67, 0x75, 0x6e, 0x2f, 0x67, 0x75, 0x62, 0x65, 0x72,
-	0x6e, 0x61, 0x74, 0x6f, 0x72, 0x80, 0x01, 0x01, 0x62, 0x06, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x33,
+	0x52, 0x65, 0x71, 0x52, 0x08, 0x72, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x73, 0x22, 0x4f, 0x0a,
+	0x11, 0x47, 0x65, 0x74, 0x52, 0x61, 0x74, 0x65, 0x4c, 0x69, 0x6d, 0x69, 0x74, 0x73, 0x52, 0x65,
+	0x73, 0x70, 0x12, 0x3a, 0x0a, 0x09, 0x72, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x73, 0x18,
+	0x01, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x1c, 0x2e, 0x70, 0x62, 0x2e, 0x67, 0x75, 0x62, 0x65, 0x72,
+	0x6e, 0x61, 0x74, 0x6f, 0x72, 0x2e, 0x52, 0x61, 0x74, 0x65, 0x4c, 0x69, 0x6d, 0x69, 0x74, 0x52,
+	0x65, 0x73, 0x70, 0x52, 0x09, 0x72, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x73, 0x22, 0xbd,
+	0x06, 0x0a, 0x0c, 0x52, 0x61, 0x74, 0x65, 0x4c, 0x69, 0x6d, 0x69, 0x74, 0x52, 0x65, 0x71, 0x12,
+	0x12, 0x0a, 0x04, 0x6e, 0x61, 0x6d, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x6e,
+	0x61, 0x6d, 0x65, 0x12, 0x1d, 0x0a, 0x0a, 0x75, 0x6e, 0x69, 0x71, 0x75, 0x65, 0x5f, 0x6b, 0x65,
+	0x79, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x09, 0x75, 0x6e, 0x69, 0x71, 0x75, 0x65, 0x4b,
+	0x65, 0x79, 0x12, 0x12, 0x0a, 0x04, 0x68, 0x69, 0x74, 0x73, 0x18, 0x03, 0x20, 0x01, 0x28, 0x03,
+	0x52, 0x04, 0x68, 0x69, 0x74, 0x73, 0x12, 0x14, 0x0a, 0x05, 0x6c, 0x69, 0x6d, 0x69, 0x74, 0x18,
+	0x04, 0x20, 0x01, 0x28, 0x03, 0x52, 0x05, 0x6c, 0x69, 0x6d, 0x69, 0x74, 0x12, 0x1a, 0x0a, 0x08,
+	0x64, 0x75, 0x72, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x18, 0x05, 0x20, 0x01, 0x28, 0x03, 0x52, 0x08,
+	0x64, 0x75, 0x72, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x12, 0x36, 0x0a, 0x09, 0x61, 0x6c, 0x67, 0x6f,
+	0x72, 0x69, 0x74, 0x68, 0x6d, 0x18, 0x06, 0x20, 0x01, 0x28, 0x0e, 0x32, 0x18, 0x2e, 0x70, 0x62,
+	0x2e, 0x67, 0x75, 0x62, 0x65, 0x72, 0x6e, 0x61, 0x74, 0x6f, 0x72, 0x2e, 0x41, 0x6c, 0x67, 0x6f,
+	0x72, 0x69, 0x74, 0x68, 0x6d, 0x52, 0x09, 0x61, 0x6c, 0x67, 0x6f, 0x72, 0x69, 0x74, 0x68, 0x6d,
+	0x12, 0x33, 0x0a, 0x08, 0x62, 0x65, 0x68, 0x61, 0x76, 0x69, 0x6f, 0x72, 0x18, 0x07, 0x20, 0x01,
+	0x28, 0x0e, 0x32, 0x17, 0x2e, 0x70, 0x62, 0x2e, 0x67, 0x75, 0x62, 0x65, 0x72, 0x6e, 0x61, 0x74,
+	0x6f, 0x72, 0x2e, 0x42, 0x65, 0x68, 0x61, 0x76, 0x69, 0x6f, 0x72, 0x52, 0x08, 0x62, 0x65, 0x68,
+	0x61, 0x76, 0x69, 0x6f, 0x72, 0x12, 0x14, 0x0a, 0x05, 0x62, 0x75, 0x72, 0x73, 0x74, 0x18, 0x08,
+	0x20, 0x01, 0x28, 0x03, 0x52, 0x05, 0x62, 0x75, 0x72, 0x73, 0x74, 0x12, 0x18, 0x0a, 0x07, 0x70,
+	0x65, 0x6e, 0x61, 0x6c, 0x74, 0x79, 0x18, 0x09, 0x20, 0x01, 0x28, 0x03, 0x52, 0x07, 0x70, 0x65,
+	0x6e, 0x61, 0x6c, 0x74, 0x79, 0x12, 0x12, 0x0a, 0x04, 0x63, 0x6f, 0x73, 0x74, 0x18, 0x0a, 0x20,
+	0x01, 0x28, 0x01, 0x52, 0x04, 0x63, 0x6f, 0x73, 0x74, 0x12, 0x21, 0x0a, 0x0c, 0x72, 0x65, 0x73,
+	0x65, 0x74, 0x5f, 0x6a, 0x69, 0x74, 0x74, 0x65, 0x72, 0x18, 0x0b, 0x20, 0x01, 0x28, 0x03, 0x52,
+	0x0b, 0x72, 0x65, 0x73, 0x65, 0x74, 0x4a, 0x69, 0x74, 0x74, 0x65, 0x72, 0x12, 0x27, 0x0a, 0x0f,
+	0x69, 0x64, 0x65, 0x6d, 0x70, 0x6f, 0x74, 0x65, 0x6e, 0x63, 0x79, 0x5f, 0x6b, 0x65, 0x79, 0x18,
+	0x0c, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0e, 0x69, 0x64, 0x65, 0x6d, 0x70, 0x6f, 0x74, 0x65, 0x6e,
+	0x63, 0x79, 0x4b, 0x65, 0x79, 0x12, 0x45, 0x0a, 0x08, 0x6d, 0x65, 0x74, 0x61, 0x64, 0x61, 0x74,
+	0x61, 0x18, 0x0d, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x29, 0x2e, 0x70, 0x62, 0x2e, 0x67, 0x75, 0x62,
+	0x65, 0x72, 0x6e, 0x61, 0x74, 0x6f, 0x72, 0x2e, 0x52, 0x61, 0x74, 0x65, 0x4c, 0x69, 0x6d, 0x69,
+	0x74, 0x52, 0x65, 0x71, 0x2e, 0x4d, 0x65, 0x74, 0x61, 0x64, 0x61, 0x74, 0x61, 0x45, 0x6e, 0x74,
+	0x72, 0x79, 0x52, 0x08, 0x6d, 0x65, 0x74, 0x61, 0x64, 0x61, 0x74, 0x61, 0x12, 0x21, 0x0a, 0x0c,
+	0x70, 0x65, 0x6e, 0x61, 0x6c, 0x74, 0x79, 0x5f, 0x73, 0x74, 0x65, 0x70, 0x18, 0x0e, 0x20, 0x01,
+	0x28, 0x03, 0x52, 0x0b, 0x70, 0x65, 0x6e, 0x61, 0x6c, 0x74, 0x79, 0x53, 0x74, 0x65, 0x70, 0x12,
+	0x35, 0x0a, 0x07, 0x70, 0x61, 0x72, 0x65, 0x6e, 0x74, 0x73, 0x18, 0x0f, 0x20, 0x03, 0x28, 0x0b,
+	0x32, 0x1b, 0x2e, 0x70, 0x62, 0x2e, 0x67, 0x75, 0x62, 0x65, 0x72, 0x6e, 0x61, 0x74, 0x6f, 0x72,
+	0x2e, 0x52, 0x61, 0x74, 0x65, 0x4c, 0x69, 0x6d, 0x69, 0x74, 0x52, 0x65, 0x71, 0x52, 0x07, 0x70,
+	0x61, 0x72, 0x65, 0x6e, 0x74, 0x73, 0x12, 0x1a, 0x0a, 0x08, 0x74, 0x69, 0x6d, 0x65, 0x7a, 0x6f,
+	0x6e, 0x65, 0x18, 0x10, 0x20, 0x01, 0x28, 0x09, 0x52, 0x08, 0x74, 0x69, 0x6d, 0x65, 0x7a, 0x6f,
+	0x6e, 0x65, 0x12, 0x1a, 0x0a, 0x08, 0x70, 0x72, 0x69, 0x6f, 0x72, 0x69, 0x74, 0x79, 0x18, 0x11,
+	0x20, 0x01, 0x28, 0x05, 0x52, 0x08, 0x70, 0x72, 0x69, 0x6f, 0x72, 0x69, 0x74, 0x79, 0x12, 0x3d,
+	0x0a, 0x0c, 0x6c, 0x65, 0x61, 0x6b, 0x5f, 0x70, 0x72, 0x6f, 0x66, 0x69, 0x6c, 0x65, 0x18, 0x12,
+	0x20, 0x01, 0x28, 0x0e, 0x32, 0x1a, 0x2e, 0x70, 0x62, 0x2e, 0x67, 0x75, 0x62, 0x65, 0x72, 0x6e,
+	0x61, 0x74, 0x6f, 0x72, 0x2e, 0x4c, 0x65, 0x61, 0x6b, 0x50, 0x72, 0x6f, 0x66, 0x69, 0x6c, 0x65,
+	0x52, 0x0b, 0x6c, 0x65, 0x61, 0x6b, 0x50, 0x72, 0x6f, 0x66, 0x69, 0x6c, 0x65, 0x12, 0x39, 0x0a,
+	0x08, 0x73, 0x63, 0x68, 0x65, 0x64, 0x75, 0x6c, 0x65, 0x18, 0x13, 0x20, 0x03, 0x28, 0x0b, 0x32,
+	0x1d, 0x2e, 0x70, 0x62, 0x2e, 0x67, 0x75, 0x62, 0x65, 0x72, 0x6e, 0x61, 0x74, 0x6f, 0x72, 0x2e,
+	0x53, 0x63, 0x68, 0x65, 0x64, 0x75, 0x6c, 0x65, 0x57, 0x69, 0x6e, 0x64, 0x6f, 0x77, 0x52, 0x08,
+	0x73, 0x63, 0x68, 0x65, 0x64, 0x75, 0x6c, 0x65, 0x12, 0x27, 0x0a, 0x0f, 0x65, 0x78, 0x70, 0x6c,
+	0x69, 0x63, 0x69, 0x74, 0x5f, 0x66, 0x69, 0x65, 0x6c, 0x64, 0x73, 0x18, 0x14, 0x20, 0x03, 0x28,
+	0x09, 0x52, 0x0e, 0x65, 0x78, 0x70, 0x6c, 0x69, 0x63, 0x69, 0x74, 0x46, 0x69, 0x65, 0x6c, 0x64,
+	0x73, 0x1a, 0x3b, 0x0a, 0x0d, 0x4d, 0x65, 0x74, 0x61, 0x64, 0x61, 0x74, 0x61, 0x45, 0x6e, 0x74,
+	0x72, 0x79, 0x12, 0x10, 0x0a, 0x03, 0x6b, 0x65, 0x79, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52,
+	0x03, 0x6b, 0x65, 0x79, 0x12, 0x14, 0x0a, 0x05, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x18, 0x02, 0x20,
+	0x01, 0x28, 0x09, 0x52, 0x05, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x3a, 0x02, 0x38, 0x01, 0x22, 0x4e,
+	0x0a, 0x0e, 0x53, 0x63, 0x68, 0x65, 0x64, 0x75, 0x6c, 0x65, 0x57, 0x69, 0x6e, 0x64, 0x6f, 0x77,
+	0x12, 0x14, 0x0a, 0x05, 0x73, 0x74, 0x61, 0x72, 0x74, 0x18, 0x01, 0x20, 0x01, 0x28, 0x05, 0x52,
+	0x05, 0x73, 0x74, 0x61, 0x72, 0x74, 0x12, 0x10, 0x0a, 0x03, 0x65, 0x6e, 0x64, 0x18, 0x02, 0x20,
+	0x01, 0x28, 0x05, 0x52, 0x03, 0x65, 0x6e, 0x64, 0x12, 0x14, 0x0a, 0x05, 0x6c, 0x69, 0x6d, 0x69,
+	0x74, 0x18, 0x03, 0x20, 0x01, 0x28, 0x03, 0x52, 0x05, 0x6c, 0x69, 0x6d, 0x69, 0x74, 0x22, 0xc0,
+	0x05, 0x0a, 0x0d, 0x52, 0x61, 0x74, 0x65, 0x4c, 0x69, 0x6d, 0x69, 0x74, 0x52, 0x65, 0x73, 0x70,
+	0x12, 0x2d, 0x0a, 0x06, 0x73, 0x74, 0x61, 0x74, 0x75, 0x73, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0e,
+	0x32, 0x15, 0x2e, 0x70, 0x62, 0x2e, 0x67, 0x75, 0x62, 0x65, 0x72, 0x6e, 0x61, 0x74, 0x6f, 0x72,
+	0x2e, 0x53, 0x74, 0x61, 0x74, 0x75, 0x73, 0x52, 0x06, 0x73, 0x74, 0x61, 0x74, 0x75, 0x73, 0x12,
+	0x14, 0x0a, 0x05, 0x6c, 0x69, 0x6d, 0x69, 0x74, 0x18, 0x02, 0x20, 0x01, 0x28, 0x03, 0x52, 0x05,
+	0x6c, 0x69, 0x6d, 0x69, 0x74, 0x12, 0x1c, 0x0a, 0x09, 0x72, 0x65, 0x6d, 0x61, 0x69, 0x6e, 0x69,
+	0x6e, 0x67, 0x18, 0x03, 0x20, 0x01, 0x28, 0x03, 0x52, 0x09, 0x72, 0x65, 0x6d, 0x61, 0x69, 0x6e,
+	0x69, 0x6e, 0x67, 0x12, 0x1d, 0x0a, 0x0a, 0x72, 0x65, 0x73, 0x65, 0x74, 0x5f, 0x74, 0x69, 0x6d,
+	0x65, 0x18, 0x04, 0x20, 0x01, 0x28, 0x03, 0x52, 0x09, 0x72, 0x65, 0x73, 0x65, 0x74, 0x54, 0x69,
+	0x6d, 0x65, 0x12, 0x14, 0x0a, 0x05, 0x65, 0x72, 0x72, 0x6f, 0x72, 0x18, 0x05, 0x20, 0x01, 0x28,
+	0x09, 0x52, 0x05, 0x65, 0x72, 0x72, 0x6f, 0x72, 0x12, 0x46, 0x0a, 0x08, 0x6d, 0x65, 0x74, 0x61,
+	0x64, 0x61, 0x74, 0x61, 0x18, 0x06, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x2a, 0x2e, 0x70, 0x62, 0x2e,
+	0x67, 0x75, 0x62, 0x65, 0x72, 0x6e, 0x61, 0x74, 0x6f, 0x72, 0x2e, 0x52, 0x61, 0x74, 0x65, 0x4c,
+	0x69, 0x6d, 0x69, 0x74, 0x52, 0x65, 0x73, 0x70, 0x2e, 0x4d, 0x65, 0x74, 0x61, 0x64, 0x61, 0x74,
+	0x61, 0x45, 0x6e, 0x74, 0x72, 0x79, 0x52, 0x08, 0x6d, 0x65, 0x74, 0x61, 0x64, 0x61, 0x74, 0x61,
+	0x12, 0x36, 0x0a, 0x09, 0x61, 0x6c, 0x67, 0x6f, 0x72, 0x69, 0x74, 0x68, 0x6d, 0x18, 0x07, 0x20,
+	0x01, 0x28, 0x0e, 0x32, 0x18, 0x2e, 0x70, 0x62, 0x2e, 0x67, 0x75, 0x62, 0x65, 0x72, 0x6e, 0x61,
+	0x74, 0x6f, 0x72, 0x2e, 0x41, 0x6c, 0x67, 0x6f, 0x72, 0x69, 0x74, 0x68, 0x6d, 0x52, 0x09, 0x61,
+	0x6c, 0x67, 0x6f, 0x72, 0x69, 0x74, 0x68, 0x6d, 0x12, 0x1f, 0x0a, 0x0b, 0x72, 0x65, 0x73, 0x65,
+	0x74, 0x5f, 0x61, 0x66, 0x74, 0x65, 0x72, 0x18, 0x08, 0x20, 0x01, 0x28, 0x03, 0x52, 0x0a, 0x72,
+	0x65, 0x73, 0x65, 0x74, 0x41, 0x66, 0x74, 0x65, 0x72, 0x12, 0x36, 0x0a, 0x06, 0x63, 0x6f, 0x6e,
+	0x66, 0x69, 0x67, 0x18, 0x09, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x1e, 0x2e, 0x70, 0x62, 0x2e, 0x67,
+	0x75, 0x62, 0x65, 0x72, 0x6e, 0x61, 0x74, 0x6f, 0x72, 0x2e, 0x52, 0x61, 0x74, 0x65, 0x4c, 0x69,
+	0x6d, 0x69, 0x74, 0x43, 0x6f, 0x6e, 0x66, 0x69, 0x67, 0x52, 0x06, 0x63, 0x6f, 0x6e, 0x66, 0x69,
+	0x67, 0x12, 0x43, 0x0a, 0x0f, 0x72, 0x65, 0x73, 0x65, 0x74, 0x5f, 0x74, 0x69, 0x6d, 0x65, 0x73,
+	0x74, 0x61, 0x6d, 0x70, 0x18, 0x0a, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x1a, 0x2e, 0x67, 0x6f, 0x6f,
+	0x67, 0x6c, 0x65, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x62, 0x75, 0x66, 0x2e, 0x54, 0x69, 0x6d,
+	0x65, 0x73, 0x74, 0x61, 0x6d, 0x70, 0x52, 0x0e, 0x72, 0x65, 0x73, 0x65, 0x74, 0x54, 0x69, 0x6d,
+	0x65, 0x73, 0x74, 0x61, 0x6d, 0x70, 0x12, 0x1d, 0x0a, 0x0a, 0x74, 0x6f, 0x74, 0x61, 0x6c, 0x5f,
+	0x68, 0x69, 0x74, 0x73, 0x18, 0x0b, 0x20, 0x01, 0x28, 0x03, 0x52, 0x09, 0x74, 0x6f, 0x74, 0x61,
+	0x6c, 0x48, 0x69, 0x74, 0x73, 0x12, 0x1b, 0x0a, 0x09, 0x6f, 0x76, 0x65, 0x72, 0x5f, 0x68, 0x69,
+	0x74, 0x73, 0x18, 0x0c, 0x20, 0x01, 0x28, 0x03, 0x52, 0x08, 0x6f, 0x76, 0x65, 0x72, 0x48, 0x69,
+	0x74, 0x73, 0x12, 0x36, 0x0a, 0x07, 0x70, 0x61, 0x72, 0x65, 0x6e, 0x74, 0x73, 0x18, 0x0d, 0x20,
+	0x03, 0x28, 0x0b, 0x32, 0x1c, 0x2e, 0x70, 0x62, 0x2e, 0x67, 0x75, 0x62, 0x65, 0x72, 0x6e, 0x61,
+	0x74, 0x6f, 0x72, 0x2e, 0x52, 0x61, 0x74, 0x65, 0x4c, 0x69, 0x6d, 0x69, 0x74, 0x52, 0x65, 0x73,
+	0x70, 0x52, 0x07, 0x70, 0x61, 0x72, 0x65, 0x6e, 0x74, 0x73, 0x12, 0x14, 0x0a, 0x05, 0x6f, 0x77,
+	0x6e, 0x65, 0x72, 0x18, 0x0e, 0x20, 0x01, 0x28, 0x09, 0x52, 0x05, 0x6f, 0x77, 0x6e, 0x65, 0x72,
+	0x12, 0x32, 0x0a, 0x15, 0x73, 0x75, 0x67, 0x67, 0x65, 0x73, 0x74, 0x65, 0x64, 0x5f, 0x72, 0x65,
+	0x74, 0x72, 0x79, 0x5f, 0x61, 0x66, 0x74, 0x65, 0x72, 0x18, 0x0f, 0x20, 0x01, 0x28, 0x03, 0x52,
+	0x13, 0x73, 0x75, 0x67, 0x67, 0x65, 0x73, 0x74, 0x65, 0x64, 0x52, 0x65, 0x74, 0x72, 0x79, 0x41,
+	0x66, 0x74, 0x65, 0x72, 0x1a, 0x3b, 0x0a, 0x0d, 0x4d, 0x65, 0x74, 0x61, 0x64, 0x61, 0x74, 0x61,
+	0x45, 0x6e, 0x74, 0x72, 0x79, 0x12, 0x10, 0x0a, 0x03, 0x6b, 0x65, 0x79, 0x18, 0x01, 0x20, 0x01,
+	0x28, 0x09, 0x52, 0x03, 0x6b, 0x65, 0x79, 0x12, 0x14, 0x0a, 0x05, 0x76, 0x61, 0x6c, 0x75, 0x65,
+	0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x05, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x3a, 0x02, 0x38,
+	0x01, 0x22, 0x91, 0x01, 0x0a, 0x0f, 0x52, 0x61, 0x74, 0x65, 0x4c, 0x69, 0x6d, 0x69, 0x74, 0x43,
+	0x6f, 0x6e, 0x66, 0x69, 0x67, 0x12, 0x36, 0x0a, 0x09, 0x61, 0x6c, 0x67, 0x6f, 0x72, 0x69, 0x74,
+	0x68, 0x6d, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0e, 0x32, 0x18, 0x2e, 0x70, 0x62, 0x2e, 0x67, 0x75,
+	0x62, 0x65, 0x72, 0x6e, 0x61, 0x74, 0x6f, 0x72, 0x2e, 0x41, 0x6c, 0x67, 0x6f, 0x72, 0x69, 0x74,
+	0x68, 0x6d, 0x52, 0x09, 0x61, 0x6c, 0x67, 0x6f, 0x72, 0x69, 0x74, 0x68, 0x6d, 0x12, 0x14, 0x0a,
+	0x05, 0x6c, 0x69, 0x6d, 0x69, 0x74, 0x18, 0x02, 0x20, 0x01, 0x28, 0x03, 0x52, 0x05, 0x6c, 0x69,
+	0x6d, 0x69, 0x74, 0x12, 0x1a, 0x0a, 0x08, 0x64, 0x75, 0x72, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x18,
+	0x03, 0x20, 0x01, 0x28, 0x03, 0x52, 0x08, 0x64, 0x75, 0x72, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x12,
+	0x14, 0x0a, 0x05, 0x62, 0x75, 0x72, 0x73, 0x74, 0x18, 0x04, 0x20, 0x01, 0x28, 0x03, 0x52, 0x05,
+	0x62, 0x75, 0x72, 0x73, 0x74, 0x22, 0x10, 0x0a, 0x0e, 0x48, 0x65, 0x61, 0x6c, 0x74, 0x68, 0x43,
+	0x68, 0x65, 0x63, 0x6b, 0x52, 0x65, 0x71, 0x22, 0x62, 0x0a, 0x0f, 0x48, 0x65, 0x61, 0x6c, 0x74,
+	0x68, 0x43, 0x68, 0x65, 0x63, 0x6b, 0x52, 0x65, 0x73, 0x70, 0x12, 0x16, 0x0a, 0x06, 0x73, 0x74,
+	0x61, 0x74, 0x75, 0x73, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x06, 0x73, 0x74, 0x61, 0x74,
+	0x75, 0x73, 0x12, 0x18, 0x0a, 0x07, 0x6d, 0x65, 0x73, 0x73, 0x61, 0x67, 0x65, 0x18, 0x02, 0x20,
+	0x01, 0x28, 0x09, 0x52, 0x07, 0x6d, 0x65, 0x73, 0x73, 0x61, 0x67, 0x65, 0x12, 0x1d, 0x0a, 0x0a,
+	0x70, 0x65, 0x65, 0x72, 0x5f, 0x63, 0x6f, 0x75, 0x6e, 0x74, 0x18, 0x03, 0x20, 0x01, 0x28, 0x05,
+	0x52, 0x09, 0x70, 0x65, 0x65, 0x72, 0x43, 0x6f, 0x75, 0x6e, 0x74, 0x22, 0x43, 0x0a, 0x0e, 0x47,
+	0x65, 0x74, 0x50, 0x65, 0x65, 0x72, 0x49, 0x6e, 0x66, 0x6f, 0x52, 0x65, 0x71, 0x12, 0x12, 0x0a,
+	0x04, 0x6e, 0x61, 0x6d, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x6e, 0x61, 0x6d,
+	0x65, 0x12, 0x1d, 0x0a, 0x0a, 0x75, 0x6e, 0x69, 0x71, 0x75, 0x65, 0x5f, 0x6b, 0x65, 0x79, 0x18,
+	0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x09, 0x75, 0x6e, 0x69, 0x71, 0x75, 0x65, 0x4b, 0x65, 0x79,
+	0x22, 0x75, 0x0a, 0x0f, 0x47, 0x65, 0x74, 0x50, 0x65, 0x65, 0x72, 0x49, 0x6e, 0x66, 0x6f, 0x52,
+	0x65, 0x73, 0x70, 0x12, 0x30, 0x0a, 0x05, 0x70, 0x65, 0x65, 0x72, 0x73, 0x18, 0x01, 0x20, 0x03,
+	0x28, 0x0b, 0x32, 0x1a, 0x2e, 0x70, 0x62, 0x2e, 0x67, 0x75, 0x62, 0x65, 0x72, 0x6e, 0x61, 0x74,
+	0x6f, 0x72, 0x2e, 0x50, 0x65, 0x65, 0x72, 0x44, 0x65, 0x74, 0x61, 0x69, 0x6c, 0x73, 0x52, 0x05,
+	0x70, 0x65, 0x65, 0x72, 0x73, 0x12, 0x30, 0x0a, 0x05, 0x6f, 0x77, 0x6e, 0x65, 0x72, 0x18, 0x02,
+	0x20, 0x01, 0x28, 0x0b, 0x32, 0x1a, 0x2e, 0x70, 0x62, 0x2e, 0x67, 0x75, 0x62, 0x65, 0x72, 0x6e,
+	0x61, 0x74, 0x6f, 0x72, 0x2e, 0x50, 0x65, 0x65, 0x72, 0x44, 0x65, 0x74, 0x61, 0x69, 0x6c, 0x73,
+	0x52, 0x05, 0x6f, 0x77, 0x6e, 0x65, 0x72, 0x22, 0xc1, 0x01, 0x0a, 0x0b, 0x50, 0x65, 0x65, 0x72,
+	0x44, 0x65, 0x74, 0x61, 0x69, 0x6c, 0x73, 0x12, 0x21, 0x0a, 0x0c, 0x67, 0x72, 0x70, 0x63, 0x5f,
+	0x61, 0x64, 0x64, 0x72, 0x65, 0x73, 0x73, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0b, 0x67,
+	0x72, 0x70, 0x63, 0x41, 0x64, 0x64, 0x72, 0x65, 0x73, 0x73, 0x12, 0x21, 0x0a, 0x0c, 0x68, 0x74,
+	0x74, 0x70, 0x5f, 0x61, 0x64, 0x64, 0x72, 0x65, 0x73, 0x73, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09,
+	0x52, 0x0b, 0x68, 0x74, 0x74, 0x70, 0x41, 0x64, 0x64, 0x72, 0x65, 0x73, 0x73, 0x12, 0x1f, 0x0a,
+	0x0b, 0x64, 0x61, 0x74, 0x61, 0x5f, 0x63, 0x65, 0x6e, 0x74, 0x65, 0x72, 0x18, 0x03, 0x20, 0x01,
+	0x28, 0x09, 0x52, 0x0a, 0x64, 0x61, 0x74, 0x61, 0x43, 0x65, 0x6e, 0x74, 0x65, 0x72, 0x12, 0x19,
+	0x0a, 0x08, 0x69, 0x73, 0x5f, 0x6f, 0x77, 0x6e, 0x65, 0x72, 0x18, 0x04, 0x20, 0x01, 0x28, 0x08,
+	0x52, 0x07, 0x69, 0x73, 0x4f, 0x77, 0x6e, 0x65, 0x72, 0x12, 0x16, 0x0a, 0x06, 0x77, 0x65, 0x69,
+	0x67, 0x68, 0x74, 0x18, 0x05, 0x20, 0x01, 0x28, 0x05, 0x52, 0x06, 0x77, 0x65, 0x69, 0x67, 0x68,
+	0x74, 0x12, 0x18, 0x0a, 0x07, 0x68, 0x65, 0x61, 0x6c, 0x74, 0x68, 0x79, 0x18, 0x06, 0x20, 0x01,
+	0x28, 0x08, 0x52, 0x07, 0x68, 0x65, 0x61, 0x6c, 0x74, 0x68, 0x79, 0x2a, 0x3b, 0x0a, 0x09, 0x41,
+	0x6c, 0x67, 0x6f, 0x72, 0x69, 0x74, 0x68, 0x6d, 0x12, 0x10, 0x0a, 0x0c, 0x54, 0x4f, 0x4b, 0x45,
+	0x4e, 0x5f, 0x42, 0x55, 0x43, 0x4b, 0x45, 0x54, 0x10, 0x00, 0x12, 0x10, 0x0a, 0x0c, 0x4c, 0x45,
+	0x41, 0x4b, 0x59, 0x5f, 0x42, 0x55, 0x43, 0x4b, 0x45, 0x54, 0x10, 0x01, 0x12, 0x0a, 0x0a, 0x06,
+	0x48, 0x59, 0x42, 0x52, 0x49, 0x44, 0x10, 0x02, 0x2a, 0x28, 0x0a, 0x0b, 0x4c, 0x65, 0x61, 0x6b,
+	0x50, 0x72, 0x6f, 0x66, 0x69, 0x6c, 0x65, 0x12, 0x0a, 0x0a, 0x06, 0x4c, 0x49, 0x4e, 0x45, 0x41,
+	0x52, 0x10, 0x00, 0x12, 0x0d, 0x0a, 0x09, 0x51, 0x55, 0x41, 0x44, 0x52, 0x41, 0x54, 0x49, 0x43,
+	0x10, 0x01, 0x2a, 0x92, 0x03, 0x0a, 0x08, 0x42, 0x65, 0x68, 0x61, 0x76, 0x69, 0x6f, 0x72, 0x12,
+	0x0c, 0x0a, 0x08, 0x42, 0x41, 0x54, 0x43, 0x48, 0x49, 0x4e, 0x47, 0x10, 0x00, 0x12, 0x0f, 0x0a,
+	0x0b, 0x4e, 0x4f, 0x5f, 0x42, 0x41, 0x54, 0x43, 0x48, 0x49, 0x4e, 0x47, 0x10, 0x01, 0x12, 0x0a,
+	0x0a, 0x06, 0x47, 0x4c, 0x4f, 0x42, 0x41, 0x4c, 0x10, 0x02, 0x12, 0x19, 0x0a, 0x15, 0x44, 0x55,
+	0x52, 0x41, 0x54, 0x49, 0x4f, 0x4e, 0x5f, 0x49, 0x53, 0x5f, 0x47, 0x52, 0x45, 0x47, 0x4f, 0x52,
+	0x49, 0x41, 0x4e, 0x10, 0x04, 0x12, 0x13, 0x0a, 0x0f, 0x52, 0x45, 0x53, 0x45, 0x54, 0x5f, 0x52,
+	0x45, 0x4d, 0x41, 0x49, 0x4e, 0x49, 0x4e, 0x47, 0x10, 0x08, 0x12, 0x10, 0x0a, 0x0c, 0x4d, 0x55,
+	0x4c, 0x54, 0x49, 0x5f, 0x52, 0x45, 0x47, 0x49, 0x4f, 0x4e, 0x10, 0x10, 0x12, 0x14, 0x0a, 0x10,
+	0x44, 0x52, 0x41, 0x49, 0x4e, 0x5f, 0x4f, 0x56, 0x45, 0x52, 0x5f, 0x4c, 0x49, 0x4d, 0x49, 0x54,
+	0x10, 0x20, 0x12, 0x14, 0x0a, 0x10, 0x50, 0x45, 0x4e, 0x41, 0x4c, 0x54, 0x59, 0x5f, 0x43, 0x4f,
+	0x4f, 0x4c, 0x44, 0x4f, 0x57, 0x4e, 0x10, 0x40, 0x12, 0x09, 0x0a, 0x04, 0x50, 0x45, 0x45, 0x4b,
+	0x10, 0x80, 0x01, 0x12, 0x11, 0x0a, 0x0c, 0x52, 0x45, 0x53, 0x45, 0x54, 0x5f, 0x4a, 0x49, 0x54,
+	0x54, 0x45, 0x52, 0x10, 0x80, 0x02, 0x12, 0x12, 0x0a, 0x0d, 0x52, 0x45, 0x54, 0x55, 0x52, 0x4e,
+	0x5f, 0x43, 0x4f, 0x4e, 0x46, 0x49, 0x47, 0x10, 0x80, 0x04, 0x12, 0x0d, 0x0a, 0x08, 0x4e, 0x4f,
+	0x5f, 0x53, 0x54, 0x4f, 0x52, 0x45, 0x10, 0x80, 0x08, 0x12, 0x16, 0x0a, 0x11, 0x52, 0x45, 0x54,
+	0x55, 0x52, 0x4e, 0x5f, 0x54, 0x4f, 0x54, 0x41, 0x4c, 0x5f, 0x48, 0x49, 0x54, 0x53, 0x10, 0x80,
+	0x10, 0x12, 0x0f, 0x0a, 0x0a, 0x54, 0x4f, 0x4b, 0x45, 0x4e, 0x5f, 0x44, 0x52, 0x49, 0x50, 0x10,
+	0x80, 0x20, 0x12, 0x14, 0x0a, 0x0f, 0x50, 0x41, 0x52, 0x54, 0x49, 0x41, 0x4c, 0x5f, 0x43, 0x4f,
+	0x4e, 0x53, 0x55, 0x4d, 0x45, 0x10, 0x80, 0x40, 0x12, 0x15, 0x0a, 0x0f, 0x53, 0x4c, 0x49, 0x44,
+	0x49, 0x4e, 0x47, 0x5f, 0x50, 0x45, 0x4e, 0x41, 0x4c, 0x54, 0x59, 0x10, 0x80, 0x80, 0x01, 0x12,
+	0x11, 0x0a, 0x0b, 0x52, 0x45, 0x54, 0x52, 0x59, 0x5f, 0x41, 0x46, 0x54, 0x45, 0x52, 0x10, 0x80,
+	0x80, 0x02, 0x12, 0x14, 0x0a, 0x0e, 0x46, 0x49, 0x52, 0x53, 0x54, 0x5f, 0x48, 0x49, 0x54, 0x5f,
+	0x46, 0x52, 0x45, 0x45, 0x10, 0x80, 0x80, 0x04, 0x12, 0x12, 0x0a, 0x0c, 0x42, 0x41, 0x43, 0x4b,
+	0x4f, 0x46, 0x46, 0x5f, 0x48, 0x49, 0x4e, 0x54, 0x10, 0x80, 0x80, 0x08, 0x12, 0x19, 0x0a, 0x13,
+	0x49, 0x47, 0x4e, 0x4f, 0x52, 0x45, 0x5f, 0x53, 0x54, 0x4f, 0x52, 0x45, 0x5f, 0x45, 0x52, 0x52,
+	0x4f, 0x52, 0x53, 0x10, 0x80, 0x80, 0x10, 0x2a, 0x29, 0x0a, 0x06, 0x53, 0x74, 0x61, 0x74, 0x75,
+	0x73, 0x12, 0x0f, 0x0a, 0x0b, 0x55, 0x4e, 0x44, 0x45, 0x52, 0x5f, 0x4c, 0x49, 0x4d, 0x49, 0x54,
+	0x10, 0x00, 0x12, 0x0e, 0x0a, 0x0a, 0x4f, 0x56, 0x45, 0x52, 0x5f, 0x4c, 0x49, 0x4d, 0x49, 0x54,
+	0x10, 0x01, 0x32, 0xc2, 0x03, 0x0a, 0x02, 0x56, 0x31, 0x12, 0x70, 0x0a, 0x0d, 0x47, 0x65, 0x74,
+	0x52, 0x61, 0x74, 0x65, 0x4c, 0x69, 0x6d, 0x69, 0x74, 0x73, 0x12, 0x1f, 0x2e, 0x70, 0x62, 0x2e,
+	0x67, 0x75, 0x62, 0x65, 0x72, 0x6e, 0x61, 0x74, 0x6f, 0x72, 0x2e, 0x47, 0x65, 0x74, 0x52, 0x61,
+	0x74, 0x65, 0x4c, 0x69, 0x6d, 0x69, 0x74, 0x73, 0x52, 0x65, 0x71, 0x1a, 0x20, 0x2e, 0x70, 0x62,
+	0x2e, 0x67, 0x75, 0x62, 0x65, 0x72, 0x6e, 0x61, 0x74, 0x6f, 0x72, 0x2e, 0x47, 0x65, 0x74, 0x52,
+	0x61, 0x74, 0x65, 0x4c, 0x69, 0x6d, 0x69, 0x74, 0x73, 0x52, 0x65, 0x73, 0x70, 0x22, 0x1c, 0x82,
+	0xd3, 0xe4, 0x93, 0x02, 0x16, 0x22, 0x11, 0x2f, 0x76, 0x31, 0x2f, 0x47, 0x65, 0x74, 0x52, 0x61,
+	0x74, 0x65, 0x4c, 0x69, 0x6d, 0x69, 0x74, 0x73, 0x3a, 0x01, 0x2a, 0x12, 0x7c, 0x0a, 0x13, 0x41,
+	0x74, 0x6f, 0x6d, 0x69, 0x63, 0x47, 0x65, 0x74, 0x52, 0x61, 0x74, 0x65, 0x4c, 0x69, 0x6d, 0x69,
+	0x74, 0x73, 0x12, 0x1f, 0x2e, 0x70, 0x62, 0x2e, 0x67, 0x75, 0x62, 0x65, 0x72, 0x6e, 0x61, 0x74,
+	0x6f, 0x72, 0x2e, 0x47, 0x65, 0x74, 0x52, 0x61, 0x74, 0x65, 0x4c, 0x69, 0x6d, 0x69, 0x74, 0x73,
+	0x52, 0x65, 0x71, 0x1a, 0x20, 0x2e, 0x70, 0x62, 0x2e, 0x67, 0x75, 0x62, 0x65, 0x72, 0x6e, 0x61,
+	0x74, 0x6f, 0x72, 0x2e, 0x47, 0x65, 0x74, 0x52, 0x61, 0x74, 0x65, 0x4c, 0x69, 0x6d, 0x69, 0x74,
+	0x73, 0x52, 0x65, 0x73, 0x70, 0x22, 0x22, 0x82, 0xd3, 0xe4, 0x93, 0x02, 0x1c, 0x22, 0x17, 0x2f,
+	0x76, 0x31, 0x2f, 0x41, 0x74, 0x6f, 0x6d, 0x69, 0x63, 0x47, 0x65, 0x74, 0x52, 0x61, 0x74, 0x65,
+	0x4c, 0x69, 0x6d, 0x69, 0x74, 0x73, 0x3a, 0x01, 0x2a, 0x12, 0x65, 0x0a, 0x0b, 0x48, 0x65, 0x61,
+	0x6c, 0x74, 0x68, 0x43, 0x68, 0x65, 0x63, 0x6b, 0x12, 0x1d, 0x2e, 0x70, 0x62, 0x2e, 0x67, 0x75,
+	0x62, 0x65, 0x72, 0x6e, 0x61, 0x74, 0x6f, 0x72, 0x2e, 0x48, 0x65, 0x61, 0x6c, 0x74, 0x68, 0x43,
+	0x68, 0x65, 0x63, 0x6b, 0x52, 0x65, 0x71, 0x1a, 0x1e, 0x2e, 0x70, 0x62, 0x2e, 0x67, 0x75, 0x62,
+	0x65, 0x72, 0x6e, 0x61, 0x74, 0x6f, 0x72, 0x2e, 0x48, 0x65, 0x61, 0x6c, 0x74, 0x68, 0x43, 0x68,
+	0x65, 0x63, 0x6b, 0x52, 0x65, 0x73, 0x70, 0x22, 0x17, 0x82, 0xd3, 0xe4, 0x93, 0x02, 0x11, 0x12,
+	0x0f, 0x2f, 0x76, 0x31, 0x2f, 0x48, 0x65, 0x61, 0x6c, 0x74, 0x68, 0x43, 0x68, 0x65, 0x63, 0x6b,
+	0x12, 0x65, 0x0a, 0x0b, 0x47, 0x65, 0x74, 0x50, 0x65, 0x65, 0x72, 0x49, 0x6e, 0x66, 0x6f, 0x12,
+	0x1d, 0x2e, 0x70, 0x62, 0x2e, 0x67, 0x75, 0x62, 0x65, 0x72, 0x6e, 0x61, 0x74, 0x6f, 0x72, 0x2e,
+	0x47, 0x65, 0x74, 0x50, 0x65, 0x65, 0x72, 0x49, 0x6e, 0x66, 0x6f, 0x52, 0x65, 0x71, 0x1a, 0x1e,
+	0x2e, 0x70, 0x62, 0x2e, 0x67, 0x75, 0x62, 0x65, 0x72, 0x6e, 0x61, 0x74, 0x6f, 0x72, 0x2e, 0x47,
+	0x65, 0x74, 0x50, 0x65, 0x65, 0x72, 0x49, 0x6e, 0x66, 0x6f, 0x52, 0x65, 0x73, 0x70, 0x22, 0x17,
+	0x82, 0xd3, 0xe4, 0x93, 0x02, 0x11, 0x12, 0x0f, 0x2f, 0x76, 0x31, 0x2f, 0x47, 0x65, 0x74, 0x50,
+	0x65, 0x65, 0x72, 0x49, 0x6e, 0x66, 0x6f, 0x42, 0x22, 0x5a, 0x1d, 0x67, 0x69, 0x74, 0x68, 0x75,
+	0x62, 0x2e, 0x63, 0x6f, 0x6d, 0x2f, 0x6d, 0x61, 0x69, 0x6c, 0x67, 0x75, 0x6e, 0x2f, 0x67, 0x75,
+	0x62, 0x65, 0x72, 0x6e, 0x61, 0x74, 0x6f, 0x72, 0x80, 0x01, 0x01, 0x62, 0x06, 0x70, 0x72, 0x6f,
+	0x74, 0x6f, 0x33,
 }
 
 var (
@@ -754,36 +1625,59 @@ func file_gubernator_proto_rawDescGZIP() []byte {
 	return file_gubernator_proto_rawDescData
 }
 
-var file_gubernator_proto_enumTypes = make([]protoimpl.EnumInfo, 3)
-var file_gubernator_proto_msgTypes = make([]protoimpl.MessageInfo, 7)
+var file_gubernator_proto_enumTypes = make([]protoimpl.EnumInfo, 4)
+var file_gubernator_proto_msgTypes = make([]protoimpl.MessageInfo, 13)
 var file_gubernator_proto_goTypes = []interface{}{
-	(Algorithm)(0),            // 0: pb.gubernator.Algorithm
-	(Behavior)(0),             // 1: pb.gubernator.Behavior
-	(Status)(0),               // 2: pb.gubernator.Status
-	(*GetRateLimitsReq)(nil),  // 3: pb.gubernator.GetRateLimitsReq
-	(*GetRateLimitsResp)(nil), // 4: pb.gubernator.GetRateLimitsResp
-	(*RateLimitReq)(nil),      // 5: pb.gubernator.RateLimitReq
-	(*RateLimitResp)(nil),     // 6: pb.gubernator.RateLimitResp
-	(*HealthCheckReq)(nil),    // 7: pb.gubernator.HealthCheckReq
-	(*HealthCheckResp)(nil),   // 8: pb.gubernator.HealthCheckResp
-	nil,                       // 9: pb.gubernator.RateLimitResp.MetadataEntry
+	(Algorithm)(0),                // 0: pb.gubernator.Algorithm
+	(LeakProfile)(0),              // 1: pb.gubernator.LeakProfile
+	(Behavior)(0),                 // 2: pb.gubernator.Behavior
+	(Status)(0),                   // 3: pb.gubernator.Status
+	(*GetRateLimitsReq)(nil),      // 4: pb.gubernator.GetRateLimitsReq
+	(*GetRateLimitsResp)(nil),     // 5: pb.gubernator.GetRateLimitsResp
+	(*RateLimitReq)(nil),          // 6: pb.gubernator.RateLimitReq
+	(*ScheduleWindow)(nil),        // 7: pb.gubernator.ScheduleWindow
+	(*RateLimitResp)(nil),         // 8: pb.gubernator.RateLimitResp
+	(*RateLimitConfig)(nil),       // 9: pb.gubernator.RateLimitConfig
+	(*HealthCheckReq)(nil),        // 10: pb.gubernator.HealthCheckReq
+	(*HealthCheckResp)(nil),       // 11: pb.gubernator.HealthCheckResp
+	(*GetPeerInfoReq)(nil),        // 12: pb.gubernator.GetPeerInfoReq
+	(*GetPeerInfoResp)(nil),       // 13: pb.gubernator.GetPeerInfoResp
+	(*PeerDetails)(nil),           // 14: pb.gubernator.PeerDetails
+	nil,                           // 15: pb.gubernator.RateLimitReq.MetadataEntry
+	nil,                           // 16: pb.gubernator.RateLimitResp.MetadataEntry
+	(*timestamppb.Timestamp)(nil), // 17: google.protobuf.Timestamp
 }
 var file_gubernator_proto_depIdxs = []int32{
-	5, // 0: pb.gubernator.GetRateLimitsReq.requests:type_name -> pb.gubernator.RateLimitReq
-	6, // 1: pb.gubernator.GetRateLimitsResp.responses:type_name -> pb.gubernator.RateLimitResp
-	0, // 2: pb.gubernator.RateLimitReq.algorithm:type_name -> pb.gubernator.Algorithm
-	1, // 3: pb.gubernator.RateLimitReq.behavior:type_name -> pb.gubernator.Behavior
-	2, // 4: pb.gubernator.RateLimitResp.status:type_name -> pb.gubernator.Status
-	9, // 5: pb.gubernator.RateLimitResp.metadata:type_name -> pb.gubernator.RateLimitResp.MetadataEntry
-	3, // 6: pb.gubernator.V1.GetRateLimits:input_type -> pb.gubernator.GetRateLimitsReq
-	7, // 7: pb.gubernator.V1.HealthCheck:input_type -> pb.gubernator.HealthCheckReq
-	4, // 8: pb.gubernator.V1.GetRateLimits:output_type -> pb.gubernator.GetRateLimitsResp
-	8, // 9: pb.gubernator.V1.HealthCheck:output_type -> pb.gubernator.HealthCheckResp
-	8, // [8:10] is the sub-list for method output_type
-	6, // [6:8] is the sub-list for method input_type
-	6, // [6:6] is the sub-list for extension type_name
-	6, // [6:6] is the sub-list for extension extendee
-	0, // [0:6] is the sub-list for field type_name
+	6,  // 0: pb.gubernator.GetRateLimitsReq.requests:type_name -> pb.gubernator.RateLimitReq
+	8,  // 1: pb.gubernator.GetRateLimitsResp.responses:type_name -> pb.gubernator.RateLimitResp
+	0,  // 2: pb.gubernator.RateLimitReq.algorithm:type_name -> pb.gubernator.Algorithm
+	2,  // 3: pb.gubernator.RateLimitReq.behavior:type_name -> pb.gubernator.Behavior
+	15, // 4: pb.gubernator.RateLimitReq.metadata:type_name -> pb.gubernator.RateLimitReq.MetadataEntry
+	6,  // 5: pb.gubernator.RateLimitReq.parents:type_name -> pb.gubernator.RateLimitReq
+	1,  // 6: pb.gubernator.RateLimitReq.leak_profile:type_name -> pb.gubernator.LeakProfile
+	7,  // 7: pb.gubernator.RateLimitReq.schedule:type_name -> pb.gubernator.ScheduleWindow
+	3,  // 8: pb.gubernator.RateLimitResp.status:type_name -> pb.gubernator.Status
+	16, // 9: pb.gubernator.RateLimitResp.metadata:type_name -> pb.gubernator.RateLimitResp.MetadataEntry
+	0,  // 10: pb.gubernator.RateLimitResp.algorithm:type_name -> pb.gubernator.Algorithm
+	9,  // 11: pb.gubernator.RateLimitResp.config:type_name -> pb.gubernator.RateLimitConfig
+	17, // 12: pb.gubernator.RateLimitResp.reset_timestamp:type_name -> google.protobuf.Timestamp
+	8,  // 13: pb.gubernator.RateLimitResp.parents:type_name -> pb.gubernator.RateLimitResp
+	0,  // 14: pb.gubernator.RateLimitConfig.algorithm:type_name -> pb.gubernator.Algorithm
+	14, // 15: pb.gubernator.GetPeerInfoResp.peers:type_name -> pb.gubernator.PeerDetails
+	14, // 16: pb.gubernator.GetPeerInfoResp.owner:type_name -> pb.gubernator.PeerDetails
+	4,  // 17: pb.gubernator.V1.GetRateLimits:input_type -> pb.gubernator.GetRateLimitsReq
+	4,  // 18: pb.gubernator.V1.AtomicGetRateLimits:input_type -> pb.gubernator.GetRateLimitsReq
+	10, // 19: pb.gubernator.V1.HealthCheck:input_type -> pb.gubernator.HealthCheckReq
+	12, // 20: pb.gubernator.V1.GetPeerInfo:input_type -> pb.gubernator.GetPeerInfoReq
+	5,  // 21: pb.gubernator.V1.GetRateLimits:output_type -> pb.gubernator.GetRateLimitsResp
+	5,  // 22: pb.gubernator.V1.AtomicGetRateLimits:output_type -> pb.gubernator.GetRateLimitsResp
+	11, // 23: pb.gubernator.V1.HealthCheck:output_type -> pb.gubernator.HealthCheckResp
+	13, // 24: pb.gubernator.V1.GetPeerInfo:output_type -> pb.gubernator.GetPeerInfoResp
+	21, // [21:25] is the sub-list for method output_type
+	17, // [17:21] is the sub-list for method input_type
+	17, // [17:17] is the sub-list for extension type_name
+	17, // [17:17] is the sub-list for extension extendee
+	0,  // [0:17] is the sub-list for field type_name
 }
 
 func init() { file_gubernator_proto_init() }
@@ -829,7 +1723,7 @@ func file_gubernator_proto_init() {
 			}
 		}
 		file_gubernator_proto_msgTypes[3].Exporter = func(v interface{}, i int) interface{} {
-			switch v := v.(*RateLimitResp); i {
+			switch v := v.(*ScheduleWindow); i {
 			case 0:
 				return &v.state
 			case 1:
@@ -841,7 +1735,7 @@ func file_gubernator_proto_init() {
 			}
 		}
 		file_gubernator_proto_msgTypes[4].Exporter = func(v interface{}, i int) interface{} {
-			switch v := v.(*HealthCheckReq); i {
+			switch v := v.(*RateLimitResp); i {
 			case 0:
 				return &v.state
 			case 1:
@@ -853,6 +1747,30 @@ func file_gubernator_proto_init() {
 			}
 		}
 		file_gubernator_proto_msgTypes[5].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*RateLimitConfig); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_gubernator_proto_msgTypes[6].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*HealthCheckReq); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_gubernator_proto_msgTypes[7].Exporter = func(v interface{}, i int) interface{} {
 			switch v := v.(*HealthCheckResp); i {
 			case 0:
 				return &v.state
@@ -864,14 +1782,50 @@ func file_gubernator_proto_init() {
 				return nil
 			}
 		}
+		file_gubernator_proto_msgTypes[8].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*GetPeerInfoReq); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_gubernator_proto_msgTypes[9].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*GetPeerInfoResp); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_gubernator_proto_msgTypes[10].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*PeerDetails); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
 	}
 	type x struct{}
 	out := protoimpl.TypeBuilder{
 		File: protoimpl.DescBuilder{
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: file_gubernator_proto_rawDesc,
-			NumEnums:      3,
-			NumMessages:   7,
+			NumEnums:      4,
+			NumMessages:   13,
 			NumExtensions: 0,
 			NumServices:   1,
 		},
@@ -20,9 +20,17 @@ const _ = grpc.SupportPackageIsVersion7
 type V1Client interface {
 	// Given a list of rate limit requests, return the rate limits of each.
 	GetRateLimits(ctx context.Context, in *GetRateLimitsReq, opts ...grpc.CallOption) (*GetRateLimitsResp, error)
+	// Given a list of rate limit requests, apply the hits of every request only if each of the rate limits
+	// would be UNDER_LIMIT. If any of the rate limits would be OVER_LIMIT no hits are applied, and the
+	// responses report the status of each rate limit as if the hits were applied.
+	AtomicGetRateLimits(ctx context.Context, in *GetRateLimitsReq, opts ...grpc.CallOption) (*GetRateLimitsResp, error)
 	// This method is for round trip benchmarking and can be used by
 	// the client to determine connectivity to the server
 	HealthCheck(ctx context.Context, in *HealthCheckReq, opts ...grpc.CallOption) (*HealthCheckResp, error)
+	// Returns the peers known to this instance and, if a name and unique key are
+	// provided, the peer which owns the rate limit. Use this to debug which peer
+	// requests for a rate limit are routed to.
+	GetPeerInfo(ctx context.Context, in *GetPeerInfoReq, opts ...grpc.CallOption) (*GetPeerInfoResp, error)
 }
 
 type v1Client struct {
@@ -42,6 +50,15 @@ func (c *v1Client) GetRateLimits(ctx context.Context, in *GetRateLimitsReq, opts
 	return out, nil
 }
 
+func (c *v1Client) AtomicGetRateLimits(ctx context.Context, in *GetRateLimitsReq, opts ...grpc.CallOption) (*GetRateLimitsResp, error) {
+	out := new(GetRateLimitsResp)
+	err := c.cc.Invoke(ctx, "/pb.gubernator.V1/AtomicGetRateLimits", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 func (c *v1Client) HealthCheck(ctx context.Context, in *HealthCheckReq, opts ...grpc.CallOption) (*HealthCheckResp, error) {
 	out := new(HealthCheckResp)
 	err := c.cc.Invoke(ctx, "/pb.gubernator.V1/HealthCheck", in, out, opts...)
@@ -51,15 +68,32 @@ func (c *v1Client) HealthCheck(ctx context.Context, in *HealthCheckReq, opts ...
 	return out, nil
 }
 
+func (c *v1Client) GetPeerInfo(ctx context.Context, in *GetPeerInfoReq, opts ...grpc.CallOption) (*GetPeerInfoResp, error) {
+	out := new(GetPeerInfoResp)
+	err := c.cc.Invoke(ctx, "/pb.gubernator.V1/GetPeerInfo", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // V1Server is the server API for V1 service.
 // All implementations must embed UnimplementedV1Server
 // for forward compatibility
 type V1Server interface {
 	// Given a list of rate limit requests, return the rate limits of each.
 	GetRateLimits(context.Context, *GetRateLimitsReq) (*GetRateLimitsResp, error)
+	// Given a list of rate limit requests, apply the hits of every request only if each of the rate limits
+	// would be UNDER_LIMIT. If any of the rate limits would be OVER_LIMIT no hits are applied, and the
+	// responses report the status of each rate limit as if the hits were applied.
+	AtomicGetRateLimits(context.Context, *GetRateLimitsReq) (*GetRateLimitsResp, error)
 	// This method is for round trip benchmarking and can be used by
 	// the client to determine connectivity to the server
 	HealthCheck(context.Context, *HealthCheckReq) (*HealthCheckResp, error)
+	// Returns the peers known to this instance and, if a name and unique key are
+	// provided, the peer which owns the rate limit. Use this to debug which peer
+	// requests for a rate limit are routed to.
+	GetPeerInfo(context.Context, *GetPeerInfoReq) (*GetPeerInfoResp, error)
 	mustEmbedUnimplementedV1Server()
 }
 
@@ -70,9 +104,15 @@ type UnimplementedV1Server struct {
 func (UnimplementedV1Server) GetRateLimits(context.Context, *GetRateLimitsReq) (*GetRateLimitsResp, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method GetRateLimits not implemented")
 }
+func (UnimplementedV1Server) AtomicGetRateLimits(context.Context, *GetRateLimitsReq) (*GetRateLimitsResp, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method AtomicGetRateLimits not implemented")
+}
 func (UnimplementedV1Server) HealthCheck(context.Context, *HealthCheckReq) (*HealthCheckResp, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method HealthCheck not implemented")
 }
+func (UnimplementedV1Server) GetPeerInfo(context.Context, *GetPeerInfoReq) (*GetPeerInfoResp, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetPeerInfo not implemented")
+}
 func (UnimplementedV1Server) mustEmbedUnimplementedV1Server() {}
 
 // UnsafeV1Server may be embedded to opt out of forward compatibility for this service.
@@ -104,6 +144,24 @@ func _V1_GetRateLimits_Handler(srv interface{}, ctx context.Context, dec func(in
 	return interceptor(ctx, in, info, handler)
 }
 
+func _V1_AtomicGetRateLimits_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetRateLimitsReq)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(V1Server).AtomicGetRateLimits(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/pb.gubernator.V1/AtomicGetRateLimits",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(V1Server).AtomicGetRateLimits(ctx, req.(*GetRateLimitsReq))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 func _V1_HealthCheck_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
 	in := new(HealthCheckReq)
 	if err := dec(in); err != nil {
@@ -122,6 +180,24 @@ func _V1_HealthCheck_Handler(srv interface{}, ctx context.Context, dec func(inte
 	return interceptor(ctx, in, info, handler)
 }
 
+func _V1_GetPeerInfo_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetPeerInfoReq)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(V1Server).GetPeerInfo(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/pb.gubernator.V1/GetPeerInfo",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(V1Server).GetPeerInfo(ctx, req.(*GetPeerInfoReq))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // V1_ServiceDesc is the grpc.ServiceDesc for V1 service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -133,10 +209,18 @@ var V1_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "GetRateLimits",
 			Handler:    _V1_GetRateLimits_Handler,
 		},
+		{
+			MethodName: "AtomicGetRateLimits",
+			Handler:    _V1_AtomicGetRateLimits_Handler,
+		},
 		{
 			MethodName: "HealthCheck",
 			Handler:    _V1_HealthCheck_Handler,
 		},
+		{
+			MethodName: "GetPeerInfo",
+			Handler:    _V1_GetPeerInfo_Handler,
+		},
 	},
 	Streams:  []grpc.StreamDesc{},
 	Metadata: "gubernator.proto",
@@ -43,6 +43,7 @@ import (
 	"strconv"
 	"sync"
 	"sync/atomic"
+	"time"
 
 	"github.com/OneOfOne/xxhash"
 	"github.com/mailgun/holster/v4/errors"
@@ -51,6 +52,7 @@ import (
 	"github.com/sirupsen/logrus"
 	"go.opentelemetry.io/otel/attribute"
 	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/protobuf/proto"
 )
 
 type GubernatorPool struct {
@@ -59,7 +61,9 @@ type GubernatorPool struct {
 	hasher          ipoolHasher
 	hashRingStep    uint64
 	conf            *Config
+	log             FieldLogger
 	done            chan struct{}
+	wg              sync.WaitGroup
 }
 
 type poolWorker struct {
@@ -71,6 +75,14 @@ type poolWorker struct {
 	loadRequest         chan poolLoadRequest
 	addCacheItemRequest chan poolAddCacheItemRequest
 	getCacheItemRequest chan poolGetCacheItemRequest
+	setRemainingRequest chan poolSetRemainingRequest
+	deleteRequest       chan poolDeleteRequest
+	removeRequest       chan poolRemoveRequest
+	importRequest       chan poolImportRequest
+	// The responses remembered for requests with an `idempotency_key`
+	responses *expiringMap
+	// The consecutive rejections of rate limits with `Behavior_BACKOFF_HINT`
+	rejections *expiringMap
 }
 
 type ipoolHasher interface {
@@ -120,6 +132,48 @@ type poolGetCacheItemResponse struct {
 	ok   bool
 }
 
+type poolSetRemainingRequest struct {
+	ctx      context.Context
+	response chan poolSetRemainingResponse
+	request  *SetRemainingReq
+}
+
+type poolSetRemainingResponse struct {
+	rl  *RateLimitResp
+	err error
+}
+
+type poolDeleteRequest struct {
+	ctx      context.Context
+	response chan poolDeleteResponse
+	request  *DeleteRateLimitReq
+}
+
+type poolDeleteResponse struct {
+	found bool
+}
+
+type poolRemoveRequest struct {
+	ctx      context.Context
+	response chan poolRemoveResponse
+	match    func(key string) bool
+}
+
+type poolRemoveResponse struct {
+	keys []string
+}
+
+type poolImportRequest struct {
+	ctx      context.Context
+	response chan poolImportResponse
+	snapshot *CacheItemSnapshot
+}
+
+type poolImportResponse struct {
+	rl  *RateLimitResp
+	err error
+}
+
 var _ io.Closer = &GubernatorPool{}
 var _ ipoolHasher = &poolHasher{}
 
@@ -127,6 +181,10 @@ var poolWorkerCounter int64
 
 func NewGubernatorPool(conf *Config, concurrency int, cacheSize int) *GubernatorPool {
 	setter.SetDefault(&cacheSize, 50_000)
+	// Each worker must have room for at least one rate limit
+	if cacheSize < concurrency {
+		cacheSize = concurrency
+	}
 
 	// Compute hashRingStep as interval between workers' 63-bit hash ranges.
 	// 64th bit is used here as a max value that is just out of range of 63-bit space to calculate the step.
@@ -136,13 +194,19 @@ func NewGubernatorPool(conf *Config, concurrency int, cacheSize int) *Gubernator
 		hasher:          newPoolHasher(),
 		hashRingStep:    uint64(1<<63) / uint64(concurrency),
 		conf:            conf,
+		log:             conf.Logger,
 		done:            make(chan struct{}),
 	}
+	setter.SetDefault(&chp.log, logrus.WithField("category", "gubernator"))
 
 	// Create workers.
 	for i := 0; i < concurrency; i++ {
 		chp.workers[i] = chp.newWorker()
-		go chp.worker(chp.workers[i])
+		chp.wg.Add(1)
+		go func(worker *poolWorker) {
+			defer chp.wg.Done()
+			chp.worker(worker)
+		}(chp.workers[i])
 	}
 
 	return chp
@@ -156,8 +220,11 @@ func (ph *poolHasher) ComputeHash63(input string) uint64 {
 	return xxhash.ChecksumString64S(input, 0) >> 1
 }
 
+// Close stops the workers and waits for them to exit, such that the cache is no longer
+// touched once Close returns.
 func (chp *GubernatorPool) Close() error {
 	close(chp.done)
+	chp.wg.Wait()
 	return nil
 }
 
@@ -172,6 +239,12 @@ func (chp *GubernatorPool) newWorker() *poolWorker {
 		loadRequest:         make(chan poolLoadRequest, commandChannelSize),
 		addCacheItemRequest: make(chan poolAddCacheItemRequest, commandChannelSize),
 		getCacheItemRequest: make(chan poolGetCacheItemRequest, commandChannelSize),
+		setRemainingRequest: make(chan poolSetRemainingRequest, commandChannelSize),
+		deleteRequest:       make(chan poolDeleteRequest, commandChannelSize),
+		removeRequest:       make(chan poolRemoveRequest, commandChannelSize),
+		importRequest:       make(chan poolImportRequest, commandChannelSize),
+		responses:           newExpiringMap(chp.workerCacheSize),
+		rejections:          newExpiringMap(chp.workerCacheSize),
 	}
 	workerNumber := atomic.AddInt64(&poolWorkerCounter, 1) - 1
 	worker.name = strconv.FormatInt(workerNumber, 10)
@@ -189,8 +262,19 @@ func (chp *GubernatorPool) getWorker(key string) *poolWorker {
 // Pool worker for processing Gubernator requests.
 // Each worker maintains its own state.
 // A hash ring will distribute requests to an assigned worker by key.
+// Since requests for a rate limit are handled one at a time by the same
+// worker, a cache miss results in a single Store.Get() for the rate limit
+// however many requests for it are in flight; the rest find it in the cache.
 // See: getWorker()
 func (chp *GubernatorPool) worker(worker *poolWorker) {
+	// Expired items are removed by the worker to avoid concurrent access to the cache
+	var expire <-chan time.Time
+	if _, ok := worker.cache.(ExpiringCache); ok && chp.conf.CacheExpireInterval != 0 {
+		tick := time.NewTicker(chp.conf.CacheExpireInterval)
+		defer tick.Stop()
+		expire = tick.C
+	}
+
 	for {
 		// Dispatch requests from each channel.
 		select {
@@ -201,7 +285,7 @@ func (chp *GubernatorPool) worker(worker *poolWorker) {
 				return
 			}
 
-			chp.handleGetRateLimit(req, worker.cache)
+			chp.handleGetRateLimit(req, worker)
 
 		case req, ok := <-worker.storeRequest:
 			if !ok {
@@ -239,6 +323,45 @@ func (chp *GubernatorPool) worker(worker *poolWorker) {
 
 			chp.handleGetCacheItem(req, worker.cache)
 
+		case req, ok := <-worker.setRemainingRequest:
+			if !ok {
+				// Channel closed.  Unexpected, but should be handled.
+				logrus.Error("checkHandlerPool worker stopped because channel closed")
+				return
+			}
+
+			chp.handleSetRemaining(req, worker.cache)
+
+		case req, ok := <-worker.deleteRequest:
+			if !ok {
+				// Channel closed.  Unexpected, but should be handled.
+				logrus.Error("checkHandlerPool worker stopped because channel closed")
+				return
+			}
+
+			chp.handleDelete(req, worker.cache)
+
+		case req, ok := <-worker.removeRequest:
+			if !ok {
+				// Channel closed.  Unexpected, but should be handled.
+				logrus.Error("checkHandlerPool worker stopped because channel closed")
+				return
+			}
+
+			chp.handleRemove(req, worker.cache)
+
+		case req, ok := <-worker.importRequest:
+			if !ok {
+				// Channel closed.  Unexpected, but should be handled.
+				logrus.Error("checkHandlerPool worker stopped because channel closed")
+				return
+			}
+
+			chp.handleImport(req, worker.cache)
+
+		case <-expire:
+			worker.cache.(ExpiringCache).RemoveExpired()
+
 		case <-chp.done:
 			// Clean up.
 			return
@@ -254,8 +377,9 @@ func (chp *GubernatorPool) GetRateLimit(ctx context.Context, rlRequest *RateLimi
 	}()
 	span := trace.SpanFromContext(ctx)
 
-	// Delegate request to assigned channel based on request key.
-	worker := chp.getWorker(rlRequest.UniqueKey)
+	// Delegate request to assigned channel based on request key. Must pick the same
+	// worker as AddCacheItem() and GetCacheItem() do for the rate limit.
+	worker := chp.getWorker(rlRequest.HashKey())
 	handlerRequest := &request{
 		ctx:     ctx,
 		resp:    make(chan *response, 1),
@@ -288,16 +412,49 @@ func (chp *GubernatorPool) GetRateLimit(ctx context.Context, rlRequest *RateLimi
 }
 
 // Handle request received by worker.
-func (chp *GubernatorPool) handleGetRateLimit(handlerRequest *request, cache Cache) {
+func (chp *GubernatorPool) handleGetRateLimit(handlerRequest *request, worker *poolWorker) {
 	ctx := tracing.StartScopeDebug(handlerRequest.ctx)
 	defer tracing.EndScope(ctx, nil)
 
+	cache := worker.cache
 	var rlResponse *RateLimitResp
 	var err error
 
+	// Avoid consulting the store on behalf of a request which is no longer waiting
+	if ctx.Err() != nil {
+		return
+	}
+
+	// The limit of the time of day applies. The window is chosen once, such that the limit and the window
+	// recorded with the rate limit agree should the clock cross into another window meanwhile.
+	var segment int32
+	handlerRequest.request, segment = scheduledRequest(handlerRequest.request)
+
+	// A retried request returns the response to the original request
+	idempotent := handlerRequest.request.IdempotencyKey != "" &&
+		!HasBehavior(handlerRequest.request.Behavior, Behavior_PEEK)
+	if idempotent {
+		if v, ok := worker.responses.Get(idempotencyKey(handlerRequest.request)); ok {
+			rl := v.(*RateLimitResp)
+			chp.respond(ctx, handlerRequest, &response{rl: proto.Clone(rl).(*RateLimitResp)})
+			return
+		}
+	}
+
+	store := chp.conf.Store
+	if HasBehavior(handlerRequest.request.Behavior, Behavior_NO_STORE) {
+		store = nil
+	}
+	if HasBehavior(handlerRequest.request.Behavior, Behavior_PEEK) {
+		// Compute the result of the hits without changing the rate limit
+		cache = newPeekCache(cache)
+		store = newPeekStore(store)
+	}
+
+	policy := storePolicy{onError: chp.conf.StoreErrorPolicy, reconcile: chp.conf.StoreReconcilePolicy}
 	switch handlerRequest.request.Algorithm {
 	case Algorithm_TOKEN_BUCKET:
-		rlResponse, err = tokenBucket(ctx, chp.conf.Store, cache, handlerRequest.request)
+		rlResponse, err = tokenBucket(ctx, chp.log, store, cache, handlerRequest.request, chp.conf.DisableAlgorithmMigration, policy, segment)
 		if err != nil {
 			msg := "Error in tokenBucket"
 			countError(err, msg)
@@ -306,7 +463,7 @@ func (chp *GubernatorPool) handleGetRateLimit(handlerRequest *request, cache Cac
 		}
 
 	case Algorithm_LEAKY_BUCKET:
-		rlResponse, err = leakyBucket(ctx, chp.conf.Store, cache, handlerRequest.request)
+		rlResponse, err = leakyBucket(ctx, chp.log, store, cache, handlerRequest.request, chp.conf.DisableAlgorithmMigration, policy, segment)
 		if err != nil {
 			msg := "Error in leakyBucket"
 			countError(err, msg)
@@ -314,24 +471,105 @@ func (chp *GubernatorPool) handleGetRateLimit(handlerRequest *request, cache Cac
 			trace.SpanFromContext(ctx).RecordError(err)
 		}
 
+	case Algorithm_HYBRID:
+		rlResponse, err = hybridBucket(ctx, chp.log, store, cache, handlerRequest.request, chp.conf.DisableAlgorithmMigration, policy, segment)
+		if err != nil {
+			msg := "Error in hybridBucket"
+			countError(err, msg)
+			err = errors.Wrap(err, msg)
+			trace.SpanFromContext(ctx).RecordError(err)
+		}
+
 	default:
 		err = errors.Errorf("Invalid rate limit algorithm '%d'", handlerRequest.request.Algorithm)
 		trace.SpanFromContext(ctx).RecordError(err)
 		checkErrorCounter.WithLabelValues("Invalid algorithm").Add(1)
 	}
 
-	handlerResponse := &response{
+	if err == nil && HasBehavior(handlerRequest.request.Behavior, Behavior_RETURN_CONFIG) {
+		if item, ok := cache.GetItem(handlerRequest.request.HashKey()); ok {
+			rlResponse.Config = cacheItemToConfig(item)
+		}
+	}
+
+	if err == nil && HasBehavior(handlerRequest.request.Behavior, Behavior_RETURN_TOTAL_HITS) {
+		if item, ok := cache.GetItem(handlerRequest.request.HashKey()); ok {
+			rlResponse.TotalHits = cacheItemTotalHits(item)
+		}
+	}
+
+	if err == nil && HasBehavior(handlerRequest.request.Behavior, Behavior_BACKOFF_HINT) {
+		chp.suggestRetryAfter(worker.rejections, handlerRequest.request, rlResponse)
+	}
+
+	if err == nil && idempotent {
+		worker.responses.Set(idempotencyKey(handlerRequest.request), proto.Clone(rlResponse),
+			MillisecondNow()+chp.conf.IdempotencyTTL.Milliseconds())
+	}
+
+	chp.respond(ctx, handlerRequest, &response{
 		rl:  rlResponse,
 		err: err,
-	}
+	})
+}
 
+func (chp *GubernatorPool) respond(ctx context.Context, handlerRequest *request, handlerResponse *response) {
 	select {
 	case handlerRequest.resp <- handlerResponse:
 		// Success.
 
 	case <-ctx.Done():
 		// Context canceled.
-		trace.SpanFromContext(ctx).RecordError(err)
+		trace.SpanFromContext(ctx).RecordError(handlerResponse.err)
+	}
+}
+
+// idempotencyKey returns the key of the response remembered for a request with an `idempotency_key`.
+// The NUL separator keeps the idempotency key apart from the hash key of the rate limit.
+func idempotencyKey(r *RateLimitReq) string {
+	return r.HashKey() + "\x00" + r.IdempotencyKey
+}
+
+// suggestRetryAfter counts the consecutive rejections of the rate limit and provides an OVER_LIMIT
+// response with the milliseconds until the reset plus a delay which doubles with each rejection.
+// The count is forgotten once a request is UNDER_LIMIT, or when no rejection follows the suggestion
+// within `BackoffHintMax`. A `Behavior_PEEK` request is given the suggestion without being counted.
+func (chp *GubernatorPool) suggestRetryAfter(counts *expiringMap, r *RateLimitReq, rl *RateLimitResp) {
+	key := r.HashKey()
+	peek := HasBehavior(r.Behavior, Behavior_PEEK)
+	if rl.Status != Status_OVER_LIMIT {
+		if !peek {
+			counts.Remove(key)
+		}
+		return
+	}
+
+	var rejections int64
+	if v, ok := counts.Get(key); ok {
+		rejections = v.(int64)
+	}
+	rejections++
+
+	step := chp.conf.BackoffHintStep.Milliseconds()
+	ceiling := chp.conf.BackoffHintMax.Milliseconds()
+	delay := ceiling
+	// Avoid overflowing the shift, the delay is capped long before
+	if rejections <= 32 {
+		delay = step << (rejections - 1)
+	}
+	if delay > ceiling || delay < 0 {
+		delay = ceiling
+	}
+
+	now := MillisecondNow()
+	wait := rl.ResetTime - now
+	if wait < 0 {
+		wait = 0
+	}
+	rl.SuggestedRetryAfter = wait + delay
+
+	if !peek {
+		counts.Set(key, rejections, now+rl.SuggestedRetryAfter+ceiling)
 	}
 }
 
@@ -349,6 +587,27 @@ func (chp *GubernatorPool) Load(ctx context.Context) (reterr error) {
 		return errors.Wrap(err, "Error in loader.Load")
 	}
 
+	return chp.loadItems(ctx, ch)
+}
+
+// Atomically warm the cache from a store which implements BulkStore.
+// Workers are locked during this load operation to prevent race conditions.
+func (chp *GubernatorPool) LoadStore(ctx context.Context, store BulkStore) (reterr error) {
+	ctx = tracing.StartScope(ctx)
+	defer func() {
+		tracing.EndScope(ctx, reterr)
+	}()
+
+	ch, err := store.LoadAll()
+	if err != nil {
+		return errors.Wrap(err, "Error in store.LoadAll")
+	}
+
+	return chp.loadItems(ctx, ch)
+}
+
+// Read each item from the channel and load into each appropriate worker's cache.
+func (chp *GubernatorPool) loadItems(ctx context.Context, ch chan *CacheItem) error {
 	type loadChannel struct {
 		ch       chan *CacheItem
 		worker   *poolWorker
@@ -471,6 +730,22 @@ func (chp *GubernatorPool) Store(ctx context.Context) (reterr error) {
 		tracing.EndScope(ctx, reterr)
 	}()
 
+	out := chp.each(ctx)
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+
+	err := chp.conf.Loader.Save(out)
+	if err != nil {
+		return errors.Wrap(err, "error in chp.conf.Loader.Save")
+	}
+
+	return nil
+}
+
+// Iterate each worker's cache to the returned channel, which is closed once every
+// item has been sent. Workers are locked while their cache is iterated.
+func (chp *GubernatorPool) each(ctx context.Context) chan *CacheItem {
 	var wg sync.WaitGroup
 	out := make(chan *CacheItem, 500)
 
@@ -518,16 +793,7 @@ func (chp *GubernatorPool) Store(ctx context.Context) (reterr error) {
 		close(out)
 	}()
 
-	if ctx.Err() != nil {
-		return ctx.Err()
-	}
-
-	err := chp.conf.Loader.Save(out)
-	if err != nil {
-		return errors.Wrap(err, "error in chp.conf.Loader.Save")
-	}
-
-	return nil
+	return out
 }
 
 func (chp *GubernatorPool) handleStore(request poolStoreRequest, cache Cache) {
@@ -651,7 +917,11 @@ func (chp *GubernatorPool) handleGetCacheItem(request poolGetCacheItemRequest, c
 	ctx := tracing.StartScope(request.ctx)
 	defer tracing.EndScope(ctx, nil)
 
+	// The caller must not see changes the worker makes to the item after it is returned
 	item, ok := cache.GetItem(request.key)
+	if ok {
+		item = copyCacheItem(item)
+	}
 	response := poolGetCacheItemResponse{item, ok}
 
 	select {
@@ -663,3 +933,241 @@ func (chp *GubernatorPool) handleGetCacheItem(request poolGetCacheItemRequest, c
 		trace.SpanFromContext(ctx).RecordError(ctx.Err())
 	}
 }
+
+// Set the remaining hits of a rate limit in the worker's cache.
+func (chp *GubernatorPool) SetRemaining(ctx context.Context, r *SetRemainingReq) (retval *RateLimitResp, reterr error) {
+	ctx = tracing.StartScope(ctx)
+	defer func() {
+		tracing.EndScope(ctx, reterr)
+	}()
+
+	respChan := make(chan poolSetRemainingResponse)
+	// Must pick the same worker as GetRateLimit() does for the rate limit
+	worker := chp.getWorker(r.Name + "_" + r.UniqueKey)
+	req := poolSetRemainingRequest{
+		ctx:      ctx,
+		response: respChan,
+		request:  r,
+	}
+
+	select {
+	case worker.setRemainingRequest <- req:
+		// Successfully sent request.
+		poolWorkerQueueLength.WithLabelValues("SetRemaining", worker.name).Observe(float64(len(worker.setRemainingRequest)))
+
+		select {
+		case resp := <-respChan:
+			// Successfully received response.
+			return resp.rl, resp.err
+
+		case <-ctx.Done():
+			// Context canceled.
+			return nil, ctx.Err()
+		}
+
+	case <-ctx.Done():
+		// Context canceled.
+		return nil, ctx.Err()
+	}
+}
+
+func (chp *GubernatorPool) handleSetRemaining(request poolSetRemainingRequest, cache Cache) {
+	ctx := tracing.StartScope(request.ctx)
+	defer tracing.EndScope(ctx, nil)
+
+	rl, err := setRemaining(ctx, chp.conf.Store, cache, request.request)
+	response := poolSetRemainingResponse{rl, err}
+
+	select {
+	case request.response <- response:
+		// Successfully sent response.
+
+	case <-ctx.Done():
+		// Context canceled.
+		trace.SpanFromContext(ctx).RecordError(ctx.Err())
+	}
+}
+
+// Remove a rate limit from the worker's cache and the store.
+func (chp *GubernatorPool) Delete(ctx context.Context, r *DeleteRateLimitReq) (found bool, reterr error) {
+	ctx = tracing.StartScope(ctx)
+	defer func() {
+		tracing.EndScope(ctx, reterr)
+	}()
+
+	respChan := make(chan poolDeleteResponse)
+	// Must pick the same worker as GetRateLimit() does for the rate limit
+	worker := chp.getWorker(r.Name + "_" + r.UniqueKey)
+	req := poolDeleteRequest{
+		ctx:      ctx,
+		response: respChan,
+		request:  r,
+	}
+
+	select {
+	case worker.deleteRequest <- req:
+		// Successfully sent request.
+		poolWorkerQueueLength.WithLabelValues("Delete", worker.name).Observe(float64(len(worker.deleteRequest)))
+
+		select {
+		case resp := <-respChan:
+			// Successfully received response.
+			return resp.found, nil
+
+		case <-ctx.Done():
+			// Context canceled.
+			return false, ctx.Err()
+		}
+
+	case <-ctx.Done():
+		// Context canceled.
+		return false, ctx.Err()
+	}
+}
+
+func (chp *GubernatorPool) handleDelete(request poolDeleteRequest, cache Cache) {
+	ctx := tracing.StartScope(request.ctx)
+	defer tracing.EndScope(ctx, nil)
+
+	req := &RateLimitReq{Name: request.request.Name, UniqueKey: request.request.UniqueKey}
+	hashKey := req.HashKey()
+
+	_, found := cache.GetItem(hashKey)
+	cache.Remove(hashKey)
+	if s := chp.conf.Store; s != nil {
+		if !found {
+			_, found = s.Get(ctx, req)
+		}
+		s.Remove(ctx, hashKey)
+	}
+	response := poolDeleteResponse{found}
+
+	select {
+	case request.response <- response:
+		// Successfully sent response.
+
+	case <-ctx.Done():
+		// Context canceled.
+		trace.SpanFromContext(ctx).RecordError(ctx.Err())
+	}
+}
+
+// Remove the items whose key matches from every worker's cache. Returns the keys of the
+// items removed. Unlike Delete() the store is not changed.
+func (chp *GubernatorPool) RemoveMatching(ctx context.Context, match func(key string) bool) (keys []string, reterr error) {
+	ctx = tracing.StartScope(ctx)
+	defer func() {
+		tracing.EndScope(ctx, reterr)
+	}()
+
+	respChan := make(chan poolRemoveResponse, len(chp.workers))
+	for _, worker := range chp.workers {
+		req := poolRemoveRequest{
+			ctx:      ctx,
+			response: respChan,
+			match:    match,
+		}
+
+		select {
+		case worker.removeRequest <- req:
+			// Successfully sent request.
+			poolWorkerQueueLength.WithLabelValues("RemoveMatching", worker.name).Observe(float64(len(worker.removeRequest)))
+
+		case <-ctx.Done():
+			// Context canceled.
+			return nil, ctx.Err()
+		}
+	}
+
+	for range chp.workers {
+		select {
+		case resp := <-respChan:
+			// Successfully received response.
+			keys = append(keys, resp.keys...)
+
+		case <-ctx.Done():
+			// Context canceled.
+			return nil, ctx.Err()
+		}
+	}
+	return keys, nil
+}
+
+func (chp *GubernatorPool) handleRemove(request poolRemoveRequest, cache Cache) {
+	ctx := tracing.StartScope(request.ctx)
+	defer tracing.EndScope(ctx, nil)
+
+	// The cache must not be changed while it is iterated
+	var keys []string
+	for item := range cache.Each() {
+		if request.match(item.Key) {
+			keys = append(keys, item.Key)
+		}
+	}
+	for _, key := range keys {
+		cache.Remove(key)
+	}
+
+	select {
+	case request.response <- poolRemoveResponse{keys: keys}:
+		// Successfully sent response.
+
+	case <-ctx.Done():
+		// Context canceled.
+		trace.SpanFromContext(ctx).RecordError(ctx.Err())
+	}
+}
+
+// Write the imported state of a rate limit to the worker's cache and the store.
+func (chp *GubernatorPool) Import(ctx context.Context, r *CacheItemSnapshot) (retval *RateLimitResp, reterr error) {
+	ctx = tracing.StartScope(ctx)
+	defer func() {
+		tracing.EndScope(ctx, reterr)
+	}()
+
+	respChan := make(chan poolImportResponse)
+	// Must pick the same worker as GetRateLimit() does for the rate limit
+	worker := chp.getWorker(r.Name + "_" + r.UniqueKey)
+	req := poolImportRequest{
+		ctx:      ctx,
+		response: respChan,
+		snapshot: r,
+	}
+
+	select {
+	case worker.importRequest <- req:
+		// Successfully sent request.
+		poolWorkerQueueLength.WithLabelValues("Import", worker.name).Observe(float64(len(worker.importRequest)))
+
+		select {
+		case resp := <-respChan:
+			// Successfully received response.
+			return resp.rl, resp.err
+
+		case <-ctx.Done():
+			// Context canceled.
+			return nil, ctx.Err()
+		}
+
+	case <-ctx.Done():
+		// Context canceled.
+		return nil, ctx.Err()
+	}
+}
+
+func (chp *GubernatorPool) handleImport(request poolImportRequest, cache Cache) {
+	ctx := tracing.StartScope(request.ctx)
+	defer tracing.EndScope(ctx, nil)
+
+	rl, err := importRateLimit(ctx, chp.conf.Store, cache, request.snapshot)
+	response := poolImportResponse{rl, err}
+
+	select {
+	case request.response <- response:
+		// Successfully sent response.
+
+	case <-ctx.Done():
+		// Context canceled.
+		trace.SpanFromContext(ctx).RecordError(ctx.Err())
+	}
+}
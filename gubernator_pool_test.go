@@ -20,9 +20,14 @@ import (
 	"context"
 	"fmt"
 	"sort"
+	"sync"
 	"testing"
 
 	guber "github.com/mailgun/gubernator/v2"
+	"github.com/mailgun/holster/v4/clock"
+	"github.com/mailgun/holster/v4/testutil"
+	"github.com/sirupsen/logrus"
+	logtest "github.com/sirupsen/logrus/hooks/test"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
@@ -130,3 +135,167 @@ func TestGubernatorPool(t *testing.T) {
 		})
 	}
 }
+
+func TestGubernatorPoolExpireCache(t *testing.T) {
+	var caches []*guber.LRUCache
+	var mutex sync.Mutex
+	conf := &guber.Config{
+		CacheExpireInterval: 10 * clock.Millisecond,
+		CacheFactory: func(maxSize int) guber.Cache {
+			mutex.Lock()
+			defer mutex.Unlock()
+			cache := guber.NewLRUCache(maxSize)
+			caches = append(caches, cache)
+			return cache
+		},
+	}
+	require.NoError(t, conf.SetDefaults())
+	chp := guber.NewGubernatorPool(conf, 4, 0)
+	defer chp.Close()
+
+	size := func() int64 {
+		mutex.Lock()
+		defer mutex.Unlock()
+		var size int64
+		for _, cache := range caches {
+			size += cache.Size()
+		}
+		return size
+	}
+
+	// Create short lived rate limits which are never accessed again
+	for i := 0; i < 100; i++ {
+		_, err := chp.GetRateLimit(context.Background(), &guber.RateLimitReq{
+			Name:      "test_expire_cache",
+			UniqueKey: fmt.Sprintf("account:%d", i),
+			Algorithm: guber.Algorithm_TOKEN_BUCKET,
+			Duration:  50 * guber.Millisecond,
+			Limit:     10,
+			Hits:      1,
+		})
+		require.NoError(t, err)
+	}
+	assert.Equal(t, int64(100), size())
+
+	testutil.UntilPass(t, 20, 50*clock.Millisecond, func(t testutil.TestingT) {
+		assert.Equal(t, int64(0), size())
+	})
+}
+
+// mismatchCache returns items stored under a different key than requested
+type mismatchCache struct {
+	guber.Cache
+}
+
+func (c *mismatchCache) GetItem(key string) (*guber.CacheItem, bool) {
+	return &guber.CacheItem{
+		Key:       "wrong_" + key,
+		Algorithm: guber.Algorithm_TOKEN_BUCKET,
+		Value:     &guber.TokenBucketItem{},
+		ExpireAt:  clock.Now().Add(clock.Minute).UnixMilli(),
+	}, true
+}
+
+func TestGubernatorPoolLogger(t *testing.T) {
+	logger, hook := logtest.NewNullLogger()
+	conf := &guber.Config{
+		Logger: logrus.NewEntry(logger),
+		CacheFactory: func(maxSize int) guber.Cache {
+			return &mismatchCache{Cache: guber.NewLRUCache(maxSize)}
+		},
+	}
+	require.NoError(t, conf.SetDefaults())
+	chp := guber.NewGubernatorPool(conf, 1, 0)
+	defer chp.Close()
+
+	for _, algorithm := range []guber.Algorithm{guber.Algorithm_TOKEN_BUCKET, guber.Algorithm_LEAKY_BUCKET} {
+		hook.Reset()
+		resp, err := chp.GetRateLimit(context.Background(), &guber.RateLimitReq{
+			Name:      "test_pool_logger",
+			UniqueKey: "account:1234",
+			Algorithm: algorithm,
+			Duration:  guber.Minute,
+			Limit:     10,
+			Hits:      1,
+		})
+		require.NoError(t, err)
+		// The mismatched item is discarded and a new rate limit is created
+		assert.Equal(t, int64(9), resp.Remaining)
+
+		entry := hook.LastEntry()
+		require.NotNil(t, entry)
+		assert.Equal(t, logrus.ErrorLevel, entry.Level)
+		assert.Contains(t, entry.Message, "Invalid cache item; key mismatch")
+		assert.Equal(t, "test_pool_logger_account:1234", entry.Data["hashKey"])
+		assert.Equal(t, "wrong_test_pool_logger_account:1234", entry.Data["itemKey"])
+		assert.Equal(t, "test_pool_logger", entry.Data["name"])
+	}
+}
+
+func TestGubernatorPoolIdempotencyKeyNotCached(t *testing.T) {
+	cache := guber.NewLRUCache(100)
+	conf := &guber.Config{
+		CacheFactory: func(maxSize int) guber.Cache {
+			return cache
+		},
+	}
+	require.NoError(t, conf.SetDefaults())
+	chp := guber.NewGubernatorPool(conf, 1, 0)
+	defer chp.Close()
+
+	for i := 0; i < 2; i++ {
+		resp, err := chp.GetRateLimit(context.Background(), &guber.RateLimitReq{
+			Name:           "test_pool_idempotency",
+			UniqueKey:      "account:1234",
+			Algorithm:      guber.Algorithm_TOKEN_BUCKET,
+			Duration:       guber.Minute,
+			Limit:          10,
+			Hits:           1,
+			IdempotencyKey: "request:1",
+		})
+		require.NoError(t, err)
+		assert.Equal(t, int64(9), resp.Remaining)
+	}
+
+	// Only the rate limit is held in the cache, the remembered response is not
+	assert.Equal(t, int64(1), cache.Size())
+	for item := range cache.Each() {
+		assert.Equal(t, "test_pool_idempotency_account:1234", item.Key)
+	}
+}
+
+func TestGubernatorPoolBackoffHintNotCached(t *testing.T) {
+	cache := guber.NewLRUCache(100)
+	conf := &guber.Config{
+		CacheFactory: func(maxSize int) guber.Cache {
+			return cache
+		},
+	}
+	require.NoError(t, conf.SetDefaults())
+	chp := guber.NewGubernatorPool(conf, 1, 0)
+	defer chp.Close()
+
+	var suggested int64
+	for i := 0; i < 3; i++ {
+		resp, err := chp.GetRateLimit(context.Background(), &guber.RateLimitReq{
+			Name:      "test_pool_backoff_hint",
+			UniqueKey: "account:1234",
+			Algorithm: guber.Algorithm_TOKEN_BUCKET,
+			Behavior:  guber.Behavior_BACKOFF_HINT,
+			Duration:  guber.Minute,
+			Limit:     1,
+			Hits:      2,
+		})
+		require.NoError(t, err)
+		assert.Equal(t, guber.Status_OVER_LIMIT, resp.Status)
+		// The count of rejections is remembered between requests
+		assert.Greater(t, resp.SuggestedRetryAfter, suggested)
+		suggested = resp.SuggestedRetryAfter
+	}
+
+	// Only the rate limit is held in the cache, the count of rejections is not
+	assert.Equal(t, int64(1), cache.Size())
+	for item := range cache.Each() {
+		assert.Equal(t, "test_pool_backoff_hint_account:1234", item.Key)
+	}
+}
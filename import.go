@@ -0,0 +1,237 @@
+/*
+Copyright 2018-2022 Mailgun Technologies Inc
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gubernator
+
+import (
+	"context"
+	"sync"
+
+	"github.com/mailgun/holster/v4/errors"
+	"github.com/mailgun/holster/v4/tracing"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// ImportRateLimits writes the state of each rate limit provided to the cache and store of its owning
+// peer. The items owned by each peer are sent to the peer in a single request.
+func (s *V1Instance) ImportRateLimits(ctx context.Context, r *ImportRateLimitsReq) (retval *ImportRateLimitsResp, reterr error) {
+	ctx = tracing.StartScope(ctx)
+	defer func() {
+		tracing.EndScope(ctx, reterr)
+	}()
+
+	if err := checkImportRequest(r); err != nil {
+		return nil, err
+	}
+
+	// Group the items by the peer which owns them
+	batches := make(map[*PeerClient][]int)
+	resp := &ImportRateLimitsResp{Responses: make([]*RateLimitResp, len(r.Items))}
+	for i, item := range r.Items {
+		key := item.Name + "_" + item.UniqueKey
+		peer, err := s.GetPeer(ctx, key)
+		if err != nil {
+			err = errors.Wrapf(err, "while finding peer that owns rate limit '%s'", key)
+			resp.Responses[i] = &RateLimitResp{Error: err.Error()}
+			continue
+		}
+		batches[peer] = append(batches[peer], i)
+	}
+
+	var wg sync.WaitGroup
+	for peer, idx := range batches {
+		wg.Add(1)
+		go func(peer *PeerClient, idx []int) {
+			defer wg.Done()
+			req := &ImportRateLimitsReq{Items: make([]*CacheItemSnapshot, len(idx))}
+			for j, i := range idx {
+				req.Items[j] = r.Items[i]
+			}
+
+			var pr *ImportRateLimitsResp
+			var err error
+			if peer.Info().IsOwner {
+				pr, err = s.ImportPeerRateLimits(ctx, req)
+			} else {
+				pr, err = peer.ImportPeerRateLimits(ctx, req)
+			}
+			if err == nil && len(pr.Responses) != len(idx) {
+				err = errors.New("peer responded with incorrect rate limit list size")
+			}
+			for j, i := range idx {
+				if err != nil {
+					err := errors.Wrapf(err, "while importing rate limits on peer '%s'", peer.Info().GRPCAddress)
+					resp.Responses[i] = &RateLimitResp{Error: err.Error()}
+					continue
+				}
+				resp.Responses[i] = pr.Responses[j]
+			}
+		}(peer, idx)
+	}
+	wg.Wait()
+
+	return resp, nil
+}
+
+// ImportPeerRateLimits writes the state of each rate limit provided to the cache and store of this
+// instance. This method should only be called by a peer relaying an AdminV1 ImportRateLimits request.
+func (s *V1Instance) ImportPeerRateLimits(ctx context.Context, r *ImportRateLimitsReq) (retval *ImportRateLimitsResp, reterr error) {
+	ctx = tracing.StartScope(ctx)
+	defer func() {
+		tracing.EndScope(ctx, reterr)
+	}()
+
+	if err := checkImportRequest(r); err != nil {
+		return nil, err
+	}
+
+	resp := &ImportRateLimitsResp{Responses: make([]*RateLimitResp, len(r.Items))}
+	for i, item := range r.Items {
+		rl, err := s.gubernatorPool.Import(ctx, item)
+		if err != nil {
+			rl = &RateLimitResp{Error: errors.Wrap(err, "Error in gubernatorPool.Import").Error()}
+		}
+		resp.Responses[i] = rl
+	}
+	return resp, nil
+}
+
+func checkImportRequest(r *ImportRateLimitsReq) error {
+	if len(r.Items) == 0 {
+		return status.Error(codes.InvalidArgument, "field 'items' cannot be empty")
+	}
+	if len(r.Items) > maxBatchSize {
+		return status.Errorf(codes.OutOfRange, "'ImportRateLimitsReq.items' list too large; max size is '%d'", maxBatchSize)
+	}
+
+	now := MillisecondNow()
+	for _, item := range r.Items {
+		key := item.Name + "_" + item.UniqueKey
+		switch {
+		case len(item.Name) == 0:
+			return status.Error(codes.InvalidArgument, "field 'name' cannot be empty")
+		case len(item.UniqueKey) == 0:
+			return status.Error(codes.InvalidArgument, "field 'unique_key' cannot be empty")
+		case item.Limit <= 0:
+			return status.Errorf(codes.InvalidArgument, "'limit' of '%s' must be greater than zero", key)
+		case item.Duration <= 0:
+			return status.Errorf(codes.InvalidArgument, "'duration' of '%s' must be greater than zero", key)
+		case item.Burst < 0:
+			return status.Errorf(codes.InvalidArgument, "'burst' of '%s' cannot be negative", key)
+		}
+
+		capacity := tokenBucketBurst(&RateLimitReq{Limit: item.Limit, Burst: item.Burst})
+		if item.Algorithm == Algorithm_LEAKY_BUCKET && item.Burst != 0 {
+			capacity = item.Burst
+		}
+		if item.Remaining < 0 || item.Remaining > capacity {
+			return status.Errorf(codes.InvalidArgument, "'remaining' of '%s' must be between 0 and '%d'", key, capacity)
+		}
+
+		switch item.Algorithm {
+		case Algorithm_TOKEN_BUCKET:
+			if item.ResetTime <= now || item.ResetTime > now+item.Duration {
+				return status.Errorf(codes.InvalidArgument,
+					"'reset_time' of '%s' must be in the future and within the 'duration'", key)
+			}
+		case Algorithm_LEAKY_BUCKET:
+		default:
+			return status.Errorf(codes.InvalidArgument, "invalid algorithm '%d' for '%s'", item.Algorithm, key)
+		}
+	}
+	return nil
+}
+
+// importRateLimit writes the imported state of a rate limit to the cache and the store. An existing
+// rate limit of another algorithm is not replaced, since the import most likely names the wrong key.
+func importRateLimit(ctx context.Context, s Store, c Cache, r *CacheItemSnapshot) (*RateLimitResp, error) {
+	req := &RateLimitReq{
+		Name:      r.Name,
+		UniqueKey: r.UniqueKey,
+		Algorithm: r.Algorithm,
+		Limit:     r.Limit,
+		Duration:  r.Duration,
+		Burst:     r.Burst,
+	}
+	hashKey := req.HashKey()
+	now := MillisecondNow()
+
+	existing, ok := c.GetItem(hashKey)
+	if s != nil && !ok {
+		existing, ok = s.Get(ctx, req)
+	}
+	if ok && existing.ExpireAt > now && existing.Algorithm != r.Algorithm {
+		return nil, status.Errorf(codes.FailedPrecondition,
+			"rate limit '%s' exists with algorithm '%s'; delete it before importing a '%s'",
+			hashKey, existing.Algorithm, r.Algorithm)
+	}
+
+	item := &CacheItem{
+		Algorithm: r.Algorithm,
+		Key:       hashKey,
+	}
+	rl := &RateLimitResp{
+		Status:    Status_UNDER_LIMIT,
+		Algorithm: r.Algorithm,
+		Limit:     r.Limit,
+		Remaining: r.Remaining,
+	}
+
+	switch r.Algorithm {
+	case Algorithm_TOKEN_BUCKET:
+		t := &TokenBucketItem{
+			Status:    Status_UNDER_LIMIT,
+			Limit:     r.Limit,
+			Duration:  r.Duration,
+			Remaining: float64(r.Remaining),
+			CreatedAt: r.ResetTime - r.Duration,
+		}
+		if burst := tokenBucketBurst(req); burst > r.Limit {
+			t.Burst = burst
+		}
+		if r.Remaining == 0 {
+			t.Status = Status_OVER_LIMIT
+			rl.Status = Status_OVER_LIMIT
+		}
+		item.Value = t
+		item.ExpireAt = r.ResetTime
+		rl.ResetTime = r.ResetTime
+	case Algorithm_LEAKY_BUCKET:
+		if req.Burst == 0 {
+			req.Burst = r.Limit
+		}
+		b := &LeakyBucketItem{
+			Limit:     r.Limit,
+			Duration:  r.Duration,
+			Remaining: float64(r.Remaining),
+			UpdatedAt: now,
+			Burst:     req.Burst,
+		}
+		item.Value = b
+		item.ExpireAt = now + r.Duration
+		rl.ResetTime = leakyBucketResetTime(now, r.Limit, r.Remaining, float64(r.Duration)/float64(r.Limit), LeakProfile_LINEAR)
+	default:
+		return nil, status.Errorf(codes.InvalidArgument, "invalid algorithm '%d' for '%s'", r.Algorithm, hashKey)
+	}
+
+	// Replaces whatever we may have cached
+	c.Add(item)
+	if s != nil {
+		s.OnChange(ctx, req, item)
+	}
+	return rl, nil
+}
@@ -18,10 +18,13 @@ package gubernator
 
 import (
 	"errors"
+	"sync"
 	"time"
 
 	"github.com/mailgun/holster/v4/clock"
 	"github.com/mailgun/holster/v4/syncutil"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
 // Interval is a one-shot ticker.  Call `Next()` to trigger the start of an
@@ -78,34 +81,107 @@ const (
 	GregorianWeeks
 	GregorianMonths
 	GregorianYears
+	GregorianQuarters
 )
 
-// GregorianDuration returns the entire duration of the Gregorian interval
-func GregorianDuration(now clock.Time, d int64) (int64, error) {
+// gregorianIntervals describes the valid `Duration` values of a `DURATION_IS_GREGORIAN` rate limit
+const gregorianIntervals = "0 (minutes), 1 (hours), 2 (days), 3 (weeks), 4 (months), 5 (years) or 6 (quarters)"
+
+// checkGregorian returns an InvalidArgument error naming the valid intervals if the request has
+// Behavior_DURATION_IS_GREGORIAN but the `Duration` is not a gregorian interval
+func checkGregorian(r *RateLimitReq) error {
+	if !HasBehavior(r.Behavior, Behavior_DURATION_IS_GREGORIAN) {
+		return nil
+	}
+	if r.Algorithm == Algorithm_HYBRID {
+		return status.Errorf(codes.InvalidArgument, "behavior DURATION_IS_GREGORIAN for '%s' is not supported "+
+			"by algorithm HYBRID", r.HashKey())
+	}
+	if r.Duration < GregorianMinutes || r.Duration > GregorianQuarters {
+		return status.Errorf(codes.InvalidArgument, "'duration' of '%d' for '%s' is not a valid gregorian "+
+			"interval for behavior DURATION_IS_GREGORIAN; must be %s", r.Duration, r.HashKey(), gregorianIntervals)
+	}
+	if _, err := gregorianLocation(r); err != nil {
+		return status.Errorf(codes.InvalidArgument, "'timezone' of '%s' for '%s' is invalid: %s",
+			r.Timezone, r.HashKey(), err)
+	}
+	return nil
+}
+
+// gregorianLocations caches the locations of the `timezone` of requests by name
+var gregorianLocations sync.Map
+
+// gregorianLocation returns the location of the `timezone` of the request, the local time zone if the
+// request does not provide one
+func gregorianLocation(r *RateLimitReq) (*time.Location, error) {
+	if r.Timezone == "" {
+		return time.Local, nil
+	}
+	if loc, ok := gregorianLocations.Load(r.Timezone); ok {
+		return loc.(*time.Location), nil
+	}
+	loc, err := time.LoadLocation(r.Timezone)
+	if err != nil {
+		return nil, err
+	}
+	gregorianLocations.Store(r.Timezone, loc)
+	return loc, nil
+}
+
+// gregorianNow returns the current time in the location of the `timezone` of the request
+func gregorianNow(r *RateLimitReq) (clock.Time, error) {
+	loc, err := gregorianLocation(r)
+	if err != nil {
+		return clock.Time{}, err
+	}
+	return clock.Now().In(loc), nil
+}
+
+// gregorianInterval returns the beginning of the Gregorian interval `now` falls within and the beginning
+// of the next interval. The interval boundaries are calculated in the location of `now` using calendar
+// arithmetic, such that month lengths, leap years and DST transitions are accounted for. Weeks begin on
+// Monday.
+func gregorianInterval(now clock.Time, d int64) (clock.Time, clock.Time, error) {
+	y, m, day := now.Date()
 	switch d {
 	case GregorianMinutes:
-		return 60000, nil
+		begin := now.Truncate(clock.Minute)
+		return begin, begin.Add(clock.Minute), nil
 	case GregorianHours:
-		return 3.6e+6, nil
+		// See time.Truncate() documentation on why we can' reliably use time.Truncate(Hour) here. We also
+		// avoid clock.Date() as the hour is ambiguous when the clock is turned back for DST.
+		begin := now.Add(-(clock.Duration(now.Minute())*clock.Minute +
+			clock.Duration(now.Second())*clock.Second + clock.Duration(now.Nanosecond())))
+		return begin, begin.Add(clock.Hour), nil
 	case GregorianDays:
-		return 8.64e+7, nil
+		begin := clock.Date(y, m, day, 0, 0, 0, 0, now.Location())
+		return begin, begin.AddDate(0, 0, 1), nil
 	case GregorianWeeks:
-		return 0, errors.New("`Duration = GregorianWeeks` not yet supported; consider making a PR!`")
+		// Weekday() considers Sunday the first day of the week; shift so Monday is day zero.
+		offset := (int(now.Weekday()) + 6) % 7
+		begin := clock.Date(y, m, day-offset, 0, 0, 0, 0, now.Location())
+		return begin, begin.AddDate(0, 0, 7), nil
 	case GregorianMonths:
-		y, m, _ := now.Date()
-		// Given the beginning of the month, subtract the end of the current month to get the duration
 		begin := clock.Date(y, m, 1, 0, 0, 0, 0, now.Location())
-		end := begin.AddDate(0, 1, 0).Add(-clock.Nanosecond)
-		return end.UnixNano() - begin.UnixNano()/1000000, nil
+		return begin, begin.AddDate(0, 1, 0), nil
+	case GregorianQuarters:
+		begin := clock.Date(y, ((m-1)/3)*3+1, 1, 0, 0, 0, 0, now.Location())
+		return begin, begin.AddDate(0, 3, 0), nil
 	case GregorianYears:
-		y, _, _ := now.Date()
-		// Given the beginning of the year, subtract the end of the current year to get the duration
 		begin := clock.Date(y, clock.January, 1, 0, 0, 0, 0, now.Location())
-		end := begin.AddDate(1, 0, 0).Add(-clock.Nanosecond)
-		return end.UnixNano() - begin.UnixNano()/1000000, nil
+		return begin, begin.AddDate(1, 0, 0), nil
 	}
-	return 0, errors.New("behavior DURATION_IS_GREGORIAN is set; but `Duration` is not a valid gregorian interval")
+	return clock.Time{}, clock.Time{},
+		errors.New("behavior DURATION_IS_GREGORIAN is set; but `Duration` is not a valid gregorian interval")
+}
 
+// GregorianDuration returns the entire duration of the Gregorian interval in milliseconds
+func GregorianDuration(now clock.Time, d int64) (int64, error) {
+	begin, end, err := gregorianInterval(now, d)
+	if err != nil {
+		return 0, err
+	}
+	return end.Sub(begin).Milliseconds(), nil
 }
 
 // GregorianExpiration returns an gregorian interval as defined by the
@@ -115,34 +191,20 @@ func GregorianDuration(now clock.Time, d int64) (int64, error) {
 // Example: If `now` is 2019-01-01 11:20:10 and `d` = GregorianMinutes then the return
 // expire time would be 2019-01-01 11:20:59 in milliseconds since epoch
 func GregorianExpiration(now clock.Time, d int64) (int64, error) {
-	switch d {
-	case GregorianMinutes:
-		return now.Truncate(clock.Minute).
-			Add(clock.Minute-clock.Nanosecond).
-			UnixNano() / 1000000, nil
-	case GregorianHours:
-		y, m, d := now.Date()
-		// See time.Truncate() documentation on why we can' reliably use time.Truncate(Hour) here.
-		return clock.Date(y, m, d, now.Hour(), 0, 0, 0, now.Location()).
-			Add(clock.Hour-clock.Nanosecond).
-			UnixNano() / 1000000, nil
-	case GregorianDays:
-		y, m, d := now.Date()
-		return clock.Date(y, m, d, 23, 59, 59, int(clock.Second-clock.Nanosecond), now.Location()).
-			UnixNano() / 1000000, nil
-	case GregorianWeeks:
-		return 0, errors.New("`Duration = GregorianWeeks` not yet supported; consider making a PR!`")
-	case GregorianMonths:
-		y, m, _ := now.Date()
-		return clock.Date(y, m, 1, 0, 0, 0, 0, now.Location()).
-			AddDate(0, 1, 0).Add(-clock.Nanosecond).
-			UnixNano() / 1000000, nil
-	case GregorianYears:
-		y, _, _ := now.Date()
-		return clock.Date(y, clock.January, 1, 0, 0, 0, 0, now.Location()).
-			AddDate(1, 0, 0).
-			Add(-clock.Nanosecond).
-			UnixNano() / 1000000, nil
+	_, end, err := gregorianInterval(now, d)
+	if err != nil {
+		return 0, err
+	}
+	return end.Add(-clock.Nanosecond).UnixNano() / 1000000, nil
+}
+
+// gregorianResetTime returns the time in milliseconds at which a `DURATION_IS_GREGORIAN` rate limit
+// resets. This is the first millisecond of the next gregorian interval, such that a request which arrives
+// exactly at the boundary is counted against the new interval.
+func gregorianResetTime(now clock.Time, d int64) (int64, error) {
+	expire, err := GregorianExpiration(now, d)
+	if err != nil {
+		return 0, err
 	}
-	return 0, errors.New("behavior DURATION_IS_GREGORIAN is set; but `Duration` is not a valid gregorian interval")
+	return expire + 1, nil
 }
@@ -17,6 +17,7 @@ limitations under the License.
 package gubernator_test
 
 import (
+	"context"
 	"testing"
 	"time"
 
@@ -24,6 +25,8 @@ import (
 	"github.com/mailgun/holster/v4/clock"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
 func TestInterval(t *testing.T) {
@@ -128,6 +131,173 @@ func TestGregorianExpirationYear(t *testing.T) {
 	assert.Equal(t, int64(1577836799999), expire)
 }
 
+func TestGregorianExpirationWeek(t *testing.T) {
+	// Weeks begin on Monday and end on Sunday
+	now := clock.Date(2019, clock.November, 13, 10, 12, 00, 00, clock.UTC)
+	expire, err := gubernator.GregorianExpiration(now, gubernator.GregorianWeeks)
+	assert.Nil(t, err)
+	assert.Equal(t, clock.Date(2019, clock.November, 17, 23, 59, 59, 999000000, clock.UTC),
+		clock.Unix(0, expire*1000000).UTC())
+
+	// Expect the same expire time on the first and last millisecond of the week
+	now = clock.Date(2019, clock.November, 11, 00, 00, 00, 00, clock.UTC)
+	expire, err = gubernator.GregorianExpiration(now, gubernator.GregorianWeeks)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(1574035199999), expire)
+
+	now = clock.Date(2019, clock.November, 17, 23, 59, 59, 999000000, clock.UTC)
+	expire, err = gubernator.GregorianExpiration(now, gubernator.GregorianWeeks)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(1574035199999), expire)
+
+	// A week which spans the end of the year
+	now = clock.Date(2019, clock.December, 31, 23, 59, 00, 00, clock.UTC)
+	expire, err = gubernator.GregorianExpiration(now, gubernator.GregorianWeeks)
+	assert.Nil(t, err)
+	assert.Equal(t, clock.Date(2020, clock.January, 5, 23, 59, 59, 999000000, clock.UTC),
+		clock.Unix(0, expire*1000000).UTC())
+}
+
+func TestGregorianExpirationQuarter(t *testing.T) {
+	tests := []struct {
+		Name   string
+		Now    clock.Time
+		Expire clock.Time
+	}{
+		{
+			Name:   "last millisecond of the first quarter",
+			Now:    clock.Date(2019, clock.March, 31, 23, 59, 59, 999000000, clock.UTC),
+			Expire: clock.Date(2019, clock.March, 31, 23, 59, 59, 999000000, clock.UTC),
+		},
+		{
+			Name:   "first millisecond of the second quarter",
+			Now:    clock.Date(2019, clock.April, 1, 00, 00, 00, 00, clock.UTC),
+			Expire: clock.Date(2019, clock.June, 30, 23, 59, 59, 999000000, clock.UTC),
+		},
+		{
+			Name:   "middle of the third quarter",
+			Now:    clock.Date(2019, clock.August, 15, 12, 30, 00, 00, clock.UTC),
+			Expire: clock.Date(2019, clock.September, 30, 23, 59, 59, 999000000, clock.UTC),
+		},
+		{
+			Name:   "last minute of the year",
+			Now:    clock.Date(2019, clock.December, 31, 23, 59, 00, 00, clock.UTC),
+			Expire: clock.Date(2019, clock.December, 31, 23, 59, 59, 999000000, clock.UTC),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.Name, func(t *testing.T) {
+			expire, err := gubernator.GregorianExpiration(tt.Now, gubernator.GregorianQuarters)
+			require.NoError(t, err)
+			assert.Equal(t, tt.Expire.UnixNano()/1000000, expire)
+		})
+	}
+}
+
+func TestGregorianExpirationLeapYear(t *testing.T) {
+	defer clock.Freeze(clock.Date(2020, clock.February, 29, 12, 00, 00, 00, clock.UTC)).Unfreeze()
+
+	expire, err := gubernator.GregorianExpiration(clock.Now(), gubernator.GregorianDays)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1583020799999), expire)
+
+	expire, err = gubernator.GregorianExpiration(clock.Now(), gubernator.GregorianMonths)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1583020799999), expire)
+
+	expire, err = gubernator.GregorianExpiration(clock.Now(), gubernator.GregorianYears)
+	require.NoError(t, err)
+	assert.Equal(t, clock.Date(2020, clock.December, 31, 23, 59, 59, 999000000, clock.UTC).UnixNano()/1000000, expire)
+}
+
+func TestGregorianExpirationDST(t *testing.T) {
+	loc, err := time.LoadLocation("America/New_York")
+	require.NoError(t, err)
+
+	// On 2019-11-03 at 2:00 EDT the clock is turned back to 1:00 EST, such that 1:30 occurs twice.
+	now := clock.Date(2019, clock.November, 3, 1, 30, 00, 00, loc).Add(clock.Hour)
+	require.Equal(t, "EST", now.Format("MST"))
+
+	expire, err := gubernator.GregorianExpiration(now, gubernator.GregorianHours)
+	require.NoError(t, err)
+	assert.Equal(t, now.Add(30*clock.Minute).UnixNano()/1000000-1, expire)
+
+	expire, err = gubernator.GregorianExpiration(now, gubernator.GregorianDays)
+	require.NoError(t, err)
+	assert.Equal(t, clock.Date(2019, clock.November, 3, 23, 59, 59, 999000000, loc).UnixNano()/1000000, expire)
+}
+
+func TestGregorianDuration(t *testing.T) {
+	const day = int64(24 * clock.Hour / clock.Millisecond)
+	loc, err := time.LoadLocation("America/New_York")
+	require.NoError(t, err)
+
+	tests := []struct {
+		Name     string
+		Now      clock.Time
+		Interval int64
+		Duration int64
+	}{
+		{
+			Name:     "minute",
+			Now:      clock.Date(2019, clock.November, 11, 00, 00, 30, 00, clock.UTC),
+			Interval: gubernator.GregorianMinutes,
+			Duration: 60000,
+		},
+		{
+			Name:     "hour",
+			Now:      clock.Date(2019, clock.November, 11, 10, 20, 30, 00, clock.UTC),
+			Interval: gubernator.GregorianHours,
+			Duration: 3600000,
+		},
+		{
+			Name:     "day",
+			Now:      clock.Date(2019, clock.November, 11, 10, 20, 30, 00, clock.UTC),
+			Interval: gubernator.GregorianDays,
+			Duration: day,
+		},
+		{
+			Name:     "day with DST transition has 25 hours",
+			Now:      clock.Date(2019, clock.November, 3, 10, 00, 00, 00, loc),
+			Interval: gubernator.GregorianDays,
+			Duration: day + 3600000,
+		},
+		{
+			Name:     "week",
+			Now:      clock.Date(2019, clock.November, 13, 10, 20, 30, 00, clock.UTC),
+			Interval: gubernator.GregorianWeeks,
+			Duration: 7 * day,
+		},
+		{
+			Name:     "february in a leap year",
+			Now:      clock.Date(2020, clock.February, 29, 10, 20, 30, 00, clock.UTC),
+			Interval: gubernator.GregorianMonths,
+			Duration: 29 * day,
+		},
+		{
+			Name:     "first quarter",
+			Now:      clock.Date(2019, clock.February, 1, 00, 00, 00, 00, clock.UTC),
+			Interval: gubernator.GregorianQuarters,
+			Duration: 90 * day,
+		},
+		{
+			Name:     "leap year",
+			Now:      clock.Date(2020, clock.December, 31, 23, 59, 00, 00, clock.UTC),
+			Interval: gubernator.GregorianYears,
+			Duration: 366 * day,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.Name, func(t *testing.T) {
+			d, err := gubernator.GregorianDuration(tt.Now, tt.Interval)
+			require.NoError(t, err)
+			assert.Equal(t, tt.Duration, d)
+		})
+	}
+}
+
 func TestGregorianExpirationInvalid(t *testing.T) {
 	now := clock.Date(2019, clock.January, 1, 00, 00, 00, 00, clock.UTC)
 	expire, err := gubernator.GregorianExpiration(now, 99)
@@ -135,3 +305,93 @@ func TestGregorianExpirationInvalid(t *testing.T) {
 	assert.Equal(t, int64(0), expire)
 	assert.Equal(t, "behavior DURATION_IS_GREGORIAN is set; but `Duration` is not a valid gregorian interval", err.Error())
 }
+
+func TestGregorianTimezone(t *testing.T) {
+	ny, err := time.LoadLocation("America/New_York")
+	require.NoError(t, err)
+	tokyo, err := time.LoadLocation("Asia/Tokyo")
+	require.NoError(t, err)
+
+	// On 2019-11-03 the clock in New York is turned back an hour, such that the day lasts 25 hours
+	defer clock.Freeze(clock.Date(2019, clock.November, 3, 12, 0, 0, 0, clock.UTC)).Unfreeze()
+
+	srv := newV1Server(t, "", gubernator.Config{GregorianLocation: tokyo})
+	defer srv.Close()
+
+	send := func(key, timezone string, interval int64) *gubernator.RateLimitResp {
+		resp, err := srv.srv.GetRateLimits(context.Background(), &gubernator.GetRateLimitsReq{
+			Requests: []*gubernator.RateLimitReq{{
+				Name:      "test_gregorian_timezone",
+				UniqueKey: key,
+				Behavior:  gubernator.Behavior_DURATION_IS_GREGORIAN,
+				Duration:  interval,
+				Limit:     10,
+				Hits:      1,
+				Timezone:  timezone,
+			}},
+		})
+		require.NoError(t, err)
+		require.Len(t, resp.Responses, 1)
+		require.Empty(t, resp.Responses[0].Error)
+		return resp.Responses[0]
+	}
+
+	for _, test := range []struct {
+		Name      string
+		Timezone  string
+		Interval  int64
+		ResetTime clock.Time
+	}{
+		{
+			Name:      "day in new york",
+			Timezone:  "America/New_York",
+			Interval:  gubernator.GregorianDays,
+			ResetTime: clock.Date(2019, clock.November, 4, 0, 0, 0, 0, ny),
+		},
+		{
+			Name:      "day in tokyo",
+			Timezone:  "Asia/Tokyo",
+			Interval:  gubernator.GregorianDays,
+			ResetTime: clock.Date(2019, clock.November, 4, 0, 0, 0, 0, tokyo),
+		},
+		{
+			Name:      "day in the location of the server",
+			Interval:  gubernator.GregorianDays,
+			ResetTime: clock.Date(2019, clock.November, 4, 0, 0, 0, 0, tokyo),
+		},
+		{
+			Name:      "month in new york",
+			Timezone:  "America/New_York",
+			Interval:  gubernator.GregorianMonths,
+			ResetTime: clock.Date(2019, clock.December, 1, 0, 0, 0, 0, ny),
+		},
+		{
+			Name:      "month in tokyo",
+			Timezone:  "Asia/Tokyo",
+			Interval:  gubernator.GregorianMonths,
+			ResetTime: clock.Date(2019, clock.December, 1, 0, 0, 0, 0, tokyo),
+		},
+	} {
+		t.Run(test.Name, func(t *testing.T) {
+			rl := send(test.Name, test.Timezone, test.Interval)
+			assert.Equal(t, test.ResetTime.UnixMilli(), rl.ResetTime)
+		})
+	}
+
+	// The same day resets at a distinct time in each time zone
+	assert.NotEqual(t, send("distinct:ny", "America/New_York", gubernator.GregorianDays).ResetTime,
+		send("distinct:tokyo", "Asia/Tokyo", gubernator.GregorianDays).ResetTime)
+
+	_, err = srv.srv.GetRateLimits(context.Background(), &gubernator.GetRateLimitsReq{
+		Requests: []*gubernator.RateLimitReq{{
+			Name:      "test_gregorian_timezone",
+			UniqueKey: "invalid",
+			Behavior:  gubernator.Behavior_DURATION_IS_GREGORIAN,
+			Duration:  gubernator.GregorianDays,
+			Limit:     10,
+			Hits:      1,
+			Timezone:  "Mars/Olympus_Mons",
+		}},
+	})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+}
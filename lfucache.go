@@ -0,0 +1,256 @@
+/*
+Copyright 2018-2022 Mailgun Technologies Inc
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gubernator
+
+import (
+	"container/heap"
+	"sync/atomic"
+
+	"github.com/mailgun/holster/v4/setter"
+)
+
+// LFUCache is a Cache which evicts the least frequently used item of the lowest CacheItem.Priority
+// when full, breaking ties by evicting the least recently used item. Unlike LRUCache, a burst of requests for keys which are
+// only seen once does not evict the hot keys, but new keys are evicted before keys which were
+// used often in the past, even if those keys are no longer used.
+// Not thread-safe.  Be sure to use a mutex to prevent concurrent method calls.
+type LFUCache struct {
+	cache     map[string]*lfuEntry
+	entries   lfuHeap
+	cacheSize int
+	cacheLen  int64
+	stats     CacheStats
+	tick      uint64
+}
+
+type lfuEntry struct {
+	item *CacheItem
+	// The number of times the item was added or found by GetItem()
+	uses int64
+	// When the item was last used, breaks ties between items with the same `uses`
+	usedAt uint64
+	// The priority of the item when it was last used, items of a lower priority are evicted first
+	priority int32
+	index    int
+}
+
+var _ Cache = &LFUCache{}
+var _ ExpiringCache = &LFUCache{}
+var _ StaleCache = &LFUCache{}
+
+// NewLFUCache creates a new LFUCache with a maximum size. If maxSize is less than 1 the default size
+// of 50,000 is used.
+func NewLFUCache(maxSize int) *LFUCache {
+	if maxSize < 0 {
+		maxSize = 0
+	}
+	setter.SetDefault(&maxSize, 50_000)
+	return &LFUCache{
+		cache:     make(map[string]*lfuEntry),
+		cacheSize: maxSize,
+	}
+}
+
+// Each returns each item in the cache. Like LRUCache.Each(), other methods of the cache must not
+// be called until the channel is read to completion.
+func (c *LFUCache) Each() chan *CacheItem {
+	out := make(chan *CacheItem)
+	go func() {
+		for _, e := range c.cache {
+			out <- e.item
+		}
+		close(out)
+	}()
+	return out
+}
+
+// Add adds a value to the cache. Replacing the value of an existing key counts as a use of the key.
+func (c *LFUCache) Add(item *CacheItem) bool {
+	if e, ok := c.cache[item.Key]; ok {
+		e.item = item
+		c.use(e)
+		return true
+	}
+
+	if len(c.entries) >= c.cacheSize {
+		c.removeLeastUsed()
+	}
+	c.tick++
+	e := &lfuEntry{item: item, uses: 1, usedAt: c.tick, priority: item.Priority}
+	heap.Push(&c.entries, e)
+	c.cache[item.Key] = e
+	atomic.StoreInt64(&c.cacheLen, int64(len(c.entries)))
+	return false
+}
+
+// GetItem returns the item stored in the cache
+func (c *LFUCache) GetItem(key string) (item *CacheItem, ok bool) {
+	e, hit := c.cache[key]
+	if !hit {
+		c.miss()
+		return
+	}
+
+	now := MillisecondNow()
+	if (e.item.InvalidAt != 0 && e.item.InvalidAt < now) || e.item.ExpireAt <= now {
+		c.removeEntry(e)
+		c.miss()
+		return
+	}
+
+	accessMetric.WithLabelValues("hit").Add(1)
+	atomic.AddInt64(&c.stats.Hit, 1)
+	c.use(e)
+	return e.item, true
+}
+
+func (c *LFUCache) use(e *lfuEntry) {
+	c.tick++
+	e.uses++
+	e.usedAt = c.tick
+	e.priority = e.item.Priority
+	heap.Fix(&c.entries, e.index)
+}
+
+func (c *LFUCache) miss() {
+	accessMetric.WithLabelValues("miss").Add(1)
+	atomic.AddInt64(&c.stats.Miss, 1)
+}
+
+// Remove removes the provided key from the cache.
+func (c *LFUCache) Remove(key string) {
+	if e, hit := c.cache[key]; hit {
+		c.removeEntry(e)
+	}
+}
+
+func (c *LFUCache) removeLeastUsed() {
+	if len(c.entries) == 0 {
+		return
+	}
+	e := c.entries[0]
+	if MillisecondNow() < e.item.ExpireAt {
+		unexpiredEvictionsMetric.Add(1)
+		atomic.AddInt64(&c.stats.UnexpiredEvictions, 1)
+	}
+	evictionsMetric.Add(1)
+	atomic.AddInt64(&c.stats.Evictions, 1)
+	c.removeEntry(e)
+}
+
+func (c *LFUCache) removeEntry(e *lfuEntry) {
+	heap.Remove(&c.entries, e.index)
+	delete(c.cache, e.item.Key)
+	atomic.StoreInt64(&c.cacheLen, int64(len(c.entries)))
+}
+
+// RemoveExpired removes all the expired and invalidated items from the cache
+func (c *LFUCache) RemoveExpired() int {
+	now := MillisecondNow()
+	var removed int
+	for _, e := range c.cache {
+		if e.item.ExpireAt <= now || (e.item.InvalidAt != 0 && e.item.InvalidAt < now) {
+			c.removeEntry(e)
+			removed++
+		}
+	}
+	atomic.AddInt64(&c.stats.Expired, int64(removed))
+	return removed
+}
+
+// Size returns the number of items in the cache.
+func (c *LFUCache) Size() int64 {
+	return atomic.LoadInt64(&c.cacheLen)
+}
+
+// Stats returns the activity of the cache since it was created. Unlike other methods
+// Stats() is safe to call concurrently with other calls to the cache.
+func (c *LFUCache) Stats() CacheStats {
+	return CacheStats{
+		Size:               atomic.LoadInt64(&c.cacheLen),
+		Hit:                atomic.LoadInt64(&c.stats.Hit),
+		Miss:               atomic.LoadInt64(&c.stats.Miss),
+		Evictions:          atomic.LoadInt64(&c.stats.Evictions),
+		UnexpiredEvictions: atomic.LoadInt64(&c.stats.UnexpiredEvictions),
+		Expired:            atomic.LoadInt64(&c.stats.Expired),
+	}
+}
+
+// GetStaleItem returns the item stored in the cache even if it was invalidated, see StaleCache
+func (c *LFUCache) GetStaleItem(key string) (*CacheItem, bool) {
+	e, hit := c.cache[key]
+	if !hit {
+		return nil, false
+	}
+	item := e.item
+	if item.ExpireAt <= MillisecondNow() {
+		return nil, false
+	}
+	return item, true
+}
+
+// UpdateExpiration updates the expiration time for the key
+func (c *LFUCache) UpdateExpiration(key string, expireAt int64) bool {
+	if e, hit := c.cache[key]; hit {
+		e.item.ExpireAt = expireAt
+		return true
+	}
+	return false
+}
+
+func (c *LFUCache) Close() error {
+	c.cache = nil
+	c.entries = nil
+	c.cacheLen = 0
+	return nil
+}
+
+// lfuHeap implements heap.Interface with the least used entry of the lowest priority first
+type lfuHeap []*lfuEntry
+
+func (h lfuHeap) Len() int { return len(h) }
+
+func (h lfuHeap) Less(i, j int) bool {
+	if h[i].priority != h[j].priority {
+		return h[i].priority < h[j].priority
+	}
+	if h[i].uses != h[j].uses {
+		return h[i].uses < h[j].uses
+	}
+	return h[i].usedAt < h[j].usedAt
+}
+
+func (h lfuHeap) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].index = i
+	h[j].index = j
+}
+
+func (h *lfuHeap) Push(x interface{}) {
+	e := x.(*lfuEntry)
+	e.index = len(*h)
+	*h = append(*h, e)
+}
+
+func (h *lfuHeap) Pop() interface{} {
+	old := *h
+	n := len(old)
+	e := old[n-1]
+	old[n-1] = nil
+	*h = old[:n-1]
+	return e
+}
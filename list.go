@@ -0,0 +1,301 @@
+/*
+Copyright 2018-2022 Mailgun Technologies Inc
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gubernator
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/mailgun/holster/v4/errors"
+	"github.com/mailgun/holster/v4/tracing"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const defaultListPageSize = 100
+
+// ListRateLimits lists the active rate limits of a namespace by asking each peer in the local
+// datacenter for the rate limits it owns and merging the results.
+func (s *V1Instance) ListRateLimits(ctx context.Context, r *ListRateLimitsReq) (retval *ListRateLimitsResp, reterr error) {
+	ctx = tracing.StartScope(ctx)
+	defer func() {
+		tracing.EndScope(ctx, reterr)
+	}()
+
+	if err := checkListRequest(r); err != nil {
+		return nil, err
+	}
+
+	s.peerMutex.RLock()
+	peers := s.conf.LocalPicker.Peers()
+	s.peerMutex.RUnlock()
+
+	pages := make([]*ListRateLimitsResp, len(peers))
+	errs := make([]error, len(peers))
+	var wg sync.WaitGroup
+	for i, peer := range peers {
+		wg.Add(1)
+		go func(i int, peer *PeerClient) {
+			defer wg.Done()
+			if peer.Info().IsOwner {
+				pages[i], errs[i] = s.ListPeerRateLimits(ctx, r)
+				return
+			}
+			pages[i], errs[i] = peer.ListPeerRateLimits(ctx, r)
+		}(i, peer)
+	}
+	wg.Wait()
+
+	// A partial listing would be misleading, so fail if any peer could not be asked
+	for i, err := range errs {
+		if err != nil {
+			return nil, errors.Wrapf(err, "while listing rate limits on peer '%s'", peers[i].Info().GRPCAddress)
+		}
+	}
+
+	return mergeListPages(pages, listPageSize(r)), nil
+}
+
+// ListPeerRateLimits lists the active rate limits of a namespace owned by this instance. This method
+// should only be called by a peer fanning out an AdminV1 ListRateLimits request.
+func (s *V1Instance) ListPeerRateLimits(ctx context.Context, r *ListRateLimitsReq) (retval *ListRateLimitsResp, reterr error) {
+	ctx = tracing.StartScope(ctx)
+	defer func() {
+		tracing.EndScope(ctx, reterr)
+	}()
+
+	if err := checkListRequest(r); err != nil {
+		return nil, err
+	}
+
+	items := make(map[string]*RateLimitItem)
+	err := s.walkRateLimits(ctx, r.Name, r.IncludeStore, func(rl *RateLimitItem) error {
+		if rl.UniqueKey > r.Cursor {
+			items[rl.UniqueKey] = rl
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	page := &ListRateLimitsResp{}
+	for _, rl := range items {
+		page.Items = append(page.Items, rl)
+	}
+	return mergeListPages([]*ListRateLimitsResp{page}, listPageSize(r)), nil
+}
+
+// StreamRateLimits streams the active rate limits of a namespace by asking each peer in the local
+// datacenter in turn to stream the rate limits it owns.
+func (s *V1Instance) StreamRateLimits(r *StreamRateLimitsReq, stream AdminV1_StreamRateLimitsServer) (reterr error) {
+	ctx := tracing.StartScope(stream.Context())
+	defer func() {
+		tracing.EndScope(ctx, reterr)
+	}()
+
+	if len(r.Name) == 0 {
+		return status.Error(codes.InvalidArgument, "field 'name' cannot be empty")
+	}
+
+	s.peerMutex.RLock()
+	peers := s.conf.LocalPicker.Peers()
+	s.peerMutex.RUnlock()
+
+	// Peers are streamed one at a time, such that a slow client only holds up a single peer
+	for _, peer := range peers {
+		var err error
+		if peer.Info().IsOwner {
+			err = s.walkRateLimits(ctx, r.Name, r.IncludeStore, stream.Send)
+		} else {
+			err = peer.StreamPeerRateLimits(ctx, r, stream.Send)
+		}
+		if err != nil {
+			return errors.Wrapf(err, "while streaming rate limits from peer '%s'", peer.Info().GRPCAddress)
+		}
+	}
+	return nil
+}
+
+// StreamPeerRateLimits streams the active rate limits of a namespace owned by this instance. This method
+// should only be called by a peer fanning out an AdminV1 StreamRateLimits request.
+func (s *V1Instance) StreamPeerRateLimits(r *StreamRateLimitsReq, stream PeersV1_StreamPeerRateLimitsServer) (reterr error) {
+	ctx := tracing.StartScope(stream.Context())
+	defer func() {
+		tracing.EndScope(ctx, reterr)
+	}()
+
+	if len(r.Name) == 0 {
+		return status.Error(codes.InvalidArgument, "field 'name' cannot be empty")
+	}
+	return s.walkRateLimits(ctx, r.Name, r.IncludeStore, stream.Send)
+}
+
+// walkRateLimits calls `fn` with each active rate limit of the namespace owned by this instance, first
+// from the cache and then, if `includeStore` is set, from the store. Each rate limit is provided once.
+// The walk is abandoned once `fn` returns an error, which is returned.
+func (s *V1Instance) walkRateLimits(ctx context.Context, name string, includeStore bool, fn func(*RateLimitItem) error) error {
+	prefix := name + "_"
+	now := MillisecondNow()
+	// Only needed to skip the rate limits in the store which were found in the cache
+	var seen map[string]struct{}
+	if includeStore {
+		seen = make(map[string]struct{})
+	}
+
+	var reterr error
+	add := func(item *CacheItem) {
+		if reterr != nil || item.ExpireAt <= now || !strings.HasPrefix(item.Key, prefix) {
+			return
+		}
+		key := strings.TrimPrefix(item.Key, prefix)
+		if seen != nil {
+			if _, ok := seen[key]; ok {
+				return
+			}
+		}
+		// Skip the rate limits we don't own, such as the local copies of GLOBAL rate limits
+		owner, err := s.GetPeer(ctx, item.Key)
+		if err != nil || !owner.Info().IsOwner {
+			return
+		}
+		rl := cacheItemToListItem(item, now)
+		if rl == nil {
+			return
+		}
+		if seen != nil {
+			seen[key] = struct{}{}
+		}
+		rl.Name = name
+		rl.UniqueKey = key
+		reterr = fn(rl)
+	}
+
+	// The channel must be read to completion, else the pool workers remain locked
+	for item := range s.gubernatorPool.each(ctx) {
+		add(item)
+	}
+	if reterr != nil {
+		return reterr
+	}
+
+	// Items in the cache are more recent than those in the store
+	if s.bulkStore != nil && includeStore {
+		ch, err := s.bulkStore.LoadAll()
+		if err != nil {
+			return errors.Wrap(err, "Error in store.LoadAll")
+		}
+		for item := range ch {
+			add(item)
+		}
+	}
+	return reterr
+}
+
+func checkListRequest(r *ListRateLimitsReq) error {
+	if len(r.Name) == 0 {
+		return status.Error(codes.InvalidArgument, "field 'name' cannot be empty")
+	}
+	if r.PageSize < 0 || r.PageSize > maxBatchSize {
+		return status.Errorf(codes.OutOfRange, "field 'page_size' must be between 0 and '%d'", maxBatchSize)
+	}
+	return nil
+}
+
+func listPageSize(r *ListRateLimitsReq) int {
+	if r.PageSize == 0 {
+		return defaultListPageSize
+	}
+	return int(r.PageSize)
+}
+
+// mergeListPages merges the pages into a single page of at most `size` items ordered by key. Since
+// each page holds the first items after the cursor, the merged page does as well.
+func mergeListPages(pages []*ListRateLimitsResp, size int) *ListRateLimitsResp {
+	resp := &ListRateLimitsResp{}
+	more := false
+	for _, page := range pages {
+		resp.Items = append(resp.Items, page.Items...)
+		more = more || page.NextCursor != ""
+	}
+
+	sort.Slice(resp.Items, func(i, j int) bool {
+		return resp.Items[i].UniqueKey < resp.Items[j].UniqueKey
+	})
+
+	if len(resp.Items) > size {
+		resp.Items = resp.Items[:size]
+		more = true
+	}
+	if more && len(resp.Items) != 0 {
+		resp.NextCursor = resp.Items[len(resp.Items)-1].UniqueKey
+	}
+	return resp
+}
+
+// cacheItemToListItem returns nil if the item does not hold the state of a rate limit algorithm
+func cacheItemToListItem(item *CacheItem, now int64) *RateLimitItem {
+	switch t := item.Value.(type) {
+	case *TokenBucketItem:
+		return &RateLimitItem{
+			Algorithm: Algorithm_TOKEN_BUCKET,
+			Limit:     t.Limit,
+			Remaining: tokenBucketRemaining(t.Remaining),
+			ResetTime: item.ExpireAt,
+		}
+	case *LeakyBucketItem:
+		if t.Limit == 0 {
+			return nil
+		}
+		// Account for the hits which leaked since the bucket was last updated. The LeakProfile of the
+		// request is not kept with the bucket, as such the leak is estimated as LINEAR.
+		rate := float64(t.Duration) / float64(t.Limit)
+		remaining := t.Remaining
+		if rate > 0 {
+			remaining += float64(elapsedMillis(now, t.UpdatedAt)) / rate
+		}
+		if remaining > float64(t.Burst) {
+			remaining = float64(t.Burst)
+		}
+		return &RateLimitItem{
+			Algorithm: Algorithm_LEAKY_BUCKET,
+			Limit:     t.Limit,
+			Remaining: int64(remaining),
+			ResetTime: leakyBucketResetTime(now, t.Limit, int64(remaining), rate, LeakProfile_LINEAR),
+		}
+	case *HybridBucketItem:
+		// The bucket is full once the window ends, else account for the hits which leaked
+		b := *t
+		if now >= addMillis(b.CreatedAt, b.Duration) {
+			b.Remaining = float64(b.Burst)
+		} else if b.Duration > 0 {
+			b.Remaining += float64(elapsedMillis(now, b.UpdatedAt)) * float64(b.Limit) / float64(b.Duration)
+		}
+		if b.Remaining > float64(b.Burst) {
+			b.Remaining = float64(b.Burst)
+		}
+		return &RateLimitItem{
+			Algorithm: Algorithm_HYBRID,
+			Limit:     b.Limit,
+			Remaining: int64(b.Remaining),
+			ResetTime: hybridBucketResetTime(&b, now),
+		}
+	}
+	return nil
+}
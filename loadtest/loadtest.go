@@ -0,0 +1,280 @@
+/*
+Copyright 2018-2022 Mailgun Technologies Inc
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package loadtest drives a configurable load of rate limit checks against a gubernator cluster and
+// reports the throughput and latency observed by the clients. Used by the gubernator-loadtest command
+// and by benchmarks to compare the performance of changes.
+package loadtest
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/mailgun/gubernator/v2"
+	"github.com/mailgun/gubernator/v2/cluster"
+	"github.com/mailgun/holster/v4/clock"
+	"github.com/mailgun/holster/v4/ctxutil"
+	"github.com/mailgun/holster/v4/errors"
+	"github.com/mailgun/holster/v4/setter"
+	"golang.org/x/time/rate"
+)
+
+// Config configures a load test run by Run()
+type Config struct {
+	// (Optional) The GRPC addresses of a running cluster to send requests to, spread evenly between the
+	// workers. Defaults to an in-process cluster of `Peers` instances started by Run() for the run.
+	Addresses []string
+
+	// (Optional) The number of instances of the in-process cluster. Ignored if `Addresses` is set.
+	// Defaults to 3
+	Peers int
+
+	// (Optional) The GetRateLimits() requests per second sent by all the workers together. Defaults to 0,
+	// which sends requests as fast as the cluster answers them.
+	QPS float64
+
+	// (Optional) The number of workers sending requests concurrently. Defaults to 10
+	Concurrency int
+
+	// (Optional) The number of GetRateLimits() requests to send. Defaults to 0, which sends requests
+	// until `Duration` has passed.
+	Requests int
+
+	// (Optional) How long to send requests for if `Requests` is not set. Defaults to 10 seconds
+	Duration time.Duration
+
+	// (Optional) The number of distinct unique keys the rate limit checks are spread across, chosen
+	// at random. Defaults to 1,000
+	Keys int
+
+	// (Optional) The number of rate limit checks in each GetRateLimits() request. Defaults to 1
+	ChecksPerRequest int
+
+	// (Optional) The timeout of each GetRateLimits() request. Defaults to 1 second
+	Timeout time.Duration
+
+	// (Optional) The `name` of the rate limits checked. Defaults to 'loadtest'
+	Name string
+
+	// (Optional) The `algorithm` of the rate limits checked. Defaults to TOKEN_BUCKET
+	Algorithm gubernator.Algorithm
+
+	// (Optional) The `behavior` of the rate limits checked.
+	Behavior gubernator.Behavior
+
+	// (Optional) The `limit` of the rate limits checked. Defaults to 1,000
+	Limit int64
+
+	// (Optional) The `duration` of the rate limits checked in milliseconds. Defaults to 1 minute
+	RateLimitDuration int64
+
+	// (Optional) The `hits` of each rate limit check. Defaults to 1
+	Hits int64
+}
+
+// Result reports the load observed by the clients during a run
+type Result struct {
+	// The number of GetRateLimits() requests which completed, including those which failed
+	Requests int64
+	// The number of GetRateLimits() requests which failed, or returned an error for any of the checks
+	Errors int64
+	// The number of rate limit checks which were OVER_LIMIT
+	OverLimit int64
+	// How long the requests took to send
+	Elapsed time.Duration
+	// Percentiles of the latency of the GetRateLimits() requests
+	P50, P90, P99, Max time.Duration
+}
+
+// Throughput returns the completed GetRateLimits() requests per second
+func (r Result) Throughput() float64 {
+	if r.Elapsed <= 0 {
+		return 0
+	}
+	return float64(r.Requests) / r.Elapsed.Seconds()
+}
+
+func (r Result) String() string {
+	return fmt.Sprintf("requests: %d errors: %d over_limit: %d elapsed: %s throughput: %.1f/s "+
+		"latency p50: %s p90: %s p99: %s max: %s", r.Requests, r.Errors, r.OverLimit,
+		r.Elapsed.Round(time.Millisecond), r.Throughput(), r.P50, r.P90, r.P99, r.Max)
+}
+
+// Run sends rate limit checks as configured until `Requests` have been sent, `Duration` has passed
+// or the context is cancelled, and reports the load observed.
+func Run(ctx context.Context, conf Config) (Result, error) {
+	if err := setDefaults(&conf); err != nil {
+		return Result{}, err
+	}
+
+	addresses := conf.Addresses
+	if len(addresses) == 0 {
+		if cluster.NumOfDaemons() != 0 {
+			return Result{}, errors.New("an in-process cluster is already running; provide Config.Addresses")
+		}
+		if err := cluster.Start(conf.Peers); err != nil {
+			return Result{}, errors.Wrap(err, "while starting the in-process cluster")
+		}
+		defer cluster.Stop()
+		for _, peer := range cluster.GetPeers() {
+			addresses = append(addresses, peer.GRPCAddress)
+		}
+	}
+
+	clients := make([]gubernator.V1Client, len(addresses))
+	for i, address := range addresses {
+		client, err := gubernator.DialV1Server(address, nil)
+		if err != nil {
+			return Result{}, errors.Wrapf(err, "while connecting to '%s'", address)
+		}
+		clients[i] = client
+	}
+
+	if conf.Requests == 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = ctxutil.WithTimeout(ctx, conf.Duration)
+		defer cancel()
+	}
+	var limiter *rate.Limiter
+	if conf.QPS > 0 {
+		limiter = rate.NewLimiter(rate.Limit(conf.QPS), 1)
+	}
+
+	var (
+		result    Result
+		sent      int64
+		mutex     sync.Mutex
+		latencies []time.Duration
+		wg        sync.WaitGroup
+	)
+	start := clock.Now()
+	for i := 0; i < conf.Concurrency; i++ {
+		wg.Add(1)
+		go func(client gubernator.V1Client) {
+			defer wg.Done()
+			var local []time.Duration
+			for ctx.Err() == nil {
+				if conf.Requests != 0 && atomic.AddInt64(&sent, 1) > int64(conf.Requests) {
+					break
+				}
+				if limiter != nil && limiter.Wait(ctx) != nil {
+					break
+				}
+
+				begin := clock.Now()
+				overLimit, err := send(ctx, client, &conf)
+				// Requests cut short by the end of the run are not counted
+				if err != nil && ctx.Err() != nil {
+					break
+				}
+				local = append(local, clock.Since(begin))
+				atomic.AddInt64(&result.Requests, 1)
+				atomic.AddInt64(&result.OverLimit, overLimit)
+				if err != nil {
+					atomic.AddInt64(&result.Errors, 1)
+				}
+			}
+			mutex.Lock()
+			latencies = append(latencies, local...)
+			mutex.Unlock()
+		}(clients[i%len(clients)])
+	}
+	wg.Wait()
+	result.Elapsed = clock.Since(start)
+
+	sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+	result.P50 = percentile(latencies, 0.50)
+	result.P90 = percentile(latencies, 0.90)
+	result.P99 = percentile(latencies, 0.99)
+	if len(latencies) != 0 {
+		result.Max = latencies[len(latencies)-1]
+	}
+	return result, nil
+}
+
+// send sends a single GetRateLimits() request and returns the number of checks which were OVER_LIMIT
+func send(ctx context.Context, client gubernator.V1Client, conf *Config) (int64, error) {
+	req := &gubernator.GetRateLimitsReq{Requests: make([]*gubernator.RateLimitReq, conf.ChecksPerRequest)}
+	for i := range req.Requests {
+		req.Requests[i] = &gubernator.RateLimitReq{
+			Name:      conf.Name,
+			UniqueKey: fmt.Sprintf("key:%d", rand.Intn(conf.Keys)),
+			Algorithm: conf.Algorithm,
+			Behavior:  conf.Behavior,
+			Limit:     conf.Limit,
+			Duration:  conf.RateLimitDuration,
+			Hits:      conf.Hits,
+		}
+	}
+
+	ctx, cancel := ctxutil.WithTimeout(ctx, conf.Timeout)
+	defer cancel()
+	resp, err := client.GetRateLimits(ctx, req)
+	if err != nil {
+		return 0, err
+	}
+
+	var overLimit int64
+	for _, rl := range resp.Responses {
+		if rl.Error != "" {
+			err = errors.New(rl.Error)
+		}
+		if rl.Status == gubernator.Status_OVER_LIMIT {
+			overLimit++
+		}
+	}
+	return overLimit, err
+}
+
+// percentile returns the latency below which the fraction `p` of the sorted latencies fall
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+	idx := int(float64(len(sorted))*p+0.5) - 1
+	if idx < 0 {
+		idx = 0
+	}
+	if idx >= len(sorted) {
+		idx = len(sorted) - 1
+	}
+	return sorted[idx]
+}
+
+func setDefaults(conf *Config) error {
+	if conf.Requests < 0 {
+		return fmt.Errorf("Config.Requests cannot be negative; got '%d'", conf.Requests)
+	}
+	if conf.QPS < 0 {
+		return fmt.Errorf("Config.QPS cannot be negative; got '%f'", conf.QPS)
+	}
+	setter.SetDefault(&conf.Peers, 3)
+	setter.SetDefault(&conf.Concurrency, 10)
+	setter.SetDefault(&conf.Duration, time.Second*10)
+	setter.SetDefault(&conf.Keys, 1_000)
+	setter.SetDefault(&conf.ChecksPerRequest, 1)
+	setter.SetDefault(&conf.Timeout, time.Second)
+	setter.SetDefault(&conf.Name, "loadtest")
+	setter.SetDefault(&conf.Limit, int64(1_000))
+	setter.SetDefault(&conf.RateLimitDuration, int64(gubernator.Minute))
+	setter.SetDefault(&conf.Hits, int64(1))
+	return nil
+}
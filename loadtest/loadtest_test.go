@@ -0,0 +1,130 @@
+/*
+Copyright 2018-2022 Mailgun Technologies Inc
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package loadtest_test
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/mailgun/gubernator/v2"
+	"github.com/mailgun/gubernator/v2/cluster"
+	"github.com/mailgun/gubernator/v2/loadtest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMain(m *testing.M) {
+	if err := cluster.Start(3); err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	code := m.Run()
+	cluster.Stop()
+	os.Exit(code)
+}
+
+func addresses() []string {
+	var addrs []string
+	for _, peer := range cluster.GetPeers() {
+		addrs = append(addrs, peer.GRPCAddress)
+	}
+	return addrs
+}
+
+func TestRun(t *testing.T) {
+	res, err := loadtest.Run(context.Background(), loadtest.Config{
+		Addresses:        addresses(),
+		Requests:         200,
+		Concurrency:      4,
+		Keys:             10,
+		ChecksPerRequest: 2,
+		Name:             "test_loadtest_run",
+		Limit:            20,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, int64(200), res.Requests)
+	assert.Zero(t, res.Errors)
+	// 400 checks across 10 keys with a limit of 20 each
+	assert.GreaterOrEqual(t, res.OverLimit, int64(400-10*20))
+	assert.LessOrEqual(t, res.P50, res.P99)
+	assert.LessOrEqual(t, res.P99, res.Max)
+	assert.Greater(t, res.Throughput(), 0.0)
+
+	t.Run("QPS", func(t *testing.T) {
+		res, err := loadtest.Run(context.Background(), loadtest.Config{
+			Addresses: addresses(),
+			Requests:  20,
+			QPS:       100,
+			Name:      "test_loadtest_qps",
+		})
+		require.NoError(t, err)
+		assert.Equal(t, int64(20), res.Requests)
+		// The first request is sent immediately, the remainder at 100 per second
+		assert.GreaterOrEqual(t, res.Elapsed, 150*time.Millisecond)
+	})
+
+	t.Run("Duration", func(t *testing.T) {
+		res, err := loadtest.Run(context.Background(), loadtest.Config{
+			Addresses: addresses(),
+			Duration:  200 * time.Millisecond,
+			QPS:       100,
+			Name:      "test_loadtest_duration",
+		})
+		require.NoError(t, err)
+		assert.NotZero(t, res.Requests)
+		assert.Less(t, res.Elapsed, time.Second)
+	})
+
+	t.Run("In-process cluster is already running", func(t *testing.T) {
+		_, err := loadtest.Run(context.Background(), loadtest.Config{Requests: 1})
+		require.Error(t, err)
+	})
+}
+
+// BenchmarkLoadTest runs the full cluster path at a small scale, run with -benchtime to
+// control the number of requests, IE: `go test -run none -bench . -benchtime 1000x ./loadtest`
+func BenchmarkLoadTest(b *testing.B) {
+	for _, test := range []struct {
+		name      string
+		algorithm gubernator.Algorithm
+		behavior  gubernator.Behavior
+	}{
+		{name: "Token bucket", algorithm: gubernator.Algorithm_TOKEN_BUCKET},
+		{name: "Leaky bucket", algorithm: gubernator.Algorithm_LEAKY_BUCKET},
+		{name: "Global", algorithm: gubernator.Algorithm_TOKEN_BUCKET, behavior: gubernator.Behavior_GLOBAL},
+	} {
+		b.Run(test.name, func(b *testing.B) {
+			b.ResetTimer()
+			res, err := loadtest.Run(context.Background(), loadtest.Config{
+				Addresses: addresses(),
+				Requests:  b.N,
+				Keys:      100,
+				Name:      "benchmark_loadtest",
+				Algorithm: test.algorithm,
+				Behavior:  test.behavior,
+			})
+			require.NoError(b, err)
+			require.Zero(b, res.Errors)
+			b.ReportMetric(float64(res.P50.Microseconds()), "p50-µs")
+			b.ReportMetric(float64(res.P99.Microseconds()), "p99-µs")
+			b.ReportMetric(res.Throughput(), "req/s")
+		})
+	}
+}
@@ -20,6 +20,8 @@ package gubernator
 
 import (
 	"container/list"
+	"fmt"
+	"sort"
 	"sync/atomic"
 
 	"github.com/mailgun/holster/v4/clock"
@@ -27,13 +29,53 @@ import (
 	"github.com/prometheus/client_golang/prometheus"
 )
 
-// Cache is an LRU cache that supports expiration.
+// Cache is an LRU cache that supports expiration. When full the least recently used item of the
+// lowest CacheItem.Priority is evicted.
 // Not thread-safe.  Be sure to use a mutex to prevent concurrent method calls.
 type LRUCache struct {
-	cache     map[string]*list.Element
-	ll        *list.List
-	cacheSize int
-	cacheLen  int64
+	cache map[string]*list.Element
+	// The items of each priority, most recently used first
+	lists map[int32]*list.List
+	// The priorities which have items, lowest first
+	priorities []int32
+	cacheSize  int
+	cacheLen   int64
+	stats      CacheStats
+	onEvict    func(*CacheItem)
+}
+
+// LRUCacheConfig configures an LRUCache
+type LRUCacheConfig struct {
+	// (Optional) The maximum number of items the cache will hold before evicting the least
+	// recently used item of the lowest priority. Defaults to 50,000.
+	MaxSize int
+
+	// (Optional) Called with each item evicted from the cache to make room for a new item. This gives
+	// the implementor the opportunity to persist the rate limit before it is lost. Called while the
+	// cache is in use by the caller of Add(); the callback must not call methods of the cache.
+	OnEvict func(*CacheItem)
+
+	// (Optional) The number of independently locked shards `MaxSize` is divided between by
+	// NewShardedLRUCache(). Ignored by NewLRUCacheWithConfig(). Defaults to 32.
+	Shards int
+}
+
+// CacheStats reports the activity of an LRUCache since it was created
+type CacheStats struct {
+	// The number of items in the cache
+	Size int64
+	// The number of GetItem() calls which returned an item
+	Hit int64
+	// The number of GetItem() calls which did not return an item, including those
+	// which found an item that had expired
+	Miss int64
+	// The number of items removed to make room for new items because the cache was full
+	Evictions int64
+	// The number of evicted items which had not yet expired. Unexpired evictions
+	// cause rate limits to reset early and indicate the cache size is too small.
+	UnexpiredEvictions int64
+	// The number of expired items removed by RemoveExpired()
+	Expired int64
 }
 
 // Prometheus metrics collector for LRUCache.
@@ -43,6 +85,8 @@ type LRUCacheCollector struct {
 }
 
 var _ Cache = &LRUCache{}
+var _ ExpiringCache = &LRUCache{}
+var _ StaleCache = &LRUCache{}
 var _ prometheus.Collector = &LRUCacheCollector{}
 
 var sizeMetric = prometheus.NewGauge(prometheus.GaugeOpts{
@@ -57,16 +101,40 @@ var unexpiredEvictionsMetric = prometheus.NewCounter(prometheus.CounterOpts{
 	Name: "gubernator_unexpired_evictions_count",
 	Help: "Count the number of cache items which were evicted while unexpired.",
 })
+var evictionsMetric = prometheus.NewCounter(prometheus.CounterOpts{
+	Name: "gubernator_cache_evictions_count",
+	Help: "Count the number of cache items which were evicted to make room for new items.",
+})
+
+// lruEntry is the value of each element of the lists of an LRUCache
+type lruEntry struct {
+	item *CacheItem
+	// The priority of the list which holds the entry, the item may since have changed priority
+	priority int32
+}
 
-// New creates a new Cache with a maximum size.
+// New creates a new Cache with a maximum size. If maxSize is less than 1 the default size is used.
 func NewLRUCache(maxSize int) *LRUCache {
-	setter.SetDefault(&maxSize, 50_000)
+	if maxSize < 0 {
+		maxSize = 0
+	}
+	c, _ := NewLRUCacheWithConfig(LRUCacheConfig{MaxSize: maxSize})
+	return c
+}
+
+// NewLRUCacheWithConfig creates a new Cache as configured by LRUCacheConfig
+func NewLRUCacheWithConfig(conf LRUCacheConfig) (*LRUCache, error) {
+	if conf.MaxSize < 0 {
+		return nil, fmt.Errorf("LRUCacheConfig.MaxSize cannot be negative; got '%d'", conf.MaxSize)
+	}
+	setter.SetDefault(&conf.MaxSize, 50_000)
 
 	return &LRUCache{
 		cache:     make(map[string]*list.Element),
-		ll:        list.New(),
-		cacheSize: maxSize,
-	}
+		lists:     make(map[int32]*list.List),
+		cacheSize: conf.MaxSize,
+		onEvict:   conf.OnEvict,
+	}, nil
 }
 
 // FIXME: Not threadsafe.  Each() maintains a goroutine that iterates.
@@ -78,7 +146,7 @@ func (c *LRUCache) Each() chan *CacheItem {
 	out := make(chan *CacheItem)
 	go func() {
 		for _, ele := range c.cache {
-			out <- ele.Value.(*CacheItem)
+			out <- ele.Value.(*lruEntry).item
 		}
 		close(out)
 	}()
@@ -89,20 +157,61 @@ func (c *LRUCache) Each() chan *CacheItem {
 func (c *LRUCache) Add(item *CacheItem) bool {
 	// If the key already exist, set the new value
 	if ee, ok := c.cache[item.Key]; ok {
-		c.ll.MoveToFront(ee)
-		ee.Value = item
+		ee.Value.(*lruEntry).item = item
+		c.touch(ee)
 		return true
 	}
 
-	ele := c.ll.PushFront(item)
-	c.cache[item.Key] = ele
-	if c.cacheSize != 0 && c.ll.Len() > c.cacheSize {
+	c.push(item)
+	if len(c.cache) > c.cacheSize {
 		c.removeOldest()
 	}
-	atomic.StoreInt64(&c.cacheLen, int64(c.ll.Len()))
+	atomic.StoreInt64(&c.cacheLen, int64(len(c.cache)))
 	return false
 }
 
+// push adds the item as the most recently used of its priority
+func (c *LRUCache) push(item *CacheItem) {
+	l, ok := c.lists[item.Priority]
+	if !ok {
+		l = list.New()
+		c.lists[item.Priority] = l
+		i := sort.Search(len(c.priorities), func(i int) bool { return c.priorities[i] > item.Priority })
+		c.priorities = append(c.priorities, 0)
+		copy(c.priorities[i+1:], c.priorities[i:])
+		c.priorities[i] = item.Priority
+	}
+	c.cache[item.Key] = l.PushFront(&lruEntry{item: item, priority: item.Priority})
+}
+
+// touch marks the element as the most recently used of the priority of its item
+func (c *LRUCache) touch(ele *list.Element) {
+	e := ele.Value.(*lruEntry)
+	if e.item.Priority == e.priority {
+		c.lists[e.priority].MoveToFront(ele)
+		return
+	}
+	c.unlink(ele)
+	c.push(e.item)
+}
+
+// unlink removes the element from the list of its priority, but not from the cache map
+func (c *LRUCache) unlink(ele *list.Element) {
+	e := ele.Value.(*lruEntry)
+	l := c.lists[e.priority]
+	l.Remove(ele)
+	if l.Len() != 0 {
+		return
+	}
+	delete(c.lists, e.priority)
+	for i, p := range c.priorities {
+		if p == e.priority {
+			c.priorities = append(c.priorities[:i], c.priorities[i+1:]...)
+			break
+		}
+	}
+}
+
 // Return unix epoch in milliseconds
 func MillisecondNow() int64 {
 	return clock.Now().UnixNano() / 1000000
@@ -111,32 +220,39 @@ func MillisecondNow() int64 {
 // GetItem returns the item stored in the cache
 func (c *LRUCache) GetItem(key string) (item *CacheItem, ok bool) {
 	if ele, hit := c.cache[key]; hit {
-		entry := ele.Value.(*CacheItem)
+		entry := ele.Value.(*lruEntry).item
 
 		now := MillisecondNow()
 		// If the entry is invalidated
 		if entry.InvalidAt != 0 && entry.InvalidAt < now {
 			c.removeElement(ele)
-			accessMetric.WithLabelValues("miss").Add(1)
+			c.miss()
 			return
 		}
 
-		// If the entry has expired, remove it from the cache
-		if entry.ExpireAt < now {
+		// If the entry has expired, remove it from the cache. The millisecond at
+		// which the entry expires belongs to the next rate limit window.
+		if entry.ExpireAt <= now {
 			c.removeElement(ele)
-			accessMetric.WithLabelValues("miss").Add(1)
+			c.miss()
 			return
 		}
 
 		accessMetric.WithLabelValues("hit").Add(1)
-		c.ll.MoveToFront(ele)
+		atomic.AddInt64(&c.stats.Hit, 1)
+		c.touch(ele)
 		return entry, true
 	}
 
-	accessMetric.WithLabelValues("miss").Add(1)
+	c.miss()
 	return
 }
 
+func (c *LRUCache) miss() {
+	accessMetric.WithLabelValues("miss").Add(1)
+	atomic.AddInt64(&c.stats.Miss, 1)
+}
+
 // Remove removes the provided key from the cache.
 func (c *LRUCache) Remove(key string) {
 	if ele, hit := c.cache[key]; hit {
@@ -144,25 +260,53 @@ func (c *LRUCache) Remove(key string) {
 	}
 }
 
-// RemoveOldest removes the oldest item from the cache.
+// RemoveOldest removes the oldest item of the lowest priority from the cache.
 func (c *LRUCache) removeOldest() {
-	ele := c.ll.Back()
+	if len(c.priorities) == 0 {
+		return
+	}
+	ele := c.lists[c.priorities[0]].Back()
 	if ele != nil {
-		entry := ele.Value.(*CacheItem)
+		entry := ele.Value.(*lruEntry).item
 
 		if MillisecondNow() < entry.ExpireAt {
 			unexpiredEvictionsMetric.Add(1)
+			atomic.AddInt64(&c.stats.UnexpiredEvictions, 1)
 		}
+		evictionsMetric.Add(1)
+		atomic.AddInt64(&c.stats.Evictions, 1)
 
 		c.removeElement(ele)
+		if c.onEvict != nil {
+			c.onEvict(entry)
+		}
 	}
 }
 
 func (c *LRUCache) removeElement(e *list.Element) {
-	c.ll.Remove(e)
-	kv := e.Value.(*CacheItem)
-	delete(c.cache, kv.Key)
-	atomic.StoreInt64(&c.cacheLen, int64(c.ll.Len()))
+	c.unlink(e)
+	delete(c.cache, e.Value.(*lruEntry).item.Key)
+	atomic.StoreInt64(&c.cacheLen, int64(len(c.cache)))
+}
+
+// RemoveExpired removes all the expired and invalidated items from the cache. Expired items would
+// otherwise remain in the cache until accessed or evicted by newer items.
+func (c *LRUCache) RemoveExpired() int {
+	now := MillisecondNow()
+	var removed int
+	for _, l := range c.lists {
+		for ele := l.Back(); ele != nil; {
+			prev := ele.Prev()
+			entry := ele.Value.(*lruEntry).item
+			if entry.ExpireAt <= now || (entry.InvalidAt != 0 && entry.InvalidAt < now) {
+				c.removeElement(ele)
+				removed++
+			}
+			ele = prev
+		}
+	}
+	atomic.AddInt64(&c.stats.Expired, int64(removed))
+	return removed
 }
 
 // Returns the number of items in the cache.
@@ -170,10 +314,36 @@ func (c *LRUCache) Size() int64 {
 	return atomic.LoadInt64(&c.cacheLen)
 }
 
+// Stats returns the activity of the cache since it was created. Unlike other methods
+// Stats() is safe to call concurrently with other calls to the cache.
+func (c *LRUCache) Stats() CacheStats {
+	return CacheStats{
+		Size:               atomic.LoadInt64(&c.cacheLen),
+		Hit:                atomic.LoadInt64(&c.stats.Hit),
+		Miss:               atomic.LoadInt64(&c.stats.Miss),
+		Evictions:          atomic.LoadInt64(&c.stats.Evictions),
+		UnexpiredEvictions: atomic.LoadInt64(&c.stats.UnexpiredEvictions),
+		Expired:            atomic.LoadInt64(&c.stats.Expired),
+	}
+}
+
+// GetStaleItem returns the item stored in the cache even if it was invalidated, see StaleCache
+func (c *LRUCache) GetStaleItem(key string) (*CacheItem, bool) {
+	ele, hit := c.cache[key]
+	if !hit {
+		return nil, false
+	}
+	item := ele.Value.(*lruEntry).item
+	if item.ExpireAt <= MillisecondNow() {
+		return nil, false
+	}
+	return item, true
+}
+
 // Update the expiration time for the key
 func (c *LRUCache) UpdateExpiration(key string, expireAt int64) bool {
 	if ele, hit := c.cache[key]; hit {
-		entry := ele.Value.(*CacheItem)
+		entry := ele.Value.(*lruEntry).item
 		entry.ExpireAt = expireAt
 		return true
 	}
@@ -182,7 +352,8 @@ func (c *LRUCache) UpdateExpiration(key string, expireAt int64) bool {
 
 func (c *LRUCache) Close() error {
 	c.cache = nil
-	c.ll = nil
+	c.lists = nil
+	c.priorities = nil
 	c.cacheLen = 0
 	return nil
 }
@@ -203,6 +374,7 @@ func (collector *LRUCacheCollector) Describe(ch chan<- *prometheus.Desc) {
 	sizeMetric.Describe(ch)
 	accessMetric.Describe(ch)
 	unexpiredEvictionsMetric.Describe(ch)
+	evictionsMetric.Describe(ch)
 }
 
 // Collect fetches metric counts and gauges from the cache
@@ -211,6 +383,7 @@ func (collector *LRUCacheCollector) Collect(ch chan<- prometheus.Metric) {
 	sizeMetric.Collect(ch)
 	accessMetric.Collect(ch)
 	unexpiredEvictionsMetric.Collect(ch)
+	evictionsMetric.Collect(ch)
 }
 
 func (collector *LRUCacheCollector) getSize() float64 {
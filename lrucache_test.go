@@ -348,6 +348,8 @@ func TestLRUCache(t *testing.T) {
 
 		cache := gubernator.NewLRUCache(10)
 		cacheCollector.AddCache(cache)
+		// The metric is shared by every cache in the process
+		before := unexpiredEvictions(t, cacheCollector)
 
 		// fill cache with short duration cache items
 		for i := 0; i < 10; i++ {
@@ -370,17 +372,8 @@ func TestLRUCache(t *testing.T) {
 			ExpireAt:  clock.Now().Add(1 * time.Hour).UnixMilli(),
 		})
 
-		collChan := make(chan prometheus.Metric, 64)
-		cacheCollector.Collect(collChan)
 		// Check metrics to verify evicted cache key is expired
-		<-collChan
-		<-collChan
-		<-collChan
-		m := <-collChan // gubernator_unexpired_evictions_count
-		met := new(dto.Metric)
-		m.Write(met)
-		assert.Contains(t, m.Desc().String(), "gubernator_unexpired_evictions_count")
-		assert.Equal(t, 0, int(*met.Counter.Value))
+		assert.Equal(t, before, unexpiredEvictions(t, cacheCollector))
 	})
 
 	t.Run("Check gubernator_unexpired_evictions_count metric is incremented when unexpired item is evicted", func(t *testing.T) {
@@ -395,6 +388,8 @@ func TestLRUCache(t *testing.T) {
 
 		cache := gubernator.NewLRUCache(10)
 		cacheCollector.AddCache(cache)
+		// The metric is shared by every cache in the process
+		before := unexpiredEvictions(t, cacheCollector)
 
 		// fill cache with long duration cache items
 		for i := 0; i < 10; i++ {
@@ -415,19 +410,150 @@ func TestLRUCache(t *testing.T) {
 		})
 
 		// Check metrics to verify evicted cache key is *NOT* expired
-		collChan := make(chan prometheus.Metric, 64)
-		cacheCollector.Collect(collChan)
-		<-collChan
-		<-collChan
-		<-collChan
-		m := <-collChan // gubernator_unexpired_evictions_count
-		met := new(dto.Metric)
-		m.Write(met)
-		assert.Contains(t, m.Desc().String(), "gubernator_unexpired_evictions_count")
-		assert.Equal(t, 1, int(*met.Counter.Value))
+		assert.Equal(t, before+1, unexpiredEvictions(t, cacheCollector))
 	})
 }
 
+// unexpiredEvictions returns the value of the gubernator_unexpired_evictions_count metric
+func unexpiredEvictions(t *testing.T, cacheCollector *gubernator.LRUCacheCollector) int {
+	collChan := make(chan prometheus.Metric, 64)
+	cacheCollector.Collect(collChan)
+	<-collChan
+	<-collChan
+	<-collChan
+	m := <-collChan // gubernator_unexpired_evictions_count
+	met := new(dto.Metric)
+	m.Write(met)
+	assert.Contains(t, m.Desc().String(), "gubernator_unexpired_evictions_count")
+	return int(*met.Counter.Value)
+}
+
+func TestLRUCacheConfig(t *testing.T) {
+	expireAt := clock.Now().Add(time.Hour).UnixMilli()
+	fill := func(cache *gubernator.LRUCache, n int) {
+		for i := 0; i < n; i++ {
+			cache.Add(&gubernator.CacheItem{Key: strconv.Itoa(i), Value: i, ExpireAt: expireAt})
+		}
+	}
+
+	t.Run("Default capacity", func(t *testing.T) {
+		cache, err := gubernator.NewLRUCacheWithConfig(gubernator.LRUCacheConfig{})
+		require.NoError(t, err)
+		fill(cache, 50_001)
+		assert.Equal(t, int64(50_000), cache.Size())
+
+		_, ok := cache.GetItem("0")
+		assert.False(t, ok)
+	})
+
+	t.Run("Explicit capacity", func(t *testing.T) {
+		cache, err := gubernator.NewLRUCacheWithConfig(gubernator.LRUCacheConfig{MaxSize: 5})
+		require.NoError(t, err)
+		fill(cache, 10)
+		assert.Equal(t, int64(5), cache.Size())
+
+		for i := 0; i < 10; i++ {
+			_, ok := cache.GetItem(strconv.Itoa(i))
+			assert.Equal(t, i >= 5, ok, i)
+		}
+	})
+
+	t.Run("Invalid capacity", func(t *testing.T) {
+		_, err := gubernator.NewLRUCacheWithConfig(gubernator.LRUCacheConfig{MaxSize: -1})
+		assert.EqualError(t, err, "LRUCacheConfig.MaxSize cannot be negative; got '-1'")
+	})
+
+	t.Run("OnEvict", func(t *testing.T) {
+		var evicted []string
+		cache, err := gubernator.NewLRUCacheWithConfig(gubernator.LRUCacheConfig{
+			MaxSize: 5,
+			OnEvict: func(item *gubernator.CacheItem) {
+				evicted = append(evicted, item.Key)
+			},
+		})
+		require.NoError(t, err)
+		fill(cache, 8)
+		assert.Equal(t, []string{"0", "1", "2"}, evicted)
+
+		// Removing an item is not an eviction
+		cache.Remove("7")
+		assert.Len(t, evicted, 3)
+	})
+}
+
+func TestLRUCacheRemoveExpired(t *testing.T) {
+	defer clock.Freeze(clock.Now()).Unfreeze()
+
+	cache := gubernator.NewLRUCache(0)
+	for i := 0; i < 10; i++ {
+		cache.Add(&gubernator.CacheItem{
+			Key:      fmt.Sprintf("short-%d", i),
+			Value:    i,
+			ExpireAt: clock.Now().Add(time.Minute).UnixMilli(),
+		})
+		cache.Add(&gubernator.CacheItem{
+			Key:      fmt.Sprintf("long-%d", i),
+			Value:    i,
+			ExpireAt: clock.Now().Add(time.Hour).UnixMilli(),
+		})
+	}
+
+	assert.Equal(t, 0, cache.RemoveExpired())
+	assert.Equal(t, int64(20), cache.Size())
+
+	clock.Advance(time.Minute)
+	assert.Equal(t, 10, cache.RemoveExpired())
+	assert.Equal(t, int64(10), cache.Size())
+	assert.Equal(t, int64(10), cache.Stats().Expired)
+
+	for i := 0; i < 10; i++ {
+		_, ok := cache.GetItem(fmt.Sprintf("long-%d", i))
+		assert.True(t, ok)
+	}
+}
+
+func TestLRUCacheStats(t *testing.T) {
+	defer clock.Freeze(clock.Now()).Unfreeze()
+
+	cache := gubernator.NewLRUCache(10)
+
+	// Overflow the cache, the first 5 items are evicted before they expire
+	for i := 0; i < 15; i++ {
+		cache.Add(&gubernator.CacheItem{
+			Algorithm: gubernator.Algorithm_TOKEN_BUCKET,
+			Key:       fmt.Sprintf("key-%d", i),
+			Value:     "bar",
+			ExpireAt:  clock.Now().Add(time.Minute).UnixMilli(),
+		})
+	}
+	stats := cache.Stats()
+	assert.Equal(t, int64(10), stats.Size)
+	assert.Equal(t, int64(5), stats.Evictions)
+	assert.Equal(t, int64(5), stats.UnexpiredEvictions)
+
+	_, ok := cache.GetItem("key-0")
+	assert.False(t, ok)
+	_, ok = cache.GetItem("key-14")
+	assert.True(t, ok)
+
+	// Expired items are not counted as unexpired evictions
+	clock.Advance(2 * time.Minute)
+	cache.Add(&gubernator.CacheItem{
+		Algorithm: gubernator.Algorithm_TOKEN_BUCKET,
+		Key:       "key-15",
+		Value:     "bar",
+		ExpireAt:  clock.Now().Add(time.Minute).UnixMilli(),
+	})
+
+	assert.Equal(t, gubernator.CacheStats{
+		Size:               10,
+		Hit:                1,
+		Miss:               1,
+		Evictions:          6,
+		UnexpiredEvictions: 5,
+	}, cache.Stats())
+}
+
 func BenchmarkLRUCache(b *testing.B) {
 	var mutex sync.Mutex
 
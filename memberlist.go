@@ -26,6 +26,7 @@ import (
 	"net"
 	"runtime"
 	"strconv"
+	"sync"
 
 	ml "github.com/hashicorp/memberlist"
 	"github.com/mailgun/holster/v4/clock"
@@ -66,7 +67,7 @@ type MemberListPoolConfig struct {
 }
 
 func NewMemberListPool(ctx context.Context, conf MemberListPoolConfig) (*MemberListPool, error) {
-	setter.SetDefault(conf.Logger, logrus.WithField("category", "gubernator"))
+	setter.SetDefault(&conf.Logger, logrus.WithField("category", "gubernator"))
 	m := &MemberListPool{
 		log:  conf.Logger,
 		conf: conf,
@@ -97,6 +98,7 @@ func NewMemberListPool(ctx context.Context, conf MemberListPoolConfig) (*MemberL
 	config.Events = m.events
 	config.AdvertiseAddr = host
 	config.AdvertisePort = port
+	config.BindPort = port
 
 	if conf.NodeName != "" {
 		config.Name = conf.NodeName
@@ -158,6 +160,7 @@ func (m *MemberListPool) Close() {
 }
 
 type memberListEventHandler struct {
+	mutex sync.Mutex
 	peers map[string]PeerInfo
 	log   FieldLogger
 	conf  MemberListPoolConfig
@@ -178,10 +181,12 @@ func (e *memberListEventHandler) addPeer(node *ml.Node) {
 	peer, err := unmarshallPeer(node.Meta, ip)
 	if err != nil {
 		e.log.WithError(err).Warnf("while adding to peers")
-	} else {
-		e.peers[ip] = peer
-		e.callOnUpdate()
+		return
 	}
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	e.peers[node.Address()] = peer
+	e.callOnUpdate()
 }
 
 func (e *memberListEventHandler) NotifyJoin(node *ml.Node) {
@@ -195,15 +200,18 @@ func (e *memberListEventHandler) NotifyJoin(node *ml.Node) {
 		return
 	}
 	peer.IsOwner = false
-	e.peers[ip] = peer
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	e.peers[node.Address()] = peer
 	e.callOnUpdate()
 }
 
 func (e *memberListEventHandler) NotifyLeave(node *ml.Node) {
-	ip := getIP(node.Address())
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
 
 	// Remove PeerInfo
-	delete(e.peers, ip)
+	delete(e.peers, node.Address())
 
 	e.callOnUpdate()
 }
@@ -214,12 +222,16 @@ func (e *memberListEventHandler) NotifyUpdate(node *ml.Node) {
 	peer, err := unmarshallPeer(node.Meta, ip)
 	if err != nil {
 		e.log.WithError(err).Warn("while unmarshalling peer info")
+		return
 	}
 	peer.IsOwner = false
-	e.peers[ip] = peer
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	e.peers[node.Address()] = peer
 	e.callOnUpdate()
 }
 
+// callOnUpdate must be called with the mutex held
 func (e *memberListEventHandler) callOnUpdate() {
 	var peers []PeerInfo
 
@@ -0,0 +1,112 @@
+/*
+Copyright 2018-2022 Mailgun Technologies Inc
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gubernator_test
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	guber "github.com/mailgun/gubernator/v2"
+	"github.com/mailgun/holster/v4/clock"
+	"github.com/mailgun/holster/v4/testutil"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type peerRecorder struct {
+	mutex sync.Mutex
+	peers []guber.PeerInfo
+}
+
+func (r *peerRecorder) OnUpdate(peers []guber.PeerInfo) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.peers = peers
+}
+
+// Addresses returns the sorted GRPC addresses of the last update
+func (r *peerRecorder) Addresses() []string {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	var addrs []string
+	for _, p := range r.peers {
+		addrs = append(addrs, p.GRPCAddress)
+	}
+	sort.Strings(addrs)
+	return addrs
+}
+
+func TestMemberListPool(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), clock.Second*10)
+	defer cancel()
+
+	members := []struct {
+		grpc       string
+		memberList string
+	}{
+		{grpc: "127.0.0.1:9700", memberList: "127.0.0.1:9710"},
+		{grpc: "127.0.0.1:9701", memberList: "127.0.0.1:9711"},
+		{grpc: "127.0.0.1:9702", memberList: "127.0.0.1:9712"},
+	}
+
+	var recorders []*peerRecorder
+	var pools []*guber.MemberListPool
+	for _, m := range members {
+		r := &peerRecorder{}
+		pool, err := guber.NewMemberListPool(ctx, guber.MemberListPoolConfig{
+			Advertise:         guber.PeerInfo{GRPCAddress: m.grpc},
+			MemberListAddress: m.memberList,
+			KnownNodes:        []string{members[0].memberList},
+			NodeName:          m.grpc,
+			OnUpdate:          r.OnUpdate,
+			Logger:            logrus.WithField("member", m.grpc),
+		})
+		require.NoError(t, err)
+		recorders = append(recorders, r)
+		pools = append(pools, pool)
+	}
+
+	all := []string{members[0].grpc, members[1].grpc, members[2].grpc}
+	testutil.UntilPass(t, 50, 100*time.Millisecond, func(t testutil.TestingT) {
+		for _, r := range recorders {
+			assert.Equal(t, all, r.Addresses())
+		}
+	})
+
+	// Each member should report itself as the owner
+	for i, r := range recorders {
+		r.mutex.Lock()
+		for _, p := range r.peers {
+			assert.Equal(t, p.GRPCAddress == members[i].grpc, p.IsOwner)
+		}
+		r.mutex.Unlock()
+	}
+
+	// The remaining members should remove the member which left
+	pools[2].Close()
+	testutil.UntilPass(t, 50, 100*time.Millisecond, func(t testutil.TestingT) {
+		for _, r := range recorders[:2] {
+			assert.Equal(t, all[:2], r.Addresses())
+		}
+	})
+	pools[0].Close()
+	pools[1].Close()
+}
@@ -0,0 +1,98 @@
+/*
+Copyright 2018-2022 Mailgun Technologies Inc
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gubernator
+
+import (
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/sirupsen/logrus"
+)
+
+// otherLabelValue labels the metadata values seen once Config.MetadataLabelValues is reached
+const otherLabelValue = "other"
+
+// echoMetadata copies the metadata of the request into the response. Keys set by the server, such
+// as `owner`, are kept.
+func echoMetadata(r *RateLimitReq, rl *RateLimitResp) {
+	for k, v := range r.Metadata {
+		if _, ok := rl.Metadata[k]; ok {
+			continue
+		}
+		setMetadata(rl, k, v)
+	}
+}
+
+// metadataFields returns the metadata of the request as fields of a log line
+func metadataFields(r *RateLimitReq) logrus.Fields {
+	fields := make(logrus.Fields, len(r.Metadata))
+	for k, v := range r.Metadata {
+		fields["metadata."+k] = v
+	}
+	return fields
+}
+
+// metadataCounter counts rate limit checks labeled by the values of Config.MetadataLabels. The
+// number of distinct values counted per label is capped to bound the cardinality of the metric.
+type metadataCounter struct {
+	labels    []string
+	maxValues int
+	counter   *prometheus.CounterVec
+	mutex     sync.Mutex
+	seen      []map[string]struct{}
+}
+
+func newMetadataCounter(labels []string, maxValues int) *metadataCounter {
+	mc := metadataCounter{
+		labels:    labels,
+		maxValues: maxValues,
+		counter: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "gubernator_metadata_check_counter",
+			Help: "The number of rate limits checked, labeled by the request metadata keys in Config.MetadataLabels.  Label \"status\" is the status of the response or \"error\".",
+		}, append(append([]string{}, labels...), "status")),
+		seen: make([]map[string]struct{}, len(labels)),
+	}
+	for i := range mc.seen {
+		mc.seen[i] = make(map[string]struct{})
+	}
+	return &mc
+}
+
+// Observe counts the check of the request which returned the response
+func (mc *metadataCounter) Observe(r *RateLimitReq, rl *RateLimitResp) {
+	values := make([]string, len(mc.labels)+1)
+
+	mc.mutex.Lock()
+	for i, key := range mc.labels {
+		v := r.Metadata[key]
+		if _, ok := mc.seen[i][v]; !ok {
+			if len(mc.seen[i]) >= mc.maxValues {
+				v = otherLabelValue
+			} else {
+				mc.seen[i][v] = struct{}{}
+			}
+		}
+		values[i] = v
+	}
+	mc.mutex.Unlock()
+
+	values[len(mc.labels)] = rl.Status.String()
+	if rl.Error != "" {
+		values[len(mc.labels)] = "error"
+	}
+	mc.counter.WithLabelValues(values...).Add(1)
+}
@@ -56,7 +56,7 @@ func (mm *mutliRegionManager) runAsyncReqs() {
 			// Aggregate the hits into a single request
 			_, ok := hits[key]
 			if ok {
-				hits[key].Hits += r.Hits
+				aggregateHits(hits[key], r)
 			} else {
 				hits[key] = r
 			}
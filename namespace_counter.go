@@ -0,0 +1,79 @@
+/*
+Copyright 2018-2022 Mailgun Technologies Inc
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gubernator
+
+import (
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// namespaceCounter counts OVER_LIMIT responses labeled by the name of the rate limit. Only the names
+// in Config.OverLimitNamespaces, or else the first Config.OverLimitNamespaceValues names seen, are
+// counted under their own label to bound the cardinality of the metric.
+type namespaceCounter struct {
+	allowed   map[string]struct{}
+	maxValues int
+	counter   *prometheus.CounterVec
+	mutex     sync.Mutex
+	seen      map[string]struct{}
+}
+
+func newNamespaceCounter(names []string, maxValues int) *namespaceCounter {
+	nc := namespaceCounter{
+		maxValues: maxValues,
+		counter: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "gubernator_namespace_over_limit_counter",
+			Help: "The number of rate limit checks that are over the limit, labeled by the name of the rate limit.  Label \"name\" is \"other\" for names not in Config.OverLimitNamespaces or seen once Config.OverLimitNamespaceValues is reached.",
+		}, []string{"name"}),
+		seen: make(map[string]struct{}),
+	}
+	if len(names) != 0 {
+		nc.allowed = make(map[string]struct{}, len(names))
+		for _, name := range names {
+			nc.allowed[name] = struct{}{}
+		}
+	}
+	return &nc
+}
+
+// Observe counts the response if the request is over the limit
+func (nc *namespaceCounter) Observe(r *RateLimitReq, rl *RateLimitResp) {
+	if rl.Status != Status_OVER_LIMIT || rl.Error != "" {
+		return
+	}
+	nc.counter.WithLabelValues(nc.label(r.Name)).Add(1)
+}
+
+func (nc *namespaceCounter) label(name string) string {
+	if nc.allowed != nil {
+		if _, ok := nc.allowed[name]; ok {
+			return name
+		}
+		return otherLabelValue
+	}
+
+	nc.mutex.Lock()
+	defer nc.mutex.Unlock()
+	if _, ok := nc.seen[name]; !ok {
+		if len(nc.seen) >= nc.maxValues {
+			return otherLabelValue
+		}
+		nc.seen[name] = struct{}{}
+	}
+	return name
+}
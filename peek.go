@@ -0,0 +1,115 @@
+/*
+Copyright 2018-2022 Mailgun Technologies Inc
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gubernator
+
+import "context"
+
+// peekCache allows the rate limit algorithms to compute the result of a request with
+// Behavior_PEEK without modifying the underlying cache. Items are copied on read and
+// all changes are discarded.
+type peekCache struct {
+	cache Cache
+	items map[string]*CacheItem
+}
+
+var _ Cache = &peekCache{}
+
+func newPeekCache(c Cache) *peekCache {
+	return &peekCache{cache: c, items: make(map[string]*CacheItem)}
+}
+
+func (p *peekCache) Add(item *CacheItem) bool {
+	_, exists := p.GetItem(item.Key)
+	p.items[item.Key] = item
+	return exists
+}
+
+func (p *peekCache) UpdateExpiration(key string, expireAt int64) bool {
+	item, ok := p.GetItem(key)
+	if ok {
+		item.ExpireAt = expireAt
+	}
+	return ok
+}
+
+func (p *peekCache) GetItem(key string) (*CacheItem, bool) {
+	if item, ok := p.items[key]; ok {
+		return item, item != nil
+	}
+	item, ok := p.cache.GetItem(key)
+	if !ok {
+		return nil, false
+	}
+	item = copyCacheItem(item)
+	p.items[key] = item
+	return item, true
+}
+
+func (p *peekCache) Each() chan *CacheItem {
+	return p.cache.Each()
+}
+
+func (p *peekCache) Remove(key string) {
+	p.items[key] = nil
+}
+
+func (p *peekCache) Size() int64 {
+	return p.cache.Size()
+}
+
+func (p *peekCache) Close() error {
+	return nil
+}
+
+// peekStore reads through to the underlying store but discards all changes
+type peekStore struct {
+	store Store
+}
+
+func newPeekStore(s Store) Store {
+	if s == nil {
+		return nil
+	}
+	return &peekStore{store: s}
+}
+
+func (p *peekStore) OnChange(context.Context, *RateLimitReq, *CacheItem) {}
+
+func (p *peekStore) Get(ctx context.Context, r *RateLimitReq) (*CacheItem, bool) {
+	return p.store.Get(ctx, r)
+}
+
+func (p *peekStore) GetWithError(ctx context.Context, r *RateLimitReq) (*CacheItem, bool, error) {
+	return getFromStore(ctx, p.store, r)
+}
+
+func (p *peekStore) Remove(context.Context, string) {}
+
+// peekGlobal computes the result of applying the hits of the request to a GLOBAL rate limit
+// response from the local cache, without queueing the hits to the owning peer.
+func peekGlobal(r *RateLimitReq, rl *RateLimitResp) *RateLimitResp {
+	cost := requestCost(r)
+	if cost == 0 {
+		return rl
+	}
+	if cost > float64(rl.Remaining) {
+		rl.Status = Status_OVER_LIMIT
+		return rl
+	}
+	rl.Remaining = tokenBucketRemaining(float64(rl.Remaining) - cost)
+	return rl
+}
@@ -20,6 +20,7 @@ import (
 	"context"
 	"crypto/tls"
 	"fmt"
+	"io"
 	"sync"
 
 	"github.com/mailgun/holster/v4/clock"
@@ -33,7 +34,9 @@ import (
 	"go.opentelemetry.io/otel/attribute"
 	"go.opentelemetry.io/otel/trace"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/status"
 )
 
 type PeerPicker interface {
@@ -54,11 +57,13 @@ const (
 )
 
 type PeerClient struct {
-	client   PeersV1Client
-	conn     *grpc.ClientConn
-	conf     PeerConfig
-	queue    chan *request
-	lastErrs *collections.LRUCache
+	client    PeersV1Client
+	conn      *grpc.ClientConn
+	admin     PeersV1Client
+	adminConn *grpc.ClientConn
+	conf      PeerConfig
+	queue     chan *request
+	lastErrs  *collections.LRUCache
 
 	mutex  sync.RWMutex   // This mutex is for verifying the closing state of the client
 	status peerStatus     // Keep the current status of the peer
@@ -77,10 +82,24 @@ type request struct {
 }
 
 type PeerConfig struct {
-	TLS      *tls.Config
-	Behavior BehaviorConfig
-	Info     PeerInfo
-	Log      FieldLogger
+	TLS *tls.Config
+	// (Optional) The TLS config used to relay AdminV1 requests to `Info.AdminAddress`, defaults to TLS
+	AdminTLS  *tls.Config
+	Transport GRPCTransportConfig
+	Behavior  BehaviorConfig
+	Info      PeerInfo
+	Log       FieldLogger
+	// (Optional) The provider used to trace requests to the peer, defaults to the global provider
+	TracerProvider trace.TracerProvider
+}
+
+// otelgrpcOptions returns the options for the OpenTelemetry gRPC interceptors. If `tp` is nil the
+// global tracer provider is used.
+func otelgrpcOptions(tp trace.TracerProvider) []otelgrpc.Option {
+	if tp == nil {
+		return nil
+	}
+	return []otelgrpc.Option{otelgrpc.WithTracerProvider(tp)}
 }
 
 func NewPeerClient(conf PeerConfig) *PeerClient {
@@ -135,24 +154,27 @@ func (c *PeerClient) connect(ctx context.Context) (reterr error) {
 			return nil
 		}
 
-		// Setup OpenTelemetry interceptor to propagate spans.
-		opts := []grpc.DialOption{
-			grpc.WithUnaryInterceptor(otelgrpc.UnaryClientInterceptor()),
-			grpc.WithStreamInterceptor(otelgrpc.StreamClientInterceptor()),
-		}
-
-		if c.conf.TLS != nil {
-			opts = append(opts, grpc.WithTransportCredentials(credentials.NewTLS(c.conf.TLS)))
-		} else {
-			opts = append(opts, grpc.WithInsecure())
-		}
-
 		var err error
-		c.conn, err = grpc.Dial(c.conf.Info.GRPCAddress, opts...)
+		c.conn, err = grpc.Dial(c.conf.Info.GRPCAddress, c.dialOptions(c.conf.TLS)...)
 		if err != nil {
 			return c.setLastErr(&PeerErr{err: errors.Wrapf(err, "failed to dial peer %s", c.conf.Info.GRPCAddress)})
 		}
 		c.client = NewPeersV1Client(c.conn)
+		c.admin = c.client
+
+		// AdminV1 requests are relayed to the admin listener of the peer if it has one
+		if c.conf.Info.AdminAddress != "" {
+			adminTLS := c.conf.TLS
+			if c.conf.AdminTLS != nil {
+				adminTLS = c.conf.AdminTLS
+			}
+			c.adminConn, err = grpc.Dial(c.conf.Info.AdminAddress, c.dialOptions(adminTLS)...)
+			if err != nil {
+				c.conn.Close()
+				return c.setLastErr(&PeerErr{err: errors.Wrapf(err, "failed to dial peer %s", c.conf.Info.AdminAddress)})
+			}
+			c.admin = NewPeersV1Client(c.adminConn)
+		}
 		c.status = peerConnected
 		go c.run()
 		return nil
@@ -161,6 +183,22 @@ func (c *PeerClient) connect(ctx context.Context) (reterr error) {
 	return nil
 }
 
+// dialOptions returns the options used to dial the peer with the provided TLS config
+func (c *PeerClient) dialOptions(tlsConf *tls.Config) []grpc.DialOption {
+	// Setup OpenTelemetry interceptor to propagate spans.
+	opts := []grpc.DialOption{
+		grpc.WithUnaryInterceptor(otelgrpc.UnaryClientInterceptor(otelgrpcOptions(c.conf.TracerProvider)...)),
+		grpc.WithStreamInterceptor(otelgrpc.StreamClientInterceptor(otelgrpcOptions(c.conf.TracerProvider)...)),
+	}
+
+	if tlsConf != nil {
+		opts = append(opts, grpc.WithTransportCredentials(credentials.NewTLS(tlsConf)))
+	} else {
+		opts = append(opts, grpc.WithInsecure())
+	}
+	return append(opts, c.conf.Transport.DialOptions()...)
+}
+
 // Info returns PeerInfo struct that describes this PeerClient
 func (c *PeerClient) Info() PeerInfo {
 	return c.conf.Info
@@ -274,6 +312,206 @@ func (c *PeerClient) UpdatePeerGlobals(ctx context.Context, r *UpdatePeerGlobals
 	return resp, err
 }
 
+// TransferRateLimits hands off the state of rate limits to the peer
+func (c *PeerClient) TransferRateLimits(ctx context.Context, r *TransferRateLimitsReq) (retval *TransferRateLimitsResp, reterr error) {
+	ctx = tracing.StartScope(ctx)
+	defer func() {
+		tracing.EndScope(ctx, reterr)
+	}()
+
+	if err := c.connect(ctx); err != nil {
+		return nil, c.setLastErr(err)
+	}
+
+	// See NOTE above about RLock and wg.Add(1)
+	c.mutex.RLock()
+	c.wg.Add(1)
+	defer func() {
+		c.mutex.RUnlock()
+		defer c.wg.Done()
+	}()
+
+	resp, err := c.client.TransferRateLimits(ctx, r)
+	if err != nil {
+		c.setLastErr(err)
+	}
+
+	return resp, err
+}
+
+// SetPeerRemaining relays an AdminV1 SetRemaining request to the peer, over `Info.AdminAddress` if provided
+func (c *PeerClient) SetPeerRemaining(ctx context.Context, r *SetRemainingReq) (retval *SetRemainingResp, reterr error) {
+	ctx = tracing.StartScope(ctx)
+	defer func() {
+		tracing.EndScope(ctx, reterr)
+	}()
+
+	if err := c.connect(ctx); err != nil {
+		return nil, c.setLastErr(err)
+	}
+
+	// See NOTE above about RLock and wg.Add(1)
+	c.mutex.RLock()
+	c.wg.Add(1)
+	defer func() {
+		c.mutex.RUnlock()
+		defer c.wg.Done()
+	}()
+
+	resp, err := c.admin.SetPeerRemaining(ctx, r)
+	// An unknown rate limit says nothing about the health of the peer
+	if err != nil && status.Code(err) != codes.NotFound {
+		c.setLastErr(err)
+	}
+
+	return resp, err
+}
+
+// DeletePeerRateLimit relays an AdminV1 DeleteRateLimit request to the peer, over `Info.AdminAddress` if provided
+func (c *PeerClient) DeletePeerRateLimit(ctx context.Context, r *DeleteRateLimitReq) (retval *DeleteRateLimitResp, reterr error) {
+	ctx = tracing.StartScope(ctx)
+	defer func() {
+		tracing.EndScope(ctx, reterr)
+	}()
+
+	if err := c.connect(ctx); err != nil {
+		return nil, c.setLastErr(err)
+	}
+
+	// See NOTE above about RLock and wg.Add(1)
+	c.mutex.RLock()
+	c.wg.Add(1)
+	defer func() {
+		c.mutex.RUnlock()
+		defer c.wg.Done()
+	}()
+
+	resp, err := c.admin.DeletePeerRateLimit(ctx, r)
+	if err != nil {
+		c.setLastErr(err)
+	}
+
+	return resp, err
+}
+
+// GetPeerGlobalView asks the peer for its view of a GLOBAL rate limit
+func (c *PeerClient) GetPeerGlobalView(ctx context.Context, r *GetGlobalRateLimitViewsReq) (retval *GlobalRateLimitView, reterr error) {
+	ctx = tracing.StartScope(ctx)
+	defer func() {
+		tracing.EndScope(ctx, reterr)
+	}()
+
+	if err := c.connect(ctx); err != nil {
+		return nil, c.setLastErr(err)
+	}
+
+	// See NOTE above about RLock and wg.Add(1)
+	c.mutex.RLock()
+	c.wg.Add(1)
+	defer func() {
+		c.mutex.RUnlock()
+		defer c.wg.Done()
+	}()
+
+	resp, err := c.client.GetPeerGlobalView(ctx, r)
+	if err != nil {
+		c.setLastErr(err)
+	}
+
+	return resp, err
+}
+
+// ListPeerRateLimits lists the rate limits owned by the peer, over `Info.AdminAddress` if provided
+func (c *PeerClient) ListPeerRateLimits(ctx context.Context, r *ListRateLimitsReq) (retval *ListRateLimitsResp, reterr error) {
+	ctx = tracing.StartScope(ctx)
+	defer func() {
+		tracing.EndScope(ctx, reterr)
+	}()
+
+	if err := c.connect(ctx); err != nil {
+		return nil, c.setLastErr(err)
+	}
+
+	// See NOTE above about RLock and wg.Add(1)
+	c.mutex.RLock()
+	c.wg.Add(1)
+	defer func() {
+		c.mutex.RUnlock()
+		defer c.wg.Done()
+	}()
+
+	resp, err := c.admin.ListPeerRateLimits(ctx, r)
+	if err != nil {
+		c.setLastErr(err)
+	}
+
+	return resp, err
+}
+
+// StreamPeerRateLimits calls `fn` with each rate limit streamed by the peer, over `Info.AdminAddress` if
+// provided, until the peer has streamed all the rate limits it owns or `fn` returns an error
+func (c *PeerClient) StreamPeerRateLimits(ctx context.Context, r *StreamRateLimitsReq, fn func(*RateLimitItem) error) (reterr error) {
+	ctx = tracing.StartScope(ctx)
+	defer func() {
+		tracing.EndScope(ctx, reterr)
+	}()
+
+	if err := c.connect(ctx); err != nil {
+		return c.setLastErr(err)
+	}
+
+	// See NOTE above about RLock and wg.Add(1)
+	c.mutex.RLock()
+	c.wg.Add(1)
+	defer func() {
+		c.mutex.RUnlock()
+		defer c.wg.Done()
+	}()
+
+	// Closes the stream if we return before the peer is done
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	stream, err := c.admin.StreamPeerRateLimits(ctx, r)
+	if err != nil {
+		return c.setLastErr(err)
+	}
+	for {
+		item, err := stream.Recv()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return c.setLastErr(err)
+		}
+		if err := fn(item); err != nil {
+			return err
+		}
+	}
+}
+
+// HealthCheck calls the V1 HealthCheck of the peer. A returned error indicates the peer is unreachable
+func (c *PeerClient) HealthCheck(ctx context.Context) (retval *HealthCheckResp, reterr error) {
+	ctx = tracing.StartScopeDebug(ctx)
+	defer func() {
+		tracing.EndScope(ctx, reterr)
+	}()
+
+	if err := c.connect(ctx); err != nil {
+		return nil, err
+	}
+
+	// See NOTE above about RLock and wg.Add(1)
+	c.mutex.RLock()
+	c.wg.Add(1)
+	defer func() {
+		c.mutex.RUnlock()
+		defer c.wg.Done()
+	}()
+
+	return NewV1Client(c.conn).HealthCheck(ctx, &HealthCheckReq{})
+}
+
 func (c *PeerClient) setLastErr(err error) error {
 	// If we get a nil error return without caching it
 	if err == nil {
@@ -533,6 +771,9 @@ func (c *PeerClient) Shutdown(ctx context.Context) error {
 		if c.conn != nil {
 			c.conn.Close()
 		}
+		if c.adminConn != nil {
+			c.adminConn.Close()
+		}
 	}()
 
 	// This allows us to wait on the waitgroup, or until the context
@@ -578,3 +819,59 @@ func IsNotReady(err error) bool {
 	te, ok := err.(notReadyErr)
 	return ok && te.NotReady()
 }
+
+// ResetPeerNamespace asks the peer to remove the rate limits of a namespace it holds, over
+// `Info.AdminAddress` if provided
+func (c *PeerClient) ResetPeerNamespace(ctx context.Context, r *ResetNamespaceReq) (retval *ResetNamespaceResp, reterr error) {
+	ctx = tracing.StartScope(ctx)
+	defer func() {
+		tracing.EndScope(ctx, reterr)
+	}()
+
+	if err := c.connect(ctx); err != nil {
+		return nil, c.setLastErr(err)
+	}
+
+	// See NOTE above about RLock and wg.Add(1)
+	c.mutex.RLock()
+	c.wg.Add(1)
+	defer func() {
+		c.mutex.RUnlock()
+		defer c.wg.Done()
+	}()
+
+	resp, err := c.admin.ResetPeerNamespace(ctx, r)
+	if err != nil {
+		c.setLastErr(err)
+	}
+
+	return resp, err
+}
+
+// ImportPeerRateLimits asks the peer to import the state of rate limits it owns, over `Info.AdminAddress`
+// if provided
+func (c *PeerClient) ImportPeerRateLimits(ctx context.Context, r *ImportRateLimitsReq) (retval *ImportRateLimitsResp, reterr error) {
+	ctx = tracing.StartScope(ctx)
+	defer func() {
+		tracing.EndScope(ctx, reterr)
+	}()
+
+	if err := c.connect(ctx); err != nil {
+		return nil, c.setLastErr(err)
+	}
+
+	// See NOTE above about RLock and wg.Add(1)
+	c.mutex.RLock()
+	c.wg.Add(1)
+	defer func() {
+		c.mutex.RUnlock()
+		defer c.wg.Done()
+	}()
+
+	resp, err := c.admin.ImportPeerRateLimits(ctx, r)
+	if err != nil {
+		c.setLastErr(err)
+	}
+
+	return resp, err
+}
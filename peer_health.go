@@ -0,0 +1,165 @@
+/*
+Copyright 2018-2022 Mailgun Technologies Inc
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gubernator
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/mailgun/holster/v4/syncutil"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var peerHealthMetric = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+	Name: "gubernator_peer_healthy",
+	Help: "Reports 1 if the peer passed the last health check and is in the hash ring, or 0 if the peer has been removed from the hash ring.",
+}, []string{"peerAddr"})
+
+// peerHealthChecker periodically health checks each peer in the local data center. Peers which fail
+// a health check are removed from the local picker such that rate limits owned by the unhealthy peer
+// are handled by the remaining peers. Once a peer passes a health check it is added back to the picker.
+type peerHealthChecker struct {
+	instance *V1Instance
+	conf     BehaviorConfig
+	log      FieldLogger
+	wg       syncutil.WaitGroup
+
+	// Clients used to health check peers, these are separate from the clients in the
+	// picker so we can continue to check peers that have been removed from the picker.
+	// Only accessed by the health check goroutine.
+	clients map[string]*PeerClient
+
+	mutex     sync.RWMutex
+	unhealthy map[string]struct{}
+}
+
+func newPeerHealthChecker(conf BehaviorConfig, instance *V1Instance) *peerHealthChecker {
+	h := &peerHealthChecker{
+		instance:  instance,
+		conf:      conf,
+		log:       instance.log,
+		clients:   make(map[string]*PeerClient),
+		unhealthy: make(map[string]struct{}),
+	}
+
+	tick := time.NewTicker(conf.PeerHealthCheckInterval)
+	h.wg.Until(func(done chan struct{}) bool {
+		select {
+		case <-tick.C:
+			h.check(context.Background())
+		case <-done:
+			tick.Stop()
+			return false
+		}
+		return true
+	})
+	return h
+}
+
+// IsHealthy returns false if the peer failed its last health check
+func (h *peerHealthChecker) IsHealthy(addr string) bool {
+	if h == nil {
+		return true
+	}
+	h.mutex.RLock()
+	defer h.mutex.RUnlock()
+	_, ok := h.unhealthy[addr]
+	return !ok
+}
+
+// check health checks all the peers in the local data center and updates
+// the local picker if the health of any peer has changed.
+func (h *peerHealthChecker) check(ctx context.Context) {
+	seen := make(map[string]struct{})
+	var clients []*PeerClient
+	for _, info := range h.instance.getPeerInfo() {
+		if info.IsOwner || info.DataCenter != h.instance.conf.DataCenter {
+			continue
+		}
+		seen[info.GRPCAddress] = struct{}{}
+		c, ok := h.clients[info.GRPCAddress]
+		if !ok {
+			c = NewPeerClient(PeerConfig{
+				TLS:            h.instance.conf.PeerTLS,
+				Transport:      h.instance.conf.PeerTransport,
+				Behavior:       h.conf,
+				Log:            h.log,
+				Info:           info,
+				TracerProvider: h.instance.conf.TracerProvider,
+			})
+			h.clients[info.GRPCAddress] = c
+		}
+		clients = append(clients, c)
+	}
+
+	// Forget about peers that are no longer in the cluster
+	for addr, c := range h.clients {
+		if _, ok := seen[addr]; !ok {
+			_ = c.Shutdown(ctx)
+			delete(h.clients, addr)
+			peerHealthMetric.DeleteLabelValues(addr)
+		}
+	}
+
+	result := FanOut(ctx, clients, h.conf.PeerHealthCheckTimeout,
+		func(ctx context.Context, c *PeerClient) (interface{}, error) {
+			return c.HealthCheck(ctx)
+		})
+
+	unhealthy := make(map[string]struct{})
+	for _, f := range result.Unresponsive {
+		unhealthy[f.Peer.GRPCAddress] = struct{}{}
+	}
+
+	var changed bool
+	h.mutex.Lock()
+	for addr := range seen {
+		_, was := h.unhealthy[addr]
+		_, is := unhealthy[addr]
+		if was == is {
+			continue
+		}
+		changed = true
+		if is {
+			h.log.WithField("peer", addr).Warn("peer failed health check; removing from picker")
+		} else {
+			h.log.WithField("peer", addr).Info("peer passed health check; adding to picker")
+		}
+	}
+	h.unhealthy = unhealthy
+	h.mutex.Unlock()
+
+	for addr := range seen {
+		if _, ok := unhealthy[addr]; ok {
+			peerHealthMetric.WithLabelValues(addr).Set(0)
+		} else {
+			peerHealthMetric.WithLabelValues(addr).Set(1)
+		}
+	}
+
+	if changed {
+		h.instance.SetPeers(h.instance.getPeerInfo())
+	}
+}
+
+func (h *peerHealthChecker) Close() {
+	h.wg.Stop()
+	for _, c := range h.clients {
+		_ = c.Shutdown(context.Background())
+	}
+}
@@ -22,6 +22,29 @@ type PeersV1Client interface {
 	GetPeerRateLimits(ctx context.Context, in *GetPeerRateLimitsReq, opts ...grpc.CallOption) (*GetPeerRateLimitsResp, error)
 	// Used by peers send global rate limit updates to other peers
 	UpdatePeerGlobals(ctx context.Context, in *UpdatePeerGlobalsReq, opts ...grpc.CallOption) (*UpdatePeerGlobalsResp, error)
+	// Used by a departing peer to hand off the rate limits it owns to the peer which will own
+	// them once the departing peer leaves the cluster
+	TransferRateLimits(ctx context.Context, in *TransferRateLimitsReq, opts ...grpc.CallOption) (*TransferRateLimitsResp, error)
+	// Used by peers to relay an AdminV1 SetRemaining request to the peer which owns the rate limit.
+	// Only served on the admin listener if provided
+	SetPeerRemaining(ctx context.Context, in *SetRemainingReq, opts ...grpc.CallOption) (*SetRemainingResp, error)
+	// Used by peers to relay an AdminV1 DeleteRateLimit request to the peer which owns the rate limit.
+	// Only served on the admin listener if provided
+	DeletePeerRateLimit(ctx context.Context, in *DeleteRateLimitReq, opts ...grpc.CallOption) (*DeleteRateLimitResp, error)
+	// Used by peers to list the rate limits owned by the peer for an AdminV1 ListRateLimits request.
+	// Only served on the admin listener if provided
+	ListPeerRateLimits(ctx context.Context, in *ListRateLimitsReq, opts ...grpc.CallOption) (*ListRateLimitsResp, error)
+	// Used by peers to stream the rate limits owned by the peer for an AdminV1 StreamRateLimits request.
+	// Only served on the admin listener if provided
+	StreamPeerRateLimits(ctx context.Context, in *StreamRateLimitsReq, opts ...grpc.CallOption) (PeersV1_StreamPeerRateLimitsClient, error)
+	// Used by peers to ask for the local view of a GLOBAL rate limit for an AdminV1 GetGlobalRateLimitViews request
+	GetPeerGlobalView(ctx context.Context, in *GetGlobalRateLimitViewsReq, opts ...grpc.CallOption) (*GlobalRateLimitView, error)
+	// Used by peers to remove the rate limits of a namespace held by the peer for an AdminV1 ResetNamespace request.
+	// Only served on the admin listener if provided
+	ResetPeerNamespace(ctx context.Context, in *ResetNamespaceReq, opts ...grpc.CallOption) (*ResetNamespaceResp, error)
+	// Used by peers to relay the items of an AdminV1 ImportRateLimits request to the peer which owns them.
+	// Only served on the admin listener if provided
+	ImportPeerRateLimits(ctx context.Context, in *ImportRateLimitsReq, opts ...grpc.CallOption) (*ImportRateLimitsResp, error)
 }
 
 type peersV1Client struct {
@@ -50,6 +73,101 @@ func (c *peersV1Client) UpdatePeerGlobals(ctx context.Context, in *UpdatePeerGlo
 	return out, nil
 }
 
+func (c *peersV1Client) TransferRateLimits(ctx context.Context, in *TransferRateLimitsReq, opts ...grpc.CallOption) (*TransferRateLimitsResp, error) {
+	out := new(TransferRateLimitsResp)
+	err := c.cc.Invoke(ctx, "/pb.gubernator.PeersV1/TransferRateLimits", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *peersV1Client) SetPeerRemaining(ctx context.Context, in *SetRemainingReq, opts ...grpc.CallOption) (*SetRemainingResp, error) {
+	out := new(SetRemainingResp)
+	err := c.cc.Invoke(ctx, "/pb.gubernator.PeersV1/SetPeerRemaining", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *peersV1Client) DeletePeerRateLimit(ctx context.Context, in *DeleteRateLimitReq, opts ...grpc.CallOption) (*DeleteRateLimitResp, error) {
+	out := new(DeleteRateLimitResp)
+	err := c.cc.Invoke(ctx, "/pb.gubernator.PeersV1/DeletePeerRateLimit", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *peersV1Client) ListPeerRateLimits(ctx context.Context, in *ListRateLimitsReq, opts ...grpc.CallOption) (*ListRateLimitsResp, error) {
+	out := new(ListRateLimitsResp)
+	err := c.cc.Invoke(ctx, "/pb.gubernator.PeersV1/ListPeerRateLimits", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *peersV1Client) StreamPeerRateLimits(ctx context.Context, in *StreamRateLimitsReq, opts ...grpc.CallOption) (PeersV1_StreamPeerRateLimitsClient, error) {
+	stream, err := c.cc.NewStream(ctx, &PeersV1_ServiceDesc.Streams[0], "/pb.gubernator.PeersV1/StreamPeerRateLimits", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &peersV1StreamPeerRateLimitsClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type PeersV1_StreamPeerRateLimitsClient interface {
+	Recv() (*RateLimitItem, error)
+	grpc.ClientStream
+}
+
+type peersV1StreamPeerRateLimitsClient struct {
+	grpc.ClientStream
+}
+
+func (x *peersV1StreamPeerRateLimitsClient) Recv() (*RateLimitItem, error) {
+	m := new(RateLimitItem)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *peersV1Client) GetPeerGlobalView(ctx context.Context, in *GetGlobalRateLimitViewsReq, opts ...grpc.CallOption) (*GlobalRateLimitView, error) {
+	out := new(GlobalRateLimitView)
+	err := c.cc.Invoke(ctx, "/pb.gubernator.PeersV1/GetPeerGlobalView", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *peersV1Client) ResetPeerNamespace(ctx context.Context, in *ResetNamespaceReq, opts ...grpc.CallOption) (*ResetNamespaceResp, error) {
+	out := new(ResetNamespaceResp)
+	err := c.cc.Invoke(ctx, "/pb.gubernator.PeersV1/ResetPeerNamespace", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *peersV1Client) ImportPeerRateLimits(ctx context.Context, in *ImportRateLimitsReq, opts ...grpc.CallOption) (*ImportRateLimitsResp, error) {
+	out := new(ImportRateLimitsResp)
+	err := c.cc.Invoke(ctx, "/pb.gubernator.PeersV1/ImportPeerRateLimits", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // PeersV1Server is the server API for PeersV1 service.
 // All implementations must embed UnimplementedPeersV1Server
 // for forward compatibility
@@ -58,6 +176,29 @@ type PeersV1Server interface {
 	GetPeerRateLimits(context.Context, *GetPeerRateLimitsReq) (*GetPeerRateLimitsResp, error)
 	// Used by peers send global rate limit updates to other peers
 	UpdatePeerGlobals(context.Context, *UpdatePeerGlobalsReq) (*UpdatePeerGlobalsResp, error)
+	// Used by a departing peer to hand off the rate limits it owns to the peer which will own
+	// them once the departing peer leaves the cluster
+	TransferRateLimits(context.Context, *TransferRateLimitsReq) (*TransferRateLimitsResp, error)
+	// Used by peers to relay an AdminV1 SetRemaining request to the peer which owns the rate limit.
+	// Only served on the admin listener if provided
+	SetPeerRemaining(context.Context, *SetRemainingReq) (*SetRemainingResp, error)
+	// Used by peers to relay an AdminV1 DeleteRateLimit request to the peer which owns the rate limit.
+	// Only served on the admin listener if provided
+	DeletePeerRateLimit(context.Context, *DeleteRateLimitReq) (*DeleteRateLimitResp, error)
+	// Used by peers to list the rate limits owned by the peer for an AdminV1 ListRateLimits request.
+	// Only served on the admin listener if provided
+	ListPeerRateLimits(context.Context, *ListRateLimitsReq) (*ListRateLimitsResp, error)
+	// Used by peers to stream the rate limits owned by the peer for an AdminV1 StreamRateLimits request.
+	// Only served on the admin listener if provided
+	StreamPeerRateLimits(*StreamRateLimitsReq, PeersV1_StreamPeerRateLimitsServer) error
+	// Used by peers to ask for the local view of a GLOBAL rate limit for an AdminV1 GetGlobalRateLimitViews request
+	GetPeerGlobalView(context.Context, *GetGlobalRateLimitViewsReq) (*GlobalRateLimitView, error)
+	// Used by peers to remove the rate limits of a namespace held by the peer for an AdminV1 ResetNamespace request.
+	// Only served on the admin listener if provided
+	ResetPeerNamespace(context.Context, *ResetNamespaceReq) (*ResetNamespaceResp, error)
+	// Used by peers to relay the items of an AdminV1 ImportRateLimits request to the peer which owns them.
+	// Only served on the admin listener if provided
+	ImportPeerRateLimits(context.Context, *ImportRateLimitsReq) (*ImportRateLimitsResp, error)
 	mustEmbedUnimplementedPeersV1Server()
 }
 
@@ -71,6 +212,30 @@ func (UnimplementedPeersV1Server) GetPeerRateLimits(context.Context, *GetPeerRat
 func (UnimplementedPeersV1Server) UpdatePeerGlobals(context.Context, *UpdatePeerGlobalsReq) (*UpdatePeerGlobalsResp, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method UpdatePeerGlobals not implemented")
 }
+func (UnimplementedPeersV1Server) TransferRateLimits(context.Context, *TransferRateLimitsReq) (*TransferRateLimitsResp, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method TransferRateLimits not implemented")
+}
+func (UnimplementedPeersV1Server) SetPeerRemaining(context.Context, *SetRemainingReq) (*SetRemainingResp, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SetPeerRemaining not implemented")
+}
+func (UnimplementedPeersV1Server) DeletePeerRateLimit(context.Context, *DeleteRateLimitReq) (*DeleteRateLimitResp, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DeletePeerRateLimit not implemented")
+}
+func (UnimplementedPeersV1Server) ListPeerRateLimits(context.Context, *ListRateLimitsReq) (*ListRateLimitsResp, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListPeerRateLimits not implemented")
+}
+func (UnimplementedPeersV1Server) StreamPeerRateLimits(*StreamRateLimitsReq, PeersV1_StreamPeerRateLimitsServer) error {
+	return status.Errorf(codes.Unimplemented, "method StreamPeerRateLimits not implemented")
+}
+func (UnimplementedPeersV1Server) GetPeerGlobalView(context.Context, *GetGlobalRateLimitViewsReq) (*GlobalRateLimitView, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetPeerGlobalView not implemented")
+}
+func (UnimplementedPeersV1Server) ResetPeerNamespace(context.Context, *ResetNamespaceReq) (*ResetNamespaceResp, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ResetPeerNamespace not implemented")
+}
+func (UnimplementedPeersV1Server) ImportPeerRateLimits(context.Context, *ImportRateLimitsReq) (*ImportRateLimitsResp, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ImportPeerRateLimits not implemented")
+}
 func (UnimplementedPeersV1Server) mustEmbedUnimplementedPeersV1Server() {}
 
 // UnsafePeersV1Server may be embedded to opt out of forward compatibility for this service.
@@ -120,6 +285,153 @@ func _PeersV1_UpdatePeerGlobals_Handler(srv interface{}, ctx context.Context, de
 	return interceptor(ctx, in, info, handler)
 }
 
+func _PeersV1_TransferRateLimits_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(TransferRateLimitsReq)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PeersV1Server).TransferRateLimits(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/pb.gubernator.PeersV1/TransferRateLimits",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PeersV1Server).TransferRateLimits(ctx, req.(*TransferRateLimitsReq))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _PeersV1_SetPeerRemaining_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SetRemainingReq)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PeersV1Server).SetPeerRemaining(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/pb.gubernator.PeersV1/SetPeerRemaining",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PeersV1Server).SetPeerRemaining(ctx, req.(*SetRemainingReq))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _PeersV1_DeletePeerRateLimit_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DeleteRateLimitReq)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PeersV1Server).DeletePeerRateLimit(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/pb.gubernator.PeersV1/DeletePeerRateLimit",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PeersV1Server).DeletePeerRateLimit(ctx, req.(*DeleteRateLimitReq))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _PeersV1_ListPeerRateLimits_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListRateLimitsReq)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PeersV1Server).ListPeerRateLimits(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/pb.gubernator.PeersV1/ListPeerRateLimits",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PeersV1Server).ListPeerRateLimits(ctx, req.(*ListRateLimitsReq))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _PeersV1_StreamPeerRateLimits_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(StreamRateLimitsReq)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(PeersV1Server).StreamPeerRateLimits(m, &peersV1StreamPeerRateLimitsServer{stream})
+}
+
+type PeersV1_StreamPeerRateLimitsServer interface {
+	Send(*RateLimitItem) error
+	grpc.ServerStream
+}
+
+type peersV1StreamPeerRateLimitsServer struct {
+	grpc.ServerStream
+}
+
+func (x *peersV1StreamPeerRateLimitsServer) Send(m *RateLimitItem) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _PeersV1_GetPeerGlobalView_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetGlobalRateLimitViewsReq)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PeersV1Server).GetPeerGlobalView(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/pb.gubernator.PeersV1/GetPeerGlobalView",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PeersV1Server).GetPeerGlobalView(ctx, req.(*GetGlobalRateLimitViewsReq))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _PeersV1_ResetPeerNamespace_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ResetNamespaceReq)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PeersV1Server).ResetPeerNamespace(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/pb.gubernator.PeersV1/ResetPeerNamespace",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PeersV1Server).ResetPeerNamespace(ctx, req.(*ResetNamespaceReq))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _PeersV1_ImportPeerRateLimits_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ImportRateLimitsReq)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PeersV1Server).ImportPeerRateLimits(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/pb.gubernator.PeersV1/ImportPeerRateLimits",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PeersV1Server).ImportPeerRateLimits(ctx, req.(*ImportRateLimitsReq))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // PeersV1_ServiceDesc is the grpc.ServiceDesc for PeersV1 service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -135,7 +447,41 @@ var PeersV1_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "UpdatePeerGlobals",
 			Handler:    _PeersV1_UpdatePeerGlobals_Handler,
 		},
+		{
+			MethodName: "TransferRateLimits",
+			Handler:    _PeersV1_TransferRateLimits_Handler,
+		},
+		{
+			MethodName: "SetPeerRemaining",
+			Handler:    _PeersV1_SetPeerRemaining_Handler,
+		},
+		{
+			MethodName: "DeletePeerRateLimit",
+			Handler:    _PeersV1_DeletePeerRateLimit_Handler,
+		},
+		{
+			MethodName: "ListPeerRateLimits",
+			Handler:    _PeersV1_ListPeerRateLimits_Handler,
+		},
+		{
+			MethodName: "GetPeerGlobalView",
+			Handler:    _PeersV1_GetPeerGlobalView_Handler,
+		},
+		{
+			MethodName: "ResetPeerNamespace",
+			Handler:    _PeersV1_ResetPeerNamespace_Handler,
+		},
+		{
+			MethodName: "ImportPeerRateLimits",
+			Handler:    _PeersV1_ImportPeerRateLimits_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "StreamPeerRateLimits",
+			Handler:       _PeersV1_StreamPeerRateLimits_Handler,
+			ServerStreams: true,
+		},
 	},
-	Streams:  []grpc.StreamDesc{},
 	Metadata: "peers.proto",
 }
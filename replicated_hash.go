@@ -45,7 +45,13 @@ type peerInfo struct {
 	peer *PeerClient
 }
 
+// NewReplicatedConsistentHash returns a consistent hash which places `replicas` virtual nodes on the ring
+// for each peer. More replicas smooth the distribution of keys across peers at the cost of memory. If `fn`
+// is nil or `replicas` is less than 1 the defaults are used.
 func NewReplicatedConsistentHash(fn HashString64, replicas int) *ReplicatedConsistentHash {
+	if replicas < 1 {
+		replicas = defaultReplicas
+	}
 	ch := &ReplicatedConsistentHash{
 		hashFunc: fn,
 		peers:    make(map[string]*PeerClient),
@@ -74,12 +80,12 @@ func (ch *ReplicatedConsistentHash) Peers() []*PeerClient {
 	return results
 }
 
-// Adds a peer to the hash
+// Adds a peer to the hash, peers are assigned `replicas * weight` virtual nodes on the ring
 func (ch *ReplicatedConsistentHash) Add(peer *PeerClient) {
 	ch.peers[peer.Info().GRPCAddress] = peer
 
 	key := fmt.Sprintf("%x", md5.Sum([]byte(peer.Info().GRPCAddress)))
-	for i := 0; i < ch.replicas; i++ {
+	for i := 0; i < ch.replicas*peer.Info().weight(); i++ {
 		hash := ch.hashFunc(strconv.Itoa(i) + key)
 		ch.peerKeys = append(ch.peerKeys, peerInfo{
 			hash: hash,
@@ -87,7 +93,14 @@ func (ch *ReplicatedConsistentHash) Add(peer *PeerClient) {
 		})
 	}
 
-	sort.Slice(ch.peerKeys, func(i, j int) bool { return ch.peerKeys[i].hash < ch.peerKeys[j].hash })
+	// Virtual nodes which hash to the same position are ordered by address, such that every peer computes
+	// the same owners regardless of the order in which the peers were added
+	sort.Slice(ch.peerKeys, func(i, j int) bool {
+		if ch.peerKeys[i].hash != ch.peerKeys[j].hash {
+			return ch.peerKeys[i].hash < ch.peerKeys[j].hash
+		}
+		return ch.peerKeys[i].peer.Info().GRPCAddress < ch.peerKeys[j].peer.Info().GRPCAddress
+	})
 }
 
 // Returns number of peers in the picker
@@ -105,6 +118,33 @@ func (ch *ReplicatedConsistentHash) Get(key string) (*PeerClient, error) {
 	if ch.Size() == 0 {
 		return nil, errors.New("unable to pick a peer; pool is empty")
 	}
+	return ch.peerKeys[ch.index(key)].peer, nil
+}
+
+// GetSuccessors returns up to `n` distinct peers which follow the owner of the key on the ring, in the
+// order in which they would become the owner of the key should the owner and the peers before them leave.
+func (ch *ReplicatedConsistentHash) GetSuccessors(key string, n int) []*PeerClient {
+	if ch.Size() == 0 || n < 1 {
+		return nil
+	}
+	idx := ch.index(key)
+	owner := ch.peerKeys[idx].peer
+	seen := map[*PeerClient]bool{owner: true}
+
+	var results []*PeerClient
+	for i := 1; i < len(ch.peerKeys) && len(results) < n; i++ {
+		peer := ch.peerKeys[(idx+i)%len(ch.peerKeys)].peer
+		if seen[peer] {
+			continue
+		}
+		seen[peer] = true
+		results = append(results, peer)
+	}
+	return results
+}
+
+// Returns the index into `peerKeys` of the first replica assigned to the key
+func (ch *ReplicatedConsistentHash) index(key string) int {
 	hash := ch.hashFunc(key)
 
 	// Binary search for appropriate peer
@@ -114,6 +154,5 @@ func (ch *ReplicatedConsistentHash) Get(key string) (*PeerClient, error) {
 	if idx == len(ch.peerKeys) {
 		idx = 0
 	}
-
-	return ch.peerKeys[idx].peer, nil
+	return idx
 }
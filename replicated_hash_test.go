@@ -17,12 +17,18 @@ limitations under the License.
 package gubernator
 
 import (
+	"context"
+	"math"
 	"net"
 	"testing"
 
+	"github.com/OneOfOne/xxhash"
+	promtest "github.com/prometheus/client_golang/prometheus/testutil"
 	"github.com/segmentio/fasthash/fnv1"
 	"github.com/segmentio/fasthash/fnv1a"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
 )
 
 func TestReplicatedConsistentHash(t *testing.T) {
@@ -100,6 +106,220 @@ func TestReplicatedConsistentHash(t *testing.T) {
 		}
 	})
 
+	t.Run("replicas", func(t *testing.T) {
+		keys := make([]string, 10000)
+		for i := range keys {
+			keys[i] = net.IPv4(192, 168, byte(i>>8), byte(i)).String()
+		}
+
+		// Returns the standard deviation of the number of keys assigned to each host
+		stdDev := func(fn HashString64, replicas int) float64 {
+			hash := NewReplicatedConsistentHash(fn, replicas)
+			distribution := make(map[string]int)
+			for _, h := range hosts {
+				hash.Add(&PeerClient{conf: PeerConfig{Info: PeerInfo{GRPCAddress: h}}})
+			}
+			for _, key := range keys {
+				peer, err := hash.Get(key)
+				require.NoError(t, err)
+				distribution[peer.Info().GRPCAddress]++
+			}
+
+			mean := float64(len(keys)) / float64(len(hosts))
+			var sum float64
+			for _, h := range hosts {
+				sum += math.Pow(float64(distribution[h])-mean, 2)
+			}
+			return math.Sqrt(sum / float64(len(hosts)))
+		}
+
+		for name, fn := range map[string]HashString64{
+			"fasthash/fnv1a": fnv1a.HashString64,
+			"fasthash/fnv1":  fnv1.HashString64,
+			"xxhash":         xxhash.ChecksumString64,
+		} {
+			t.Run(name, func(t *testing.T) {
+				few, many := stdDev(fn, 4), stdDev(fn, 2048)
+				t.Logf("standard deviation with 4 replicas: %.2f, 2048 replicas: %.2f", few, many)
+				assert.Less(t, many, few*0.75)
+			})
+		}
+	})
+
+	t.Run("weights", func(t *testing.T) {
+		hash := NewReplicatedConsistentHash(xxhash.ChecksumString64, defaultReplicas)
+		hash.Add(&PeerClient{conf: PeerConfig{Info: PeerInfo{GRPCAddress: "heavy.svc.local", Weight: 3}}})
+		hash.Add(&PeerClient{conf: PeerConfig{Info: PeerInfo{GRPCAddress: "light.svc.local"}}})
+
+		distribution := make(map[string]int)
+		for i := 0; i < 10000; i++ {
+			peer, err := hash.Get(net.IPv4(192, 168, byte(i>>8), byte(i)).String())
+			require.NoError(t, err)
+			distribution[peer.Info().GRPCAddress]++
+		}
+
+		ratio := float64(distribution["heavy.svc.local"]) / float64(distribution["light.svc.local"])
+		t.Logf("distribution: %v ratio: %.2f", distribution, ratio)
+		assert.InDelta(t, 3, ratio, 0.5)
+	})
+
+	t.Run("successors", func(t *testing.T) {
+		hash := NewReplicatedConsistentHash(nil, defaultReplicas)
+		peers := make(map[string]*PeerClient)
+		for _, h := range hosts {
+			peer := &PeerClient{conf: PeerConfig{Info: PeerInfo{GRPCAddress: h}}}
+			hash.Add(peer)
+			peers[h] = peer
+		}
+
+		for i := 0; i < 1000; i++ {
+			key := net.IPv4(192, 168, byte(i>>8), byte(i)).String()
+			owner, err := hash.Get(key)
+			require.NoError(t, err)
+
+			successors := hash.GetSuccessors(key, len(hosts))
+			require.Len(t, successors, len(hosts)-1)
+			assert.NotContains(t, successors, owner)
+			assert.NotEqual(t, successors[0], successors[1])
+
+			// The first successor owns the key once the owner leaves
+			without := hash.New()
+			for _, h := range hosts {
+				if peers[h] != owner {
+					without.Add(peers[h])
+				}
+			}
+			next, err := without.Get(key)
+			require.NoError(t, err)
+			assert.Equal(t, successors[0], next, key)
+		}
+		assert.Len(t, hash.GetSuccessors("key", 1), 1)
+		assert.Empty(t, NewReplicatedConsistentHash(nil, defaultReplicas).GetSuccessors("key", 1))
+	})
+
+	t.Run("insertion order", func(t *testing.T) {
+		// A coarse hash places many virtual nodes of different peers at the same position
+		coarse := func(data string) uint64 { return fnv1.HashString64(data) % 256 }
+		orders := [][]string{
+			{"a.svc.local", "b.svc.local", "c.svc.local", "d.svc.local"},
+			{"d.svc.local", "c.svc.local", "b.svc.local", "a.svc.local"},
+			{"c.svc.local", "a.svc.local", "d.svc.local", "b.svc.local"},
+		}
+
+		for name, fn := range map[string]HashString64{"default": nil, "coarse": coarse} {
+			t.Run(name, func(t *testing.T) {
+				var hashes []*ReplicatedConsistentHash
+				for _, order := range orders {
+					hash := NewReplicatedConsistentHash(fn, defaultReplicas)
+					for _, h := range order {
+						hash.Add(&PeerClient{conf: PeerConfig{Info: PeerInfo{GRPCAddress: h}}})
+					}
+					hashes = append(hashes, hash)
+				}
+
+				for i := 0; i < 10000; i++ {
+					key := net.IPv4(192, 168, byte(i>>8), byte(i)).String()
+					expected, err := hashes[0].Get(key)
+					require.NoError(t, err)
+					for _, hash := range hashes[1:] {
+						peer, err := hash.Get(key)
+						require.NoError(t, err)
+						require.Equal(t, expected.Info().GRPCAddress, peer.Info().GRPCAddress, key)
+					}
+				}
+			})
+		}
+	})
+
+	t.Run("default replicas", func(t *testing.T) {
+		hash := NewReplicatedConsistentHash(nil, 0)
+		hash.Add(&PeerClient{conf: PeerConfig{Info: PeerInfo{GRPCAddress: hosts[0]}}})
+		assert.Len(t, hash.peerKeys, defaultReplicas)
+	})
+}
+
+func TestSetPeersWeight(t *testing.T) {
+	s, err := NewV1Instance(Config{GRPCServers: []*grpc.Server{grpc.NewServer()}})
+	require.NoError(t, err)
+	defer s.Close()
+
+	weights := func() map[string]int {
+		w := make(map[string]int)
+		for _, peer := range s.GetPeerList() {
+			w[peer.Info().GRPCAddress] = peer.Info().Weight
+		}
+		return w
+	}
+
+	s.SetPeers([]PeerInfo{
+		{GRPCAddress: "a.svc.local", Weight: 3},
+		{GRPCAddress: "b.svc.local"},
+	})
+	assert.Equal(t, map[string]int{"a.svc.local": 3, "b.svc.local": 0}, weights())
+
+	// Changes to the weight of an existing peer must reach the picker
+	s.SetPeers([]PeerInfo{
+		{GRPCAddress: "a.svc.local", Weight: 1},
+		{GRPCAddress: "b.svc.local", Weight: 2},
+	})
+	assert.Equal(t, map[string]int{"a.svc.local": 1, "b.svc.local": 2}, weights())
+}
+
+func TestSetPeersChanges(t *testing.T) {
+	s, err := NewV1Instance(Config{GRPCServers: []*grpc.Server{grpc.NewServer()}})
+	require.NoError(t, err)
+	defer s.Close()
+
+	keys := make([]string, 10000)
+	for i := range keys {
+		keys[i] = net.IPv4(192, 168, byte(i>>8), byte(i)).String()
+	}
+	owners := func() map[string]string {
+		o := make(map[string]string)
+		for _, key := range keys {
+			peer, err := s.GetPeer(context.Background(), key)
+			require.NoError(t, err)
+			o[key] = peer.Info().GRPCAddress
+		}
+		return o
+	}
+	changes := func(change string) float64 {
+		return promtest.ToFloat64(peerChangeCounter.WithLabelValues(change))
+	}
+
+	peers := []PeerInfo{
+		{GRPCAddress: "a.svc.local"},
+		{GRPCAddress: "b.svc.local"},
+		{GRPCAddress: "c.svc.local"},
+	}
+	s.SetPeers(peers)
+	before := owners()
+	picker := s.conf.LocalPicker
+
+	// An update without changes to the membership keeps the current ring
+	s.SetPeers(peers)
+	assert.Same(t, picker, s.conf.LocalPicker)
+
+	added, removed := changes("added"), changes("removed")
+	s.SetPeers(append(peers, PeerInfo{GRPCAddress: "d.svc.local"}))
+	assert.Equal(t, added+1, changes("added"))
+	assert.Equal(t, removed, changes("removed"))
+
+	// Only the keys now owned by the new peer should move
+	var moved int
+	for key, owner := range owners() {
+		if owner != before[key] {
+			assert.Equal(t, "d.svc.local", owner)
+			moved++
+		}
+	}
+	t.Logf("%d of %d keys moved", moved, len(keys))
+	assert.InDelta(t, len(keys)/4, moved, float64(len(keys))*0.1)
+
+	// Removing the peer returns the keys to their previous owners
+	s.SetPeers(peers)
+	assert.Equal(t, removed+1, changes("removed"))
+	assert.Equal(t, before, owners())
 }
 
 func BenchmarkReplicatedConsistantHash(b *testing.B) {
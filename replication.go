@@ -0,0 +1,152 @@
+/*
+Copyright 2018-2022 Mailgun Technologies Inc
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gubernator
+
+import (
+	"context"
+
+	"github.com/mailgun/holster/v4/syncutil"
+	"github.com/mailgun/holster/v4/tracing"
+)
+
+// SuccessorPicker is implemented by a PeerPicker which knows which peers become the owner of a key when
+// the owner leaves. Required by Config.ReplicationFactor.
+type SuccessorPicker interface {
+	// GetSuccessors returns up to `n` distinct peers other than the owner of the key, in the order in
+	// which they would become the owner of the key should the owner and the peers before them leave.
+	GetSuccessors(key string, n int) []*PeerClient
+}
+
+// replicationManager copies the state of the rate limits this instance owns to the peers which
+// become the owner of the rate limits should this instance leave, such that they already hold
+// recent state when they take over.
+type replicationManager struct {
+	queue    chan string
+	wg       syncutil.WaitGroup
+	conf     BehaviorConfig
+	factor   int
+	log      FieldLogger
+	instance *V1Instance
+}
+
+func newReplicationManager(conf BehaviorConfig, factor int, instance *V1Instance) *replicationManager {
+	rm := replicationManager{
+		queue:    make(chan string, maxBatchSize),
+		conf:     conf,
+		factor:   factor,
+		log:      instance.log,
+		instance: instance,
+	}
+	rm.runReplication()
+	return &rm
+}
+
+// QueueUpdate queues the rate limit with the hash key to be copied to its successors
+func (rm *replicationManager) QueueUpdate(key string) {
+	rm.queue <- key
+}
+
+// runReplication collects the keys of changed rate limits and copies them to their
+// successors once per `ReplicationSyncWait`
+func (rm *replicationManager) runReplication() {
+	var interval = NewInterval(rm.conf.ReplicationSyncWait)
+	keys := make(map[string]struct{})
+
+	rm.wg.Until(func(done chan struct{}) bool {
+		ctx := tracing.StartScope(context.Background())
+		defer tracing.EndScope(ctx, nil)
+
+		select {
+		case key := <-rm.queue:
+			keys[key] = struct{}{}
+
+			if len(keys) == maxBatchSize {
+				rm.replicate(ctx, keys)
+				keys = make(map[string]struct{})
+				return true
+			}
+
+			// If this is our first queued key since last send
+			// queue the next interval
+			if len(keys) == 1 {
+				interval.Next()
+			}
+
+		case <-interval.C:
+			if len(keys) != 0 {
+				rm.replicate(ctx, keys)
+				keys = make(map[string]struct{})
+			}
+		case <-done:
+			return false
+		}
+		return true
+	})
+}
+
+// replicate sends the current state of each rate limit to its successors
+func (rm *replicationManager) replicate(ctx context.Context, keys map[string]struct{}) {
+	batches := make(map[*PeerClient][]*TransferItem)
+
+	for key := range keys {
+		item, ok, err := rm.instance.gubernatorPool.GetCacheItem(ctx, key)
+		if err != nil {
+			rm.log.WithError(err).Errorf("while getting the rate limit '%s' to replicate", key)
+			continue
+		}
+		if !ok {
+			continue
+		}
+		ti := cacheItemToTransfer(item)
+		if ti == nil {
+			continue
+		}
+
+		rm.instance.peerMutex.RLock()
+		var successors []*PeerClient
+		if picker, ok := rm.instance.conf.LocalPicker.(SuccessorPicker); ok {
+			successors = picker.GetSuccessors(rm.instance.shardKey(key), rm.factor-1)
+		}
+		rm.instance.peerMutex.RUnlock()
+
+		for _, peer := range successors {
+			// We are no longer the owner if the peers changed since the rate limit was updated
+			if peer.Info().IsOwner {
+				continue
+			}
+			batches[peer] = append(batches[peer], ti)
+		}
+	}
+
+	for peer, items := range batches {
+		for len(items) != 0 {
+			size := len(items)
+			if size > maxBatchSize {
+				size = maxBatchSize
+			}
+			if err := rm.instance.transfer(ctx, peer, items[:size]); err != nil {
+				rm.log.WithError(err).Error("while replicating rate limits")
+				break
+			}
+			items = items[size:]
+		}
+	}
+}
+
+func (rm *replicationManager) Close() {
+	rm.wg.Stop()
+}
@@ -0,0 +1,127 @@
+/*
+Copyright 2018-2022 Mailgun Technologies Inc
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gubernator
+
+import (
+	"github.com/mailgun/holster/v4/clock"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+)
+
+const minutesPerDay = 24 * 60
+
+// checkSchedule returns an InvalidArgument error if a window of the `schedule` of the request is invalid
+func checkSchedule(r *RateLimitReq) error {
+	if len(r.Schedule) == 0 {
+		return nil
+	}
+	for i, w := range r.Schedule {
+		if w.Start < 0 || w.Start >= minutesPerDay || w.End <= 0 || w.End > minutesPerDay || w.Start == w.End {
+			return status.Errorf(codes.InvalidArgument, "window %d of 'schedule' for '%s' must begin at a minute "+
+				"of the day from 0 to %d and end at another from 1 to %d; got '%d' to '%d'",
+				i, r.HashKey(), minutesPerDay-1, minutesPerDay, w.Start, w.End)
+		}
+		if w.Limit < 0 {
+			return status.Errorf(codes.InvalidArgument, "'limit' of window %d of 'schedule' for '%s' cannot be negative",
+				i, r.HashKey())
+		}
+	}
+	if _, err := gregorianLocation(r); err != nil {
+		return status.Errorf(codes.InvalidArgument, "'timezone' of '%s' for '%s' is invalid: %s",
+			r.Timezone, r.HashKey(), err)
+	}
+	return nil
+}
+
+// scheduleSegment returns the window of the `schedule` of the request which contains the current time of
+// day, counting from 1, and the limit of the window. Returns 0 and the `limit` of the request if no window
+// contains the current time of day.
+func scheduleSegment(r *RateLimitReq) (int32, int64) {
+	if len(r.Schedule) == 0 {
+		return 0, r.Limit
+	}
+	now, err := gregorianNow(r)
+	if err != nil {
+		// Rejected by checkSchedule()
+		now = clock.Now()
+	}
+	minute := int32(now.Hour()*60 + now.Minute())
+	for i, w := range r.Schedule {
+		inside := w.Start <= minute && minute < w.End
+		if w.End < w.Start {
+			// The window spans midnight
+			inside = minute >= w.Start || minute < w.End
+		}
+		if inside {
+			return int32(i + 1), w.Limit
+		}
+	}
+	return 0, r.Limit
+}
+
+// scheduledRequest returns a copy of the request with the `limit` of the window of its `schedule` which
+// is active, such that the algorithms enforce the limit of the time of day, and the active window as
+// returned by scheduleSegment()
+func scheduledRequest(r *RateLimitReq) (*RateLimitReq, int32) {
+	if len(r.Schedule) == 0 {
+		return r, 0
+	}
+	segment, limit := scheduleSegment(r)
+	r = proto.Clone(r).(*RateLimitReq)
+	r.Limit = limit
+	return r, segment
+}
+
+// updateSchedule scales the remaining of the rate limit in proportion to the limit of the request once
+// `segment`, the window of the schedule chosen by scheduledRequest(), became active, rather than leaving
+// the algorithm to add the difference of the limits, and records the active window in the item
+func updateSchedule(item *CacheItem, r *RateLimitReq, segment int32) {
+	if item.ScheduleSegment == segment {
+		return
+	}
+	item.ScheduleSegment = segment
+
+	scale := func(remaining float64, limit int64) float64 {
+		if limit <= 0 {
+			return remaining
+		}
+		return remaining * float64(r.Limit) / float64(limit)
+	}
+	switch t := item.Value.(type) {
+	case *TokenBucketItem:
+		// Only the capacity of a bucket without a burst follows the limit
+		if t.Burst == 0 {
+			t.Remaining = scale(t.Remaining, t.Limit)
+			t.Limit = r.Limit
+		}
+	case *LeakyBucketItem:
+		t.Remaining = scale(t.Remaining, t.Limit)
+		t.Limit = r.Limit
+		t.Burst = r.Burst
+		if t.Remaining > float64(t.Burst) {
+			t.Remaining = float64(t.Burst)
+		}
+	case *HybridBucketItem:
+		t.Remaining = scale(t.Remaining, t.Limit)
+		t.Limit = r.Limit
+		t.Burst = r.Burst
+		if t.Remaining > float64(t.Burst) {
+			t.Remaining = float64(t.Burst)
+		}
+	}
+}
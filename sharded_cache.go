@@ -0,0 +1,166 @@
+/*
+Copyright 2018-2022 Mailgun Technologies Inc
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gubernator
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/OneOfOne/xxhash"
+	"github.com/mailgun/holster/v4/setter"
+)
+
+// ShardedLRUCache is a thread-safe Cache which partitions keys between several LRUCache shards,
+// each guarded by its own mutex, such that concurrent calls for different keys rarely contend
+// for the same lock. Items are evicted by the shard the key belongs to, as such the least
+// recently used item of the cache as a whole is not always the first to be evicted.
+type ShardedLRUCache struct {
+	shards []*lruCacheShard
+}
+
+type lruCacheShard struct {
+	sync.Mutex
+	cache *LRUCache
+}
+
+var _ Cache = &ShardedLRUCache{}
+var _ ExpiringCache = &ShardedLRUCache{}
+
+// NewShardedLRUCache creates a new ShardedLRUCache as configured by LRUCacheConfig. Each shard
+// holds at most `MaxSize / Shards` items.
+func NewShardedLRUCache(conf LRUCacheConfig) (*ShardedLRUCache, error) {
+	if conf.MaxSize < 0 {
+		return nil, fmt.Errorf("LRUCacheConfig.MaxSize cannot be negative; got '%d'", conf.MaxSize)
+	}
+	if conf.Shards < 0 {
+		return nil, fmt.Errorf("LRUCacheConfig.Shards cannot be negative; got '%d'", conf.Shards)
+	}
+	setter.SetDefault(&conf.MaxSize, 50_000)
+	setter.SetDefault(&conf.Shards, 32)
+
+	shardSize := conf.MaxSize / conf.Shards
+	if shardSize < 1 {
+		shardSize = 1
+	}
+
+	c := &ShardedLRUCache{shards: make([]*lruCacheShard, conf.Shards)}
+	for i := range c.shards {
+		cache, err := NewLRUCacheWithConfig(LRUCacheConfig{MaxSize: shardSize, OnEvict: conf.OnEvict})
+		if err != nil {
+			return nil, err
+		}
+		c.shards[i] = &lruCacheShard{cache: cache}
+	}
+	return c, nil
+}
+
+func (c *ShardedLRUCache) shard(key string) *lruCacheShard {
+	return c.shards[xxhash.ChecksumString64S(key, 0)%uint64(len(c.shards))]
+}
+
+// Add adds a value to the cache
+func (c *ShardedLRUCache) Add(item *CacheItem) bool {
+	s := c.shard(item.Key)
+	s.Lock()
+	defer s.Unlock()
+	return s.cache.Add(item)
+}
+
+// UpdateExpiration updates the expiration time for the key
+func (c *ShardedLRUCache) UpdateExpiration(key string, expireAt int64) bool {
+	s := c.shard(key)
+	s.Lock()
+	defer s.Unlock()
+	return s.cache.UpdateExpiration(key, expireAt)
+}
+
+// GetItem returns the item stored in the cache
+func (c *ShardedLRUCache) GetItem(key string) (*CacheItem, bool) {
+	s := c.shard(key)
+	s.Lock()
+	defer s.Unlock()
+	return s.cache.GetItem(key)
+}
+
+// Each returns each item in the cache. Each shard is locked while its items are read, as such the
+// channel must be read to completion.
+func (c *ShardedLRUCache) Each() chan *CacheItem {
+	out := make(chan *CacheItem)
+	go func() {
+		for _, s := range c.shards {
+			s.Lock()
+			for item := range s.cache.Each() {
+				out <- item
+			}
+			s.Unlock()
+		}
+		close(out)
+	}()
+	return out
+}
+
+// Remove removes the provided key from the cache
+func (c *ShardedLRUCache) Remove(key string) {
+	s := c.shard(key)
+	s.Lock()
+	defer s.Unlock()
+	s.cache.Remove(key)
+}
+
+// RemoveExpired removes all the expired and invalidated items from each shard
+func (c *ShardedLRUCache) RemoveExpired() int {
+	var removed int
+	for _, s := range c.shards {
+		s.Lock()
+		removed += s.cache.RemoveExpired()
+		s.Unlock()
+	}
+	return removed
+}
+
+// Size returns the number of items in the cache
+func (c *ShardedLRUCache) Size() int64 {
+	var size int64
+	for _, s := range c.shards {
+		size += s.cache.Size()
+	}
+	return size
+}
+
+// Stats returns the combined activity of the shards since the cache was created
+func (c *ShardedLRUCache) Stats() CacheStats {
+	var stats CacheStats
+	for _, s := range c.shards {
+		st := s.cache.Stats()
+		stats.Size += st.Size
+		stats.Hit += st.Hit
+		stats.Miss += st.Miss
+		stats.Evictions += st.Evictions
+		stats.UnexpiredEvictions += st.UnexpiredEvictions
+		stats.Expired += st.Expired
+	}
+	return stats
+}
+
+func (c *ShardedLRUCache) Close() error {
+	for _, s := range c.shards {
+		s.Lock()
+		_ = s.cache.Close()
+		s.Unlock()
+	}
+	return nil
+}
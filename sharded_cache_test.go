@@ -0,0 +1,109 @@
+/*
+Copyright 2018-2022 Mailgun Technologies Inc
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gubernator_test
+
+import (
+	"fmt"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mailgun/gubernator/v2"
+	"github.com/mailgun/holster/v4/clock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestShardedLRUCache(t *testing.T) {
+	expireAt := clock.Now().Add(time.Hour).UnixMilli()
+
+	t.Run("Concurrent access per key", func(t *testing.T) {
+		cache, err := gubernator.NewShardedLRUCache(gubernator.LRUCacheConfig{MaxSize: 10_000, Shards: 8})
+		require.NoError(t, err)
+
+		const workers, keys = 16, 100
+		var wg sync.WaitGroup
+		for w := 0; w < workers; w++ {
+			wg.Add(1)
+			go func(w int) {
+				defer wg.Done()
+				for i := 0; i < keys; i++ {
+					key := fmt.Sprintf("%d_%d", w, i)
+					assert.False(t, cache.Add(&gubernator.CacheItem{Key: key, Value: i, ExpireAt: expireAt}))
+				}
+				for round := 1; round <= 10; round++ {
+					for i := 0; i < keys; i++ {
+						key := fmt.Sprintf("%d_%d", w, i)
+						item, ok := cache.GetItem(key)
+						if !assert.True(t, ok, key) {
+							return
+						}
+						assert.Equal(t, i*round, item.Value, key)
+						assert.True(t, cache.Add(&gubernator.CacheItem{Key: key, Value: i * (round + 1), ExpireAt: expireAt}))
+						assert.True(t, cache.UpdateExpiration(key, expireAt+int64(round)))
+					}
+				}
+			}(w)
+		}
+		wg.Wait()
+
+		assert.Equal(t, int64(workers*keys), cache.Size())
+		var count int
+		for item := range cache.Each() {
+			assert.Equal(t, expireAt+10, item.ExpireAt, item.Key)
+			count++
+		}
+		assert.Equal(t, workers*keys, count)
+
+		cache.Remove("0_0")
+		_, ok := cache.GetItem("0_0")
+		assert.False(t, ok)
+		assert.Equal(t, int64(workers*keys-1), cache.Size())
+	})
+
+	t.Run("Capacity is divided between shards", func(t *testing.T) {
+		cache, err := gubernator.NewShardedLRUCache(gubernator.LRUCacheConfig{MaxSize: 40, Shards: 4})
+		require.NoError(t, err)
+		for i := 0; i < 1000; i++ {
+			cache.Add(&gubernator.CacheItem{Key: strconv.Itoa(i), Value: i, ExpireAt: expireAt})
+		}
+		assert.Equal(t, int64(40), cache.Size())
+		assert.Equal(t, int64(960), cache.Stats().Evictions)
+	})
+
+	t.Run("RemoveExpired", func(t *testing.T) {
+		defer clock.Freeze(clock.Now()).Unfreeze()
+		cache, err := gubernator.NewShardedLRUCache(gubernator.LRUCacheConfig{})
+		require.NoError(t, err)
+		for i := 0; i < 100; i++ {
+			cache.Add(&gubernator.CacheItem{
+				Key:      strconv.Itoa(i),
+				Value:    i,
+				ExpireAt: clock.Now().Add(time.Duration(i%2+1) * time.Minute).UnixMilli(),
+			})
+		}
+		clock.Advance(time.Minute)
+		assert.Equal(t, 50, cache.RemoveExpired())
+		assert.Equal(t, int64(50), cache.Size())
+	})
+
+	t.Run("Invalid shards", func(t *testing.T) {
+		_, err := gubernator.NewShardedLRUCache(gubernator.LRUCacheConfig{Shards: -1})
+		assert.EqualError(t, err, "LRUCacheConfig.Shards cannot be negative; got '-1'")
+	})
+}
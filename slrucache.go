@@ -0,0 +1,247 @@
+/*
+Copyright 2018-2022 Mailgun Technologies Inc
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gubernator
+
+import (
+	"container/list"
+	"sync/atomic"
+
+	"github.com/mailgun/holster/v4/setter"
+)
+
+// SegmentedLRUCache is a Cache which splits its items between a probationary and a protected LRU
+// segment. New items enter the probationary segment and are moved to the protected segment when
+// used again. When full, the least recently used probationary item is evicted first, such that
+// keys which are only seen once, IE: a scan of many keys, do not evict keys which are used often.
+// CacheItem.Priority is not considered.
+// Not thread-safe.  Be sure to use a mutex to prevent concurrent method calls.
+type SegmentedLRUCache struct {
+	cache         map[string]*list.Element
+	probation     *list.List
+	protected     *list.List
+	cacheSize     int
+	protectedSize int
+	cacheLen      int64
+	stats         CacheStats
+}
+
+type slruEntry struct {
+	item      *CacheItem
+	protected bool
+}
+
+var _ Cache = &SegmentedLRUCache{}
+var _ ExpiringCache = &SegmentedLRUCache{}
+var _ StaleCache = &SegmentedLRUCache{}
+
+// NewSegmentedLRUCache creates a new SegmentedLRUCache with a maximum size, of which 80% is reserved
+// for the protected segment. If maxSize is less than 1 the default size of 50,000 is used.
+func NewSegmentedLRUCache(maxSize int) *SegmentedLRUCache {
+	if maxSize < 0 {
+		maxSize = 0
+	}
+	setter.SetDefault(&maxSize, 50_000)
+	return &SegmentedLRUCache{
+		cache:         make(map[string]*list.Element),
+		probation:     list.New(),
+		protected:     list.New(),
+		cacheSize:     maxSize,
+		protectedSize: maxSize * 4 / 5,
+	}
+}
+
+// Each returns each item in the cache. Like LRUCache.Each(), other methods of the cache must not
+// be called until the channel is read to completion.
+func (c *SegmentedLRUCache) Each() chan *CacheItem {
+	out := make(chan *CacheItem)
+	go func() {
+		for _, ele := range c.cache {
+			out <- ele.Value.(*slruEntry).item
+		}
+		close(out)
+	}()
+	return out
+}
+
+// Add adds a value to the cache. Replacing the value of an existing key counts as a use of the key.
+func (c *SegmentedLRUCache) Add(item *CacheItem) bool {
+	if ele, ok := c.cache[item.Key]; ok {
+		ele.Value.(*slruEntry).item = item
+		c.use(ele)
+		return true
+	}
+
+	c.cache[item.Key] = c.probation.PushFront(&slruEntry{item: item})
+	if len(c.cache) > c.cacheSize {
+		c.removeOldest()
+	}
+	atomic.StoreInt64(&c.cacheLen, int64(len(c.cache)))
+	return false
+}
+
+// GetItem returns the item stored in the cache
+func (c *SegmentedLRUCache) GetItem(key string) (item *CacheItem, ok bool) {
+	ele, hit := c.cache[key]
+	if !hit {
+		c.miss()
+		return
+	}
+
+	entry := ele.Value.(*slruEntry)
+	now := MillisecondNow()
+	if (entry.item.InvalidAt != 0 && entry.item.InvalidAt < now) || entry.item.ExpireAt <= now {
+		c.removeElement(ele)
+		c.miss()
+		return
+	}
+
+	accessMetric.WithLabelValues("hit").Add(1)
+	atomic.AddInt64(&c.stats.Hit, 1)
+	c.use(ele)
+	return entry.item, true
+}
+
+// use moves the item to the front of the protected segment, demoting the least recently used
+// protected item to the probationary segment if the protected segment is full.
+func (c *SegmentedLRUCache) use(ele *list.Element) {
+	entry := ele.Value.(*slruEntry)
+	if entry.protected {
+		c.protected.MoveToFront(ele)
+		return
+	}
+	if c.protectedSize == 0 {
+		c.probation.MoveToFront(ele)
+		return
+	}
+
+	c.probation.Remove(ele)
+	entry.protected = true
+	c.cache[entry.item.Key] = c.protected.PushFront(entry)
+
+	if c.protected.Len() > c.protectedSize {
+		oldest := c.protected.Back()
+		demoted := c.protected.Remove(oldest).(*slruEntry)
+		demoted.protected = false
+		c.cache[demoted.item.Key] = c.probation.PushFront(demoted)
+	}
+}
+
+func (c *SegmentedLRUCache) miss() {
+	accessMetric.WithLabelValues("miss").Add(1)
+	atomic.AddInt64(&c.stats.Miss, 1)
+}
+
+// Remove removes the provided key from the cache.
+func (c *SegmentedLRUCache) Remove(key string) {
+	if ele, hit := c.cache[key]; hit {
+		c.removeElement(ele)
+	}
+}
+
+// removeOldest removes the least recently used probationary item, or the least recently used
+// protected item if there are no probationary items.
+func (c *SegmentedLRUCache) removeOldest() {
+	ele := c.probation.Back()
+	if ele == nil {
+		ele = c.protected.Back()
+	}
+	if ele == nil {
+		return
+	}
+
+	if MillisecondNow() < ele.Value.(*slruEntry).item.ExpireAt {
+		unexpiredEvictionsMetric.Add(1)
+		atomic.AddInt64(&c.stats.UnexpiredEvictions, 1)
+	}
+	evictionsMetric.Add(1)
+	atomic.AddInt64(&c.stats.Evictions, 1)
+	c.removeElement(ele)
+}
+
+func (c *SegmentedLRUCache) removeElement(ele *list.Element) {
+	entry := ele.Value.(*slruEntry)
+	if entry.protected {
+		c.protected.Remove(ele)
+	} else {
+		c.probation.Remove(ele)
+	}
+	delete(c.cache, entry.item.Key)
+	atomic.StoreInt64(&c.cacheLen, int64(len(c.cache)))
+}
+
+// RemoveExpired removes all the expired and invalidated items from the cache
+func (c *SegmentedLRUCache) RemoveExpired() int {
+	now := MillisecondNow()
+	var removed int
+	for _, ele := range c.cache {
+		item := ele.Value.(*slruEntry).item
+		if item.ExpireAt <= now || (item.InvalidAt != 0 && item.InvalidAt < now) {
+			c.removeElement(ele)
+			removed++
+		}
+	}
+	atomic.AddInt64(&c.stats.Expired, int64(removed))
+	return removed
+}
+
+// Size returns the number of items in the cache.
+func (c *SegmentedLRUCache) Size() int64 {
+	return atomic.LoadInt64(&c.cacheLen)
+}
+
+// Stats returns the activity of the cache since it was created. Unlike other methods
+// Stats() is safe to call concurrently with other calls to the cache.
+func (c *SegmentedLRUCache) Stats() CacheStats {
+	return CacheStats{
+		Size:               atomic.LoadInt64(&c.cacheLen),
+		Hit:                atomic.LoadInt64(&c.stats.Hit),
+		Miss:               atomic.LoadInt64(&c.stats.Miss),
+		Evictions:          atomic.LoadInt64(&c.stats.Evictions),
+		UnexpiredEvictions: atomic.LoadInt64(&c.stats.UnexpiredEvictions),
+		Expired:            atomic.LoadInt64(&c.stats.Expired),
+	}
+}
+
+// GetStaleItem returns the item stored in the cache even if it was invalidated, see StaleCache
+func (c *SegmentedLRUCache) GetStaleItem(key string) (*CacheItem, bool) {
+	ele, hit := c.cache[key]
+	if !hit {
+		return nil, false
+	}
+	item := ele.Value.(*slruEntry).item
+	if item.ExpireAt <= MillisecondNow() {
+		return nil, false
+	}
+	return item, true
+}
+
+// UpdateExpiration updates the expiration time for the key
+func (c *SegmentedLRUCache) UpdateExpiration(key string, expireAt int64) bool {
+	if ele, hit := c.cache[key]; hit {
+		ele.Value.(*slruEntry).item.ExpireAt = expireAt
+		return true
+	}
+	return false
+}
+
+func (c *SegmentedLRUCache) Close() error {
+	c.cache = nil
+	c.probation = nil
+	c.protected = nil
+	c.cacheLen = 0
+	return nil
+}
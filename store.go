@@ -16,7 +16,14 @@ limitations under the License.
 
 package gubernator
 
-import "context"
+import (
+	"context"
+
+	"github.com/mailgun/holster/v4/errors"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
 
 // PERSISTENT STORE DETAILS
 
@@ -32,14 +39,37 @@ type LeakyBucketItem struct {
 	Remaining float64
 	UpdatedAt int64
 	Burst     int64
+	// The hits accepted since the bucket was last empty
+	TotalHits int64
 }
 
 type TokenBucketItem struct {
 	Status    Status
 	Limit     int64
 	Duration  int64
-	Remaining int64
+	Remaining float64
 	CreatedAt int64
+	// Timestamp when a PENALTY_COOLDOWN began in epoch milliseconds, zero if there is no cooldown
+	OverLimitAt int64
+	// The number of tokens the bucket may accumulate when greater than the limit, else zero
+	Burst int64
+	// The hits accepted since the bucket was created or last refilled
+	TotalHits int64
+}
+
+// HybridBucketItem holds the state of an Algorithm_HYBRID bucket, which leaks like a LeakyBucketItem
+// and refills to the burst at the end of each window like a TokenBucketItem.
+type HybridBucketItem struct {
+	Limit     int64
+	Duration  int64
+	Remaining float64
+	// Timestamp of the last leak in epoch milliseconds
+	UpdatedAt int64
+	// Timestamp when the current window began in epoch milliseconds
+	CreatedAt int64
+	Burst     int64
+	// The hits accepted since the window began
+	TotalHits int64
 }
 
 // Store interface allows implementors to off load storage of all or a subset of ratelimits to
@@ -56,6 +86,8 @@ type Store interface {
 	// Called by gubernator when a rate limit is missing from the cache. It's up to the store
 	// to decide if this request is fulfilled. Should return true if the request is fulfilled
 	// and false if the request is not fulfilled or doesn't exist in the store.
+	// The context carries the deadline of the client request; implementations should return
+	// promptly once it is done, as the rate limit worker is blocked until Get() returns.
 	Get(ctx context.Context, r *RateLimitReq) (*CacheItem, bool)
 
 	// Called by gubernator when an existing rate limit should be removed from the store.
@@ -64,6 +96,166 @@ type Store interface {
 	Remove(ctx context.Context, key string)
 }
 
+// FallibleStore is an optional interface a Store may implement to report a failed read, which Get()
+// cannot tell apart from a rate limit which is not in the store. The circuit breaker enabled by
+// Config.StoreBreakerThreshold counts failed reads towards opening the breaker.
+type FallibleStore interface {
+	Store
+
+	// GetWithError behaves like Get(), but returns an error if the store could not be read
+	GetWithError(ctx context.Context, r *RateLimitReq) (*CacheItem, bool, error)
+}
+
+// getFromStore calls GetWithError() if the store implements FallibleStore, else Get()
+func getFromStore(ctx context.Context, s Store, r *RateLimitReq) (*CacheItem, bool, error) {
+	if fs, ok := s.(FallibleStore); ok {
+		return fs.GetWithError(ctx, r)
+	}
+	item, ok := s.Get(ctx, r)
+	return item, ok, nil
+}
+
+// StoreErrorPolicy chooses how a rate limit missing from the cache is handled when the Store could
+// not be read, see Config.StoreErrorPolicy. Only the errors of a store which implements FallibleStore
+// are known.
+type StoreErrorPolicy int
+
+const (
+	// StoreErrorNew creates the rate limit anew, as if it was not in the store. This favors availability,
+	// however the remaining of a rate limit the store could not provide is reset.
+	StoreErrorNew StoreErrorPolicy = iota
+	// StoreErrorFail fails the request with the error of the store, unless the request has
+	// `Behavior_IGNORE_STORE_ERRORS`, in which case the rate limit is created anew.
+	StoreErrorFail
+	// StoreErrorServeCached uses the rate limit the cache invalidated to read it from the store, see
+	// `CacheItem.InvalidAt`, if the cache still holds it and it has not expired. Otherwise the rate
+	// limit is created anew. Requires a cache which implements StaleCache.
+	StoreErrorServeCached
+)
+
+// StoreReconcilePolicy chooses between the rate limit the Store provides and the one the cache invalidated
+// to read it from the store, see `CacheItem.InvalidAt` and Config.StoreReconcilePolicy. The two may differ
+// when another instance changed the rate limit in the store, IE: after a split brain. Requires a cache which
+// implements StaleCache, else the store is trusted.
+type StoreReconcilePolicy int
+
+const (
+	// StoreReconcileStore trusts the store
+	StoreReconcileStore StoreReconcilePolicy = iota
+	// StoreReconcileNewest picks the rate limit changed last, by the `UpdatedAt` of a LEAKY_BUCKET or HYBRID
+	// and the `CreatedAt` of a TOKEN_BUCKET. Since `CreatedAt` is when the window began, TOKEN_BUCKET copies
+	// of the same window are told apart by their `TotalHits`, the copy which accepted more hits was changed
+	// last. The store wins a tie.
+	StoreReconcileNewest
+	// StoreReconcileConservative picks the rate limit with the least remaining, which never allows more hits
+	// than either would. The store wins a tie.
+	StoreReconcileConservative
+)
+
+// storePolicy is how the algorithms handle the reads of the Store
+type storePolicy struct {
+	onError   StoreErrorPolicy
+	reconcile StoreReconcilePolicy
+}
+
+// getStoreItem reads the rate limit missing from the cache from the store. A failed read is handled
+// according to `policy.onError` and a successful one is reconciled with `stale`, the item invalidated
+// by the cache, if any.
+func getStoreItem(ctx context.Context, s Store, r *RateLimitReq, stale *CacheItem, policy storePolicy) (*CacheItem, bool, error) {
+	item, ok, err := getFromStore(ctx, s, r)
+	// The caller gave up on the request while the store was read, IE: the request exceeded the
+	// BatchItemTimeout. Leave the rate limit untouched, as the caller was told it was not applied.
+	if ctx.Err() != nil {
+		return nil, false, ctx.Err()
+	}
+	if err == nil {
+		if ok && stale != nil {
+			return reconcileItems(stale, item, policy.reconcile), true, nil
+		}
+		return item, ok, nil
+	}
+
+	switch {
+	case policy.onError == StoreErrorServeCached && stale != nil:
+		return stale, true, nil
+	case policy.onError == StoreErrorFail && !HasBehavior(r.Behavior, Behavior_IGNORE_STORE_ERRORS):
+		return nil, false, errors.Wrap(err, "Error in store.Get")
+	}
+	return nil, false, nil
+}
+
+// reconcileItems returns the winner of the item in the cache and the item in the store under `policy`.
+// Items of different algorithms cannot be compared, in which case the store wins.
+func reconcileItems(cached, stored *CacheItem, policy StoreReconcilePolicy) *CacheItem {
+	var cachedAt, storedAt int64
+	var cachedRemaining, storedRemaining float64
+	switch c := cached.Value.(type) {
+	case *TokenBucketItem:
+		s, ok := stored.Value.(*TokenBucketItem)
+		if !ok {
+			return stored
+		}
+		cachedAt, storedAt = c.CreatedAt, s.CreatedAt
+		cachedRemaining, storedRemaining = c.Remaining, s.Remaining
+		// The hits accepted only grow within a window
+		if policy == StoreReconcileNewest && cachedAt == storedAt {
+			cachedAt, storedAt = c.TotalHits, s.TotalHits
+		}
+	case *LeakyBucketItem:
+		s, ok := stored.Value.(*LeakyBucketItem)
+		if !ok {
+			return stored
+		}
+		cachedAt, storedAt = c.UpdatedAt, s.UpdatedAt
+		cachedRemaining, storedRemaining = c.Remaining, s.Remaining
+	case *HybridBucketItem:
+		s, ok := stored.Value.(*HybridBucketItem)
+		if !ok {
+			return stored
+		}
+		cachedAt, storedAt = c.UpdatedAt, s.UpdatedAt
+		cachedRemaining, storedRemaining = c.Remaining, s.Remaining
+	default:
+		return stored
+	}
+
+	switch {
+	case policy == StoreReconcileNewest && cachedAt > storedAt:
+		return cached
+	case policy == StoreReconcileConservative && cachedRemaining < storedRemaining:
+		return cached
+	}
+	return stored
+}
+
+// getStaleItem returns the item for `key` if the cache implements StaleCache and the
+// policy needs the items it invalidated.
+func getStaleItem(c Cache, key string, policy storePolicy) *CacheItem {
+	if policy.onError != StoreErrorServeCached && policy.reconcile == StoreReconcileStore {
+		return nil
+	}
+	if sc, ok := c.(StaleCache); ok {
+		if item, ok := sc.GetStaleItem(key); ok {
+			return item
+		}
+	}
+	return nil
+}
+
+// BulkStore is an optional interface a Store may implement to warm the cache when the instance starts.
+// Without it, a node which (re)joins the cluster with a cold cache calls `Get()` for every rate limit it
+// receives, which can result in a thundering herd of reads against the store. If the configured Store
+// implements BulkStore, gubernator calls `LoadAll()` once before the instance is ready to accept requests,
+// otherwise rate limits are loaded lazily via `Get()`.
+type BulkStore interface {
+	Store
+
+	// LoadAll is called by gubernator just before the instance is ready to accept requests. The implementation
+	// should return a channel gubernator can read to load all rate limits that should be loaded into the
+	// instance cache. The implementation should close the channel to indicate no more rate limits left to load.
+	LoadAll() (chan *CacheItem, error)
+}
+
 // Loader interface allows implementors to store all or a subset of ratelimits into a persistent
 // store during startup and shutdown of the gubernator instance.
 type Loader interface {
@@ -77,6 +269,55 @@ type Loader interface {
 	Save(chan *CacheItem) error
 }
 
+// tracedStore creates a span for each call to the Store it wraps
+type tracedStore struct {
+	store Store
+	tp    trace.TracerProvider
+}
+
+func newTracedStore(s Store, tp trace.TracerProvider) Store {
+	return &tracedStore{store: s, tp: tp}
+}
+
+func (t *tracedStore) start(ctx context.Context, name, key string) (context.Context, trace.Span) {
+	tp := t.tp
+	if tp == nil {
+		tp = otel.GetTracerProvider()
+	}
+	return tp.Tracer("gubernator").Start(ctx, name, trace.WithAttributes(attribute.String("hashKey", key)))
+}
+
+func (t *tracedStore) OnChange(ctx context.Context, r *RateLimitReq, item *CacheItem) {
+	ctx, span := t.start(ctx, "Store.OnChange", item.Key)
+	defer span.End()
+	t.store.OnChange(ctx, r, item)
+}
+
+func (t *tracedStore) Get(ctx context.Context, r *RateLimitReq) (*CacheItem, bool) {
+	ctx, span := t.start(ctx, "Store.Get", r.HashKey())
+	defer span.End()
+	item, ok := t.store.Get(ctx, r)
+	span.SetAttributes(attribute.Bool("found", ok))
+	return item, ok
+}
+
+func (t *tracedStore) GetWithError(ctx context.Context, r *RateLimitReq) (*CacheItem, bool, error) {
+	ctx, span := t.start(ctx, "Store.Get", r.HashKey())
+	defer span.End()
+	item, ok, err := getFromStore(ctx, t.store, r)
+	span.SetAttributes(attribute.Bool("found", ok))
+	if err != nil {
+		span.RecordError(err)
+	}
+	return item, ok, err
+}
+
+func (t *tracedStore) Remove(ctx context.Context, key string) {
+	ctx, span := t.start(ctx, "Store.Remove", key)
+	defer span.End()
+	t.store.Remove(ctx, key)
+}
+
 func NewMockStore() *MockStore {
 	ml := &MockStore{
 		Called:     make(map[string]int),
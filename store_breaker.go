@@ -0,0 +1,203 @@
+/*
+Copyright 2018-2022 Mailgun Technologies Inc
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gubernator
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/mailgun/holster/v4/clock"
+	"github.com/mailgun/holster/v4/errors"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var storeBreakerCounter = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Name: "gubernator_store_breaker_transitions",
+	Help: "The number of times the circuit breaker around the Store changed state.  Label \"state\" is the new state, \"open\", \"half-open\" or \"closed\".",
+}, []string{"state"})
+
+// ErrStoreUnavailable is returned by FallibleStore.GetWithError() while the circuit breaker around the
+// Store is open, see Config.StoreBreakerThreshold
+var ErrStoreUnavailable = errors.New("store is unavailable; circuit breaker is open")
+
+type breakerState int
+
+const (
+	breakerClosed breakerState = iota
+	breakerOpen
+	breakerHalfOpen
+)
+
+func (s breakerState) String() string {
+	switch s {
+	case breakerOpen:
+		return "open"
+	case breakerHalfOpen:
+		return "half-open"
+	}
+	return "closed"
+}
+
+// breakerStore wraps a Store with a circuit breaker. Once `threshold` consecutive calls fail or take
+// longer than `timeout` the breaker opens and the store is not called for `cooldown`, during which
+// rate limits missing from the cache are created new and changes are not written to the store. Once
+// the cooldown has passed a single Get() probes the store, which closes the breaker if it succeeds.
+type breakerStore struct {
+	store     Store
+	threshold int
+	timeout   time.Duration
+	cooldown  time.Duration
+	log       FieldLogger
+
+	mutex    sync.Mutex
+	state    breakerState
+	failures int
+	openedAt time.Time
+	probing  bool
+}
+
+var _ FallibleStore = &breakerStore{}
+
+func newBreakerStore(store Store, conf Config, log FieldLogger) *breakerStore {
+	return &breakerStore{
+		store:     store,
+		threshold: conf.StoreBreakerThreshold,
+		timeout:   conf.StoreBreakerTimeout,
+		cooldown:  conf.StoreBreakerCooldown,
+		log:       log,
+	}
+}
+
+func (b *breakerStore) OnChange(ctx context.Context, r *RateLimitReq, item *CacheItem) {
+	_ = b.call(ctx, false, func(ctx context.Context) error {
+		b.store.OnChange(ctx, r, item)
+		return nil
+	})
+}
+
+func (b *breakerStore) Get(ctx context.Context, r *RateLimitReq) (*CacheItem, bool) {
+	item, ok, _ := b.GetWithError(ctx, r)
+	return item, ok
+}
+
+func (b *breakerStore) GetWithError(ctx context.Context, r *RateLimitReq) (item *CacheItem, ok bool, err error) {
+	err = b.call(ctx, true, func(ctx context.Context) error {
+		var err error
+		item, ok, err = getFromStore(ctx, b.store, r)
+		return err
+	})
+	if err != nil {
+		return nil, false, err
+	}
+	return item, ok, nil
+}
+
+func (b *breakerStore) Remove(ctx context.Context, key string) {
+	_ = b.call(ctx, false, func(ctx context.Context) error {
+		b.store.Remove(ctx, key)
+		return nil
+	})
+}
+
+// call calls `fn` with a context which expires after `timeout` unless the breaker is open. Only a
+// `probe` may be made while the breaker is half open, since only Get() reports whether the store
+// succeeded.
+func (b *breakerStore) call(ctx context.Context, probe bool, fn func(ctx context.Context) error) error {
+	if !b.allow(probe) {
+		return ErrStoreUnavailable
+	}
+
+	callCtx, cancel := context.WithTimeout(ctx, b.timeout)
+	defer cancel()
+	err := fn(callCtx)
+	// A deadline of the client which is shorter than ours is not the fault of the store
+	if err == nil && callCtx.Err() != nil && ctx.Err() == nil {
+		err = errors.Errorf("store call exceeded '%s'", b.timeout)
+	}
+	b.done(err, probe)
+	return err
+}
+
+// allow returns false if the store should not be called
+func (b *breakerStore) allow(probe bool) bool {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	if b.state == breakerOpen && clock.Since(b.openedAt) >= b.cooldown {
+		b.transition(breakerHalfOpen)
+	}
+	switch b.state {
+	case breakerOpen:
+		return false
+	case breakerHalfOpen:
+		if !probe || b.probing {
+			return false
+		}
+		b.probing = true
+	}
+	return true
+}
+
+// done records the outcome of a call to the store. Only the outcome of a `probe` is known to be a
+// success, other calls only report timeouts.
+func (b *breakerStore) done(err error, probe bool) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	switch b.state {
+	case breakerHalfOpen:
+		if !probe {
+			return
+		}
+		b.probing = false
+		if err != nil {
+			b.openedAt = clock.Now()
+			b.transition(breakerOpen)
+			return
+		}
+		b.failures = 0
+		b.transition(breakerClosed)
+	case breakerClosed:
+		if err == nil {
+			if probe {
+				b.failures = 0
+			}
+			return
+		}
+		b.failures++
+		if b.failures >= b.threshold {
+			b.openedAt = clock.Now()
+			b.transition(breakerOpen)
+		}
+	}
+}
+
+// transition must be called with the mutex held
+func (b *breakerStore) transition(state breakerState) {
+	b.state = state
+	storeBreakerCounter.WithLabelValues(state.String()).Add(1)
+	switch state {
+	case breakerOpen:
+		b.log.WithField("failures", b.failures).
+			Warnf("store circuit breaker opened; serving from the cache only for %s", b.cooldown)
+	case breakerHalfOpen:
+		b.log.Info("store circuit breaker is half open; probing the store")
+	case breakerClosed:
+		b.log.Info("store circuit breaker closed; the store has recovered")
+	}
+}
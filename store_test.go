@@ -18,16 +18,23 @@ package gubernator_test
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"net"
+	"sync"
+	"sync/atomic"
 	"testing"
 
+	"github.com/OneOfOne/xxhash"
 	"github.com/mailgun/gubernator/v2"
 	"github.com/mailgun/holster/v4/clock"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
 )
 
 type v1Server struct {
@@ -120,7 +127,7 @@ func TestLoader(t *testing.T) {
 	item, ok := loader.CacheItems[0].Value.(*gubernator.TokenBucketItem)
 	require.Equal(t, true, ok)
 	assert.Equal(t, int64(2), item.Limit)
-	assert.Equal(t, int64(1), item.Remaining)
+	assert.Equal(t, float64(1), item.Remaining)
 	assert.Equal(t, gubernator.Status_UNDER_LIMIT, item.Status)
 }
 
@@ -200,7 +207,7 @@ func TestStore(t *testing.T) {
 				Limit:     req.Limit,
 				Duration:  req.Duration,
 				CreatedAt: gubernator.MillisecondNow(),
-				Remaining: req.Limit,
+				Remaining: float64(req.Limit),
 			}
 
 		case gubernator.Algorithm_LEAKY_BUCKET:
@@ -309,6 +316,58 @@ func TestStore(t *testing.T) {
 				store.AssertExpectations(t)
 			})
 
+			t.Run("No store behavior skips the store", func(t *testing.T) {
+				store, srv, client := setup()
+				defer tearDown(srv)
+
+				ephemeral := &gubernator.RateLimitReq{
+					Name:      "test_no_store",
+					UniqueKey: "account:1234",
+					Algorithm: testCase.Algorithm,
+					Behavior:  gubernator.Behavior_NO_STORE,
+					Duration:  gubernator.Second,
+					Limit:     10,
+					Hits:      1,
+				}
+				req := &gubernator.RateLimitReq{
+					Name:      "test_over_limit",
+					UniqueKey: "account:1234",
+					Algorithm: testCase.Algorithm,
+					Duration:  gubernator.Second,
+					Limit:     10,
+					Hits:      1,
+				}
+
+				// Only the normal request reaches the store.
+				store.On("Get", mock.Anything, matchReq(req)).Once().Return(nil, false)
+				store.On("OnChange", mock.Anything, matchReq(req), matchItem(req)).Once()
+
+				// Call code; the ephemeral rate limit is still held by the cache.
+				for i, remaining := range []int64{9, 8} {
+					resp, err := client.GetRateLimits(ctx, &gubernator.GetRateLimitsReq{
+						Requests: []*gubernator.RateLimitReq{ephemeral},
+					})
+					require.NoError(t, err)
+					require.Len(t, resp.Responses, 1)
+					assert.Equal(t, "", resp.Responses[0].Error)
+					assert.Equal(t, remaining, resp.Responses[0].Remaining, i)
+				}
+
+				reset := proto.Clone(ephemeral).(*gubernator.RateLimitReq)
+				reset.Behavior |= gubernator.Behavior_RESET_REMAINING
+				resp, err := client.GetRateLimits(ctx, &gubernator.GetRateLimitsReq{
+					Requests: []*gubernator.RateLimitReq{reset, req},
+				})
+				require.NoError(t, err)
+				require.Len(t, resp.Responses, 2)
+				assert.Equal(t, "", resp.Responses[0].Error)
+				assert.Equal(t, "", resp.Responses[1].Error)
+				store.AssertExpectations(t)
+				store.AssertNotCalled(t, "Get", mock.Anything, matchReq(ephemeral))
+				store.AssertNotCalled(t, "OnChange", mock.Anything, matchReq(ephemeral), mock.Anything)
+				store.AssertNotCalled(t, "Remove", mock.Anything, ephemeral.HashKey())
+			})
+
 			t.Run("Algorithm changed", func(t *testing.T) {
 				// Removes stored item, then creates new.
 				store, srv, client := setup()
@@ -533,12 +592,1029 @@ func TestStore(t *testing.T) {
 	}
 }
 
+type bulkStore struct {
+	*gubernator.MockStore
+	LoadAllCalled int
+}
+
+var _ gubernator.BulkStore = &bulkStore{}
+
+func (bs *bulkStore) LoadAll() (chan *gubernator.CacheItem, error) {
+	bs.LoadAllCalled += 1
+
+	ch := make(chan *gubernator.CacheItem, 10)
+	go func() {
+		for _, item := range bs.CacheItems {
+			ch <- item
+		}
+		close(ch)
+	}()
+	return ch, nil
+}
+
+func TestBulkStore(t *testing.T) {
+	req := &gubernator.RateLimitReq{
+		Name:      "test_bulk_store",
+		UniqueKey: "account:1234",
+		Algorithm: gubernator.Algorithm_TOKEN_BUCKET,
+		Duration:  gubernator.Minute,
+		Limit:     10,
+		Hits:      1,
+	}
+
+	store := &bulkStore{MockStore: gubernator.NewMockStore()}
+	store.CacheItems[req.HashKey()] = &gubernator.CacheItem{
+		Algorithm: gubernator.Algorithm_TOKEN_BUCKET,
+		Key:       req.HashKey(),
+		Value: &gubernator.TokenBucketItem{
+			Status:    gubernator.Status_UNDER_LIMIT,
+			Limit:     10,
+			Duration:  gubernator.Minute,
+			Remaining: 3,
+			CreatedAt: gubernator.MillisecondNow(),
+		},
+		ExpireAt: gubernator.MillisecondNow() + gubernator.Minute,
+	}
+
+	srv := newV1Server(t, "", gubernator.Config{
+		Behaviors: gubernator.BehaviorConfig{
+			GlobalSyncWait: clock.Millisecond * 50, // Suitable for testing but not production
+			GlobalTimeout:  clock.Second,
+		},
+		Store: store,
+	})
+	defer srv.Close()
+
+	// store.LoadAll() should have been called during gubernator startup
+	assert.Equal(t, 1, store.LoadAllCalled)
+
+	client, err := gubernator.DialV1Server(srv.listener.Addr().String(), nil)
+	require.NoError(t, err)
+
+	resp, err := client.GetRateLimits(context.Background(), &gubernator.GetRateLimitsReq{
+		Requests: []*gubernator.RateLimitReq{req},
+	})
+	require.NoError(t, err)
+	require.Len(t, resp.Responses, 1)
+	assert.Equal(t, "", resp.Responses[0].Error)
+	assert.Equal(t, int64(2), resp.Responses[0].Remaining)
+
+	// The cache was warmed, so the store should not have been asked for the rate limit
+	assert.Equal(t, 0, store.Called["Get()"])
+	assert.Equal(t, 1, store.Called["OnChange()"])
+}
+
+func TestResetNamespaceStore(t *testing.T) {
+	newReq := func(name, key string) *gubernator.RateLimitReq {
+		return &gubernator.RateLimitReq{
+			Name:      name,
+			UniqueKey: key,
+			Algorithm: gubernator.Algorithm_TOKEN_BUCKET,
+			Duration:  gubernator.Minute,
+			Limit:     10,
+			Hits:      1,
+		}
+	}
+	stored := newReq("test_reset_ns", "account:stored")
+
+	store := &bulkStore{MockStore: gubernator.NewMockStore()}
+	store.CacheItems[stored.HashKey()] = &gubernator.CacheItem{
+		Algorithm: gubernator.Algorithm_TOKEN_BUCKET,
+		Key:       stored.HashKey(),
+		Value: &gubernator.TokenBucketItem{
+			Status:    gubernator.Status_UNDER_LIMIT,
+			Limit:     10,
+			Duration:  gubernator.Minute,
+			Remaining: 3,
+			CreatedAt: gubernator.MillisecondNow(),
+		},
+		ExpireAt: gubernator.MillisecondNow() + gubernator.Minute,
+	}
+
+	srv := newV1Server(t, "", gubernator.Config{
+		Behaviors: gubernator.BehaviorConfig{
+			GlobalSyncWait: clock.Millisecond * 50, // Suitable for testing but not production
+			GlobalTimeout:  clock.Second,
+		},
+		Store: store,
+		// The keys of `test_reset_ns_sub` also begin with `test_reset_ns_`
+		NamespaceDefaults: map[string]*gubernator.RateLimitReq{
+			"test_reset_ns_sub": {Limit: 10},
+		},
+	})
+	defer srv.Close()
+
+	reset := newReq("test_reset_ns", "account:1")
+	other := newReq("test_reset_ns_sub", "account:1")
+	_, err := srv.srv.GetRateLimits(context.Background(), &gubernator.GetRateLimitsReq{
+		Requests: []*gubernator.RateLimitReq{reset, other},
+	})
+	require.NoError(t, err)
+
+	resp, err := srv.srv.ResetNamespace(context.Background(), &gubernator.ResetNamespaceReq{Name: "test_reset_ns"})
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), resp.Cleared)
+
+	// Removed from the store, except for the rate limit of the longer namespace
+	assert.NotContains(t, store.CacheItems, stored.HashKey())
+	assert.NotContains(t, store.CacheItems, reset.HashKey())
+	assert.Contains(t, store.CacheItems, other.HashKey())
+
+	other.Hits = 0
+	r, err := srv.srv.GetRateLimits(context.Background(), &gubernator.GetRateLimitsReq{
+		Requests: []*gubernator.RateLimitReq{other},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, int64(9), r.Responses[0].Remaining)
+}
+
+func TestStorePeek(t *testing.T) {
+	for _, algorithm := range []gubernator.Algorithm{gubernator.Algorithm_TOKEN_BUCKET, gubernator.Algorithm_LEAKY_BUCKET} {
+		t.Run(algorithm.String(), func(t *testing.T) {
+			store := &MockStore2{}
+			srv := newV1Server(t, "", gubernator.Config{
+				Behaviors: gubernator.BehaviorConfig{
+					GlobalSyncWait: clock.Millisecond * 50, // Suitable for testing but not production
+					GlobalTimeout:  clock.Second,
+				},
+				Store: store,
+			})
+			defer srv.Close()
+
+			client, err := gubernator.DialV1Server(srv.listener.Addr().String(), nil)
+			require.NoError(t, err)
+
+			req := &gubernator.RateLimitReq{
+				Name:      "test_store_peek",
+				UniqueKey: "account:1234",
+				Algorithm: algorithm,
+				Behavior:  gubernator.Behavior_PEEK,
+				Duration:  gubernator.Minute,
+				Limit:     10,
+				Hits:      4,
+			}
+
+			// The store is consulted for the rate limit, but never told of a change
+			store.On("Get", mock.Anything, mock.Anything).Times(2).Return(nil, false)
+
+			for i := 0; i < 2; i++ {
+				resp, err := client.GetRateLimits(context.Background(), &gubernator.GetRateLimitsReq{
+					Requests: []*gubernator.RateLimitReq{req},
+				})
+				require.NoError(t, err)
+				require.Len(t, resp.Responses, 1)
+				assert.Empty(t, resp.Responses[0].Error)
+				assert.Equal(t, gubernator.Status_UNDER_LIMIT, resp.Responses[0].Status)
+				assert.Equal(t, int64(6), resp.Responses[0].Remaining)
+			}
+			store.AssertExpectations(t)
+			store.AssertNotCalled(t, "OnChange", mock.Anything, mock.Anything, mock.Anything)
+			store.AssertNotCalled(t, "Remove", mock.Anything, mock.Anything)
+		})
+	}
+}
+
+// A thread safe store which records the latest item for each rate limit
+type syncStore struct {
+	mutex sync.Mutex
+	items map[string]*gubernator.CacheItem
+}
+
+var _ gubernator.Store = &syncStore{}
+
+func (ss *syncStore) OnChange(ctx context.Context, r *gubernator.RateLimitReq, item *gubernator.CacheItem) {
+	ss.mutex.Lock()
+	defer ss.mutex.Unlock()
+	ss.items[item.Key] = item
+}
+
+func (ss *syncStore) Get(ctx context.Context, r *gubernator.RateLimitReq) (*gubernator.CacheItem, bool) {
+	ss.mutex.Lock()
+	defer ss.mutex.Unlock()
+	item, ok := ss.items[r.HashKey()]
+	return item, ok
+}
+
+func (ss *syncStore) Remove(ctx context.Context, key string) {
+	ss.mutex.Lock()
+	defer ss.mutex.Unlock()
+	delete(ss.items, key)
+}
+
+func TestAsyncStore(t *testing.T) {
+	const (
+		keys = 20
+		hits = 50
+	)
+	store := &syncStore{items: make(map[string]*gubernator.CacheItem)}
+
+	srv := newV1Server(t, "", gubernator.Config{
+		Behaviors: gubernator.BehaviorConfig{
+			GlobalSyncWait: clock.Millisecond * 50, // Suitable for testing but not production
+			GlobalTimeout:  clock.Second,
+		},
+		Store: store,
+		// Only flush when the buffer fills or when the instance is closed
+		StoreFlushInterval: clock.Hour,
+		StoreBufferSize:    7,
+	})
+
+	client, err := gubernator.DialV1Server(srv.listener.Addr().String(), nil)
+	require.NoError(t, err)
+
+	// Send a steady stream of hits to each rate limit concurrently
+	var wg sync.WaitGroup
+	for i := 0; i < keys; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < hits; j++ {
+				resp, err := client.GetRateLimits(context.Background(), &gubernator.GetRateLimitsReq{
+					Requests: []*gubernator.RateLimitReq{
+						{
+							Name:      "test_async_store",
+							UniqueKey: fmt.Sprintf("account:%d", i),
+							Algorithm: gubernator.Algorithm_TOKEN_BUCKET,
+							Duration:  gubernator.Minute,
+							Limit:     1000,
+							Hits:      1,
+						},
+					},
+				})
+				assert.NoError(t, err)
+				assert.Equal(t, "", resp.Responses[0].Error)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	require.NoError(t, srv.Close())
+
+	// After close all changes should have reached the store
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+	require.Len(t, store.items, keys)
+	for i := 0; i < keys; i++ {
+		item, ok := store.items[fmt.Sprintf("test_async_store_account:%d", i)]
+		require.True(t, ok)
+		assert.Equal(t, int64(1000-hits), getRemaining(item))
+	}
+}
+
+func TestAsyncStoreResetNamespace(t *testing.T) {
+	store := &syncStore{items: make(map[string]*gubernator.CacheItem)}
+
+	srv := newV1Server(t, "", gubernator.Config{
+		Behaviors: gubernator.BehaviorConfig{
+			GlobalSyncWait: clock.Millisecond * 50, // Suitable for testing but not production
+			GlobalTimeout:  clock.Second,
+		},
+		Store: store,
+		// Only flush when the instance is closed
+		StoreFlushInterval: clock.Hour,
+		StoreBufferSize:    1000,
+	})
+
+	client, err := gubernator.DialV1Server(srv.listener.Addr().String(), nil)
+	require.NoError(t, err)
+
+	resp, err := client.GetRateLimits(context.Background(), &gubernator.GetRateLimitsReq{
+		Requests: []*gubernator.RateLimitReq{
+			{
+				Name:      "test_async_store_reset",
+				UniqueKey: "account:1",
+				Algorithm: gubernator.Algorithm_TOKEN_BUCKET,
+				Duration:  gubernator.Minute,
+				Limit:     10,
+				Hits:      1,
+			},
+		},
+	})
+	require.NoError(t, err)
+	require.Equal(t, "", resp.Responses[0].Error)
+
+	reset, err := srv.srv.ResetNamespace(context.Background(), &gubernator.ResetNamespaceReq{Name: "test_async_store_reset"})
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), reset.Cleared)
+
+	require.NoError(t, srv.Close())
+
+	// The buffered change must not be flushed to the store once the namespace was reset
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+	assert.NotContains(t, store.items, "test_async_store_reset_account:1")
+}
+
+// A store which holds the first OnChange() until `release` is closed, `writing` is closed once it is held
+type heldStore struct {
+	syncStore
+	writing chan struct{}
+	release chan struct{}
+	once    sync.Once
+}
+
+func (hs *heldStore) OnChange(ctx context.Context, r *gubernator.RateLimitReq, item *gubernator.CacheItem) {
+	hs.once.Do(func() {
+		close(hs.writing)
+		<-hs.release
+	})
+	hs.syncStore.OnChange(ctx, r, item)
+}
+
+func TestAsyncStoreRemoveDuringFlush(t *testing.T) {
+	store := &heldStore{
+		syncStore: syncStore{items: make(map[string]*gubernator.CacheItem)},
+		writing:   make(chan struct{}),
+		release:   make(chan struct{}),
+	}
+
+	srv := newV1Server(t, "", gubernator.Config{
+		Behaviors: gubernator.BehaviorConfig{
+			GlobalSyncWait: clock.Millisecond * 50, // Suitable for testing but not production
+			GlobalTimeout:  clock.Second,
+		},
+		Store: store,
+		// Flush as soon as a rate limit changes
+		StoreFlushInterval: clock.Hour,
+		StoreBufferSize:    1,
+	})
+
+	resp, err := srv.srv.GetRateLimits(context.Background(), &gubernator.GetRateLimitsReq{
+		Requests: []*gubernator.RateLimitReq{
+			{
+				Name:      "test_async_store_remove",
+				UniqueKey: "account:1",
+				Algorithm: gubernator.Algorithm_TOKEN_BUCKET,
+				Duration:  gubernator.Minute,
+				Limit:     10,
+				Hits:      1,
+			},
+		},
+	})
+	require.NoError(t, err)
+	require.Equal(t, "", resp.Responses[0].Error)
+
+	// Remove the rate limit while the flush is writing it to the store
+	<-store.writing
+	deleted := make(chan *gubernator.DeleteRateLimitResp)
+	go func() {
+		del, err := srv.srv.DeleteRateLimit(context.Background(), &gubernator.DeleteRateLimitReq{
+			Name:      "test_async_store_remove",
+			UniqueKey: "account:1",
+		})
+		assert.NoError(t, err)
+		deleted <- del
+	}()
+
+	// Give DeleteRateLimit the chance to finish before the flush does
+	var del *gubernator.DeleteRateLimitResp
+	select {
+	case del = <-deleted:
+	case <-clock.After(clock.Millisecond * 100):
+	}
+	close(store.release)
+	if del == nil {
+		del = <-deleted
+	}
+	assert.True(t, del.Found)
+	require.NoError(t, srv.Close())
+
+	// The write of the flush must not restore the removed rate limit
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+	assert.NotContains(t, store.items, "test_async_store_remove_account:1")
+}
+
 func getRemaining(item *gubernator.CacheItem) int64 {
 	switch item.Algorithm {
 	case gubernator.Algorithm_TOKEN_BUCKET:
-		return item.Value.(*gubernator.TokenBucketItem).Remaining
+		return int64(item.Value.(*gubernator.TokenBucketItem).Remaining)
 	case gubernator.Algorithm_LEAKY_BUCKET:
 		return int64(item.Value.(*gubernator.LeakyBucketItem).Remaining)
 	}
 	return 0
 }
+
+// A store which counts the calls to Get() and takes a while to answer them
+type countingStore struct {
+	syncStore
+	gets int64
+}
+
+func (cs *countingStore) Get(ctx context.Context, r *gubernator.RateLimitReq) (*gubernator.CacheItem, bool) {
+	atomic.AddInt64(&cs.gets, 1)
+	clock.Sleep(clock.Millisecond * 50)
+	return cs.syncStore.Get(ctx, r)
+}
+
+func TestStoreGetOncePerKey(t *testing.T) {
+	const callers = 50
+
+	for _, test := range []struct {
+		name    string
+		inStore bool
+	}{
+		{name: "InStore", inStore: true},
+		{name: "NotInStore", inStore: false},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			req := &gubernator.RateLimitReq{
+				Name:      "test_store_get_once_per_key",
+				UniqueKey: "account:" + test.name,
+				Algorithm: gubernator.Algorithm_TOKEN_BUCKET,
+				Duration:  gubernator.Minute,
+				Limit:     100,
+				Hits:      1,
+			}
+
+			store := &countingStore{syncStore: syncStore{items: make(map[string]*gubernator.CacheItem)}}
+			if test.inStore {
+				store.items[req.HashKey()] = &gubernator.CacheItem{
+					Algorithm: gubernator.Algorithm_TOKEN_BUCKET,
+					Key:       req.HashKey(),
+					Value: &gubernator.TokenBucketItem{
+						Status:    gubernator.Status_UNDER_LIMIT,
+						Limit:     100,
+						Duration:  gubernator.Minute,
+						Remaining: 80,
+						CreatedAt: gubernator.MillisecondNow(),
+					},
+					ExpireAt: gubernator.MillisecondNow() + gubernator.Minute,
+				}
+			}
+
+			srv := newV1Server(t, "", gubernator.Config{Store: store})
+			defer srv.Close()
+
+			client, err := gubernator.DialV1Server(srv.listener.Addr().String(), nil)
+			require.NoError(t, err)
+
+			// Every caller misses the cache at the same time
+			var wg sync.WaitGroup
+			for i := 0; i < callers; i++ {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					resp, err := client.GetRateLimits(context.Background(), &gubernator.GetRateLimitsReq{
+						Requests: []*gubernator.RateLimitReq{req},
+					})
+					require.NoError(t, err)
+					assert.Equal(t, gubernator.Status_UNDER_LIMIT, resp.Responses[0].Status)
+				}()
+			}
+			wg.Wait()
+
+			// Every request for a rate limit is handled by the same pool worker, so only the
+			// first request reads the store and the rest find the rate limit in the cache.
+			assert.Equal(t, int64(1), atomic.LoadInt64(&store.gets))
+
+			// The hits of every caller were applied to the loaded rate limit
+			expected := int64(100 - callers)
+			if test.inStore {
+				expected = 80 - callers
+			}
+			req.Hits = 0
+			resp, err := client.GetRateLimits(context.Background(), &gubernator.GetRateLimitsReq{
+				Requests: []*gubernator.RateLimitReq{req},
+			})
+			require.NoError(t, err)
+			assert.Equal(t, expected, resp.Responses[0].Remaining)
+			assert.Equal(t, int64(1), atomic.LoadInt64(&store.gets))
+		})
+	}
+}
+
+// A store which blocks in Get() until the context is done
+type slowStore struct {
+	getErr chan error
+}
+
+var _ gubernator.Store = &slowStore{}
+
+func (ss *slowStore) OnChange(ctx context.Context, r *gubernator.RateLimitReq, item *gubernator.CacheItem) {
+}
+
+func (ss *slowStore) Get(ctx context.Context, r *gubernator.RateLimitReq) (*gubernator.CacheItem, bool) {
+	select {
+	case <-ctx.Done():
+		ss.getErr <- ctx.Err()
+	case <-clock.After(clock.Second * 10):
+		ss.getErr <- nil
+	}
+	return nil, false
+}
+
+func (ss *slowStore) Remove(ctx context.Context, key string) {}
+
+func TestStoreDeadline(t *testing.T) {
+	store := &slowStore{getErr: make(chan error, 1)}
+	srv := newV1Server(t, "", gubernator.Config{
+		Behaviors: gubernator.BehaviorConfig{
+			GlobalSyncWait: clock.Millisecond * 50, // Suitable for testing but not production
+			GlobalTimeout:  clock.Second,
+		},
+		Store: store,
+	})
+	defer srv.Close()
+
+	client, err := gubernator.DialV1Server(srv.listener.Addr().String(), nil)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), clock.Millisecond*100)
+	defer cancel()
+
+	start := clock.Now()
+	_, err = client.GetRateLimits(ctx, &gubernator.GetRateLimitsReq{
+		Requests: []*gubernator.RateLimitReq{
+			{
+				Name:      "test_store_deadline",
+				UniqueKey: "account:1234",
+				Algorithm: gubernator.Algorithm_TOKEN_BUCKET,
+				Duration:  gubernator.Minute,
+				Limit:     10,
+				Hits:      1,
+			},
+		},
+	})
+	require.Error(t, err)
+	assert.Equal(t, codes.DeadlineExceeded, status.Code(err))
+	assert.Less(t, clock.Since(start), clock.Second)
+
+	// The store should have observed the deadline of the client request. Depending on which arrives
+	// first, the server context is done either by its own deadline or by the client cancelling the stream.
+	select {
+	case err := <-store.getErr:
+		if !errors.Is(err, context.Canceled) {
+			assert.ErrorIs(t, err, context.DeadlineExceeded)
+		}
+	case <-clock.After(clock.Second):
+		t.Fatal("timed out waiting for the store to return")
+	}
+}
+
+// A store which fails to read while `failing` is set. When `invalidAfter` is set, the cache is asked
+// to read each changed rate limit from the store again once it has passed.
+type failingStore struct {
+	failing      int32
+	gets         int64
+	invalidAfter clock.Duration
+}
+
+var _ gubernator.FallibleStore = &failingStore{}
+
+func (fs *failingStore) OnChange(ctx context.Context, r *gubernator.RateLimitReq, item *gubernator.CacheItem) {
+	if fs.invalidAfter != 0 {
+		item.InvalidAt = gubernator.MillisecondNow() + fs.invalidAfter.Milliseconds()
+	}
+}
+
+func (fs *failingStore) Get(ctx context.Context, r *gubernator.RateLimitReq) (*gubernator.CacheItem, bool) {
+	item, ok, _ := fs.GetWithError(ctx, r)
+	return item, ok
+}
+
+func (fs *failingStore) GetWithError(ctx context.Context, r *gubernator.RateLimitReq) (*gubernator.CacheItem, bool, error) {
+	atomic.AddInt64(&fs.gets, 1)
+	if atomic.LoadInt32(&fs.failing) != 0 {
+		return nil, false, fmt.Errorf("connection refused")
+	}
+	return nil, false, nil
+}
+
+func (fs *failingStore) Remove(ctx context.Context, key string) {}
+
+func TestStoreBreaker(t *testing.T) {
+	defer clock.Freeze(clock.Now()).Unfreeze()
+
+	store := &failingStore{failing: 1}
+	srv := newV1Server(t, "", gubernator.Config{
+		Behaviors: gubernator.BehaviorConfig{
+			GlobalSyncWait: clock.Millisecond * 50, // Suitable for testing but not production
+			GlobalTimeout:  clock.Second,
+		},
+		Store:                 store,
+		StoreBreakerThreshold: 3,
+		StoreBreakerCooldown:  clock.Second * 5,
+	})
+	defer srv.Close()
+
+	client, err := gubernator.DialV1Server(srv.listener.Addr().String(), nil)
+	require.NoError(t, err)
+
+	// Each request misses the cache and asks the store
+	sendHit := func(key string) {
+		t.Helper()
+		resp, err := client.GetRateLimits(context.Background(), &gubernator.GetRateLimitsReq{
+			Requests: []*gubernator.RateLimitReq{{
+				Name:      "test_store_breaker",
+				UniqueKey: key,
+				Algorithm: gubernator.Algorithm_TOKEN_BUCKET,
+				Duration:  gubernator.Minute,
+				Limit:     10,
+				Hits:      1,
+			}},
+		})
+		require.NoError(t, err)
+		assert.Empty(t, resp.Responses[0].Error)
+		assert.Equal(t, gubernator.Status_UNDER_LIMIT, resp.Responses[0].Status)
+		assert.Equal(t, int64(9), resp.Responses[0].Remaining)
+	}
+
+	// The breaker opens after the threshold, after which requests are served from the cache alone
+	for i := 0; i < 10; i++ {
+		sendHit(fmt.Sprintf("account:%d", i))
+	}
+	assert.Equal(t, int64(3), atomic.LoadInt64(&store.gets))
+
+	// Still open until the cooldown has passed
+	clock.Advance(clock.Second * 4)
+	sendHit("account:10")
+	assert.Equal(t, int64(3), atomic.LoadInt64(&store.gets))
+
+	// A failed probe opens the breaker again
+	clock.Advance(clock.Second)
+	sendHit("account:11")
+	sendHit("account:12")
+	assert.Equal(t, int64(4), atomic.LoadInt64(&store.gets))
+
+	// A successful probe closes the breaker
+	atomic.StoreInt32(&store.failing, 0)
+	clock.Advance(clock.Second * 5)
+	sendHit("account:13")
+	sendHit("account:14")
+	sendHit("account:15")
+	assert.Equal(t, int64(7), atomic.LoadInt64(&store.gets))
+}
+
+func TestStoreErrorPolicy(t *testing.T) {
+	defer clock.Freeze(clock.Now()).Unfreeze()
+
+	tests := []struct {
+		Name     string
+		Policy   gubernator.StoreErrorPolicy
+		Behavior gubernator.Behavior
+		// The remaining after a hit while the store fails, or an error
+		Remaining int64
+		Error     bool
+	}{
+		{
+			Name:      "new",
+			Policy:    gubernator.StoreErrorNew,
+			Remaining: 9,
+		},
+		{
+			Name:   "fail",
+			Policy: gubernator.StoreErrorFail,
+			Error:  true,
+		},
+		{
+			Name:      "fail ignored by the request",
+			Policy:    gubernator.StoreErrorFail,
+			Behavior:  gubernator.Behavior_IGNORE_STORE_ERRORS,
+			Remaining: 9,
+		},
+		{
+			Name:      "serve cached",
+			Policy:    gubernator.StoreErrorServeCached,
+			Remaining: 4,
+		},
+	}
+
+	for _, algorithm := range []gubernator.Algorithm{gubernator.Algorithm_TOKEN_BUCKET, gubernator.Algorithm_LEAKY_BUCKET} {
+		for _, test := range tests {
+			t.Run(algorithm.String()+"/"+test.Name, func(t *testing.T) {
+				store := &failingStore{invalidAfter: clock.Second}
+				srv := newV1Server(t, "", gubernator.Config{
+					Store:            store,
+					StoreErrorPolicy: test.Policy,
+				})
+				defer srv.Close()
+
+				send := func(hits int64) *gubernator.RateLimitResp {
+					resp, err := srv.srv.GetRateLimits(context.Background(), &gubernator.GetRateLimitsReq{
+						Requests: []*gubernator.RateLimitReq{{
+							Name:      "test_store_error_policy",
+							UniqueKey: "account:1234",
+							Algorithm: algorithm,
+							Behavior:  test.Behavior,
+							Duration:  gubernator.Minute,
+							Limit:     10,
+							Hits:      hits,
+						}},
+					})
+					require.NoError(t, err)
+					return resp.Responses[0]
+				}
+
+				rl := send(5)
+				require.Empty(t, rl.Error)
+				assert.Equal(t, int64(5), rl.Remaining)
+
+				// The cache invalidated the rate limit and the store cannot provide it
+				clock.Advance(clock.Second * 2)
+				atomic.StoreInt32(&store.failing, 1)
+				gets := atomic.LoadInt64(&store.gets)
+
+				rl = send(1)
+				assert.Equal(t, gets+1, atomic.LoadInt64(&store.gets))
+				if test.Error {
+					assert.Contains(t, rl.Error, "connection refused")
+					return
+				}
+				require.Empty(t, rl.Error)
+				assert.Equal(t, gubernator.Status_UNDER_LIMIT, rl.Status)
+				assert.Equal(t, test.Remaining, rl.Remaining)
+			})
+		}
+	}
+}
+
+// A store which provides a copy of `item` from Get() once set, and asks the cache to read each changed
+// rate limit from the store again a second later
+type seededStore struct {
+	mutex sync.Mutex
+	item  *gubernator.CacheItem
+}
+
+var _ gubernator.Store = &seededStore{}
+
+func (ss *seededStore) OnChange(ctx context.Context, r *gubernator.RateLimitReq, item *gubernator.CacheItem) {
+	item.InvalidAt = gubernator.MillisecondNow() + clock.Second.Milliseconds()
+}
+
+func (ss *seededStore) Get(ctx context.Context, r *gubernator.RateLimitReq) (*gubernator.CacheItem, bool) {
+	ss.mutex.Lock()
+	defer ss.mutex.Unlock()
+	if ss.item == nil {
+		return nil, false
+	}
+	t := *ss.item.Value.(*gubernator.TokenBucketItem)
+	item := *ss.item
+	item.Value = &t
+	return &item, true
+}
+
+func (ss *seededStore) Remove(ctx context.Context, key string) {}
+
+func TestStoreReconcilePolicy(t *testing.T) {
+	defer clock.Freeze(clock.Now()).Unfreeze()
+
+	tests := []struct {
+		Name   string
+		Policy gubernator.StoreReconcilePolicy
+		// The rate limit in the store, relative to the one in the cache which has 8 remaining
+		StoreRemaining float64
+		StoreCreatedAt clock.Duration
+		StoreTotalHits int64
+		Remaining      int64
+	}{
+		{
+			Name:           "store",
+			Policy:         gubernator.StoreReconcileStore,
+			StoreRemaining: 5,
+			StoreCreatedAt: -clock.Second,
+			Remaining:      5,
+		},
+		{
+			Name:           "newest is the cache",
+			Policy:         gubernator.StoreReconcileNewest,
+			StoreRemaining: 5,
+			StoreCreatedAt: -clock.Second,
+			Remaining:      8,
+		},
+		{
+			Name:           "newest is the store",
+			Policy:         gubernator.StoreReconcileNewest,
+			StoreRemaining: 9,
+			StoreCreatedAt: clock.Second,
+			Remaining:      9,
+		},
+		{
+			Name:           "newest of the same window is the cache",
+			Policy:         gubernator.StoreReconcileNewest,
+			StoreRemaining: 9,
+			StoreTotalHits: 1,
+			Remaining:      8,
+		},
+		{
+			Name:           "newest of the same window is the store",
+			Policy:         gubernator.StoreReconcileNewest,
+			StoreRemaining: 5,
+			StoreTotalHits: 5,
+			Remaining:      5,
+		},
+		{
+			Name:           "conservative is the cache",
+			Policy:         gubernator.StoreReconcileConservative,
+			StoreRemaining: 9,
+			StoreCreatedAt: clock.Second,
+			Remaining:      8,
+		},
+		{
+			Name:           "conservative is the store",
+			Policy:         gubernator.StoreReconcileConservative,
+			StoreRemaining: 5,
+			StoreCreatedAt: -clock.Second,
+			Remaining:      5,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			store := &seededStore{}
+			srv := newV1Server(t, "", gubernator.Config{
+				Store:                store,
+				StoreReconcilePolicy: test.Policy,
+			})
+			defer srv.Close()
+
+			req := &gubernator.RateLimitReq{
+				Name:      "test_store_reconcile_policy",
+				UniqueKey: "account:1234",
+				Algorithm: gubernator.Algorithm_TOKEN_BUCKET,
+				Duration:  gubernator.Minute,
+				Limit:     10,
+			}
+			send := func(hits int64) *gubernator.RateLimitResp {
+				r := proto.Clone(req).(*gubernator.RateLimitReq)
+				r.Hits = hits
+				resp, err := srv.srv.GetRateLimits(context.Background(), &gubernator.GetRateLimitsReq{
+					Requests: []*gubernator.RateLimitReq{r},
+				})
+				require.NoError(t, err)
+				require.Empty(t, resp.Responses[0].Error)
+				return resp.Responses[0]
+			}
+
+			now := gubernator.MillisecondNow()
+			rl := send(2)
+			assert.Equal(t, int64(8), rl.Remaining)
+
+			// Another instance changed the rate limit in the store
+			createdAt := now + test.StoreCreatedAt.Milliseconds()
+			store.mutex.Lock()
+			store.item = &gubernator.CacheItem{
+				Algorithm: gubernator.Algorithm_TOKEN_BUCKET,
+				Key:       req.HashKey(),
+				ExpireAt:  createdAt + gubernator.Minute,
+				Value: &gubernator.TokenBucketItem{
+					Status:    gubernator.Status_UNDER_LIMIT,
+					Limit:     10,
+					Duration:  gubernator.Minute,
+					Remaining: test.StoreRemaining,
+					CreatedAt: createdAt,
+					TotalHits: test.StoreTotalHits,
+				},
+			}
+			store.mutex.Unlock()
+
+			// The cache invalidated the rate limit, both it and the store hold a copy
+			clock.Advance(clock.Second * 2)
+			rl = send(0)
+			assert.Equal(t, test.Remaining, rl.Remaining)
+		})
+	}
+}
+
+// A store which takes `delay` in Get() for the rate limits with the unique key `slow`
+type slowKeyStore struct {
+	delay clock.Duration
+}
+
+var _ gubernator.Store = &slowKeyStore{}
+
+func (ss *slowKeyStore) OnChange(ctx context.Context, r *gubernator.RateLimitReq, item *gubernator.CacheItem) {
+}
+
+func (ss *slowKeyStore) Get(ctx context.Context, r *gubernator.RateLimitReq) (*gubernator.CacheItem, bool) {
+	if r.UniqueKey == "slow" {
+		clock.Sleep(ss.delay)
+	}
+	return nil, false
+}
+
+func (ss *slowKeyStore) Remove(ctx context.Context, key string) {}
+
+func TestBatchItemTimeout(t *testing.T) {
+	srv := newV1Server(t, "", gubernator.Config{
+		Behaviors: gubernator.BehaviorConfig{
+			BatchItemTimeout: clock.Millisecond * 100,
+		},
+		Store:       &slowKeyStore{delay: clock.Second},
+		PoolWorkers: 2,
+	})
+	defer srv.Close()
+
+	newReq := func(key string) *gubernator.RateLimitReq {
+		return &gubernator.RateLimitReq{
+			Name:      "test_batch_item_timeout",
+			UniqueKey: key,
+			Algorithm: gubernator.Algorithm_TOKEN_BUCKET,
+			Duration:  gubernator.Minute,
+			Limit:     10,
+			Hits:      1,
+		}
+	}
+
+	// The fast rate limits must not wait behind the slow rate limit for the same pool worker
+	slow := newReq("slow")
+	req := &gubernator.GetPeerRateLimitsReq{Requests: []*gubernator.RateLimitReq{slow}}
+	for i := 0; len(req.Requests) < 3; i++ {
+		fast := newReq(fmt.Sprintf("fast:%d", i))
+		if poolWorkerIdx(fast, 2) != poolWorkerIdx(slow, 2) {
+			req.Requests = append(req.Requests, fast)
+		}
+	}
+
+	start := clock.Now()
+	resp, err := srv.srv.GetPeerRateLimits(context.Background(), req)
+	require.NoError(t, err)
+	// The batch is answered without waiting for the slow rate limit
+	assert.Less(t, clock.Since(start), clock.Millisecond*500)
+	require.Len(t, resp.RateLimits, 3)
+
+	assert.Contains(t, resp.RateLimits[0].Error, codes.DeadlineExceeded.String())
+	for _, i := range []int{1, 2} {
+		rl := resp.RateLimits[i]
+		assert.Empty(t, rl.Error, i)
+		assert.Equal(t, gubernator.Status_UNDER_LIMIT, rl.Status, i)
+		assert.Equal(t, int64(9), rl.Remaining, i)
+	}
+
+	// The hit of the slow rate limit is not applied once the store answers after the deadline
+	clock.Sleep(clock.Second)
+	slow.Hits = 0
+	status, err := srv.srv.GetRateLimits(context.Background(), &gubernator.GetRateLimitsReq{
+		Requests: []*gubernator.RateLimitReq{slow},
+	})
+	require.NoError(t, err)
+	assert.Empty(t, status.Responses[0].Error)
+	assert.Equal(t, int64(10), status.Responses[0].Remaining)
+}
+
+// A cache which takes `delay` in GetItem() for the rate limits with the unique key `slow` once slow is set
+type slowKeyCache struct {
+	gubernator.Cache
+	delay clock.Duration
+	slow  int32
+}
+
+func (sc *slowKeyCache) GetItem(key string) (*gubernator.CacheItem, bool) {
+	if atomic.LoadInt32(&sc.slow) == 1 && key == "test_batch_item_timeout_cached_slow" {
+		clock.Sleep(sc.delay)
+	}
+	return sc.Cache.GetItem(key)
+}
+
+func TestBatchItemTimeoutCached(t *testing.T) {
+	cache := &slowKeyCache{Cache: gubernator.NewLRUCache(0), delay: clock.Millisecond * 500}
+	srv := newV1Server(t, "", gubernator.Config{
+		Behaviors: gubernator.BehaviorConfig{
+			BatchItemTimeout: clock.Millisecond * 100,
+		},
+		CacheFactory: func(int) gubernator.Cache { return cache },
+		PoolWorkers:  1,
+	})
+	defer srv.Close()
+
+	req := &gubernator.RateLimitReq{
+		Name:      "test_batch_item_timeout_cached",
+		UniqueKey: "slow",
+		Algorithm: gubernator.Algorithm_TOKEN_BUCKET,
+		Duration:  gubernator.Minute,
+		Limit:     10,
+		Hits:      1,
+	}
+	send := func() *gubernator.RateLimitResp {
+		resp, err := srv.srv.GetPeerRateLimits(context.Background(), &gubernator.GetPeerRateLimitsReq{
+			Requests: []*gubernator.RateLimitReq{req},
+		})
+		require.NoError(t, err)
+		require.Len(t, resp.RateLimits, 1)
+		return resp.RateLimits[0]
+	}
+
+	rl := send()
+	assert.Empty(t, rl.Error)
+	assert.Equal(t, int64(9), rl.Remaining)
+
+	// The rate limit is found in the cache after the deadline passed
+	atomic.StoreInt32(&cache.slow, 1)
+	rl = send()
+	assert.Contains(t, rl.Error, codes.DeadlineExceeded.String())
+
+	// Wait for the pool worker to get the rate limit from the cache
+	clock.Sleep(clock.Millisecond * 600)
+	atomic.StoreInt32(&cache.slow, 0)
+
+	// The hit answered as timed out is not applied
+	req.Hits = 0
+	rl = send()
+	assert.Empty(t, rl.Error)
+	assert.Equal(t, int64(9), rl.Remaining)
+}
+
+// poolWorkerIdx mirrors the way GubernatorPool assigns rate limits to its workers
+func poolWorkerIdx(r *gubernator.RateLimitReq, workers int) uint64 {
+	return (xxhash.ChecksumString64S(r.HashKey(), 0) >> 1) / (uint64(1<<63) / uint64(workers))
+}
@@ -341,3 +341,38 @@ func TestHTTPSClientAuth(t *testing.T) {
 	require.NoError(t, err)
 	assert.Equal(t, `{"status":"healthy","message":"","peer_count":1}`, strings.ReplaceAll(string(b), " ", ""))
 }
+
+func TestTLSCertificateAuthority(t *testing.T) {
+	conf := gubernator.DaemonConfig{
+		GRPCListenAddress: "127.0.0.1:9695",
+		HTTPListenAddress: "127.0.0.1:9685",
+		TLS: &gubernator.TLSConfig{
+			CaFile:     "certs/ca.cert",
+			CaKeyFile:  "certs/ca.key",
+			CertFile:   "certs/gubernator.pem",
+			KeyFile:    "certs/gubernator.key",
+			ClientAuth: tls.RequireAndVerifyClientCert,
+		},
+	}
+
+	d := spawnDaemon(t, conf)
+	defer d.Close()
+
+	// A client with the proper CA and a client certificate signed by it is allowed
+	err := makeRequest(t, d.Config())
+	require.NoError(t, err)
+
+	// Given a client which trusts a different CA
+	other := &gubernator.TLSConfig{AutoTLS: true}
+	require.NoError(t, gubernator.SetupTLS(other))
+	err = makeRequest(t, gubernator.DaemonConfig{GRPCListenAddress: conf.GRPCListenAddress, TLS: other})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "certificate")
+
+	// Given a client which trusts the proper CA but provides no client certificate
+	noCert := &gubernator.TLSConfig{
+		ClientTLS: &tls.Config{RootCAs: conf.TLS.ClientTLS.RootCAs},
+	}
+	err = makeRequest(t, gubernator.DaemonConfig{GRPCListenAddress: conf.GRPCListenAddress, TLS: noCert})
+	require.Error(t, err)
+}
@@ -0,0 +1,107 @@
+/*
+Copyright 2018-2022 Mailgun Technologies Inc
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gubernator_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/mailgun/gubernator/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestTracerProvider(t *testing.T) {
+	prev := otel.GetTextMapPropagator()
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	defer otel.SetTextMapPropagator(prev)
+
+	exporter := tracetest.NewInMemoryExporter()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	defer func() { _ = tp.Shutdown(context.Background()) }()
+
+	var daemons []*gubernator.Daemon
+	var peers []gubernator.PeerInfo
+	for i := 0; i < 2; i++ {
+		d := spawnDaemon(t, gubernator.DaemonConfig{
+			GRPCListenAddress: fmt.Sprintf("127.0.0.1:%d", 9720+i*2),
+			HTTPListenAddress: fmt.Sprintf("127.0.0.1:%d", 9721+i*2),
+			TracerProvider:    tp,
+		})
+		defer d.Close()
+		daemons = append(daemons, d)
+		peers = append(peers, gubernator.PeerInfo{GRPCAddress: d.Config().GRPCListenAddress})
+	}
+	for _, d := range daemons {
+		d.SetPeers(peers)
+	}
+
+	// Find a rate limit owned by the second daemon
+	var key string
+	for i := 0; ; i++ {
+		key = fmt.Sprintf("account:%d", i)
+		peer, err := daemons[0].V1Server.GetPeer(context.Background(), "test_tracing_"+key)
+		require.NoError(t, err)
+		if peer.Info().GRPCAddress == daemons[1].Config().GRPCListenAddress {
+			break
+		}
+	}
+
+	client, err := gubernator.DialV1Server(daemons[0].Config().GRPCListenAddress, nil)
+	require.NoError(t, err)
+	resp, err := client.GetRateLimits(context.Background(), &gubernator.GetRateLimitsReq{
+		Requests: []*gubernator.RateLimitReq{
+			{
+				Name:      "test_tracing",
+				UniqueKey: key,
+				Behavior:  gubernator.Behavior_NO_BATCHING,
+				Duration:  gubernator.Minute,
+				Limit:     10,
+				Hits:      1,
+			},
+		},
+	})
+	require.NoError(t, err)
+	require.Empty(t, resp.Responses[0].Error)
+	assert.Equal(t, daemons[1].Config().GRPCListenAddress, resp.Responses[0].Metadata["owner"])
+
+	find := func(name string, kind trace.SpanKind) tracetest.SpanStub {
+		for _, s := range exporter.GetSpans() {
+			if s.Name == name && s.SpanKind == kind {
+				return s
+			}
+		}
+		require.Failf(t, "span not found", "%s (%s)", name, kind)
+		return tracetest.SpanStub{}
+	}
+
+	server := find("pb.gubernator.V1/GetRateLimits", trace.SpanKindServer)
+	forward := find("pb.gubernator.PeersV1/GetPeerRateLimits", trace.SpanKindClient)
+	owner := find("pb.gubernator.PeersV1/GetPeerRateLimits", trace.SpanKindServer)
+
+	// The request to the owner should be a child of the forwarded request
+	assert.Equal(t, server.SpanContext.TraceID(), forward.SpanContext.TraceID())
+	assert.Equal(t, forward.SpanContext.TraceID(), owner.SpanContext.TraceID())
+	assert.Equal(t, forward.SpanContext.SpanID(), owner.Parent.SpanID())
+	assert.True(t, owner.Parent.IsRemote())
+}